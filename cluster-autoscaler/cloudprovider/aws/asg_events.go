@@ -0,0 +1,162 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/aws"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/service/sqs"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// eventsResyncInterval is how often the ASG cache is fully refreshed when it is kept up to date from
+	// events, to catch up with changes no event was received for, e.g. ASGs created or retagged.
+	eventsResyncInterval = 10 * time.Minute
+	// maxEventBatchesPerPoll bounds the number of batches of messages received from the queue in a single
+	// poll, so a flood of events doesn't hold the main loop.
+	maxEventBatchesPerPoll = 10
+	maxMessagesPerBatch    = 10
+
+	eventSourceAutoScaling = "aws.autoscaling"
+	eventSourceEC2         = "aws.ec2"
+	// ec2InstanceStateChangeEvent is the detail type of EC2 instance state changes, ASG events all
+	// carry the name of their ASG and are handled the same regardless of their type.
+	ec2InstanceStateChangeEvent = "EC2 Instance State-change Notification"
+)
+
+// sqsI is the interface abstracting specific API calls of the SQS service provided by AWS SDK for use in CA
+type sqsI interface {
+	ReceiveMessage(input *sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error)
+	DeleteMessageBatch(input *sqs.DeleteMessageBatchInput) (*sqs.DeleteMessageBatchOutput, error)
+}
+
+// asgEvent is the subset of the EventBridge events of Auto Scaling and EC2 used to find the affected ASG or instance.
+type asgEvent struct {
+	Source     string `json:"source"`
+	DetailType string `json:"detail-type"`
+	Detail     struct {
+		AutoScalingGroupName string `json:"AutoScalingGroupName"`
+		InstanceId           string `json:"instance-id"`
+		State                string `json:"state"`
+	} `json:"detail"`
+}
+
+// asgEventsQueue consumes the ASG lifecycle and EC2 instance state change events EventBridge forwards
+// to an SQS queue, to tell when the cached view of the ASGs is out of date.
+type asgEventsQueue struct {
+	sqsI
+	queueURL string
+}
+
+func newASGEventsQueue(sqsService sqsI, queueURL string) *asgEventsQueue {
+	return &asgEventsQueue{
+		sqsI:     sqsService,
+		queueURL: queueURL,
+	}
+}
+
+// eventChanges are the changes to the cached ASGs told by events.
+type eventChanges struct {
+	// asgs are the cached ASGs affected by events, directly or through one of their instances.
+	asgs map[AwsRef]bool
+	// unknown is set if some events couldn't be parsed, so that all the cached ASGs may be affected.
+	unknown bool
+}
+
+// poll receives the pending events from the queue, deleting them once handled, and returns the cached ASGs
+// they affect.
+func (q *asgEventsQueue) poll(cache *asgCache) (eventChanges, error) {
+	changed := eventChanges{asgs: make(map[AwsRef]bool)}
+	for i := 0; i < maxEventBatchesPerPoll; i++ {
+		start := time.Now()
+		out, err := q.ReceiveMessage(&sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(q.queueURL),
+			MaxNumberOfMessages: aws.Int64(maxMessagesPerBatch),
+		})
+		observeAWSRequest("ReceiveMessage", err, start)
+		if err != nil {
+			return changed, err
+		}
+		if len(out.Messages) == 0 {
+			return changed, nil
+		}
+
+		entries := make([]*sqs.DeleteMessageBatchRequestEntry, 0, len(out.Messages))
+		for j, message := range out.Messages {
+			ref, err := affectedAsg(cache, aws.StringValue(message.Body))
+			if err != nil {
+				klog.Warningf("Failed to parse ASG event %q: %v", aws.StringValue(message.Body), err)
+				changed.unknown = true
+			} else if ref != nil {
+				changed.asgs[*ref] = true
+			}
+			entries = append(entries, &sqs.DeleteMessageBatchRequestEntry{
+				Id:            aws.String(strconv.Itoa(j)),
+				ReceiptHandle: message.ReceiptHandle,
+			})
+		}
+
+		start = time.Now()
+		deleted, err := q.DeleteMessageBatch(&sqs.DeleteMessageBatchInput{
+			QueueUrl: aws.String(q.queueURL),
+			Entries:  entries,
+		})
+		observeAWSRequest("DeleteMessageBatch", err, start)
+		if err != nil {
+			return changed, fmt.Errorf("failed to delete events from queue %s: %v", q.queueURL, err)
+		}
+		// events failing to be deleted are received again, which is harmless.
+		if len(deleted.Failed) > 0 {
+			klog.Warningf("Failed to delete %d events from queue %s", len(deleted.Failed), q.queueURL)
+		}
+	}
+	return changed, nil
+}
+
+// affectedAsg returns the cached ASG the event is about, directly or through one of its instances, or nil
+// if it is about none of them.
+func affectedAsg(cache *asgCache, body string) (*AwsRef, error) {
+	event := asgEvent{}
+	if err := json.Unmarshal([]byte(body), &event); err != nil {
+		return nil, err
+	}
+
+	switch event.Source {
+	case eventSourceAutoScaling:
+		// The queue gets the events of the autoscaler's own account, ASGs managed with an assumed role are
+		// only refreshed by the events of their instances and full regenerations.
+		ref := AwsRef{Name: event.Detail.AutoScalingGroupName}
+		if _, found := cache.Get()[ref]; found {
+			klog.V(4).Infof("Received %q event for ASG %s", event.DetailType, event.Detail.AutoScalingGroupName)
+			return &ref, nil
+		}
+	case eventSourceEC2:
+		if event.DetailType != ec2InstanceStateChangeEvent {
+			return nil, nil
+		}
+		if asg := cache.findForInstanceID(event.Detail.InstanceId); asg != nil {
+			klog.V(4).Infof("Received %q event for instance %s of ASG %s, now %s", event.DetailType, event.Detail.InstanceId, asg.Name, event.Detail.State)
+			return &asg.AwsRef, nil
+		}
+	}
+	return nil, nil
+}
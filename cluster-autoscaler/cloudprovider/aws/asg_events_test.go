@@ -0,0 +1,149 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/aws"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/service/sqs"
+)
+
+type sqsMock struct {
+	mock.Mock
+}
+
+func (s *sqsMock) ReceiveMessage(input *sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error) {
+	args := s.Called(input)
+	return args.Get(0).(*sqs.ReceiveMessageOutput), args.Error(1)
+}
+
+func (s *sqsMock) DeleteMessageBatch(input *sqs.DeleteMessageBatchInput) (*sqs.DeleteMessageBatchOutput, error) {
+	args := s.Called(input)
+	return args.Get(0).(*sqs.DeleteMessageBatchOutput), args.Error(1)
+}
+
+func newTestEventsCache() *asgCache {
+	testAsg := &asg{AwsRef: AwsRef{Name: "test-asg"}}
+	return &asgCache{
+		registeredAsgs: map[AwsRef]*asg{testAsg.AwsRef: testAsg},
+		instanceToAsg: map[AwsInstanceRef]*asg{
+			{ProviderID: "aws:///us-east-1a/i-0123456789", Name: "i-0123456789"}: testAsg,
+		},
+		instanceIDToAsg: map[string]*asg{"i-0123456789": testAsg},
+	}
+}
+
+func TestAffectedAsg(t *testing.T) {
+	cache := newTestEventsCache()
+	testAsg := &AwsRef{Name: "test-asg"}
+
+	for _, tc := range []struct {
+		desc     string
+		body     string
+		expected *AwsRef
+		wantErr  bool
+	}{
+		{
+			desc:     "launch in a cached ASG",
+			body:     `{"source":"aws.autoscaling","detail-type":"EC2 Instance Launch Successful","detail":{"AutoScalingGroupName":"test-asg","EC2InstanceId":"i-9876543210"}}`,
+			expected: testAsg,
+		},
+		{
+			desc:     "launch in another ASG",
+			body:     `{"source":"aws.autoscaling","detail-type":"EC2 Instance Launch Successful","detail":{"AutoScalingGroupName":"other-asg","EC2InstanceId":"i-9876543210"}}`,
+			expected: nil,
+		},
+		{
+			desc:     "state change of a cached instance",
+			body:     `{"source":"aws.ec2","detail-type":"EC2 Instance State-change Notification","detail":{"instance-id":"i-0123456789","state":"stopping"}}`,
+			expected: testAsg,
+		},
+		{
+			desc:     "state change of another instance",
+			body:     `{"source":"aws.ec2","detail-type":"EC2 Instance State-change Notification","detail":{"instance-id":"i-9876543210","state":"stopping"}}`,
+			expected: nil,
+		},
+		{
+			desc:     "unrelated EC2 event",
+			body:     `{"source":"aws.ec2","detail-type":"EC2 Spot Instance Interruption Warning","detail":{"instance-id":"i-0123456789"}}`,
+			expected: nil,
+		},
+		{
+			desc:    "invalid event",
+			body:    `not json`,
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			ref, err := affectedAsg(cache, tc.body)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, ref)
+		})
+	}
+}
+
+func TestPollASGEvents(t *testing.T) {
+	cache := newTestEventsCache()
+	s := &sqsMock{}
+	queue := newASGEventsQueue(s, "https://sqs.us-east-1.amazonaws.com/123456789012/asg-events")
+
+	s.On("ReceiveMessage", mock.AnythingOfType("*sqs.ReceiveMessageInput")).Return(&sqs.ReceiveMessageOutput{
+		Messages: []*sqs.Message{
+			{
+				Body:          aws.String(`{"source":"aws.autoscaling","detail-type":"EC2 Instance Terminate Successful","detail":{"AutoScalingGroupName":"other-asg"}}`),
+				ReceiptHandle: aws.String("handle-1"),
+			},
+			{
+				Body:          aws.String(`{"source":"aws.autoscaling","detail-type":"EC2 Instance Terminate Successful","detail":{"AutoScalingGroupName":"test-asg"}}`),
+				ReceiptHandle: aws.String("handle-2"),
+			},
+		},
+	}, nil).Once()
+	s.On("ReceiveMessage", mock.AnythingOfType("*sqs.ReceiveMessageInput")).Return(&sqs.ReceiveMessageOutput{}, nil).Once()
+	s.On("DeleteMessageBatch", &sqs.DeleteMessageBatchInput{
+		QueueUrl: aws.String(queue.queueURL),
+		Entries: []*sqs.DeleteMessageBatchRequestEntry{
+			{Id: aws.String("0"), ReceiptHandle: aws.String("handle-1")},
+			{Id: aws.String("1"), ReceiptHandle: aws.String("handle-2")},
+		},
+	}).Return(&sqs.DeleteMessageBatchOutput{}, nil).Once()
+
+	changes, err := queue.poll(cache)
+	assert.NoError(t, err)
+	assert.Equal(t, map[AwsRef]bool{{Name: "test-asg"}: true}, changes.asgs)
+	assert.False(t, changes.unknown)
+	s.AssertExpectations(t)
+
+	// nothing changed when the queue is empty.
+	s.On("ReceiveMessage", mock.AnythingOfType("*sqs.ReceiveMessageInput")).Return(&sqs.ReceiveMessageOutput{}, nil).Once()
+	changes, err = queue.poll(cache)
+	assert.NoError(t, err)
+	assert.Empty(t, changes.asgs)
+	assert.False(t, changes.unknown)
+
+	s.On("ReceiveMessage", mock.AnythingOfType("*sqs.ReceiveMessageInput")).Return(&sqs.ReceiveMessageOutput{}, errors.New("access denied")).Once()
+	_, err = queue.poll(cache)
+	assert.Error(t, err)
+}
@@ -24,7 +24,10 @@ import (
 	"time"
 
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/aws"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/aws/awserr"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/service/autoscaling"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/service/ec2"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/service/eks"
 	"k8s.io/autoscaler/cluster-autoscaler/config/dynamic"
 	klog "k8s.io/klog/v2"
 )
@@ -33,22 +36,44 @@ const (
 	scaleToZeroSupported           = true
 	placeholderInstanceNamePrefix  = "i-placeholder"
 	placeholderUnfulfillableStatus = "placeholder-cannot-be-fulfilled"
+
+	// scaleDownModeTag is the ASG tag selecting how scale-downs remove instances from the ASG.
+	scaleDownModeTag = "k8s.io/cluster-autoscaler/scale-down-mode"
+	// scaleDownModeDetachAndTerminate detaches instances from the ASG, decrementing its desired capacity, before
+	// terminating them through the EC2 API, so that ASG processes such as AZRebalance don't replace them.
+	scaleDownModeDetachAndTerminate = "detach-and-terminate"
+
+	// eksClusterNameTag and eksNodegroupNameTag are set by EKS on the ASGs of managed node groups.
+	eksClusterNameTag   = "eks:cluster-name"
+	eksNodegroupNameTag = "eks:nodegroup-name"
 )
 
 type asgCache struct {
 	registeredAsgs       map[AwsRef]*asg
 	asgToInstances       map[AwsRef][]AwsInstanceRef
 	instanceToAsg        map[AwsInstanceRef]*asg
+	instanceIDToAsg      map[string]*asg
 	instanceStatus       map[AwsInstanceRef]*string
 	instanceLifecycle    map[AwsInstanceRef]*string
 	asgInstanceTypeCache *instanceTypeExpirationStore
 	mutex                sync.Mutex
 	awsService           *awsWrapper
+	assumedRoles         *assumedRoleServices
 	interrupt            chan struct{}
 
 	asgAutoDiscoverySpecs []asgAutoDiscoveryConfig
 	explicitlyConfigured  map[AwsRef]bool
 	autoscalingOptions    map[AwsRef]map[string]string
+
+	// eksNodegroupAPI sets the desired size of the ASGs of EKS managed node groups through the EKS API.
+	eksNodegroupAPI bool
+
+	// detachedInstances are the instances detached from their ASG that failed to terminate, with the ASG they
+	// were detached from. Their termination is retried on every regeneration of the cache.
+	detachedInstances map[AwsInstanceRef]*asg
+	// unsyncedNodegroups are the ASGs of EKS managed node groups whose desired size couldn't be set through the
+	// EKS API while the node group was being updated. It is set to the one of the ASG on the next regenerations.
+	unsyncedNodegroups map[AwsRef]bool
 }
 
 type launchTemplate struct {
@@ -60,6 +85,8 @@ type mixedInstancesPolicy struct {
 	launchTemplate                *launchTemplate
 	instanceTypesOverrides        []string
 	instanceRequirementsOverrides *autoscaling.InstanceRequirements
+	// spot is set when all the instances of the ASG are spot instances.
+	spot bool
 }
 
 type asg struct {
@@ -69,6 +96,8 @@ type asg struct {
 	maxSize        int
 	curSize        int
 	lastUpdateTime time.Time
+	// warmPoolSize is the number of instances in the warm pool of the ASG, from which it is scaled up first.
+	warmPoolSize int
 
 	AvailabilityZones       []string
 	LaunchConfigurationName string
@@ -83,6 +112,7 @@ func newASGCache(awsService *awsWrapper, explicitSpecs []string, autoDiscoverySp
 		awsService:            awsService,
 		asgToInstances:        make(map[AwsRef][]AwsInstanceRef),
 		instanceToAsg:         make(map[AwsInstanceRef]*asg),
+		instanceIDToAsg:       make(map[string]*asg),
 		instanceStatus:        make(map[AwsInstanceRef]*string),
 		instanceLifecycle:     make(map[AwsInstanceRef]*string),
 		asgInstanceTypeCache:  newAsgInstanceTypeCache(awsService),
@@ -90,6 +120,8 @@ func newASGCache(awsService *awsWrapper, explicitSpecs []string, autoDiscoverySp
 		asgAutoDiscoverySpecs: autoDiscoverySpecs,
 		explicitlyConfigured:  make(map[AwsRef]bool),
 		autoscalingOptions:    make(map[AwsRef]map[string]string),
+		detachedInstances:     make(map[AwsInstanceRef]*asg),
+		unsyncedNodegroups:    make(map[AwsRef]bool),
 	}
 
 	if err := registry.parseExplicitAsgs(explicitSpecs); err != nil {
@@ -101,16 +133,20 @@ func newASGCache(awsService *awsWrapper, explicitSpecs []string, autoDiscoverySp
 
 // Use a function variable for ease of testing
 var getInstanceTypeForAsg = func(m *asgCache, group *asg) (string, error) {
-	if obj, found, _ := m.asgInstanceTypeCache.GetByKey(group.AwsRef.Name); found {
+	if obj, found, _ := m.asgInstanceTypeCache.GetByKey(group.AwsRef.id()); found {
 		return obj.(instanceTypeCachedObject).instanceType, nil
 	}
 
-	result, err := m.awsService.getInstanceTypesForAsgs([]*asg{group})
+	awsService, err := m.serviceFor(group)
+	if err != nil {
+		return "", err
+	}
+	result, err := awsService.getInstanceTypesForAsgs([]*asg{group})
 	if err != nil {
 		return "", fmt.Errorf("could not get instance type for %s: %w", group.AwsRef.Name, err)
 	}
 
-	if instanceType, ok := result[group.AwsRef.Name]; ok {
+	if instanceType, ok := result[group.AwsRef.id()]; ok {
 		return instanceType, nil
 	}
 
@@ -149,6 +185,7 @@ func (m *asgCache) register(asg *asg) *asg {
 		}
 
 		existing.curSize = asg.curSize
+		existing.warmPoolSize = asg.warmPoolSize
 
 		// Those information are mainly required to create templates when scaling
 		// from zero
@@ -174,13 +211,28 @@ func (m *asgCache) unregister(a *asg) *asg {
 	return a
 }
 
+// setAssumedRoles enables managing ASGs in other accounts with the given services.
+func (m *asgCache) setAssumedRoles(assumedRoles *assumedRoleServices) {
+	m.assumedRoles = assumedRoles
+	m.asgInstanceTypeCache.assumedRoles = assumedRoles
+}
+
+// serviceFor returns the service to use for calls related to the given ASG.
+func (m *asgCache) serviceFor(asg *asg) (*awsWrapper, error) {
+	return m.assumedRoles.serviceFor(m.awsService, asg.RoleARN)
+}
+
 func (m *asgCache) buildAsgFromSpec(spec string) (*asg, error) {
 	s, err := dynamic.SpecFromString(spec, scaleToZeroSupported)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse node group spec: %v", err)
 	}
+	name, roleARN, err := splitRoleARN(s.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse node group spec: %v", err)
+	}
 	asg := &asg{
-		AwsRef:  AwsRef{Name: s.Name},
+		AwsRef:  AwsRef{Name: name, RoleARN: roleARN},
 		minSize: s.MinSize,
 		maxSize: s.MaxSize,
 	}
@@ -218,6 +270,14 @@ func (m *asgCache) findForInstance(instance AwsInstanceRef) *asg {
 	return nil
 }
 
+// findForInstanceID returns the cached ASG of the instance with the given ID, or nil if it belongs to none.
+func (m *asgCache) findForInstanceID(instanceID string) *asg {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.instanceIDToAsg[instanceID]
+}
+
 // InstancesByAsg returns the nodes of an ASG
 func (m *asgCache) InstancesByAsg(ref AwsRef) ([]AwsInstanceRef, error) {
 	m.mutex.Lock()
@@ -257,15 +317,24 @@ func (m *asgCache) SetAsgSize(asg *asg, size int) error {
 }
 
 func (m *asgCache) setAsgSizeNoLock(asg *asg, size int) error {
-	params := &autoscaling.SetDesiredCapacityInput{
-		AutoScalingGroupName: aws.String(asg.Name),
-		DesiredCapacity:      aws.Int64(int64(size)),
-		HonorCooldown:        aws.Bool(false),
+	awsService, err := m.serviceFor(asg)
+	if err != nil {
+		return err
 	}
-	klog.V(0).Infof("Setting asg %s size to %d", asg.Name, size)
 	start := time.Now()
-	_, err := m.awsService.SetDesiredCapacity(params)
-	observeAWSRequest("SetDesiredCapacity", err, start)
+	if clusterName, nodegroupName, managed := m.managedNodegroupFor(asg); managed {
+		klog.V(0).Infof("Setting EKS nodegroup %s (asg %s) size to %d", nodegroupName, asg.Name, size)
+		err = setNodegroupDesiredSize(awsService, clusterName, nodegroupName, size)
+		if isResourceInUse(err) {
+			// Failing would back the node group off for the whole update, scale its ASG directly instead.
+			klog.Warningf("EKS nodegroup %s is being updated, setting asg %s size directly: %v", nodegroupName, asg.Name, err)
+			m.unsyncedNodegroups[asg.AwsRef] = true
+			err = setAsgDesiredCapacity(awsService, asg, size)
+		}
+	} else {
+		klog.V(0).Infof("Setting asg %s size to %d", asg.Name, size)
+		err = setAsgDesiredCapacity(awsService, asg, size)
+	}
 	if err != nil {
 		return err
 	}
@@ -277,6 +346,18 @@ func (m *asgCache) setAsgSizeNoLock(asg *asg, size int) error {
 	return nil
 }
 
+func setAsgDesiredCapacity(awsService *awsWrapper, asg *asg, size int) error {
+	params := &autoscaling.SetDesiredCapacityInput{
+		AutoScalingGroupName: aws.String(asg.Name),
+		DesiredCapacity:      aws.Int64(int64(size)),
+		HonorCooldown:        aws.Bool(false),
+	}
+	start := time.Now()
+	_, err := awsService.SetDesiredCapacity(params)
+	observeAWSRequest("SetDesiredCapacity", err, start)
+	return err
+}
+
 func (m *asgCache) decreaseAsgSizeByOneNoLock(asg *asg) error {
 	return m.setAsgSizeNoLock(asg, asg.curSize-1)
 }
@@ -306,6 +387,13 @@ func (m *asgCache) DeleteInstances(instances []*AwsInstanceRef) error {
 		}
 	}
 
+	awsService, err := m.serviceFor(commonAsg)
+	if err != nil {
+		return err
+	}
+
+	// decremented is set once the desired capacity of the ASG was decremented by terminating instances.
+	decremented := false
 	for _, instance := range instances {
 		// check if the instance is a placeholder - a requested instance that was never created by the node group
 		// if it is, just decrease the size of the node group, as there's no specific instance we can remove
@@ -326,17 +414,27 @@ func (m *asgCache) DeleteInstances(instances []*AwsInstanceRef) error {
 				*lifecycle == autoscaling.LifecycleStateTerminated ||
 				*lifecycle == autoscaling.LifecycleStateTerminating ||
 				*lifecycle == autoscaling.LifecycleStateTerminatingWait ||
-				*lifecycle == autoscaling.LifecycleStateTerminatingProceed {
+				*lifecycle == autoscaling.LifecycleStateTerminatingProceed ||
+				*lifecycle == autoscaling.LifecycleStateDetaching ||
+				*lifecycle == autoscaling.LifecycleStateDetached {
 				klog.V(2).Infof("instance %s is already terminating in state %s, will skip instead", instance.Name, *lifecycle)
 				continue
 			}
 
+			if detachBeforeTerminate(commonAsg) {
+				if err := m.detachAndTerminateInstanceNoLock(awsService, commonAsg, instance); err != nil {
+					return err
+				}
+				decremented = true
+				continue
+			}
+
 			params := &autoscaling.TerminateInstanceInAutoScalingGroupInput{
 				InstanceId:                     aws.String(instance.Name),
 				ShouldDecrementDesiredCapacity: aws.Bool(true),
 			}
 			start := time.Now()
-			resp, err := m.awsService.TerminateInstanceInAutoScalingGroup(params)
+			resp, err := awsService.TerminateInstanceInAutoScalingGroup(params)
 			observeAWSRequest("TerminateInstanceInAutoScalingGroup", err, start)
 			if err != nil {
 				return err
@@ -345,8 +443,161 @@ func (m *asgCache) DeleteInstances(instances []*AwsInstanceRef) error {
 
 			// Proactively decrement the size so autoscaler makes better decisions
 			commonAsg.curSize--
+			decremented = true
+		}
+	}
+
+	// Terminating instances decremented the desired capacity of the ASG behind the back of EKS.
+	if clusterName, nodegroupName, managed := m.managedNodegroupFor(commonAsg); managed && decremented {
+		err := setNodegroupDesiredSize(awsService, clusterName, nodegroupName, commonAsg.curSize)
+		if isResourceInUse(err) {
+			klog.Warningf("EKS nodegroup %s is being updated, will set its desired size once done: %v", nodegroupName, err)
+			m.unsyncedNodegroups[commonAsg.AwsRef] = true
+		} else if err != nil {
+			return fmt.Errorf("failed to set desired size of EKS nodegroup %s to %d: %v", nodegroupName, commonAsg.curSize, err)
+		}
+	}
+	return nil
+}
+
+// syncNodegroupSizesNoLock sets the desired size of the EKS managed node groups whose ASG was scaled directly
+// while they were being updated to the one of their ASG.
+func (m *asgCache) syncNodegroupSizesNoLock() {
+	for ref := range m.unsyncedNodegroups {
+		asg, found := m.registeredAsgs[ref]
+		clusterName, nodegroupName, managed := "", "", false
+		if found {
+			clusterName, nodegroupName, managed = m.managedNodegroupFor(asg)
+		}
+		if !managed {
+			delete(m.unsyncedNodegroups, ref)
+			continue
+		}
+		awsService, err := m.serviceFor(asg)
+		if err == nil {
+			err = setNodegroupDesiredSize(awsService, clusterName, nodegroupName, asg.curSize)
+		}
+		if isResourceInUse(err) {
+			klog.V(4).Infof("EKS nodegroup %s is still being updated, will set its desired size later", nodegroupName)
+			continue
+		}
+		if err != nil {
+			klog.Warningf("Failed to set desired size of EKS nodegroup %s to %d, will retry: %v", nodegroupName, asg.curSize, err)
+			continue
+		}
+		klog.V(2).Infof("Set desired size of EKS nodegroup %s to the one of asg %s: %d", nodegroupName, asg.Name, asg.curSize)
+		delete(m.unsyncedNodegroups, ref)
+	}
+}
+
+// isResourceInUse returns true if the error is returned by the EKS API for a node group being updated.
+func isResourceInUse(err error) bool {
+	awsErr, ok := err.(awserr.Error)
+	return ok && awsErr.Code() == eks.ErrCodeResourceInUseException
+}
+
+// managedNodegroupFor returns the cluster and name of the EKS managed node group of the ASG, if it is one
+// and its size is to be set through the EKS API.
+func (m *asgCache) managedNodegroupFor(asg *asg) (clusterName, nodegroupName string, managed bool) {
+	if !m.eksNodegroupAPI {
+		return "", "", false
+	}
+	for _, tag := range asg.Tags {
+		switch aws.StringValue(tag.Key) {
+		case eksClusterNameTag:
+			clusterName = aws.StringValue(tag.Value)
+		case eksNodegroupNameTag:
+			nodegroupName = aws.StringValue(tag.Value)
+		}
+	}
+	return clusterName, nodegroupName, clusterName != "" && nodegroupName != ""
+}
+
+// setNodegroupDesiredSize sets the desired size of an EKS managed node group, which EKS applies to its ASG. This
+// keeps the node group configuration in sync with its ASG, so that node group updates don't revert scale-ups.
+// It fails with a ResourceInUseException while an update of the node group is in progress.
+func setNodegroupDesiredSize(awsService *awsWrapper, clusterName, nodegroupName string, size int) error {
+	params := &eks.UpdateNodegroupConfigInput{
+		ClusterName:   aws.String(clusterName),
+		NodegroupName: aws.String(nodegroupName),
+		ScalingConfig: &eks.NodegroupScalingConfig{
+			DesiredSize: aws.Int64(int64(size)),
+		},
+	}
+	start := time.Now()
+	_, err := awsService.UpdateNodegroupConfig(params)
+	observeAWSRequest("UpdateNodegroupConfig", err, start)
+	return err
+}
+
+// detachBeforeTerminate returns true if the instances of the ASG are detached from it before being terminated.
+func detachBeforeTerminate(asg *asg) bool {
+	for _, tag := range asg.Tags {
+		if tag.Key != nil && *tag.Key == scaleDownModeTag {
+			return tag.Value != nil && strings.EqualFold(*tag.Value, scaleDownModeDetachAndTerminate)
+		}
+	}
+	return false
+}
+
+// detachAndTerminateInstanceNoLock detaches the instance from the ASG, decrementing its desired capacity, and
+// terminates it through the EC2 API. Unlike TerminateInstanceInAutoScalingGroup, this leaves the ASG no instance
+// to replace, e.g. to rebalance its availability zones, while the instance shuts down.
+func (m *asgCache) detachAndTerminateInstanceNoLock(awsService *awsWrapper, asg *asg, instance *AwsInstanceRef) error {
+	detachParams := &autoscaling.DetachInstancesInput{
+		AutoScalingGroupName:           aws.String(asg.Name),
+		InstanceIds:                    aws.StringSlice([]string{instance.Name}),
+		ShouldDecrementDesiredCapacity: aws.Bool(true),
+	}
+	start := time.Now()
+	resp, err := awsService.DetachInstances(detachParams)
+	observeAWSRequest("DetachInstances", err, start)
+	if err != nil {
+		return err
+	}
+	for _, activity := range resp.Activities {
+		if activity.Description != nil {
+			klog.V(4).Infof(*activity.Description)
 		}
 	}
+
+	// Proactively decrement the size so autoscaler makes better decisions
+	asg.curSize--
+
+	if err := terminateDetachedInstance(awsService, asg, *instance); err != nil {
+		// The instance is no longer part of the ASG, so the deletion can't be retried through it.
+		klog.Warningf("%v, will retry", err)
+		m.detachedInstances[*instance] = asg
+	}
+	return nil
+}
+
+// retryDetachedInstancesNoLock retries terminating the instances detached from their ASG that failed to terminate.
+func (m *asgCache) retryDetachedInstancesNoLock() {
+	for instance, asg := range m.detachedInstances {
+		awsService, err := m.serviceFor(asg)
+		if err == nil {
+			err = terminateDetachedInstance(awsService, asg, instance)
+		}
+		if err != nil {
+			klog.Warningf("%v, will retry", err)
+			continue
+		}
+		delete(m.detachedInstances, instance)
+	}
+}
+
+func terminateDetachedInstance(awsService *awsWrapper, asg *asg, instance AwsInstanceRef) error {
+	terminateParams := &ec2.TerminateInstancesInput{
+		InstanceIds: aws.StringSlice([]string{instance.Name}),
+	}
+	start := time.Now()
+	_, err := awsService.TerminateInstances(terminateParams)
+	observeAWSRequest("TerminateInstances", err, start)
+	if err != nil {
+		return fmt.Errorf("instance %s was detached from asg %s but failed to terminate: %v", instance.Name, asg.Name, err)
+	}
+	klog.V(4).Infof("Terminated instance %s detached from asg %s", instance.Name, asg.Name)
 	return nil
 }
 
@@ -368,15 +619,13 @@ func (m *asgCache) buildAsgTags() map[string]string {
 	return groupTags
 }
 
-func (m *asgCache) buildAsgNames() []string {
-	refreshNames := make([]string, len(m.explicitlyConfigured))
-	i := 0
-	for k := range m.explicitlyConfigured {
-		refreshNames[i] = k.Name
-		i++
+// buildAsgNamesByRole groups names of explicitly configured ASGs by the role assumed to manage them.
+func (m *asgCache) buildAsgNamesByRole() map[string][]string {
+	namesByRole := make(map[string][]string)
+	for ref := range m.explicitlyConfigured {
+		namesByRole[ref.RoleARN] = append(namesByRole[ref.RoleARN], ref.Name)
 	}
-
-	return refreshNames
+	return namesByRole
 }
 
 // regenerate the cached view of explicitly configured and auto-discovered ASGs
@@ -385,18 +634,32 @@ func (m *asgCache) regenerate() error {
 	defer m.mutex.Unlock()
 
 	newInstanceToAsgCache := make(map[AwsInstanceRef]*asg)
+	newInstanceIDToAsgCache := make(map[string]*asg)
 	newAsgToInstancesCache := make(map[AwsRef][]AwsInstanceRef)
 	newInstanceStatusMap := make(map[AwsInstanceRef]*string)
 	newInstanceLifecycleMap := make(map[AwsInstanceRef]*string)
 
-	// Fetch details of all ASGs
-	refreshNames := m.buildAsgNames()
-	klog.V(4).Infof("Regenerating instance to ASG map for ASG names: %v", refreshNames)
-	namedGroups, err := m.awsService.getAutoscalingGroupsByNames(refreshNames)
-	if err != nil {
-		return err
+	// Fetch details of all ASGs, remembering the role each one was fetched with
+	var namedGroups []*autoscaling.Group
+	groupRoles := make(map[*autoscaling.Group]string)
+	for roleARN, refreshNames := range m.buildAsgNamesByRole() {
+		klog.V(4).Infof("Regenerating instance to ASG map for ASG names: %v", refreshNames)
+		awsService, err := m.assumedRoles.serviceFor(m.awsService, roleARN)
+		if err != nil {
+			return err
+		}
+		groups, err := awsService.getAutoscalingGroupsByNames(refreshNames)
+		if err != nil {
+			return err
+		}
+		for _, group := range groups {
+			groupRoles[group] = roleARN
+		}
+		namedGroups = append(namedGroups, groups...)
 	}
 
+	// Auto-discovered ASGs are only looked up in the autoscaler's own account, they can't be managed with an
+	// assumed role.
 	refreshTags := m.buildAsgTags()
 	klog.V(4).Infof("Regenerating instance to ASG map for ASG tags: %v", refreshTags)
 	taggedGroups, err := m.awsService.getAutoscalingGroupsByTags(refreshTags)
@@ -409,12 +672,12 @@ func (m *asgCache) regenerate() error {
 	// If currently any ASG has more Desired than running Instances, introduce placeholders
 	// for the instances to come up. This is required to track Desired instances that
 	// will never come up, like with Spot Request that can't be fulfilled
-	groups = m.createPlaceholdersForDesiredNonStartedInstances(groups)
+	groups = m.createPlaceholdersForDesiredNonStartedInstances(groups, groupRoles)
 
 	// Register or update ASGs
 	exists := make(map[AwsRef]bool)
 	for _, group := range groups {
-		asg, err := m.buildAsgFromAWS(group)
+		asg, err := m.buildAsgFromAWS(group, groupRoles[group])
 		if err != nil {
 			return err
 		}
@@ -427,6 +690,7 @@ func (m *asgCache) regenerate() error {
 		for i, instance := range group.Instances {
 			ref := m.buildInstanceRefFromAWS(instance)
 			newInstanceToAsgCache[ref] = asg
+			newInstanceIDToAsgCache[ref.Name] = asg
 			newAsgToInstancesCache[asg.AwsRef][i] = ref
 			newInstanceStatusMap[ref] = instance.HealthStatus
 			newInstanceLifecycleMap[ref] = instance.LifecycleState
@@ -457,13 +721,82 @@ func (m *asgCache) regenerate() error {
 
 	m.asgToInstances = newAsgToInstancesCache
 	m.instanceToAsg = newInstanceToAsgCache
+	m.instanceIDToAsg = newInstanceIDToAsgCache
 	m.autoscalingOptions = newAutoscalingOptions
 	m.instanceStatus = newInstanceStatusMap
 	m.instanceLifecycle = newInstanceLifecycleMap
+
+	m.retryDetachedInstancesNoLock()
+	m.syncNodegroupSizesNoLock()
+	return nil
+}
+
+// regenerateAsgs refreshes only the given cached ASGs and their instances, e.g. the ones affected by events.
+// ASGs that no longer exist are left to the next full regeneration.
+func (m *asgCache) regenerateAsgs(refs map[AwsRef]bool) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	namesByRole := make(map[string][]string)
+	for ref := range refs {
+		if _, found := m.registeredAsgs[ref]; found {
+			namesByRole[ref.RoleARN] = append(namesByRole[ref.RoleARN], ref.Name)
+		}
+	}
+	var groups []*autoscaling.Group
+	groupRoles := make(map[*autoscaling.Group]string)
+	for roleARN, names := range namesByRole {
+		klog.V(4).Infof("Regenerating instance to ASG map for ASG names: %v", names)
+		awsService, err := m.assumedRoles.serviceFor(m.awsService, roleARN)
+		if err != nil {
+			return err
+		}
+		roleGroups, err := awsService.getAutoscalingGroupsByNames(names)
+		if err != nil {
+			return err
+		}
+		for _, group := range roleGroups {
+			groupRoles[group] = roleARN
+		}
+		groups = append(groups, roleGroups...)
+	}
+	groups = m.createPlaceholdersForDesiredNonStartedInstances(groups, groupRoles)
+
+	for _, group := range groups {
+		asg, err := m.buildAsgFromAWS(group, groupRoles[group])
+		if err != nil {
+			return err
+		}
+		asg = m.register(asg)
+
+		for _, ref := range m.asgToInstances[asg.AwsRef] {
+			delete(m.instanceToAsg, ref)
+			delete(m.instanceIDToAsg, ref.Name)
+			delete(m.instanceStatus, ref)
+			delete(m.instanceLifecycle, ref)
+		}
+		instances := make([]AwsInstanceRef, len(group.Instances))
+		for i, instance := range group.Instances {
+			ref := m.buildInstanceRefFromAWS(instance)
+			m.instanceToAsg[ref] = asg
+			m.instanceIDToAsg[ref.Name] = asg
+			m.instanceStatus[ref] = instance.HealthStatus
+			m.instanceLifecycle[ref] = instance.LifecycleState
+			instances[i] = ref
+		}
+		m.asgToInstances[asg.AwsRef] = instances
+		m.autoscalingOptions[asg.AwsRef] = extractAutoscalingOptionsFromTags(asg.Tags)
+	}
+
+	m.retryDetachedInstancesNoLock()
+	m.syncNodegroupSizesNoLock()
 	return nil
 }
 
-func (m *asgCache) createPlaceholdersForDesiredNonStartedInstances(groups []*autoscaling.Group) []*autoscaling.Group {
+// createPlaceholdersForDesiredNonStartedInstances adds placeholder instances to the groups with fewer instances
+// than desired. groupRoles holds the role assumed to manage each group, groups missing from it are in the
+// autoscaler's own account.
+func (m *asgCache) createPlaceholdersForDesiredNonStartedInstances(groups []*autoscaling.Group, groupRoles map[*autoscaling.Group]string) []*autoscaling.Group {
 	for _, g := range groups {
 		desired := *g.DesiredCapacity
 		realInstances := int64(len(g.Instances))
@@ -475,7 +808,7 @@ func (m *asgCache) createPlaceholdersForDesiredNonStartedInstances(groups []*aut
 			"Creating placeholder instances.", *g.AutoScalingGroupName, realInstances, desired)
 
 		healthStatus := ""
-		isAvailable, err := m.isNodeGroupAvailable(g)
+		isAvailable, err := m.isNodeGroupAvailable(g, groupRoles[g])
 		if err != nil {
 			klog.V(4).Infof("Could not check instance availability, creating placeholder node anyways: %v", err)
 		} else if !isAvailable {
@@ -495,20 +828,24 @@ func (m *asgCache) createPlaceholdersForDesiredNonStartedInstances(groups []*aut
 	return groups
 }
 
-func (m *asgCache) isNodeGroupAvailable(group *autoscaling.Group) (bool, error) {
+func (m *asgCache) isNodeGroupAvailable(group *autoscaling.Group, roleARN string) (bool, error) {
 	input := &autoscaling.DescribeScalingActivitiesInput{
 		AutoScalingGroupName: group.AutoScalingGroupName,
 	}
 
+	awsService, err := m.assumedRoles.serviceFor(m.awsService, roleARN)
+	if err != nil {
+		return true, err
+	}
 	start := time.Now()
-	response, err := m.awsService.DescribeScalingActivities(input)
+	response, err := awsService.DescribeScalingActivities(input)
 	observeAWSRequest("DescribeScalingActivities", err, start)
 	if err != nil {
 		return true, err // If we can't describe the scaling activities we assume the node group is available
 	}
 
 	for _, activity := range response.Activities {
-		asgRef := AwsRef{Name: *group.AutoScalingGroupName}
+		asgRef := AwsRef{Name: *group.AutoScalingGroupName, RoleARN: roleARN}
 		if a, ok := m.registeredAsgs[asgRef]; ok {
 			lut := a.lastUpdateTime
 			if activity.StartTime.Before(lut) {
@@ -524,7 +861,8 @@ func (m *asgCache) isNodeGroupAvailable(group *autoscaling.Group) (bool, error)
 	return true, nil
 }
 
-func (m *asgCache) buildAsgFromAWS(g *autoscaling.Group) (*asg, error) {
+// buildAsgFromAWS builds the ASG described by g, managed with the given role.
+func (m *asgCache) buildAsgFromAWS(g *autoscaling.Group, roleARN string) (*asg, error) {
 	spec := dynamic.NodeGroupSpec{
 		Name:               aws.StringValue(g.AutoScalingGroupName),
 		MinSize:            int(aws.Int64Value(g.MinSize)),
@@ -537,7 +875,7 @@ func (m *asgCache) buildAsgFromAWS(g *autoscaling.Group) (*asg, error) {
 	}
 
 	asg := &asg{
-		AwsRef:  AwsRef{Name: spec.Name},
+		AwsRef:  AwsRef{Name: spec.Name, RoleARN: roleARN},
 		minSize: spec.MinSize,
 		maxSize: spec.MaxSize,
 
@@ -547,6 +885,11 @@ func (m *asgCache) buildAsgFromAWS(g *autoscaling.Group) (*asg, error) {
 		Tags:                    g.Tags,
 	}
 
+	// Instances of a warm pool being deleted aren't used for scale-ups anymore.
+	if g.WarmPoolConfiguration != nil && aws.StringValue(g.WarmPoolConfiguration.Status) != autoscaling.WarmPoolStatusPendingDelete {
+		asg.warmPoolSize = int(aws.Int64Value(g.WarmPoolSize))
+	}
+
 	if g.LaunchTemplate != nil {
 		asg.LaunchTemplate = buildLaunchTemplateFromSpec(g.LaunchTemplate)
 	}
@@ -575,6 +918,12 @@ func (m *asgCache) buildAsgFromAWS(g *autoscaling.Group) (*asg, error) {
 			instanceRequirementsOverrides: getInstanceTypeRequirements(g.MixedInstancesPolicy.LaunchTemplate.Overrides),
 		}
 
+		// The on-demand percentage above base capacity defaults to 100 when unset.
+		if distribution := g.MixedInstancesPolicy.InstancesDistribution; distribution != nil {
+			asg.MixedInstancesPolicy.spot = aws.Int64Value(distribution.OnDemandBaseCapacity) == 0 &&
+				distribution.OnDemandPercentageAboveBaseCapacity != nil && *distribution.OnDemandPercentageAboveBaseCapacity == 0
+		}
+
 		if len(asg.MixedInstancesPolicy.instanceTypesOverrides) != 0 && asg.MixedInstancesPolicy.instanceRequirementsOverrides != nil {
 			return nil, fmt.Errorf("invalid setup of both instance type and instance requirements overrides configured")
 		}
@@ -22,8 +22,11 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/aws"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/aws/awserr"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/service/autoscaling"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/service/eks"
 )
 
 func TestBuildAsg(t *testing.T) {
@@ -34,6 +37,22 @@ func TestBuildAsg(t *testing.T) {
 	assert.Equal(t, asg.minSize, 1)
 	assert.Equal(t, asg.maxSize, 5)
 	assert.Equal(t, asg.Name, "test-asg")
+	assert.Equal(t, asg.RoleARN, "")
+
+	asg, err = asgCache.buildAsgFromSpec("1:5:test-asg@arn:aws:iam::111122223333:role/cluster-autoscaler")
+	assert.NoError(t, err)
+	assert.Equal(t, asg.Name, "test-asg")
+	assert.Equal(t, asg.RoleARN, "arn:aws:iam::111122223333:role/cluster-autoscaler")
+
+	// ASGs with the same name in different accounts are different node groups.
+	local, err := asgCache.buildAsgFromSpec("1:5:test-asg")
+	assert.NoError(t, err)
+	assert.NotEqual(t, local.AwsRef, asg.AwsRef)
+	assert.Equal(t, "test-asg", local.AwsRef.id())
+	assert.Equal(t, "test-asg@arn:aws:iam::111122223333:role/cluster-autoscaler", asg.AwsRef.id())
+
+	_, err = asgCache.buildAsgFromSpec("1:5:test-asg@not-an-arn")
+	assert.Error(t, err)
 
 	_, err = asgCache.buildAsgFromSpec("a")
 	assert.Error(t, err)
@@ -155,7 +174,7 @@ func TestCreatePlaceholders(t *testing.T) {
 					Instances:            []*autoscaling.Instance{},
 				},
 			}
-			asgCache.createPlaceholdersForDesiredNonStartedInstances(groups)
+			asgCache.createPlaceholdersForDesiredNonStartedInstances(groups, nil)
 			assert.Equal(t, int64(len(groups[0].Instances)), *tc.desiredCapacity)
 			if tc.activities != nil && *tc.activities[0].StatusCode == "Failed" && tc.activities[0].StartTime.After(tc.groupLastUpdateTime) && asgName == registeredAsgName {
 				assert.Equal(t, *groups[0].Instances[0].HealthStatus, placeholderUnfulfillableStatus)
@@ -166,3 +185,164 @@ func TestCreatePlaceholders(t *testing.T) {
 		})
 	}
 }
+
+func TestSetAsgSizeManagedNodegroup(t *testing.T) {
+	managedTags := []*autoscaling.TagDescription{
+		{Key: aws.String(eksClusterNameTag), Value: aws.String("test-cluster")},
+		{Key: aws.String(eksNodegroupNameTag), Value: aws.String("test-nodegroup")},
+	}
+
+	cases := []struct {
+		name            string
+		eksNodegroupAPI bool
+		tags            []*autoscaling.TagDescription
+		expectEKS       bool
+	}{
+		{
+			name:            "managed node group scaled through EKS",
+			eksNodegroupAPI: true,
+			tags:            managedTags,
+			expectEKS:       true,
+		},
+		{
+			name:            "EKS API mode disabled",
+			eksNodegroupAPI: false,
+			tags:            managedTags,
+		},
+		{
+			name:            "self-managed ASG",
+			eksNodegroupAPI: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := &autoScalingMock{}
+			k := &eksMock{}
+			if tc.expectEKS {
+				k.On("UpdateNodegroupConfig", &eks.UpdateNodegroupConfigInput{
+					ClusterName:   aws.String("test-cluster"),
+					NodegroupName: aws.String("test-nodegroup"),
+					ScalingConfig: &eks.NodegroupScalingConfig{DesiredSize: aws.Int64(3)},
+				}).Return(&eks.UpdateNodegroupConfigOutput{}, nil).Once()
+			} else {
+				a.On("SetDesiredCapacity", &autoscaling.SetDesiredCapacityInput{
+					AutoScalingGroupName: aws.String("test-asg"),
+					DesiredCapacity:      aws.Int64(3),
+					HonorCooldown:        aws.Bool(false),
+				}).Return(&autoscaling.SetDesiredCapacityOutput{}, nil).Once()
+			}
+
+			m := newTestAwsManagerWithMockServices(a, nil, k, nil, nil)
+			m.asgCache.eksNodegroupAPI = tc.eksNodegroupAPI
+			asg := &asg{AwsRef: AwsRef{Name: "test-asg"}, maxSize: 10, curSize: 1, Tags: tc.tags}
+			m.asgCache.registeredAsgs[asg.AwsRef] = asg
+
+			err := m.asgCache.SetAsgSize(asg, 3)
+			assert.NoError(t, err)
+			assert.Equal(t, 3, asg.curSize)
+			a.AssertExpectations(t)
+			k.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSetAsgSizeManagedNodegroupBeingUpdated(t *testing.T) {
+	a := &autoScalingMock{}
+	k := &eksMock{}
+	updateInput := &eks.UpdateNodegroupConfigInput{
+		ClusterName:   aws.String("test-cluster"),
+		NodegroupName: aws.String("test-nodegroup"),
+		ScalingConfig: &eks.NodegroupScalingConfig{DesiredSize: aws.Int64(3)},
+	}
+	k.On("UpdateNodegroupConfig", updateInput).Return(&eks.UpdateNodegroupConfigOutput{},
+		awserr.New(eks.ErrCodeResourceInUseException, "nodegroup is being updated", nil)).Twice()
+	a.On("SetDesiredCapacity", &autoscaling.SetDesiredCapacityInput{
+		AutoScalingGroupName: aws.String("test-asg"),
+		DesiredCapacity:      aws.Int64(3),
+		HonorCooldown:        aws.Bool(false),
+	}).Return(&autoscaling.SetDesiredCapacityOutput{}, nil).Once()
+
+	m := newTestAwsManagerWithMockServices(a, nil, k, nil, nil)
+	m.asgCache.eksNodegroupAPI = true
+	asg := &asg{AwsRef: AwsRef{Name: "test-asg"}, maxSize: 10, curSize: 1, Tags: []*autoscaling.TagDescription{
+		{Key: aws.String(eksClusterNameTag), Value: aws.String("test-cluster")},
+		{Key: aws.String(eksNodegroupNameTag), Value: aws.String("test-nodegroup")},
+	}}
+	m.asgCache.registeredAsgs[asg.AwsRef] = asg
+
+	// The ASG is scaled directly while the node group is being updated.
+	err := m.asgCache.SetAsgSize(asg, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, asg.curSize)
+	assert.True(t, m.asgCache.unsyncedNodegroups[asg.AwsRef])
+
+	// The desired size of the node group is set once the update is done.
+	m.asgCache.syncNodegroupSizesNoLock()
+	assert.True(t, m.asgCache.unsyncedNodegroups[asg.AwsRef])
+	k.On("UpdateNodegroupConfig", updateInput).Return(&eks.UpdateNodegroupConfigOutput{}, nil).Once()
+	m.asgCache.syncNodegroupSizesNoLock()
+	assert.Empty(t, m.asgCache.unsyncedNodegroups)
+	a.AssertExpectations(t)
+	k.AssertExpectations(t)
+}
+
+func TestRegenerateAsgs(t *testing.T) {
+	a := &autoScalingMock{}
+	a.On("DescribeAutoScalingGroupsPages",
+		&autoscaling.DescribeAutoScalingGroupsInput{
+			AutoScalingGroupNames: aws.StringSlice([]string{"test-asg"}),
+			MaxRecords:            aws.Int64(maxRecordsReturnedByAPI),
+		},
+		mock.AnythingOfType("func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool"),
+	).Run(func(args mock.Arguments) {
+		fn := args.Get(1).(func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool)
+		fn(&autoscaling.DescribeAutoScalingGroupsOutput{
+			AutoScalingGroups: []*autoscaling.Group{
+				{
+					AutoScalingGroupName: aws.String("test-asg"),
+					AvailabilityZones:    aws.StringSlice([]string{"us-east-1a"}),
+					MinSize:              aws.Int64(0),
+					MaxSize:              aws.Int64(10),
+					DesiredCapacity:      aws.Int64(1),
+					Instances: []*autoscaling.Instance{
+						{
+							InstanceId:       aws.String("i-new"),
+							AvailabilityZone: aws.String("us-east-1a"),
+							HealthStatus:     aws.String("Healthy"),
+							LifecycleState:   aws.String(autoscaling.LifecycleStateInService),
+						},
+					},
+				},
+			}}, false)
+	}).Return(nil).Once()
+
+	m := newTestAwsManagerWithMockServices(a, nil, nil, nil, nil)
+	cache := m.asgCache
+	cache.instanceStatus = make(map[AwsInstanceRef]*string)
+	cache.instanceLifecycle = make(map[AwsInstanceRef]*string)
+	testAsg := &asg{AwsRef: AwsRef{Name: "test-asg"}, maxSize: 10, curSize: 1}
+	otherAsg := &asg{AwsRef: AwsRef{Name: "other-asg"}, maxSize: 10, curSize: 1}
+	oldRef := AwsInstanceRef{ProviderID: "aws:///us-east-1a/i-old", Name: "i-old"}
+	otherRef := AwsInstanceRef{ProviderID: "aws:///us-east-1a/i-other", Name: "i-other"}
+	cache.registeredAsgs[testAsg.AwsRef] = testAsg
+	cache.registeredAsgs[otherAsg.AwsRef] = otherAsg
+	cache.asgToInstances[testAsg.AwsRef] = []AwsInstanceRef{oldRef}
+	cache.asgToInstances[otherAsg.AwsRef] = []AwsInstanceRef{otherRef}
+	cache.instanceToAsg[oldRef] = testAsg
+	cache.instanceToAsg[otherRef] = otherAsg
+	cache.instanceIDToAsg[oldRef.Name] = testAsg
+	cache.instanceIDToAsg[otherRef.Name] = otherAsg
+
+	// Only the instances of the ASG the event is about are replaced.
+	err := cache.regenerateAsgs(map[AwsRef]bool{testAsg.AwsRef: true})
+	assert.NoError(t, err)
+	newRef := AwsInstanceRef{ProviderID: "aws:///us-east-1a/i-new", Name: "i-new"}
+	assert.Equal(t, []AwsInstanceRef{newRef}, cache.asgToInstances[testAsg.AwsRef])
+	assert.Equal(t, []AwsInstanceRef{otherRef}, cache.asgToInstances[otherAsg.AwsRef])
+	assert.Nil(t, cache.findForInstanceID("i-old"))
+	assert.Equal(t, testAsg, cache.findForInstanceID("i-new"))
+	assert.Equal(t, otherAsg, cache.findForInstanceID("i-other"))
+	assert.Equal(t, "Healthy", aws.StringValue(cache.instanceStatus[newRef]))
+	a.AssertExpectations(t)
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/aws"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/aws/arn"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/aws/credentials/stscreds"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/aws/request"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/aws/session"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/service/autoscaling"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/service/ec2"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/service/eks"
+	"k8s.io/client-go/util/flowcontrol"
+	klog "k8s.io/klog/v2"
+)
+
+const (
+	// roleARNSeparator separates the ASG name from the ARN of the IAM role assumed to manage it
+	// in explicit node group specs, e.g. 1:10:my-asg@arn:aws:iam::111122223333:role/cluster-autoscaler.
+	roleARNSeparator = "@"
+	// assumedRoleAccountQPS is the rate of AWS API calls made with assumed roles, per account.
+	assumedRoleAccountQPS = 5
+	// assumedRoleAccountBurst is the burst of AWS API calls made with assumed roles, per account.
+	assumedRoleAccountBurst = 10
+)
+
+// splitRoleARN splits a node group name into the ASG name and the optional ARN of the role
+// to assume for managing the ASG.
+func splitRoleARN(name string) (string, string, error) {
+	asgName, roleARN, found := strings.Cut(name, roleARNSeparator)
+	if !found {
+		return name, "", nil
+	}
+	if _, err := accountFromRoleARN(roleARN); err != nil {
+		return "", "", err
+	}
+	return asgName, roleARN, nil
+}
+
+func accountFromRoleARN(roleARN string) (string, error) {
+	parsed, err := arn.Parse(roleARN)
+	if err != nil {
+		return "", fmt.Errorf("invalid role ARN %q: %v", roleARN, err)
+	}
+	if parsed.Service != "iam" || !strings.HasPrefix(parsed.Resource, "role/") || parsed.AccountID == "" {
+		return "", fmt.Errorf("invalid role ARN %q: not an IAM role", roleARN)
+	}
+	return parsed.AccountID, nil
+}
+
+// assumedRoleServices builds AWS services authenticated with assumed IAM roles, allowing
+// to manage ASGs in other accounts. Services are cached per role, so STS credentials are
+// reused until they expire, and API calls are rate limited per account.
+type assumedRoleServices struct {
+	mutex      sync.Mutex
+	services   map[string]*awsWrapper
+	limiters   map[string]flowcontrol.RateLimiter
+	newService func(roleARN string, limiter flowcontrol.RateLimiter) *awsWrapper
+}
+
+func newAssumedRoleServices(sess *session.Session) *assumedRoleServices {
+	return &assumedRoleServices{
+		services: make(map[string]*awsWrapper),
+		limiters: make(map[string]flowcontrol.RateLimiter),
+		newService: func(roleARN string, limiter flowcontrol.RateLimiter) *awsWrapper {
+			roleSess := sess.Copy(&aws.Config{Credentials: stscreds.NewCredentials(sess, roleARN)})
+			roleSess.Handlers.Send.PushFront(func(*request.Request) {
+				limiter.Accept()
+			})
+			return &awsWrapper{autoscaling.New(roleSess), ec2.New(roleSess), eks.New(roleSess)}
+		},
+	}
+}
+
+// serviceFor returns the service to use for ASGs managed with the given role, or
+// defaultService if no role should be assumed.
+func (s *assumedRoleServices) serviceFor(defaultService *awsWrapper, roleARN string) (*awsWrapper, error) {
+	if roleARN == "" {
+		return defaultService, nil
+	}
+	if s == nil {
+		return nil, fmt.Errorf("assuming role %s is not supported", roleARN)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if service, found := s.services[roleARN]; found {
+		return service, nil
+	}
+	account, err := accountFromRoleARN(roleARN)
+	if err != nil {
+		return nil, err
+	}
+	limiter, found := s.limiters[account]
+	if !found {
+		limiter = flowcontrol.NewTokenBucketRateLimiter(assumedRoleAccountQPS, assumedRoleAccountBurst)
+		s.limiters[account] = limiter
+	}
+	klog.V(2).Infof("Creating AWS services assuming role %s", roleARN)
+	service := s.newService(roleARN, limiter)
+	s.services[roleARN] = service
+	return service, nil
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+func TestSplitRoleARN(t *testing.T) {
+	name, roleARN, err := splitRoleARN("test-asg")
+	assert.NoError(t, err)
+	assert.Equal(t, "test-asg", name)
+	assert.Equal(t, "", roleARN)
+
+	name, roleARN, err = splitRoleARN("test-asg@arn:aws:iam::111122223333:role/cluster-autoscaler")
+	assert.NoError(t, err)
+	assert.Equal(t, "test-asg", name)
+	assert.Equal(t, "arn:aws:iam::111122223333:role/cluster-autoscaler", roleARN)
+
+	_, _, err = splitRoleARN("test-asg@arn:aws:s3:::bucket")
+	assert.Error(t, err)
+	_, _, err = splitRoleARN("test-asg@arn:aws:iam::111122223333:user/someone")
+	assert.Error(t, err)
+}
+
+func TestAssumedRoleServices(t *testing.T) {
+	defaultService := &awsWrapper{}
+	limiters := map[string]flowcontrol.RateLimiter{}
+	created := 0
+	roles := &assumedRoleServices{
+		services: make(map[string]*awsWrapper),
+		limiters: make(map[string]flowcontrol.RateLimiter),
+		newService: func(roleARN string, limiter flowcontrol.RateLimiter) *awsWrapper {
+			created++
+			limiters[roleARN] = limiter
+			return &awsWrapper{}
+		},
+	}
+
+	service, err := roles.serviceFor(defaultService, "")
+	assert.NoError(t, err)
+	assert.Same(t, defaultService, service)
+
+	roleA := "arn:aws:iam::111122223333:role/a"
+	roleB := "arn:aws:iam::111122223333:role/b"
+	roleC := "arn:aws:iam::444455556666:role/c"
+
+	serviceA, err := roles.serviceFor(defaultService, roleA)
+	assert.NoError(t, err)
+	assert.NotSame(t, defaultService, serviceA)
+	again, err := roles.serviceFor(defaultService, roleA)
+	assert.NoError(t, err)
+	assert.Same(t, serviceA, again)
+
+	_, err = roles.serviceFor(defaultService, roleB)
+	assert.NoError(t, err)
+	_, err = roles.serviceFor(defaultService, roleC)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, created)
+
+	// Roles in the same account share a rate limiter.
+	assert.Same(t, limiters[roleA], limiters[roleB])
+	assert.NotSame(t, limiters[roleA], limiters[roleC])
+
+	_, err = roles.serviceFor(defaultService, "arn:aws:iam::111122223333:user/someone")
+	assert.Error(t, err)
+}
+
+func TestAssumedRoleServicesNil(t *testing.T) {
+	var roles *assumedRoleServices
+	defaultService := &awsWrapper{}
+
+	service, err := roles.serviceFor(defaultService, "")
+	assert.NoError(t, err)
+	assert.Same(t, defaultService, service)
+
+	_, err = roles.serviceFor(defaultService, "arn:aws:iam::111122223333:role/a")
+	assert.Error(t, err)
+}
@@ -26,6 +26,7 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/service/sqs"
 	"k8s.io/autoscaler/cluster-autoscaler/config"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/gpu"
@@ -55,6 +56,7 @@ var (
 type awsCloudProvider struct {
 	awsManager      *AwsManager
 	resourceLimiter *cloudprovider.ResourceLimiter
+	pricingModel    *AwsPriceModel
 }
 
 // BuildAwsCloudProvider builds CloudProvider implementation for AWS.
@@ -155,7 +157,10 @@ func (aws *awsCloudProvider) HasInstance(node *apiv1.Node) (bool, error) {
 
 // Pricing returns pricing model for this cloud provider or error if not available.
 func (aws *awsCloudProvider) Pricing() (cloudprovider.PricingModel, errors.AutoscalerError) {
-	return nil, cloudprovider.ErrNotImplemented
+	if aws.pricingModel == nil {
+		return nil, cloudprovider.ErrNotImplemented
+	}
+	return aws.pricingModel, nil
 }
 
 // GetAvailableMachineTypes get all machine types that can be requested from the cloud provider.
@@ -184,6 +189,17 @@ func (aws *awsCloudProvider) Refresh() error {
 // AwsRef contains a reference to some entity in AWS world.
 type AwsRef struct {
 	Name string
+	// RoleARN is the IAM role assumed to manage the entity, empty for entities in the autoscaler's own account.
+	// It tells apart ASGs with the same name in different accounts.
+	RoleARN string
+}
+
+// id returns the name of the entity, suffixed with the role assumed to manage it like in node group specs.
+func (ref AwsRef) id() string {
+	if ref.RoleARN == "" {
+		return ref.Name
+	}
+	return ref.Name + roleARNSeparator + ref.RoleARN
 }
 
 // AwsInstanceRef contains a reference to an instance in the AWS world.
@@ -229,6 +245,11 @@ func (ng *AwsNodeGroup) TargetSize() (int, error) {
 	return ng.asg.curSize, nil
 }
 
+// RefreshTargetSize re-reads the desired capacity of the ASG, and its instances, from the AWS API.
+func (ng *AwsNodeGroup) RefreshTargetSize() error {
+	return ng.awsManager.asgCache.regenerateAsgs(map[AwsRef]bool{ng.asg.AwsRef: true})
+}
+
 // Exist checks if the node group really exists on the cloud provider side. Allows to tell the
 // theoretical node group from the real one.
 func (ng *AwsNodeGroup) Exist() bool {
@@ -260,6 +281,27 @@ func (ng *AwsNodeGroup) GetOptions(defaults config.NodeGroupAutoscalingOptions)
 	return ng.awsManager.GetAsgOptions(*ng.asg, defaults), nil
 }
 
+// WarmCapacity returns the number of instances in the warm pool of the ASG, which are used first
+// when it is scaled up.
+func (ng *AwsNodeGroup) WarmCapacity() (int, error) {
+	return ng.asg.warmPoolSize, nil
+}
+
+// PreferWarmCapacity returns true if the ASG has a warm pool and is tagged to be preferred when
+// it can absorb scale-ups.
+func (ng *AwsNodeGroup) PreferWarmCapacity() bool {
+	return ng.asg.warmPoolSize > 0 && ng.awsManager.PreferAsgWarmPool(*ng.asg)
+}
+
+// CapacityType returns SpotCapacity for ASGs with a mixed instances policy launching only spot instances,
+// OnDemandCapacity otherwise.
+func (ng *AwsNodeGroup) CapacityType() cloudprovider.CapacityType {
+	if ng.asg.MixedInstancesPolicy != nil && ng.asg.MixedInstancesPolicy.spot {
+		return cloudprovider.SpotCapacity
+	}
+	return cloudprovider.OnDemandCapacity
+}
+
 // IncreaseSize increases Asg size
 func (ng *AwsNodeGroup) IncreaseSize(delta int) error {
 	if delta <= 0 {
@@ -334,9 +376,14 @@ func (ng *AwsNodeGroup) DeleteNodes(nodes []*apiv1.Node) error {
 	return ng.awsManager.DeleteInstances(refs)
 }
 
+// ForceDeleteNodes deletes nodes from the group regardless of constraints.
+func (ng *AwsNodeGroup) ForceDeleteNodes(nodes []*apiv1.Node) error {
+	return cloudprovider.ErrNotImplemented
+}
+
 // Id returns asg id.
 func (ng *AwsNodeGroup) Id() string {
-	return ng.asg.Name
+	return ng.asg.AwsRef.id()
 }
 
 // Debug returns a debug string for the Asg.
@@ -446,11 +493,27 @@ func BuildAWS(opts config.AutoscalingOptions, do cloudprovider.NodeGroupDiscover
 	if err != nil {
 		klog.Fatalf("Failed to create AWS Manager: %v", err)
 	}
+	if opts.AWSEKSNodegroupAPI {
+		klog.Infof("Scaling EKS managed node groups through the EKS API")
+		manager.asgCache.eksNodegroupAPI = true
+	}
+	if opts.AWSEventsQueueURL != "" {
+		klog.Infof("Refreshing ASGs from the events of queue %s", opts.AWSEventsQueueURL)
+		manager.asgEvents = newASGEventsQueue(sqs.New(sdkProvider.session), opts.AWSEventsQueueURL)
+	}
 
 	provider, err := BuildAwsCloudProvider(manager, rl)
 	if err != nil {
 		klog.Fatalf("Failed to create AWS cloud provider: %v", err)
 	}
+	if opts.AWSPriceListPricing {
+		if opts.AWSCommitmentAwarePricing {
+			klog.Infof("Discounting prices of nodes by the unused commitment of Savings Plans and Reserved Instances")
+		}
+		provider.(*awsCloudProvider).pricingModel = newAwsPriceModelFromSession(sdkProvider.session, opts.AWSCommitmentAwarePricing)
+	} else if opts.AWSCommitmentAwarePricing {
+		klog.Warningf("Ignoring --aws-commitment-aware-pricing, as --aws-price-list-pricing is not set")
+	}
 	RegisterMetrics()
 	return provider
 }
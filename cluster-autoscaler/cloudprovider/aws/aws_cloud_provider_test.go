@@ -17,6 +17,7 @@ limitations under the License.
 package aws
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -26,6 +27,7 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/aws"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/service/autoscaling"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/service/ec2"
 	"k8s.io/autoscaler/cluster-autoscaler/config"
 )
 
@@ -48,12 +50,15 @@ func newTestAwsManagerWithMockServices(mockAutoScaling autoScalingI, mockEC2 ec2
 			registeredAsgs:        make(map[AwsRef]*asg, 0),
 			asgToInstances:        make(map[AwsRef][]AwsInstanceRef),
 			instanceToAsg:         make(map[AwsInstanceRef]*asg),
+			instanceIDToAsg:       make(map[string]*asg),
 			asgInstanceTypeCache:  newAsgInstanceTypeCache(&awsService),
 			explicitlyConfigured:  make(map[AwsRef]bool),
 			interrupt:             make(chan struct{}),
 			asgAutoDiscoverySpecs: autoDiscoverySpecs,
 			awsService:            &awsService,
 			autoscalingOptions:    make(map[AwsRef]map[string]string),
+			detachedInstances:     make(map[AwsInstanceRef]*asg),
+			unsyncedNodegroups:    make(map[AwsRef]bool),
 		},
 	}
 
@@ -329,6 +334,40 @@ func TestTargetSize(t *testing.T) {
 	a.AssertNumberOfCalls(t, "DescribeAutoScalingGroupsPages", 1)
 }
 
+func TestRefreshTargetSize(t *testing.T) {
+	a := &autoScalingMock{}
+	provider := testProvider(t, newTestAwsManagerWithAsgs(t, a, nil, []string{"1:5:test-asg"}))
+	asgs := provider.NodeGroups()
+
+	for _, desiredCapacity := range []int64{2, 3} {
+		capacity := desiredCapacity
+		a.On("DescribeAutoScalingGroupsPages",
+			&autoscaling.DescribeAutoScalingGroupsInput{
+				AutoScalingGroupNames: aws.StringSlice([]string{"test-asg"}),
+				MaxRecords:            aws.Int64(maxRecordsReturnedByAPI),
+			},
+			mock.AnythingOfType("func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool"),
+		).Run(func(args mock.Arguments) {
+			fn := args.Get(1).(func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool)
+			fn(testNamedDescribeAutoScalingGroupsOutput("test-asg", capacity, "test-instance-id", "second-test-instance-id"), false)
+		}).Return(nil).Once()
+	}
+
+	provider.Refresh()
+	targetSize, err := asgs[0].TargetSize()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, targetSize)
+
+	// The ASG was resized outside of the autoscaler.
+	err = asgs[0].(cloudprovider.TargetSizeRefreshingNodeGroup).RefreshTargetSize()
+	assert.NoError(t, err)
+	targetSize, err = asgs[0].TargetSize()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, targetSize)
+
+	a.AssertNumberOfCalls(t, "DescribeAutoScalingGroupsPages", 2)
+}
+
 func TestIncreaseSize(t *testing.T) {
 	a := &autoScalingMock{}
 	provider := testProvider(t, newTestAwsManagerWithAsgs(t, a, nil, []string{"1:5:test-asg"}))
@@ -456,6 +495,104 @@ func TestDeleteNodes(t *testing.T) {
 	assert.Equal(t, 1, newSize)
 }
 
+func TestDeleteNodesDetachAndTerminate(t *testing.T) {
+	a := &autoScalingMock{}
+	e := &ec2Mock{}
+	provider := testProvider(t, newTestAwsManagerWithAsgs(t, a, e, []string{"1:5:test-asg"}))
+	asgs := provider.NodeGroups()
+
+	a.On("DetachInstances", &autoscaling.DetachInstancesInput{
+		AutoScalingGroupName:           aws.String("test-asg"),
+		InstanceIds:                    aws.StringSlice([]string{"test-instance-id"}),
+		ShouldDecrementDesiredCapacity: aws.Bool(true),
+	}).Return(&autoscaling.DetachInstancesOutput{
+		Activities: []*autoscaling.Activity{{Description: aws.String("Detached instance")}},
+	}, nil)
+	e.On("TerminateInstances", &ec2.TerminateInstancesInput{
+		InstanceIds: aws.StringSlice([]string{"test-instance-id"}),
+	}).Return(&ec2.TerminateInstancesOutput{}, nil)
+
+	a.On("DescribeAutoScalingGroupsPages",
+		&autoscaling.DescribeAutoScalingGroupsInput{
+			AutoScalingGroupNames: aws.StringSlice([]string{"test-asg"}),
+			MaxRecords:            aws.Int64(maxRecordsReturnedByAPI),
+		},
+		mock.AnythingOfType("func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool"),
+	).Run(func(args mock.Arguments) {
+		fn := args.Get(1).(func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool)
+		output := testNamedDescribeAutoScalingGroupsOutput("test-asg", 2, "test-instance-id", "second-test-instance-id")
+		output.AutoScalingGroups[0].Tags = []*autoscaling.TagDescription{
+			{Key: aws.String(scaleDownModeTag), Value: aws.String(scaleDownModeDetachAndTerminate)},
+		}
+		fn(output, false)
+	}).Return(nil)
+
+	provider.Refresh()
+
+	node := &apiv1.Node{
+		Spec: apiv1.NodeSpec{
+			ProviderID: "aws:///us-east-1a/test-instance-id",
+		},
+	}
+	err := asgs[0].DeleteNodes([]*apiv1.Node{node})
+	assert.NoError(t, err)
+	a.AssertNumberOfCalls(t, "DetachInstances", 1)
+	a.AssertNotCalled(t, "TerminateInstanceInAutoScalingGroup", mock.Anything)
+	e.AssertNumberOfCalls(t, "TerminateInstances", 1)
+
+	newSize, err := asgs[0].TargetSize()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, newSize)
+}
+
+func TestDeleteNodesDetachAndTerminateRetriesTermination(t *testing.T) {
+	a := &autoScalingMock{}
+	e := &ec2Mock{}
+	provider := testProvider(t, newTestAwsManagerWithAsgs(t, a, e, []string{"1:5:test-asg"}))
+	asgs := provider.NodeGroups()
+
+	a.On("DetachInstances", mock.Anything).Return(&autoscaling.DetachInstancesOutput{}, nil)
+	terminateInput := &ec2.TerminateInstancesInput{
+		InstanceIds: aws.StringSlice([]string{"test-instance-id"}),
+	}
+	e.On("TerminateInstances", terminateInput).Return(&ec2.TerminateInstancesOutput{}, errors.New("throttled")).Once()
+	e.On("TerminateInstances", terminateInput).Return(&ec2.TerminateInstancesOutput{}, nil).Once()
+
+	a.On("DescribeAutoScalingGroupsPages",
+		&autoscaling.DescribeAutoScalingGroupsInput{
+			AutoScalingGroupNames: aws.StringSlice([]string{"test-asg"}),
+			MaxRecords:            aws.Int64(maxRecordsReturnedByAPI),
+		},
+		mock.AnythingOfType("func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool"),
+	).Run(func(args mock.Arguments) {
+		fn := args.Get(1).(func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool)
+		output := testNamedDescribeAutoScalingGroupsOutput("test-asg", 2, "test-instance-id", "second-test-instance-id")
+		output.AutoScalingGroups[0].Tags = []*autoscaling.TagDescription{
+			{Key: aws.String(scaleDownModeTag), Value: aws.String(scaleDownModeDetachAndTerminate)},
+		}
+		fn(output, false)
+	}).Return(nil)
+
+	provider.Refresh()
+
+	node := &apiv1.Node{
+		Spec: apiv1.NodeSpec{
+			ProviderID: "aws:///us-east-1a/test-instance-id",
+		},
+	}
+	// The instance is detached, so the deletion succeeds and its termination is retried.
+	err := asgs[0].DeleteNodes([]*apiv1.Node{node})
+	assert.NoError(t, err)
+	e.AssertNumberOfCalls(t, "TerminateInstances", 1)
+
+	provider.awsManager.forceRefresh()
+	e.AssertNumberOfCalls(t, "TerminateInstances", 2)
+	assert.Empty(t, provider.awsManager.asgCache.detachedInstances)
+
+	provider.awsManager.forceRefresh()
+	e.AssertNumberOfCalls(t, "TerminateInstances", 2)
+}
+
 func TestDeleteNodesTerminatingInstances(t *testing.T) {
 	a := &autoScalingMock{}
 	provider := testProvider(t, newTestAwsManagerWithAsgs(t, a, nil, []string{"1:5:test-asg"}))
@@ -724,3 +861,109 @@ func TestHasInstance(t *testing.T) {
 	assert.False(t, present)
 
 }
+
+func TestWarmCapacity(t *testing.T) {
+	cache, _ := newASGCache(nil, []string{}, []asgAutoDiscoveryConfig{})
+	awsManager := &AwsManager{asgCache: cache}
+
+	group := &autoscaling.Group{
+		AutoScalingGroupName: aws.String("warm-asg"),
+		MinSize:              aws.Int64(0),
+		MaxSize:              aws.Int64(10),
+		DesiredCapacity:      aws.Int64(1),
+		WarmPoolConfiguration: &autoscaling.WarmPoolConfiguration{
+			PoolState: aws.String(autoscaling.WarmPoolStateStopped),
+		},
+		WarmPoolSize: aws.Int64(3),
+	}
+	warmAsg, err := cache.buildAsgFromAWS(group, "")
+	assert.NoError(t, err)
+	ng := &AwsNodeGroup{awsManager: awsManager, asg: warmAsg}
+
+	warm, err := ng.WarmCapacity()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, warm)
+	assert.False(t, ng.PreferWarmCapacity())
+
+	cache.autoscalingOptions[warmAsg.AwsRef] = map[string]string{preferWarmPoolKey: "true"}
+	assert.True(t, ng.PreferWarmCapacity())
+
+	// instances of a warm pool being deleted can't be used.
+	group.WarmPoolConfiguration.Status = aws.String(autoscaling.WarmPoolStatusPendingDelete)
+	deletedAsg, err := cache.buildAsgFromAWS(group, "")
+	assert.NoError(t, err)
+	ng = &AwsNodeGroup{awsManager: awsManager, asg: deletedAsg}
+	warm, err = ng.WarmCapacity()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, warm)
+	assert.False(t, ng.PreferWarmCapacity())
+}
+
+func TestCapacityType(t *testing.T) {
+	cache, _ := newASGCache(nil, []string{}, []asgAutoDiscoveryConfig{})
+	awsManager := &AwsManager{asgCache: cache}
+
+	for _, tc := range []struct {
+		desc         string
+		distribution *autoscaling.InstancesDistribution
+		want         cloudprovider.CapacityType
+	}{
+		{
+			desc: "no instances distribution",
+			want: cloudprovider.OnDemandCapacity,
+		},
+		{
+			desc:         "default instances distribution",
+			distribution: &autoscaling.InstancesDistribution{},
+			want:         cloudprovider.OnDemandCapacity,
+		},
+		{
+			desc:         "spot only",
+			distribution: &autoscaling.InstancesDistribution{OnDemandPercentageAboveBaseCapacity: aws.Int64(0)},
+			want:         cloudprovider.SpotCapacity,
+		},
+		{
+			desc:         "on-demand base capacity",
+			distribution: &autoscaling.InstancesDistribution{OnDemandBaseCapacity: aws.Int64(1), OnDemandPercentageAboveBaseCapacity: aws.Int64(0)},
+			want:         cloudprovider.OnDemandCapacity,
+		},
+		{
+			desc:         "mixed spot and on-demand",
+			distribution: &autoscaling.InstancesDistribution{OnDemandPercentageAboveBaseCapacity: aws.Int64(50)},
+			want:         cloudprovider.OnDemandCapacity,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			group := &autoscaling.Group{
+				AutoScalingGroupName: aws.String("mixed-asg"),
+				MinSize:              aws.Int64(0),
+				MaxSize:              aws.Int64(10),
+				DesiredCapacity:      aws.Int64(1),
+				MixedInstancesPolicy: &autoscaling.MixedInstancesPolicy{
+					LaunchTemplate: &autoscaling.LaunchTemplate{
+						LaunchTemplateSpecification: &autoscaling.LaunchTemplateSpecification{
+							LaunchTemplateName: aws.String("lt"),
+							Version:            aws.String("1"),
+						},
+					},
+					InstancesDistribution: tc.distribution,
+				},
+			}
+			asg, err := cache.buildAsgFromAWS(group, "")
+			assert.NoError(t, err)
+			ng := &AwsNodeGroup{awsManager: awsManager, asg: asg}
+			assert.Equal(t, tc.want, ng.CapacityType())
+		})
+	}
+
+	// ASGs without a mixed instances policy are on-demand.
+	asg, err := cache.buildAsgFromAWS(&autoscaling.Group{
+		AutoScalingGroupName: aws.String("asg"),
+		MinSize:              aws.Int64(0),
+		MaxSize:              aws.Int64(10),
+		DesiredCapacity:      aws.Int64(1),
+	}, "")
+	assert.NoError(t, err)
+	ng := &AwsNodeGroup{awsManager: awsManager, asg: asg}
+	assert.Equal(t, cloudprovider.OnDemandCapacity, ng.CapacityType())
+}
@@ -25,6 +25,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	apiv1 "k8s.io/api/core/v1"
@@ -50,6 +51,9 @@ const (
 	autoDiscovererTypeASG   = "asg"
 	asgAutoDiscovererKeyTag = "tag"
 	optionsTagsPrefix       = "k8s.io/cluster-autoscaler/node-template/autoscaling-options/"
+	// preferWarmPoolKey is the autoscaling option preferring the ASG in the warm-capacity expander when
+	// its warm pool can absorb scale-ups.
+	preferWarmPoolKey       = "preferwarmpool"
 	labelAwsCSITopologyZone = "topology.ebs.csi.aws.com/zone"
 )
 
@@ -60,6 +64,19 @@ type AwsManager struct {
 	lastRefresh           time.Time
 	instanceTypes         map[string]*InstanceType
 	managedNodegroupCache *managedNodegroupCache
+	// asgEvents, when set, tells when the ASG cache needs to be refreshed, instead of polling AWS for changes.
+	asgEvents *asgEventsQueue
+
+	// requirementsCapacities caches the template capacity built from the instance requirements of ASGs, as
+	// building it takes several AWS calls. It is keyed by requirementsCapacityKey.
+	requirementsCapacitiesMutex sync.Mutex
+	requirementsCapacities      map[string]requirementsCapacity
+}
+
+// requirementsCapacity is the template capacity built from instance requirements.
+type requirementsCapacity struct {
+	capacity  apiv1.ResourceList
+	fetchedAt time.Time
 }
 
 type asgTemplate struct {
@@ -91,6 +108,10 @@ func createAWSManagerInternal(
 		return nil, err
 	}
 
+	if awsSDKProvider != nil {
+		cache.setAssumedRoles(newAssumedRoleServices(awsSDKProvider.session))
+	}
+
 	mngCache := newManagedNodeGroupCache(awsService)
 
 	manager := &AwsManager{
@@ -115,12 +136,40 @@ func CreateAwsManager(awsSDKProvider *awsSDKProvider, discoveryOpts cloudprovide
 // Refresh is called before every main loop and can be used to dynamically update cloud provider state.
 // In particular the list of node groups returned by NodeGroups can change as a result of CloudProvider.Refresh().
 func (m *AwsManager) Refresh() error {
+	if m.asgEvents != nil {
+		return m.refreshFromEvents()
+	}
 	if m.lastRefresh.Add(refreshInterval).After(time.Now()) {
 		return nil
 	}
 	return m.forceRefresh()
 }
 
+// refreshFromEvents refreshes the ASGs affected by events as soon as they are received, and all of them only
+// every eventsResyncInterval. If the events can't be received, it falls back to refreshing every refreshInterval.
+func (m *AwsManager) refreshFromEvents() error {
+	changes, err := m.asgEvents.poll(m.asgCache)
+	if err != nil {
+		klog.Errorf("Failed to receive ASG events from queue %s: %v", m.asgEvents.queueURL, err)
+		if m.lastRefresh.Add(refreshInterval).After(time.Now()) {
+			return nil
+		}
+		return m.forceRefresh()
+	}
+	if changes.unknown || m.lastRefresh.Add(eventsResyncInterval).Before(time.Now()) {
+		return m.forceRefresh()
+	}
+	if len(changes.asgs) == 0 {
+		return nil
+	}
+	if err := m.asgCache.regenerateAsgs(changes.asgs); err != nil {
+		klog.Errorf("Failed to regenerate ASGs affected by events: %v", err)
+		return err
+	}
+	klog.V(2).Infof("Refreshed %d ASGs affected by events", len(changes.asgs))
+	return nil
+}
+
 func (m *AwsManager) forceRefresh() error {
 	if err := m.asgCache.regenerate(); err != nil {
 		klog.Errorf("Failed to regenerate ASG cache: %v", err)
@@ -255,9 +304,32 @@ func (m *AwsManager) GetAsgOptions(asg asg, defaults config.NodeGroupAutoscaling
 		}
 	}
 
+	if stringOpt, found := options[config.DefaultRecreationCostKey]; found {
+		if opt, err := strconv.ParseFloat(stringOpt, 64); err != nil {
+			klog.Warningf("failed to convert asg %s %s tag to float: %v",
+				asg.Name, config.DefaultRecreationCostKey, err)
+		} else {
+			defaults.RecreationCost = opt
+		}
+	}
+
 	return &defaults
 }
 
+// PreferAsgWarmPool returns true if the ASG is tagged to be preferred by the warm-capacity expander.
+func (m *AwsManager) PreferAsgWarmPool(asg asg) bool {
+	stringOpt, found := m.getAutoscalingOptions(asg.AwsRef)[preferWarmPoolKey]
+	if !found {
+		return false
+	}
+	opt, err := strconv.ParseBool(stringOpt)
+	if err != nil {
+		klog.Warningf("failed to convert asg %s %s tag to bool: %v", asg.Name, preferWarmPoolKey, err)
+		return false
+	}
+	return opt
+}
+
 func (m *AwsManager) buildNodeFromTemplate(asg *asg, template *asgTemplate) (*apiv1.Node, error) {
 	node := apiv1.Node{}
 	nodeName := fmt.Sprintf("%s-asg-%d", asg.Name, rand.Int63())
@@ -278,7 +350,7 @@ func (m *AwsManager) buildNodeFromTemplate(asg *asg, template *asgTemplate) (*ap
 	node.Status.Capacity[gpu.ResourceNvidiaGPU] = *resource.NewQuantity(template.InstanceType.GPU, resource.DecimalSI)
 	node.Status.Capacity[apiv1.ResourceMemory] = *resource.NewQuantity(template.InstanceType.MemoryMb*1024*1024, resource.DecimalSI)
 
-	if err := m.updateCapacityWithRequirementsOverrides(&node.Status.Capacity, asg.MixedInstancesPolicy); err != nil {
+	if err := m.updateCapacityWithRequirementsOverrides(&node.Status.Capacity, asg); err != nil {
 		return nil, err
 	}
 
@@ -314,6 +386,10 @@ func (m *AwsManager) buildNodeFromTemplate(asg *asg, template *asgTemplate) (*ap
 		mngTaints, err := m.managedNodegroupCache.getManagedNodegroupTaints(nodegroupName, clusterName)
 		if err != nil {
 			klog.Errorf("Failed to get taints from EKS DescribeNodegroup API for nodegroup %s in cluster %s because %s.", nodegroupName, clusterName, err)
+		} else if m.asgCache != nil && m.asgCache.eksNodegroupAPI {
+			// The node group is scaled through the EKS API, so its taints override the ones of the ASG tags.
+			node.Spec.Taints = joinTaintsChoosingAPIValues(node.Spec.Taints, mngTaints)
+			klog.V(5).Infof("node.Spec.Taints : %+v\n", node.Spec.Taints)
 		} else if mngTaints != nil && len(mngTaints) > 0 {
 			node.Spec.Taints = append(node.Spec.Taints, mngTaints...)
 			klog.V(5).Infof("node.Spec.Taints : %+v\n", node.Spec.Taints)
@@ -355,47 +431,181 @@ func joinNodeLabelsChoosingUserValuesOverAPIValues(extractedLabels map[string]st
 	return result
 }
 
-func (m *AwsManager) updateCapacityWithRequirementsOverrides(capacity *apiv1.ResourceList, policy *mixedInstancesPolicy) error {
+// joinTaintsChoosingAPIValues returns the taints from the EKS DescribeNodegroup API call, and the ones extracted
+// from the ASG tags whose key and effect aren't set by the API.
+func joinTaintsChoosingAPIValues(extractedTaints []apiv1.Taint, mngTaints []apiv1.Taint) []apiv1.Taint {
+	result := make([]apiv1.Taint, 0, len(extractedTaints)+len(mngTaints))
+	result = append(result, mngTaints...)
+	for _, taint := range extractedTaints {
+		overridden := false
+		for _, mngTaint := range mngTaints {
+			if taint.MatchTaint(&mngTaint) {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			result = append(result, taint)
+		}
+	}
+	return result
+}
+
+func (m *AwsManager) updateCapacityWithRequirementsOverrides(capacity *apiv1.ResourceList, asg *asg) error {
+	policy := asg.MixedInstancesPolicy
 	if policy == nil || len(policy.instanceTypesOverrides) > 0 {
 		return nil
 	}
 
-	instanceRequirements, err := m.getInstanceRequirementsFromMixedInstancesPolicy(policy)
+	requirementsCapacity, err := m.getRequirementsCapacity(asg)
 	if err != nil {
-		return fmt.Errorf("error while building node template using instance requirements: (%s)", err)
+		return err
+	}
+	for name, quantity := range requirementsCapacity {
+		if name == gpu.ResourceNvidiaGPU && quantity.IsZero() {
+			// Some of the instance types the ASG may launch have no GPU.
+			delete(*capacity, name)
+			continue
+		}
+		(*capacity)[name] = quantity
+	}
+	return nil
+}
+
+// requirementsCapacityKey identifies the instance requirements of the ASG: they come from its launch
+// template version, unless they are overridden in its mixed instances policy.
+func requirementsCapacityKey(asg *asg) string {
+	policy := asg.MixedInstancesPolicy
+	key := asg.AwsRef.id()
+	if policy.launchTemplate != nil {
+		key += "/" + policy.launchTemplate.name + "/" + policy.launchTemplate.version
+	}
+	if policy.instanceRequirementsOverrides != nil {
+		key += "/" + policy.instanceRequirementsOverrides.String()
+	}
+	return key
+}
+
+// getRequirementsCapacity returns the template capacity built from the instance requirements of the ASG,
+// using the cached one if it was built less than asgInstanceTypeCacheTTL ago. The TTL picks up changes of
+// the launch templates referenced by $Latest or $Default versions.
+func (m *AwsManager) getRequirementsCapacity(asg *asg) (apiv1.ResourceList, error) {
+	key := requirementsCapacityKey(asg)
+	m.requirementsCapacitiesMutex.Lock()
+	cached, found := m.requirementsCapacities[key]
+	m.requirementsCapacitiesMutex.Unlock()
+	if found && time.Since(cached.fetchedAt) < asgInstanceTypeCacheTTL {
+		return cached.capacity, nil
+	}
+
+	capacity, complete, err := m.buildRequirementsCapacity(asg)
+	if err != nil {
+		return nil, err
+	}
+	if !complete {
+		// Retry the instance types lookup with the next template.
+		return capacity, nil
+	}
+
+	m.requirementsCapacitiesMutex.Lock()
+	defer m.requirementsCapacitiesMutex.Unlock()
+	if m.requirementsCapacities == nil {
+		m.requirementsCapacities = make(map[string]requirementsCapacity)
+	}
+	m.requirementsCapacities[key] = requirementsCapacity{capacity: capacity, fetchedAt: time.Now()}
+	return capacity, nil
+}
+
+// buildRequirementsCapacity builds the template capacity from the instance requirements of the ASG. It is
+// incomplete if the instance types matching the requirements couldn't be listed.
+func (m *AwsManager) buildRequirementsCapacity(asg *asg) (capacity apiv1.ResourceList, complete bool, err error) {
+	policy := asg.MixedInstancesPolicy
+	awsService, err := m.asgCache.serviceFor(asg)
+	if err != nil {
+		return nil, false, err
+	}
+	instanceRequirements, err := getInstanceRequirementsFromMixedInstancesPolicy(awsService, policy)
+	if err != nil {
+		return nil, false, fmt.Errorf("error while building node template using instance requirements: (%s)", err)
 	}
 
+	capacity = apiv1.ResourceList{}
 	if instanceRequirements.VCpuCount != nil && instanceRequirements.VCpuCount.Min != nil {
-		(*capacity)[apiv1.ResourceCPU] = *resource.NewQuantity(*instanceRequirements.VCpuCount.Min, resource.DecimalSI)
+		capacity[apiv1.ResourceCPU] = *resource.NewQuantity(*instanceRequirements.VCpuCount.Min, resource.DecimalSI)
 	}
 
 	if instanceRequirements.MemoryMiB != nil && instanceRequirements.MemoryMiB.Min != nil {
-		(*capacity)[apiv1.ResourceMemory] = *resource.NewQuantity(*instanceRequirements.MemoryMiB.Min*1024*1024, resource.DecimalSI)
+		capacity[apiv1.ResourceMemory] = *resource.NewQuantity(*instanceRequirements.MemoryMiB.Min*1024*1024, resource.DecimalSI)
 	}
 
-	for _, manufacturer := range instanceRequirements.AcceleratorManufacturers {
-		if *manufacturer == autoscaling.AcceleratorManufacturerNvidia {
-			for _, acceleratorType := range instanceRequirements.AcceleratorTypes {
-				if *acceleratorType == autoscaling.AcceleratorTypeGpu {
-					(*capacity)[gpu.ResourceNvidiaGPU] = *resource.NewQuantity(*instanceRequirements.AcceleratorCount.Min, resource.DecimalSI)
+	if instanceRequirements.AcceleratorCount != nil && instanceRequirements.AcceleratorCount.Min != nil {
+		for _, manufacturer := range instanceRequirements.AcceleratorManufacturers {
+			if *manufacturer == autoscaling.AcceleratorManufacturerNvidia {
+				for _, acceleratorType := range instanceRequirements.AcceleratorTypes {
+					if *acceleratorType == autoscaling.AcceleratorTypeGpu {
+						capacity[gpu.ResourceNvidiaGPU] = *resource.NewQuantity(*instanceRequirements.AcceleratorCount.Min, resource.DecimalSI)
+					}
 				}
 			}
 		}
 	}
 
-	return nil
+	// The requirements minimums are only a lower bound, narrow the template down to
+	// what the instance types the ASG may actually launch provide.
+	if policy.launchTemplate == nil {
+		return capacity, true, nil
+	}
+	instanceTypes, err := awsService.getInstanceTypesMatchingRequirements(policy)
+	if err != nil {
+		klog.Warningf("Failed to get instance types matching the requirements of ASG %s, using the requirements minimums: %v", asg.Name, err)
+		return capacity, false, nil
+	}
+	if smallest := m.smallestInstanceType(instanceTypes); smallest != nil {
+		capacity[apiv1.ResourceCPU] = *resource.NewQuantity(smallest.VCPU, resource.DecimalSI)
+		capacity[apiv1.ResourceMemory] = *resource.NewQuantity(smallest.MemoryMb*1024*1024, resource.DecimalSI)
+		capacity[gpu.ResourceNvidiaGPU] = *resource.NewQuantity(smallest.GPU, resource.DecimalSI)
+	}
+	return capacity, true, nil
+}
+
+// smallestInstanceType returns the smallest vCPU, memory and GPU count found among the given
+// instance types, so that a pod fitting the resulting template fits any of them. Instance types
+// missing from the known list are ignored, nil is returned when none of them is known.
+func (m *AwsManager) smallestInstanceType(instanceTypeNames []string) *InstanceType {
+	var smallest *InstanceType
+	for _, name := range instanceTypeNames {
+		t, ok := m.instanceTypes[name]
+		if !ok {
+			klog.V(4).Infof("Ignoring unknown EC2 instance type %q matching instance requirements", name)
+			continue
+		}
+		if smallest == nil {
+			smallest = &InstanceType{VCPU: t.VCPU, MemoryMb: t.MemoryMb, GPU: t.GPU, Architecture: t.Architecture}
+			continue
+		}
+		if t.VCPU < smallest.VCPU {
+			smallest.VCPU = t.VCPU
+		}
+		if t.MemoryMb < smallest.MemoryMb {
+			smallest.MemoryMb = t.MemoryMb
+		}
+		if t.GPU < smallest.GPU {
+			smallest.GPU = t.GPU
+		}
+	}
+	return smallest
 }
 
-func (m *AwsManager) getInstanceRequirementsFromMixedInstancesPolicy(policy *mixedInstancesPolicy) (*ec2.InstanceRequirements, error) {
+func getInstanceRequirementsFromMixedInstancesPolicy(awsService *awsWrapper, policy *mixedInstancesPolicy) (*ec2.InstanceRequirements, error) {
 	instanceRequirements := &ec2.InstanceRequirements{}
 	if policy.instanceRequirementsOverrides != nil {
 		var err error
-		instanceRequirements, err = m.awsService.getEC2RequirementsFromAutoscaling(policy.instanceRequirementsOverrides)
+		instanceRequirements, err = awsService.getEC2RequirementsFromAutoscaling(policy.instanceRequirementsOverrides)
 		if err != nil {
 			return nil, err
 		}
 	} else if policy.launchTemplate != nil {
-		templateData, err := m.awsService.getLaunchTemplateData(policy.launchTemplate.name, policy.launchTemplate.version)
+		templateData, err := awsService.getLaunchTemplateData(policy.launchTemplate.name, policy.launchTemplate.version)
 		if err != nil {
 			return nil, err
 		}
@@ -317,6 +317,44 @@ func TestBuildNodeFromTemplateWithManagedNodegroup(t *testing.T) {
 	assert.Equal(t, observedNode.Spec.Taints[1].Value, taintValue2)
 }
 
+func TestBuildNodeFromTemplateWithManagedNodegroupEKSAPI(t *testing.T) {
+	mngCache := newManagedNodeGroupCache(nil)
+	awsManager := &AwsManager{managedNodegroupCache: mngCache, asgCache: &asgCache{eksNodegroupAPI: true}}
+	asg := &asg{AwsRef: AwsRef{Name: "test-auto-scaling-group"}}
+	c5Instance := &InstanceType{
+		InstanceType: "c5.xlarge",
+		VCPU:         4,
+		MemoryMb:     8192,
+	}
+
+	eksTaint := apiv1.Taint{Key: "dedicated", Value: "gpu", Effect: apiv1.TaintEffectNoSchedule}
+	err := mngCache.Add(managedNodegroupCachedObject{
+		name:        "nodegroup-1",
+		clusterName: "cluster-1",
+		taints:      []apiv1.Taint{eksTaint},
+		labels:      map[string]string{"team": "ml"},
+	})
+	require.NoError(t, err)
+
+	observedNode, observedErr := awsManager.buildNodeFromTemplate(asg, &asgTemplate{
+		InstanceType: c5Instance,
+		Tags: []*autoscaling.TagDescription{
+			{Key: aws.String("eks:nodegroup-name"), Value: aws.String("nodegroup-1")},
+			{Key: aws.String("eks:cluster-name"), Value: aws.String("cluster-1")},
+			{Key: aws.String("k8s.io/cluster-autoscaler/node-template/label/team"), Value: aws.String("web")},
+			{Key: aws.String("k8s.io/cluster-autoscaler/node-template/taint/dedicated"), Value: aws.String("web:NoSchedule")},
+			{Key: aws.String("k8s.io/cluster-autoscaler/node-template/taint/spot"), Value: aws.String("true:PreferNoSchedule")},
+		},
+	})
+	assert.NoError(t, observedErr)
+	assert.Equal(t, "ml", observedNode.Labels["team"])
+	// The taint of the node group overrides the stale one of the ASG tags, other ASG tag taints are kept.
+	assert.ElementsMatch(t, []apiv1.Taint{
+		eksTaint,
+		{Key: "spot", Value: "true", Effect: apiv1.TaintEffectPreferNoSchedule},
+	}, observedNode.Spec.Taints)
+}
+
 func TestBuildNodeFromTemplateWithManagedNodegroupNoLabelsOrTaints(t *testing.T) {
 	mngCache := newManagedNodeGroupCache(nil)
 	awsManager := &AwsManager{managedNodegroupCache: mngCache}
@@ -412,7 +450,7 @@ func TestBuildNodeFromTemplateWithManagedNodegroupNilLabelsOrTaints(t *testing.T
 }
 
 func TestBuildNodeFromTemplate(t *testing.T) {
-	awsManager := &AwsManager{}
+	awsManager := &AwsManager{asgCache: &asgCache{awsService: &awsWrapper{}}}
 	asg := &asg{AwsRef: AwsRef{Name: "test-auto-scaling-group"}}
 	c5Instance := &InstanceType{
 		InstanceType: "c5.xlarge",
@@ -535,6 +573,99 @@ func TestBuildNodeFromTemplate(t *testing.T) {
 	assert.Equal(t, int64(4), observedGpuRequirement.Value())
 }
 
+func TestBuildNodeFromTemplateCachesInstanceRequirements(t *testing.T) {
+	e := &ec2Mock{}
+	awsService := &awsWrapper{ec2I: e}
+	awsManager := &AwsManager{
+		asgCache: &asgCache{awsService: awsService},
+		instanceTypes: map[string]*InstanceType{
+			"c5.xlarge":  {InstanceType: "c5.xlarge", VCPU: 4, MemoryMb: 8192, Architecture: "amd64"},
+			"g4dn.large": {InstanceType: "g4dn.large", VCPU: 4, MemoryMb: 16384, GPU: 1, Architecture: "amd64"},
+		},
+	}
+	asg := &asg{
+		AwsRef: AwsRef{Name: "test-auto-scaling-group"},
+		MixedInstancesPolicy: &mixedInstancesPolicy{
+			launchTemplate: &launchTemplate{name: "launchTemplateName", version: "1"},
+			instanceRequirementsOverrides: &autoscaling.InstanceRequirements{
+				VCpuCount: &autoscaling.VCpuCountRequest{Min: aws.Int64(4)},
+				MemoryMiB: &autoscaling.MemoryMiBRequest{Min: aws.Int64(8192)},
+			},
+		},
+	}
+
+	e.On("DescribeLaunchTemplateVersions", &ec2.DescribeLaunchTemplateVersionsInput{
+		LaunchTemplateName: aws.String("launchTemplateName"),
+		Versions:           []*string{aws.String("1")},
+	}).Return(&ec2.DescribeLaunchTemplateVersionsOutput{
+		LaunchTemplateVersions: []*ec2.LaunchTemplateVersion{{
+			LaunchTemplateData: &ec2.ResponseLaunchTemplateData{ImageId: aws.String("123")},
+		}},
+	})
+	e.On("DescribeImages", &ec2.DescribeImagesInput{
+		ImageIds: []*string{aws.String("123")},
+	}).Return(&ec2.DescribeImagesOutput{
+		Images: []*ec2.Image{{Architecture: aws.String("x86_64"), VirtualizationType: aws.String("hvm")}},
+	})
+	e.On("GetInstanceTypesFromInstanceRequirementsPages", mock.Anything,
+		mock.AnythingOfType("func(*ec2.GetInstanceTypesFromInstanceRequirementsOutput, bool) bool"),
+	).Run(func(args mock.Arguments) {
+		fn := args.Get(1).(func(*ec2.GetInstanceTypesFromInstanceRequirementsOutput, bool) bool)
+		fn(&ec2.GetInstanceTypesFromInstanceRequirementsOutput{
+			InstanceTypes: []*ec2.InstanceTypeInfoFromInstanceRequirements{
+				{InstanceType: aws.String("c5.xlarge")},
+				{InstanceType: aws.String("g4dn.large")},
+			},
+		}, false)
+	}).Return(nil)
+
+	for i := 0; i < 2; i++ {
+		node, err := awsManager.buildNodeFromTemplate(asg, &asgTemplate{InstanceType: awsManager.instanceTypes["g4dn.large"]})
+		assert.NoError(t, err)
+		memory := node.Status.Capacity[apiv1.ResourceMemory]
+		assert.Equal(t, int64(8192*1024*1024), memory.Value())
+		// c5.xlarge has no GPU, so the template doesn't have any.
+		_, found := node.Status.Capacity[gpu.ResourceNvidiaGPU]
+		assert.False(t, found)
+	}
+	// The second template is built from the cached instance requirements.
+	e.AssertNumberOfCalls(t, "GetInstanceTypesFromInstanceRequirementsPages", 1)
+
+	// Another launch template version is looked up again.
+	asg.MixedInstancesPolicy.launchTemplate.version = "2"
+	e.On("DescribeLaunchTemplateVersions", &ec2.DescribeLaunchTemplateVersionsInput{
+		LaunchTemplateName: aws.String("launchTemplateName"),
+		Versions:           []*string{aws.String("2")},
+	}).Return(&ec2.DescribeLaunchTemplateVersionsOutput{
+		LaunchTemplateVersions: []*ec2.LaunchTemplateVersion{{
+			LaunchTemplateData: &ec2.ResponseLaunchTemplateData{ImageId: aws.String("123")},
+		}},
+	})
+	_, err := awsManager.buildNodeFromTemplate(asg, &asgTemplate{InstanceType: awsManager.instanceTypes["g4dn.large"]})
+	assert.NoError(t, err)
+	e.AssertNumberOfCalls(t, "GetInstanceTypesFromInstanceRequirementsPages", 2)
+}
+
+func TestSmallestInstanceType(t *testing.T) {
+	awsManager := &AwsManager{
+		instanceTypes: map[string]*InstanceType{
+			"c5.xlarge":  {InstanceType: "c5.xlarge", VCPU: 4, MemoryMb: 8192, Architecture: "amd64"},
+			"m5.large":   {InstanceType: "m5.large", VCPU: 2, MemoryMb: 8192, Architecture: "amd64"},
+			"r5.large":   {InstanceType: "r5.large", VCPU: 2, MemoryMb: 16384, Architecture: "amd64"},
+			"g4dn.large": {InstanceType: "g4dn.large", VCPU: 4, MemoryMb: 16384, GPU: 1, Architecture: "amd64"},
+		},
+	}
+
+	smallest := awsManager.smallestInstanceType([]string{"c5.xlarge", "r5.large", "unknown.large"})
+	assert.Equal(t, &InstanceType{VCPU: 2, MemoryMb: 8192, GPU: 0, Architecture: "amd64"}, smallest)
+
+	smallest = awsManager.smallestInstanceType([]string{"g4dn.large"})
+	assert.Equal(t, int64(1), smallest.GPU)
+
+	assert.Nil(t, awsManager.smallestInstanceType([]string{"unknown.large"}))
+	assert.Nil(t, awsManager.smallestInstanceType(nil))
+}
+
 func TestExtractLabelsFromAsg(t *testing.T) {
 	tags := []*autoscaling.TagDescription{
 		{
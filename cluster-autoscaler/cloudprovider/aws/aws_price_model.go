@@ -0,0 +1,443 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/aws"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/aws/session"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/service/costexplorer"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/service/pricing"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/units"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// pricingAPIRegion is the region of the endpoints of the Price List and Cost Explorer APIs.
+	pricingAPIRegion = "us-east-1"
+	// pricesTTL is how long prices and unused commitments are cached, failed refreshes are retried sooner.
+	pricesTTL      = 24 * time.Hour
+	pricesRetryTTL = 10 * time.Minute
+	// utilizationLookback is the period over which the unused commitment of Savings Plans and Reserved Instances
+	// is averaged.
+	utilizationLookback = 7 * 24 * time.Hour
+	maxPriceListPages   = 100
+	costExplorerDate    = "2006-01-02"
+
+	// Fallback prices of on-demand general purpose instances, used for pods and for instance types without a price.
+	cpuPricePerHour         = 0.034
+	memoryPricePerHourPerGb = 0.0035
+	// spotDiscount is the approximate ratio of the Spot price to the on-demand price.
+	spotDiscount = 0.3
+
+	// Attributes of the Savings Plans and Reserved Instances reported by Cost Explorer, and the type of
+	// the Savings Plans applying to a single instance family.
+	savingsPlansTypeAttribute        = "SavingsPlansType"
+	savingsPlansRegionAttribute      = "Region"
+	savingsPlansFamilyAttribute      = "InstanceFamily"
+	instanceSavingsPlansType         = "EC2InstanceSavingsPlans"
+	reservationInstanceTypeAttribute = "instanceType"
+
+	// capacityTypeLabel is set by EKS on the nodes of managed node groups, to SPOT for Spot instances.
+	capacityTypeLabel = "eks.amazonaws.com/capacityType"
+	capacityTypeSpot  = "SPOT"
+)
+
+// pricingI is the interface abstracting specific API calls of the Price List service provided by AWS SDK for use in CA
+type pricingI interface {
+	GetProducts(input *pricing.GetProductsInput) (*pricing.GetProductsOutput, error)
+}
+
+// costExplorerI is the interface abstracting specific API calls of the Cost Explorer service provided by AWS SDK for use in CA
+type costExplorerI interface {
+	GetSavingsPlansUtilizationDetails(input *costexplorer.GetSavingsPlansUtilizationDetailsInput) (*costexplorer.GetSavingsPlansUtilizationDetailsOutput, error)
+	GetReservationUtilization(input *costexplorer.GetReservationUtilizationInput) (*costexplorer.GetReservationUtilizationOutput, error)
+}
+
+// AwsPriceModel implements the PricingModel interface for AWS, using the on-demand Linux prices of the Price List
+// API, discounted by the commitment of Savings Plans and Reserved Instances left unused when it is tracked, since
+// only that commitment would pay for new nodes. Prices are refreshed in the background, until the first refresh
+// completes nodes are priced from their CPU and memory. All prices are in USD.
+type AwsPriceModel struct {
+	prices *pricingCache
+}
+
+// NewAwsPriceModel returns an AwsPriceModel for the instances of the region. costExplorer may be nil, in which
+// case commitments are ignored.
+func NewAwsPriceModel(pricingService pricingI, costExplorer costExplorerI, region string) *AwsPriceModel {
+	return &AwsPriceModel{prices: newPricingCache(pricingService, costExplorer, region)}
+}
+
+// newAwsPriceModelFromSession returns an AwsPriceModel for the region of the session, taking commitments
+// into account if commitmentAware is set.
+func newAwsPriceModelFromSession(sess *session.Session, commitmentAware bool) *AwsPriceModel {
+	config := aws.NewConfig().WithRegion(pricingAPIRegion)
+	var costExplorer costExplorerI
+	if commitmentAware {
+		costExplorer = costexplorer.New(sess, config)
+	}
+	return NewAwsPriceModel(pricing.New(sess, config), costExplorer, aws.StringValue(sess.Config.Region))
+}
+
+// NodePrice returns a price of running the given node for a given period of time.
+func (model *AwsPriceModel) NodePrice(node *apiv1.Node, startTime time.Time, endTime time.Time) (float64, error) {
+	hours := getHours(startTime, endTime)
+	instanceType := node.Labels[apiv1.LabelInstanceTypeStable]
+	spot := node.Labels[capacityTypeLabel] == capacityTypeSpot
+
+	pricePerHour, unused, found := model.prices.get(instanceType)
+	if !found {
+		klog.V(4).Infof("Pricing information not found for instance type %q; will fallback to default pricing", instanceType)
+		pricePerHour = getBasePrice(node.Status.Capacity, 1)
+	}
+	if spot {
+		// commitments don't apply to Spot instances.
+		return pricePerHour * spotDiscount * hours, nil
+	}
+	return math.Max(0, pricePerHour-unused) * hours, nil
+}
+
+// PodPrice returns a theoretical minimum price of running a pod for a given
+// period of time on a perfectly matching machine.
+func (model *AwsPriceModel) PodPrice(pod *apiv1.Pod, startTime time.Time, endTime time.Time) (float64, error) {
+	price := 0.0
+	for _, container := range pod.Spec.Containers {
+		price += getBasePrice(container.Resources.Requests, getHours(startTime, endTime))
+	}
+	return price, nil
+}
+
+func getBasePrice(resources apiv1.ResourceList, hours float64) float64 {
+	if len(resources) == 0 {
+		return 0
+	}
+	price := 0.0
+	cpu := resources[apiv1.ResourceCPU]
+	mem := resources[apiv1.ResourceMemory]
+	price += float64(cpu.MilliValue()) / 1000.0 * cpuPricePerHour * hours
+	price += float64(mem.Value()) / float64(units.GiB) * memoryPricePerHourPerGb * hours
+	return price
+}
+
+func getHours(startTime time.Time, endTime time.Time) float64 {
+	minutes := math.Ceil(float64(endTime.Sub(startTime)) / float64(time.Minute))
+	return minutes / 60.0
+}
+
+// instanceFamily returns the family of the instance type, e.g. m5 for m5.large.
+func instanceFamily(instanceType string) string {
+	return strings.SplitN(instanceType, ".", 2)[0]
+}
+
+// unusedCommitments holds the commitment of Savings Plans and Reserved Instances of a region left unused, on
+// average over the last week.
+type unusedCommitments struct {
+	// reservedInstances is the number of unused Reserved Instances per instance type.
+	reservedInstances map[string]float64
+	// instanceSavingsPlans is the unused hourly commitment of EC2 Instance Savings Plans per instance family.
+	instanceSavingsPlans map[string]float64
+	// computeSavingsPlans is the unused hourly commitment of Compute Savings Plans, which apply to any instance.
+	computeSavingsPlans float64
+}
+
+// hourlyDiscount returns the part of the hourly price of a new instance of the type that unused commitments
+// would pay for.
+func (u *unusedCommitments) hourlyDiscount(instanceType string, pricePerHour float64) float64 {
+	if u == nil {
+		return 0
+	}
+	discount := math.Min(1, u.reservedInstances[instanceType]) * pricePerHour
+	discount += u.instanceSavingsPlans[instanceFamily(instanceType)] + u.computeSavingsPlans
+	return math.Min(pricePerHour, discount)
+}
+
+// pricingCache holds the on-demand prices of all the instance types of a region and the unused commitments,
+// refreshed together in the background once they expire, so that callers get the last known values without
+// waiting for the APIs.
+type pricingCache struct {
+	pricing      pricingI
+	costExplorer costExplorerI
+	region       string
+
+	mutex      sync.Mutex
+	onDemand   map[string]float64
+	unused     *unusedCommitments
+	expiresAt  time.Time
+	refreshing bool
+}
+
+func newPricingCache(pricingService pricingI, costExplorer costExplorerI, region string) *pricingCache {
+	return &pricingCache{
+		pricing:      pricingService,
+		costExplorer: costExplorer,
+		region:       region,
+		onDemand:     make(map[string]float64),
+	}
+}
+
+// get returns the hourly on-demand price of the instance type and the part of it paid for by unused
+// commitments, starting a refresh in the background if they expired.
+func (c *pricingCache) get(instanceType string) (float64, float64, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if time.Now().After(c.expiresAt) && !c.refreshing {
+		c.refreshing = true
+		go c.refresh()
+	}
+	price, found := c.onDemand[instanceType]
+	return price, c.unused.hourlyDiscount(instanceType, price), found
+}
+
+// refresh fetches prices and unused commitments without holding the lock, keeping the previous values of
+// those that fail to be fetched.
+func (c *pricingCache) refresh() {
+	ttl := pricesTTL
+	onDemand, err := c.fetchOnDemandPrices()
+	if err != nil {
+		klog.Warningf("Failed to fetch on-demand prices of region %s: %v", c.region, err)
+		ttl = pricesRetryTTL
+	}
+	var unused *unusedCommitments
+	if c.costExplorer != nil {
+		unused, err = c.fetchUnusedCommitments()
+		if err != nil {
+			klog.Warningf("Failed to fetch unused Savings Plans and Reserved Instances commitment of region %s: %v", c.region, err)
+			ttl = pricesRetryTTL
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if onDemand != nil {
+		c.onDemand = onDemand
+	}
+	if unused != nil {
+		c.unused = unused
+	}
+	c.expiresAt = time.Now().Add(ttl)
+	c.refreshing = false
+}
+
+// priceListItem is the subset of the EC2 products of the Price List API used to find their on-demand price.
+type priceListItem struct {
+	Product struct {
+		Attributes struct {
+			InstanceType string `json:"instanceType"`
+		} `json:"attributes"`
+	} `json:"product"`
+	Terms struct {
+		OnDemand map[string]struct {
+			PriceDimensions map[string]struct {
+				PricePerUnit map[string]string `json:"pricePerUnit"`
+			} `json:"priceDimensions"`
+		} `json:"OnDemand"`
+	} `json:"terms"`
+}
+
+// fetchOnDemandPrices returns the hourly on-demand prices of the instance types of the region, for Linux
+// instances with shared tenancy and no pre-installed software.
+func (c *pricingCache) fetchOnDemandPrices() (map[string]float64, error) {
+	filters := []*pricing.Filter{}
+	for field, value := range map[string]string{
+		"regionCode":      c.region,
+		"operatingSystem": "Linux",
+		"tenancy":         "Shared",
+		"preInstalledSw":  "NA",
+		"capacitystatus":  "Used",
+		"licenseModel":    "No License required",
+	} {
+		filters = append(filters, &pricing.Filter{
+			Field: aws.String(field),
+			Type:  aws.String(pricing.FilterTypeTermMatch),
+			Value: aws.String(value),
+		})
+	}
+	input := &pricing.GetProductsInput{
+		ServiceCode: aws.String("AmazonEC2"),
+		Filters:     filters,
+	}
+
+	prices := make(map[string]float64)
+	for page := 0; page < maxPriceListPages; page++ {
+		start := time.Now()
+		out, err := c.pricing.GetProducts(input)
+		observeAWSRequest("GetProducts", err, start)
+		if err != nil {
+			return nil, err
+		}
+		for _, product := range out.PriceList {
+			instanceType, price, err := parsePriceListItem(product)
+			if err != nil {
+				klog.V(4).Infof("Skipping EC2 product: %v", err)
+				continue
+			}
+			prices[instanceType] = price
+		}
+		if aws.StringValue(out.NextToken) == "" {
+			break
+		}
+		input.NextToken = out.NextToken
+	}
+	if len(prices) == 0 {
+		return nil, fmt.Errorf("no on-demand price found")
+	}
+	return prices, nil
+}
+
+// parsePriceListItem returns the instance type and the hourly on-demand price in USD of an EC2 product.
+func parsePriceListItem(product aws.JSONValue) (string, float64, error) {
+	raw, err := json.Marshal(product)
+	if err != nil {
+		return "", 0, err
+	}
+	item := priceListItem{}
+	if err := json.Unmarshal(raw, &item); err != nil {
+		return "", 0, err
+	}
+	instanceType := item.Product.Attributes.InstanceType
+	if instanceType == "" {
+		return "", 0, fmt.Errorf("product without instance type")
+	}
+	for _, term := range item.Terms.OnDemand {
+		for _, dimension := range term.PriceDimensions {
+			usd, found := dimension.PricePerUnit["USD"]
+			if !found {
+				continue
+			}
+			price, err := strconv.ParseFloat(usd, 64)
+			if err != nil {
+				return "", 0, fmt.Errorf("invalid price %q of instance type %s: %v", usd, instanceType, err)
+			}
+			return instanceType, price, nil
+		}
+	}
+	return "", 0, fmt.Errorf("no on-demand USD price for instance type %s", instanceType)
+}
+
+// fetchUnusedCommitments returns the commitment of the Savings Plans and Reserved Instances of the region left
+// unused over the last week, per hour.
+func (c *pricingCache) fetchUnusedCommitments() (*unusedCommitments, error) {
+	now := time.Now()
+	period := &costexplorer.DateInterval{
+		Start: aws.String(now.Add(-utilizationLookback).Format(costExplorerDate)),
+		End:   aws.String(now.Format(costExplorerDate)),
+	}
+	hours := utilizationLookback.Hours()
+
+	unused := &unusedCommitments{
+		reservedInstances:    make(map[string]float64),
+		instanceSavingsPlans: make(map[string]float64),
+	}
+	if err := c.fetchUnusedSavingsPlans(period, hours, unused); err != nil {
+		return nil, fmt.Errorf("failed to get Savings Plans utilization: %v", err)
+	}
+	if err := c.fetchUnusedReservations(period, hours, unused); err != nil {
+		return nil, fmt.Errorf("failed to get Reserved Instances utilization: %v", err)
+	}
+	return unused, nil
+}
+
+// fetchUnusedSavingsPlans adds the unused hourly commitment of the Savings Plans applying to the region: Compute
+// Savings Plans, and the EC2 Instance Savings Plans of the region per instance family.
+func (c *pricingCache) fetchUnusedSavingsPlans(period *costexplorer.DateInterval, hours float64, unused *unusedCommitments) error {
+	input := &costexplorer.GetSavingsPlansUtilizationDetailsInput{TimePeriod: period}
+	for {
+		start := time.Now()
+		out, err := c.costExplorer.GetSavingsPlansUtilizationDetails(input)
+		observeAWSRequest("GetSavingsPlansUtilizationDetails", err, start)
+		if err != nil {
+			return err
+		}
+		for _, detail := range out.SavingsPlansUtilizationDetails {
+			if detail.Utilization == nil {
+				continue
+			}
+			commitment := parseAmount(detail.Utilization.UnusedCommitment) / hours
+			if aws.StringValue(detail.Attributes[savingsPlansTypeAttribute]) != instanceSavingsPlansType {
+				unused.computeSavingsPlans += commitment
+				continue
+			}
+			if aws.StringValue(detail.Attributes[savingsPlansRegionAttribute]) != c.region {
+				continue
+			}
+			if family := aws.StringValue(detail.Attributes[savingsPlansFamilyAttribute]); family != "" {
+				unused.instanceSavingsPlans[family] += commitment
+			}
+		}
+		if aws.StringValue(out.NextToken) == "" {
+			break
+		}
+		input.NextToken = out.NextToken
+	}
+	return nil
+}
+
+// fetchUnusedReservations adds the number of unused Reserved Instances of the region per instance type.
+func (c *pricingCache) fetchUnusedReservations(period *costexplorer.DateInterval, hours float64, unused *unusedCommitments) error {
+	input := &costexplorer.GetReservationUtilizationInput{
+		TimePeriod: period,
+		Filter: &costexplorer.Expression{
+			Dimensions: &costexplorer.DimensionValues{
+				Key:    aws.String(costexplorer.DimensionRegion),
+				Values: []*string{aws.String(c.region)},
+			},
+		},
+		GroupBy: []*costexplorer.GroupDefinition{{
+			Type: aws.String(costexplorer.GroupDefinitionTypeDimension),
+			Key:  aws.String("SUBSCRIPTION_ID"),
+		}},
+	}
+	for {
+		start := time.Now()
+		out, err := c.costExplorer.GetReservationUtilization(input)
+		observeAWSRequest("GetReservationUtilization", err, start)
+		if err != nil {
+			return err
+		}
+		for _, byTime := range out.UtilizationsByTime {
+			for _, group := range byTime.Groups {
+				instanceType := aws.StringValue(group.Attributes[reservationInstanceTypeAttribute])
+				if instanceType == "" || group.Utilization == nil {
+					continue
+				}
+				unused.reservedInstances[instanceType] += parseAmount(group.Utilization.UnusedHours) / hours
+			}
+		}
+		if aws.StringValue(out.NextPageToken) == "" {
+			break
+		}
+		input.NextPageToken = out.NextPageToken
+	}
+	return nil
+}
+
+func parseAmount(amount *string) float64 {
+	value, err := strconv.ParseFloat(aws.StringValue(amount), 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/aws"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/service/costexplorer"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/service/pricing"
+)
+
+type pricingMock struct {
+	mock.Mock
+}
+
+func (p *pricingMock) GetProducts(input *pricing.GetProductsInput) (*pricing.GetProductsOutput, error) {
+	args := p.Called(input)
+	return args.Get(0).(*pricing.GetProductsOutput), args.Error(1)
+}
+
+type costExplorerMock struct {
+	mock.Mock
+}
+
+func (c *costExplorerMock) GetSavingsPlansUtilizationDetails(input *costexplorer.GetSavingsPlansUtilizationDetailsInput) (*costexplorer.GetSavingsPlansUtilizationDetailsOutput, error) {
+	args := c.Called(input)
+	return args.Get(0).(*costexplorer.GetSavingsPlansUtilizationDetailsOutput), args.Error(1)
+}
+
+func (c *costExplorerMock) GetReservationUtilization(input *costexplorer.GetReservationUtilizationInput) (*costexplorer.GetReservationUtilizationOutput, error) {
+	args := c.Called(input)
+	return args.Get(0).(*costexplorer.GetReservationUtilizationOutput), args.Error(1)
+}
+
+func priceListProduct(instanceType, usd string) aws.JSONValue {
+	return aws.JSONValue{
+		"product": map[string]interface{}{
+			"attributes": map[string]interface{}{"instanceType": instanceType},
+		},
+		"terms": map[string]interface{}{
+			"OnDemand": map[string]interface{}{
+				"SKU.TERM": map[string]interface{}{
+					"priceDimensions": map[string]interface{}{
+						"SKU.TERM.RATE": map[string]interface{}{
+							"pricePerUnit": map[string]interface{}{"USD": usd},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func buildPricedNode(instanceType string, labels map[string]string) *apiv1.Node {
+	node := &apiv1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{apiv1.LabelInstanceTypeStable: instanceType},
+		},
+		Status: apiv1.NodeStatus{
+			Capacity: apiv1.ResourceList{
+				apiv1.ResourceCPU:    resource.MustParse("2"),
+				apiv1.ResourceMemory: resource.MustParse("8Gi"),
+			},
+		},
+	}
+	for k, v := range labels {
+		node.Labels[k] = v
+	}
+	return node
+}
+
+func TestNodePrice(t *testing.T) {
+	p := &pricingMock{}
+	p.On("GetProducts", mock.AnythingOfType("*pricing.GetProductsInput")).Return(&pricing.GetProductsOutput{
+		PriceList: []aws.JSONValue{
+			priceListProduct("m5.large", "0.0960000000"),
+			priceListProduct("c5.large", "0.0850000000"),
+		},
+		NextToken: aws.String("next"),
+	}, nil).Once()
+	p.On("GetProducts", mock.AnythingOfType("*pricing.GetProductsInput")).Return(&pricing.GetProductsOutput{
+		PriceList: []aws.JSONValue{priceListProduct("r5.large", "0.1260000000")},
+	}, nil).Once()
+
+	// Unused commitments are reported over a week, i.e. 168 hours.
+	ce := &costExplorerMock{}
+	ce.On("GetSavingsPlansUtilizationDetails", mock.AnythingOfType("*costexplorer.GetSavingsPlansUtilizationDetailsInput")).Return(&costexplorer.GetSavingsPlansUtilizationDetailsOutput{
+		SavingsPlansUtilizationDetails: []*costexplorer.SavingsPlansUtilizationDetail{
+			{
+				Attributes: map[string]*string{
+					savingsPlansTypeAttribute:   aws.String(instanceSavingsPlansType),
+					savingsPlansRegionAttribute: aws.String("us-east-1"),
+					savingsPlansFamilyAttribute: aws.String("m5"),
+				},
+				Utilization: &costexplorer.SavingsPlansUtilization{UnusedCommitment: aws.String("8.064")},
+			},
+			{
+				Attributes: map[string]*string{
+					savingsPlansTypeAttribute:   aws.String(instanceSavingsPlansType),
+					savingsPlansRegionAttribute: aws.String("eu-west-1"),
+					savingsPlansFamilyAttribute: aws.String("c5"),
+				},
+				Utilization: &costexplorer.SavingsPlansUtilization{UnusedCommitment: aws.String("100")},
+			},
+			{
+				Attributes:  map[string]*string{savingsPlansTypeAttribute: aws.String("ComputeSavingsPlans")},
+				Utilization: &costexplorer.SavingsPlansUtilization{UnusedCommitment: aws.String("1.68")},
+			},
+		},
+	}, nil).Once()
+	ce.On("GetReservationUtilization", mock.AnythingOfType("*costexplorer.GetReservationUtilizationInput")).Return(&costexplorer.GetReservationUtilizationOutput{
+		UtilizationsByTime: []*costexplorer.UtilizationByTime{
+			{
+				Groups: []*costexplorer.ReservationUtilizationGroup{
+					{
+						Attributes:  map[string]*string{reservationInstanceTypeAttribute: aws.String("r5.large")},
+						Utilization: &costexplorer.ReservationAggregates{UnusedHours: aws.String("336")},
+					},
+					{
+						// fully used reserved instances don't discount new nodes.
+						Attributes:  map[string]*string{reservationInstanceTypeAttribute: aws.String("c5.large")},
+						Utilization: &costexplorer.ReservationAggregates{UnusedHours: aws.String("0")},
+					},
+				},
+			},
+		},
+	}, nil).Once()
+
+	model := NewAwsPriceModel(p, ce, "us-east-1")
+	model.prices.refresh()
+	now := time.Now()
+	hour := now.Add(time.Hour)
+
+	for _, tc := range []struct {
+		desc     string
+		node     *apiv1.Node
+		expected float64
+	}{
+		{
+			desc:     "unused compute savings plans",
+			node:     buildPricedNode("c5.large", nil),
+			expected: 0.085 - 0.01,
+		},
+		{
+			desc:     "unused compute and instance savings plans",
+			node:     buildPricedNode("m5.large", nil),
+			expected: 0.096 - 0.048 - 0.01,
+		},
+		{
+			desc:     "unused reserved instances",
+			node:     buildPricedNode("r5.large", nil),
+			expected: 0,
+		},
+		{
+			desc:     "spot instances aren't covered",
+			node:     buildPricedNode("m5.large", map[string]string{capacityTypeLabel: capacityTypeSpot}),
+			expected: 0.096 * spotDiscount,
+		},
+		{
+			desc:     "unknown instance type",
+			node:     buildPricedNode("x9.large", nil),
+			expected: 2*cpuPricePerHour + 8*memoryPricePerHourPerGb,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			price, err := model.NodePrice(tc.node, now, hour)
+			assert.NoError(t, err)
+			assert.InDelta(t, tc.expected, price, 1e-9)
+		})
+	}
+	// prices are fetched once for all instance types.
+	p.AssertExpectations(t)
+	ce.AssertExpectations(t)
+}
+
+func TestNodePriceRefreshedInBackground(t *testing.T) {
+	p := &pricingMock{}
+	p.On("GetProducts", mock.AnythingOfType("*pricing.GetProductsInput")).Return(&pricing.GetProductsOutput{
+		PriceList: []aws.JSONValue{priceListProduct("m5.large", "0.0960000000")},
+	}, nil).Once()
+
+	model := NewAwsPriceModel(p, nil, "us-east-1")
+	now := time.Now()
+	node := buildPricedNode("m5.large", nil)
+
+	// the first call doesn't wait for prices to be fetched.
+	price, err := model.NodePrice(node, now, now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.InDelta(t, 2*cpuPricePerHour+8*memoryPricePerHourPerGb, price, 1e-9)
+
+	assert.Eventually(t, func() bool {
+		price, err := model.NodePrice(node, now, now.Add(time.Hour))
+		return err == nil && math.Abs(price-0.096) < 1e-9
+	}, time.Second, 10*time.Millisecond)
+	p.AssertExpectations(t)
+}
+
+func TestNodePriceFallback(t *testing.T) {
+	p := &pricingMock{}
+	p.On("GetProducts", mock.AnythingOfType("*pricing.GetProductsInput")).Return(&pricing.GetProductsOutput{}, errors.New("access denied")).Once()
+
+	model := NewAwsPriceModel(p, nil, "us-east-1")
+	model.prices.refresh()
+	now := time.Now()
+	price, err := model.NodePrice(buildPricedNode("m5.large", nil), now, now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.InDelta(t, 2*cpuPricePerHour+8*memoryPricePerHourPerGb, price, 1e-9)
+
+	// failed refreshes are retried later, not on every call.
+	_, err = model.NodePrice(buildPricedNode("m5.large", nil), now, now.Add(time.Hour))
+	assert.NoError(t, err)
+	p.AssertExpectations(t)
+}
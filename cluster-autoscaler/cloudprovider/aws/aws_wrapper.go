@@ -34,6 +34,7 @@ type autoScalingI interface {
 	DescribeAutoScalingGroupsPages(input *autoscaling.DescribeAutoScalingGroupsInput, fn func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool) error
 	DescribeLaunchConfigurations(*autoscaling.DescribeLaunchConfigurationsInput) (*autoscaling.DescribeLaunchConfigurationsOutput, error)
 	DescribeScalingActivities(*autoscaling.DescribeScalingActivitiesInput) (*autoscaling.DescribeScalingActivitiesOutput, error)
+	DetachInstances(input *autoscaling.DetachInstancesInput) (*autoscaling.DetachInstancesOutput, error)
 	SetDesiredCapacity(input *autoscaling.SetDesiredCapacityInput) (*autoscaling.SetDesiredCapacityOutput, error)
 	TerminateInstanceInAutoScalingGroup(input *autoscaling.TerminateInstanceInAutoScalingGroupInput) (*autoscaling.TerminateInstanceInAutoScalingGroupOutput, error)
 }
@@ -43,11 +44,13 @@ type ec2I interface {
 	DescribeImages(input *ec2.DescribeImagesInput) (*ec2.DescribeImagesOutput, error)
 	DescribeLaunchTemplateVersions(input *ec2.DescribeLaunchTemplateVersionsInput) (*ec2.DescribeLaunchTemplateVersionsOutput, error)
 	GetInstanceTypesFromInstanceRequirementsPages(input *ec2.GetInstanceTypesFromInstanceRequirementsInput, fn func(*ec2.GetInstanceTypesFromInstanceRequirementsOutput, bool) bool) error
+	TerminateInstances(input *ec2.TerminateInstancesInput) (*ec2.TerminateInstancesOutput, error)
 }
 
 // eksI is the interface that represents a specific aspect of EKS (Elastic Kubernetes Service) which is provided by AWS SDK for use in CA
 type eksI interface {
 	DescribeNodegroup(input *eks.DescribeNodegroupInput) (*eks.DescribeNodegroupOutput, error)
+	UpdateNodegroupConfig(input *eks.UpdateNodegroupConfigInput) (*eks.UpdateNodegroupConfigOutput, error)
 }
 
 // awsWrapper provides several utility methods over the services provided by the AWS SDK
@@ -300,7 +303,45 @@ func (m *awsWrapper) getLaunchTemplateData(templateName string, templateVersion
 	return describeData.LaunchTemplateVersions[0].LaunchTemplateData, nil
 }
 
+// getInstanceTypesMatchingRequirements returns every instance type matching the instance requirements
+// of a mixed instances policy, set either as overrides or in the policy's launch template.
+func (m *awsWrapper) getInstanceTypesMatchingRequirements(policy *mixedInstancesPolicy) ([]string, error) {
+	if policy.launchTemplate == nil {
+		return nil, fmt.Errorf("no launch template found for mixed instances policy")
+	}
+
+	templateData, err := m.getLaunchTemplateData(policy.launchTemplate.name, policy.launchTemplate.version)
+	if err != nil {
+		return nil, err
+	}
+	if templateData.ImageId == nil {
+		return nil, fmt.Errorf("no image found for launch template %s", policy.launchTemplate.name)
+	}
+
+	var requirements *ec2.InstanceRequirementsRequest
+	if policy.instanceRequirementsOverrides != nil {
+		requirements, err = m.getRequirementsRequestFromAutoscaling(policy.instanceRequirementsOverrides)
+	} else if templateData.InstanceRequirements != nil {
+		requirements, err = m.getRequirementsRequestFromEC2(templateData.InstanceRequirements)
+	} else {
+		return nil, fmt.Errorf("no instance requirements found for mixed instances policy")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return m.getInstanceTypesFromInstanceRequirements(*templateData.ImageId, requirements)
+}
+
 func (m *awsWrapper) getInstanceTypeFromInstanceRequirements(imageId string, requirementsRequest *ec2.InstanceRequirementsRequest) (string, error) {
+	instanceTypes, err := m.getInstanceTypesFromInstanceRequirements(imageId, requirementsRequest)
+	if err != nil {
+		return "", err
+	}
+	return instanceTypes[0], nil
+}
+
+func (m *awsWrapper) getInstanceTypesFromInstanceRequirements(imageId string, requirementsRequest *ec2.InstanceRequirementsRequest) ([]string, error) {
 	describeImagesInput := &ec2.DescribeImagesInput{
 		ImageIds: []*string{aws.String(imageId)},
 	}
@@ -309,7 +350,7 @@ func (m *awsWrapper) getInstanceTypeFromInstanceRequirements(imageId string, req
 	describeImagesOutput, err := m.DescribeImages(describeImagesInput)
 	observeAWSRequest("DescribeImages", err, start)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	imageArchitectures := []*string{}
@@ -335,13 +376,13 @@ func (m *awsWrapper) getInstanceTypeFromInstanceRequirements(imageId string, req
 	})
 	observeAWSRequest("GetInstanceTypesFromInstanceRequirements", err, start)
 	if err != nil {
-		return "", fmt.Errorf("unable to get instance types from requirements: %w", err)
+		return nil, fmt.Errorf("unable to get instance types from requirements: %w", err)
 	}
 
 	if len(instanceTypes) == 0 {
-		return "", fmt.Errorf("no instance types found for requirements")
+		return nil, fmt.Errorf("no instance types found for requirements")
 	}
-	return instanceTypes[0], nil
+	return instanceTypes, nil
 }
 
 func (m *awsWrapper) getRequirementsRequestFromAutoscaling(requirements *autoscaling.InstanceRequirements) (*ec2.InstanceRequirementsRequest, error) {
@@ -680,6 +721,7 @@ func (m *awsWrapper) getEC2RequirementsFromAutoscaling(autoscalingRequirements *
 	return &ec2Requirements, nil
 }
 
+// getInstanceTypesForAsgs returns the instance types of the given ASGs, keyed by the ids of their references.
 func (m *awsWrapper) getInstanceTypesForAsgs(asgs []*asg) (map[string]string, error) {
 	results := map[string]string{}
 	launchConfigsToQuery := map[string]string{}
@@ -687,7 +729,7 @@ func (m *awsWrapper) getInstanceTypesForAsgs(asgs []*asg) (map[string]string, er
 	mixedInstancesPoliciesToQuery := map[string]*mixedInstancesPolicy{}
 
 	for _, asg := range asgs {
-		name := asg.AwsRef.Name
+		name := asg.AwsRef.id()
 		if asg.LaunchConfigurationName != "" {
 			launchConfigsToQuery[name] = asg.LaunchConfigurationName
 		} else if asg.LaunchTemplate != nil {
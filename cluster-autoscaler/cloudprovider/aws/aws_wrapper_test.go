@@ -50,6 +50,11 @@ func (a *autoScalingMock) DescribeScalingActivities(i *autoscaling.DescribeScali
 	return args.Get(0).(*autoscaling.DescribeScalingActivitiesOutput), args.Error(1)
 }
 
+func (a *autoScalingMock) DetachInstances(input *autoscaling.DetachInstancesInput) (*autoscaling.DetachInstancesOutput, error) {
+	args := a.Called(input)
+	return args.Get(0).(*autoscaling.DetachInstancesOutput), args.Error(1)
+}
+
 func (a *autoScalingMock) SetDesiredCapacity(input *autoscaling.SetDesiredCapacityInput) (*autoscaling.SetDesiredCapacityOutput, error) {
 	args := a.Called(input)
 	return args.Get(0).(*autoscaling.SetDesiredCapacityOutput), nil
@@ -79,6 +84,11 @@ func (e *ec2Mock) GetInstanceTypesFromInstanceRequirementsPages(input *ec2.GetIn
 	return args.Error(0)
 }
 
+func (e *ec2Mock) TerminateInstances(input *ec2.TerminateInstancesInput) (*ec2.TerminateInstancesOutput, error) {
+	args := e.Called(input)
+	return args.Get(0).(*ec2.TerminateInstancesOutput), args.Error(1)
+}
+
 type eksMock struct {
 	mock.Mock
 }
@@ -97,6 +107,11 @@ func (k *eksMock) DescribeNodegroup(i *eks.DescribeNodegroupInput) (*eks.Describ
 	}
 }
 
+func (k *eksMock) UpdateNodegroupConfig(i *eks.UpdateNodegroupConfigInput) (*eks.UpdateNodegroupConfigOutput, error) {
+	args := k.Called(i)
+	return args.Get(0).(*eks.UpdateNodegroupConfigOutput), args.Error(1)
+}
+
 var testAwsService = awsWrapper{&autoScalingMock{}, &ec2Mock{}, &eksMock{}}
 
 func TestGetManagedNodegroup(t *testing.T) {
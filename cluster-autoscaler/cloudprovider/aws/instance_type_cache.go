@@ -36,8 +36,9 @@ const (
 // This allows to get a better repartition of the AWS queries.
 type instanceTypeExpirationStore struct {
 	cache.Store
-	jitterClock clock.Clock
-	awsService  *awsWrapper
+	jitterClock  clock.Clock
+	awsService   *awsWrapper
+	assumedRoles *assumedRoleServices
 }
 
 type instanceTypeCachedObject struct {
@@ -68,9 +69,9 @@ func newAsgInstanceTypeCache(awsService *awsWrapper) *instanceTypeExpirationStor
 
 func newAsgInstanceTypeCacheWithClock(awsService *awsWrapper, jc clock.Clock, store cache.Store) *instanceTypeExpirationStore {
 	return &instanceTypeExpirationStore{
-		store,
-		jc,
-		awsService,
+		Store:       store,
+		jitterClock: jc,
+		awsService:  awsService,
 	}
 }
 
@@ -85,7 +86,7 @@ func (c *jitterClock) Since(ts time.Time) time.Duration {
 }
 
 func (es instanceTypeExpirationStore) populate(autoscalingGroups map[AwsRef]*asg) error {
-	asgsToQuery := []*asg{}
+	asgsToQuery := map[string][]*asg{}
 
 	if c, ok := es.jitterClock.(*jitterClock); ok {
 		c.Lock()
@@ -97,11 +98,11 @@ func (es instanceTypeExpirationStore) populate(autoscalingGroups map[AwsRef]*asg
 		if asg == nil {
 			continue
 		}
-		_, found, _ := es.GetByKey(asg.AwsRef.Name)
+		_, found, _ := es.GetByKey(asg.AwsRef.id())
 		if found {
 			continue
 		}
-		asgsToQuery = append(asgsToQuery, asg)
+		asgsToQuery[asg.RoleARN] = append(asgsToQuery[asg.RoleARN], asg)
 	}
 
 	if c, ok := es.jitterClock.(*jitterClock); ok {
@@ -113,16 +114,22 @@ func (es instanceTypeExpirationStore) populate(autoscalingGroups map[AwsRef]*asg
 	// List expires old entries
 	_ = es.List()
 
-	instanceTypesByAsg, err := es.awsService.getInstanceTypesForAsgs(asgsToQuery)
-	if err != nil {
-		return err
-	}
+	for roleARN, asgs := range asgsToQuery {
+		awsService, err := es.assumedRoles.serviceFor(es.awsService, roleARN)
+		if err != nil {
+			return err
+		}
+		instanceTypesByAsg, err := awsService.getInstanceTypesForAsgs(asgs)
+		if err != nil {
+			return err
+		}
 
-	for asgName, instanceType := range instanceTypesByAsg {
-		es.Add(instanceTypeCachedObject{
-			name:         asgName,
-			instanceType: instanceType,
-		})
+		for asgID, instanceType := range instanceTypesByAsg {
+			es.Add(instanceTypeCachedObject{
+				name:         asgID,
+				instanceType: instanceType,
+			})
+		}
 	}
 	return nil
 }
@@ -18,8 +18,8 @@ package azure
 
 import (
 	"fmt"
-	"math/rand"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -27,6 +27,7 @@ import (
 	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2022-08-01/compute"
 	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2017-05-10/resources"
 	azStorage "github.com/Azure/azure-sdk-for-go/storage"
+	"github.com/Azure/go-autorest/autorest/azure"
 	"github.com/Azure/go-autorest/autorest/to"
 
 	apiv1 "k8s.io/api/core/v1"
@@ -34,6 +35,7 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	"k8s.io/autoscaler/cluster-autoscaler/config"
 	"k8s.io/autoscaler/cluster-autoscaler/config/dynamic"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
 	klog "k8s.io/klog/v2"
 	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
 )
@@ -57,6 +59,14 @@ type AgentPool struct {
 	mutex       sync.Mutex
 	lastRefresh time.Time
 	curSize     int64
+	// deployments are the scale-up deployments in progress, and the failed ones until the VMs they didn't
+	// create are deleted, by name.
+	deployments map[string]*agentPoolDeployment
+	// faultDomains are the platform fault domains of the VMs of the pool, by lowercased VM name. A VM never
+	// changes fault domain, so they are only fetched once per VM.
+	faultDomains map[string]int32
+	// faultDomainCount is the number of fault domains of the pool's availability set, 0 until fetched.
+	faultDomainCount int32
 }
 
 // NewAgentPool creates a new AgentPool.
@@ -65,10 +75,12 @@ func NewAgentPool(spec *dynamic.NodeGroupSpec, az *AzureManager) (*AgentPool, er
 		azureRef: azureRef{
 			Name: spec.Name,
 		},
-		minSize: spec.MinSize,
-		maxSize: spec.MaxSize,
-		manager: az,
-		curSize: -1,
+		minSize:      spec.MinSize,
+		maxSize:      spec.MaxSize,
+		manager:      az,
+		curSize:      -1,
+		deployments:  make(map[string]*agentPoolDeployment),
+		faultDomains: make(map[string]int32),
 	}
 
 	if err := as.initialize(); err != nil {
@@ -146,13 +158,13 @@ func (as *AgentPool) getVMsFromCache() ([]compute.VirtualMachine, error) {
 
 // GetVMIndexes gets indexes of all virtual machines belonging to the agent pool.
 func (as *AgentPool) GetVMIndexes() ([]int, map[int]string, error) {
-	klog.V(6).Infof("GetVMIndexes: starts for as %v", as)
+	klogx.ProviderAzure.V(6).Infof("GetVMIndexes: starts for as %v", as)
 
 	instances, err := as.getVMsFromCache()
 	if err != nil {
 		return nil, nil, err
 	}
-	klog.V(6).Infof("GetVMIndexes: got instances, length = %d", len(instances))
+	klogx.ProviderAzure.V(6).Infof("GetVMIndexes: got instances, length = %d", len(instances))
 
 	indexes := make([]int, 0)
 	indexToVM := make(map[int]string)
@@ -183,19 +195,21 @@ func (as *AgentPool) getCurSize() (int64, error) {
 		return as.curSize, nil
 	}
 
-	klog.V(5).Infof("Get agent pool size for %q", as.Name)
+	klogx.ProviderAzure.V(5).Infof("Get agent pool size for %q", as.Name)
 	indexes, _, err := as.GetVMIndexes()
 	if err != nil {
 		return 0, err
 	}
-	klog.V(5).Infof("Returning agent pool (%q) size: %d\n", as.Name, len(indexes))
+	as.forgetFailedDeployments(indexes)
+	size := len(indexes) + as.pendingInstanceCount(indexes)
+	klogx.ProviderAzure.V(5).Infof("Returning agent pool (%q) size: %d\n", as.Name, size)
 
-	if as.curSize != int64(len(indexes)) {
-		klog.V(6).Infof("getCurSize:as.curSize(%d) != real size (%d), invalidating cache", as.curSize, len(indexes))
+	if as.curSize != int64(size) {
+		klogx.ProviderAzure.V(6).Infof("getCurSize:as.curSize(%d) != real size (%d), invalidating cache", as.curSize, size)
 		as.manager.invalidateCache()
 	}
 
-	as.curSize = int64(len(indexes))
+	as.curSize = int64(size)
 	as.lastRefresh = time.Now()
 	return as.curSize, nil
 }
@@ -235,14 +249,14 @@ func (as *AgentPool) deleteOutdatedDeployments() (err error) {
 	}
 
 	for i := len(deployments) - 1; i >= 0; i-- {
-		klog.V(4).Infof("deleteOutdatedDeployments: found deployments[i].Name: %s", *deployments[i].Name)
+		klogx.ProviderAzure.V(4).Infof("deleteOutdatedDeployments: found deployments[i].Name: %s", *deployments[i].Name)
 		if deployments[i].Name != nil && !strings.HasPrefix(*deployments[i].Name, clusterAutoscalerDeploymentPrefix) {
 			deployments = append(deployments[:i], deployments[i+1:]...)
 		}
 	}
 
 	if int64(len(deployments)) <= as.manager.config.MaxDeploymentsCount {
-		klog.V(4).Infof("deleteOutdatedDeployments: the number of deployments (%d) is under threshold, skip deleting", len(deployments))
+		klogx.ProviderAzure.V(4).Infof("deleteOutdatedDeployments: the number of deployments (%d) is under threshold, skip deleting", len(deployments))
 		return err
 	}
 
@@ -257,7 +271,7 @@ func (as *AgentPool) deleteOutdatedDeployments() (err error) {
 
 	errList := make([]error, 0)
 	for _, deployment := range toBeDeleted {
-		klog.V(4).Infof("deleteOutdatedDeployments: starts deleting outdated deployment (%s)", *deployment.Name)
+		klogx.ProviderAzure.V(4).Infof("deleteOutdatedDeployments: starts deleting outdated deployment (%s)", *deployment.Name)
 		_, err := as.manager.azClient.deploymentsClient.Delete(ctx, as.manager.config.ResourceGroup, *deployment.Name)
 		if err != nil {
 			errList = append(errList, err)
@@ -267,7 +281,8 @@ func (as *AgentPool) deleteOutdatedDeployments() (err error) {
 	return utilerrors.NewAggregate(errList)
 }
 
-// IncreaseSize increases agent pool size
+// IncreaseSize increases agent pool size. The VMs are created by a deployment running in the background,
+// without waiting for the deployments of previous scale-ups.
 func (as *AgentPool) IncreaseSize(delta int) error {
 	as.mutex.Lock()
 	defer as.mutex.Unlock()
@@ -280,12 +295,7 @@ func (as *AgentPool) IncreaseSize(delta int) error {
 		return fmt.Errorf("size increase must be positive")
 	}
 
-	err := as.deleteOutdatedDeployments()
-	if err != nil {
-		klog.Warningf("IncreaseSize: failed to cleanup outdated deployments with err: %v.", err)
-	}
-
-	klog.V(6).Infof("IncreaseSize: invalidating cache")
+	klogx.ProviderAzure.V(6).Infof("IncreaseSize: invalidating cache")
 	as.manager.invalidateCache()
 
 	indexes, _, err := as.GetVMIndexes()
@@ -293,46 +303,18 @@ func (as *AgentPool) IncreaseSize(delta int) error {
 		return err
 	}
 
-	curSize := len(indexes)
+	curSize := len(indexes) + as.pendingInstanceCount(indexes)
 	if curSize+delta > as.MaxSize() {
 		return fmt.Errorf("size increase too large - desired:%d max:%d", curSize+delta, as.MaxSize())
 	}
 
-	highestUsedIndex := indexes[len(indexes)-1]
-	expectedSize := curSize + delta
-	countForTemplate := expectedSize
-	if highestUsedIndex != 0 {
-		countForTemplate += highestUsedIndex + 1 - curSize
-	}
-	as.parameters[as.Name+"Count"] = map[string]int{"value": countForTemplate}
-	as.parameters[as.Name+"Offset"] = map[string]int{"value": highestUsedIndex + 1}
+	deployment := as.startDeployment(indexes, delta)
+	klogx.ProviderAzure.V(3).Infof("IncreaseSize: started deployment %q creating %d VMs from index %d for agent pool %q", deployment.name, delta, deployment.offset, as.Name)
 
-	newDeploymentName := fmt.Sprintf("cluster-autoscaler-%d", rand.New(rand.NewSource(time.Now().UnixNano())).Int31())
-	newDeployment := resources.Deployment{
-		Properties: &resources.DeploymentProperties{
-			Template:   &as.template,
-			Parameters: &as.parameters,
-			Mode:       resources.Incremental,
-		},
-	}
-	ctx, cancel := getContextWithCancel()
-	defer cancel()
-	klog.V(3).Infof("Waiting for deploymentsClient.CreateOrUpdate(%s, %s, %v)", as.manager.config.ResourceGroup, newDeploymentName, newDeployment)
-	resp, err := as.manager.azClient.deploymentsClient.CreateOrUpdate(ctx, as.manager.config.ResourceGroup, newDeploymentName, newDeployment)
-	isSuccess, realError := isSuccessHTTPResponse(resp, err)
-	if isSuccess {
-		klog.V(3).Infof("deploymentsClient.CreateOrUpdate(%s, %s, %v) success", as.manager.config.ResourceGroup, newDeploymentName, newDeployment)
-
-		// Update cache after scale success.
-		as.curSize = int64(expectedSize)
-		as.lastRefresh = time.Now()
-		klog.V(6).Info("IncreaseSize: invalidating cache")
-		as.manager.invalidateCache()
-		return nil
-	}
-
-	klog.Errorf("deploymentsClient.CreateOrUpdate for deployment %q failed: %v", newDeploymentName, realError)
-	return realError
+	// Proactively set the size so autoscaler makes better decisions.
+	as.curSize = int64(curSize + delta)
+	as.lastRefresh = time.Now()
+	return nil
 }
 
 // DecreaseTargetSize decreases the target size of the node group. This function
@@ -362,7 +344,7 @@ func (as *AgentPool) DecreaseTargetSize(delta int) error {
 
 // Belongs returns true if the given node belongs to the NodeGroup.
 func (as *AgentPool) Belongs(node *apiv1.Node) (bool, error) {
-	klog.V(6).Infof("Check if node belongs to this agent pool: AgentPool:%v, node:%v\n", as, node)
+	klogx.ProviderAzure.V(6).Infof("Check if node belongs to this agent pool: AgentPool:%v, node:%v\n", as, node)
 
 	ref := &azureRef{
 		Name: node.Spec.ProviderID,
@@ -417,14 +399,17 @@ func (as *AgentPool) DeleteInstances(instances []*azureRef) error {
 		}
 	}
 
-	klog.V(6).Infof("DeleteInstances: invalidating cache")
+	klogx.ProviderAzure.V(6).Infof("DeleteInstances: invalidating cache")
 	as.manager.invalidateCache()
 	return nil
 }
 
 // DeleteNodes deletes the nodes from the group.
 func (as *AgentPool) DeleteNodes(nodes []*apiv1.Node) error {
-	klog.V(6).Infof("Delete nodes requested: %v\n", nodes)
+	klogx.ProviderAzure.V(6).Infof("Delete nodes requested: %v\n", nodes)
+	if nodes = as.deleteFailedDeploymentInstances(nodes); len(nodes) == 0 {
+		return nil
+	}
 	indexes, _, err := as.GetVMIndexes()
 	if err != nil {
 		return err
@@ -451,22 +436,146 @@ func (as *AgentPool) DeleteNodes(nodes []*apiv1.Node) error {
 		refs = append(refs, ref)
 	}
 
-	err = as.deleteOutdatedDeployments()
-	if err != nil {
-		klog.Warningf("DeleteNodes: failed to cleanup outdated deployments with err: %v.", err)
-	}
-
+	as.garbageCollectDeployments()
 	return as.DeleteInstances(refs)
 }
 
+// ForceDeleteNodes deletes nodes from the group regardless of constraints.
+func (as *AgentPool) ForceDeleteNodes(nodes []*apiv1.Node) error {
+	return cloudprovider.ErrNotImplemented
+}
+
 // Debug returns a debug string for the agent pool.
 func (as *AgentPool) Debug() string {
 	return fmt.Sprintf("%s (%d:%d)", as.Name, as.MinSize(), as.MaxSize())
 }
 
-// TemplateNodeInfo returns a node template for this agent pool.
+// TemplateNodeInfo returns a node template for this agent pool, built from one of its VMs. VMs of the pool
+// are created in an availability set, so like the cloud provider does for nodes outside of availability
+// zones, the template is labeled with the fault domain the next VM is expected to be placed in.
 func (as *AgentPool) TemplateNodeInfo() (*schedulerframework.NodeInfo, error) {
-	return nil, cloudprovider.ErrNotImplemented
+	vms, err := as.getVMsFromCache()
+	if err != nil || len(vms) == 0 {
+		return nil, cloudprovider.ErrNotImplemented
+	}
+
+	node, err := buildNodeFromTemplate(as.Name, scaleSetTemplateFromVM(vms[0]), as.manager)
+	if err != nil {
+		return nil, err
+	}
+	if vms[0].Zones == nil || len(*vms[0].Zones) == 0 {
+		faultDomain, err := as.nextFaultDomain(vms)
+		if err != nil {
+			klog.Warningf("Failed to find the next fault domain of agent pool %s: %v", as.Name, err)
+		} else {
+			node.Labels[apiv1.LabelTopologyZone] = strconv.Itoa(int(faultDomain))
+		}
+	}
+
+	nodeInfo := schedulerframework.NewNodeInfo(cloudprovider.BuildKubeProxy(as.Name))
+	nodeInfo.SetNode(node)
+	return nodeInfo, nil
+}
+
+// scaleSetTemplateFromVM returns a scale set template describing VMs like the given one.
+func scaleSetTemplateFromVM(vm compute.VirtualMachine) compute.VirtualMachineScaleSet {
+	template := compute.VirtualMachineScaleSet{
+		Location: vm.Location,
+		Zones:    vm.Zones,
+		Tags:     vm.Tags,
+		Sku:      &compute.Sku{Name: to.StringPtr("")},
+		VirtualMachineScaleSetProperties: &compute.VirtualMachineScaleSetProperties{
+			VirtualMachineProfile: &compute.VirtualMachineScaleSetVMProfile{},
+		},
+	}
+	if template.Location == nil {
+		template.Location = to.StringPtr("")
+	}
+	if vm.VirtualMachineProperties == nil {
+		return template
+	}
+	if vm.HardwareProfile != nil {
+		template.Sku.Name = to.StringPtr(string(vm.HardwareProfile.VMSize))
+	}
+	profile := template.VirtualMachineProfile
+	if vm.OsProfile != nil {
+		profile.OsProfile = &compute.VirtualMachineScaleSetOSProfile{WindowsConfiguration: vm.OsProfile.WindowsConfiguration}
+	}
+	if vm.StorageProfile != nil && vm.StorageProfile.OsDisk != nil {
+		profile.StorageProfile = &compute.VirtualMachineScaleSetStorageProfile{
+			OsDisk: &compute.VirtualMachineScaleSetOSDisk{
+				DiskSizeGB:       vm.StorageProfile.OsDisk.DiskSizeGB,
+				DiffDiskSettings: vm.StorageProfile.OsDisk.DiffDiskSettings,
+			},
+		}
+	}
+	profile.SecurityProfile = vm.SecurityProfile
+	profile.Priority = vm.Priority
+	return template
+}
+
+// nextFaultDomain returns the fault domain of the pool's availability set the next VM is expected to be placed
+// in. Azure spreads VMs evenly across fault domains, so this is the least populated one.
+func (as *AgentPool) nextFaultDomain(vms []compute.VirtualMachine) (int32, error) {
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+
+	ctx, cancel := getContextWithCancel()
+	defer cancel()
+
+	if as.faultDomainCount == 0 {
+		var availabilitySetID string
+		for _, vm := range vms {
+			if vm.VirtualMachineProperties != nil && vm.AvailabilitySet != nil && vm.AvailabilitySet.ID != nil {
+				availabilitySetID = *vm.AvailabilitySet.ID
+				break
+			}
+		}
+		if availabilitySetID == "" {
+			return 0, fmt.Errorf("VMs aren't in an availability set")
+		}
+		resource, err := azure.ParseResourceID(availabilitySetID)
+		if err != nil {
+			return 0, err
+		}
+		availabilitySet, rerr := as.manager.azClient.availabilitySetsClient.Get(ctx, resource.ResourceGroup, resource.ResourceName)
+		if rerr != nil {
+			return 0, rerr.Error()
+		}
+		if availabilitySet.AvailabilitySetProperties == nil || availabilitySet.PlatformFaultDomainCount == nil {
+			return 0, fmt.Errorf("availability set %s has no fault domain count", resource.ResourceName)
+		}
+		as.faultDomainCount = *availabilitySet.PlatformFaultDomainCount
+	}
+
+	listed := make(map[string]bool, len(vms))
+	counts := make(map[int32]int)
+	for _, vm := range vms {
+		if vm.Name == nil {
+			continue
+		}
+		name := strings.ToLower(*vm.Name)
+		listed[name] = true
+		faultDomain, found := as.faultDomains[name]
+		if !found {
+			withInstanceView, rerr := as.manager.azClient.virtualMachinesClient.Get(ctx, as.manager.config.ResourceGroup, *vm.Name, compute.InstanceViewTypesInstanceView)
+			if rerr != nil {
+				return 0, rerr.Error()
+			}
+			if withInstanceView.VirtualMachineProperties == nil || withInstanceView.InstanceView == nil || withInstanceView.InstanceView.PlatformFaultDomain == nil {
+				continue
+			}
+			faultDomain = *withInstanceView.InstanceView.PlatformFaultDomain
+			as.faultDomains[name] = faultDomain
+		}
+		counts[faultDomain]++
+	}
+	for name := range as.faultDomains {
+		if !listed[name] {
+			delete(as.faultDomains, name)
+		}
+	}
+	return leastPopulatedDomain(counts, as.faultDomainCount), nil
 }
 
 // Nodes returns a list of all nodes that belong to this node group.
@@ -491,6 +600,16 @@ func (as *AgentPool) Nodes() ([]cloudprovider.Instance, error) {
 		nodes = append(nodes, cloudprovider.Instance{Id: resourceID})
 	}
 
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+	if len(as.deployments) > 0 {
+		indexes, _, err := as.GetVMIndexes()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, as.failedDeploymentInstances(indexes)...)
+	}
+
 	return nodes, nil
 }
 
@@ -498,7 +617,9 @@ func (as *AgentPool) deleteBlob(accountName, vhdContainer, vhdBlob string) error
 	ctx, cancel := getContextWithCancel()
 	defer cancel()
 
+	start := time.Now()
 	storageKeysResult, rerr := as.manager.azClient.storageAccountsClient.ListKeys(ctx, as.manager.config.SubscriptionID, as.manager.config.ResourceGroup, accountName)
+	observeARMRequest("StorageAccounts.ListKeys", start, rerr)
 	if rerr != nil {
 		return rerr.Error()
 	}
@@ -521,10 +642,12 @@ func (as *AgentPool) deleteVirtualMachine(name string) error {
 	ctx, cancel := getContextWithCancel()
 	defer cancel()
 
+	start := time.Now()
 	vm, rerr := as.manager.azClient.virtualMachinesClient.Get(ctx, as.manager.config.ResourceGroup, name, "")
+	observeARMRequest("VirtualMachines.Get", start, rerr)
 	if rerr != nil {
 		if exists, _ := checkResourceExistsFromRetryError(rerr); !exists {
-			klog.V(2).Infof("VirtualMachine %s/%s has already been removed", as.manager.config.ResourceGroup, name)
+			klogx.ProviderAzure.V(2).Infof("VirtualMachine %s/%s has already been removed", as.manager.config.ResourceGroup, name)
 			return nil
 		}
 
@@ -557,24 +680,28 @@ func (as *AgentPool) deleteVirtualMachine(name string) error {
 	defer deleteCancel()
 
 	klog.Infof("waiting for VirtualMachine deletion: %s/%s", as.manager.config.ResourceGroup, name)
+	start = time.Now()
 	rerr = as.manager.azClient.virtualMachinesClient.Delete(deleteCtx, as.manager.config.ResourceGroup, name)
+	observeARMRequest("VirtualMachines.Delete", start, rerr)
 	_, realErr := checkResourceExistsFromRetryError(rerr)
 	if realErr != nil {
 		return realErr
 	}
-	klog.V(2).Infof("VirtualMachine %s/%s removed", as.manager.config.ResourceGroup, name)
+	klogx.ProviderAzure.V(2).Infof("VirtualMachine %s/%s removed", as.manager.config.ResourceGroup, name)
 
 	if len(nicName) > 0 {
 		klog.Infof("deleting nic: %s/%s", as.manager.config.ResourceGroup, nicName)
 		interfaceCtx, interfaceCancel := getContextWithCancel()
 		defer interfaceCancel()
+		start = time.Now()
 		rerr := as.manager.azClient.interfacesClient.Delete(interfaceCtx, as.manager.config.ResourceGroup, nicName)
+		observeARMRequest("Interfaces.Delete", start, rerr)
 		klog.Infof("waiting for nic deletion: %s/%s", as.manager.config.ResourceGroup, nicName)
 		_, realErr := checkResourceExistsFromRetryError(rerr)
 		if realErr != nil {
 			return realErr
 		}
-		klog.V(2).Infof("interface %s/%s removed", as.manager.config.ResourceGroup, nicName)
+		klogx.ProviderAzure.V(2).Infof("interface %s/%s removed", as.manager.config.ResourceGroup, nicName)
 	}
 
 	if vhd != nil {
@@ -591,7 +718,7 @@ func (as *AgentPool) deleteVirtualMachine(name string) error {
 			if realErr != nil {
 				return realErr
 			}
-			klog.V(2).Infof("Blob %s/%s removed", as.manager.config.ResourceGroup, vhdBlob)
+			klogx.ProviderAzure.V(2).Infof("Blob %s/%s removed", as.manager.config.ResourceGroup, vhdBlob)
 		}
 	} else if managedDisk != nil {
 		if osDiskName == nil {
@@ -600,12 +727,14 @@ func (as *AgentPool) deleteVirtualMachine(name string) error {
 			klog.Infof("deleting managed disk: %s/%s", as.manager.config.ResourceGroup, *osDiskName)
 			disksCtx, disksCancel := getContextWithCancel()
 			defer disksCancel()
+			start = time.Now()
 			rerr := as.manager.azClient.disksClient.Delete(disksCtx, as.manager.config.SubscriptionID, as.manager.config.ResourceGroup, *osDiskName)
+			observeARMRequest("Disks.Delete", start, rerr)
 			_, realErr := checkResourceExistsFromRetryError(rerr)
 			if realErr != nil {
 				return realErr
 			}
-			klog.V(2).Infof("disk %s/%s removed", as.manager.config.ResourceGroup, *osDiskName)
+			klogx.ProviderAzure.V(2).Infof("disk %s/%s removed", as.manager.config.ResourceGroup, *osDiskName)
 		}
 	}
 
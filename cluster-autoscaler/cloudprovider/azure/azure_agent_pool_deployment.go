@@ -0,0 +1,250 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2017-05-10/resources"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
+	klog "k8s.io/klog/v2"
+)
+
+const (
+	// failedDeploymentInstancePrefix prefixes the names of the placeholders for VMs a scale-up deployment failed to create.
+	failedDeploymentInstancePrefix = "failed-deployment-"
+	// agentPoolDeploymentTimeout bounds the wait for a scale-up deployment, which fails once it is over.
+	agentPoolDeploymentTimeout = 30 * time.Minute
+)
+
+// deploymentGCInProgress is set while outdated deployments are garbage-collected. Deployments are shared by all
+// the agent pools of the resource group, so a single garbage collection runs at a time.
+var deploymentGCInProgress int32
+
+// agentPoolDeployment is a scale-up deployment of an agent pool, creating the VMs of indexes
+// [offset, offset+count).
+type agentPoolDeployment struct {
+	name    string
+	offset  int
+	count   int
+	started time.Time
+	// err is the provisioning error of a failed deployment.
+	err error
+	// deleted are the indexes whose placeholders were deleted after the deployment failed.
+	deleted map[int]bool
+}
+
+// missingIndexes returns the indexes the deployment creates which have neither a VM nor a deleted placeholder.
+func (d *agentPoolDeployment) missingIndexes(vmIndexes map[int]bool) []int {
+	var missing []int
+	for index := d.offset; index < d.offset+d.count; index++ {
+		if !vmIndexes[index] && !d.deleted[index] {
+			missing = append(missing, index)
+		}
+	}
+	return missing
+}
+
+// startDeployment reserves the VM indexes of a scale-up of delta VMs and runs its deployment asynchronously,
+// so that scale-ups of the agent pool don't wait for the deployments of previous ones. mutex must be held.
+func (as *AgentPool) startDeployment(indexes []int, delta int) *agentPoolDeployment {
+	offset := 0
+	if len(indexes) > 0 {
+		offset = indexes[len(indexes)-1] + 1
+	}
+	for _, deployment := range as.deployments {
+		if end := deployment.offset + deployment.count; end > offset {
+			offset = end
+		}
+	}
+
+	deployment := &agentPoolDeployment{
+		// Names are distinct across agent pools and concurrent scale-ups, for their deployments not to replace each other.
+		name:    fmt.Sprintf("%s%s-%d-%d", clusterAutoscalerDeploymentPrefix, strings.ToLower(as.Name), offset, rand.Int31()),
+		offset:  offset,
+		count:   delta,
+		started: time.Now(),
+		deleted: make(map[int]bool),
+	}
+	// Concurrent deployments must not share their parameters.
+	parameters := make(map[string]interface{}, len(as.parameters)+2)
+	for key, value := range as.parameters {
+		parameters[key] = value
+	}
+	parameters[as.Name+"Count"] = map[string]int{"value": offset + delta}
+	parameters[as.Name+"Offset"] = map[string]int{"value": offset}
+
+	as.deployments[deployment.name] = deployment
+	go as.runDeployment(deployment, parameters)
+	return deployment
+}
+
+// runDeployment waits for the deployment to complete, concurrently with the other deployments of the resource group.
+// Succeeded deployments are forgotten, as their VMs are listed from now on, while failed ones are kept to report
+// the VMs they failed to create until these are deleted.
+func (as *AgentPool) runDeployment(deployment *agentPoolDeployment, parameters map[string]interface{}) {
+	newDeployment := resources.Deployment{
+		Properties: &resources.DeploymentProperties{
+			Template:   &as.template,
+			Parameters: &parameters,
+			Mode:       resources.Incremental,
+		},
+	}
+	ctx, cancel := getContextWithTimeout(agentPoolDeploymentTimeout)
+	defer cancel()
+	klogx.ProviderAzure.V(3).Infof("Waiting for deploymentsClient.CreateOrUpdate(%s, %s, %v)", as.manager.config.ResourceGroup, deployment.name, newDeployment)
+	resp, err := as.manager.azClient.deploymentsClient.CreateOrUpdate(ctx, as.manager.config.ResourceGroup, deployment.name, newDeployment)
+	isSuccess, realError := isSuccessHTTPResponse(resp, err)
+
+	as.mutex.Lock()
+	if isSuccess {
+		klogx.ProviderAzure.V(3).Infof("deploymentsClient.CreateOrUpdate(%s, %s) success after %v", as.manager.config.ResourceGroup, deployment.name, time.Since(deployment.started))
+		delete(as.deployments, deployment.name)
+	} else {
+		klog.Errorf("deploymentsClient.CreateOrUpdate for deployment %q of agent pool %q failed: %v", deployment.name, as.Name, realError)
+		deployment.err = realError
+	}
+	// Refresh the size from the VMs created by the deployment.
+	as.lastRefresh = time.Time{}
+	as.mutex.Unlock()
+
+	klogx.ProviderAzure.V(6).Info("runDeployment: invalidating cache")
+	as.manager.invalidateCache()
+	as.garbageCollectDeployments()
+}
+
+// garbageCollectDeployments deletes the outdated deployments asynchronously, unless another garbage collection
+// is already in progress.
+func (as *AgentPool) garbageCollectDeployments() {
+	if !atomic.CompareAndSwapInt32(&deploymentGCInProgress, 0, 1) {
+		return
+	}
+	go func() {
+		defer atomic.StoreInt32(&deploymentGCInProgress, 0)
+		if err := as.deleteOutdatedDeployments(); err != nil {
+			klog.Warningf("failed to cleanup outdated deployments with err: %v.", err)
+		}
+	}()
+}
+
+// pendingInstanceCount returns the number of VMs the deployments are still expected to create, or failed to
+// create but weren't deleted yet. mutex must be held.
+func (as *AgentPool) pendingInstanceCount(indexes []int) int {
+	vmIndexes := indexSet(indexes)
+	pending := 0
+	for _, deployment := range as.deployments {
+		pending += len(deployment.missingIndexes(vmIndexes))
+	}
+	return pending
+}
+
+// forgetFailedDeployments forgets the failed deployments whose placeholders were all deleted. mutex must be held.
+func (as *AgentPool) forgetFailedDeployments(indexes []int) {
+	vmIndexes := indexSet(indexes)
+	for name, deployment := range as.deployments {
+		if deployment.err != nil && len(deployment.missingIndexes(vmIndexes)) == 0 {
+			delete(as.deployments, name)
+		}
+	}
+}
+
+// failedDeploymentInstances returns placeholders for the VMs failed deployments didn't create. They are reported
+// as instances failing to be created with the error of their deployment, for the agent pool to be backed off.
+// mutex must be held.
+func (as *AgentPool) failedDeploymentInstances(indexes []int) []cloudprovider.Instance {
+	vmIndexes := indexSet(indexes)
+	var instances []cloudprovider.Instance
+	for _, deployment := range as.deployments {
+		if deployment.err == nil {
+			continue
+		}
+		errorInfo := &cloudprovider.InstanceErrorInfo{
+			ErrorClass:   cloudprovider.OtherErrorClass,
+			ErrorCode:    "deployment-failed",
+			ErrorMessage: fmt.Sprintf("Azure deployment %s failed: %v", deployment.name, deployment.err),
+		}
+		if isOutOfCapacityError(deployment.err) || strings.Contains(deployment.err.Error(), quotaExceededErrorCode) {
+			errorInfo.ErrorClass = cloudprovider.OutOfResourcesErrorClass
+			errorInfo.ErrorCode = "out-of-capacity"
+		}
+		for _, index := range deployment.missingIndexes(vmIndexes) {
+			instances = append(instances, cloudprovider.Instance{
+				Id: as.failedDeploymentInstanceID(deployment, index),
+				Status: &cloudprovider.InstanceStatus{
+					State:     cloudprovider.InstanceCreating,
+					ErrorInfo: errorInfo,
+				},
+			})
+		}
+	}
+	return instances
+}
+
+func (as *AgentPool) failedDeploymentInstanceID(deployment *agentPoolDeployment, index int) string {
+	return fmt.Sprintf("azure:///subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/virtualMachines/%s%s-%d",
+		as.manager.config.SubscriptionID, strings.ToLower(as.manager.config.ResourceGroup),
+		failedDeploymentInstancePrefix, deployment.name, index)
+}
+
+// deleteFailedDeploymentInstances drops the placeholders of failed deployments among the given nodes, which have no
+// VM to delete, and returns the other nodes.
+func (as *AgentPool) deleteFailedDeploymentInstances(nodes []*apiv1.Node) []*apiv1.Node {
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+
+	remaining := make([]*apiv1.Node, 0, len(nodes))
+	for _, node := range nodes {
+		placeholder := false
+		for _, deployment := range as.deployments {
+			if deployment.err == nil {
+				continue
+			}
+			for index := deployment.offset; index < deployment.offset+deployment.count; index++ {
+				if strings.EqualFold(as.failedDeploymentInstanceID(deployment, index), node.Spec.ProviderID) {
+					deployment.deleted[index] = true
+					placeholder = true
+					break
+				}
+			}
+			if placeholder {
+				break
+			}
+		}
+		if !placeholder {
+			remaining = append(remaining, node)
+		}
+	}
+	if len(remaining) < len(nodes) {
+		as.lastRefresh = time.Time{}
+	}
+	return remaining
+}
+
+func indexSet(indexes []int) map[int]bool {
+	set := make(map[int]bool, len(indexes))
+	for _, index := range indexes {
+		set[index] = true
+	}
+	return set
+}
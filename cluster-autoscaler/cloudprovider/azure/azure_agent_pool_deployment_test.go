@@ -0,0 +1,169 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2017-05-10/resources"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmclient/mockvmclient"
+
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+)
+
+// blockingDeploymentsClient holds deployments until released, then completes them with err.
+type blockingDeploymentsClient struct {
+	*DeploymentsClientMock
+	release chan struct{}
+	err     error
+	// inFlight and maxInFlight count the deployments in progress.
+	inFlight    int32
+	maxInFlight int32
+}
+
+func (c *blockingDeploymentsClient) CreateOrUpdate(ctx context.Context, resourceGroupName string, deploymentName string, parameters resources.Deployment) (*http.Response, error) {
+	inFlight := atomic.AddInt32(&c.inFlight, 1)
+	defer atomic.AddInt32(&c.inFlight, -1)
+	for {
+		max := atomic.LoadInt32(&c.maxInFlight)
+		if inFlight <= max || atomic.CompareAndSwapInt32(&c.maxInFlight, max, inFlight) {
+			break
+		}
+	}
+	<-c.release
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.DeploymentsClientMock.CreateOrUpdate(ctx, resourceGroupName, deploymentName, parameters)
+}
+
+func newTestAgentPoolWithVMs(t *testing.T, ctrl *gomock.Controller, client DeploymentsClient) *AgentPool {
+	as := newTestAgentPool(newTestAzureManager(t), "as")
+	as.manager.azClient.deploymentsClient = client
+	mockVMClient := mockvmclient.NewMockInterface(ctrl)
+	as.manager.azClient.virtualMachinesClient = mockVMClient
+	mockVMClient.EXPECT().List(gomock.Any(), as.manager.config.ResourceGroup).Return(getExpectedVMs(), nil).AnyTimes()
+	ac, err := newAzureCache(as.manager.azClient, refreshInterval, []string{as.manager.config.ResourceGroup}, vmTypeStandard, false, "")
+	assert.NoError(t, err)
+	as.manager.azureCache = ac
+	return as
+}
+
+func deploymentCount(as *AgentPool) int {
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+	return len(as.deployments)
+}
+
+func TestAgentPoolConcurrentDeployments(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := &blockingDeploymentsClient{
+		DeploymentsClientMock: &DeploymentsClientMock{FakeStore: map[string]resources.DeploymentExtended{}},
+		release:               make(chan struct{}),
+	}
+	as := newTestAgentPoolWithVMs(t, ctrl, client)
+	indexes, _, err := as.GetVMIndexes()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(indexes))
+	nextIndex := indexes[len(indexes)-1] + 1
+
+	// The second scale-up doesn't wait for the deployment of the first one, nor reuses its indexes.
+	assert.NoError(t, as.IncreaseSize(1))
+	assert.NoError(t, as.IncreaseSize(2))
+	assert.Equal(t, 2, deploymentCount(as))
+	offsets := map[int]int{}
+	as.mutex.Lock()
+	for _, deployment := range as.deployments {
+		offsets[deployment.offset] = deployment.count
+	}
+	as.mutex.Unlock()
+	assert.Equal(t, map[int]int{nextIndex: 1, nextIndex + 1: 2}, offsets)
+
+	size, err := as.TargetSize()
+	assert.NoError(t, err)
+	assert.Equal(t, 5, size)
+	// In-progress deployments count toward the max size.
+	assert.Equal(t, fmt.Errorf("size increase too large - desired:6 max:5"), as.IncreaseSize(1))
+
+	// The deployments run concurrently.
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&client.inFlight) == 2 }, 5*time.Second, 10*time.Millisecond)
+
+	close(client.release)
+	assert.Eventually(t, func() bool { return deploymentCount(as) == 0 }, 5*time.Second, 10*time.Millisecond)
+	// The deployments had distinct names, so neither replaced the other.
+	stored, err := client.List(context.Background(), "", "", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(stored))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&client.maxInFlight))
+}
+
+func TestAgentPoolFailedDeployment(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := &blockingDeploymentsClient{
+		DeploymentsClientMock: &DeploymentsClientMock{FakeStore: map[string]resources.DeploymentExtended{}},
+		release:               make(chan struct{}),
+		err:                   fmt.Errorf("Code=\"AllocationFailed\" Message=\"Allocation failed\""),
+	}
+	close(client.release)
+	as := newTestAgentPoolWithVMs(t, ctrl, client)
+
+	assert.NoError(t, as.IncreaseSize(2))
+	assert.Eventually(t, func() bool {
+		as.mutex.Lock()
+		defer as.mutex.Unlock()
+		for _, deployment := range as.deployments {
+			return deployment.err != nil
+		}
+		return false
+	}, 5*time.Second, 10*time.Millisecond)
+
+	instances, err := as.Nodes()
+	assert.NoError(t, err)
+	var placeholders []*apiv1.Node
+	for _, instance := range instances {
+		if instance.Status == nil {
+			continue
+		}
+		assert.Equal(t, cloudprovider.InstanceCreating, instance.Status.State)
+		assert.Equal(t, cloudprovider.OutOfResourcesErrorClass, instance.Status.ErrorInfo.ErrorClass)
+		placeholders = append(placeholders, &apiv1.Node{Spec: apiv1.NodeSpec{ProviderID: instance.Id}})
+	}
+	assert.Equal(t, 2, len(placeholders))
+
+	// The VMs the deployment failed to create count toward the target size until they are deleted.
+	size, err := as.TargetSize()
+	assert.NoError(t, err)
+	assert.Equal(t, 4, size)
+
+	assert.NoError(t, as.DeleteNodes(placeholders))
+	size, err = as.TargetSize()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, size)
+	assert.Equal(t, 0, deploymentCount(as))
+}
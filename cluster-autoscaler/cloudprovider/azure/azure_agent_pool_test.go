@@ -27,6 +27,7 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/storageaccountclient/mockstorageaccountclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmasclient/mockvmasclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmclient/mockvmclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
 
@@ -52,11 +53,13 @@ func newTestAgentPool(manager *AzureManager, name string) *AgentPool {
 		azureRef: azureRef{
 			Name: name,
 		},
-		manager:    manager,
-		minSize:    1,
-		maxSize:    5,
-		parameters: make(map[string]interface{}),
-		template:   make(map[string]interface{}),
+		manager:      manager,
+		minSize:      1,
+		maxSize:      5,
+		parameters:   make(map[string]interface{}),
+		template:     make(map[string]interface{}),
+		deployments:  make(map[string]*agentPoolDeployment),
+		faultDomains: make(map[string]int32),
 	}
 }
 
@@ -185,7 +188,7 @@ func TestGetVMsFromCache(t *testing.T) {
 	mockVMClient := mockvmclient.NewMockInterface(ctrl)
 	testAS.manager.azClient.virtualMachinesClient = mockVMClient
 	mockVMClient.EXPECT().List(gomock.Any(), testAS.manager.config.ResourceGroup).Return(expectedVMs, nil)
-	ac, err := newAzureCache(testAS.manager.azClient, refreshInterval, testAS.manager.config.ResourceGroup, vmTypeStandard, false, "")
+	ac, err := newAzureCache(testAS.manager.azClient, refreshInterval, []string{testAS.manager.config.ResourceGroup}, vmTypeStandard, false, "")
 	assert.NoError(t, err)
 	testAS.manager.azureCache = ac
 
@@ -203,7 +206,7 @@ func TestGetVMIndexes(t *testing.T) {
 	mockVMClient := mockvmclient.NewMockInterface(ctrl)
 	as.manager.azClient.virtualMachinesClient = mockVMClient
 	mockVMClient.EXPECT().List(gomock.Any(), as.manager.config.ResourceGroup).Return(expectedVMs, nil)
-	ac, err := newAzureCache(as.manager.azClient, refreshInterval, as.manager.config.ResourceGroup, vmTypeStandard, false, "")
+	ac, err := newAzureCache(as.manager.azClient, refreshInterval, []string{as.manager.config.ResourceGroup}, vmTypeStandard, false, "")
 	assert.NoError(t, err)
 	as.manager.azureCache = ac
 
@@ -242,7 +245,7 @@ func TestGetCurSize(t *testing.T) {
 	mockVMClient := mockvmclient.NewMockInterface(ctrl)
 	as.manager.azClient.virtualMachinesClient = mockVMClient
 	mockVMClient.EXPECT().List(gomock.Any(), as.manager.config.ResourceGroup).Return(expectedVMs, nil)
-	ac, err := newAzureCache(as.manager.azClient, refreshInterval, as.manager.config.ResourceGroup, vmTypeStandard, false, "")
+	ac, err := newAzureCache(as.manager.azClient, refreshInterval, []string{as.manager.config.ResourceGroup}, vmTypeStandard, false, "")
 	assert.NoError(t, err)
 	as.manager.azureCache = ac
 
@@ -266,7 +269,7 @@ func TestAgentPoolTargetSize(t *testing.T) {
 	as.manager.azClient.virtualMachinesClient = mockVMClient
 	expectedVMs := getExpectedVMs()
 	mockVMClient.EXPECT().List(gomock.Any(), as.manager.config.ResourceGroup).Return(expectedVMs, nil)
-	ac, err := newAzureCache(as.manager.azClient, refreshInterval, as.manager.config.ResourceGroup, vmTypeStandard, false, "")
+	ac, err := newAzureCache(as.manager.azClient, refreshInterval, []string{as.manager.config.ResourceGroup}, vmTypeStandard, false, "")
 	assert.NoError(t, err)
 	as.manager.azureCache = ac
 
@@ -285,7 +288,7 @@ func TestAgentPoolIncreaseSize(t *testing.T) {
 	as.manager.azClient.virtualMachinesClient = mockVMClient
 	expectedVMs := getExpectedVMs()
 	mockVMClient.EXPECT().List(gomock.Any(), as.manager.config.ResourceGroup).Return(expectedVMs, nil).MaxTimes(2)
-	ac, err := newAzureCache(as.manager.azClient, refreshInterval, as.manager.config.ResourceGroup, vmTypeStandard, false, "")
+	ac, err := newAzureCache(as.manager.azClient, refreshInterval, []string{as.manager.config.ResourceGroup}, vmTypeStandard, false, "")
 	assert.NoError(t, err)
 	as.manager.azureCache = ac
 
@@ -313,7 +316,7 @@ func TestDecreaseTargetSize(t *testing.T) {
 	as.manager.azClient.virtualMachinesClient = mockVMClient
 	expectedVMs := getExpectedVMs()
 	mockVMClient.EXPECT().List(gomock.Any(), as.manager.config.ResourceGroup).Return(expectedVMs, nil).MaxTimes(3)
-	ac, err := newAzureCache(as.manager.azClient, refreshInterval, as.manager.config.ResourceGroup, vmTypeStandard, false, "")
+	ac, err := newAzureCache(as.manager.azClient, refreshInterval, []string{as.manager.config.ResourceGroup}, vmTypeStandard, false, "")
 	assert.NoError(t, err)
 	as.manager.azureCache = ac
 
@@ -431,7 +434,7 @@ func TestAgentPoolDeleteNodes(t *testing.T) {
 	mockSAClient := mockstorageaccountclient.NewMockInterface(ctrl)
 	as.manager.azClient.storageAccountsClient = mockSAClient
 	mockVMClient.EXPECT().List(gomock.Any(), as.manager.config.ResourceGroup).Return(expectedVMs, nil)
-	ac, err := newAzureCache(as.manager.azClient, refreshInterval, as.manager.config.ResourceGroup, vmTypeStandard, false, "")
+	ac, err := newAzureCache(as.manager.azClient, refreshInterval, []string{as.manager.config.ResourceGroup}, vmTypeStandard, false, "")
 	assert.NoError(t, err)
 	as.manager.azureCache = ac
 
@@ -497,7 +500,7 @@ func TestAgentPoolNodes(t *testing.T) {
 	mockVMClient := mockvmclient.NewMockInterface(ctrl)
 	as.manager.azClient.virtualMachinesClient = mockVMClient
 	mockVMClient.EXPECT().List(gomock.Any(), as.manager.config.ResourceGroup).Return(expectedVMs, nil)
-	ac, err := newAzureCache(as.manager.azClient, refreshInterval, as.manager.config.ResourceGroup, vmTypeStandard, false, "")
+	ac, err := newAzureCache(as.manager.azClient, refreshInterval, []string{as.manager.config.ResourceGroup}, vmTypeStandard, false, "")
 	assert.NoError(t, err)
 	as.manager.azureCache = ac
 
@@ -519,3 +522,54 @@ func TestAgentPoolNodes(t *testing.T) {
 	assert.Equal(t, expectedErr, err)
 	assert.Nil(t, nodes)
 }
+
+func TestAgentPoolTemplateNodeInfo(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	as := newTestAgentPool(newTestAzureManager(t), "as")
+	vm := func(name string) compute.VirtualMachine {
+		return compute.VirtualMachine{
+			Name:     to.StringPtr(name),
+			ID:       to.StringPtr("/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/" + name),
+			Location: to.StringPtr("eastus"),
+			Tags:     map[string]*string{"poolName": to.StringPtr("as")},
+			VirtualMachineProperties: &compute.VirtualMachineProperties{
+				HardwareProfile: &compute.HardwareProfile{VMSize: compute.StandardD2V2},
+				AvailabilitySet: &compute.SubResource{ID: to.StringPtr("/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/availabilitySets/as-availabilitySet")},
+			},
+		}
+	}
+	vmWithFaultDomain := func(name string, faultDomain int32) compute.VirtualMachine {
+		withInstanceView := vm(name)
+		withInstanceView.InstanceView = &compute.VirtualMachineInstanceView{PlatformFaultDomain: to.Int32Ptr(faultDomain)}
+		return withInstanceView
+	}
+	expectedVMs := []compute.VirtualMachine{vm("as-vm-0"), vm("as-vm-1"), vm("as-vm-2")}
+
+	mockVMClient := mockvmclient.NewMockInterface(ctrl)
+	as.manager.azClient.virtualMachinesClient = mockVMClient
+	mockVMClient.EXPECT().List(gomock.Any(), as.manager.config.ResourceGroup).Return(expectedVMs, nil)
+	mockVMClient.EXPECT().Get(gomock.Any(), as.manager.config.ResourceGroup, "as-vm-0", compute.InstanceViewTypesInstanceView).Return(vmWithFaultDomain("as-vm-0", 0), nil).Times(1)
+	mockVMClient.EXPECT().Get(gomock.Any(), as.manager.config.ResourceGroup, "as-vm-1", compute.InstanceViewTypesInstanceView).Return(vmWithFaultDomain("as-vm-1", 2), nil).Times(1)
+	mockVMClient.EXPECT().Get(gomock.Any(), as.manager.config.ResourceGroup, "as-vm-2", compute.InstanceViewTypesInstanceView).Return(vmWithFaultDomain("as-vm-2", 0), nil).Times(1)
+	mockVMASClient := mockvmasclient.NewMockInterface(ctrl)
+	as.manager.azClient.availabilitySetsClient = mockVMASClient
+	mockVMASClient.EXPECT().Get(gomock.Any(), "rg", "as-availabilitySet").Return(compute.AvailabilitySet{
+		AvailabilitySetProperties: &compute.AvailabilitySetProperties{PlatformFaultDomainCount: to.Int32Ptr(3)},
+	}, nil).Times(1)
+	ac, err := newAzureCache(as.manager.azClient, refreshInterval, []string{as.manager.config.ResourceGroup}, vmTypeStandard, false, "")
+	assert.NoError(t, err)
+	as.manager.azureCache = ac
+
+	nodeInfo, err := as.TemplateNodeInfo()
+	assert.NoError(t, err)
+	node := nodeInfo.Node()
+	assert.Equal(t, "Standard_D2_v2", node.Labels[apiv1.LabelInstanceTypeStable])
+	assert.Equal(t, "eastus", node.Labels[apiv1.LabelTopologyRegion])
+	assert.Equal(t, "1", node.Labels[apiv1.LabelTopologyZone])
+
+	// Fault domains of the VMs and of the availability set are only fetched once.
+	_, err = as.TemplateNodeInfo()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2022-08-01/compute"
+	"github.com/Azure/go-autorest/autorest/azure"
+	klog "k8s.io/klog/v2"
+	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
+
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
+)
+
+// defaultAtomicScaleUpTimeout is how long an atomic scale-up may take before it is rolled back, unless
+// configured with atomicScaleUpTimeout.
+const defaultAtomicScaleUpTimeout = 15 * time.Minute
+
+// AtomicIncreaseSize increases the scale set size by delta, keeping either all the new instances or none of
+// them. Scale sets spanning several zones must have zoneBalance enabled, for Azure to fail the scale-out rather
+// than create the instances in a subset of their zones. The instances created are deleted if the scale-out
+// fails, or doesn't complete within the atomic scale-up timeout. Other scale-ups of the scale set are refused
+// until then, so that the instances the scale-out created are exactly the ones listed after it and not before.
+func (scaleSet *ScaleSet) AtomicIncreaseSize(delta int) error {
+	if delta <= 0 {
+		return fmt.Errorf("size increase must be positive")
+	}
+
+	size, err := scaleSet.GetScaleSetSize()
+	if err != nil {
+		return err
+	}
+
+	if size == -1 {
+		return fmt.Errorf("the scale set %s is under initialization, skipping AtomicIncreaseSize", scaleSet.Name)
+	}
+
+	// Starting stopped instances can't be rolled back along with the scale-out.
+	if stopped := scaleSet.getStoppedInstances(); len(stopped) > 0 {
+		return fmt.Errorf("the scale set %s has %d stopped instances and can't be scaled up atomically", scaleSet.Name, len(stopped))
+	}
+	if int(size)+delta > scaleSet.MaxSize() {
+		return fmt.Errorf("size increase too large - desired:%d max:%d", int(size)+delta, scaleSet.MaxSize())
+	}
+
+	vmss, err := scaleSet.getVMSSFromCache()
+	if err != nil {
+		return err
+	}
+	if vmss.VirtualMachineScaleSetProperties != nil && vmss.OrchestrationMode == compute.Flexible {
+		return fmt.Errorf("vmss - %q with Flexible orchestration can't be scaled up atomically", scaleSet.Name)
+	}
+	// Without strict zone balance, Azure places the instances it can allocate in a subset of the zones rather
+	// than failing the scale-out, which the rollback would then have to undo.
+	zoneBalance := vmss.VirtualMachineScaleSetProperties != nil && vmss.ZoneBalance != nil && *vmss.ZoneBalance
+	if vmss.Zones != nil && len(*vmss.Zones) > 1 && !zoneBalance {
+		return fmt.Errorf("vmss - %q spans %d zones without zoneBalance and can't be scaled up atomically", scaleSet.Name, len(*vmss.Zones))
+	}
+
+	if err := scaleSet.checkSpotEvictionCooldown(); err != nil {
+		return err
+	}
+
+	if err := scaleSet.checkQuota(delta); err != nil {
+		return err
+	}
+
+	if err := scaleSet.startAtomicScaleUp(); err != nil {
+		return err
+	}
+	existing, err := scaleSet.listAtomicScaleUpBaseline()
+	if err == nil {
+		var future *azure.Future
+		future, _, err = scaleSet.updateCapacity(size+int64(delta), true)
+		if err == nil {
+			go scaleSet.waitForAtomicScaleUp(future, existing, delta)
+			return nil
+		}
+	}
+	scaleSet.finishAtomicScaleUp()
+	return err
+}
+
+// startAtomicScaleUp marks an atomic scale-up of the scale set as in flight, unless there is one already.
+func (scaleSet *ScaleSet) startAtomicScaleUp() error {
+	scaleSet.sizeMutex.Lock()
+	defer scaleSet.sizeMutex.Unlock()
+
+	if scaleSet.atomicScaleUpInProgress {
+		return fmt.Errorf("an atomic scale-up of vmss %q is in progress", scaleSet.Name)
+	}
+	scaleSet.atomicScaleUpInProgress = true
+	return nil
+}
+
+// finishAtomicScaleUp marks the atomic scale-up of the scale set as over, allowing other scale-ups again.
+func (scaleSet *ScaleSet) finishAtomicScaleUp() {
+	scaleSet.sizeMutex.Lock()
+	defer scaleSet.sizeMutex.Unlock()
+
+	scaleSet.atomicScaleUpInProgress = false
+}
+
+// listAtomicScaleUpBaseline returns the instance IDs of the scale set before an atomic scale-up. It fails if the
+// listing doesn't match the scale set size, as the instances of a pending capacity change could then be mistaken
+// for the ones of the atomic scale-up.
+func (scaleSet *ScaleSet) listAtomicScaleUpBaseline() (map[string]bool, error) {
+	vms, rerr := scaleSet.GetScaleSetVms()
+	if rerr != nil {
+		return nil, rerr.Error()
+	}
+	existing := make(map[string]bool, len(vms))
+	for _, vm := range vms {
+		if vm.InstanceID != nil {
+			existing[*vm.InstanceID] = true
+		}
+	}
+
+	scaleSet.sizeMutex.Lock()
+	curSize := scaleSet.curSize
+	scaleSet.sizeMutex.Unlock()
+	if int64(len(existing)) != curSize {
+		return nil, fmt.Errorf("vmss %q has %d instances for a size of %d, waiting for its pending capacity changes before scaling it up atomically", scaleSet.Name, len(existing), curSize)
+	}
+	return existing, nil
+}
+
+// atomicScaleUpTimeout returns how long an atomic scale-up may take before it is rolled back.
+func (scaleSet *ScaleSet) atomicScaleUpTimeout() time.Duration {
+	if scaleSet.manager.config.AtomicScaleUpTimeout > 0 {
+		return time.Duration(scaleSet.manager.config.AtomicScaleUpTimeout) * time.Second
+	}
+	return defaultAtomicScaleUpTimeout
+}
+
+// waitForAtomicScaleUp waits for the capacity update of an atomic scale-up by delta instances, and rolls it back
+// if it fails or times out. existing are the instance IDs of the scale set before the scale-up.
+func (scaleSet *ScaleSet) waitForAtomicScaleUp(future *azure.Future, existing map[string]bool, delta int) {
+	defer scaleSet.finishAtomicScaleUp()
+
+	ctx, cancel := getContextWithTimeout(scaleSet.atomicScaleUpTimeout())
+	defer cancel()
+
+	klogx.ProviderAzure.V(3).Infof("Calling virtualMachineScaleSetsClient.WaitForCreateOrUpdateResult(%s) for atomic scale-up", scaleSet.Name)
+	start := time.Now()
+	httpResponse, err := scaleSet.manager.azClient.virtualMachineScaleSetsClient.WaitForCreateOrUpdateResult(ctx, future, scaleSet.resourceGroup())
+	observeSDKRequest("VirtualMachineScaleSets.WaitForCreateOrUpdateResult", start, err)
+
+	isSuccess, err := isSuccessHTTPResponse(httpResponse, err)
+	if isSuccess {
+		klogx.ProviderAzure.V(3).Infof("virtualMachineScaleSetsClient.WaitForCreateOrUpdateResult(%s) for atomic scale-up success", scaleSet.Name)
+		scaleSet.invalidateInstanceCache()
+		return
+	}
+
+	klog.Errorf("Atomic scale-up of vmss %q by %d instances failed, rolling it back: %v", scaleSet.Name, delta, err)
+	scaleSet.rollbackAtomicScaleUp(existing)
+	if isOutOfCapacityError(err) {
+		scaleSet.addFailedScaleUps(delta, err)
+	}
+}
+
+// createdInstanceIDs returns the instance IDs of the scale set not in existing. While the atomic scale-up is in
+// flight, these are the instances it created.
+func (scaleSet *ScaleSet) createdInstanceIDs(existing map[string]bool) ([]string, *retry.Error) {
+	vms, rerr := scaleSet.GetScaleSetVms()
+	if rerr != nil {
+		return nil, rerr
+	}
+	instanceIDs := []string{}
+	for _, vm := range vms {
+		if vm.InstanceID != nil && !existing[*vm.InstanceID] {
+			instanceIDs = append(instanceIDs, *vm.InstanceID)
+		}
+	}
+	return instanceIDs, nil
+}
+
+// rollbackAtomicScaleUp deletes the instances created by a failed atomic scale-up, and invalidates the cached
+// size and instances of the scale set. It must be called before the atomic scale-up is finished.
+func (scaleSet *ScaleSet) rollbackAtomicScaleUp(existing map[string]bool) {
+	defer func() {
+		scaleSet.invalidateLastSizeRefreshWithLock()
+		scaleSet.invalidateInstanceCache()
+	}()
+
+	instanceIDs, rerr := scaleSet.createdInstanceIDs(existing)
+	if rerr != nil {
+		klog.Errorf("Failed to list the instances of vmss %q to roll back its atomic scale-up: %v", scaleSet.Name, rerr.Error())
+		return
+	}
+	if len(instanceIDs) == 0 {
+		return
+	}
+
+	requiredIds := &compute.VirtualMachineScaleSetVMInstanceRequiredIDs{
+		InstanceIds: &instanceIDs,
+	}
+	ctx, cancel := getContextWithTimeout(vmssContextTimeout)
+	defer cancel()
+	klogx.ProviderAzure.V(3).Infof("Calling virtualMachineScaleSetsClient.DeleteInstancesAsync(%v) to roll back atomic scale-up of %s", instanceIDs, scaleSet.Name)
+	start := time.Now()
+	future, rerr := scaleSet.manager.azClient.virtualMachineScaleSetsClient.DeleteInstancesAsync(ctx, scaleSet.resourceGroup(), scaleSet.Name, *requiredIds, scaleSet.manager.config.EnableForceDelete)
+	observeARMRequest("VirtualMachineScaleSets.DeleteInstances", start, rerr)
+	if rerr != nil {
+		klog.Errorf("virtualMachineScaleSetsClient.DeleteInstancesAsync for instances %v failed: %v", instanceIDs, rerr)
+		return
+	}
+	scaleSet.waitForDeleteInstances(future, requiredIds)
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2022-08-01/compute"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmssclient/mockvmssclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmssvmclient/mockvmssvmclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
+)
+
+func newAtomicTestScaleSet(t *testing.T, ctrl *gomock.Controller, vms []compute.VirtualMachineScaleSetVM, zoneBalance bool) (*ScaleSet, *mockvmssclient.MockInterface) {
+	manager := newTestAzureManager(t)
+	scaleSets := newTestVMSSList(3, "test-asg", "eastus", compute.Uniform)
+	scaleSets[0].Zones = &[]string{"1", "2", "3"}
+	scaleSets[0].ZoneBalance = to.BoolPtr(zoneBalance)
+
+	mockVMSSClient := mockvmssclient.NewMockInterface(ctrl)
+	mockVMSSClient.EXPECT().List(gomock.Any(), manager.config.ResourceGroup).Return(scaleSets, nil).AnyTimes()
+	manager.azClient.virtualMachineScaleSetsClient = mockVMSSClient
+	mockVMSSVMClient := mockvmssvmclient.NewMockInterface(ctrl)
+	mockVMSSVMClient.EXPECT().List(gomock.Any(), manager.config.ResourceGroup, "test-asg", gomock.Any()).Return(vms, nil).AnyTimes()
+	manager.azClient.virtualMachineScaleSetVMsClient = mockVMSSVMClient
+	assert.NoError(t, manager.forceRefresh())
+
+	scaleSet := newTestScaleSet(manager, "test-asg")
+	assert.True(t, manager.RegisterNodeGroup(scaleSet))
+	return scaleSet, mockVMSSClient
+}
+
+func TestAtomicIncreaseSize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	scaleSet, mockVMSSClient := newAtomicTestScaleSet(t, ctrl, newTestVMSSVMList(3), true)
+	mockVMSSClient.EXPECT().CreateOrUpdateAsync(gomock.Any(), scaleSet.manager.config.ResourceGroup, "test-asg", gomock.Any()).DoAndReturn(
+		func(ctx context.Context, resourceGroup, name string, vmss compute.VirtualMachineScaleSet) (*azure.Future, *retry.Error) {
+			assert.Equal(t, int64(5), *vmss.Sku.Capacity)
+			// The zone balance of the scale set is left as configured.
+			assert.Nil(t, vmss.VirtualMachineScaleSetProperties)
+			return nil, nil
+		})
+	mockVMSSClient.EXPECT().WaitForCreateOrUpdateResult(gomock.Any(), gomock.Any(), scaleSet.manager.config.ResourceGroup).Return(&http.Response{StatusCode: http.StatusOK}, nil).AnyTimes()
+
+	assert.Error(t, scaleSet.AtomicIncreaseSize(0))
+	assert.Error(t, scaleSet.AtomicIncreaseSize(3))
+
+	assert.NoError(t, scaleSet.AtomicIncreaseSize(2))
+	targetSize, err := scaleSet.TargetSize()
+	assert.NoError(t, err)
+	assert.Equal(t, 5, targetSize)
+}
+
+func TestAtomicScaleUpRollback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The scale-out created 2 of the 3 requested instances before Azure ran out of capacity.
+	scaleSet, mockVMSSClient := newAtomicTestScaleSet(t, ctrl, newTestVMSSVMList(5), true)
+	failure := fmt.Errorf("Code=\"ZonalAllocationFailed\" Message=\"Allocation failed. We do not have sufficient capacity for the requested VM size in this zone.\"")
+	mockVMSSClient.EXPECT().WaitForCreateOrUpdateResult(gomock.Any(), gomock.Any(), scaleSet.manager.config.ResourceGroup).Return(nil, failure)
+	mockVMSSClient.EXPECT().DeleteInstancesAsync(gomock.Any(), scaleSet.manager.config.ResourceGroup, "test-asg", compute.VirtualMachineScaleSetVMInstanceRequiredIDs{
+		InstanceIds: &[]string{"3", "4"},
+	}, false).Return(nil, nil)
+	mockVMSSClient.EXPECT().WaitForDeleteInstancesResult(gomock.Any(), gomock.Any(), scaleSet.manager.config.ResourceGroup).Return(&http.Response{StatusCode: http.StatusOK}, nil)
+
+	scaleSet.atomicScaleUpInProgress = true
+	scaleSet.waitForAtomicScaleUp(nil, map[string]bool{"0": true, "1": true, "2": true}, 3)
+	assert.False(t, scaleSet.atomicScaleUpInProgress)
+
+	// The whole scale-up is reported as failed, for the scale set to be backed off.
+	scaleSet.instanceMutex.Lock()
+	assert.Equal(t, 3, len(scaleSet.failedScaleUps))
+	scaleSet.instanceMutex.Unlock()
+}
+
+func TestAtomicScaleUpExcludesOtherScaleUps(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	scaleSet, _ := newAtomicTestScaleSet(t, ctrl, newTestVMSSVMList(3), true)
+	scaleSet.atomicScaleUpInProgress = true
+
+	// The instances of other scale-ups would be mistaken for the ones of the atomic scale-up in flight.
+	assert.Error(t, scaleSet.AtomicIncreaseSize(1))
+	assert.Error(t, scaleSet.IncreaseSize(1))
+	targetSize, err := scaleSet.TargetSize()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, targetSize)
+}
+
+func TestAtomicIncreaseSizeWithoutZoneBalance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Without strict zone balance, Azure may place the instances in a subset of the zones instead of failing.
+	scaleSet, _ := newAtomicTestScaleSet(t, ctrl, newTestVMSSVMList(3), false)
+
+	assert.Error(t, scaleSet.AtomicIncreaseSize(1))
+	assert.False(t, scaleSet.atomicScaleUpInProgress)
+}
+
+func TestAtomicIncreaseSizeWithPendingCapacityChange(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The last of the 3 instances of the scale set isn't listed yet.
+	scaleSet, _ := newAtomicTestScaleSet(t, ctrl, newTestVMSSVMList(2), true)
+
+	assert.Error(t, scaleSet.AtomicIncreaseSize(1))
+	assert.False(t, scaleSet.atomicScaleUpInProgress)
+}
+
+func TestAtomicScaleUpTimeout(t *testing.T) {
+	scaleSet := newTestScaleSet(newTestAzureManager(t), "test-asg")
+	assert.Equal(t, defaultAtomicScaleUpTimeout, scaleSet.atomicScaleUpTimeout())
+	scaleSet.manager.config.AtomicScaleUpTimeout = 60
+	assert.Equal(t, time.Minute, scaleSet.atomicScaleUpTimeout())
+}
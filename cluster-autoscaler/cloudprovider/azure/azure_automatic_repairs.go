@@ -0,0 +1,141 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2022-08-01/compute"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
+	klog "k8s.io/klog/v2"
+)
+
+// defaultAutomaticRepairsGracePeriod is the grace period of automatic instance repairs when the policy of the
+// scale set doesn't set one, which is the Azure default.
+const defaultAutomaticRepairsGracePeriod = 30 * time.Minute
+
+// repairedInstancesError is returned when deleting the unregistered nodes of instances created by automatic
+// instance repairs during their grace period, which are left to the repairs.
+type repairedInstancesError struct {
+	scaleSet string
+	nodes    []string
+}
+
+func (e *repairedInstancesError) Error() string {
+	return fmt.Sprintf("not deleting unregistered nodes %s of vmss %q, created by automatic instance repairs during their grace period", strings.Join(e.nodes, ", "), e.scaleSet)
+}
+
+// automaticRepairsGracePeriod returns the grace period of the automatic instance repairs of the scale set, and
+// false if automatic repairs aren't enabled.
+func (scaleSet *ScaleSet) automaticRepairsGracePeriod(vmss compute.VirtualMachineScaleSet) (time.Duration, bool) {
+	if vmss.VirtualMachineScaleSetProperties == nil {
+		return 0, false
+	}
+	policy := vmss.AutomaticRepairsPolicy
+	if policy == nil || policy.Enabled == nil || !*policy.Enabled {
+		return 0, false
+	}
+	if policy.GracePeriod == nil {
+		return defaultAutomaticRepairsGracePeriod, true
+	}
+	gracePeriod, err := parseISO8601Duration(*policy.GracePeriod)
+	if err != nil {
+		klog.Warningf("Invalid automatic repairs grace period of vmss %q, using %v: %v", scaleSet.Name, defaultAutomaticRepairsGracePeriod, err)
+		return defaultAutomaticRepairsGracePeriod, true
+	}
+	return gracePeriod, true
+}
+
+// trackRepairedInstances records the instances that appeared since the previous listing without the scale set
+// growing: they replace unhealthy instances deleted by automatic instance repairs. Instances are tracked for the
+// repair grace period. instanceMutex must be held, so the scale set is passed from the cache rather than read from it.
+func (scaleSet *ScaleSet) trackRepairedInstances(vmss compute.VirtualMachineScaleSet, previous []cloudprovider.Instance, listedBefore bool, size int64, now time.Time) {
+	defer func() { scaleSet.listedSize = size }()
+
+	gracePeriod, enabled := scaleSet.automaticRepairsGracePeriod(vmss)
+	if !enabled {
+		scaleSet.repairedInstances = nil
+		return
+	}
+	known := make(map[string]bool, len(previous))
+	for _, instance := range previous {
+		known[strings.ToLower(instance.Id)] = true
+	}
+	replacements := listedBefore && size <= scaleSet.listedSize
+	repaired := make(map[string]time.Time)
+	for _, instance := range scaleSet.instanceCache {
+		id := strings.ToLower(instance.Id)
+		if since, found := scaleSet.repairedInstances[id]; found {
+			if now.Sub(since) < gracePeriod {
+				repaired[id] = since
+			}
+			continue
+		}
+		if replacements && !known[id] {
+			klogx.ProviderAzure.V(3).Infof("Instance %s of vmss %q was created by automatic instance repairs", instance.Id, scaleSet.Name)
+			repaired[id] = now
+		}
+	}
+	scaleSet.repairedInstances = repaired
+}
+
+// skipRepairedInstances drops the unregistered nodes of the instances created by automatic instance repairs less
+// than the repair grace period ago, and returns the other nodes, with a *repairedInstancesError if any was dropped.
+// Azure repairs these instances again if they don't become healthy, deleting them as well would replace them twice.
+func (scaleSet *ScaleSet) skipRepairedInstances(nodes []*apiv1.Node) ([]*apiv1.Node, error) {
+	// The scale set is read from the cache before taking the instance lock, which must not be held while
+	// taking the cache lock.
+	vmss, err := scaleSet.getVMSSFromCache()
+	if err != nil {
+		return nodes, nil
+	}
+	gracePeriod, enabled := scaleSet.automaticRepairsGracePeriod(vmss)
+
+	scaleSet.instanceMutex.Lock()
+	defer scaleSet.instanceMutex.Unlock()
+
+	if !enabled || len(scaleSet.repairedInstances) == 0 {
+		return nodes, nil
+	}
+	remaining := make([]*apiv1.Node, 0, len(nodes))
+	var skipped []string
+	for _, node := range nodes {
+		since, found := scaleSet.repairedInstances[strings.ToLower(node.Spec.ProviderID)]
+		if found && node.Annotations[cloudprovider.FakeNodeReasonAnnotation] == cloudprovider.FakeNodeUnregistered && time.Since(since) < gracePeriod {
+			klogx.ProviderAzure.V(3).Infof("Skipping deleting unregistered node %s, created by automatic instance repairs %v ago", node.Name, time.Since(since))
+			skipped = append(skipped, node.Name)
+			continue
+		}
+		remaining = append(remaining, node)
+	}
+	if len(skipped) > 0 {
+		return remaining, &repairedInstancesError{scaleSet: scaleSet.Name, nodes: skipped}
+	}
+	return remaining, nil
+}
+
+// parseISO8601Duration parses the ISO 8601 time durations used by Azure, e.g. PT30M or PT1H30M.
+func parseISO8601Duration(duration string) (time.Duration, error) {
+	if !strings.HasPrefix(duration, "PT") || len(duration) == 2 {
+		return 0, fmt.Errorf("unsupported ISO 8601 duration %q", duration)
+	}
+	return time.ParseDuration(strings.ToLower(duration[2:]))
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2022-08-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+)
+
+func TestParseISO8601Duration(t *testing.T) {
+	for duration, expected := range map[string]time.Duration{
+		"PT10M":   10 * time.Minute,
+		"PT1H30M": 90 * time.Minute,
+		"PT90S":   90 * time.Second,
+	} {
+		got, err := parseISO8601Duration(duration)
+		assert.NoError(t, err, duration)
+		assert.Equal(t, expected, got, duration)
+	}
+	for _, duration := range []string{"", "PT", "10m", "P1D"} {
+		_, err := parseISO8601Duration(duration)
+		assert.Error(t, err, duration)
+	}
+}
+
+func TestAutomaticRepairsGracePeriod(t *testing.T) {
+	testCases := []struct {
+		name            string
+		policy          *compute.AutomaticRepairsPolicy
+		expectedEnabled bool
+		expectedPeriod  time.Duration
+	}{
+		{name: "no policy"},
+		{name: "disabled", policy: &compute.AutomaticRepairsPolicy{Enabled: to.BoolPtr(false)}},
+		{name: "default grace period", policy: &compute.AutomaticRepairsPolicy{Enabled: to.BoolPtr(true)}, expectedEnabled: true, expectedPeriod: defaultAutomaticRepairsGracePeriod},
+		{name: "grace period", policy: &compute.AutomaticRepairsPolicy{Enabled: to.BoolPtr(true), GracePeriod: to.StringPtr("PT30M")}, expectedEnabled: true, expectedPeriod: 30 * time.Minute},
+		{name: "invalid grace period", policy: &compute.AutomaticRepairsPolicy{Enabled: to.BoolPtr(true), GracePeriod: to.StringPtr("30m")}, expectedEnabled: true, expectedPeriod: defaultAutomaticRepairsGracePeriod},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			vmss := newTestVMSSList(3, "test-asg", "eastus", compute.Uniform)[0]
+			vmss.AutomaticRepairsPolicy = tc.policy
+			gracePeriod, enabled := newTestScaleSet(&AzureManager{}, "test-asg").automaticRepairsGracePeriod(vmss)
+			assert.Equal(t, tc.expectedEnabled, enabled)
+			assert.Equal(t, tc.expectedPeriod, gracePeriod)
+		})
+	}
+}
+
+func TestRepairedInstances(t *testing.T) {
+	vmss := newTestVMSSList(3, "test-asg", "eastus", compute.Uniform)[0]
+	vmss.AutomaticRepairsPolicy = &compute.AutomaticRepairsPolicy{Enabled: to.BoolPtr(true), GracePeriod: to.StringPtr("PT30M")}
+	manager := &AzureManager{azureCache: &azureCache{
+		scaleSets: map[string]compute.VirtualMachineScaleSet{"test-asg": vmss},
+	}}
+	scaleSet := newTestScaleSet(manager, "test-asg")
+	instance := func(id string) cloudprovider.Instance {
+		return cloudprovider.Instance{Id: "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/test-asg/virtualMachines/" + id}
+	}
+	unregistered := func(instance cloudprovider.Instance) *apiv1.Node {
+		return &apiv1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        instance.Id,
+				Annotations: map[string]string{cloudprovider.FakeNodeReasonAnnotation: cloudprovider.FakeNodeUnregistered},
+			},
+			Spec: apiv1.NodeSpec{ProviderID: instance.Id},
+		}
+	}
+	now := time.Now()
+
+	// Instances of the first listing aren't replacements.
+	scaleSet.instanceCache = []cloudprovider.Instance{instance("0"), instance("1")}
+	scaleSet.trackRepairedInstances(vmss, nil, false, 2, now)
+	assert.Empty(t, scaleSet.repairedInstances)
+
+	// Scale-ups don't create replacements.
+	previous := scaleSet.instanceCache
+	scaleSet.instanceCache = []cloudprovider.Instance{instance("0"), instance("1"), instance("2")}
+	scaleSet.trackRepairedInstances(vmss, previous, true, 3, now)
+	assert.Empty(t, scaleSet.repairedInstances)
+
+	// Instance 1 is replaced by instance 3 without the scale set growing.
+	previous = scaleSet.instanceCache
+	scaleSet.instanceCache = []cloudprovider.Instance{instance("0"), instance("2"), instance("3")}
+	scaleSet.trackRepairedInstances(vmss, previous, true, 3, now)
+	assert.Equal(t, map[string]time.Time{strings.ToLower(instance("3").Id): now}, scaleSet.repairedInstances)
+
+	// Only the unregistered node of the replacement is skipped.
+	registered := &apiv1.Node{Spec: apiv1.NodeSpec{ProviderID: instance("3").Id}}
+	nodes := []*apiv1.Node{unregistered(instance("0")), unregistered(instance("3")), registered}
+	remaining, err := scaleSet.skipRepairedInstances(nodes)
+	assert.Equal(t, []*apiv1.Node{nodes[0], registered}, remaining)
+	assert.IsType(t, &repairedInstancesError{}, err)
+
+	// Replacements are forgotten after the grace period.
+	scaleSet.trackRepairedInstances(vmss, scaleSet.instanceCache, true, 3, now.Add(time.Hour))
+	assert.Empty(t, scaleSet.repairedInstances)
+	remaining, err = scaleSet.skipRepairedInstances(nodes)
+	assert.Equal(t, nodes, remaining)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,277 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2022-08-01/compute"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/to"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/config/dynamic"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
+	klog "k8s.io/klog/v2"
+)
+
+const (
+	// autoprovisionedScaleSetPrefix prefixes the names of the auto-provisioned scale sets. The names are kept
+	// short enough to be used as computer name prefixes of Windows instances too.
+	autoprovisionedScaleSetPrefix = "nap"
+	// defaultAutoprovisioningMaxSize is the maximum size of auto-provisioned scale sets if none is configured.
+	defaultAutoprovisioningMaxSize = 100
+)
+
+// ScaleSetDeleteClient defines needed functions to delete scale sets.
+type ScaleSetDeleteClient interface {
+	Delete(ctx context.Context, resourceGroupName, vmScaleSetName string) (resp *http.Response, err error)
+}
+
+type azScaleSetDeleteClient struct {
+	client compute.VirtualMachineScaleSetsClient
+}
+
+func newAzScaleSetDeleteClient(subscriptionID, endpoint string, authorizer autorest.Authorizer) *azScaleSetDeleteClient {
+	scaleSetsClient := compute.NewVirtualMachineScaleSetsClientWithBaseURI(endpoint, subscriptionID)
+	scaleSetsClient.Authorizer = authorizer
+	scaleSetsClient.PollingDelay = 5 * time.Second
+	configureUserAgent(&scaleSetsClient.Client)
+
+	return &azScaleSetDeleteClient{
+		client: scaleSetsClient,
+	}
+}
+
+// Delete deletes the given scale set and waits for the operation to complete.
+func (az *azScaleSetDeleteClient) Delete(ctx context.Context, resourceGroupName, vmScaleSetName string) (resp *http.Response, err error) {
+	start := time.Now()
+	klogx.ProviderAzure.V(10).Infof("azScaleSetDeleteClient.Delete(%q,%q): start", resourceGroupName, vmScaleSetName)
+	defer func() {
+		observeSDKRequest("VirtualMachineScaleSets.Delete", start, err)
+		klogx.ProviderAzure.V(10).Infof("azScaleSetDeleteClient.Delete(%q,%q): end", resourceGroupName, vmScaleSetName)
+	}()
+
+	future, err := az.client.Delete(ctx, resourceGroupName, vmScaleSetName, nil)
+	if err != nil {
+		return future.Response(), err
+	}
+
+	err = future.WaitForCompletionRef(ctx, az.client.Client)
+	return future.Response(), err
+}
+
+// isAutoprovisioned returns true if the scale set tags mark it as created by node auto-provisioning.
+func isAutoprovisioned(tags map[string]*string) bool {
+	value, found := tags[autoprovisionedTag]
+	return found && value != nil && strings.EqualFold(*value, "true")
+}
+
+// autoprovisionedScaleSetName returns the name of the scale set auto-provisioned for the given machine type,
+// labels and taints, so that the same node group is proposed for the same pods on every loop.
+func autoprovisionedScaleSetName(machineType string, labels map[string]string, taints []apiv1.Taint) string {
+	parts := []string{strings.ToLower(machineType)}
+	for key, value := range labels {
+		parts = append(parts, "label:"+key+"="+value)
+	}
+	for _, taint := range taints {
+		parts = append(parts, "taint:"+taint.Key+"="+taint.Value+":"+string(taint.Effect))
+	}
+	sort.Strings(parts[1:])
+
+	hash := fnv.New32a()
+	hash.Write([]byte(strings.Join(parts, ",")))
+	return fmt.Sprintf("%s%06x", autoprovisionedScaleSetPrefix, hash.Sum32()&0xffffff)
+}
+
+// encodeTemplateTagKey encodes a label or taint key as the suffix of a node template tag.
+func encodeTemplateTagKey(key string) string {
+	return strings.Replace(strings.Replace(key, "_", "~2", -1), "/", "_", -1)
+}
+
+// newAutoprovisionedScaleSet builds the theoretical scale set node auto-provisioning would create for the given
+// machine type, labels and taints, from the profile of the template scale set. The scale set already registered
+// under the same name is returned if it exists.
+func (m *AzureManager) newAutoprovisionedScaleSet(machineType string, labels map[string]string, taints []apiv1.Taint) (cloudprovider.NodeGroup, error) {
+	if !m.isAutoprovisioningMachineType(machineType) {
+		return nil, fmt.Errorf("machine type %q is not available for node auto-provisioning", machineType)
+	}
+
+	name := autoprovisionedScaleSetName(machineType, labels, taints)
+	// The scale set is created in the resource group of the template.
+	templateResourceGroup, _ := splitScaleSetKey(m.config.AutoprovisioningTemplate, m.config.ResourceGroup)
+	key := name
+	if templateResourceGroup != "" {
+		key = templateResourceGroup + "/" + name
+	}
+	for _, nodeGroup := range m.getNodeGroups() {
+		if strings.EqualFold(nodeGroup.Id(), key) {
+			return nodeGroup, nil
+		}
+	}
+
+	template, found := m.azureCache.getScaleSets()[m.config.AutoprovisioningTemplate]
+	if !found {
+		return nil, fmt.Errorf("node auto-provisioning template vmss %q not found", m.config.AutoprovisioningTemplate)
+	}
+	if template.VirtualMachineScaleSetProperties == nil || template.VirtualMachineProfile == nil {
+		return nil, fmt.Errorf("node auto-provisioning template vmss %q has no virtual machine profile", m.config.AutoprovisioningTemplate)
+	}
+
+	maxSize := m.config.AutoprovisioningMaxSize
+	if maxSize <= 0 {
+		maxSize = defaultAutoprovisioningMaxSize
+	}
+
+	tags := make(map[string]*string)
+	for key, value := range template.Tags {
+		// the template's own node template labels and taints don't apply to the auto-provisioned scale set.
+		if strings.HasPrefix(key, nodeLabelTagName) || strings.HasPrefix(key, nodeTaintTagName) {
+			continue
+		}
+		tags[key] = value
+	}
+	tags["min"] = to.StringPtr("0")
+	tags["max"] = to.StringPtr(strconv.Itoa(maxSize))
+	tags[autoprovisionedTag] = to.StringPtr("true")
+	for key, value := range labels {
+		tags[nodeLabelTagName+encodeTemplateTagKey(key)] = to.StringPtr(value)
+	}
+	for _, taint := range taints {
+		tags[nodeTaintTagName+encodeTemplateTagKey(taint.Key)] = to.StringPtr(taint.Value + ":" + string(taint.Effect))
+	}
+
+	// the profiles are copied before being updated, as they are shared with the cached template.
+	properties := *template.VirtualMachineScaleSetProperties
+	profile := *properties.VirtualMachineProfile
+	if profile.OsProfile != nil {
+		osProfile := *profile.OsProfile
+		osProfile.ComputerNamePrefix = to.StringPtr(name)
+		profile.OsProfile = &osProfile
+	}
+	properties.VirtualMachineProfile = &profile
+	properties.ProvisioningState = nil
+	properties.UniqueID = nil
+
+	sku := &compute.Sku{
+		Name:     to.StringPtr(machineType),
+		Capacity: to.Int64Ptr(0),
+	}
+	if template.Sku != nil {
+		sku.Tier = template.Sku.Tier
+	}
+
+	scaleSet, err := NewScaleSet(&dynamic.NodeGroupSpec{
+		Name:               key,
+		MinSize:            0,
+		MaxSize:            maxSize,
+		SupportScaleToZero: scaleToZeroSupportedVMSS,
+	}, m, 0)
+	if err != nil {
+		return nil, err
+	}
+	scaleSet.autoprovisioned = true
+	scaleSet.pendingCreation = &compute.VirtualMachineScaleSet{
+		Name:                             to.StringPtr(name),
+		Location:                         template.Location,
+		Zones:                            template.Zones,
+		Plan:                             template.Plan,
+		Identity:                         template.Identity,
+		Sku:                              sku,
+		Tags:                             tags,
+		VirtualMachineScaleSetProperties: &properties,
+	}
+	return scaleSet, nil
+}
+
+func (m *AzureManager) isAutoprovisioningMachineType(machineType string) bool {
+	for _, available := range m.config.AutoprovisioningMachineTypes {
+		if strings.EqualFold(available, machineType) {
+			return true
+		}
+	}
+	return false
+}
+
+// createAutoprovisionedScaleSet creates the pending scale set of an auto-provisioned node group, in the resource
+// group of its template, and registers it.
+func (m *AzureManager) createAutoprovisionedScaleSet(pending *ScaleSet) (cloudprovider.NodeGroup, error) {
+	resourceGroup := pending.resourceGroup()
+	ctx, cancel := getContextWithTimeout(vmssContextTimeout)
+	defer cancel()
+
+	klogx.ProviderAzure.V(2).Infof("Creating auto-provisioned vmss %s/%s with sku %s", resourceGroup, pending.Name, *pending.pendingCreation.Sku.Name)
+	start := time.Now()
+	rerr := m.azClient.virtualMachineScaleSetsClient.CreateOrUpdate(ctx, resourceGroup, pending.Name, *pending.pendingCreation)
+	observeARMRequest("VirtualMachineScaleSets.CreateOrUpdate", start, rerr)
+	if rerr != nil {
+		klog.Errorf("Failed to create auto-provisioned vmss %s/%s: %v", resourceGroup, pending.Name, rerr.Error())
+		return nil, rerr.Error()
+	}
+
+	if err := m.azureCache.refreshScaleSet(resourceGroup, pending.Name); err != nil {
+		klog.Warningf("Failed to refresh auto-provisioned vmss %q: %v", pending.Id(), err)
+	}
+
+	scaleSet, err := NewScaleSet(&dynamic.NodeGroupSpec{
+		Name:               pending.Id(),
+		MinSize:            pending.MinSize(),
+		MaxSize:            pending.MaxSize(),
+		SupportScaleToZero: scaleToZeroSupportedVMSS,
+	}, m, 0)
+	if err != nil {
+		return nil, err
+	}
+	scaleSet.autoprovisioned = true
+	m.RegisterNodeGroup(scaleSet)
+	m.invalidateCache()
+	return scaleSet, nil
+}
+
+// deleteAutoprovisionedScaleSet deletes an empty auto-provisioned scale set and unregisters it.
+func (m *AzureManager) deleteAutoprovisionedScaleSet(scaleSet *ScaleSet) error {
+	size, err := scaleSet.GetScaleSetSize()
+	if err != nil {
+		return err
+	}
+	if size > 0 {
+		return fmt.Errorf("auto-provisioned vmss %q still has %d instances", scaleSet.Name, size)
+	}
+
+	resourceGroup := scaleSet.resourceGroup()
+	ctx, cancel := getContextWithTimeout(vmssContextTimeout)
+	defer cancel()
+
+	klogx.ProviderAzure.V(2).Infof("Deleting empty auto-provisioned vmss %s/%s", resourceGroup, scaleSet.Name)
+	resp, err := m.azClient.scaleSetDeleteClient.Delete(ctx, resourceGroup, scaleSet.Name)
+	if isSuccess, realError := isSuccessHTTPResponse(resp, err); !isSuccess {
+		klog.Errorf("Failed to delete auto-provisioned vmss %s/%s: %v", resourceGroup, scaleSet.Name, realError)
+		return realError
+	}
+
+	m.UnregisterNodeGroup(scaleSet)
+	m.invalidateCache()
+	return nil
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2022-08-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmssclient/mockvmssclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
+)
+
+func TestAutoprovisionedScaleSetName(t *testing.T) {
+	taints := []apiv1.Taint{{Key: "dedicated", Value: "ml", Effect: apiv1.TaintEffectNoSchedule}}
+	name := autoprovisionedScaleSetName("Standard_D8s_v3", map[string]string{"team": "ml", "tier": "batch"}, taints)
+	assert.Len(t, name, 9)
+	assert.Equal(t, name, autoprovisionedScaleSetName("standard_d8s_v3", map[string]string{"tier": "batch", "team": "ml"}, taints))
+	assert.NotEqual(t, name, autoprovisionedScaleSetName("Standard_D4s_v3", map[string]string{"team": "ml", "tier": "batch"}, taints))
+	assert.NotEqual(t, name, autoprovisionedScaleSetName("Standard_D8s_v3", map[string]string{"team": "ml", "tier": "batch"}, nil))
+}
+
+func TestAutoprovisionedScaleSetLifecycle(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager := newTestAzureManager(t)
+	manager.config.EnableNodeAutoprovisioning = true
+	manager.config.AutoprovisioningTemplate = "template-vmss"
+	manager.config.AutoprovisioningMachineTypes = []string{"Standard_D8s_v3"}
+
+	template := newTestVMSSList(3, "template-vmss", "eastus", compute.Uniform)[0]
+	template.Tags = map[string]*string{
+		"cluster":                   to.StringPtr("test"),
+		nodeLabelTagName + "pool":   to.StringPtr("template"),
+		nodeTaintTagName + "system": to.StringPtr("true:NoSchedule"),
+	}
+	template.VirtualMachineProfile = &compute.VirtualMachineScaleSetVMProfile{
+		OsProfile: &compute.VirtualMachineScaleSetOSProfile{ComputerNamePrefix: to.StringPtr("template")},
+	}
+	var created *compute.VirtualMachineScaleSet
+	mockVMSSClient := mockvmssclient.NewMockInterface(ctrl)
+	mockVMSSClient.EXPECT().List(gomock.Any(), manager.config.ResourceGroup).Return([]compute.VirtualMachineScaleSet{template}, nil).AnyTimes()
+	mockVMSSClient.EXPECT().CreateOrUpdate(gomock.Any(), manager.config.ResourceGroup, gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, _, _ string, parameters compute.VirtualMachineScaleSet) *retry.Error {
+			created = &parameters
+			return nil
+		})
+	mockVMSSClient.EXPECT().Get(gomock.Any(), manager.config.ResourceGroup, gomock.Any()).DoAndReturn(
+		func(_ interface{}, _, _ string) (compute.VirtualMachineScaleSet, *retry.Error) {
+			return *created, nil
+		})
+	manager.azClient.virtualMachineScaleSetsClient = mockVMSSClient
+	deleteClient := &ScaleSetDeleteClientMock{}
+	manager.azClient.scaleSetDeleteClient = deleteClient
+
+	provider, err := BuildAzureCloudProvider(manager, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, manager.forceRefresh())
+	assert.Empty(t, provider.NodeGroups())
+
+	machineTypes, err := provider.GetAvailableMachineTypes()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Standard_D8s_v3"}, machineTypes)
+	_, err = provider.NewNodeGroup("Standard_D2s_v3", nil, nil, nil, nil)
+	assert.Error(t, err)
+
+	// The theoretical node group is built from the template, with the requested labels and taints.
+	taints := []apiv1.Taint{{Key: "dedicated", Value: "ml", Effect: apiv1.TaintEffectNoSchedule}}
+	nodeGroup, err := provider.NewNodeGroup("Standard_D8s_v3", map[string]string{"example.com/team": "ml"}, nil, taints, nil)
+	assert.NoError(t, err)
+	assert.False(t, nodeGroup.Exist())
+	assert.True(t, nodeGroup.Autoprovisioned())
+	assert.Equal(t, defaultAutoprovisioningMaxSize, nodeGroup.MaxSize())
+	targetSize, err := nodeGroup.TargetSize()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, targetSize)
+	nodeInfo, err := nodeGroup.TemplateNodeInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, "ml", nodeInfo.Node().Labels["example.com/team"])
+	assert.NotContains(t, nodeInfo.Node().Labels, "pool")
+	assert.Equal(t, taints, nodeInfo.Node().Spec.Taints)
+	assert.Equal(t, "template", *manager.azureCache.getScaleSets()["template-vmss"].VirtualMachineProfile.OsProfile.ComputerNamePrefix)
+
+	// Creating it creates the scale set and registers it.
+	createdNodeGroup, err := nodeGroup.Create()
+	assert.NoError(t, err)
+	assert.True(t, createdNodeGroup.Exist())
+	assert.True(t, createdNodeGroup.Autoprovisioned())
+	assert.Equal(t, nodeGroup.Id(), *created.Name)
+	assert.Equal(t, "Standard_D8s_v3", *created.Sku.Name)
+	assert.Equal(t, int64(0), *created.Sku.Capacity)
+	assert.Equal(t, nodeGroup.Id(), *created.VirtualMachineProfile.OsProfile.ComputerNamePrefix)
+	assert.Equal(t, "true", *created.Tags[autoprovisionedTag])
+	assert.Equal(t, "test", *created.Tags["cluster"])
+	assert.Equal(t, "ml", *created.Tags[nodeLabelTagName+"example.com_team"])
+	assert.Equal(t, "ml:NoSchedule", *created.Tags[nodeTaintTagName+"dedicated"])
+	assert.NotContains(t, created.Tags, nodeLabelTagName+"pool")
+	assert.NotContains(t, created.Tags, nodeTaintTagName+"system")
+	assert.Len(t, provider.NodeGroups(), 1)
+	sameNodeGroup, err := provider.NewNodeGroup("Standard_D8s_v3", map[string]string{"example.com/team": "ml"}, nil, taints, nil)
+	assert.NoError(t, err)
+	assert.True(t, sameNodeGroup.Exist())
+	_, err = createdNodeGroup.Create()
+	assert.Equal(t, cloudprovider.ErrAlreadyExist, err)
+
+	// Deleting it once empty deletes the scale set and unregisters it.
+	assert.NoError(t, createdNodeGroup.Delete())
+	assert.Equal(t, []string{nodeGroup.Id()}, deleteClient.Deleted)
+	assert.Empty(t, provider.NodeGroups())
+}
+
+func TestAutoprovisionedScaleSetDiscovery(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager := newTestAzureManager(t)
+	scaleSets := append(newTestVMSSList(0, "nap123456", "eastus", compute.Uniform), newTestVMSSList(3, "test-vmss", "eastus", compute.Uniform)...)
+	scaleSets[0].Tags = map[string]*string{
+		autoprovisionedTag: to.StringPtr("true"),
+		"min":              to.StringPtr("0"),
+		"max":              to.StringPtr("10"),
+	}
+	mockVMSSClient := mockvmssclient.NewMockInterface(ctrl)
+	mockVMSSClient.EXPECT().List(gomock.Any(), manager.config.ResourceGroup).Return(scaleSets, nil).AnyTimes()
+	manager.azClient.virtualMachineScaleSetsClient = mockVMSSClient
+	assert.NoError(t, manager.forceRefresh())
+
+	// Auto-provisioned scale sets are ignored unless node auto-provisioning is enabled.
+	assert.Empty(t, manager.getNodeGroups())
+
+	manager.config.EnableNodeAutoprovisioning = true
+	assert.NoError(t, manager.forceRefresh())
+	nodeGroups := manager.getNodeGroups()
+	assert.Len(t, nodeGroups, 1)
+	assert.Equal(t, "nap123456", nodeGroups[0].Id())
+	assert.True(t, nodeGroups[0].Autoprovisioned())
+	assert.Equal(t, 10, nodeGroups[0].MaxSize())
+
+	// Scale sets which weren't auto-provisioned can't be deleted.
+	assert.Equal(t, cloudprovider.ErrNotImplemented, newTestScaleSet(manager, "test-vmss").Delete())
+}
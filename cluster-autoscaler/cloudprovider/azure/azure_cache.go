@@ -30,10 +30,16 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
+
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
 )
 
 var (
-	virtualMachineRE = regexp.MustCompile(`^azure://(?:.*)/providers/Microsoft.Compute/virtualMachines/(.+)$`)
+	virtualMachineRE     = regexp.MustCompile(`(?i)^azure://(?:.*)/providers/Microsoft.Compute/virtualMachines/(.+)$`)
+	scaleSetInstanceIDRE = regexp.MustCompile(`(?i)^azure://(?:.*)/providers/Microsoft.Compute/virtualMachineScaleSets/(.+)/virtualMachines/(?:.+)$`)
+	// VMs created by Flexible scale sets are named after the scale set, followed by an underscore and a random suffix.
+	flexScaleSetInstanceIDRE = regexp.MustCompile(`(?i)^azure://(?:.*)/providers/Microsoft.Compute/virtualMachines/(.+)_[0-9a-z]+$`)
 )
 
 // azureCache is used for caching cluster resources state.
@@ -49,8 +55,9 @@ type azureCache struct {
 	refreshInterval time.Duration
 
 	// Cache content.
-	resourceGroup        string
-	vmType               string
+	resourceGroups []string
+	vmType         string
+	// scaleSets are keyed by scaleSetKey, from their resource group and name.
 	scaleSets            map[string]compute.VirtualMachineScaleSet
 	virtualMachines      map[string][]compute.VirtualMachine
 	registeredNodeGroups []cloudprovider.NodeGroup
@@ -58,14 +65,30 @@ type azureCache struct {
 	unownedInstances     map[azureRef]bool
 	autoscalingOptions   map[azureRef]map[string]string
 	skus                 map[string]*skewer.Cache
+	skusFetchedAt        map[string]time.Time
+	skuCacheTTL          time.Duration
+
+	// instanceMappingStale is set when an instance of a registered scale set
+	// is missing from instanceToNodeGroup, so that the mapping gets rebuilt.
+	instanceMappingStale bool
+
+	// throttleMutex guards the ARM throttling state, which is also updated
+	// by scale sets listing their instances outside of mutex.
+	throttleMutex sync.Mutex
+	// throttledUntil is the time until which list calls are suspended
+	// because ARM throttled them.
+	throttledUntil time.Time
+	// throttleBackoff is the last suspension applied when ARM throttled a
+	// call without a Retry-After header.
+	throttleBackoff time.Duration
 }
 
-func newAzureCache(client *azClient, cacheTTL time.Duration, resourceGroup, vmType string, enableDynamicInstanceList bool, defaultLocation string) (*azureCache, error) {
+func newAzureCache(client *azClient, cacheTTL time.Duration, resourceGroups []string, vmType string, enableDynamicInstanceList bool, defaultLocation string) (*azureCache, error) {
 	cache := &azureCache{
 		interrupt:            make(chan struct{}),
 		azClient:             client,
 		refreshInterval:      cacheTTL,
-		resourceGroup:        resourceGroup,
+		resourceGroups:       resourceGroups,
 		vmType:               vmType,
 		scaleSets:            make(map[string]compute.VirtualMachineScaleSet),
 		virtualMachines:      make(map[string][]compute.VirtualMachine),
@@ -74,6 +97,8 @@ func newAzureCache(client *azClient, cacheTTL time.Duration, resourceGroup, vmTy
 		unownedInstances:     make(map[azureRef]bool),
 		autoscalingOptions:   make(map[azureRef]map[string]string),
 		skus:                 make(map[string]*skewer.Cache),
+		skusFetchedAt:        make(map[string]time.Time),
+		skuCacheTTL:          skuCacheTTLDefault,
 	}
 
 	if enableDynamicInstanceList {
@@ -115,12 +140,12 @@ func (m *azureCache) regenerate() error {
 	// Regenerate instance to node groups mapping.
 	newInstanceToNodeGroupCache := make(map[azureRef]cloudprovider.NodeGroup)
 	for _, ng := range m.registeredNodeGroups {
-		klog.V(4).Infof("regenerate: finding nodes for node group %s", ng.Id())
+		klogx.ProviderAzure.V(4).Infof("regenerate: finding nodes for node group %s", ng.Id())
 		instances, err := ng.Nodes()
 		if err != nil {
 			return err
 		}
-		klog.V(4).Infof("regenerate: found nodes for node group %s: %+v", ng.Id(), instances)
+		klogx.ProviderAzure.V(4).Infof("regenerate: found nodes for node group %s: %+v", ng.Id(), instances)
 
 		for _, instance := range instances {
 			ref := azureRef{Name: instance.Id}
@@ -130,30 +155,23 @@ func (m *azureCache) regenerate() error {
 
 	// Regenerate VMSS to autoscaling options mapping.
 	newAutoscalingOptions := make(map[azureRef]map[string]string)
-	for _, vmss := range m.scaleSets {
-		ref := azureRef{Name: *vmss.Name}
+	for key, vmss := range m.scaleSets {
+		ref := azureRef{Name: key}
 		options := extractAutoscalingOptionsFromScaleSetTags(vmss.Tags)
 		if !reflect.DeepEqual(m.getAutoscalingOptions(ref), options) {
-			klog.V(4).Infof("Extracted autoscaling options from %q ScaleSet tags: %v", *vmss.Name, options)
+			klogx.ProviderAzure.V(4).Infof("Extracted autoscaling options from %q ScaleSet tags: %v", key, options)
 		}
 		newAutoscalingOptions[ref] = options
 	}
 
-	newSkuCache := make(map[string]*skewer.Cache)
-	for location := range m.skus {
-		cache, err := m.fetchSKUs(context.Background(), location)
-		if err != nil {
-			return err
-		}
-		newSkuCache[location] = cache
-	}
+	m.refreshExpiredSKUs(context.Background())
 
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
 	m.instanceToNodeGroup = newInstanceToNodeGroupCache
+	m.instanceMappingStale = false
 	m.autoscalingOptions = newAutoscalingOptions
-	m.skus = newSkuCache
 
 	// Reset unowned instances cache.
 	m.unownedInstances = make(map[azureRef]bool)
@@ -165,37 +183,112 @@ func (m *azureCache) fetchAzureResources() error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
+	if throttledUntil, throttled := m.throttled(); throttled {
+		klog.Warningf("ARM requests are throttled until %v, would return the cached scale sets and virtual machines", throttledUntil)
+		return nil
+	}
+
 	switch m.vmType {
 	case vmTypeVMSS:
 		// List all VMSS in the RG.
-		vmssResult, err := m.fetchScaleSets()
-		if err == nil {
-			m.scaleSets = vmssResult
-		} else {
-			return err
+		vmssResult, rerr := m.fetchScaleSets()
+		if rerr != nil {
+			return m.handleListError(rerr)
 		}
+		m.scaleSets = vmssResult
 	case vmTypeStandard, vmTypeAKS:
 		// List all VMs in the RG.
-		vmResult, err := m.fetchVirtualMachines()
-		if err == nil {
-			m.virtualMachines = vmResult
-		} else {
-			return err
+		vmResult, rerr := m.fetchVirtualMachines()
+		if rerr != nil {
+			return m.handleListError(rerr)
 		}
+		m.virtualMachines = vmResult
 	}
+	m.resetThrottleBackoff()
+
+	return nil
+}
 
+// handleListError returns the error of a failed list call, unless ARM
+// throttled it, in which case further list calls are suspended and the
+// cached resources are kept.
+func (m *azureCache) handleListError(rerr *retry.Error) error {
+	if !m.observeThrottling(rerr) {
+		return rerr.Error()
+	}
+	klog.Warningf("Listing resources in resource groups %v is throttled with message %v, would return the cached scale sets and virtual machines", m.resourceGroups, rerr)
 	return nil
 }
 
+// throttled returns the time until which ARM list calls are suspended, and
+// whether that time is still ahead.
+func (m *azureCache) throttled() (time.Time, bool) {
+	m.throttleMutex.Lock()
+	defer m.throttleMutex.Unlock()
+
+	return m.throttledUntil, m.throttledUntil.After(time.Now())
+}
+
+// observeThrottling suspends ARM list calls if rerr shows the request was
+// throttled, and returns whether it was. The suspension lasts until the time
+// given by the Retry-After header or, without one, for an exponential backoff.
+func (m *azureCache) observeThrottling(rerr *retry.Error) bool {
+	if !isAzureRequestsThrottled(rerr) {
+		return false
+	}
+
+	m.throttleMutex.Lock()
+	defer m.throttleMutex.Unlock()
+
+	now := time.Now()
+	until := rerr.RetryAfter
+	if !until.After(now) {
+		m.throttleBackoff = nextThrottleBackoff(m.throttleBackoff)
+		until = now.Add(m.throttleBackoff)
+	}
+	if until.After(m.throttledUntil) {
+		start := now
+		if m.throttledUntil.After(now) {
+			start = m.throttledUntil
+		}
+		registerThrottledDuration(until.Sub(start))
+		m.throttledUntil = until
+	}
+	return true
+}
+
+// resetThrottleBackoff resets the backoff once ARM list calls succeed again.
+func (m *azureCache) resetThrottleBackoff() {
+	m.throttleMutex.Lock()
+	defer m.throttleMutex.Unlock()
+
+	m.throttleBackoff = 0
+}
+
+// nextThrottleBackoff doubles the previous backoff, within the configured bounds.
+func nextThrottleBackoff(previous time.Duration) time.Duration {
+	if previous <= 0 {
+		return initialThrottleBackoff
+	}
+	if next := 2 * previous; next < maxThrottleBackoff {
+		return next
+	}
+	return maxThrottleBackoff
+}
+
 // fetchVirtualMachines returns the updated list of virtual machines in the config resource group using the Azure API.
-func (m *azureCache) fetchVirtualMachines() (map[string][]compute.VirtualMachine, error) {
+// Agent pools are deployed in that resource group only, the additional resource groups are ignored.
+func (m *azureCache) fetchVirtualMachines() (map[string][]compute.VirtualMachine, *retry.Error) {
 	ctx, cancel := getContextWithCancel()
 	defer cancel()
 
-	result, err := m.azClient.virtualMachinesClient.List(ctx, m.resourceGroup)
+	resourceGroup := m.resourceGroups[0]
+	start := time.Now()
+	result, err := m.azClient.virtualMachinesClient.List(ctx, resourceGroup)
+	observeARMRequest("VirtualMachines.List", start, err)
 	if err != nil {
-		klog.Errorf("VirtualMachinesClient.List in resource group %q failed: %v", m.resourceGroup, err)
-		return nil, err.Error()
+		klog.Errorf("VirtualMachinesClient.List in resource group %q failed: %v", resourceGroup, err)
+		return nil, err
 	}
 
 	instances := make(map[string][]compute.VirtualMachine)
@@ -215,24 +308,85 @@ func (m *azureCache) fetchVirtualMachines() (map[string][]compute.VirtualMachine
 	return instances, nil
 }
 
-// fetchScaleSets returns the updated list of scale sets in the config resource group using the Azure API.
-func (m *azureCache) fetchScaleSets() (map[string]compute.VirtualMachineScaleSet, error) {
+// fetchScaleSets returns the updated list of scale sets in the config resource groups using the Azure API, keyed by
+// scaleSetKey.
+func (m *azureCache) fetchScaleSets() (map[string]compute.VirtualMachineScaleSet, *retry.Error) {
 	ctx, cancel := getContextWithTimeout(vmssContextTimeout)
 	defer cancel()
 
-	result, err := m.azClient.virtualMachineScaleSetsClient.List(ctx, m.resourceGroup)
-	if err != nil {
-		klog.Errorf("VirtualMachineScaleSetsClient.List in resource group %q failed: %v", m.resourceGroup, err)
-		return nil, err.Error()
-	}
-
 	sets := make(map[string]compute.VirtualMachineScaleSet)
-	for _, vmss := range result {
-		sets[*vmss.Name] = vmss
+	for _, resourceGroup := range m.resourceGroups {
+		start := time.Now()
+		result, err := m.azClient.virtualMachineScaleSetsClient.List(ctx, resourceGroup)
+		observeARMRequest("VirtualMachineScaleSets.List", start, err)
+		if err != nil {
+			klog.Errorf("VirtualMachineScaleSetsClient.List in resource group %q failed: %v", resourceGroup, err)
+			return nil, err
+		}
+
+		for _, vmss := range result {
+			sets[m.scaleSetKey(resourceGroup, *vmss.Name)] = vmss
+		}
 	}
 	return sets, nil
 }
 
+// scaleSetKey returns the key of a scale set in the cache, which is also the ID of its node group: the resource group
+// followed by a slash and the name for the additional resource groups, so that scale sets with the same name in
+// different resource groups don't collide, and the name alone otherwise.
+func (m *azureCache) scaleSetKey(resourceGroup, name string) string {
+	for i := 1; i < len(m.resourceGroups); i++ {
+		if strings.EqualFold(resourceGroup, m.resourceGroups[i]) {
+			return m.resourceGroups[i] + "/" + name
+		}
+	}
+	return name
+}
+
+// splitScaleSetKey returns the additional resource group, empty for the resource group of the cluster, and the name
+// of the scale set from its key.
+func splitScaleSetKey(key, clusterResourceGroup string) (additionalResourceGroup, name string) {
+	i := strings.Index(key, "/")
+	if i < 0 {
+		return "", key
+	}
+	if strings.EqualFold(key[:i], clusterResourceGroup) {
+		return "", key[i+1:]
+	}
+	return key[:i], key[i+1:]
+}
+
+// refreshScaleSet gets the scale set from ARM and replaces its cache entry, leaving the other scale sets untouched.
+func (m *azureCache) refreshScaleSet(resourceGroup, name string) error {
+	if throttledUntil, throttled := m.throttled(); throttled {
+		klog.Warningf("ARM requests are throttled until %v, would return the cached vmss %s/%s", throttledUntil, resourceGroup, name)
+		return nil
+	}
+
+	ctx, cancel := getContextWithTimeout(vmssContextTimeout)
+	defer cancel()
+	start := time.Now()
+	vmss, rerr := m.azClient.virtualMachineScaleSetsClient.Get(ctx, resourceGroup, name)
+	observeARMRequest("VirtualMachineScaleSets.Get", start, rerr)
+	if rerr != nil {
+		if m.observeThrottling(rerr) {
+			return nil
+		}
+		return rerr.Error()
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	// The scale sets map is handed out to callers, so it is replaced rather than updated in place.
+	scaleSets := make(map[string]compute.VirtualMachineScaleSet, len(m.scaleSets))
+	for scaleSetName, scaleSet := range m.scaleSets {
+		scaleSets[scaleSetName] = scaleSet
+	}
+	scaleSets[m.scaleSetKey(resourceGroup, name)] = vmss
+	m.scaleSets = scaleSets
+	return nil
+}
+
 // Register registers a node group if it hasn't been registered.
 func (m *azureCache) Register(nodeGroup cloudprovider.NodeGroup) bool {
 	m.mutex.Lock()
@@ -245,21 +399,27 @@ func (m *azureCache) Register(nodeGroup cloudprovider.NodeGroup) bool {
 				return false
 			}
 
-			m.registeredNodeGroups[i] = nodeGroup
-			klog.V(4).Infof("Node group %q updated", nodeGroup.Id())
+			// Scale sets are updated in place, so that their cached size and instances are kept.
+			existingScaleSet, isScaleSet := existing.(*ScaleSet)
+			if _, ok := nodeGroup.(*ScaleSet); ok && isScaleSet {
+				existingScaleSet.setSizeLimits(nodeGroup.MinSize(), nodeGroup.MaxSize())
+			} else {
+				m.registeredNodeGroups[i] = nodeGroup
+			}
+			klogx.ProviderAzure.V(4).Infof("Node group %q updated", nodeGroup.Id())
 			m.invalidateUnownedInstanceCache()
 			return true
 		}
 	}
 
-	klog.V(4).Infof("Registering Node Group %q", nodeGroup.Id())
+	klogx.ProviderAzure.V(4).Infof("Registering Node Group %q", nodeGroup.Id())
 	m.registeredNodeGroups = append(m.registeredNodeGroups, nodeGroup)
 	m.invalidateUnownedInstanceCache()
 	return true
 }
 
 func (m *azureCache) invalidateUnownedInstanceCache() {
-	klog.V(4).Info("Invalidating unowned instance cache")
+	klogx.ProviderAzure.V(4).Info("Invalidating unowned instance cache")
 	m.unownedInstances = make(map[azureRef]bool)
 }
 
@@ -272,7 +432,7 @@ func (m *azureCache) Unregister(nodeGroup cloudprovider.NodeGroup) bool {
 	changed := false
 	for _, existing := range m.registeredNodeGroups {
 		if strings.EqualFold(existing.Id(), nodeGroup.Id()) {
-			klog.V(1).Infof("Unregistered node group %s", nodeGroup.Id())
+			klogx.ProviderAzure.V(1).Infof("Unregistered node group %s", nodeGroup.Id())
 			changed = true
 			continue
 		}
@@ -282,26 +442,75 @@ func (m *azureCache) Unregister(nodeGroup cloudprovider.NodeGroup) bool {
 	return changed
 }
 
-func (m *azureCache) fetchSKUs(ctx context.Context, location string) (*skewer.Cache, error) {
+func (m *azureCache) fetchSKUs(ctx context.Context, location string) (cache *skewer.Cache, err error) {
+	start := time.Now()
+	defer func() {
+		observeSDKRequest("ResourceSkus.List", start, err)
+	}()
 	return skewer.NewCache(ctx,
 		skewer.WithLocation(location),
 		skewer.WithResourceClient(m.azClient.skuClient),
 	)
 }
 
-func (m *azureCache) GetSKU(ctx context.Context, skuName, location string) (skewer.SKU, error) {
+// refreshExpiredSKUs refetches the SKUs of every location whose cache is older than skuCacheTTL.
+// A location that fails to refresh keeps serving its previous SKUs until the next attempt.
+func (m *azureCache) refreshExpiredSKUs(ctx context.Context) {
 	m.mutex.Lock()
-	defer m.mutex.Unlock()
+	locations := make([]string, 0, len(m.skus))
+	for location := range m.skus {
+		locations = append(locations, location)
+	}
+	m.mutex.Unlock()
 
-	cache, ok := m.skus[location]
-	if !ok {
-		var err error
-		cache, err = m.fetchSKUs(ctx, location)
-		if err != nil {
-			klog.V(1).Infof("Failed to instantiate cache, err: %v", err)
-			return skewer.SKU{}, err
+	for _, location := range locations {
+		if _, _, err := m.getSKUCache(ctx, location); err != nil {
+			klog.Warningf("Failed to refresh SKUs for location %s: %v", location, err)
 		}
-		m.skus[location] = cache
+	}
+}
+
+// getSKUCache returns the SKU cache of the location and the time it was fetched at, fetching it from
+// the SKU API if it is missing or expired. If the API is unavailable a stale cache is returned along
+// with the error, nil if there is none. The SKU API is called without holding the mutex, so that
+// a slow call doesn't block the rest of the cache.
+func (m *azureCache) getSKUCache(ctx context.Context, location string) (*skewer.Cache, time.Time, error) {
+	m.mutex.Lock()
+	cache, found := m.skus[location]
+	fetchedAt := m.skusFetchedAt[location]
+	m.mutex.Unlock()
+	if found && time.Since(fetchedAt) < m.skuCacheTTL {
+		return cache, fetchedAt, nil
+	}
+
+	fetched, err := m.fetchSKUs(ctx, location)
+	if err != nil {
+		if !found || fetchedAt.IsZero() {
+			return nil, time.Time{}, err
+		}
+		return cache, fetchedAt, err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	now := time.Now()
+	if m.skusFetchedAt[location].After(fetchedAt) {
+		// SKUs were refreshed concurrently, keep the ones already swapped in.
+		return m.skus[location], m.skusFetchedAt[location], nil
+	}
+	m.skus[location] = fetched
+	m.skusFetchedAt[location] = now
+	return fetched, now, nil
+}
+
+func (m *azureCache) GetSKU(ctx context.Context, skuName, location string) (skewer.SKU, error) {
+	cache, fetchedAt, err := m.getSKUCache(ctx, location)
+	if cache == nil {
+		klogx.ProviderAzure.V(1).Infof("Failed to instantiate cache, err: %v", err)
+		return skewer.SKU{}, err
+	}
+	if err != nil {
+		klogx.ProviderAzure.V(1).Infof("Failed to refresh SKUs for location %s, using SKUs fetched at %v: %v", location, fetchedAt, err)
 	}
 
 	return cache.Get(ctx, skuName, skewer.VirtualMachines, location)
@@ -326,9 +535,9 @@ func (m *azureCache) FindForInstance(instance *azureRef, vmType string) (cloudpr
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	klog.V(4).Infof("FindForInstance: starts, ref: %s", instance.Name)
+	klogx.ProviderAzure.V(4).Infof("FindForInstance: starts, ref: %s", instance.Name)
 	resourceID, err := convertResourceGroupNameToLower(instance.Name)
-	klog.V(4).Infof("FindForInstance: resourceID: %s", resourceID)
+	klogx.ProviderAzure.V(4).Infof("FindForInstance: resourceID: %s", resourceID)
 	if err != nil {
 		return nil, err
 	}
@@ -336,7 +545,7 @@ func (m *azureCache) FindForInstance(instance *azureRef, vmType string) (cloudpr
 	if m.unownedInstances[inst] {
 		// We already know we don't own this instance. Return early and avoid
 		// additional calls.
-		klog.V(4).Infof("FindForInstance: Couldn't find NodeGroup of instance %q", inst)
+		klogx.ProviderAzure.V(4).Infof("FindForInstance: Couldn't find NodeGroup of instance %q", inst)
 		return nil, nil
 	}
 
@@ -344,7 +553,7 @@ func (m *azureCache) FindForInstance(instance *azureRef, vmType string) (cloudpr
 		if m.areAllScaleSetsUniform() {
 			// Omit virtual machines not managed by vmss only in case of uniform scale set.
 			if ok := virtualMachineRE.Match([]byte(inst.Name)); ok {
-				klog.V(3).Infof("Instance %q is not managed by vmss, omit it in autoscaler", instance.Name)
+				klogx.ProviderAzure.V(3).Infof("Instance %q is not managed by vmss, omit it in autoscaler", instance.Name)
 				m.unownedInstances[inst] = true
 				return nil, nil
 			}
@@ -354,22 +563,140 @@ func (m *azureCache) FindForInstance(instance *azureRef, vmType string) (cloudpr
 	if vmType == vmTypeStandard {
 		// Omit virtual machines with providerID not in Azure resource ID format.
 		if ok := virtualMachineRE.Match([]byte(inst.Name)); !ok {
-			klog.V(3).Infof("Instance %q is not in Azure resource ID format, omit it in autoscaler", instance.Name)
+			klogx.ProviderAzure.V(3).Infof("Instance %q is not in Azure resource ID format, omit it in autoscaler", instance.Name)
 			m.unownedInstances[inst] = true
 			return nil, nil
 		}
 	}
 
 	// Look up caches for the instance.
-	klog.V(6).Infof("FindForInstance: attempting to retrieve instance %v from cache", m.instanceToNodeGroup)
+	klogx.ProviderAzure.V(6).Infof("FindForInstance: attempting to retrieve instance %v from cache", m.instanceToNodeGroup)
 	if nodeGroup := m.getInstanceFromCache(inst.Name); nodeGroup != nil {
-		klog.V(4).Infof("FindForInstance: found node group %q in cache", nodeGroup.Id())
+		klogx.ProviderAzure.V(4).Infof("FindForInstance: found node group %q in cache", nodeGroup.Id())
 		return nodeGroup, nil
 	}
-	klog.V(4).Infof("FindForInstance: Couldn't find node group of instance %q", inst)
+	klogx.ProviderAzure.V(4).Infof("FindForInstance: Couldn't find node group of instance %q", inst)
+	// The instance is either new or its provider ID changed format, e.g. after infrastructure
+	// tooling changed its casing: have the mapping rebuilt on next refresh.
+	if m.isRegisteredScaleSetInstance(inst.Name) {
+		klogx.ProviderAzure.V(2).Infof("FindForInstance: instance %q of a registered scale set is missing from the cache, marking it stale", inst)
+		m.instanceMappingStale = true
+	}
 	return nil, nil
 }
 
+// isRegisteredScaleSetInstance returns true if the given provider ID is an instance of a registered scale set.
+// Should be called with lock.
+func (m *azureCache) isRegisteredScaleSetInstance(providerID string) bool {
+	key, ok := m.scaleSetKeyOfInstance(providerID)
+	if !ok {
+		return false
+	}
+	for _, nodeGroup := range m.registeredNodeGroups {
+		if strings.EqualFold(nodeGroup.Id(), key) {
+			return true
+		}
+	}
+	return false
+}
+
+// isInstanceMappingStale returns true if the instance to node group mapping needs to be rebuilt.
+func (m *azureCache) isInstanceMappingStale() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.instanceMappingStale
+}
+
+// HasInstance returns if the given instance exists in the cache and is not being deleted.
+// Instances of registered scale sets missing from a VM listing done after registeredSince are
+// reported as deleted. cloudprovider.ErrNotImplemented is returned for other instances which are
+// not in the cache, so that the caller can fall back to its own detection of deleted instances.
+func (m *azureCache) HasInstance(providerID string, registeredSince time.Time) (bool, error) {
+	resourceID, err := convertResourceGroupNameToLower(providerID)
+	if err != nil {
+		return false, err
+	}
+	// The scale set is looked up under the cache lock, but queried after releasing it: scale sets
+	// take their instance lock before the cache lock when listing their instances.
+	nodeGroup, registered := m.nodeGroupOfInstance(resourceID)
+	if nodeGroup == nil {
+		return false, cloudprovider.ErrNotImplemented
+	}
+	scaleSet, ok := nodeGroup.(*ScaleSet)
+	if !ok {
+		return true, nil
+	}
+	if scaleSet.isStopped(resourceID) {
+		klogx.ProviderAzure.V(4).Infof("HasInstance: instance %s was stopped by a scale-down", resourceID)
+		return false, nil
+	}
+	instance, found := scaleSet.getInstanceByProviderID(resourceID)
+	if !found {
+		if !registered {
+			return true, nil
+		}
+		// VMs are created before their nodes register, so the instance is gone only if
+		// the listing is more recent than the node.
+		if scaleSet.listedSince(registeredSince) {
+			klogx.ProviderAzure.V(4).Infof("HasInstance: instance %s not found in vmss %s", resourceID, scaleSet.Name)
+			return false, nil
+		}
+		return false, cloudprovider.ErrNotImplemented
+	}
+	if instance.Status != nil && instance.Status.State == cloudprovider.InstanceDeleting {
+		klogx.ProviderAzure.V(4).Infof("HasInstance: instance %s is being deleted", resourceID)
+		return false, nil
+	}
+	return true, nil
+}
+
+// nodeGroupOfInstance returns the node group of the given instance from the instance cache or, failing
+// that, the registered scale set its provider ID belongs to, in which case registered is true.
+func (m *azureCache) nodeGroupOfInstance(providerID string) (nodeGroup cloudprovider.NodeGroup, registered bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if nodeGroup := m.getInstanceFromCache(providerID); nodeGroup != nil {
+		return nodeGroup, false
+	}
+	if scaleSet := m.getRegisteredScaleSet(providerID); scaleSet != nil {
+		return scaleSet, true
+	}
+	return nil, false
+}
+
+// scaleSetKeyOfInstance returns the key of the scale set the given provider ID is an instance of, if it is one.
+func (m *azureCache) scaleSetKeyOfInstance(providerID string) (string, bool) {
+	matches := scaleSetInstanceIDRE.FindStringSubmatch(providerID)
+	if len(matches) != 2 {
+		matches = flexScaleSetInstanceIDRE.FindStringSubmatch(providerID)
+	}
+	if len(matches) != 2 {
+		return "", false
+	}
+	resourceGroup := ""
+	if rgMatches := azureResourceGroupNameRE.FindStringSubmatch(providerID); len(rgMatches) == 2 {
+		resourceGroup = rgMatches[1]
+	}
+	return m.scaleSetKey(resourceGroup, matches[1]), true
+}
+
+// getRegisteredScaleSet returns the registered scale set the given provider ID belongs to, or nil.
+// Should be called with lock.
+func (m *azureCache) getRegisteredScaleSet(providerID string) *ScaleSet {
+	key, ok := m.scaleSetKeyOfInstance(providerID)
+	if !ok {
+		return nil
+	}
+	for _, nodeGroup := range m.registeredNodeGroups {
+		if scaleSet, ok := nodeGroup.(*ScaleSet); ok && strings.EqualFold(scaleSet.Id(), key) {
+			return scaleSet
+		}
+	}
+	return nil
+}
+
 // isAllScaleSetsAreUniform determines if all the scale set autoscaler is monitoring are Uniform or not.
 func (m *azureCache) areAllScaleSetsUniform() bool {
 	for _, scaleSet := range m.scaleSets {
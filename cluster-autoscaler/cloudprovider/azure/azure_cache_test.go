@@ -17,10 +17,21 @@ limitations under the License.
 package azure
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
+	skucompute "github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2019-07-01/compute"
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2022-08-01/compute"
+	"github.com/Azure/skewer"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/config/dynamic"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmssclient/mockvmssclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
 
+	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -70,3 +81,161 @@ func TestFindForInstance(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, ac.unownedInstances[inst])
 }
+
+func TestFindForInstanceMarksInstanceMappingStale(t *testing.T) {
+	provider := newTestProvider(t)
+	ac := provider.azureManager.azureCache
+	ac.registeredNodeGroups = []cloudprovider.NodeGroup{newTestScaleSet(provider.azureManager, "ss")}
+
+	// instances of unknown scale sets don't make the mapping stale
+	inst := azureRef{Name: "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/other/virtualMachines/0"}
+	nodeGroup, err := ac.FindForInstance(&inst, vmTypeVMSS)
+	assert.Nil(t, nodeGroup)
+	assert.NoError(t, err)
+	assert.False(t, ac.isInstanceMappingStale())
+
+	inst = azureRef{Name: "azure:///subscriptions/sub/resourcegroups/rg/providers/microsoft.compute/virtualmachinescalesets/SS/virtualmachines/0"}
+	nodeGroup, err = ac.FindForInstance(&inst, vmTypeVMSS)
+	assert.Nil(t, nodeGroup)
+	assert.NoError(t, err)
+	assert.True(t, ac.isInstanceMappingStale())
+}
+
+func TestIsRegisteredScaleSetInstance(t *testing.T) {
+	ac := &azureCache{registeredNodeGroups: []cloudprovider.NodeGroup{newTestScaleSet(nil, "ss")}}
+
+	assert.True(t, ac.isRegisteredScaleSetInstance("azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/ss/virtualMachines/0"))
+	// VMs of Flexible scale sets are named after the scale set.
+	assert.True(t, ac.isRegisteredScaleSetInstance("azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/ss_1a2b3c4d"))
+	assert.False(t, ac.isRegisteredScaleSetInstance("azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/other_1a2b3c4d"))
+	assert.False(t, ac.isRegisteredScaleSetInstance("azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/ss"))
+}
+
+func TestFetchAzureResourcesThrottled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cachedScaleSets := map[string]compute.VirtualMachineScaleSet{"test-vmss": newTestVMSSList(3, "test-vmss", "eastus", compute.Uniform)[0]}
+	throttledErr := &retry.Error{HTTPStatusCode: http.StatusTooManyRequests, RetryAfter: time.Now().Add(time.Hour)}
+	mockVMSSClient := mockvmssclient.NewMockInterface(ctrl)
+	// Once throttled, list calls are suspended until the Retry-After time.
+	mockVMSSClient.EXPECT().List(gomock.Any(), "rg").Return(nil, throttledErr).Times(1)
+
+	ac := &azureCache{
+		azClient:       &azClient{virtualMachineScaleSetsClient: mockVMSSClient},
+		resourceGroups: []string{"rg"},
+		vmType:         vmTypeVMSS,
+		scaleSets:      cachedScaleSets,
+	}
+
+	assert.NoError(t, ac.fetchAzureResources())
+	assert.Equal(t, cachedScaleSets, ac.scaleSets)
+	throttledUntil, throttled := ac.throttled()
+	assert.True(t, throttled)
+	assert.Equal(t, throttledErr.RetryAfter, throttledUntil)
+
+	assert.NoError(t, ac.fetchAzureResources())
+	assert.Equal(t, cachedScaleSets, ac.scaleSets)
+}
+
+func TestObserveThrottling(t *testing.T) {
+	ac := &azureCache{}
+
+	assert.False(t, ac.observeThrottling(&retry.Error{HTTPStatusCode: http.StatusInternalServerError}))
+	_, throttled := ac.throttled()
+	assert.False(t, throttled)
+
+	// Without a Retry-After header, the backoff doubles up to its maximum.
+	assert.True(t, ac.observeThrottling(&retry.Error{HTTPStatusCode: http.StatusTooManyRequests}))
+	assert.Equal(t, initialThrottleBackoff, ac.throttleBackoff)
+	_, throttled = ac.throttled()
+	assert.True(t, throttled)
+	assert.True(t, ac.observeThrottling(&retry.Error{HTTPStatusCode: http.StatusTooManyRequests}))
+	assert.Equal(t, 2*initialThrottleBackoff, ac.throttleBackoff)
+	ac.throttleBackoff = maxThrottleBackoff
+	assert.True(t, ac.observeThrottling(&retry.Error{HTTPStatusCode: http.StatusTooManyRequests}))
+	assert.Equal(t, maxThrottleBackoff, ac.throttleBackoff)
+
+	ac.resetThrottleBackoff()
+	assert.Equal(t, time.Duration(0), ac.throttleBackoff)
+}
+
+func TestFetchScaleSetsFromResourceGroups(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockVMSSClient := mockvmssclient.NewMockInterface(ctrl)
+	mockVMSSClient.EXPECT().List(gomock.Any(), "rg").Return(newTestVMSSList(3, "test-vmss", "eastus", compute.Uniform), nil)
+	mockVMSSClient.EXPECT().List(gomock.Any(), "other-rg").Return(append(
+		newTestVMSSList(2, "other-vmss", "eastus", compute.Uniform),
+		newTestVMSSList(1, "test-vmss", "eastus", compute.Uniform)...), nil)
+
+	ac := &azureCache{
+		azClient:       &azClient{virtualMachineScaleSetsClient: mockVMSSClient},
+		resourceGroups: []string{"rg", "other-rg"},
+		vmType:         vmTypeVMSS,
+	}
+
+	assert.NoError(t, ac.fetchAzureResources())
+	assert.Len(t, ac.scaleSets, 3)
+	// Scale sets with the same name in different resource groups are both kept.
+	assert.Equal(t, int64(3), *ac.scaleSets["test-vmss"].Sku.Capacity)
+	assert.Equal(t, int64(1), *ac.scaleSets["other-rg/test-vmss"].Sku.Capacity)
+	assert.Contains(t, ac.scaleSets, "other-rg/other-vmss")
+}
+
+func TestScaleSetResourceGroup(t *testing.T) {
+	manager := newTestAzureManager(t)
+	manager.azureCache.resourceGroups = []string{"rg", "other-rg"}
+
+	scaleSet, err := NewScaleSet(&dynamic.NodeGroupSpec{Name: "test-vmss", MinSize: 1, MaxSize: 5}, manager, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, "rg", scaleSet.resourceGroup())
+	assert.Equal(t, "test-vmss", scaleSet.Id())
+
+	scaleSet, err = NewScaleSet(&dynamic.NodeGroupSpec{Name: "other-rg/test-vmss", MinSize: 1, MaxSize: 5}, manager, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, "other-rg", scaleSet.resourceGroup())
+	assert.Equal(t, "test-vmss", scaleSet.Name)
+	assert.Equal(t, "other-rg/test-vmss", scaleSet.Id())
+
+	// Instances are matched to the scale set of their own resource group.
+	manager.azureCache.registeredNodeGroups = []cloudprovider.NodeGroup{scaleSet}
+	assert.True(t, manager.azureCache.isRegisteredScaleSetInstance("azure:///subscriptions/sub/resourceGroups/other-rg/providers/Microsoft.Compute/virtualMachineScaleSets/test-vmss/virtualMachines/0"))
+	assert.False(t, manager.azureCache.isRegisteredScaleSetInstance("azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/test-vmss/virtualMachines/0"))
+}
+
+func TestGetSKUFetchesWithoutHoldingMutex(t *testing.T) {
+	fetching := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(fetching)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"value": [{"name": "Standard_D2_v2", "resourceType": "virtualMachines", "locations": ["eastus"]}]}`))
+	}))
+	defer server.Close()
+
+	ac := &azureCache{
+		azClient:      &azClient{skuClient: skucompute.NewResourceSkusClientWithBaseURI(server.URL, "subscription")},
+		skus:          make(map[string]*skewer.Cache),
+		skusFetchedAt: make(map[string]time.Time),
+		skuCacheTTL:   time.Hour,
+	}
+	result := make(chan error)
+	go func() {
+		_, err := ac.GetSKU(context.Background(), "Standard_D2_v2", "eastus")
+		result <- err
+	}()
+
+	// The rest of the cache stays available while the SKUs are fetched.
+	<-fetching
+	ac.mutex.Lock()
+	ac.mutex.Unlock()
+	close(release)
+
+	assert.NoError(t, <-result)
+	assert.False(t, ac.skusFetchedAt["eastus"].IsZero())
+	_, err := ac.GetSKU(context.Background(), "Standard_D2_v2", "eastus")
+	assert.NoError(t, err)
+}
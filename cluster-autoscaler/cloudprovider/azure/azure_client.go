@@ -18,6 +18,8 @@ package azure
 
 import (
 	"context"
+	"crypto/rsa"
+	"crypto/x509"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -32,12 +34,13 @@ import (
 	"github.com/Azure/go-autorest/autorest/azure"
 	"github.com/Azure/go-autorest/autorest/azure/auth"
 
-	klog "k8s.io/klog/v2"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
 
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/containerserviceclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/diskclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/interfaceclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/storageaccountclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmasclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmssclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmssvmclient"
@@ -69,27 +72,33 @@ func newAzDeploymentsClient(subscriptionID, endpoint string, authorizer autorest
 }
 
 func (az *azDeploymentsClient) Get(ctx context.Context, resourceGroupName string, deploymentName string) (result resources.DeploymentExtended, err error) {
-	klog.V(10).Infof("azDeploymentsClient.Get(%q,%q): start", resourceGroupName, deploymentName)
+	start := time.Now()
+	klogx.ProviderAzure.V(10).Infof("azDeploymentsClient.Get(%q,%q): start", resourceGroupName, deploymentName)
 	defer func() {
-		klog.V(10).Infof("azDeploymentsClient.Get(%q,%q): end", resourceGroupName, deploymentName)
+		observeSDKRequest("Deployments.Get", start, err)
+		klogx.ProviderAzure.V(10).Infof("azDeploymentsClient.Get(%q,%q): end", resourceGroupName, deploymentName)
 	}()
 
 	return az.client.Get(ctx, resourceGroupName, deploymentName)
 }
 
 func (az *azDeploymentsClient) ExportTemplate(ctx context.Context, resourceGroupName string, deploymentName string) (result resources.DeploymentExportResult, err error) {
-	klog.V(10).Infof("azDeploymentsClient.ExportTemplate(%q,%q): start", resourceGroupName, deploymentName)
+	start := time.Now()
+	klogx.ProviderAzure.V(10).Infof("azDeploymentsClient.ExportTemplate(%q,%q): start", resourceGroupName, deploymentName)
 	defer func() {
-		klog.V(10).Infof("azDeploymentsClient.ExportTemplate(%q,%q): end", resourceGroupName, deploymentName)
+		observeSDKRequest("Deployments.ExportTemplate", start, err)
+		klogx.ProviderAzure.V(10).Infof("azDeploymentsClient.ExportTemplate(%q,%q): end", resourceGroupName, deploymentName)
 	}()
 
 	return az.client.ExportTemplate(ctx, resourceGroupName, deploymentName)
 }
 
 func (az *azDeploymentsClient) CreateOrUpdate(ctx context.Context, resourceGroupName string, deploymentName string, parameters resources.Deployment) (resp *http.Response, err error) {
-	klog.V(10).Infof("azDeploymentsClient.CreateOrUpdate(%q,%q): start", resourceGroupName, deploymentName)
+	start := time.Now()
+	klogx.ProviderAzure.V(10).Infof("azDeploymentsClient.CreateOrUpdate(%q,%q): start", resourceGroupName, deploymentName)
 	defer func() {
-		klog.V(10).Infof("azDeploymentsClient.CreateOrUpdate(%q,%q): end", resourceGroupName, deploymentName)
+		observeSDKRequest("Deployments.CreateOrUpdate", start, err)
+		klogx.ProviderAzure.V(10).Infof("azDeploymentsClient.CreateOrUpdate(%q,%q): end", resourceGroupName, deploymentName)
 	}()
 
 	future, err := az.client.CreateOrUpdate(ctx, resourceGroupName, deploymentName, parameters)
@@ -102,9 +111,11 @@ func (az *azDeploymentsClient) CreateOrUpdate(ctx context.Context, resourceGroup
 }
 
 func (az *azDeploymentsClient) List(ctx context.Context, resourceGroupName, filter string, top *int32) (result []resources.DeploymentExtended, err error) {
-	klog.V(10).Infof("azDeploymentsClient.List(%q): start", resourceGroupName)
+	start := time.Now()
+	klogx.ProviderAzure.V(10).Infof("azDeploymentsClient.List(%q): start", resourceGroupName)
 	defer func() {
-		klog.V(10).Infof("azDeploymentsClient.List(%q): end", resourceGroupName)
+		observeSDKRequest("Deployments.List", start, err)
+		klogx.ProviderAzure.V(10).Infof("azDeploymentsClient.List(%q): end", resourceGroupName)
 	}()
 
 	iterator, err := az.client.ListByResourceGroupComplete(ctx, resourceGroupName, filter, top)
@@ -125,9 +136,11 @@ func (az *azDeploymentsClient) List(ctx context.Context, resourceGroupName, filt
 }
 
 func (az *azDeploymentsClient) Delete(ctx context.Context, resourceGroupName, deploymentName string) (resp *http.Response, err error) {
-	klog.V(10).Infof("azDeploymentsClient.Delete(%q,%q): start", resourceGroupName, deploymentName)
+	start := time.Now()
+	klogx.ProviderAzure.V(10).Infof("azDeploymentsClient.Delete(%q,%q): start", resourceGroupName, deploymentName)
 	defer func() {
-		klog.V(10).Infof("azDeploymentsClient.Delete(%q,%q): end", resourceGroupName, deploymentName)
+		observeSDKRequest("Deployments.Delete", start, err)
+		klogx.ProviderAzure.V(10).Infof("azDeploymentsClient.Delete(%q,%q): end", resourceGroupName, deploymentName)
 	}()
 
 	future, err := az.client.Delete(ctx, resourceGroupName, deploymentName)
@@ -147,12 +160,19 @@ type azClient struct {
 	virtualMachineScaleSetsClient   vmssclient.Interface
 	virtualMachineScaleSetVMsClient vmssvmclient.Interface
 	virtualMachinesClient           vmclient.Interface
+	availabilitySetsClient          vmasclient.Interface
 	deploymentsClient               DeploymentsClient
 	interfacesClient                interfaceclient.Interface
 	disksClient                     diskclient.Interface
 	storageAccountsClient           storageaccountclient.Interface
 	managedKubernetesServicesClient containerserviceclient.Interface
 	skuClient                       compute.ResourceSkusClient
+	scaleSetPowerClient             ScaleSetPowerClient
+	scaleSetDeleteClient            ScaleSetDeleteClient
+	usagesClient                    UsagesClient
+
+	// authorizer is shared by all clients and replaced when credentials are rotated.
+	authorizer *reloadableAuthorizer
 }
 
 // newServicePrincipalTokenFromCredentials creates a new ServicePrincipalToken using values of the
@@ -164,37 +184,45 @@ func newServicePrincipalTokenFromCredentials(config *Config, env *azure.Environm
 	}
 
 	if config.UseWorkloadIdentityExtension {
-		klog.V(2).Infoln("azure: using workload identity extension to retrieve access token")
-		jwt, err := os.ReadFile(config.AADFederatedTokenFile)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read a file with a federated token: %v", err)
+		klogx.ProviderAzure.V(2).Infoln("azure: using workload identity extension to retrieve access token")
+		// The federated token file is rotated by kubelet, so it is read again every time
+		// the access token is refreshed.
+		jwtCallback := federatedTokenCallback(config.AADFederatedTokenFile)
+		if _, err := jwtCallback(); err != nil {
+			return nil, err
 		}
-		token, err := adal.NewServicePrincipalTokenFromFederatedToken(*oauthConfig, config.AADClientID, string(jwt), env.ResourceManagerEndpoint)
+		token, err := adal.NewServicePrincipalTokenFromFederatedTokenCallback(*oauthConfig, config.AADClientID, jwtCallback, env.ResourceManagerEndpoint)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create a workload identity token: %v", err)
 		}
 		return token, nil
 	}
 	if config.UseManagedIdentityExtension {
-		klog.V(2).Infoln("azure: using managed identity extension to retrieve access token")
+		klogx.ProviderAzure.V(2).Infoln("azure: using managed identity extension to retrieve access token")
 		msiEndpoint, err := adal.GetMSIVMEndpoint()
 		if err != nil {
 			return nil, fmt.Errorf("getting the managed service identity endpoint: %v", err)
 		}
+		if len(config.UserAssignedIdentityResourceID) > 0 {
+			klogx.ProviderAzure.V(4).Info("azure: using User Assigned MSI resource ID to retrieve access token")
+			return adal.NewServicePrincipalTokenFromMSIWithIdentityResourceID(msiEndpoint,
+				env.ServiceManagementEndpoint,
+				config.UserAssignedIdentityResourceID)
+		}
 		if len(config.UserAssignedIdentityID) > 0 {
-			klog.V(4).Info("azure: using User Assigned MSI ID to retrieve access token")
+			klogx.ProviderAzure.V(4).Info("azure: using User Assigned MSI ID to retrieve access token")
 			return adal.NewServicePrincipalTokenFromMSIWithUserAssignedID(msiEndpoint,
 				env.ServiceManagementEndpoint,
 				config.UserAssignedIdentityID)
 		}
-		klog.V(4).Info("azure: using System Assigned MSI to retrieve access token")
+		klogx.ProviderAzure.V(4).Info("azure: using System Assigned MSI to retrieve access token")
 		return adal.NewServicePrincipalTokenFromMSI(
 			msiEndpoint,
 			env.ServiceManagementEndpoint)
 	}
 
 	if len(config.AADClientSecret) > 0 {
-		klog.V(2).Infoln("azure: using client_id+client_secret to retrieve access token")
+		klogx.ProviderAzure.V(2).Infoln("azure: using client_id+client_secret to retrieve access token")
 		return adal.NewServicePrincipalToken(
 			*oauthConfig,
 			config.AADClientID,
@@ -203,14 +231,10 @@ func newServicePrincipalTokenFromCredentials(config *Config, env *azure.Environm
 	}
 
 	if len(config.AADClientCertPath) > 0 && len(config.AADClientCertPassword) > 0 {
-		klog.V(2).Infoln("azure: using jwt client_assertion (client_cert+client_private_key) to retrieve access token")
-		certData, err := ioutil.ReadFile(config.AADClientCertPath)
-		if err != nil {
-			return nil, fmt.Errorf("reading the client certificate from file %s: %v", config.AADClientCertPath, err)
-		}
-		certificate, privateKey, err := decodePkcs12(certData, config.AADClientCertPassword)
+		klogx.ProviderAzure.V(2).Infoln("azure: using jwt client_assertion (client_cert+client_private_key) to retrieve access token")
+		certificate, privateKey, err := readClientCertificate(config)
 		if err != nil {
-			return nil, fmt.Errorf("decoding the client certificate: %v", err)
+			return nil, err
 		}
 		return adal.NewServicePrincipalTokenFromCertificate(
 			*oauthConfig,
@@ -223,11 +247,76 @@ func newServicePrincipalTokenFromCredentials(config *Config, env *azure.Environm
 	return nil, fmt.Errorf("no credentials provided for AAD application %s", config.AADClientID)
 }
 
+// newMultiTenantServicePrincipalTokenFromCredentials creates a MultiTenantServicePrincipalToken for the tenant of
+// the service principal and its auxiliary tenants, from its client secret or certificate.
+func newMultiTenantServicePrincipalTokenFromCredentials(config *Config, env *azure.Environment) (*adal.MultiTenantServicePrincipalToken, error) {
+	multiTenantOAuthConfig, err := adal.NewMultiTenantOAuthConfig(env.ActiveDirectoryEndpoint, config.TenantID, config.AuxiliaryTenantIDs, adal.OAuthOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("creating the multi-tenant OAuth config: %v", err)
+	}
+
+	if len(config.AADClientSecret) > 0 {
+		klogx.ProviderAzure.V(2).Infof("azure: using client_id+client_secret to retrieve access tokens of auxiliary tenants %v", config.AuxiliaryTenantIDs)
+		return adal.NewMultiTenantServicePrincipalToken(
+			multiTenantOAuthConfig,
+			config.AADClientID,
+			config.AADClientSecret,
+			env.ServiceManagementEndpoint)
+	}
+
+	if len(config.AADClientCertPath) > 0 && len(config.AADClientCertPassword) > 0 {
+		klogx.ProviderAzure.V(2).Infof("azure: using jwt client_assertion (client_cert+client_private_key) to retrieve access tokens of auxiliary tenants %v", config.AuxiliaryTenantIDs)
+		certificate, privateKey, err := readClientCertificate(config)
+		if err != nil {
+			return nil, err
+		}
+		return adal.NewMultiTenantServicePrincipalTokenFromCertificate(
+			multiTenantOAuthConfig,
+			config.AADClientID,
+			certificate,
+			privateKey,
+			env.ServiceManagementEndpoint)
+	}
+
+	return nil, fmt.Errorf("no client secret or certificate provided for AAD application %s", config.AADClientID)
+}
+
+// readClientCertificate reads the client certificate and private key of the service principal.
+func readClientCertificate(config *Config) (*x509.Certificate, *rsa.PrivateKey, error) {
+	certData, err := ioutil.ReadFile(config.AADClientCertPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading the client certificate from file %s: %v", config.AADClientCertPath, err)
+	}
+	certificate, privateKey, err := decodePkcs12(certData, config.AADClientCertPassword)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding the client certificate: %v", err)
+	}
+	return certificate, privateKey, nil
+}
+
+// federatedTokenCallback returns a callback reading the federated token from the given file.
+func federatedTokenCallback(path string) adal.JWTCallback {
+	return func() (string, error) {
+		jwt, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read a file with a federated token: %v", err)
+		}
+		return string(jwt), nil
+	}
+}
+
 func newAuthorizer(config *Config, env *azure.Environment) (autorest.Authorizer, error) {
 	switch config.AuthMethod {
 	case authMethodCLI:
 		return auth.NewAuthorizerFromCLI()
 	case "", authMethodPrincipal:
+		if len(config.AuxiliaryTenantIDs) > 0 {
+			token, err := newMultiTenantServicePrincipalTokenFromCredentials(config, env)
+			if err != nil {
+				return nil, fmt.Errorf("retrieve multi-tenant service principal token: %v", err)
+			}
+			return autorest.NewMultiTenantBearerAuthorizer(token), nil
+		}
 		token, err := newServicePrincipalTokenFromCredentials(config, env)
 		if err != nil {
 			return nil, fmt.Errorf("retrieve service principal token: %v", err)
@@ -239,50 +328,74 @@ func newAuthorizer(config *Config, env *azure.Environment) (autorest.Authorizer,
 }
 
 func newAzClient(cfg *Config, env *azure.Environment) (*azClient, error) {
-	authorizer, err := newAuthorizer(cfg, env)
+	baseAuthorizer, err := newAuthorizer(cfg, env)
 	if err != nil {
 		return nil, err
 	}
+	authorizer := newReloadableAuthorizer(baseAuthorizer)
 
 	azClientConfig := cfg.getAzureClientConfig(authorizer, env)
 	azClientConfig.UserAgent = getUserAgentExtension()
 
-	vmssClientConfig := azClientConfig.WithRateLimiter(cfg.VirtualMachineScaleSetRateLimit)
-	scaleSetsClient := vmssclient.New(vmssClientConfig)
-	klog.V(5).Infof("Created scale set client with authorizer: %v", scaleSetsClient)
-
-	vmssVMClientConfig := azClientConfig.WithRateLimiter(cfg.VirtualMachineScaleSetRateLimit)
-	scaleSetVMsClient := vmssvmclient.New(vmssVMClientConfig)
-	klog.V(5).Infof("Created scale set vm client with authorizer: %v", scaleSetVMsClient)
+	var scaleSetsClient vmssclient.Interface
+	var scaleSetVMsClient vmssvmclient.Interface
+	if cfg.EnableTrack2SDK {
+		// The track 2 clients rely on the retry policy of the SDK, the client rate limits don't apply to them.
+		scaleSetsClient, scaleSetVMsClient, err = newTrack2ScaleSetClients(cfg.SubscriptionID, env, authorizer)
+		if err != nil {
+			return nil, err
+		}
+		klogx.ProviderAzure.V(5).Infof("Created track 2 scale set and scale set vm clients")
+	} else {
+		vmssClientConfig := azClientConfig.WithRateLimiter(cfg.VirtualMachineScaleSetRateLimit)
+		scaleSetsClient = vmssclient.New(vmssClientConfig)
+		klogx.ProviderAzure.V(5).Infof("Created scale set client with authorizer: %v", scaleSetsClient)
+
+		vmssVMClientConfig := azClientConfig.WithRateLimiter(cfg.VirtualMachineScaleSetRateLimit)
+		scaleSetVMsClient = vmssvmclient.New(vmssVMClientConfig)
+		klogx.ProviderAzure.V(5).Infof("Created scale set vm client with authorizer: %v", scaleSetVMsClient)
+	}
 
 	vmClientConfig := azClientConfig.WithRateLimiter(cfg.VirtualMachineRateLimit)
 	virtualMachinesClient := vmclient.New(vmClientConfig)
-	klog.V(5).Infof("Created vm client with authorizer: %v", virtualMachinesClient)
+	klogx.ProviderAzure.V(5).Infof("Created vm client with authorizer: %v", virtualMachinesClient)
+
+	availabilitySetsClient := vmasclient.New(vmClientConfig)
+	klogx.ProviderAzure.V(5).Infof("Created availability set client with authorizer: %v", availabilitySetsClient)
 
 	deploymentsClient := newAzDeploymentsClient(cfg.SubscriptionID, env.ResourceManagerEndpoint, authorizer)
-	klog.V(5).Infof("Created deployments client with authorizer: %v", deploymentsClient)
+	klogx.ProviderAzure.V(5).Infof("Created deployments client with authorizer: %v", deploymentsClient)
+
+	scaleSetPowerClient := newAzScaleSetPowerClient(cfg.SubscriptionID, env.ResourceManagerEndpoint, authorizer, cfg.HibernateAPIVersion)
+	klogx.ProviderAzure.V(5).Infof("Created scale set power client with authorizer: %v", scaleSetPowerClient)
+
+	scaleSetDeleteClient := newAzScaleSetDeleteClient(cfg.SubscriptionID, env.ResourceManagerEndpoint, authorizer)
+	klogx.ProviderAzure.V(5).Infof("Created scale set delete client with authorizer: %v", scaleSetDeleteClient)
+
+	usagesClient := newAzUsagesClient(cfg.SubscriptionID, env.ResourceManagerEndpoint, authorizer)
+	klogx.ProviderAzure.V(5).Infof("Created usages client with authorizer: %v", usagesClient)
 
 	interfaceClientConfig := azClientConfig.WithRateLimiter(cfg.InterfaceRateLimit)
 	interfacesClient := interfaceclient.New(interfaceClientConfig)
-	klog.V(5).Infof("Created interfaces client with authorizer: %v", interfacesClient)
+	klogx.ProviderAzure.V(5).Infof("Created interfaces client with authorizer: %v", interfacesClient)
 
 	accountClientConfig := azClientConfig.WithRateLimiter(cfg.StorageAccountRateLimit)
 	storageAccountsClient := storageaccountclient.New(accountClientConfig)
-	klog.V(5).Infof("Created storage accounts client with authorizer: %v", storageAccountsClient)
+	klogx.ProviderAzure.V(5).Infof("Created storage accounts client with authorizer: %v", storageAccountsClient)
 
 	diskClientConfig := azClientConfig.WithRateLimiter(cfg.DiskRateLimit)
 	disksClient := diskclient.New(diskClientConfig)
-	klog.V(5).Infof("Created disks client with authorizer: %v", disksClient)
+	klogx.ProviderAzure.V(5).Infof("Created disks client with authorizer: %v", disksClient)
 
 	aksClientConfig := azClientConfig.WithRateLimiter(cfg.KubernetesServiceRateLimit)
 	kubernetesServicesClient := containerserviceclient.New(aksClientConfig)
-	klog.V(5).Infof("Created kubernetes services client with authorizer: %v", kubernetesServicesClient)
+	klogx.ProviderAzure.V(5).Infof("Created kubernetes services client with authorizer: %v", kubernetesServicesClient)
 
 	// Reference on why selecting ResourceManagerEndpoint as baseURI -
 	// https://github.com/Azure/go-autorest/blob/main/autorest/azure/environments.go
 	skuClient := compute.NewResourceSkusClientWithBaseURI(azClientConfig.ResourceManagerEndpoint, cfg.SubscriptionID)
 	skuClient.Authorizer = azClientConfig.Authorizer
-	klog.V(5).Infof("Created sku client with authorizer: %v", skuClient)
+	klogx.ProviderAzure.V(5).Infof("Created sku client with authorizer: %v", skuClient)
 
 	return &azClient{
 		disksClient:                     disksClient,
@@ -291,8 +404,13 @@ func newAzClient(cfg *Config, env *azure.Environment) (*azClient, error) {
 		virtualMachineScaleSetVMsClient: scaleSetVMsClient,
 		deploymentsClient:               deploymentsClient,
 		virtualMachinesClient:           virtualMachinesClient,
+		availabilitySetsClient:          availabilitySetsClient,
 		storageAccountsClient:           storageAccountsClient,
 		managedKubernetesServicesClient: kubernetesServicesClient,
 		skuClient:                       skuClient,
+		scaleSetPowerClient:             scaleSetPowerClient,
+		scaleSetDeleteClient:            scaleSetDeleteClient,
+		usagesClient:                    usagesClient,
+		authorizer:                      authorizer,
 	}, nil
 }
@@ -0,0 +1,45 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAuthorizerWithAuxiliaryTenants(t *testing.T) {
+	cfg := &Config{
+		TenantID:        "tenant",
+		AADClientID:     "client",
+		AADClientSecret: "secret",
+	}
+	authorizer, err := newAuthorizer(cfg, &azure.PublicCloud)
+	assert.NoError(t, err)
+	assert.IsType(t, &autorest.BearerAuthorizer{}, authorizer)
+
+	cfg.AuxiliaryTenantIDs = []string{"other-tenant"}
+	authorizer, err = newAuthorizer(cfg, &azure.PublicCloud)
+	assert.NoError(t, err)
+	assert.IsType(t, &autorest.MultiTenantBearerAuthorizer{}, authorizer)
+
+	cfg.AADClientSecret = ""
+	_, err = newAuthorizer(cfg, &azure.PublicCloud)
+	assert.Error(t, err)
+}
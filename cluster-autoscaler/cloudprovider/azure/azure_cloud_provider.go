@@ -17,8 +17,12 @@ limitations under the License.
 package azure
 
 import (
+	"bytes"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"strings"
 
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -26,6 +30,9 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/config"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/gpu"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
+	kube_client "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
 	klog "k8s.io/klog/v2"
 )
 
@@ -36,9 +43,15 @@ const (
 
 var (
 	availableGPUTypes = map[string]struct{}{
+		"nvidia":            {},
+		"nvidia-a10":        {},
+		"nvidia-h100":       {},
 		"nvidia-tesla-a100": {},
 		"nvidia-tesla-k80":  {},
+		"nvidia-tesla-m60":  {},
 		"nvidia-tesla-p100": {},
+		"nvidia-tesla-p40":  {},
+		"nvidia-tesla-t4":   {},
 		"nvidia-tesla-v100": {},
 	}
 )
@@ -47,6 +60,7 @@ var (
 type AzureCloudProvider struct {
 	azureManager    *AzureManager
 	resourceLimiter *cloudprovider.ResourceLimiter
+	pricingModel    *AzurePriceModel
 }
 
 // BuildAzureCloudProvider creates new AzureCloudProvider
@@ -54,6 +68,7 @@ func BuildAzureCloudProvider(azureManager *AzureManager, resourceLimiter *cloudp
 	azure := &AzureCloudProvider{
 		azureManager:    azureManager,
 		resourceLimiter: resourceLimiter,
+		pricingModel:    NewAzurePriceModel(),
 	}
 
 	return azure, nil
@@ -100,40 +115,55 @@ func (azure *AzureCloudProvider) NodeGroups() []cloudprovider.NodeGroup {
 
 // NodeGroupForNode returns the node group for the given node.
 func (azure *AzureCloudProvider) NodeGroupForNode(node *apiv1.Node) (cloudprovider.NodeGroup, error) {
-	klog.V(6).Infof("NodeGroupForNode: starts")
+	klogx.ProviderAzure.V(6).Infof("NodeGroupForNode: starts")
 	if node.Spec.ProviderID == "" {
-		klog.V(6).Infof("Skipping the search for node group for the node '%s' because it has no spec.ProviderID", node.ObjectMeta.Name)
+		klogx.ProviderAzure.V(6).Infof("Skipping the search for node group for the node '%s' because it has no spec.ProviderID", node.ObjectMeta.Name)
 		return nil, nil
 	}
-	klog.V(6).Infof("Searching for node group for the node: %s\n", node.Spec.ProviderID)
+	klogx.ProviderAzure.V(6).Infof("Searching for node group for the node: %s\n", node.Spec.ProviderID)
 	ref := &azureRef{
 		Name: node.Spec.ProviderID,
 	}
 
-	klog.V(6).Infof("NodeGroupForNode: ref.Name %s", ref.Name)
+	klogx.ProviderAzure.V(6).Infof("NodeGroupForNode: ref.Name %s", ref.Name)
 	return azure.azureManager.GetNodeGroupForInstance(ref)
 }
 
-// HasInstance returns whether a given node has a corresponding instance in this cloud provider
-func (azure *AzureCloudProvider) HasInstance(*apiv1.Node) (bool, error) {
-	return true, cloudprovider.ErrNotImplemented
+// HasInstance returns whether a given node has a corresponding instance in this cloud provider.
+// Instances which are being deleted are reported as missing, so that their nodes are treated
+// as deleted rather than as registered capacity.
+func (azure *AzureCloudProvider) HasInstance(node *apiv1.Node) (bool, error) {
+	if node.Spec.ProviderID == "" {
+		return false, fmt.Errorf("ProviderID for node %s is empty", node.Name)
+	}
+	if !strings.HasPrefix(node.Spec.ProviderID, "azure://") {
+		// Not an Azure VM, e.g. a virtual node: leave it to the caller.
+		return true, cloudprovider.ErrNotImplemented
+	}
+	return azure.azureManager.azureCache.HasInstance(node.Spec.ProviderID, node.CreationTimestamp.Time)
 }
 
 // Pricing returns pricing model for this cloud provider or error if not available.
 func (azure *AzureCloudProvider) Pricing() (cloudprovider.PricingModel, errors.AutoscalerError) {
-	return nil, cloudprovider.ErrNotImplemented
+	return azure.pricingModel, nil
 }
 
 // GetAvailableMachineTypes get all machine types that can be requested from the cloud provider.
 func (azure *AzureCloudProvider) GetAvailableMachineTypes() ([]string, error) {
-	return []string{}, nil
+	if !azure.azureManager.config.EnableNodeAutoprovisioning {
+		return []string{}, nil
+	}
+	return azure.azureManager.config.AutoprovisioningMachineTypes, nil
 }
 
 // NewNodeGroup builds a theoretical node group based on the node definition provided. The node group is not automatically
 // created on the cloud provider side. The node group is not returned by NodeGroups() until it is created.
 func (azure *AzureCloudProvider) NewNodeGroup(machineType string, labels map[string]string, systemLabels map[string]string,
 	taints []apiv1.Taint, extraResources map[string]resource.Quantity) (cloudprovider.NodeGroup, error) {
-	return nil, cloudprovider.ErrNotImplemented
+	if !azure.azureManager.config.EnableNodeAutoprovisioning {
+		return nil, cloudprovider.ErrNotImplemented
+	}
+	return azure.azureManager.newAutoprovisionedScaleSet(machineType, labels, taints)
 }
 
 // GetResourceLimiter returns struct containing limits (max, min) for resources (cores, memory etc.).
@@ -165,7 +195,30 @@ func (m *azureRef) String() string {
 // BuildAzure builds Azure cloud provider, manager etc.
 func BuildAzure(opts config.AutoscalingOptions, do cloudprovider.NodeGroupDiscoveryOptions, rl *cloudprovider.ResourceLimiter) cloudprovider.CloudProvider {
 	var config io.ReadCloser
-	if opts.CloudConfig != "" {
+	var kubeClient kube_client.Interface
+	var secretNamespace, secretName string
+	var secretData []byte
+	if opts.CloudConfigSecret != "" {
+		klog.Infof("Creating Azure Manager using cloud-config secret: %v", opts.CloudConfigSecret)
+		var err error
+		secretNamespace, secretName, err = parseSecretRef(opts.CloudConfigSecret)
+		if err != nil {
+			klog.Fatalf("Invalid cloud config secret: %v", err)
+		}
+		kubeConfig, err := clientcmd.BuildConfigFromFlags("", opts.KubeConfigPath)
+		if err != nil {
+			klog.Fatalf("Failed to build kube config: %v", err)
+		}
+		kubeClient, err = kube_client.NewForConfig(kubeConfig)
+		if err != nil {
+			klog.Fatalf("Failed to create kube client: %v", err)
+		}
+		secretData, err = readCloudConfigSecret(kubeClient, secretNamespace, secretName)
+		if err != nil {
+			klog.Fatalf("Couldn't read cloud provider configuration: %v", err)
+		}
+		config = ioutil.NopCloser(bytes.NewReader(secretData))
+	} else if opts.CloudConfig != "" {
 		klog.Infof("Creating Azure Manager using cloud-config file: %v", opts.CloudConfig)
 		var err error
 		config, err = os.Open(opts.CloudConfig)
@@ -180,9 +233,14 @@ func BuildAzure(opts config.AutoscalingOptions, do cloudprovider.NodeGroupDiscov
 	if err != nil {
 		klog.Fatalf("Failed to create Azure Manager: %v", err)
 	}
+	if kubeClient != nil {
+		manager.watchCloudConfigSecret(kubeClient, secretNamespace, secretName, secretData)
+	}
 	provider, err := BuildAzureCloudProvider(manager, rl)
 	if err != nil {
 		klog.Fatalf("Failed to create Azure cloud provider: %v", err)
 	}
+	// Register Azure API usage metrics.
+	RegisterMetrics()
 	return provider
 }
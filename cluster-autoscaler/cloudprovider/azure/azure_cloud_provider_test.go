@@ -18,12 +18,14 @@ package azure
 
 import (
 	"testing"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2022-08-01/compute"
 	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2017-05-10/resources"
 	"github.com/Azure/go-autorest/autorest/to"
 
 	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmclient/mockvmclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmssclient/mockvmssclient"
@@ -77,7 +79,7 @@ func newTestAzureManager(t *testing.T) *AzureManager {
 		},
 	}
 
-	cache, error := newAzureCache(manager.azClient, refreshInterval, manager.config.ResourceGroup, vmTypeVMSS, false, "")
+	cache, error := newAzureCache(manager.azClient, refreshInterval, []string{manager.config.ResourceGroup}, vmTypeVMSS, false, "")
 	assert.NoError(t, error)
 
 	manager.azureCache = cache
@@ -179,6 +181,66 @@ func TestNodeGroupForNode(t *testing.T) {
 
 }
 
+func TestHasInstance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	provider := newTestProvider(t)
+	mockVMSSClient := mockvmssclient.NewMockInterface(ctrl)
+	mockVMSSClient.EXPECT().List(gomock.Any(), provider.azureManager.config.ResourceGroup).Return(newTestVMSSList(3, "test-asg", "eastus", compute.Uniform), nil)
+	provider.azureManager.azClient.virtualMachineScaleSetsClient = mockVMSSClient
+	mockVMSSVMClient := mockvmssvmclient.NewMockInterface(ctrl)
+	mockVMSSVMClient.EXPECT().List(gomock.Any(), provider.azureManager.config.ResourceGroup, "test-asg", gomock.Any()).Return(newTestVMSSVMList(3), nil).AnyTimes()
+	provider.azureManager.azClient.virtualMachineScaleSetVMsClient = mockVMSSVMClient
+
+	scaleSet := newTestScaleSet(provider.azureManager, "test-asg")
+	assert.True(t, provider.azureManager.RegisterNodeGroup(scaleSet))
+	provider.azureManager.explicitlyConfigured["test-asg"] = true
+	provider.azureManager.forceRefresh()
+
+	node := newApiNode(compute.Uniform, 0)
+	exists, err := provider.HasInstance(node)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	// Instances which are being deleted shouldn't be reported as existing.
+	scaleSet.setInstanceStatusByProviderID(node.Spec.ProviderID, cloudprovider.InstanceStatus{State: cloudprovider.InstanceDeleting})
+	exists, err = provider.HasInstance(node)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	// Instances of registered scale sets missing from the VM listing are deleted, unless their
+	// node registered after the listing.
+	deletedNode := &apiv1.Node{
+		ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour))},
+		Spec: apiv1.NodeSpec{
+			ProviderID: "azure:///subscriptions/test-subscription-id/resourceGroups/test-asg/providers/Microsoft.Compute/virtualMachineScaleSets/test-asg/virtualMachines/5",
+		},
+	}
+	exists, err = provider.HasInstance(deletedNode)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	deletedNode.CreationTimestamp = metav1.NewTime(time.Now().Add(time.Hour))
+	_, err = provider.HasInstance(deletedNode)
+	assert.ErrorIs(t, err, cloudprovider.ErrNotImplemented)
+
+	// Instances unknown to the cache are left to the core to decide.
+	nodeNotInGroup := &apiv1.Node{
+		Spec: apiv1.NodeSpec{
+			ProviderID: "azure:///subscriptions/subscripion/resourceGroups/test-resource-group/providers/Microsoft.Compute/virtualMachines/test-instance-id-not-in-group",
+		},
+	}
+	_, err = provider.HasInstance(nodeNotInGroup)
+	assert.ErrorIs(t, err, cloudprovider.ErrNotImplemented)
+
+	_, err = provider.HasInstance(&apiv1.Node{})
+	assert.Error(t, err)
+	// Nodes which aren't Azure VMs are left to the core to decide.
+	exists, err = provider.HasInstance(&apiv1.Node{Spec: apiv1.NodeSpec{ProviderID: "aws:///us-east-1a/i-1"}})
+	assert.ErrorIs(t, err, cloudprovider.ErrNotImplemented)
+	assert.True(t, exists)
+}
+
 func TestNodeGroupForNodeWithNoProviderId(t *testing.T) {
 	provider := newTestProvider(t)
 	registered := provider.azureManager.RegisterNodeGroup(
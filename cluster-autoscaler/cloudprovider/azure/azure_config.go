@@ -63,6 +63,13 @@ const (
 	// toggle
 	dynamicInstanceListDefault = false
 	enableVmssFlexDefault      = false
+
+	// SKU API responses don't change often, refresh them a few times a day.
+	skuCacheTTLDefault = 6 * time.Hour
+
+	// Bounds of the suspension of list calls when ARM throttles them without a Retry-After header.
+	initialThrottleBackoff = 30 * time.Second
+	maxThrottleBackoff     = 10 * time.Minute
 )
 
 // CloudProviderRateLimitConfig indicates the rate limit config for each clients.
@@ -83,12 +90,20 @@ type CloudProviderRateLimitConfig struct {
 type Config struct {
 	CloudProviderRateLimitConfig
 
-	Cloud          string `json:"cloud" yaml:"cloud"`
-	Location       string `json:"location" yaml:"location"`
-	TenantID       string `json:"tenantId" yaml:"tenantId"`
-	SubscriptionID string `json:"subscriptionId" yaml:"subscriptionId"`
-	ResourceGroup  string `json:"resourceGroup" yaml:"resourceGroup"`
-	VMType         string `json:"vmType" yaml:"vmType"`
+	Cloud string `json:"cloud" yaml:"cloud"`
+	// EnvironmentFilePath is the path of a JSON file describing the endpoints of a custom cloud, e.g. Azure Stack Hub or an
+	// air-gapped cloud. It takes precedence over the named cloud.
+	EnvironmentFilePath string `json:"environmentFilePath,omitempty" yaml:"environmentFilePath,omitempty"`
+	// Endpoints overrides endpoints of the cloud environment.
+	Endpoints      EndpointOverrides `json:"endpoints,omitempty" yaml:"endpoints,omitempty"`
+	Location       string            `json:"location" yaml:"location"`
+	TenantID       string            `json:"tenantId" yaml:"tenantId"`
+	SubscriptionID string            `json:"subscriptionId" yaml:"subscriptionId"`
+	ResourceGroup  string            `json:"resourceGroup" yaml:"resourceGroup"`
+	VMType         string            `json:"vmType" yaml:"vmType"`
+
+	// ResourceGroups lists additional resource groups whose scale sets are managed, only applies for vmss type
+	ResourceGroups []string `json:"resourceGroups,omitempty" yaml:"resourceGroups,omitempty"`
 
 	// AuthMethod determines how to authorize requests for the Azure
 	// cloud. Valid options are "principal" (= the traditional
@@ -106,6 +121,11 @@ type Config struct {
 	UseManagedIdentityExtension  bool   `json:"useManagedIdentityExtension" yaml:"useManagedIdentityExtension"`
 	UseWorkloadIdentityExtension bool   `json:"useWorkloadIdentityExtension" yaml:"useWorkloadIdentityExtension"`
 	UserAssignedIdentityID       string `json:"userAssignedIdentityID" yaml:"userAssignedIdentityID"`
+	// UserAssignedIdentityResourceID identifies the user-assigned managed identity by its resource ID instead of its client ID.
+	UserAssignedIdentityResourceID string `json:"userAssignedIdentityResourceID" yaml:"userAssignedIdentityResourceID"`
+	// AuxiliaryTenantIDs are the tenants, other than TenantID, the service principal also gets tokens from, to manage
+	// resources homed in these tenants. Only applies to client secret and certificate credentials.
+	AuxiliaryTenantIDs []string `json:"auxiliaryTenantIDs,omitempty" yaml:"auxiliaryTenantIDs,omitempty"`
 
 	// Configs only for standard vmType (agent pools).
 	Deployment           string                 `json:"deployment" yaml:"deployment"`
@@ -138,8 +158,48 @@ type Config struct {
 	// EnableDynamicInstanceList defines whether to enable dynamic instance workflow for instance information check
 	EnableDynamicInstanceList bool `json:"enableDynamicInstanceList,omitempty" yaml:"enableDynamicInstanceList,omitempty"`
 
+	// SKU API cache TTL in seconds
+	SkuCacheTTL int64 `json:"skuCacheTTL,omitempty" yaml:"skuCacheTTL,omitempty"`
+
 	// EnableVmssFlex defines whether to enable Vmss Flex support or not
 	EnableVmssFlex bool `json:"enableVmssFlex,omitempty" yaml:"enableVmssFlex,omitempty"`
+
+	// EnableForceDelete defines whether to force delete VMSS instances, skipping their graceful shutdown
+	EnableForceDelete bool `json:"enableForceDelete,omitempty" yaml:"enableForceDelete,omitempty"`
+
+	// EnableQuotaCheck defines whether to check scale-ups against the regional and VM family vCPU quotas before scaling up
+	EnableQuotaCheck bool `json:"enableQuotaCheck,omitempty" yaml:"enableQuotaCheck,omitempty"`
+
+	// EnableRefreshOnScaleFailure defines whether to immediately refresh the cached size and instances of a scale set
+	// after one of its write operations fails, instead of invalidating the whole cache or waiting for vmssVmsCacheTTL
+	EnableRefreshOnScaleFailure bool `json:"enableRefreshOnScaleFailure,omitempty" yaml:"enableRefreshOnScaleFailure,omitempty"`
+
+	// HibernateAPIVersion is the compute API version used to hibernate instances of scale sets with the hibernate
+	// scale-down mode, at least 2023-03-01. Instances are deallocated instead if unset
+	HibernateAPIVersion string `json:"hibernateApiVersion,omitempty" yaml:"hibernateApiVersion,omitempty"`
+
+	// EnableVmssInstanceProtection defines whether to protect VMSS instances from scale-in while their nodes are drained
+	// for scale-down, so that concurrent capacity changes of the scale set don't remove them
+	EnableVmssInstanceProtection bool `json:"enableVmssInstanceProtection,omitempty" yaml:"enableVmssInstanceProtection,omitempty"`
+
+	// AtomicScaleUpTimeout is how long, in seconds, an atomic scale-up of a scale set may take before its instances
+	// are deleted, 15 minutes if unset
+	AtomicScaleUpTimeout int64 `json:"atomicScaleUpTimeout,omitempty" yaml:"atomicScaleUpTimeout,omitempty"`
+
+	// EnableNodeAutoprovisioning defines whether to create scale sets for the unschedulable pods no existing scale set fits,
+	// and to delete them once they are empty, only applies for vmss type
+	EnableNodeAutoprovisioning bool `json:"enableNodeAutoprovisioning,omitempty" yaml:"enableNodeAutoprovisioning,omitempty"`
+	// AutoprovisioningTemplate is the name of the scale set whose profile (image, network, OS profile, zones) the
+	// auto-provisioned scale sets are created from, prefixed with "<resource group>/" in an additional resource group
+	AutoprovisioningTemplate string `json:"autoprovisioningTemplate,omitempty" yaml:"autoprovisioningTemplate,omitempty"`
+	// AutoprovisioningMachineTypes lists the VM sizes auto-provisioned scale sets can use
+	AutoprovisioningMachineTypes []string `json:"autoprovisioningMachineTypes,omitempty" yaml:"autoprovisioningMachineTypes,omitempty"`
+	// AutoprovisioningMaxSize is the maximum size of auto-provisioned scale sets
+	AutoprovisioningMaxSize int `json:"autoprovisioningMaxSize,omitempty" yaml:"autoprovisioningMaxSize,omitempty"`
+
+	// EnableTrack2SDK defines whether to use the track 2 SDK (armcompute) for the scale set and scale set VM clients,
+	// during the transition from the track 1 SDK
+	EnableTrack2SDK bool `json:"enableTrack2Sdk,omitempty" yaml:"enableTrack2Sdk,omitempty"`
 }
 
 // BuildAzureConfig returns a Config object for the Azure clients
@@ -158,8 +218,16 @@ func BuildAzureConfig(configReader io.Reader) (*Config, error) {
 		}
 	} else {
 		cfg.Cloud = os.Getenv("ARM_CLOUD")
+		cfg.EnvironmentFilePath = os.Getenv("AZURE_ENVIRONMENT_FILEPATH")
+		cfg.Endpoints.ResourceManagerEndpoint = os.Getenv("AZURE_RESOURCE_MANAGER_ENDPOINT")
+		cfg.Endpoints.ActiveDirectoryEndpoint = os.Getenv("AZURE_ACTIVE_DIRECTORY_ENDPOINT")
+		cfg.Endpoints.ServiceManagementEndpoint = os.Getenv("AZURE_SERVICE_MANAGEMENT_ENDPOINT")
+		cfg.Endpoints.StorageEndpointSuffix = os.Getenv("AZURE_STORAGE_ENDPOINT_SUFFIX")
 		cfg.Location = os.Getenv("LOCATION")
 		cfg.ResourceGroup = os.Getenv("ARM_RESOURCE_GROUP")
+		if resourceGroups := os.Getenv("AZURE_RESOURCE_GROUPS"); resourceGroups != "" {
+			cfg.ResourceGroups = strings.Split(resourceGroups, ",")
+		}
 		cfg.TenantID = os.Getenv("ARM_TENANT_ID")
 		if tenantId := os.Getenv("AZURE_TENANT_ID"); tenantId != "" {
 			cfg.TenantID = tenantId
@@ -199,14 +267,14 @@ func BuildAzureConfig(configReader io.Reader) (*Config, error) {
 			}
 		}
 
-		if cfg.UseManagedIdentityExtension && cfg.UseWorkloadIdentityExtension {
-			return nil, errors.New("you can not combine both managed identity and workload identity as an authentication mechanism")
-		}
-
 		userAssignedIdentityIDFromEnv := os.Getenv("ARM_USER_ASSIGNED_IDENTITY_ID")
 		if userAssignedIdentityIDFromEnv != "" {
 			cfg.UserAssignedIdentityID = userAssignedIdentityIDFromEnv
 		}
+		cfg.UserAssignedIdentityResourceID = os.Getenv("ARM_USER_ASSIGNED_IDENTITY_RESOURCE_ID")
+		if auxiliaryTenantIDs := os.Getenv("AZURE_AUXILIARY_TENANT_IDS"); auxiliaryTenantIDs != "" {
+			cfg.AuxiliaryTenantIDs = strings.Split(auxiliaryTenantIDs, ",")
+		}
 
 		if vmssCacheTTL := os.Getenv("AZURE_VMSS_CACHE_TTL"); vmssCacheTTL != "" {
 			cfg.VmssCacheTTL, err = strconv.ParseInt(vmssCacheTTL, 10, 0)
@@ -252,6 +320,13 @@ func BuildAzureConfig(configReader io.Reader) (*Config, error) {
 			cfg.EnableDynamicInstanceList = dynamicInstanceListDefault
 		}
 
+		if skuCacheTTL := os.Getenv("AZURE_SKU_CACHE_TTL"); skuCacheTTL != "" {
+			cfg.SkuCacheTTL, err = strconv.ParseInt(skuCacheTTL, 10, 0)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse AZURE_SKU_CACHE_TTL %q: %v", skuCacheTTL, err)
+			}
+		}
+
 		if enableVmssFlex := os.Getenv("AZURE_ENABLE_VMSS_FLEX"); enableVmssFlex != "" {
 			cfg.EnableVmssFlex, err = strconv.ParseBool(enableVmssFlex)
 			if err != nil {
@@ -261,6 +336,67 @@ func BuildAzureConfig(configReader io.Reader) (*Config, error) {
 			cfg.EnableVmssFlex = enableVmssFlexDefault
 		}
 
+		if enableForceDelete := os.Getenv("AZURE_ENABLE_FORCE_DELETE"); enableForceDelete != "" {
+			cfg.EnableForceDelete, err = strconv.ParseBool(enableForceDelete)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse AZURE_ENABLE_FORCE_DELETE %q: %v", enableForceDelete, err)
+			}
+		}
+
+		if enableQuotaCheck := os.Getenv("AZURE_ENABLE_QUOTA_CHECK"); enableQuotaCheck != "" {
+			cfg.EnableQuotaCheck, err = strconv.ParseBool(enableQuotaCheck)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse AZURE_ENABLE_QUOTA_CHECK %q: %v", enableQuotaCheck, err)
+			}
+		}
+
+		if enableRefreshOnScaleFailure := os.Getenv("AZURE_ENABLE_REFRESH_ON_SCALE_FAILURE"); enableRefreshOnScaleFailure != "" {
+			cfg.EnableRefreshOnScaleFailure, err = strconv.ParseBool(enableRefreshOnScaleFailure)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse AZURE_ENABLE_REFRESH_ON_SCALE_FAILURE %q: %v", enableRefreshOnScaleFailure, err)
+			}
+		}
+
+		cfg.HibernateAPIVersion = os.Getenv("AZURE_HIBERNATE_API_VERSION")
+
+		if enableVmssInstanceProtection := os.Getenv("AZURE_ENABLE_VMSS_INSTANCE_PROTECTION"); enableVmssInstanceProtection != "" {
+			cfg.EnableVmssInstanceProtection, err = strconv.ParseBool(enableVmssInstanceProtection)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse AZURE_ENABLE_VMSS_INSTANCE_PROTECTION %q: %v", enableVmssInstanceProtection, err)
+			}
+		}
+
+		if atomicScaleUpTimeout := os.Getenv("AZURE_ATOMIC_SCALE_UP_TIMEOUT"); atomicScaleUpTimeout != "" {
+			cfg.AtomicScaleUpTimeout, err = strconv.ParseInt(atomicScaleUpTimeout, 10, 0)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse AZURE_ATOMIC_SCALE_UP_TIMEOUT %q: %v", atomicScaleUpTimeout, err)
+			}
+		}
+
+		if enableNodeAutoprovisioning := os.Getenv("AZURE_ENABLE_NODE_AUTOPROVISIONING"); enableNodeAutoprovisioning != "" {
+			cfg.EnableNodeAutoprovisioning, err = strconv.ParseBool(enableNodeAutoprovisioning)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse AZURE_ENABLE_NODE_AUTOPROVISIONING %q: %v", enableNodeAutoprovisioning, err)
+			}
+		}
+		cfg.AutoprovisioningTemplate = os.Getenv("AZURE_AUTOPROVISIONING_TEMPLATE")
+		if machineTypes := os.Getenv("AZURE_AUTOPROVISIONING_MACHINE_TYPES"); machineTypes != "" {
+			cfg.AutoprovisioningMachineTypes = strings.Split(machineTypes, ",")
+		}
+		if maxSize := os.Getenv("AZURE_AUTOPROVISIONING_MAX_SIZE"); maxSize != "" {
+			cfg.AutoprovisioningMaxSize, err = strconv.Atoi(maxSize)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse AZURE_AUTOPROVISIONING_MAX_SIZE %q: %v", maxSize, err)
+			}
+		}
+
+		if enableTrack2SDK := os.Getenv("AZURE_ENABLE_TRACK2_SDK"); enableTrack2SDK != "" {
+			cfg.EnableTrack2SDK, err = strconv.ParseBool(enableTrack2SDK)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse AZURE_ENABLE_TRACK2_SDK %q: %v", enableTrack2SDK, err)
+			}
+		}
+
 		if cfg.CloudProviderBackoff {
 			if backoffRetries := os.Getenv("BACKOFF_RETRIES"); backoffRetries != "" {
 				retries, err := strconv.ParseInt(backoffRetries, 10, 0)
@@ -303,6 +439,11 @@ func BuildAzureConfig(configReader io.Reader) (*Config, error) {
 	}
 	cfg.TrimSpace()
 
+	// Default to the token file projected by the workload identity webhook.
+	if cfg.UseWorkloadIdentityExtension && cfg.AADFederatedTokenFile == "" {
+		cfg.AADFederatedTokenFile = os.Getenv("AZURE_FEDERATED_TOKEN_FILE")
+	}
+
 	if cloudProviderRateLimit := os.Getenv("CLOUD_PROVIDER_RATE_LIMIT"); cloudProviderRateLimit != "" {
 		cfg.CloudProviderRateLimit, err = strconv.ParseBool(cloudProviderRateLimit)
 		if err != nil {
@@ -441,6 +582,7 @@ func (cfg *Config) getAzureClientConfig(authorizer autorest.Authorizer, env *azu
 	azClientConfig := &azclients.ClientConfig{
 		Location:                cfg.Location,
 		SubscriptionID:          cfg.SubscriptionID,
+		CloudName:               env.Name,
 		ResourceManagerEndpoint: env.ResourceManagerEndpoint,
 		Authorizer:              authorizer,
 		Backoff:                 &retry.Backoff{Steps: 1},
@@ -464,18 +606,51 @@ func (cfg *Config) getAzureClientConfig(authorizer autorest.Authorizer, env *azu
 // TrimSpace removes all leading and trailing white spaces.
 func (cfg *Config) TrimSpace() {
 	cfg.Cloud = strings.TrimSpace(cfg.Cloud)
+	cfg.EnvironmentFilePath = strings.TrimSpace(cfg.EnvironmentFilePath)
+	cfg.Endpoints.ResourceManagerEndpoint = strings.TrimSpace(cfg.Endpoints.ResourceManagerEndpoint)
+	cfg.Endpoints.ActiveDirectoryEndpoint = strings.TrimSpace(cfg.Endpoints.ActiveDirectoryEndpoint)
+	cfg.Endpoints.ServiceManagementEndpoint = strings.TrimSpace(cfg.Endpoints.ServiceManagementEndpoint)
+	cfg.Endpoints.StorageEndpointSuffix = strings.TrimSpace(cfg.Endpoints.StorageEndpointSuffix)
 	cfg.Location = strings.TrimSpace(cfg.Location)
 	cfg.TenantID = strings.TrimSpace(cfg.TenantID)
 	cfg.SubscriptionID = strings.TrimSpace(cfg.SubscriptionID)
 	cfg.ResourceGroup = strings.TrimSpace(cfg.ResourceGroup)
+	for i := range cfg.ResourceGroups {
+		cfg.ResourceGroups[i] = strings.TrimSpace(cfg.ResourceGroups[i])
+	}
 	cfg.VMType = strings.TrimSpace(cfg.VMType)
 	cfg.AADClientID = strings.TrimSpace(cfg.AADClientID)
 	cfg.AADClientSecret = strings.TrimSpace(cfg.AADClientSecret)
 	cfg.AADClientCertPath = strings.TrimSpace(cfg.AADClientCertPath)
 	cfg.AADClientCertPassword = strings.TrimSpace(cfg.AADClientCertPassword)
+	cfg.AADFederatedTokenFile = strings.TrimSpace(cfg.AADFederatedTokenFile)
+	cfg.UserAssignedIdentityResourceID = strings.TrimSpace(cfg.UserAssignedIdentityResourceID)
+	for i := range cfg.AuxiliaryTenantIDs {
+		cfg.AuxiliaryTenantIDs[i] = strings.TrimSpace(cfg.AuxiliaryTenantIDs[i])
+	}
 	cfg.Deployment = strings.TrimSpace(cfg.Deployment)
 	cfg.ClusterName = strings.TrimSpace(cfg.ClusterName)
 	cfg.NodeResourceGroup = strings.TrimSpace(cfg.NodeResourceGroup)
+	cfg.AutoprovisioningTemplate = strings.TrimSpace(cfg.AutoprovisioningTemplate)
+	for i := range cfg.AutoprovisioningMachineTypes {
+		cfg.AutoprovisioningMachineTypes[i] = strings.TrimSpace(cfg.AutoprovisioningMachineTypes[i])
+	}
+}
+
+// scaleSetResourceGroups returns the resource groups whose scale sets are listed: the resource group of the cluster
+// first, followed by the additional resource groups, without duplicates.
+func (cfg *Config) scaleSetResourceGroups() []string {
+	resourceGroups := []string{cfg.ResourceGroup}
+	for _, resourceGroup := range cfg.ResourceGroups {
+		duplicate := resourceGroup == ""
+		for _, existing := range resourceGroups {
+			duplicate = duplicate || strings.EqualFold(existing, resourceGroup)
+		}
+		if !duplicate {
+			resourceGroups = append(resourceGroups, resourceGroup)
+		}
+	}
+	return resourceGroups
 }
 
 func (cfg *Config) validate() error {
@@ -504,10 +679,38 @@ func (cfg *Config) validate() error {
 		return fmt.Errorf("subscription ID not set")
 	}
 
+	if cfg.EnableNodeAutoprovisioning {
+		if cfg.VMType != vmTypeVMSS {
+			return fmt.Errorf("node auto-provisioning is not supported for vmType %q", cfg.VMType)
+		}
+		if cfg.AutoprovisioningTemplate == "" {
+			return fmt.Errorf("node auto-provisioning is enabled but the template vmss is not set")
+		}
+	}
+
+	if cfg.UseManagedIdentityExtension && cfg.UseWorkloadIdentityExtension {
+		return errors.New("you can not combine both managed identity and workload identity as an authentication mechanism")
+	}
+
+	if len(cfg.AuxiliaryTenantIDs) > 0 && (cfg.UseManagedIdentityExtension || cfg.UseWorkloadIdentityExtension || cfg.AuthMethod == authMethodCLI) {
+		return errors.New("auxiliary tenants are only supported with client secret or certificate credentials")
+	}
+
+	if len(cfg.AuxiliaryTenantIDs) > 0 && cfg.EnableTrack2SDK {
+		return errors.New("auxiliary tenants are not supported with the track 2 SDK yet")
+	}
+
 	if cfg.UseManagedIdentityExtension {
+		if cfg.UserAssignedIdentityID != "" && cfg.UserAssignedIdentityResourceID != "" {
+			return errors.New("you can not set both the client ID and the resource ID of the user-assigned identity")
+		}
 		return nil
 	}
 
+	if cfg.UseWorkloadIdentityExtension && cfg.AADFederatedTokenFile == "" {
+		return errors.New("federated token file not set for workload identity")
+	}
+
 	if cfg.TenantID == "" {
 		return fmt.Errorf("tenant ID not set")
 	}
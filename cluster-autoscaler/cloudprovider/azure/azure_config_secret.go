@@ -0,0 +1,154 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	kube_client "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	klog "k8s.io/klog/v2"
+)
+
+const (
+	// cloudConfigSecretKey is the key of the cloud provider configuration in the cloud config Secret.
+	cloudConfigSecretKey = "cloud-config"
+	// cloudConfigSecretResync is how often the cloud config Secret is re-listed, on top of watch events.
+	cloudConfigSecretResync = time.Hour
+)
+
+// reloadableAuthorizer is an autorest.Authorizer delegating to an authorizer that can be
+// replaced at runtime, so that clients created once keep working after a credential rotation.
+type reloadableAuthorizer struct {
+	mutex      sync.RWMutex
+	authorizer autorest.Authorizer
+}
+
+func newReloadableAuthorizer(authorizer autorest.Authorizer) *reloadableAuthorizer {
+	return &reloadableAuthorizer{authorizer: authorizer}
+}
+
+// WithAuthorization implements autorest.Authorizer. The current authorizer is looked up on every request.
+func (a *reloadableAuthorizer) WithAuthorization() autorest.PrepareDecorator {
+	return func(p autorest.Preparer) autorest.Preparer {
+		return autorest.PreparerFunc(func(r *http.Request) (*http.Request, error) {
+			return a.get().WithAuthorization()(p).Prepare(r)
+		})
+	}
+}
+
+func (a *reloadableAuthorizer) get() autorest.Authorizer {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return a.authorizer
+}
+
+func (a *reloadableAuthorizer) set(authorizer autorest.Authorizer) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.authorizer = authorizer
+}
+
+// parseSecretRef parses a Secret reference in the namespace/name format.
+func parseSecretRef(ref string) (string, string, error) {
+	parts := strings.Split(ref, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid secret reference %q, expected namespace/name", ref)
+	}
+	return parts[0], parts[1], nil
+}
+
+// readCloudConfigSecret returns the cloud provider configuration stored in the given Secret.
+func readCloudConfigSecret(kubeClient kube_client.Interface, namespace, name string) ([]byte, error) {
+	secret, err := kubeClient.CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret %s/%s: %v", namespace, name, err)
+	}
+	return cloudConfigFromSecret(secret)
+}
+
+func cloudConfigFromSecret(secret *apiv1.Secret) ([]byte, error) {
+	data, found := secret.Data[cloudConfigSecretKey]
+	if !found {
+		return nil, fmt.Errorf("secret %s/%s has no %q key", secret.Namespace, secret.Name, cloudConfigSecretKey)
+	}
+	return data, nil
+}
+
+// watchCloudConfigSecret watches the given Secret and reloads the credentials whenever its
+// configuration changes. The watch is stopped by Cleanup.
+func (m *AzureManager) watchCloudConfigSecret(kubeClient kube_client.Interface, namespace, name string, initialData []byte) {
+	m.cloudConfigData = initialData
+	m.stopSecretWatch = make(chan struct{})
+
+	listWatch := cache.NewListWatchFromClient(kubeClient.CoreV1().RESTClient(), "secrets", namespace,
+		fields.OneTermEqualSelector("metadata.name", name))
+	_, controller := cache.NewInformer(listWatch, &apiv1.Secret{}, cloudConfigSecretResync, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if secret, ok := obj.(*apiv1.Secret); ok {
+				m.onCloudConfigSecretUpdate(secret)
+			}
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			if secret, ok := obj.(*apiv1.Secret); ok {
+				m.onCloudConfigSecretUpdate(secret)
+			}
+		},
+	})
+	go controller.Run(m.stopSecretWatch)
+}
+
+// onCloudConfigSecretUpdate re-creates the authorizer used by all Azure clients if the configuration
+// stored in the Secret changed. Only credentials are reloaded, other settings require a restart.
+func (m *AzureManager) onCloudConfigSecretUpdate(secret *apiv1.Secret) {
+	data, err := cloudConfigFromSecret(secret)
+	if err != nil {
+		klog.Errorf("Failed to reload Azure cloud config: %v", err)
+		return
+	}
+	if bytes.Equal(data, m.cloudConfigData) {
+		return
+	}
+	if m.azClient == nil || m.azClient.authorizer == nil {
+		klog.Warningf("Azure cloud config in secret %s/%s changed, but the Azure clients cannot be reloaded", secret.Namespace, secret.Name)
+		return
+	}
+
+	cfg, err := BuildAzureConfig(bytes.NewReader(data))
+	if err != nil {
+		klog.Errorf("Failed to parse Azure cloud config from secret %s/%s: %v", secret.Namespace, secret.Name, err)
+		return
+	}
+	authorizer, err := newAuthorizer(cfg, &m.env)
+	if err != nil {
+		klog.Errorf("Failed to create Azure authorizer from secret %s/%s: %v", secret.Namespace, secret.Name, err)
+		return
+	}
+	m.azClient.authorizer.set(authorizer)
+	m.cloudConfigData = data
+	klog.Infof("Reloaded Azure credentials from secret %s/%s; changes to other settings take effect after a restart", secret.Namespace, secret.Name)
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseSecretRef(t *testing.T) {
+	namespace, name, err := parseSecretRef("kube-system/azure-cloud-config")
+	assert.NoError(t, err)
+	assert.Equal(t, "kube-system", namespace)
+	assert.Equal(t, "azure-cloud-config", name)
+
+	for _, ref := range []string{"", "name", "/name", "namespace/", "a/b/c"} {
+		_, _, err := parseSecretRef(ref)
+		assert.Error(t, err, ref)
+	}
+}
+
+func TestReadCloudConfigSecret(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(
+		&apiv1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "valid"},
+			Data:       map[string][]byte{cloudConfigSecretKey: []byte(validAzureCfg)},
+		},
+		&apiv1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "invalid"},
+			Data:       map[string][]byte{"config": []byte(validAzureCfg)},
+		},
+	)
+
+	data, err := readCloudConfigSecret(kubeClient, "kube-system", "valid")
+	assert.NoError(t, err)
+	assert.Equal(t, validAzureCfg, string(data))
+
+	_, err = readCloudConfigSecret(kubeClient, "kube-system", "invalid")
+	assert.Error(t, err)
+
+	_, err = readCloudConfigSecret(kubeClient, "kube-system", "missing")
+	assert.Error(t, err)
+}
+
+func TestReloadableAuthorizer(t *testing.T) {
+	authorizer := newReloadableAuthorizer(autorest.NewAPIKeyAuthorizerWithHeaders(map[string]interface{}{"key": "old"}))
+	prepare := func() string {
+		req, err := autorest.Prepare(&http.Request{Header: http.Header{}}, authorizer.WithAuthorization())
+		assert.NoError(t, err)
+		return req.Header.Get("key")
+	}
+
+	assert.Equal(t, "old", prepare())
+	authorizer.set(autorest.NewAPIKeyAuthorizerWithHeaders(map[string]interface{}{"key": "new"}))
+	assert.Equal(t, "new", prepare())
+}
+
+func TestOnCloudConfigSecretUpdate(t *testing.T) {
+	initial := autorest.NullAuthorizer{}
+	manager := &AzureManager{
+		env:             azure.PublicCloud,
+		azClient:        &azClient{authorizer: newReloadableAuthorizer(initial)},
+		cloudConfigData: []byte(validAzureCfg),
+	}
+	secret := &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "azure-cloud-config"},
+		Data:       map[string][]byte{cloudConfigSecretKey: []byte(validAzureCfg)},
+	}
+
+	// Unchanged configuration doesn't reload the credentials.
+	manager.onCloudConfigSecretUpdate(secret)
+	assert.Equal(t, initial, manager.azClient.authorizer.get())
+
+	// Invalid configuration keeps the current credentials.
+	secret.Data[cloudConfigSecretKey] = []byte(invalidAzureCfg)
+	manager.onCloudConfigSecretUpdate(secret)
+	assert.Equal(t, initial, manager.azClient.authorizer.get())
+	assert.Equal(t, validAzureCfg, string(manager.cloudConfigData))
+
+	// Rotated credentials replace the authorizer.
+	rotated := strings.Replace(validAzureCfg, `"aadClientSecret": "fakeId"`, `"aadClientSecret": "rotatedId"`, 1)
+	secret.Data[cloudConfigSecretKey] = []byte(rotated)
+	manager.onCloudConfigSecretUpdate(secret)
+	assert.IsType(t, &autorest.BearerAuthorizer{}, manager.azClient.authorizer.get())
+	assert.Equal(t, rotated, string(manager.cloudConfigData))
+}
@@ -18,6 +18,8 @@ package azure
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -217,3 +219,92 @@ func TestOverrideDefaultRateLimitConfig(t *testing.T) {
 	newconfig = overrideDefaultRateLimitConfig(&defaultConfigWithRateLimits.RateLimitConfig, &falseCloudProviderRateLimit.RateLimitConfig)
 	assert.Equal(t, &falseCloudProviderRateLimit.RateLimitConfig, newconfig)
 }
+
+func TestValidateWorkloadIdentityConfig(t *testing.T) {
+	cfg := &Config{
+		ResourceGroup:                "rg",
+		SubscriptionID:               "sub",
+		TenantID:                     "tenant",
+		AADClientID:                  "client",
+		UseWorkloadIdentityExtension: true,
+	}
+	assert.Error(t, cfg.validate())
+
+	cfg.AADFederatedTokenFile = "/var/run/secrets/azure/tokens/azure-identity-token"
+	assert.NoError(t, cfg.validate())
+
+	cfg.UseManagedIdentityExtension = true
+	assert.Error(t, cfg.validate())
+}
+
+func TestFederatedTokenCallback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	callback := federatedTokenCallback(path)
+
+	_, err := callback()
+	assert.Error(t, err)
+
+	assert.NoError(t, os.WriteFile(path, []byte("token-1"), 0600))
+	jwt, err := callback()
+	assert.NoError(t, err)
+	assert.Equal(t, "token-1", jwt)
+
+	// Rotated tokens are picked up.
+	assert.NoError(t, os.WriteFile(path, []byte("token-2"), 0600))
+	jwt, err = callback()
+	assert.NoError(t, err)
+	assert.Equal(t, "token-2", jwt)
+}
+
+func TestScaleSetResourceGroups(t *testing.T) {
+	cfg := &Config{ResourceGroup: "rg"}
+	assert.Equal(t, []string{"rg"}, cfg.scaleSetResourceGroups())
+
+	cfg.ResourceGroups = []string{"other-rg", "RG", "", "other-rg", "third-rg"}
+	assert.Equal(t, []string{"rg", "other-rg", "third-rg"}, cfg.scaleSetResourceGroups())
+}
+
+func TestValidateNodeAutoprovisioningConfig(t *testing.T) {
+	cfg := &Config{
+		ResourceGroup:               "rg",
+		SubscriptionID:              "sub",
+		VMType:                      vmTypeVMSS,
+		UseManagedIdentityExtension: true,
+		EnableNodeAutoprovisioning:  true,
+	}
+	assert.Error(t, cfg.validate())
+
+	cfg.AutoprovisioningTemplate = "template-vmss"
+	assert.NoError(t, cfg.validate())
+
+	cfg.VMType = vmTypeStandard
+	cfg.Deployment = "deployment"
+	cfg.DeploymentParameters = map[string]interface{}{"key": "value"}
+	assert.Error(t, cfg.validate())
+}
+
+func TestValidateCrossTenantConfig(t *testing.T) {
+	cfg := &Config{
+		ResourceGroup:      "rg",
+		SubscriptionID:     "sub",
+		TenantID:           "tenant",
+		AADClientID:        "client",
+		AADClientSecret:    "secret",
+		AuxiliaryTenantIDs: []string{"other-tenant"},
+	}
+	assert.NoError(t, cfg.validate())
+
+	cfg.EnableTrack2SDK = true
+	assert.Error(t, cfg.validate())
+	cfg.EnableTrack2SDK = false
+
+	cfg.UseManagedIdentityExtension = true
+	assert.Error(t, cfg.validate())
+
+	cfg.AuxiliaryTenantIDs = nil
+	cfg.UserAssignedIdentityResourceID = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/identity"
+	assert.NoError(t, cfg.validate())
+
+	cfg.UserAssignedIdentityID = "identity-client"
+	assert.Error(t, cfg.validate())
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+	"k8s.io/klog/v2"
+)
+
+// azureStackCloudName is the name of the Azure Stack Hub cloud, for which the clients use the API versions supported
+// by Azure Stack Hub.
+const azureStackCloudName = "AZURESTACKCLOUD"
+
+// EndpointOverrides overrides endpoints of the cloud environment, e.g. for air-gapped clouds.
+type EndpointOverrides struct {
+	// ResourceManagerEndpoint is the ARM endpoint. For Azure Stack Hub, the other endpoints are fetched from its
+	// metadata endpoint unless an environment file is set.
+	ResourceManagerEndpoint string `json:"resourceManagerEndpoint,omitempty" yaml:"resourceManagerEndpoint,omitempty"`
+	// ActiveDirectoryEndpoint is the endpoint tokens are requested from.
+	ActiveDirectoryEndpoint string `json:"activeDirectoryEndpoint,omitempty" yaml:"activeDirectoryEndpoint,omitempty"`
+	// ServiceManagementEndpoint is the audience of the tokens.
+	ServiceManagementEndpoint string `json:"serviceManagementEndpoint,omitempty" yaml:"serviceManagementEndpoint,omitempty"`
+	// StorageEndpointSuffix is the suffix of the storage account endpoints.
+	StorageEndpointSuffix string `json:"storageEndpointSuffix,omitempty" yaml:"storageEndpointSuffix,omitempty"`
+}
+
+// getCloudEnvironment returns the cloud environment of the configuration, with its endpoint overrides applied. The
+// environment is read from environmentFilePath if set. Otherwise, Azure Stack Hub environments are described by the
+// metadata endpoint of their resource manager endpoint, and other clouds are looked up by name, the public cloud
+// being the default.
+func getCloudEnvironment(cfg *Config) (azure.Environment, error) {
+	env := azure.PublicCloud
+	var err error
+	isAzureStack := strings.EqualFold(cfg.Cloud, azureStackCloudName)
+	switch {
+	case cfg.EnvironmentFilePath != "":
+		env, err = azure.EnvironmentFromFile(cfg.EnvironmentFilePath)
+		if err != nil {
+			return env, fmt.Errorf("failed to read the cloud environment from %q: %v", cfg.EnvironmentFilePath, err)
+		}
+	case isAzureStack && cfg.Endpoints.ResourceManagerEndpoint != "":
+		env, err = azure.EnvironmentFromURL(cfg.Endpoints.ResourceManagerEndpoint)
+		if err != nil {
+			return env, fmt.Errorf("failed to get the cloud environment from the metadata of %q: %v", cfg.Endpoints.ResourceManagerEndpoint, err)
+		}
+	case cfg.Cloud != "":
+		env, err = azure.EnvironmentFromName(cfg.Cloud)
+		if err != nil {
+			return env, err
+		}
+	}
+
+	if isAzureStack {
+		// The name of the environment selects the API versions of the clients.
+		env.Name = azureStackCloudName
+	}
+	if endpoint := cfg.Endpoints.ResourceManagerEndpoint; endpoint != "" {
+		env.ResourceManagerEndpoint = endpoint
+	}
+	if endpoint := cfg.Endpoints.ActiveDirectoryEndpoint; endpoint != "" {
+		env.ActiveDirectoryEndpoint = endpoint
+	}
+	if endpoint := cfg.Endpoints.ServiceManagementEndpoint; endpoint != "" {
+		env.ServiceManagementEndpoint = endpoint
+		env.TokenAudience = endpoint
+	}
+	if suffix := cfg.Endpoints.StorageEndpointSuffix; suffix != "" {
+		env.StorageEndpointSuffix = suffix
+	}
+	klog.V(2).Infof("Using cloud environment %q with resource manager endpoint %q", env.Name, env.ResourceManagerEndpoint)
+	return env, nil
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetCloudEnvironment(t *testing.T) {
+	environmentFile := filepath.Join(t.TempDir(), "azurestackcloud.json")
+	assert.NoError(t, os.WriteFile(environmentFile, []byte(`{
+		"name": "AzureStackCloud",
+		"resourceManagerEndpoint": "https://management.local.azurestack.external/",
+		"activeDirectoryEndpoint": "https://login.microsoftonline.com/",
+		"serviceManagementEndpoint": "https://management.azurestackci.onmicrosoft.com/00000000-0000-0000-0000-000000000000",
+		"storageEndpointSuffix": "local.azurestack.external"
+	}`), 0600))
+
+	// The public cloud is the default.
+	env, err := getCloudEnvironment(&Config{})
+	assert.NoError(t, err)
+	assert.Equal(t, azure.PublicCloud, env)
+
+	env, err = getCloudEnvironment(&Config{Cloud: "AzureChinaCloud"})
+	assert.NoError(t, err)
+	assert.Equal(t, azure.ChinaCloud.ResourceManagerEndpoint, env.ResourceManagerEndpoint)
+
+	_, err = getCloudEnvironment(&Config{Cloud: "UnknownCloud"})
+	assert.Error(t, err)
+
+	// Azure Stack Hub from an environment file, with the endpoint of an air-gapped identity provider.
+	env, err = getCloudEnvironment(&Config{
+		Cloud:               "AzureStackCloud",
+		EnvironmentFilePath: environmentFile,
+		Endpoints:           EndpointOverrides{ActiveDirectoryEndpoint: "https://adfs.local.azurestack.external/adfs/"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, azureStackCloudName, env.Name)
+	assert.Equal(t, "https://management.local.azurestack.external/", env.ResourceManagerEndpoint)
+	assert.Equal(t, "https://adfs.local.azurestack.external/adfs/", env.ActiveDirectoryEndpoint)
+	assert.Equal(t, "local.azurestack.external", env.StorageEndpointSuffix)
+
+	// Sovereign cloud endpoints overridden without an environment file.
+	env, err = getCloudEnvironment(&Config{
+		Cloud:     "AzureUSGovernmentCloud",
+		Endpoints: EndpointOverrides{ResourceManagerEndpoint: "https://management.example.gov/", ServiceManagementEndpoint: "https://management.core.example.gov/"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, azure.USGovernmentCloud.Name, env.Name)
+	assert.Equal(t, "https://management.example.gov/", env.ResourceManagerEndpoint)
+	assert.Equal(t, "https://management.core.example.gov/", env.ServiceManagementEndpoint)
+	assert.Equal(t, azure.USGovernmentCloud.ActiveDirectoryEndpoint, env.ActiveDirectoryEndpoint)
+
+	_, err = getCloudEnvironment(&Config{EnvironmentFilePath: filepath.Join(t.TempDir(), "missing.json")})
+	assert.Error(t, err)
+}
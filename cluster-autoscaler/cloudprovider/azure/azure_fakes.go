@@ -21,9 +21,12 @@ import (
 	"fmt"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2022-08-01/compute"
 	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2017-05-10/resources"
+	"github.com/Azure/go-autorest/autorest/date"
+	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -76,7 +79,11 @@ func (m *DeploymentsClientMock) CreateOrUpdate(ctx context.Context, resourceGrou
 	deploy, ok := m.FakeStore[deploymentName]
 	if !ok {
 		deploy = resources.DeploymentExtended{
-			Properties: &resources.DeploymentPropertiesExtended{},
+			Name: to.StringPtr(deploymentName),
+			Properties: &resources.DeploymentPropertiesExtended{
+				ProvisioningState: to.StringPtr("Succeeded"),
+				Timestamp:         &date.Time{Time: time.Now()},
+			},
 		}
 		m.FakeStore[deploymentName] = deploy
 	}
@@ -113,6 +120,59 @@ func (m *DeploymentsClientMock) Delete(ctx context.Context, resourceGroupName, d
 	return
 }
 
+// ScaleSetPowerClientMock mocks for ScaleSetPowerClient.
+type ScaleSetPowerClientMock struct {
+	mutex       sync.Mutex
+	Deallocated []string
+	Hibernated  []string
+	Started     []string
+}
+
+// DeallocateInstances records the deallocated, or hibernated, instances.
+func (m *ScaleSetPowerClientMock) DeallocateInstances(ctx context.Context, resourceGroupName, vmScaleSetName string, instanceIDs []string, hibernate bool) (resp *http.Response, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if hibernate {
+		m.Hibernated = append(m.Hibernated, instanceIDs...)
+	} else {
+		m.Deallocated = append(m.Deallocated, instanceIDs...)
+	}
+	return &http.Response{StatusCode: http.StatusOK}, nil
+}
+
+// StartInstances records the started instances.
+func (m *ScaleSetPowerClientMock) StartInstances(ctx context.Context, resourceGroupName, vmScaleSetName string, instanceIDs []string) (resp *http.Response, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.Started = append(m.Started, instanceIDs...)
+	return &http.Response{StatusCode: http.StatusOK}, nil
+}
+
+// Calls returns copies of the deallocated, hibernated and started instances.
+func (m *ScaleSetPowerClientMock) Calls() (deallocated, hibernated, started []string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return append([]string(nil), m.Deallocated...), append([]string(nil), m.Hibernated...), append([]string(nil), m.Started...)
+}
+
+// ScaleSetDeleteClientMock mocks for ScaleSetDeleteClient.
+type ScaleSetDeleteClientMock struct {
+	mutex   sync.Mutex
+	Deleted []string
+}
+
+// Delete records the deleted scale set.
+func (m *ScaleSetDeleteClientMock) Delete(ctx context.Context, resourceGroupName, vmScaleSetName string) (resp *http.Response, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.Deleted = append(m.Deleted, vmScaleSetName)
+	return &http.Response{StatusCode: http.StatusOK}, nil
+}
+
 func fakeVMSSWithTags(vmssName string, tags map[string]*string) compute.VirtualMachineScaleSet {
 	skuName := "Standard_D4_v2"
 	var vmssCapacity int64 = 3
@@ -23,7 +23,7 @@ import (
 	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2022-08-01/compute"
-	"k8s.io/klog/v2"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
 )
 
 // GetVMSSTypeStatically uses static list of vmss generated at azure_instance_types.go to fetch vmss instance information.
@@ -42,7 +42,7 @@ var GetVMSSTypeStatically = func(template compute.VirtualMachineScaleSet) (*Inst
 	if promoRe.MatchString(*template.Sku.Name) {
 		if vmssType == nil {
 			// We didn't find an exact match but this is a promo type, check for matching standard
-			klog.V(4).Infof("No exact match found for %s, checking standard types", *template.Sku.Name)
+			klogx.ProviderAzure.V(4).Infof("No exact match found for %s, checking standard types", *template.Sku.Name)
 			skuName := promoRe.ReplaceAllString(*template.Sku.Name, "")
 			for k := range InstanceTypes {
 				if strings.EqualFold(k, skuName) {
@@ -70,7 +70,7 @@ var GetVMSSTypeDynamically = func(template compute.VirtualMachineScaleSet, azCac
 		promoRe := regexp.MustCompile(`(?i)_promo`)
 		skuName := promoRe.ReplaceAllString(*template.Sku.Name, "")
 		if skuName != *template.Sku.Name {
-			klog.V(1).Infof("No exact match found for %q, checking standard type %q. Error %v", *template.Sku.Name, skuName, err)
+			klogx.ProviderAzure.V(1).Infof("No exact match found for %q, checking standard type %q. Error %v", *template.Sku.Name, skuName, err)
 			sku, err = azCache.GetSKU(ctx, skuName, *template.Location)
 		}
 		if err != nil {
@@ -80,22 +80,68 @@ var GetVMSSTypeDynamically = func(template compute.VirtualMachineScaleSet, azCac
 
 	vmssType.VCPU, err = sku.VCPU()
 	if err != nil {
-		klog.V(1).Infof("Failed to parse vcpu from sku %q %v", *template.Sku.Name, err)
+		klogx.ProviderAzure.V(1).Infof("Failed to parse vcpu from sku %q %v", *template.Sku.Name, err)
 		return vmssType, err
 	}
 	gpu, err := getGpuFromSku(sku)
 	if err != nil {
-		klog.V(1).Infof("Failed to parse gpu from sku %q %v", *template.Sku.Name, err)
+		klogx.ProviderAzure.V(1).Infof("Failed to parse gpu from sku %q %v", *template.Sku.Name, err)
 		return vmssType, err
 	}
 	vmssType.GPU = gpu
 
 	memoryGb, err := sku.Memory()
 	if err != nil {
-		klog.V(1).Infof("Failed to parse memoryMb from sku %q %v", *template.Sku.Name, err)
+		klogx.ProviderAzure.V(1).Infof("Failed to parse memoryMb from sku %q %v", *template.Sku.Name, err)
 		return vmssType, err
 	}
 	vmssType.MemoryMb = int64(memoryGb) * 1024
 
+	// local disk sizes are only needed for ephemeral OS disks, they are left unknown if missing.
+	if cacheDiskBytes, err := sku.GetCapabilityIntegerQuantity(skuCachedDiskBytesCapability); err == nil {
+		vmssType.CacheDiskGb = cacheDiskBytes / (1024 * 1024 * 1024)
+	}
+	if resourceDiskMb, err := sku.GetCapabilityIntegerQuantity(skuMaxResourceVolumeMBCapability); err == nil {
+		vmssType.ResourceDiskGb = resourceDiskMb / 1024
+	}
+
 	return vmssType, nil
 }
+
+// gpuTypes maps the N-series VM sizes, without their "Standard_" prefix, to the
+// type of the GPUs they are equipped with.
+var gpuTypes = []struct {
+	sku     *regexp.Regexp
+	gpuType string
+}{
+	{regexp.MustCompile(`(?i)^nc[0-9]+r?$`), "nvidia-tesla-k80"},
+	{regexp.MustCompile(`(?i)^nc[0-9]+r?s_v2$`), "nvidia-tesla-p100"},
+	{regexp.MustCompile(`(?i)^nc[0-9]+r?s_v3$`), "nvidia-tesla-v100"},
+	{regexp.MustCompile(`(?i)^nc[0-9]+as_t4_v3$`), "nvidia-tesla-t4"},
+	{regexp.MustCompile(`(?i)^nc[0-9]+ads_a100_v4$`), "nvidia-tesla-a100"},
+	{regexp.MustCompile(`(?i)^nc[0-9]+ads_h100_v5$`), "nvidia-h100"},
+	{regexp.MustCompile(`(?i)^nd[0-9]+r?s$`), "nvidia-tesla-p40"},
+	{regexp.MustCompile(`(?i)^nd[0-9]+r?s_v2$`), "nvidia-tesla-v100"},
+	{regexp.MustCompile(`(?i)^nd[0-9]+a(m)?sr(_a100)?_v4$`), "nvidia-tesla-a100"},
+	{regexp.MustCompile(`(?i)^nd[0-9]+isr_h100_v5$`), "nvidia-h100"},
+	{regexp.MustCompile(`(?i)^nv[0-9]+(s_v3)?$`), "nvidia-tesla-m60"},
+	{regexp.MustCompile(`(?i)^nv[0-9]+ad(m)?s_a10_v5$`), "nvidia-a10"},
+}
+
+var promoSuffixRE = regexp.MustCompile(`_promo$`)
+
+// defaultGPUType is the type reported for GPU VM sizes missing from gpuTypes.
+// It is the value AKS gives to the accelerator label of its GPU nodes.
+const defaultGPUType = "nvidia"
+
+// getGpuTypeForSku returns the type of the GPUs of a VM size.
+func getGpuTypeForSku(skuName string) string {
+	name := strings.TrimPrefix(strings.ToLower(skuName), "standard_")
+	name = promoSuffixRE.ReplaceAllString(name, "")
+	for _, t := range gpuTypes {
+		if t.sku.MatchString(name) {
+			return t.gpuType
+		}
+	}
+	return defaultGPUType
+}
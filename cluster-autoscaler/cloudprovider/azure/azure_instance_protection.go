@@ -0,0 +1,92 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2022-08-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	apiv1 "k8s.io/api/core/v1"
+
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
+)
+
+// instanceProtectionSource identifies the cluster autoscaler in the VMSS VM update requests.
+const instanceProtectionSource = "cluster-autoscaler"
+
+// BeforeNodeDrain protects the instance of the node from scale-in while it is drained, so that a
+// concurrent capacity decrease of the scale set, by VMSS autoscale or by hand, doesn't remove it
+// in the middle of the drain.
+func (scaleSet *ScaleSet) BeforeNodeDrain(node *apiv1.Node) error {
+	return scaleSet.setInstanceProtection(node, true)
+}
+
+// AfterNodeDrain removes the scale-in protection set by BeforeNodeDrain, once the drain is over.
+func (scaleSet *ScaleSet) AfterNodeDrain(node *apiv1.Node) error {
+	return scaleSet.setInstanceProtection(node, false)
+}
+
+func (scaleSet *ScaleSet) setInstanceProtection(node *apiv1.Node, protect bool) error {
+	if !scaleSet.manager.config.EnableVmssInstanceProtection {
+		return nil
+	}
+	if mode, err := scaleSet.getOrchestrationMode(); err != nil || mode == compute.Flexible {
+		// Scale-in protection is a property of the VMs of uniform scale sets only.
+		return err
+	}
+	if _, found := scaleSet.getInstanceByProviderID(node.Spec.ProviderID); !found {
+		// Unregistered and failed instances are not drained.
+		return nil
+	}
+	instanceID, err := getLastSegment(node.Spec.ProviderID)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := getContextWithTimeout(vmssContextTimeout)
+	defer cancel()
+	resourceGroup := scaleSet.resourceGroup()
+	client := scaleSet.manager.azClient.virtualMachineScaleSetVMsClient
+
+	start := time.Now()
+	vm, rerr := client.Get(ctx, resourceGroup, scaleSet.Name, instanceID, "")
+	observeARMRequest("VirtualMachineScaleSetVMs.Get", start, rerr)
+	if rerr != nil {
+		return fmt.Errorf("failed to get instance %s of scale set %s: %v", instanceID, scaleSet.Name, rerr.Error())
+	}
+	if vm.VirtualMachineScaleSetVMProperties == nil {
+		vm.VirtualMachineScaleSetVMProperties = &compute.VirtualMachineScaleSetVMProperties{}
+	}
+	if vm.ProtectionPolicy == nil {
+		vm.ProtectionPolicy = &compute.VirtualMachineScaleSetVMProtectionPolicy{}
+	}
+	if to.Bool(vm.ProtectionPolicy.ProtectFromScaleIn) == protect {
+		return nil
+	}
+	vm.ProtectionPolicy.ProtectFromScaleIn = to.BoolPtr(protect)
+
+	klogx.ProviderAzure.V(3).Infof("Setting scale-in protection of instance %s of scale set %s to %t", instanceID, scaleSet.Name, protect)
+	start = time.Now()
+	rerr = client.Update(ctx, resourceGroup, scaleSet.Name, instanceID, vm, instanceProtectionSource)
+	observeARMRequest("VirtualMachineScaleSetVMs.Update", start, rerr)
+	if rerr != nil {
+		return fmt.Errorf("failed to set the scale-in protection of instance %s of scale set %s: %v", instanceID, scaleSet.Name, rerr.Error())
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2022-08-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmssclient/mockvmssclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmssvmclient/mockvmssvmclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
+)
+
+func TestInstanceProtection(t *testing.T) {
+	testCases := []struct {
+		name     string
+		enabled  bool
+		orchMode compute.OrchestrationMode
+		updates  []bool
+	}{
+		{name: "disabled", orchMode: compute.Uniform},
+		{name: "enabled", enabled: true, orchMode: compute.Uniform, updates: []bool{true, false}},
+		{name: "flexible", enabled: true, orchMode: compute.Flexible},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			manager := newTestAzureManager(t)
+			manager.config.EnableVmssInstanceProtection = tc.enabled
+			mockVMSSClient := mockvmssclient.NewMockInterface(ctrl)
+			mockVMSSClient.EXPECT().List(gomock.Any(), manager.config.ResourceGroup).Return(newTestVMSSList(3, "test-asg", "eastus", tc.orchMode), nil).AnyTimes()
+			manager.azClient.virtualMachineScaleSetsClient = mockVMSSClient
+			vms := newTestVMSSVMList(3)
+			mockVMSSVMClient := mockvmssvmclient.NewMockInterface(ctrl)
+			mockVMSSVMClient.EXPECT().List(gomock.Any(), manager.config.ResourceGroup, "test-asg", gomock.Any()).Return(vms, nil).AnyTimes()
+			manager.azClient.virtualMachineScaleSetVMsClient = mockVMSSVMClient
+
+			var updates []bool
+			if len(tc.updates) > 0 {
+				vm := vms[0]
+				mockVMSSVMClient.EXPECT().Get(gomock.Any(), manager.config.ResourceGroup, "test-asg", "0", gomock.Any()).DoAndReturn(
+					func(_, _, _, _, _ interface{}) (compute.VirtualMachineScaleSetVM, *retry.Error) {
+						return vm, nil
+					}).Times(len(tc.updates))
+				mockVMSSVMClient.EXPECT().Update(gomock.Any(), manager.config.ResourceGroup, "test-asg", "0", gomock.Any(), instanceProtectionSource).DoAndReturn(
+					func(_, _, _, _ interface{}, parameters compute.VirtualMachineScaleSetVM, _ string) *retry.Error {
+						updates = append(updates, to.Bool(parameters.ProtectionPolicy.ProtectFromScaleIn))
+						vm = parameters
+						return nil
+					}).Times(len(tc.updates))
+			}
+
+			scaleSet := newTestScaleSet(manager, "test-asg")
+			assert.True(t, manager.RegisterNodeGroup(scaleSet))
+			assert.NoError(t, manager.forceRefresh())
+			if tc.orchMode == compute.Uniform {
+				_, err := scaleSet.Nodes()
+				assert.NoError(t, err)
+			}
+
+			var drainAware cloudprovider.DrainAwareNodeGroup = scaleSet
+			node := newApiNode(tc.orchMode, 0)
+			assert.NoError(t, drainAware.BeforeNodeDrain(node))
+			assert.NoError(t, drainAware.AfterNodeDrain(node))
+			assert.Equal(t, tc.updates, updates)
+		})
+	}
+}
@@ -25,6 +25,10 @@ type InstanceType struct {
 	VCPU         int64
 	MemoryMb     int64
 	GPU          int64
+	// CacheDiskGb and ResourceDiskGb are the sizes of the local disks ephemeral OS disks can be placed on,
+	// 0 if unknown.
+	CacheDiskGb    int64
+	ResourceDiskGb int64
 }
 
 // InstanceTypes is a map of azure resources
@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/services/containerservice/mgmt/2021-10-01/containerservice"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
 	klog "k8s.io/klog/v2"
 
 	apiv1 "k8s.io/api/core/v1"
@@ -82,7 +83,7 @@ func NewAKSAgentPool(spec *dynamic.NodeGroupSpec, am *AzureManager) (*AKSAgentPo
 func (agentPool *AKSAgentPool) GetAKSAgentPool(agentProfiles *[]containerservice.ManagedClusterAgentPoolProfile) (ret *containerservice.ManagedClusterAgentPoolProfile) {
 	for _, value := range *agentProfiles {
 		profileName := *value.Name
-		klog.V(5).Infof("AKS AgentPool profile name: %s", profileName)
+		klogx.ProviderAzure.V(5).Infof("AKS AgentPool profile name: %s", profileName)
 		if strings.EqualFold(profileName, agentPool.azureRef.Name) {
 			return &value
 		}
@@ -91,22 +92,33 @@ func (agentPool *AKSAgentPool) GetAKSAgentPool(agentProfiles *[]containerservice
 	return nil
 }
 
-// getAKSNodeCount gets node count for AKS agent pool.
-func (agentPool *AKSAgentPool) getAKSNodeCount() (count int, err error) {
+// getAKSAgentPoolProfile gets the profile of the AKS agent pool from the ManagedClusters API.
+func (agentPool *AKSAgentPool) getAKSAgentPoolProfile() (*containerservice.ManagedClusterAgentPoolProfile, error) {
 	ctx, cancel := getContextWithCancel()
 	defer cancel()
 
+	start := time.Now()
 	managedCluster, rerr := agentPool.manager.azClient.managedKubernetesServicesClient.Get(ctx,
 		agentPool.resourceGroup,
 		agentPool.clusterName)
+	observeARMRequest("ManagedClusters.Get", start, rerr)
 	if rerr != nil {
 		klog.Errorf("Failed to get AKS cluster (name:%q): %v", agentPool.clusterName, rerr.Error())
-		return -1, rerr.Error()
+		return nil, rerr.Error()
 	}
 
 	pool := agentPool.GetAKSAgentPool(managedCluster.AgentPoolProfiles)
 	if pool == nil {
-		return -1, fmt.Errorf("could not find pool with name: %s", agentPool.azureRef)
+		return nil, fmt.Errorf("could not find pool with name: %s", agentPool.azureRef)
+	}
+	return pool, nil
+}
+
+// getAKSNodeCount gets node count for AKS agent pool.
+func (agentPool *AKSAgentPool) getAKSNodeCount() (count int, err error) {
+	pool, err := agentPool.getAKSAgentPoolProfile()
+	if err != nil {
+		return -1, err
 	}
 
 	if pool.Count != nil {
@@ -121,9 +133,11 @@ func (agentPool *AKSAgentPool) setAKSNodeCount(count int) error {
 	ctx, cancel := getContextWithCancel()
 	defer cancel()
 
+	start := time.Now()
 	managedCluster, rerr := agentPool.manager.azClient.managedKubernetesServicesClient.Get(ctx,
 		agentPool.resourceGroup,
 		agentPool.clusterName)
+	observeARMRequest("ManagedClusters.Get", start, rerr)
 	if rerr != nil {
 		klog.Errorf("Failed to get AKS cluster (name:%q): %v", agentPool.clusterName, rerr.Error())
 		return rerr.Error()
@@ -140,8 +154,10 @@ func (agentPool *AKSAgentPool) setAKSNodeCount(count int) error {
 	defer updateCancel()
 	*pool.Count = int32(count)
 	aksClient := agentPool.manager.azClient.managedKubernetesServicesClient
+	start = time.Now()
 	rerr = aksClient.CreateOrUpdate(updateCtx, agentPool.resourceGroup,
 		agentPool.clusterName, managedCluster, "")
+	observeARMRequest("ManagedClusters.CreateOrUpdate", start, rerr)
 	if rerr != nil {
 		klog.Errorf("Failed to update AKS cluster (%q): %v", agentPool.clusterName, rerr.Error())
 		return rerr.Error()
@@ -176,7 +192,9 @@ func (agentPool *AKSAgentPool) GetName(providerID string) (string, error) {
 	providerID = strings.TrimPrefix(providerID, "azure://")
 	ctx, cancel := getContextWithCancel()
 	defer cancel()
+	start := time.Now()
 	vms, rerr := agentPool.manager.azClient.virtualMachinesClient.List(ctx, agentPool.nodeResourceGroup)
+	observeARMRequest("VirtualMachines.List", start, rerr)
 	if rerr != nil {
 		return "", rerr.Error()
 	}
@@ -214,7 +232,7 @@ func (agentPool *AKSAgentPool) TargetSize() (int, error) {
 	if err != nil {
 		return -1, err
 	}
-	klog.V(5).Infof("Got new size %d for agent pool (%q)", count, agentPool.Name)
+	klogx.ProviderAzure.V(5).Infof("Got new size %d for agent pool (%q)", count, agentPool.Name)
 
 	agentPool.curSize = count
 	agentPool.lastRefresh = time.Now()
@@ -239,7 +257,7 @@ func (agentPool *AKSAgentPool) setSizeInternal(targetSize int, isScalingDown boo
 		return fmt.Errorf("size-decreasing request of %d is smaller than min size %d", targetSize, agentPool.MinSize())
 	}
 
-	klog.V(2).Infof("Setting size for cluster (%q) with new count (%d)", agentPool.clusterName, targetSize)
+	klogx.ProviderAzure.V(2).Infof("Setting size for cluster (%q) with new count (%d)", agentPool.clusterName, targetSize)
 
 	err = agentPool.setAKSNodeCount(targetSize)
 	if err != nil {
@@ -312,12 +330,17 @@ func (agentPool *AKSAgentPool) DeleteNodes(nodes []*apiv1.Node) error {
 		if err != nil {
 			klog.Errorf("Failed to set size for agent pool %q with error: %v", agentPool.Name, err)
 		} else {
-			klog.V(3).Infof("Size for agent pool %q has been updated to %d", agentPool.Name, targetSize)
+			klogx.ProviderAzure.V(3).Infof("Size for agent pool %q has been updated to %d", agentPool.Name, targetSize)
 		}
 	}
 	return deleteError
 }
 
+// ForceDeleteNodes deletes nodes from the group regardless of constraints.
+func (agentPool *AKSAgentPool) ForceDeleteNodes(nodes []*apiv1.Node) error {
+	return cloudprovider.ErrNotImplemented
+}
+
 // IsAKSNode checks if the tag from the vm matches the agentPool name
 func (agentPool *AKSAgentPool) IsAKSNode(tags map[string]*string) bool {
 	poolName := tags[aksManagedPoolNameTag]
@@ -325,7 +348,7 @@ func (agentPool *AKSAgentPool) IsAKSNode(tags map[string]*string) bool {
 		poolName = tags[legacyAKSPoolNameTag]
 	}
 	if poolName != nil {
-		klog.V(5).Infof("Matching agentPool name: %s with tag name: %s", agentPool.azureRef.Name, *poolName)
+		klogx.ProviderAzure.V(5).Infof("Matching agentPool name: %s with tag name: %s", agentPool.azureRef.Name, *poolName)
 		if strings.EqualFold(*poolName, agentPool.azureRef.Name) {
 			return true
 		}
@@ -338,16 +361,18 @@ func (agentPool *AKSAgentPool) IsAKSNode(tags map[string]*string) bool {
 func (agentPool *AKSAgentPool) GetNodes() ([]string, error) {
 	ctx, cancel := getContextWithCancel()
 	defer cancel()
-	klog.V(6).Infof("GetNodes: starting list aks node pools in %s", agentPool.nodeResourceGroup)
+	klogx.ProviderAzure.V(6).Infof("GetNodes: starting list aks node pools in %s", agentPool.nodeResourceGroup)
+	start := time.Now()
 	vmList, rerr := agentPool.manager.azClient.virtualMachinesClient.List(ctx, agentPool.nodeResourceGroup)
-	klog.V(6).Infof("GetNodes: list finished, len(vmlist) = %d, err = %s", len(vmList), rerr.Error())
+	observeARMRequest("VirtualMachines.List", start, rerr)
+	klogx.ProviderAzure.V(6).Infof("GetNodes: list finished, len(vmlist) = %d, err = %s", len(vmList), rerr.Error())
 	if rerr != nil {
 		klog.Errorf("Azure client list vm error : %v", rerr.Error())
 		return nil, rerr.Error()
 	}
 	var nodeArray []string
 	for _, node := range vmList {
-		klog.V(5).Infof("Node Name: %s, ID: %s", *node.Name, *node.ID)
+		klogx.ProviderAzure.V(5).Infof("Node Name: %s, ID: %s", *node.Name, *node.ID)
 		if agentPool.IsAKSNode(node.Tags) {
 			providerID, err := convertResourceGroupNameToLower(agentPool.GetProviderID(*node.ID))
 			if err != nil {
@@ -356,7 +381,7 @@ func (agentPool *AKSAgentPool) GetNodes() ([]string, error) {
 				continue
 			}
 
-			klog.V(5).Infof("Returning back the providerID: %s", providerID)
+			klogx.ProviderAzure.V(5).Infof("Returning back the providerID: %s", providerID)
 			nodeArray = append(nodeArray, providerID)
 		}
 	}
@@ -374,7 +399,7 @@ func (agentPool *AKSAgentPool) DecreaseTargetSize(delta int) error {
 		klog.Error(err)
 		return err
 	}
-	klog.V(5).Infof("DecreaseTargetSize get current size %d for agent pool %q", currentSize, agentPool.Name)
+	klogx.ProviderAzure.V(5).Infof("DecreaseTargetSize get current size %d for agent pool %q", currentSize, agentPool.Name)
 
 	// Get the current nodes in the list
 	nodes, err := agentPool.GetNodes()
@@ -384,7 +409,7 @@ func (agentPool *AKSAgentPool) DecreaseTargetSize(delta int) error {
 	}
 
 	targetSize := currentSize + delta
-	klog.V(5).Infof("DecreaseTargetSize get target size %d for agent pool %q", targetSize, agentPool.Name)
+	klogx.ProviderAzure.V(5).Infof("DecreaseTargetSize get target size %d for agent pool %q", targetSize, agentPool.Name)
 	if targetSize < len(nodes) {
 		return fmt.Errorf("attempt to delete existing nodes targetSize:%d delta:%d existingNodes: %d",
 			currentSize, delta, len(nodes))
@@ -415,9 +440,22 @@ func (agentPool *AKSAgentPool) Nodes() ([]cloudprovider.Instance, error) {
 	return instances, nil
 }
 
-// TemplateNodeInfo is not implemented.
+// TemplateNodeInfo returns a node template built from the AKS agent pool profile, so that
+// it carries the labels, taints and node image version AKS applies to the pool's nodes.
 func (agentPool *AKSAgentPool) TemplateNodeInfo() (*schedulerframework.NodeInfo, error) {
-	return nil, cloudprovider.ErrNotImplemented
+	pool, err := agentPool.getAKSAgentPoolProfile()
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := buildNodeFromAKSAgentPool(agentPool.Name, *pool, agentPool.manager)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeInfo := schedulerframework.NewNodeInfo(cloudprovider.BuildKubeProxy(agentPool.Name))
+	nodeInfo.SetNode(node)
+	return nodeInfo, nil
 }
 
 // Exist is always true since we are initialized with an existing agentpool
@@ -421,3 +421,41 @@ func TestAKSDecreaseTargetSize(t *testing.T) {
 	assert.Equal(t, 1, aksPool.curSize)
 	assert.NoError(t, err)
 }
+
+func TestAKSTemplateNodeInfo(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	aksPool := getTestAKSPool(newTestAzureManager(t), testAKSPoolName)
+	expectedMC := getExpectedManagedCluster()
+	pool := &(*expectedMC.AgentPoolProfiles)[0]
+	pool.VMSize = to.StringPtr("Standard_D4s_v3")
+	pool.MaxPods = to.Int32Ptr(30)
+	pool.Mode = containerservice.AgentPoolModeUser
+	pool.NodeImageVersion = to.StringPtr("AKSUbuntu-2204gen2containerd-202310.04.0")
+	pool.NodeLabels = map[string]*string{"workload": to.StringPtr("batch")}
+	pool.NodeTaints = &[]string{"dedicated=batch:NoSchedule", "invalid"}
+
+	mockAKSClient := mockcontainerserviceclient.NewMockInterface(ctrl)
+	mockAKSClient.EXPECT().Get(gomock.Any(), aksPool.resourceGroup, aksPool.clusterName).Return(expectedMC, nil)
+	aksPool.manager.azClient.managedKubernetesServicesClient = mockAKSClient
+
+	nodeInfo, err := aksPool.TemplateNodeInfo()
+	assert.NoError(t, err)
+	node := nodeInfo.Node()
+
+	cpu := node.Status.Capacity[apiv1.ResourceCPU]
+	assert.Equal(t, int64(4), cpu.Value())
+	pods := node.Status.Capacity[apiv1.ResourcePods]
+	assert.Equal(t, int64(30), pods.Value())
+	assert.Equal(t, "Standard_D4s_v3", node.Labels[apiv1.LabelInstanceTypeStable])
+	assert.Equal(t, testAKSPoolName, node.Labels[aksAgentPoolLabel])
+	assert.Equal(t, "user", node.Labels[aksModeLabel])
+	assert.Equal(t, "AKSUbuntu-2204gen2containerd-202310.04.0", node.Labels[aksNodeImageVersionLabel])
+	assert.Equal(t, "batch", node.Labels["workload"])
+	assert.Equal(t, []apiv1.Taint{{Key: "dedicated", Value: "batch", Effect: apiv1.TaintEffectNoSchedule}}, node.Spec.Taints)
+
+	mockAKSClient.EXPECT().Get(gomock.Any(), aksPool.resourceGroup, aksPool.clusterName).Return(expectedMC, errInternal)
+	_, err = aksPool.TemplateNodeInfo()
+	assert.Equal(t, errInternalRaw, err)
+}
@@ -29,6 +29,7 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	"k8s.io/autoscaler/cluster-autoscaler/config"
 	"k8s.io/autoscaler/cluster-autoscaler/config/dynamic"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
 	klog "k8s.io/klog/v2"
 )
 
@@ -40,6 +41,9 @@ const (
 	scaleToZeroSupportedStandard = false
 	scaleToZeroSupportedVMSS     = true
 	refreshInterval              = 1 * time.Minute
+	// staleInstanceMappingRefreshInterval is the minimal interval between refreshes triggered by instances
+	// missing from the instance to node group mapping.
+	staleInstanceMappingRefreshInterval = 1 * time.Minute
 )
 
 // AzureManager handles Azure communication and data caching.
@@ -52,6 +56,10 @@ type AzureManager struct {
 	lastRefresh          time.Time
 	autoDiscoverySpecs   []labelAutoDiscoveryConfig
 	explicitlyConfigured map[string]bool
+
+	// cloudConfigData is the last configuration read from the cloud config Secret, if any.
+	cloudConfigData []byte
+	stopSecretWatch chan struct{}
 }
 
 // createAzureManagerInternal allows for a custom azClient to be passed in by tests.
@@ -61,13 +69,9 @@ func createAzureManagerInternal(configReader io.Reader, discoveryOpts cloudprovi
 		return nil, err
 	}
 
-	// Defaulting env to Azure Public Cloud.
-	env := azure.PublicCloud
-	if cfg.Cloud != "" {
-		env, err = azure.EnvironmentFromName(cfg.Cloud)
-		if err != nil {
-			return nil, err
-		}
+	env, err := getCloudEnvironment(cfg)
+	if err != nil {
+		return nil, err
 	}
 
 	klog.Infof("Starting azure manager with subscription ID %q", cfg.SubscriptionID)
@@ -91,10 +95,13 @@ func createAzureManagerInternal(configReader io.Reader, discoveryOpts cloudprovi
 	if cfg.VmssCacheTTL != 0 {
 		cacheTTL = time.Duration(cfg.VmssCacheTTL) * time.Second
 	}
-	cache, err := newAzureCache(azClient, cacheTTL, cfg.ResourceGroup, cfg.VMType, cfg.EnableDynamicInstanceList, cfg.Location)
+	cache, err := newAzureCache(azClient, cacheTTL, cfg.scaleSetResourceGroups(), cfg.VMType, cfg.EnableDynamicInstanceList, cfg.Location)
 	if err != nil {
 		return nil, err
 	}
+	if cfg.SkuCacheTTL > 0 {
+		cache.skuCacheTTL = time.Duration(cfg.SkuCacheTTL) * time.Second
+	}
 	manager.azureCache = cache
 
 	specs, err := ParseLabelAutoDiscoverySpecs(discoveryOpts)
@@ -164,7 +171,10 @@ func (m *AzureManager) buildNodeGroupFromSpec(spec string) (cloudprovider.NodeGr
 // In particular the list of node groups returned by NodeGroups can change as a result of CloudProvider.Refresh().
 func (m *AzureManager) Refresh() error {
 	if m.lastRefresh.Add(m.azureCache.refreshInterval).After(time.Now()) {
-		return nil
+		if !m.azureCache.isInstanceMappingStale() || m.lastRefresh.Add(staleInstanceMappingRefreshInterval).After(time.Now()) {
+			return nil
+		}
+		klogx.ProviderAzure.V(2).Infof("Refreshing Azure cache early to rebuild its stale instance to node group mapping")
 	}
 	return m.forceRefresh()
 }
@@ -177,14 +187,15 @@ func (m *AzureManager) forceRefresh() error {
 		klog.Errorf("Failed to regenerate Azure cache: %v", err)
 		return err
 	}
+	m.updateSizeLimitsFromTags()
 	m.lastRefresh = time.Now()
-	klog.V(2).Infof("Refreshed Azure VM and VMSS list, next refresh after %v", m.lastRefresh.Add(m.azureCache.refreshInterval))
+	klogx.ProviderAzure.V(2).Infof("Refreshed Azure VM and VMSS list, next refresh after %v", m.lastRefresh.Add(m.azureCache.refreshInterval))
 	return nil
 }
 
 func (m *AzureManager) invalidateCache() {
 	m.lastRefresh = time.Now().Add(-1 * m.azureCache.refreshInterval)
-	klog.V(2).Infof("Invalidated Azure cache")
+	klogx.ProviderAzure.V(2).Infof("Invalidated Azure cache")
 }
 
 // Fetch automatically discovered NodeGroups. These NodeGroups should be unregistered if
@@ -204,11 +215,11 @@ func (m *AzureManager) fetchAutoNodeGroups() error {
 			// This NodeGroup was explicitly configured, but would also be
 			// autodiscovered. We want the explicitly configured min and max
 			// nodes to take precedence.
-			klog.V(3).Infof("Ignoring explicitly configured NodeGroup %s for autodiscovery.", group.Id())
+			klogx.ProviderAzure.V(3).Infof("Ignoring explicitly configured NodeGroup %s for autodiscovery.", group.Id())
 			continue
 		}
 		if m.RegisterNodeGroup(group) {
-			klog.V(3).Infof("Autodiscovered NodeGroup %s using tags %v", group.Id(), m.autoDiscoverySpecs)
+			klogx.ProviderAzure.V(3).Infof("Autodiscovered NodeGroup %s using tags %v", group.Id(), m.autoDiscoverySpecs)
 			changed = true
 		}
 	}
@@ -228,6 +239,60 @@ func (m *AzureManager) fetchAutoNodeGroups() error {
 	return nil
 }
 
+// updateSizeLimitsFromTags applies the min and max tags fetched by the last cache refresh to the autodiscovered
+// scale sets, so that changed tags are taken into account without waiting for the next refresh or restarting.
+func (m *AzureManager) updateSizeLimitsFromTags() {
+	if len(m.autoDiscoverySpecs) == 0 {
+		return
+	}
+
+	vmssList := m.azureCache.getScaleSets()
+	for _, nodeGroup := range m.getNodeGroups() {
+		scaleSet, ok := nodeGroup.(*ScaleSet)
+		if !ok || m.explicitlyConfigured[scaleSet.Id()] {
+			continue
+		}
+		vmss, found := vmssList[scaleSet.Id()]
+		if !found || !matchDiscoveryConfig(vmss.Tags, m.autoDiscoverySpecs) {
+			// fetchAutoNodeGroups unregisters it on the next refresh.
+			continue
+		}
+		minSize, maxSize, err := sizeLimitsFromTags(vmss.Tags)
+		if err != nil {
+			klog.Warningf("not updating size limits of vmss %q because of %v", scaleSet.Name, err)
+			continue
+		}
+		if scaleSet.setSizeLimits(minSize, maxSize) {
+			klogx.ProviderAzure.V(2).Infof("Updated size limits of vmss %q from its tags: min=%d, max=%d", scaleSet.Name, minSize, maxSize)
+		}
+	}
+}
+
+// sizeLimitsFromTags parses the min and max tags of a scale set.
+func sizeLimitsFromTags(tags map[string]*string) (minSize, maxSize int, err error) {
+	val, ok := tags["min"]
+	if !ok || val == nil {
+		return 0, 0, fmt.Errorf("no minimum size specified for vmss")
+	}
+	if minSize, err = strconv.Atoi(*val); err != nil {
+		return 0, 0, fmt.Errorf("invalid minimum size specified for vmss: %s", err)
+	}
+	if minSize < 0 {
+		return 0, 0, fmt.Errorf("minimum size must be a non-negative number of nodes")
+	}
+	val, ok = tags["max"]
+	if !ok || val == nil {
+		return 0, 0, fmt.Errorf("no maximum size specified for vmss")
+	}
+	if maxSize, err = strconv.Atoi(*val); err != nil {
+		return 0, 0, fmt.Errorf("invalid maximum size specified for vmss: %s", err)
+	}
+	if maxSize < minSize {
+		return 0, 0, fmt.Errorf("maximum size must be greater than minimum size: max=%d < min=%d", maxSize, minSize)
+	}
+	return minSize, maxSize, nil
+}
+
 func (m *AzureManager) getNodeGroups() []cloudprovider.NodeGroup {
 	return m.azureCache.getRegisteredNodeGroups()
 }
@@ -272,11 +337,17 @@ func (m *AzureManager) GetScaleSetOptions(scaleSetName string, defaults config.N
 
 // Cleanup the cache.
 func (m *AzureManager) Cleanup() {
+	if m.stopSecretWatch != nil {
+		close(m.stopSecretWatch)
+		m.stopSecretWatch = nil
+	}
 	m.azureCache.Cleanup()
 }
 
 func (m *AzureManager) getFilteredNodeGroups(filter []labelAutoDiscoveryConfig) (nodeGroups []cloudprovider.NodeGroup, err error) {
-	if len(filter) == 0 {
+	// auto-provisioned scale sets are discovered by their tag even without auto-discovery.
+	autoprovisioning := m.config.EnableNodeAutoprovisioning && m.config.VMType == vmTypeVMSS
+	if len(filter) == 0 && !autoprovisioning {
 		return nil, nil
 	}
 
@@ -292,9 +363,10 @@ func (m *AzureManager) getFilteredScaleSets(filter []labelAutoDiscoveryConfig) (
 	vmssList := m.azureCache.getScaleSets()
 
 	var nodeGroups []cloudprovider.NodeGroup
-	for _, scaleSet := range vmssList {
-		if len(filter) > 0 {
-			if scaleSet.Tags == nil || len(scaleSet.Tags) == 0 {
+	for key, scaleSet := range vmssList {
+		autoprovisioned := m.config.EnableNodeAutoprovisioning && isAutoprovisioned(scaleSet.Tags)
+		if !autoprovisioned {
+			if len(filter) == 0 || len(scaleSet.Tags) == 0 {
 				continue
 			}
 
@@ -302,42 +374,16 @@ func (m *AzureManager) getFilteredScaleSets(filter []labelAutoDiscoveryConfig) (
 				continue
 			}
 		}
-		spec := &dynamic.NodeGroupSpec{
-			Name:               *scaleSet.Name,
-			MinSize:            1,
-			MaxSize:            -1,
-			SupportScaleToZero: scaleToZeroSupportedVMSS,
-		}
-
-		if val, ok := scaleSet.Tags["min"]; ok {
-			if minSize, err := strconv.Atoi(*val); err == nil {
-				spec.MinSize = minSize
-			} else {
-				klog.Warningf("ignoring vmss %q because of invalid minimum size specified for vmss: %s", *scaleSet.Name, err)
-				continue
-			}
-		} else {
-			klog.Warningf("ignoring vmss %q because of no minimum size specified for vmss", *scaleSet.Name)
-			continue
-		}
-		if spec.MinSize < 0 {
-			klog.Warningf("ignoring vmss %q because of minimum size must be a non-negative number of nodes", *scaleSet.Name)
-			continue
-		}
-		if val, ok := scaleSet.Tags["max"]; ok {
-			if maxSize, err := strconv.Atoi(*val); err == nil {
-				spec.MaxSize = maxSize
-			} else {
-				klog.Warningf("ignoring vmss %q because of invalid maximum size specified for vmss: %s", *scaleSet.Name, err)
-				continue
-			}
-		} else {
-			klog.Warningf("ignoring vmss %q because of no maximum size specified for vmss", *scaleSet.Name)
+		minSize, maxSize, err := sizeLimitsFromTags(scaleSet.Tags)
+		if err != nil {
+			klog.Warningf("ignoring vmss %q because of %v", *scaleSet.Name, err)
 			continue
 		}
-		if spec.MaxSize < spec.MinSize {
-			klog.Warningf("ignoring vmss %q because of maximum size must be greater than minimum size: max=%d < min=%d", *scaleSet.Name, spec.MaxSize, spec.MinSize)
-			continue
+		spec := &dynamic.NodeGroupSpec{
+			Name:               key,
+			MinSize:            minSize,
+			MaxSize:            maxSize,
+			SupportScaleToZero: scaleToZeroSupportedVMSS,
 		}
 
 		curSize := int64(-1)
@@ -350,6 +396,7 @@ func (m *AzureManager) getFilteredScaleSets(filter []labelAutoDiscoveryConfig) (
 			klog.Warningf("ignoring vmss %q %s", *scaleSet.Name, err)
 			continue
 		}
+		vmss.autoprovisioned = autoprovisioned
 		nodeGroups = append(nodeGroups, vmss)
 	}
 
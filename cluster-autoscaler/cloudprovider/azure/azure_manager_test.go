@@ -767,6 +767,72 @@ func TestFetchAutoAsgsVmss(t *testing.T) {
 	assert.Equal(t, 1, len(asgs))
 }
 
+func TestUpdateSizeLimitsFromTags(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	vmssName := "test-vmss"
+	vmssTag := "fake-tag"
+	vmssTagValue := "fake-value"
+	minString := "1"
+	maxString := "5"
+
+	ngdo := cloudprovider.NodeGroupDiscoveryOptions{
+		NodeGroupAutoDiscoverySpecs: []string{fmt.Sprintf("label:%s=%s", vmssTag, vmssTagValue)},
+	}
+	expectedScaleSets := []compute.VirtualMachineScaleSet{fakeVMSSWithTags(vmssName, map[string]*string{vmssTag: &vmssTagValue, "min": &minString, "max": &maxString})}
+
+	manager := newTestAzureManager(t)
+	mockVMSSClient := mockvmssclient.NewMockInterface(ctrl)
+	mockVMSSClient.EXPECT().List(gomock.Any(), manager.config.ResourceGroup).Return(expectedScaleSets, nil).AnyTimes()
+	manager.azClient.virtualMachineScaleSetsClient = mockVMSSClient
+	mockVMSSVMClient := mockvmssvmclient.NewMockInterface(ctrl)
+	mockVMSSVMClient.EXPECT().List(gomock.Any(), manager.config.ResourceGroup, vmssName, gomock.Any()).Return(newTestVMSSVMList(1), nil).AnyTimes()
+	manager.azClient.virtualMachineScaleSetVMsClient = mockVMSSVMClient
+	specs, err := ParseLabelAutoDiscoverySpecs(ngdo)
+	assert.NoError(t, err)
+	manager.autoDiscoverySpecs = specs
+
+	assert.NoError(t, manager.forceRefresh())
+	assert.NoError(t, manager.fetchAutoNodeGroups())
+	asgs := manager.azureCache.getRegisteredNodeGroups()
+	assert.Equal(t, 1, len(asgs))
+	registered := asgs[0]
+	assert.Equal(t, 1, registered.MinSize())
+	assert.Equal(t, 5, registered.MaxSize())
+
+	// Changed tags are applied in place to the registered scale set.
+	minString = "0"
+	maxString = "10"
+	assert.NoError(t, manager.forceRefresh())
+	asgs = manager.azureCache.getRegisteredNodeGroups()
+	assert.Equal(t, 1, len(asgs))
+	assert.Same(t, registered, asgs[0])
+	assert.Equal(t, 0, registered.MinSize())
+	assert.Equal(t, 10, registered.MaxSize())
+}
+
+func TestSizeLimitsFromTags(t *testing.T) {
+	tags := func(min, max string) map[string]*string {
+		return map[string]*string{"min": &min, "max": &max}
+	}
+	minSize, maxSize, err := sizeLimitsFromTags(tags("0", "3"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, minSize)
+	assert.Equal(t, 3, maxSize)
+
+	for _, invalid := range []map[string]*string{
+		{},
+		tags("-1", "3"),
+		tags("x", "3"),
+		tags("1", "x"),
+		tags("4", "3"),
+	} {
+		_, _, err := sizeLimitsFromTags(invalid)
+		assert.Error(t, err)
+	}
+}
+
 func TestManagerRefreshAndCleanup(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
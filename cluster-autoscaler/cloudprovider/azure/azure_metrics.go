@@ -0,0 +1,130 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+	k8smetrics "k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
+)
+
+const (
+	caNamespace = "cluster_autoscaler"
+)
+
+var (
+	/**** Metrics related to Azure API usage ****/
+	throttledSecondsCounter = k8smetrics.NewCounter(
+		&k8smetrics.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "azure_throttled_seconds_total",
+			Help:      "Time in seconds during which Azure list calls were suspended because ARM throttled them.",
+		},
+	)
+
+	armRequestDuration = k8smetrics.NewHistogramVec(
+		&k8smetrics.HistogramOpts{
+			Namespace: caNamespace,
+			Name:      "azure_arm_request_duration_seconds",
+			Help:      "Time taken by ARM calls made by the Azure provider, including client retries, by operation and status code, in seconds.",
+			Buckets:   []float64{0.05, 0.1, 0.2, 0.5, 1.0, 2.0, 5.0, 10.0, 20.0, 30.0, 60.0, 120.0, 300.0, 600.0},
+		}, []string{"operation", "status_code"},
+	)
+
+	armThrottledRequestsCount = k8smetrics.NewCounterVec(
+		&k8smetrics.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "azure_arm_throttled_requests_total",
+			Help:      "Number of ARM calls made by the Azure provider which were throttled, by operation.",
+		}, []string{"operation"},
+	)
+)
+
+const (
+	// armStatusSuccess is the status code label of successful calls whose status code isn't reported by the client.
+	armStatusSuccess = "2xx"
+	// armStatusUnknown is the status code label of failed calls which didn't get a response.
+	armStatusUnknown = "unknown"
+)
+
+// RegisterMetrics registers all Azure metrics.
+func RegisterMetrics() {
+	legacyregistry.MustRegister(throttledSecondsCounter)
+	legacyregistry.MustRegister(armRequestDuration)
+	legacyregistry.MustRegister(armThrottledRequestsCount)
+}
+
+// registerThrottledDuration records time during which Azure list calls are suspended.
+func registerThrottledDuration(d time.Duration) {
+	throttledSecondsCounter.Add(d.Seconds())
+}
+
+// observeARMRequest records an ARM call made through the cloud-provider-azure clients.
+func observeARMRequest(operation string, start time.Time, rerr *retry.Error) {
+	if rerr == nil {
+		registerARMRequest(operation, armStatusSuccess, false, start)
+		return
+	}
+	registerARMRequest(operation, armStatusCode(rerr.HTTPStatusCode), isAzureRequestsThrottled(rerr), start)
+}
+
+// observeSDKRequest records an ARM call made through the Azure SDK clients.
+func observeSDKRequest(operation string, start time.Time, err error) {
+	if err == nil {
+		registerARMRequest(operation, armStatusSuccess, false, start)
+		return
+	}
+	statusCode := sdkErrorStatusCode(err)
+	registerARMRequest(operation, armStatusCode(statusCode), statusCode == http.StatusTooManyRequests, start)
+}
+
+// sdkErrorStatusCode returns the HTTP status code of an error returned by the Azure SDK, or 0 if the
+// request got no response.
+func sdkErrorStatusCode(err error) int {
+	var requestErr *azure.RequestError
+	if errors.As(err, &requestErr) {
+		statusCode, _ := requestErr.StatusCode.(int)
+		return statusCode
+	}
+	var detailedErr autorest.DetailedError
+	if errors.As(err, &detailedErr) {
+		statusCode, _ := detailedErr.StatusCode.(int)
+		return statusCode
+	}
+	return 0
+}
+
+func registerARMRequest(operation, statusCode string, throttled bool, start time.Time) {
+	armRequestDuration.WithLabelValues(operation, statusCode).Observe(time.Since(start).Seconds())
+	if throttled {
+		armThrottledRequestsCount.WithLabelValues(operation).Inc()
+	}
+}
+
+func armStatusCode(statusCode int) string {
+	if statusCode == 0 {
+		return armStatusUnknown
+	}
+	return strconv.Itoa(statusCode)
+}
@@ -0,0 +1,40 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSDKErrorStatusCode(t *testing.T) {
+	detailedErr := autorest.DetailedError{StatusCode: http.StatusTooManyRequests}
+	assert.Equal(t, http.StatusTooManyRequests, sdkErrorStatusCode(detailedErr))
+	assert.Equal(t, http.StatusTooManyRequests, sdkErrorStatusCode(fmt.Errorf("listing usages: %w", detailedErr)))
+	assert.Equal(t, http.StatusNotFound, sdkErrorStatusCode(&azure.RequestError{DetailedError: autorest.DetailedError{StatusCode: http.StatusNotFound}}))
+	assert.Equal(t, 0, sdkErrorStatusCode(fmt.Errorf("connection refused")))
+}
+
+func TestARMStatusCode(t *testing.T) {
+	assert.Equal(t, "429", armStatusCode(http.StatusTooManyRequests))
+	assert.Equal(t, armStatusUnknown, armStatusCode(0))
+}
@@ -0,0 +1,259 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/units"
+	klog "k8s.io/klog/v2"
+)
+
+const (
+	// retailPricesURL is the endpoint of the Azure Retail Prices API, which doesn't require authentication.
+	retailPricesURL     = "https://prices.azure.com/api/retail/prices"
+	retailPricesTimeout = 10 * time.Second
+	// retailPricesTTL is how long prices are cached, failed lookups are retried sooner.
+	retailPricesTTL       = 24 * time.Hour
+	retailPricesRetryTTL  = 10 * time.Minute
+	retailPricesMaxPages  = 10
+	retailPricesHourlyUOM = "1 Hour"
+
+	// Fallback prices of pay-as-you-go general purpose VMs, used for pods and for VM sizes without a retail price.
+	cpuPricePerHour         = 0.033
+	memoryPricePerHourPerGb = 0.004
+	// spotDiscount is the fallback ratio of the Spot price to the pay-as-you-go price.
+	spotDiscount = 0.2
+)
+
+// errPriceNotFetched is returned for VM sizes whose price is being fetched for the first time.
+var errPriceNotFetched = errors.New("retail price not fetched yet")
+
+// AzurePriceModel implements the PricingModel interface for Azure, using the prices of the Azure Retail Prices API,
+// fetched in the background. All prices are in USD.
+type AzurePriceModel struct {
+	prices hourlyPriceSource
+}
+
+// hourlyPriceSource returns the hourly price of VM sizes.
+type hourlyPriceSource interface {
+	HourlyPrice(key vmPriceKey) (float64, error)
+}
+
+// vmPriceKey identifies the price of a VM size.
+type vmPriceKey struct {
+	region  string
+	vmSize  string
+	spot    bool
+	windows bool
+}
+
+// NewAzurePriceModel returns an AzurePriceModel fetching prices from the Azure Retail Prices API.
+func NewAzurePriceModel() *AzurePriceModel {
+	return &AzurePriceModel{prices: newRetailPrices(retailPricesURL, retailPricesTimeout)}
+}
+
+// NodePrice returns a price of running the given node for a given period of time.
+func (model *AzurePriceModel) NodePrice(node *apiv1.Node, startTime time.Time, endTime time.Time) (float64, error) {
+	hours := getHours(startTime, endTime)
+	key := vmPriceKey{
+		region:  node.Labels[apiv1.LabelTopologyRegion],
+		vmSize:  node.Labels[apiv1.LabelInstanceTypeStable],
+		spot:    node.Labels[spotPriorityLabel] == spotPriorityValue,
+		windows: node.Labels[apiv1.LabelOSStable] == "windows",
+	}
+	if key.region != "" && key.vmSize != "" {
+		pricePerHour, err := model.prices.HourlyPrice(key)
+		if err == nil {
+			return pricePerHour * hours, nil
+		}
+		if err == errPriceNotFetched {
+			klog.V(4).Infof("Pricing information for VM size %s in %s not fetched yet; will fallback to default pricing", key.vmSize, key.region)
+		} else {
+			klog.Warningf("Pricing information not found for VM size %s in %s; will fallback to default pricing: %v", key.vmSize, key.region, err)
+		}
+	}
+
+	price := getBasePrice(node.Status.Capacity, hours)
+	if key.spot {
+		price *= spotDiscount
+	}
+	return price, nil
+}
+
+// PodPrice returns a theoretical minimum price of running a pod for a given
+// period of time on a perfectly matching machine.
+func (model *AzurePriceModel) PodPrice(pod *apiv1.Pod, startTime time.Time, endTime time.Time) (float64, error) {
+	price := 0.0
+	for _, container := range pod.Spec.Containers {
+		price += getBasePrice(container.Resources.Requests, getHours(startTime, endTime))
+	}
+	return price, nil
+}
+
+func getBasePrice(resources apiv1.ResourceList, hours float64) float64 {
+	if len(resources) == 0 {
+		return 0
+	}
+	price := 0.0
+	cpu := resources[apiv1.ResourceCPU]
+	mem := resources[apiv1.ResourceMemory]
+	price += float64(cpu.MilliValue()) / 1000.0 * cpuPricePerHour * hours
+	price += float64(mem.Value()) / float64(units.GiB) * memoryPricePerHourPerGb * hours
+	return price
+}
+
+func getHours(startTime time.Time, endTime time.Time) float64 {
+	minutes := math.Ceil(float64(endTime.Sub(startTime)) / float64(time.Minute))
+	return minutes / 60.0
+}
+
+// retailPrices fetches VM prices from the Azure Retail Prices API in the background and caches them, so that
+// callers get the last known price without waiting for the API.
+type retailPrices struct {
+	client  *http.Client
+	baseURL string
+
+	mutex sync.Mutex
+	cache map[vmPriceKey]*cachedPrice
+}
+
+type cachedPrice struct {
+	price     float64
+	err       error
+	expiresAt time.Time
+	fetching  bool
+}
+
+func newRetailPrices(baseURL string, timeout time.Duration) *retailPrices {
+	return &retailPrices{
+		client:  &http.Client{Timeout: timeout},
+		baseURL: baseURL,
+		cache:   make(map[vmPriceKey]*cachedPrice),
+	}
+}
+
+// HourlyPrice returns the last known hourly price of the VM size, fetching it in the background if it
+// was never fetched or expired. errPriceNotFetched is returned until the first fetch completes.
+func (r *retailPrices) HourlyPrice(key vmPriceKey) (float64, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	cached, found := r.cache[key]
+	if !found {
+		cached = &cachedPrice{err: errPriceNotFetched}
+		r.cache[key] = cached
+	}
+	if time.Now().After(cached.expiresAt) && !cached.fetching {
+		cached.fetching = true
+		go r.refresh(key)
+	}
+	return cached.price, cached.err
+}
+
+// refresh fetches the price of the VM size without holding the lock. A price fetched before is kept if
+// fetching it again fails.
+func (r *retailPrices) refresh(key vmPriceKey) {
+	price, err := r.fetch(key)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	cached := r.cache[key]
+	cached.fetching = false
+	if err != nil {
+		if cached.err != nil {
+			cached.err = err
+		} else {
+			klog.Warningf("Failed to refresh the retail price of VM size %s in %s, keeping the last known one: %v", key.vmSize, key.region, err)
+		}
+		cached.expiresAt = time.Now().Add(retailPricesRetryTTL)
+		return
+	}
+	cached.price, cached.err = price, nil
+	cached.expiresAt = time.Now().Add(retailPricesTTL)
+}
+
+type retailPriceItem struct {
+	RetailPrice   float64 `json:"retailPrice"`
+	UnitOfMeasure string  `json:"unitOfMeasure"`
+	SkuName       string  `json:"skuName"`
+	ProductName   string  `json:"productName"`
+}
+
+type retailPricesPage struct {
+	Items        []retailPriceItem `json:"Items"`
+	NextPageLink string            `json:"NextPageLink"`
+}
+
+// fetch returns the lowest hourly pay-as-you-go or Spot price of the VM size for the OS of the key.
+func (r *retailPrices) fetch(key vmPriceKey) (float64, error) {
+	filter := fmt.Sprintf("serviceName eq 'Virtual Machines' and priceType eq 'Consumption' and armRegionName eq '%s' and armSkuName eq '%s'", key.region, key.vmSize)
+	next := fmt.Sprintf("%s?$filter=%s", r.baseURL, url.QueryEscape(filter))
+
+	found := false
+	lowest := 0.0
+	for page := 0; next != "" && page < retailPricesMaxPages; page++ {
+		resp, err := r.client.Get(next)
+		if err != nil {
+			return 0, fmt.Errorf("failed to query retail prices: %v", err)
+		}
+		var p retailPricesPage
+		err = json.NewDecoder(resp.Body).Decode(&p)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return 0, fmt.Errorf("retail prices query failed with status %s", resp.Status)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to decode retail prices: %v", err)
+		}
+		for _, item := range p.Items {
+			if !item.matches(key) {
+				continue
+			}
+			if !found || item.RetailPrice < lowest {
+				lowest = item.RetailPrice
+				found = true
+			}
+		}
+		next = p.NextPageLink
+	}
+	if !found {
+		return 0, fmt.Errorf("no retail price for VM size %s in %s (spot: %v, windows: %v)", key.vmSize, key.region, key.spot, key.windows)
+	}
+	return lowest, nil
+}
+
+// matches returns true if the item is the hourly price of a VM of the OS and priority of the key.
+// Low priority (Batch) prices are ignored.
+func (item retailPriceItem) matches(key vmPriceKey) bool {
+	if item.UnitOfMeasure != retailPricesHourlyUOM || strings.HasSuffix(item.SkuName, " Low Priority") {
+		return false
+	}
+	if strings.HasSuffix(item.SkuName, " Spot") != key.spot {
+		return false
+	}
+	return strings.HasSuffix(item.ProductName, " Windows") == key.windows
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/units"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testRetailPrices = `{"Items":[
+	{"retailPrice":0.192,"unitOfMeasure":"1 Hour","skuName":"D4s v3","productName":"Virtual Machines DSv3 Series"},
+	{"retailPrice":0.376,"unitOfMeasure":"1 Hour","skuName":"D4s v3","productName":"Virtual Machines DSv3 Series Windows"},
+	{"retailPrice":0.0384,"unitOfMeasure":"1 Hour","skuName":"D4s v3 Low Priority","productName":"Virtual Machines DSv3 Series"}
+],"NextPageLink":"%s"}`
+
+const testRetailPricesNextPage = `{"Items":[
+	{"retailPrice":0.0211,"unitOfMeasure":"1 Hour","skuName":"D4s v3 Spot","productName":"Virtual Machines DSv3 Series"}
+],"NextPageLink":null}`
+
+func TestRetailPrices(t *testing.T) {
+	var requests int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Query().Get("page") == "2" {
+			w.Write([]byte(testRetailPricesNextPage))
+			return
+		}
+		assert.Equal(t, "serviceName eq 'Virtual Machines' and priceType eq 'Consumption' and armRegionName eq 'eastus' and armSkuName eq 'Standard_D4s_v3'", r.URL.Query().Get("$filter"))
+		w.Write([]byte(fmt.Sprintf(testRetailPrices, server.URL+"?page=2")))
+	}))
+	defer server.Close()
+
+	prices := newRetailPrices(server.URL, time.Second)
+	key := vmPriceKey{region: "eastus", vmSize: "Standard_D4s_v3"}
+
+	// Prices are fetched in the background, the first lookup doesn't wait for them.
+	_, err := prices.HourlyPrice(key)
+	assert.Equal(t, errPriceNotFetched, err)
+	assert.Equal(t, 0.192, eventuallyHourlyPrice(t, prices, key))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	// Prices are cached.
+	price, err := prices.HourlyPrice(key)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.192, price)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	key.windows = true
+	assert.Equal(t, 0.376, eventuallyHourlyPrice(t, prices, key))
+
+	key = vmPriceKey{region: "eastus", vmSize: "Standard_D4s_v3", spot: true}
+	assert.Equal(t, 0.0211, eventuallyHourlyPrice(t, prices, key))
+}
+
+func TestRetailPricesKeepLastKnownPrice(t *testing.T) {
+	var failing int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(testRetailPricesNextPage))
+	}))
+	defer server.Close()
+
+	prices := newRetailPrices(server.URL, time.Second)
+	key := vmPriceKey{region: "eastus", vmSize: "Standard_D4s_v3", spot: true}
+	prices.HourlyPrice(key)
+	assert.Equal(t, 0.0211, eventuallyHourlyPrice(t, prices, key))
+
+	// Expire the price and fail its refresh.
+	atomic.StoreInt32(&failing, 1)
+	prices.mutex.Lock()
+	prices.cache[key].expiresAt = time.Time{}
+	prices.mutex.Unlock()
+	prices.HourlyPrice(key)
+	assert.Eventually(t, func() bool {
+		prices.mutex.Lock()
+		defer prices.mutex.Unlock()
+		return !prices.cache[key].fetching
+	}, time.Second, 10*time.Millisecond)
+
+	price, err := prices.HourlyPrice(key)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0211, price)
+}
+
+func eventuallyHourlyPrice(t *testing.T, prices *retailPrices, key vmPriceKey) float64 {
+	var price float64
+	assert.Eventually(t, func() bool {
+		var err error
+		price, err = prices.HourlyPrice(key)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	return price
+}
+
+type fakeHourlyPrices map[vmPriceKey]float64
+
+func (f fakeHourlyPrices) HourlyPrice(key vmPriceKey) (float64, error) {
+	if price, found := f[key]; found {
+		return price, nil
+	}
+	return 0, fmt.Errorf("no price for %v", key)
+}
+
+func TestAzurePriceModel(t *testing.T) {
+	model := &AzurePriceModel{prices: fakeHourlyPrices{
+		{region: "eastus", vmSize: "Standard_D4s_v3"}:             0.192,
+		{region: "eastus", vmSize: "Standard_D4s_v3", spot: true}: 0.0211,
+	}}
+	now := time.Now()
+
+	node := BuildTestNode("regular", 4000, 16*units.GiB)
+	node.Labels[apiv1.LabelTopologyRegion] = "eastus"
+	node.Labels[apiv1.LabelInstanceTypeStable] = "Standard_D4s_v3"
+	price, err := model.NodePrice(node, now, now.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.384, price, 1e-9)
+
+	spotNode := node.DeepCopy()
+	spotNode.Labels[spotPriorityLabel] = spotPriorityValue
+	price, err = model.NodePrice(spotNode, now, now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.0211, price, 1e-9)
+
+	// VM sizes without a retail price are priced from their capacity.
+	unknownNode := node.DeepCopy()
+	unknownNode.Labels[apiv1.LabelInstanceTypeStable] = "Standard_New_v9"
+	price, err = model.NodePrice(unknownNode, now, now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.InDelta(t, 4*cpuPricePerHour+16*memoryPricePerHourPerGb, price, 1e-9)
+
+	pod := BuildTestPod("pod", 1000, 4*units.GiB)
+	price, err = model.PodPrice(pod, now, now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.InDelta(t, cpuPricePerHour+4*memoryPricePerHourPerGb, price, 1e-9)
+}
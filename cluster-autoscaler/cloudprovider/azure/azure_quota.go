@@ -0,0 +1,145 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2022-08-01/compute"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/to"
+
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
+)
+
+const (
+	// quotaExceededErrorCode prefixes the errors of scale-ups refused because they would exceed a vCPU quota.
+	quotaExceededErrorCode = "QuotaExceeded"
+	// regionalCoresQuota is the name of the total regional vCPUs quota.
+	regionalCoresQuota = "cores"
+	// spotCoresQuota is the name of the total regional Spot vCPUs quota, the only one Spot VMs count against.
+	spotCoresQuota = "lowPriorityCores"
+)
+
+// UsagesClient defines needed functions for azure compute.UsageClient.
+type UsagesClient interface {
+	List(ctx context.Context, location string) (result []compute.Usage, err error)
+}
+
+type azUsagesClient struct {
+	client compute.UsageClient
+}
+
+func newAzUsagesClient(subscriptionID, endpoint string, authorizer autorest.Authorizer) *azUsagesClient {
+	usageClient := compute.NewUsageClientWithBaseURI(endpoint, subscriptionID)
+	usageClient.Authorizer = authorizer
+	configureUserAgent(&usageClient.Client)
+
+	return &azUsagesClient{
+		client: usageClient,
+	}
+}
+
+// List returns the compute resource usages and limits of the subscription in the location.
+func (az *azUsagesClient) List(ctx context.Context, location string) (result []compute.Usage, err error) {
+	start := time.Now()
+	klogx.ProviderAzure.V(10).Infof("azUsagesClient.List(%q): start", location)
+	defer func() {
+		observeSDKRequest("Usages.List", start, err)
+		klogx.ProviderAzure.V(10).Infof("azUsagesClient.List(%q): end", location)
+	}()
+
+	iterator, err := az.client.ListComplete(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+
+	result = make([]compute.Usage, 0)
+	for ; iterator.NotDone(); err = iterator.Next() {
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, iterator.Value())
+	}
+
+	return result, err
+}
+
+// checkQuota returns an error if adding delta instances to the scale set would exceed the regional vCPU quota, or
+// the quota of its VM family, so that the scale-up fails right away instead of once Azure fails provisioning the
+// instances. The check is skipped, allowing the scale-up, when the VM size or the usages can't be fetched.
+func (scaleSet *ScaleSet) checkQuota(delta int) error {
+	if !scaleSet.manager.config.EnableQuotaCheck {
+		return nil
+	}
+	vmss, err := scaleSet.getVMSSFromCache()
+	if err != nil || vmss.Sku == nil || vmss.Sku.Name == nil || vmss.Location == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	sku, err := scaleSet.manager.azureCache.GetSKU(ctx, *vmss.Sku.Name, *vmss.Location)
+	if err != nil {
+		klogx.ProviderAzure.V(1).Infof("Skipping quota check of vmss %s: failed to get SKU %q: %v", scaleSet.Name, *vmss.Sku.Name, err)
+		return nil
+	}
+	vcpus, err := sku.VCPU()
+	if err != nil {
+		klogx.ProviderAzure.V(1).Infof("Skipping quota check of vmss %s: failed to parse vCPUs of SKU %q: %v", scaleSet.Name, *vmss.Sku.Name, err)
+		return nil
+	}
+	usages, err := scaleSet.manager.azClient.usagesClient.List(ctx, *vmss.Location)
+	if err != nil {
+		klogx.ProviderAzure.V(1).Infof("Skipping quota check of vmss %s: failed to list usages in %s: %v", scaleSet.Name, *vmss.Location, err)
+		return nil
+	}
+
+	quotas := []string{regionalCoresQuota, to.String(sku.Family)}
+	if scaleSet.isSpot() {
+		quotas = []string{spotCoresQuota}
+	}
+	if err := checkVCPUQuotas(usages, quotas, int64(delta)*vcpus); err != nil {
+		return fmt.Errorf("scale-up of vmss %s by %d instances in %s refused: %v", scaleSet.Name, delta, *vmss.Location, err)
+	}
+	return nil
+}
+
+// checkVCPUQuotas returns an error if using the required vCPUs would exceed any of the named quotas.
+// Quotas missing from the usages aren't enforced.
+func checkVCPUQuotas(usages []compute.Usage, quotas []string, required int64) error {
+	for _, usage := range usages {
+		if usage.Name == nil || usage.Name.Value == nil || usage.CurrentValue == nil || usage.Limit == nil {
+			continue
+		}
+		for _, quota := range quotas {
+			if quota == "" || !strings.EqualFold(*usage.Name.Value, quota) {
+				continue
+			}
+			available := *usage.Limit - int64(*usage.CurrentValue)
+			if required > available {
+				return fmt.Errorf("%s: %d vCPUs are needed but only %d of the %d vCPUs of the %q quota are available",
+					quotaExceededErrorCode, required, available, *usage.Limit, quota)
+			}
+		}
+	}
+	return nil
+}
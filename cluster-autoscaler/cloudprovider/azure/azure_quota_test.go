@@ -0,0 +1,76 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2022-08-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestUsage(name string, current int32, limit int64) compute.Usage {
+	return compute.Usage{
+		Name:         &compute.UsageName{Value: to.StringPtr(name)},
+		CurrentValue: to.Int32Ptr(current),
+		Limit:        to.Int64Ptr(limit),
+	}
+}
+
+func TestCheckVCPUQuotas(t *testing.T) {
+	usages := []compute.Usage{
+		newTestUsage("cores", 90, 100),
+		newTestUsage("standardDSv2Family", 40, 48),
+		newTestUsage("lowPriorityCores", 0, 100),
+	}
+
+	testCases := []struct {
+		name      string
+		quotas    []string
+		required  int64
+		expectErr bool
+	}{
+		{name: "within quotas", quotas: []string{"cores", "standardDSv2Family"}, required: 8},
+		{name: "family quota exceeded", quotas: []string{"cores", "standardDSv2Family"}, required: 9, expectErr: true},
+		{name: "regional quota exceeded", quotas: []string{"cores"}, required: 16, expectErr: true},
+		{name: "quota names are case insensitive", quotas: []string{"Cores", "standarddsv2family"}, required: 9, expectErr: true},
+		{name: "unknown quota", quotas: []string{"standardNCFamily"}, required: 200},
+		{name: "empty family", quotas: []string{"cores", ""}, required: 10},
+		{name: "spot quota", quotas: []string{"lowPriorityCores"}, required: 64},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkVCPUQuotas(usages, tc.quotas, tc.required)
+			if tc.expectErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), quotaExceededErrorCode)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckQuotaDisabled(t *testing.T) {
+	manager := newTestAzureManager(t)
+	scaleSet := newTestScaleSet(manager, "test-asg")
+
+	// No usages client is configured, the check must not reach it.
+	assert.NoError(t, scaleSet.checkQuota(100))
+}
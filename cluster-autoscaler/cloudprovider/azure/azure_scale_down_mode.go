@@ -0,0 +1,317 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2022-08-01/compute"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/to"
+
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
+	klog "k8s.io/klog/v2"
+)
+
+const (
+	// scaleDownModeDelete deletes the instances removed by scale-downs.
+	scaleDownModeDelete = "delete"
+	// scaleDownModeDeallocate deallocates the instances removed by scale-downs, to be started again by scale-ups.
+	scaleDownModeDeallocate = "deallocate"
+	// scaleDownModeHibernate hibernates the instances removed by scale-downs, to be resumed by scale-ups.
+	scaleDownModeHibernate = "hibernate"
+)
+
+// ScaleSetPowerClient defines needed functions to stop and start scale set instances.
+type ScaleSetPowerClient interface {
+	DeallocateInstances(ctx context.Context, resourceGroupName, vmScaleSetName string, instanceIDs []string, hibernate bool) (resp *http.Response, err error)
+	StartInstances(ctx context.Context, resourceGroupName, vmScaleSetName string, instanceIDs []string) (resp *http.Response, err error)
+}
+
+type azScaleSetPowerClient struct {
+	client compute.VirtualMachineScaleSetsClient
+	// hibernateAPIVersion is the compute API version deallocations hibernating instances are sent with.
+	hibernateAPIVersion string
+}
+
+func newAzScaleSetPowerClient(subscriptionID, endpoint string, authorizer autorest.Authorizer, hibernateAPIVersion string) *azScaleSetPowerClient {
+	scaleSetsClient := compute.NewVirtualMachineScaleSetsClientWithBaseURI(endpoint, subscriptionID)
+	scaleSetsClient.Authorizer = authorizer
+	scaleSetsClient.PollingDelay = 5 * time.Second
+	configureUserAgent(&scaleSetsClient.Client)
+
+	return &azScaleSetPowerClient{
+		client:              scaleSetsClient,
+		hibernateAPIVersion: hibernateAPIVersion,
+	}
+}
+
+// DeallocateInstances deallocates, or hibernates, the given instances and waits for the operation to complete.
+func (az *azScaleSetPowerClient) DeallocateInstances(ctx context.Context, resourceGroupName, vmScaleSetName string, instanceIDs []string, hibernate bool) (resp *http.Response, err error) {
+	start := time.Now()
+	klogx.ProviderAzure.V(10).Infof("azScaleSetPowerClient.DeallocateInstances(%q,%q,%v,%v): start", resourceGroupName, vmScaleSetName, instanceIDs, hibernate)
+	defer func() {
+		observeSDKRequest("VirtualMachineScaleSets.DeallocateInstances", start, err)
+		klogx.ProviderAzure.V(10).Infof("azScaleSetPowerClient.DeallocateInstances(%q,%q,%v,%v): end", resourceGroupName, vmScaleSetName, instanceIDs, hibernate)
+	}()
+
+	req, err := az.client.DeallocatePreparer(ctx, resourceGroupName, vmScaleSetName, &compute.VirtualMachineScaleSetVMInstanceIDs{InstanceIds: &instanceIDs})
+	if err != nil {
+		return nil, err
+	}
+	if hibernate {
+		if az.hibernateAPIVersion == "" {
+			return nil, fmt.Errorf("hibernating instances requires hibernateApiVersion to be configured")
+		}
+		// The SDK version in use predates hibernation, the parameter is added to the request directly,
+		// with the API version configured for it.
+		query := req.URL.Query()
+		query.Set("api-version", az.hibernateAPIVersion)
+		query.Set("hibernate", "true")
+		req.URL.RawQuery = query.Encode()
+	}
+	future, err := az.client.DeallocateSender(req)
+	if err != nil {
+		return future.Response(), err
+	}
+
+	err = future.WaitForCompletionRef(ctx, az.client.Client)
+	return future.Response(), err
+}
+
+// StartInstances starts the given instances and waits for the operation to complete.
+func (az *azScaleSetPowerClient) StartInstances(ctx context.Context, resourceGroupName, vmScaleSetName string, instanceIDs []string) (resp *http.Response, err error) {
+	start := time.Now()
+	klogx.ProviderAzure.V(10).Infof("azScaleSetPowerClient.StartInstances(%q,%q,%v): start", resourceGroupName, vmScaleSetName, instanceIDs)
+	defer func() {
+		observeSDKRequest("VirtualMachineScaleSets.StartInstances", start, err)
+		klogx.ProviderAzure.V(10).Infof("azScaleSetPowerClient.StartInstances(%q,%q,%v): end", resourceGroupName, vmScaleSetName, instanceIDs)
+	}()
+
+	future, err := az.client.Start(ctx, resourceGroupName, vmScaleSetName, &compute.VirtualMachineScaleSetVMInstanceIDs{InstanceIds: &instanceIDs})
+	if err != nil {
+		return future.Response(), err
+	}
+
+	err = future.WaitForCompletionRef(ctx, az.client.Client)
+	return future.Response(), err
+}
+
+// scaleDownMode returns how the instances removed from the scale set by scale-downs are disposed of.
+func (scaleSet *ScaleSet) scaleDownMode() string {
+	vmss, err := scaleSet.getVMSSFromCache()
+	if err != nil {
+		return scaleDownModeDelete
+	}
+	return scaleSet.scaleDownModeOf(vmss)
+}
+
+// scaleDownModeOf returns how the instances removed from the given scale set by scale-downs are disposed of, from
+// its scale-down-mode tag. Instances are stopped instead of deleted only in Uniform scale sets, whose instances are
+// listed with their power state, and not for Spot scale sets, whose deallocated instances are evictions.
+func (scaleSet *ScaleSet) scaleDownModeOf(vmss compute.VirtualMachineScaleSet) string {
+	raw, found := vmss.Tags[scaleDownModeTag]
+	if !found || raw == nil {
+		return scaleDownModeDelete
+	}
+
+	mode := strings.ToLower(*raw)
+	switch mode {
+	case scaleDownModeDelete:
+		return mode
+	case scaleDownModeDeallocate, scaleDownModeHibernate:
+		if vmss.VirtualMachineScaleSetProperties != nil && vmss.OrchestrationMode == compute.Flexible {
+			klog.Warningf("vmss %q: scale-down mode %q is only supported by Uniform scale sets, instances will be deleted", scaleSet.Name, mode)
+			return scaleDownModeDelete
+		}
+		if isSpotScaleSet(vmss) {
+			klog.Warningf("vmss %q: scale-down mode %q is not supported by Spot scale sets, instances will be deleted", scaleSet.Name, mode)
+			return scaleDownModeDelete
+		}
+		if mode == scaleDownModeHibernate && !hibernateEnabled(vmss) {
+			klog.Warningf("vmss %q: hibernation isn't enabled, instances will be deallocated", scaleSet.Name)
+			return scaleDownModeDeallocate
+		}
+		if mode == scaleDownModeHibernate && scaleSet.manager.config.HibernateAPIVersion == "" {
+			klog.Warningf("vmss %q: hibernateApiVersion isn't configured, instances will be deallocated", scaleSet.Name)
+			return scaleDownModeDeallocate
+		}
+		return mode
+	default:
+		klog.Warningf("vmss %q: unknown scale-down mode %q, instances will be deleted", scaleSet.Name, *raw)
+		return scaleDownModeDelete
+	}
+}
+
+// stoppedInstanceIDs returns the lowercased provider IDs of the deallocated, or hibernated, VMs.
+func stoppedInstanceIDs(vms []compute.VirtualMachineScaleSetVM) map[string]bool {
+	stopped := make(map[string]bool)
+	for _, vm := range vms {
+		if vm.ID == nil || vm.InstanceView == nil || vm.InstanceView.Statuses == nil {
+			continue
+		}
+		powerState := vmPowerStateFromStatuses(*vm.InstanceView.Statuses)
+		if powerState != vmPowerStateDeallocating && powerState != vmPowerStateDeallocated {
+			continue
+		}
+		stopped[strings.ToLower("azure://"+*vm.ID)] = true
+	}
+	return stopped
+}
+
+// refreshStoppedInstances updates the stopped instances from the listed VMs, accounting for the instances
+// being stopped or started whose power state isn't updated yet. instanceMutex must be held, so the scale-down
+// mode is given by the caller rather than read from the cache.
+func (scaleSet *ScaleSet) refreshStoppedInstances(vms []compute.VirtualMachineScaleSetVM, mode string) {
+	if mode == scaleDownModeDelete {
+		scaleSet.stoppedInstances = nil
+		return
+	}
+	stopped := stoppedInstanceIDs(vms)
+	for providerID, stopping := range scaleSet.pendingPowerChanges {
+		if stopping {
+			stopped[providerID] = true
+		} else {
+			delete(stopped, providerID)
+		}
+	}
+	scaleSet.stoppedInstances = stopped
+}
+
+// setPendingPowerChange records the instances as being stopped or started, or clears them once done.
+func (scaleSet *ScaleSet) setPendingPowerChange(providerIDs []string, stopping, done bool) {
+	scaleSet.instanceMutex.Lock()
+	defer scaleSet.instanceMutex.Unlock()
+
+	if scaleSet.pendingPowerChanges == nil {
+		scaleSet.pendingPowerChanges = make(map[string]bool)
+	}
+	if scaleSet.stoppedInstances == nil {
+		scaleSet.stoppedInstances = make(map[string]bool)
+	}
+	for _, providerID := range providerIDs {
+		if done {
+			delete(scaleSet.pendingPowerChanges, providerID)
+			continue
+		}
+		scaleSet.pendingPowerChanges[providerID] = stopping
+		if stopping {
+			scaleSet.stoppedInstances[providerID] = true
+		} else {
+			delete(scaleSet.stoppedInstances, providerID)
+		}
+	}
+}
+
+// isStopped returns true if the given instance was stopped by a scale-down.
+func (scaleSet *ScaleSet) isStopped(providerID string) bool {
+	scaleSet.instanceMutex.Lock()
+	defer scaleSet.instanceMutex.Unlock()
+	return scaleSet.stoppedInstances[strings.ToLower(providerID)]
+}
+
+// getStoppedInstances returns the sorted provider IDs of the instances stopped by scale-downs. Stopped instances
+// are kept in the scale set capacity, but aren't part of its target size, as they don't run nodes.
+func (scaleSet *ScaleSet) getStoppedInstances() []string {
+	scaleSet.instanceMutex.Lock()
+	defer scaleSet.instanceMutex.Unlock()
+
+	stopped := make([]string, 0, len(scaleSet.stoppedInstances))
+	for providerID := range scaleSet.stoppedInstances {
+		stopped = append(stopped, providerID)
+	}
+	sort.Strings(stopped)
+	return stopped
+}
+
+// stopInstances deallocates, or hibernates, the given instances instead of deleting them. They are proactively
+// removed from the target size, and the instance cache is refreshed if the operation fails.
+func (scaleSet *ScaleSet) stopInstances(instances []*azureRef, instanceIDs []string, hibernate bool) {
+	providerIDs := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		providerIDs = append(providerIDs, strings.ToLower(instance.Name))
+	}
+	scaleSet.setPendingPowerChange(providerIDs, true, false)
+
+	go func() {
+		ctx, cancel := getContextWithCancel()
+		defer cancel()
+		defer scaleSet.setPendingPowerChange(providerIDs, true, true)
+
+		klogx.ProviderAzure.V(3).Infof("Calling scaleSetPowerClient.DeallocateInstances(%v, hibernate: %v) for %s", instanceIDs, hibernate, scaleSet.Name)
+		httpResponse, err := scaleSet.manager.azClient.scaleSetPowerClient.DeallocateInstances(ctx, scaleSet.resourceGroup(), scaleSet.Name, instanceIDs, hibernate)
+		if isSuccess, err := isSuccessHTTPResponse(httpResponse, err); !isSuccess {
+			klog.Errorf("scaleSetPowerClient.DeallocateInstances for instances %v for %s failed with error: %v", instanceIDs, scaleSet.Name, err)
+			if !scaleSet.refreshAfterScaleFailure("VirtualMachineScaleSets.DeallocateInstances", err) {
+				scaleSet.invalidateInstanceCache()
+			}
+			return
+		}
+		klogx.ProviderAzure.V(3).Infof("scaleSetPowerClient.DeallocateInstances(%v) for %s success", instanceIDs, scaleSet.Name)
+	}()
+}
+
+// startInstances starts the given stopped instances, so that scale-ups reuse them before growing the scale set.
+// Instances Azure lacks the capacity to start are reported as failed scale-ups.
+func (scaleSet *ScaleSet) startInstances(providerIDs []string) error {
+	instanceIDs := make([]string, 0, len(providerIDs))
+	for _, providerID := range providerIDs {
+		instanceID, err := getLastSegment(providerID)
+		if err != nil {
+			return err
+		}
+		instanceIDs = append(instanceIDs, instanceID)
+	}
+
+	scaleSet.setPendingPowerChange(providerIDs, false, false)
+	for _, providerID := range providerIDs {
+		scaleSet.setInstanceStatusByProviderID(providerID, cloudprovider.InstanceStatus{State: cloudprovider.InstanceCreating})
+	}
+
+	go func() {
+		ctx, cancel := getContextWithCancel()
+		defer cancel()
+		defer scaleSet.setPendingPowerChange(providerIDs, false, true)
+
+		klogx.ProviderAzure.V(3).Infof("Calling scaleSetPowerClient.StartInstances(%v) for %s", instanceIDs, scaleSet.Name)
+		httpResponse, err := scaleSet.manager.azClient.scaleSetPowerClient.StartInstances(ctx, scaleSet.resourceGroup(), scaleSet.Name, instanceIDs)
+		if isSuccess, err := isSuccessHTTPResponse(httpResponse, err); !isSuccess {
+			klog.Errorf("scaleSetPowerClient.StartInstances for instances %v for %s failed with error: %v", instanceIDs, scaleSet.Name, err)
+			if isOutOfCapacityError(err) {
+				scaleSet.addFailedScaleUps(len(instanceIDs), err)
+			}
+			if !scaleSet.refreshAfterScaleFailure("VirtualMachineScaleSets.StartInstances", err) {
+				scaleSet.invalidateInstanceCache()
+			}
+			return
+		}
+		klogx.ProviderAzure.V(3).Infof("scaleSetPowerClient.StartInstances(%v) for %s success", instanceIDs, scaleSet.Name)
+	}()
+	return nil
+}
+
+// hibernateEnabled reports whether hibernation is enabled in the scale set VM profile.
+func hibernateEnabled(vmss compute.VirtualMachineScaleSet) bool {
+	return vmss.VirtualMachineScaleSetProperties != nil && vmss.AdditionalCapabilities != nil &&
+		to.Bool(vmss.AdditionalCapabilities.HibernationEnabled)
+}
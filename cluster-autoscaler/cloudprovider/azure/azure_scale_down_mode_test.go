@@ -0,0 +1,158 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2022-08-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmssclient/mockvmssclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmssvmclient/mockvmssvmclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
+)
+
+func setVMPowerState(vm *compute.VirtualMachineScaleSetVM, powerState string) {
+	vm.ProvisioningState = to.StringPtr(provisioningStateSucceeded)
+	vm.InstanceView = &compute.VirtualMachineScaleSetVMInstanceView{
+		Statuses: &[]compute.InstanceViewStatus{{Code: to.StringPtr(powerState)}},
+	}
+}
+
+func TestScaleDownMode(t *testing.T) {
+	testCases := []struct {
+		name      string
+		tag       *string
+		orchMode  compute.OrchestrationMode
+		spot      bool
+		hibernate bool
+		expected  string
+	}{
+		{name: "no tag", orchMode: compute.Uniform, expected: scaleDownModeDelete},
+		{name: "delete", tag: to.StringPtr("delete"), orchMode: compute.Uniform, expected: scaleDownModeDelete},
+		{name: "deallocate", tag: to.StringPtr("Deallocate"), orchMode: compute.Uniform, expected: scaleDownModeDeallocate},
+		{name: "hibernate", tag: to.StringPtr("hibernate"), orchMode: compute.Uniform, hibernate: true, expected: scaleDownModeHibernate},
+		{name: "hibernation not enabled", tag: to.StringPtr("hibernate"), orchMode: compute.Uniform, expected: scaleDownModeDeallocate},
+		{name: "unknown mode", tag: to.StringPtr("shelve"), orchMode: compute.Uniform, expected: scaleDownModeDelete},
+		{name: "flexible", tag: to.StringPtr("deallocate"), orchMode: compute.Flexible, expected: scaleDownModeDelete},
+		{name: "spot", tag: to.StringPtr("deallocate"), orchMode: compute.Uniform, spot: true, expected: scaleDownModeDelete},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			manager := newTestAzureManager(t)
+			manager.config.HibernateAPIVersion = "2023-03-01"
+			scaleSets := newTestVMSSList(3, "test-asg", "eastus", tc.orchMode)
+			if tc.tag != nil {
+				scaleSets[0].Tags = map[string]*string{scaleDownModeTag: tc.tag}
+			}
+			if tc.spot {
+				scaleSets[0].VirtualMachineProfile = &compute.VirtualMachineScaleSetVMProfile{Priority: compute.Spot}
+			}
+			if tc.hibernate {
+				scaleSets[0].AdditionalCapabilities = &compute.AdditionalCapabilities{HibernationEnabled: to.BoolPtr(true)}
+			}
+			mockVMSSClient := mockvmssclient.NewMockInterface(ctrl)
+			mockVMSSClient.EXPECT().List(gomock.Any(), manager.config.ResourceGroup).Return(scaleSets, nil).AnyTimes()
+			manager.azClient.virtualMachineScaleSetsClient = mockVMSSClient
+			assert.NoError(t, manager.forceRefresh())
+
+			scaleSet := newTestScaleSet(manager, "test-asg")
+			assert.Equal(t, tc.expected, scaleSet.scaleDownMode())
+		})
+	}
+}
+
+func TestScaleDownDeallocate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager := newTestAzureManager(t)
+	scaleSets := newTestVMSSList(3, "test-asg", "eastus", compute.Uniform)
+	scaleSets[0].Tags = map[string]*string{scaleDownModeTag: to.StringPtr(scaleDownModeDeallocate)}
+	mockVMSSClient := mockvmssclient.NewMockInterface(ctrl)
+	mockVMSSClient.EXPECT().List(gomock.Any(), manager.config.ResourceGroup).Return(scaleSets, nil).AnyTimes()
+	manager.azClient.virtualMachineScaleSetsClient = mockVMSSClient
+
+	var vmsMutex sync.Mutex
+	vms := newTestVMSSVMList(3)
+	for i := range vms {
+		setVMPowerState(&vms[i], vmPowerStateRunning)
+	}
+	mockVMSSVMClient := mockvmssvmclient.NewMockInterface(ctrl)
+	mockVMSSVMClient.EXPECT().List(gomock.Any(), manager.config.ResourceGroup, "test-asg", gomock.Any()).DoAndReturn(
+		func(_, _, _, _ interface{}) ([]compute.VirtualMachineScaleSetVM, *retry.Error) {
+			vmsMutex.Lock()
+			defer vmsMutex.Unlock()
+			return append([]compute.VirtualMachineScaleSetVM(nil), vms...), nil
+		}).AnyTimes()
+	manager.azClient.virtualMachineScaleSetVMsClient = mockVMSSVMClient
+	powerClient := &ScaleSetPowerClientMock{}
+	manager.azClient.scaleSetPowerClient = powerClient
+
+	provider, err := BuildAzureCloudProvider(manager, nil)
+	assert.NoError(t, err)
+	scaleSet := newTestScaleSet(manager, "test-asg")
+	assert.True(t, manager.RegisterNodeGroup(scaleSet))
+	manager.explicitlyConfigured["test-asg"] = true
+	assert.NoError(t, manager.forceRefresh())
+
+	// The instance is deallocated instead of deleted, DeleteInstancesAsync isn't expected.
+	node := newApiNode(compute.Uniform, 0)
+	assert.NoError(t, scaleSet.DeleteNodes([]*apiv1.Node{node}))
+	assert.Eventually(t, func() bool {
+		deallocated, _, _ := powerClient.Calls()
+		return len(deallocated) == 1 && deallocated[0] == "0"
+	}, 5*time.Second, 10*time.Millisecond)
+	vmsMutex.Lock()
+	setVMPowerState(&vms[0], vmPowerStateDeallocated)
+	vmsMutex.Unlock()
+
+	// The stopped instance isn't part of the target size nor the nodes, and is reported as missing.
+	instances, err := scaleSet.Nodes()
+	assert.NoError(t, err)
+	assert.Len(t, instances, 2)
+	targetSize, err := scaleSet.TargetSize()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, targetSize)
+	exists, err := provider.HasInstance(node)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	// Scale-ups start the stopped instance before growing the scale set.
+	mockVMSSClient.EXPECT().CreateOrUpdateAsync(gomock.Any(), manager.config.ResourceGroup, "test-asg", gomock.Any()).Return(nil, nil)
+	mockVMSSClient.EXPECT().WaitForCreateOrUpdateResult(gomock.Any(), gomock.Any(), manager.config.ResourceGroup).Return(&http.Response{StatusCode: http.StatusOK}, nil).AnyTimes()
+	assert.NoError(t, scaleSet.IncreaseSize(2))
+	assert.Eventually(t, func() bool {
+		_, _, started := powerClient.Calls()
+		return len(started) == 1 && started[0] == "0"
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(4), scaleSet.curSize)
+	instance, found := scaleSet.getInstanceByProviderID(node.Spec.ProviderID)
+	assert.True(t, found)
+	assert.Equal(t, cloudprovider.InstanceCreating, instance.Status.State)
+}
@@ -19,6 +19,7 @@ package azure
 import (
 	"fmt"
 	"math/rand"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -27,6 +28,7 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	"k8s.io/autoscaler/cluster-autoscaler/config"
 	"k8s.io/autoscaler/cluster-autoscaler/config/dynamic"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
 	klog "k8s.io/klog/v2"
 	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
 	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
@@ -42,6 +44,9 @@ var (
 )
 
 const (
+	// failedScaleUpInstancePrefix prefixes the names of the placeholders for instances a scale-up failed to create.
+	failedScaleUpInstancePrefix = "failed-scale-up-"
+
 	provisioningStateCreating  string = "Creating"
 	provisioningStateDeleting  string = "Deleting"
 	provisioningStateFailed    string = "Failed"
@@ -54,12 +59,20 @@ const (
 type ScaleSet struct {
 	azureRef
 	manager *AzureManager
+	// additionalResourceGroup is the resource group of the scale set when it isn't the one of the cluster, resolved
+	// when the node group is created.
+	additionalResourceGroup string
 
-	minSize int
-	maxSize int
+	// minSize and maxSize can be updated from VMSS tags on refresh.
+	sizeLimitsMutex sync.RWMutex
+	minSize         int
+	maxSize         int
 
 	sizeMutex sync.Mutex
 	curSize   int64
+	// atomicScaleUpInProgress is set while an atomic scale-up of the scale set is in flight, for the instances it
+	// creates to be told apart from the ones of other scale-ups, which are refused meanwhile.
+	atomicScaleUpInProgress bool
 
 	enableDynamicInstanceList bool
 
@@ -72,14 +85,47 @@ type ScaleSet struct {
 	instanceMutex       sync.Mutex
 	instanceCache       []cloudprovider.Instance
 	lastInstanceRefresh time.Time
+	// lastInstanceListing is the last time the scale set VMs were successfully listed. Unlike
+	// lastInstanceRefresh, it isn't bumped when the listing is skipped because of throttling.
+	lastInstanceListing time.Time
+	// stoppedInstances are the lowercased provider IDs of the instances deallocated, or hibernated, by scale-downs.
+	// They are kept in the scale set capacity to be started by scale-ups, but aren't part of the target size.
+	stoppedInstances map[string]bool
+	// pendingPowerChanges are the instances being stopped (true) or started (false), until the operation completes.
+	pendingPowerChanges map[string]bool
+	// lastSpotEviction is the last time evicted Spot instances were found in the scale set.
+	lastSpotEviction time.Time
+	// failedScaleUps are placeholders for the instances a scale-up failed to create because Azure ran out of
+	// capacity. They are reported with an OutOfResources error, so that the scale set is backed off, until deleted.
+	failedScaleUps     []cloudprovider.Instance
+	failedScaleUpCount int
+	// faultDomainCounts is the number of instances per platform fault domain.
+	faultDomainCounts map[int32]int
+	// zoneCounts is the number of instances per availability zone.
+	zoneCounts map[string]int
+	// zoneRotation rotates templates between the least populated zones when there are several.
+	zoneRotation int
+	// repairedInstances are the lowercased provider IDs of the instances created by automatic instance repairs,
+	// with the time they were first listed, until their repair grace period is over.
+	repairedInstances map[string]time.Time
+	// listedSize is the size of the scale set when its VMs were last listed.
+	listedSize int64
+
+	// autoprovisioned is true for the scale sets created by node auto-provisioning.
+	autoprovisioned bool
+	// pendingCreation is the scale set an auto-provisioned node group is created as, until Create is called.
+	pendingCreation *compute.VirtualMachineScaleSet
 }
 
-// NewScaleSet creates a new NewScaleSet.
+// NewScaleSet creates a new NewScaleSet. The scale sets of additional resource groups are named
+// <resource group>/<scale set name>.
 func NewScaleSet(spec *dynamic.NodeGroupSpec, az *AzureManager, curSize int64) (*ScaleSet, error) {
+	additionalResourceGroup, name := splitScaleSetKey(spec.Name, az.config.ResourceGroup)
 	scaleSet := &ScaleSet{
 		azureRef: azureRef{
-			Name: spec.Name,
+			Name: name,
 		},
+		additionalResourceGroup:   additionalResourceGroup,
 		minSize:                   spec.MinSize,
 		maxSize:                   spec.MaxSize,
 		manager:                   az,
@@ -100,29 +146,37 @@ func NewScaleSet(spec *dynamic.NodeGroupSpec, az *AzureManager, curSize int64) (
 
 // MinSize returns minimum size of the node group.
 func (scaleSet *ScaleSet) MinSize() int {
+	scaleSet.sizeLimitsMutex.RLock()
+	defer scaleSet.sizeLimitsMutex.RUnlock()
 	return scaleSet.minSize
 }
 
 // Exist checks if the node group really exists on the cloud provider side. Allows to tell the
 // theoretical node group from the real one.
 func (scaleSet *ScaleSet) Exist() bool {
-	return true
+	return scaleSet.pendingCreation == nil
 }
 
 // Create creates the node group on the cloud provider side.
 func (scaleSet *ScaleSet) Create() (cloudprovider.NodeGroup, error) {
-	return nil, cloudprovider.ErrAlreadyExist
+	if scaleSet.Exist() {
+		return nil, cloudprovider.ErrAlreadyExist
+	}
+	return scaleSet.manager.createAutoprovisionedScaleSet(scaleSet)
 }
 
 // Delete deletes the node group on the cloud provider side.
 // This will be executed only for autoprovisioned node groups, once their size drops to 0.
 func (scaleSet *ScaleSet) Delete() error {
-	return cloudprovider.ErrNotImplemented
+	if !scaleSet.autoprovisioned || !scaleSet.Exist() {
+		return cloudprovider.ErrNotImplemented
+	}
+	return scaleSet.manager.deleteAutoprovisionedScaleSet(scaleSet)
 }
 
 // Autoprovisioned returns true if the node group is autoprovisioned.
 func (scaleSet *ScaleSet) Autoprovisioned() bool {
-	return false
+	return scaleSet.autoprovisioned
 }
 
 // GetOptions returns NodeGroupAutoscalingOptions that should be used for this particular
@@ -132,22 +186,48 @@ func (scaleSet *ScaleSet) GetOptions(defaults config.NodeGroupAutoscalingOptions
 	if err != nil {
 		return nil, err
 	}
-	return scaleSet.manager.GetScaleSetOptions(*template.Name, defaults), nil
+	return scaleSet.manager.GetScaleSetOptions(scaleSet.Id(), defaults), nil
 }
 
 // MaxSize returns maximum size of the node group.
 func (scaleSet *ScaleSet) MaxSize() int {
+	scaleSet.sizeLimitsMutex.RLock()
+	defer scaleSet.sizeLimitsMutex.RUnlock()
 	return scaleSet.maxSize
 }
 
+// setSizeLimits updates the minimum and maximum size of the node group, returns true if they changed.
+func (scaleSet *ScaleSet) setSizeLimits(minSize, maxSize int) bool {
+	scaleSet.sizeLimitsMutex.Lock()
+	defer scaleSet.sizeLimitsMutex.Unlock()
+	if scaleSet.minSize == minSize && scaleSet.maxSize == maxSize {
+		return false
+	}
+	scaleSet.minSize = minSize
+	scaleSet.maxSize = maxSize
+	return true
+}
+
+// resourceGroup returns the resource group of the scale set.
+func (scaleSet *ScaleSet) resourceGroup() string {
+	if scaleSet.additionalResourceGroup != "" {
+		return scaleSet.additionalResourceGroup
+	}
+	return scaleSet.manager.config.ResourceGroup
+}
+
 func (scaleSet *ScaleSet) getVMSSFromCache() (compute.VirtualMachineScaleSet, error) {
+	if scaleSet.pendingCreation != nil {
+		return *scaleSet.pendingCreation, nil
+	}
+
 	allVMSS := scaleSet.manager.azureCache.getScaleSets()
 
-	if _, exists := allVMSS[scaleSet.Name]; !exists {
-		return compute.VirtualMachineScaleSet{}, fmt.Errorf("could not find vmss: %s", scaleSet.Name)
+	if _, exists := allVMSS[scaleSet.Id()]; !exists {
+		return compute.VirtualMachineScaleSet{}, fmt.Errorf("could not find vmss: %s", scaleSet.Id())
 	}
 
-	return allVMSS[scaleSet.Name], nil
+	return allVMSS[scaleSet.Id()], nil
 }
 
 func (scaleSet *ScaleSet) getCurSize() (int64, error) {
@@ -155,7 +235,7 @@ func (scaleSet *ScaleSet) getCurSize() (int64, error) {
 	defer scaleSet.sizeMutex.Unlock()
 
 	if scaleSet.lastSizeRefresh.Add(scaleSet.sizeRefreshPeriod).After(time.Now()) {
-		klog.V(3).Infof("VMSS: %s, returning in-memory size: %d", scaleSet.Name, scaleSet.curSize)
+		klogx.ProviderAzure.V(3).Infof("VMSS: %s, returning in-memory size: %d", scaleSet.Name, scaleSet.curSize)
 		return scaleSet.curSize, nil
 	}
 
@@ -171,10 +251,10 @@ func (scaleSet *ScaleSet) getCurSize() (int64, error) {
 
 	if scaleSet.curSize != curSize {
 		// Invalidate the instance cache if the capacity has changed.
-		klog.V(5).Infof("VMSS %q size changed from: %d to %d, invalidating instance cache", scaleSet.Name, scaleSet.curSize, curSize)
+		klogx.ProviderAzure.V(5).Infof("VMSS %q size changed from: %d to %d, invalidating instance cache", scaleSet.Name, scaleSet.curSize, curSize)
 		scaleSet.invalidateInstanceCache()
 	}
-	klog.V(3).Infof("VMSS: %s, in-memory size: %d, new size: %d", scaleSet.Name, scaleSet.curSize, curSize)
+	klogx.ProviderAzure.V(3).Infof("VMSS: %s, in-memory size: %d, new size: %d", scaleSet.Name, scaleSet.curSize, curSize)
 
 	scaleSet.curSize = curSize
 	scaleSet.lastSizeRefresh = time.Now()
@@ -190,23 +270,32 @@ func (scaleSet *ScaleSet) waitForDeleteInstances(future *azure.Future, requiredI
 	ctx, cancel := getContextWithCancel()
 	defer cancel()
 
-	klog.V(3).Infof("Calling virtualMachineScaleSetsClient.WaitForDeleteInstancesResult(%v) for %s", requiredIds.InstanceIds, scaleSet.Name)
-	httpResponse, err := scaleSet.manager.azClient.virtualMachineScaleSetsClient.WaitForDeleteInstancesResult(ctx, future, scaleSet.manager.config.ResourceGroup)
+	klogx.ProviderAzure.V(3).Infof("Calling virtualMachineScaleSetsClient.WaitForDeleteInstancesResult(%v) for %s", requiredIds.InstanceIds, scaleSet.Name)
+	start := time.Now()
+	httpResponse, err := scaleSet.manager.azClient.virtualMachineScaleSetsClient.WaitForDeleteInstancesResult(ctx, future, scaleSet.resourceGroup())
+	observeSDKRequest("VirtualMachineScaleSets.WaitForDeleteInstancesResult", start, err)
 	isSuccess, err := isSuccessHTTPResponse(httpResponse, err)
 	if isSuccess {
-		klog.V(3).Infof("virtualMachineScaleSetsClient.WaitForDeleteInstancesResult(%v) for %s success", requiredIds.InstanceIds, scaleSet.Name)
+		klogx.ProviderAzure.V(3).Infof("virtualMachineScaleSetsClient.WaitForDeleteInstancesResult(%v) for %s success", requiredIds.InstanceIds, scaleSet.Name)
 		return
 	}
 	klog.Errorf("virtualMachineScaleSetsClient.WaitForDeleteInstancesResult - DeleteInstances for instances %v for %s failed with error: %v", requiredIds.InstanceIds, scaleSet.Name, err)
+	// The instances proactively marked as deleting may still be running.
+	scaleSet.refreshAfterScaleFailure("VirtualMachineScaleSets.DeleteInstances", err)
 }
 
 // updateVMSSCapacity invokes virtualMachineScaleSetsClient to update the capacity for VMSS.
-func (scaleSet *ScaleSet) updateVMSSCapacity(future *azure.Future) {
+// increase is the number of instances the update adds, reported as failed scale-ups if Azure runs out of capacity,
+// and added to the fallback node group of the scale set instead if fallback is set.
+func (scaleSet *ScaleSet) updateVMSSCapacity(future *azure.Future, increase int64, fallback bool) {
 	var err error
 
 	defer func() {
 		if err != nil {
 			klog.Errorf("Failed to update the capacity for vmss %s with error %v, invalidate the cache so as to get the real size from API", scaleSet.Name, err)
+			if scaleSet.refreshAfterScaleFailure("VirtualMachineScaleSets.CreateOrUpdate", err) {
+				return
+			}
 			// Invalidate the VMSS size cache in order to fetch the size from the API.
 			scaleSet.invalidateLastSizeRefreshWithLock()
 			scaleSet.manager.invalidateCache()
@@ -216,28 +305,68 @@ func (scaleSet *ScaleSet) updateVMSSCapacity(future *azure.Future) {
 	ctx, cancel := getContextWithCancel()
 	defer cancel()
 
-	klog.V(3).Infof("Calling virtualMachineScaleSetsClient.WaitForCreateOrUpdateResult(%s)", scaleSet.Name)
-	httpResponse, err := scaleSet.manager.azClient.virtualMachineScaleSetsClient.WaitForCreateOrUpdateResult(ctx, future, scaleSet.manager.config.ResourceGroup)
+	klogx.ProviderAzure.V(3).Infof("Calling virtualMachineScaleSetsClient.WaitForCreateOrUpdateResult(%s)", scaleSet.Name)
+	start := time.Now()
+	httpResponse, err := scaleSet.manager.azClient.virtualMachineScaleSetsClient.WaitForCreateOrUpdateResult(ctx, future, scaleSet.resourceGroup())
+	observeSDKRequest("VirtualMachineScaleSets.WaitForCreateOrUpdateResult", start, err)
 
 	isSuccess, err := isSuccessHTTPResponse(httpResponse, err)
 	if isSuccess {
-		klog.V(3).Infof("virtualMachineScaleSetsClient.WaitForCreateOrUpdateResult(%s) success", scaleSet.Name)
+		klogx.ProviderAzure.V(3).Infof("virtualMachineScaleSetsClient.WaitForCreateOrUpdateResult(%s) success", scaleSet.Name)
 		scaleSet.invalidateInstanceCache()
 		return
 	}
 
 	klog.Errorf("virtualMachineScaleSetsClient.WaitForCreateOrUpdateResult - updateVMSSCapacity for scale set %q failed: %v", scaleSet.Name, err)
+	if increase > 0 && isOutOfCapacityError(err) {
+		scaleSet.addFailedScaleUps(int(increase), err)
+		if fallback {
+			scaleSet.scaleUpFallback(int(increase))
+		}
+	}
 }
 
 // SetScaleSetSize sets ScaleSet size.
 func (scaleSet *ScaleSet) SetScaleSetSize(size int64) error {
+	return scaleSet.setScaleSetSize(size, true)
+}
+
+// setScaleSetSize sets the scale set size. If Azure runs out of capacity for the increase, it is added to the
+// fallback node group of the scale set if fallback is set.
+func (scaleSet *ScaleSet) setScaleSetSize(size int64, fallback bool) error {
+	future, increase, err := scaleSet.updateCapacity(size, false)
+	if err != nil {
+		return err
+	}
+
+	go scaleSet.updateVMSSCapacity(future, increase, fallback)
+	return nil
+}
+
+// updateCapacity starts updating the capacity of the scale set to size. Increases other than the one of an
+// atomic scale-up are refused while an atomic scale-up is in flight. It returns the future of the update and the
+// number of instances it adds.
+func (scaleSet *ScaleSet) updateCapacity(size int64, atomic bool) (*azure.Future, int64, error) {
+	var failure error
+	defer func() {
+		// Deferred before the unlock to run after it, as refreshing takes sizeMutex.
+		if failure != nil {
+			scaleSet.refreshAfterScaleFailure("VirtualMachineScaleSets.CreateOrUpdate", failure)
+		}
+	}()
+
 	scaleSet.sizeMutex.Lock()
 	defer scaleSet.sizeMutex.Unlock()
 
 	vmssInfo, err := scaleSet.getVMSSFromCache()
 	if err != nil {
 		klog.Errorf("Failed to get information for VMSS (%q): %v", scaleSet.Name, err)
-		return err
+		return nil, 0, err
+	}
+
+	increase := size - scaleSet.curSize
+	if increase > 0 && scaleSet.atomicScaleUpInProgress && !atomic {
+		return nil, 0, fmt.Errorf("an atomic scale-up of vmss %q is in progress", scaleSet.Name)
 	}
 
 	// Update the new capacity to cache.
@@ -253,30 +382,52 @@ func (scaleSet *ScaleSet) SetScaleSetSize(size int64) error {
 	}
 	ctx, cancel := getContextWithTimeout(vmssContextTimeout)
 	defer cancel()
-	klog.V(3).Infof("Waiting for virtualMachineScaleSetsClient.CreateOrUpdateAsync(%s)", scaleSet.Name)
-	future, rerr := scaleSet.manager.azClient.virtualMachineScaleSetsClient.CreateOrUpdateAsync(ctx, scaleSet.manager.config.ResourceGroup, scaleSet.Name, op)
+	klogx.ProviderAzure.V(3).Infof("Waiting for virtualMachineScaleSetsClient.CreateOrUpdateAsync(%s)", scaleSet.Name)
+	start := time.Now()
+	future, rerr := scaleSet.manager.azClient.virtualMachineScaleSetsClient.CreateOrUpdateAsync(ctx, scaleSet.resourceGroup(), scaleSet.Name, op)
+	observeARMRequest("VirtualMachineScaleSets.CreateOrUpdate", start, rerr)
 	if rerr != nil {
 		klog.Errorf("virtualMachineScaleSetsClient.CreateOrUpdate for scale set %q failed: %v", scaleSet.Name, rerr)
-		return rerr.Error()
+		// The capacity was already updated in the cached scale set.
+		failure = rerr.Error()
+		return nil, 0, failure
 	}
 
 	// Proactively set the VMSS size so autoscaler makes better decisions.
 	scaleSet.curSize = size
 	scaleSet.lastSizeRefresh = time.Now()
 
-	go scaleSet.updateVMSSCapacity(future)
-	return nil
+	return future, increase, nil
 }
 
 // TargetSize returns the current TARGET size of the node group. It is possible that the
 // number is different from the number of nodes registered in Kubernetes.
 func (scaleSet *ScaleSet) TargetSize() (int, error) {
 	size, err := scaleSet.GetScaleSetSize()
-	return int(size), err
+	if err != nil {
+		return int(size), err
+	}
+	return int(size) - len(scaleSet.getStoppedInstances()), nil
+}
+
+// RefreshTargetSize re-reads the scale set, and thus its capacity, from the Azure API.
+func (scaleSet *ScaleSet) RefreshTargetSize() error {
+	if err := scaleSet.manager.azureCache.refreshScaleSet(scaleSet.resourceGroup(), scaleSet.Name); err != nil {
+		return err
+	}
+	scaleSet.invalidateLastSizeRefreshWithLock()
+	return nil
 }
 
 // IncreaseSize increases Scale Set size
 func (scaleSet *ScaleSet) IncreaseSize(delta int) error {
+	return scaleSet.increaseSize(delta, true)
+}
+
+// increaseSize increases the scale set size. If Azure runs out of capacity for the new instances, they are added
+// to the fallback node group of the scale set if fallback is set. Scale-ups of fallback node groups don't fall back
+// further, so that scale sets falling back to each other don't scale each other up in turn.
+func (scaleSet *ScaleSet) increaseSize(delta int, fallback bool) error {
 	if delta <= 0 {
 		return fmt.Errorf("size increase must be positive")
 	}
@@ -290,22 +441,72 @@ func (scaleSet *ScaleSet) IncreaseSize(delta int) error {
 		return fmt.Errorf("the scale set %s is under initialization, skipping IncreaseSize", scaleSet.Name)
 	}
 
-	if int(size)+delta > scaleSet.MaxSize() {
-		return fmt.Errorf("size increase too large - desired:%d max:%d", int(size)+delta, scaleSet.MaxSize())
+	stopped := scaleSet.getStoppedInstances()
+	targetSize := int(size) - len(stopped)
+	if targetSize+delta > scaleSet.MaxSize() {
+		return fmt.Errorf("size increase too large - desired:%d max:%d", targetSize+delta, scaleSet.MaxSize())
+	}
+
+	if err := scaleSet.checkSpotEvictionCooldown(); err != nil {
+		return err
+	}
+
+	if err := scaleSet.checkFlexScaleUp(); err != nil {
+		return err
+	}
+
+	if err := scaleSet.checkQuota(delta); err != nil {
+		return err
+	}
+
+	// Instances stopped by scale-downs are started before the scale set grows.
+	if len(stopped) > 0 {
+		toStart := stopped
+		if len(toStart) > delta {
+			toStart = toStart[:delta]
+		}
+		if err := scaleSet.startInstances(toStart); err != nil {
+			return err
+		}
+		delta -= len(toStart)
+		if delta == 0 {
+			return nil
+		}
 	}
 
-	return scaleSet.SetScaleSetSize(size + int64(delta))
+	return scaleSet.setScaleSetSize(size+int64(delta), fallback)
+}
+
+// checkFlexScaleUp returns an error if the scale set uses Flexible orchestration but can't create VMs
+// through its capacity: either Flex support is disabled, or the scale set has no VM profile to create them from.
+func (scaleSet *ScaleSet) checkFlexScaleUp() error {
+	vmss, err := scaleSet.getVMSSFromCache()
+	if err != nil {
+		return err
+	}
+	if vmss.VirtualMachineScaleSetProperties == nil || vmss.OrchestrationMode != compute.Flexible {
+		return nil
+	}
+	if !scaleSet.manager.config.EnableVmssFlex {
+		return fmt.Errorf("vmss - %q with Flexible orchestration detected but 'enableVmssFlex' feature flag is turned off", scaleSet.Name)
+	}
+	if vmss.VirtualMachineProfile == nil {
+		return fmt.Errorf("vmss - %q with Flexible orchestration has no virtual machine profile, its capacity can't be increased", scaleSet.Name)
+	}
+	return nil
 }
 
 // GetScaleSetVms returns list of nodes for the given scale set.
 func (scaleSet *ScaleSet) GetScaleSetVms() ([]compute.VirtualMachineScaleSetVM, *retry.Error) {
-	klog.V(4).Infof("GetScaleSetVms: starts")
+	klogx.ProviderAzure.V(4).Infof("GetScaleSetVms: starts")
 	ctx, cancel := getContextWithTimeout(vmssContextTimeout)
 	defer cancel()
 
-	resourceGroup := scaleSet.manager.config.ResourceGroup
+	resourceGroup := scaleSet.resourceGroup()
+	start := time.Now()
 	vmList, rerr := scaleSet.manager.azClient.virtualMachineScaleSetVMsClient.List(ctx, resourceGroup, scaleSet.Name, "instanceView")
-	klog.V(4).Infof("GetScaleSetVms: scaleSet.Name: %s, vmList: %v", scaleSet.Name, vmList)
+	observeARMRequest("VirtualMachineScaleSetVMs.List", start, rerr)
+	klogx.ProviderAzure.V(4).Infof("GetScaleSetVms: scaleSet.Name: %s, vmList: %v", scaleSet.Name, vmList)
 	if rerr != nil {
 		klog.Errorf("VirtualMachineScaleSetVMsClient.List failed for %s: %v", scaleSet.Name, rerr)
 		return nil, rerr
@@ -316,7 +517,7 @@ func (scaleSet *ScaleSet) GetScaleSetVms() ([]compute.VirtualMachineScaleSetVM,
 
 // GetFlexibleScaleSetVms returns list of nodes for flexible scale set.
 func (scaleSet *ScaleSet) GetFlexibleScaleSetVms() ([]compute.VirtualMachine, *retry.Error) {
-	klog.V(4).Infof("GetScaleSetVms: starts")
+	klogx.ProviderAzure.V(4).Infof("GetFlexibleScaleSetVms: starts")
 	ctx, cancel := getContextWithTimeout(vmssContextTimeout)
 	defer cancel()
 
@@ -330,12 +531,14 @@ func (scaleSet *ScaleSet) GetFlexibleScaleSetVms() ([]compute.VirtualMachine, *r
 		}
 		return nil, rerr
 	}
+	start := time.Now()
 	vmList, rerr := scaleSet.manager.azClient.virtualMachinesClient.ListVmssFlexVMsWithoutInstanceView(ctx, *vmssInfo.ID)
+	observeARMRequest("VirtualMachines.ListVmssFlexVMs", start, rerr)
 	if rerr != nil {
-		klog.Errorf("VirtualMachineScaleSetVMsClient.List failed for %s: %v", scaleSet.Name, rerr)
+		klog.Errorf("VirtualMachinesClient.ListVmssFlexVMsWithoutInstanceView failed for %s: %v", scaleSet.Name, rerr)
 		return nil, rerr
 	}
-	klog.V(4).Infof("GetFlexibleScaleSetVms: scaleSet.Name: %s, vmList: %v", scaleSet.Name, vmList)
+	klogx.ProviderAzure.V(4).Infof("GetFlexibleScaleSetVms: scaleSet.Name: %s, vmList: %v", scaleSet.Name, vmList)
 	return vmList, nil
 }
 
@@ -357,7 +560,7 @@ func (scaleSet *ScaleSet) DecreaseTargetSize(delta int) error {
 
 // Belongs returns true if the given node belongs to the NodeGroup.
 func (scaleSet *ScaleSet) Belongs(node *apiv1.Node) (bool, error) {
-	klog.V(6).Infof("Check if node belongs to this scale set: scaleset:%v, node:%v\n", scaleSet, node)
+	klogx.ProviderAzure.V(6).Infof("Check if node belongs to this scale set: scaleset:%v, node:%v\n", scaleSet, node)
 
 	ref := &azureRef{
 		Name: node.Spec.ProviderID,
@@ -382,7 +585,7 @@ func (scaleSet *ScaleSet) DeleteInstances(instances []*azureRef, hasUnregistered
 		return nil
 	}
 
-	klog.V(3).Infof("Deleting vmss instances %v", instances)
+	klogx.ProviderAzure.V(3).Infof("Deleting vmss instances %v", instances)
 
 	commonAsg, err := scaleSet.manager.GetNodeGroupForInstance(instances[0])
 	if err != nil {
@@ -401,7 +604,11 @@ func (scaleSet *ScaleSet) DeleteInstances(instances []*azureRef, hasUnregistered
 		}
 
 		if cpi, found := scaleSet.getInstanceByProviderID(instance.Name); found && cpi.Status != nil && cpi.Status.State == cloudprovider.InstanceDeleting {
-			klog.V(3).Infof("Skipping deleting instance %s as its current state is deleting", instance.Name)
+			klogx.ProviderAzure.V(3).Infof("Skipping deleting instance %s as its current state is deleting", instance.Name)
+			continue
+		}
+		if scaleSet.isStopped(instance.Name) {
+			klogx.ProviderAzure.V(3).Infof("Skipping deleting instance %s as it is already stopped", instance.Name)
 			continue
 		}
 		instancesToDelete = append(instancesToDelete, instance)
@@ -409,7 +616,7 @@ func (scaleSet *ScaleSet) DeleteInstances(instances []*azureRef, hasUnregistered
 
 	// nothing to delete
 	if len(instancesToDelete) == 0 {
-		klog.V(3).Infof("No new instances eligible for deletion, skipping")
+		klogx.ProviderAzure.V(3).Infof("No new instances eligible for deletion, skipping")
 		return nil
 	}
 
@@ -423,20 +630,29 @@ func (scaleSet *ScaleSet) DeleteInstances(instances []*azureRef, hasUnregistered
 		instanceIDs = append(instanceIDs, instanceID)
 	}
 
+	if mode := scaleSet.scaleDownMode(); mode != scaleDownModeDelete {
+		klogx.ProviderAzure.V(3).Infof("Stopping vmss instances %v instead of deleting them, scale-down mode: %s", instanceIDs, mode)
+		scaleSet.stopInstances(instancesToDelete, instanceIDs, mode == scaleDownModeHibernate)
+		return nil
+	}
+
 	requiredIds := &compute.VirtualMachineScaleSetVMInstanceRequiredIDs{
 		InstanceIds: &instanceIDs,
 	}
 
 	ctx, cancel := getContextWithTimeout(vmssContextTimeout)
 	defer cancel()
-	resourceGroup := scaleSet.manager.config.ResourceGroup
+	resourceGroup := scaleSet.resourceGroup()
 
 	scaleSet.instanceMutex.Lock()
-	klog.V(3).Infof("Calling virtualMachineScaleSetsClient.DeleteInstancesAsync(%v)", requiredIds.InstanceIds)
-	future, rerr := scaleSet.manager.azClient.virtualMachineScaleSetsClient.DeleteInstancesAsync(ctx, resourceGroup, commonAsg.Id(), *requiredIds, false)
+	klogx.ProviderAzure.V(3).Infof("Calling virtualMachineScaleSetsClient.DeleteInstancesAsync(%v)", requiredIds.InstanceIds)
+	start := time.Now()
+	future, rerr := scaleSet.manager.azClient.virtualMachineScaleSetsClient.DeleteInstancesAsync(ctx, resourceGroup, commonAsg.Id(), *requiredIds, scaleSet.manager.config.EnableForceDelete)
+	observeARMRequest("VirtualMachineScaleSets.DeleteInstances", start, rerr)
 	scaleSet.instanceMutex.Unlock()
 	if rerr != nil {
 		klog.Errorf("virtualMachineScaleSetsClient.DeleteInstancesAsync for instances %v failed: %v", requiredIds.InstanceIds, rerr)
+		scaleSet.refreshAfterScaleFailure("VirtualMachineScaleSets.DeleteInstances", rerr.Error())
 		return rerr.Error()
 	}
 
@@ -462,16 +678,47 @@ func (scaleSet *ScaleSet) DeleteInstances(instances []*azureRef, hasUnregistered
 
 // DeleteNodes deletes the nodes from the group.
 func (scaleSet *ScaleSet) DeleteNodes(nodes []*apiv1.Node) error {
-	klog.V(8).Infof("Delete nodes requested: %q\n", nodes)
-	size, err := scaleSet.GetScaleSetSize()
+	klogx.ProviderAzure.V(8).Infof("Delete nodes requested: %q\n", nodes)
+	if nodes = scaleSet.deleteFailedScaleUps(nodes); len(nodes) == 0 {
+		return nil
+	}
+	nodes, skipErr := scaleSet.skipRepairedInstances(nodes)
+	if len(nodes) == 0 {
+		return skipErr
+	}
+	size, err := scaleSet.TargetSize()
 	if err != nil {
 		return err
 	}
 
-	if int(size) <= scaleSet.MinSize() {
+	if size <= scaleSet.MinSize() {
 		return fmt.Errorf("min size reached, nodes will not be deleted")
 	}
 
+	if err := scaleSet.deleteNodes(nodes); err != nil {
+		return err
+	}
+	return skipErr
+}
+
+// ForceDeleteNodes deletes the nodes from the group regardless of the min size of the scale set.
+func (scaleSet *ScaleSet) ForceDeleteNodes(nodes []*apiv1.Node) error {
+	klogx.ProviderAzure.V(8).Infof("Force delete nodes requested: %q\n", nodes)
+	if nodes = scaleSet.deleteFailedScaleUps(nodes); len(nodes) == 0 {
+		return nil
+	}
+	nodes, skipErr := scaleSet.skipRepairedInstances(nodes)
+	if len(nodes) == 0 {
+		return skipErr
+	}
+	if err := scaleSet.deleteNodes(nodes); err != nil {
+		return err
+	}
+	return skipErr
+}
+
+// deleteNodes deletes all the given nodes with a single DeleteInstances call.
+func (scaleSet *ScaleSet) deleteNodes(nodes []*apiv1.Node) error {
 	refs := make([]*azureRef, 0, len(nodes))
 	hasUnregisteredNodes := false
 	for _, node := range nodes {
@@ -498,6 +745,9 @@ func (scaleSet *ScaleSet) DeleteNodes(nodes []*apiv1.Node) error {
 
 // Id returns ScaleSet id.
 func (scaleSet *ScaleSet) Id() string {
+	if scaleSet.additionalResourceGroup != "" {
+		return scaleSet.additionalResourceGroup + "/" + scaleSet.Name
+	}
 	return scaleSet.Name
 }
 
@@ -517,6 +767,16 @@ func (scaleSet *ScaleSet) TemplateNodeInfo() (*schedulerframework.NodeInfo, erro
 	if err != nil {
 		return nil, err
 	}
+	if zone, found := scaleSet.nextZone(template); found {
+		// a node only ever is in one of the zones of the scale set.
+		zoneLabel := strings.ToLower(*template.Location) + "-" + zone
+		node.Labels[apiv1.LabelTopologyZone] = zoneLabel
+		node.Labels[azureDiskTopologyKey] = zoneLabel
+	}
+	if template.Zones == nil || len(*template.Zones) == 0 {
+		// like the cloud provider does, nodes outside of availability zones are labeled with their fault domain.
+		node.Labels[apiv1.LabelTopologyZone] = strconv.Itoa(int(scaleSet.nextFaultDomain(template)))
+	}
 
 	nodeInfo := schedulerframework.NewNodeInfo(cloudprovider.BuildKubeProxy(scaleSet.Name))
 	nodeInfo.SetNode(node)
@@ -525,36 +785,44 @@ func (scaleSet *ScaleSet) TemplateNodeInfo() (*schedulerframework.NodeInfo, erro
 
 // Nodes returns a list of all nodes that belong to this node group.
 func (scaleSet *ScaleSet) Nodes() ([]cloudprovider.Instance, error) {
-	klog.V(4).Infof("Nodes: starts, scaleSet.Name: %s", scaleSet.Name)
+	klogx.ProviderAzure.V(4).Infof("Nodes: starts, scaleSet.Name: %s", scaleSet.Name)
+	if !scaleSet.Exist() {
+		return []cloudprovider.Instance{}, nil
+	}
 	curSize, err := scaleSet.getCurSize()
 	if err != nil {
 		klog.Errorf("Failed to get current size for vmss %q: %v", scaleSet.Name, err)
 		return nil, err
 	}
 
+	// The scale set is read from the cache before taking the instance lock, which must not be held while
+	// taking the cache lock.
+	vmss, vmssErr := scaleSet.getVMSSFromCache()
+
 	scaleSet.instanceMutex.Lock()
 	defer scaleSet.instanceMutex.Unlock()
 
 	if int64(len(scaleSet.instanceCache)) == curSize &&
 		scaleSet.lastInstanceRefresh.Add(scaleSet.instancesRefreshPeriod).After(time.Now()) {
-		klog.V(4).Infof("Nodes: returns with curSize %d", curSize)
-		return scaleSet.instanceCache, nil
+		klogx.ProviderAzure.V(4).Infof("Nodes: returns with curSize %d", curSize)
+		return scaleSet.instancesWithFailedScaleUps(), nil
 	}
 
-	klog.V(4).Infof("Nodes: starts to get VMSS VMs")
+	klogx.ProviderAzure.V(4).Infof("Nodes: starts to get VMSS VMs")
+	previousInstances, previousListing := scaleSet.instanceCache, scaleSet.lastInstanceListing
 	splay := rand.New(rand.NewSource(time.Now().UnixNano())).Intn(scaleSet.instancesRefreshJitter + 1)
 	lastRefresh := time.Now().Add(-time.Second * time.Duration(splay))
 
-	orchestrationMode, err := scaleSet.getOrchestrationMode()
-	if err != nil {
-		klog.Errorf("failed to get information for VMSS: %s, error: %v", scaleSet.Name, err)
-		return nil, err
+	if vmssErr != nil {
+		klog.Errorf("failed to get information for VMSS: %s, error: %v", scaleSet.Name, vmssErr)
+		return nil, vmssErr
 	}
+	orchestrationMode := vmss.OrchestrationMode
 
-	klog.V(4).Infof("VMSS: orchestration Mode %s", orchestrationMode)
+	klogx.ProviderAzure.V(4).Infof("VMSS: orchestration Mode %s", orchestrationMode)
 
 	if orchestrationMode == compute.Uniform {
-		err := scaleSet.buildScaleSetCache(lastRefresh)
+		err := scaleSet.buildScaleSetCache(lastRefresh, vmss)
 		if err != nil {
 			return nil, err
 		}
@@ -572,15 +840,24 @@ func (scaleSet *ScaleSet) Nodes() ([]cloudprovider.Instance, error) {
 	} else {
 		return nil, fmt.Errorf("Failed to determine orchestration mode for vmss %q", scaleSet.Name)
 	}
+	if !scaleSet.lastInstanceListing.Equal(previousListing) {
+		scaleSet.trackRepairedInstances(vmss, previousInstances, !previousListing.IsZero(), curSize, time.Now())
+	}
 
-	klog.V(4).Infof("Nodes: returns")
-	return scaleSet.instanceCache, nil
+	klogx.ProviderAzure.V(4).Infof("Nodes: returns")
+	return scaleSet.instancesWithFailedScaleUps(), nil
 }
 
-func (scaleSet *ScaleSet) buildScaleSetCache(lastRefresh time.Time) error {
+func (scaleSet *ScaleSet) buildScaleSetCache(lastRefresh time.Time, vmss compute.VirtualMachineScaleSet) error {
+	if throttledUntil, throttled := scaleSet.manager.azureCache.throttled(); throttled {
+		klog.Warningf("ARM requests are throttled until %v, would return the cached instances of vmss %q", throttledUntil, scaleSet.Name)
+		scaleSet.lastInstanceRefresh = lastRefresh
+		return nil
+	}
+
 	vms, rerr := scaleSet.GetScaleSetVms()
 	if rerr != nil {
-		if isAzureRequestsThrottled(rerr) {
+		if scaleSet.manager.azureCache.observeThrottling(rerr) {
 			// Log a warning and update the instance refresh time so that it would retry after cache expiration
 			klog.Warningf("GetScaleSetVms() is throttled with message %v, would return the cached instances", rerr)
 			scaleSet.lastInstanceRefresh = lastRefresh
@@ -589,16 +866,30 @@ func (scaleSet *ScaleSet) buildScaleSetCache(lastRefresh time.Time) error {
 		return rerr.Error()
 	}
 
-	scaleSet.instanceCache = buildInstanceCache(vms)
+	scaleSet.instanceCache = buildInstanceCache(vms, isSpotScaleSet(vmss))
+	scaleSet.refreshStoppedInstances(vms, scaleSet.scaleDownModeOf(vmss))
+	scaleSet.faultDomainCounts = countScaleSetFaultDomains(vms)
+	scaleSet.zoneCounts = countInstanceZones(vms)
 	scaleSet.lastInstanceRefresh = lastRefresh
+	scaleSet.lastInstanceListing = lastRefresh
+	if evicted := countSpotEvictedInstances(scaleSet.instanceCache); evicted > 0 {
+		klog.Warningf("Found %d evicted Spot instances in vmss %q", evicted, scaleSet.Name)
+		scaleSet.lastSpotEviction = time.Now()
+	}
 
 	return nil
 }
 
 func (scaleSet *ScaleSet) buildScaleSetCacheForFlex(lastRefresh time.Time) error {
+	if throttledUntil, throttled := scaleSet.manager.azureCache.throttled(); throttled {
+		klog.Warningf("ARM requests are throttled until %v, would return the cached instances of vmss %q", throttledUntil, scaleSet.Name)
+		scaleSet.lastInstanceRefresh = lastRefresh
+		return nil
+	}
+
 	vms, rerr := scaleSet.GetFlexibleScaleSetVms()
 	if rerr != nil {
-		if isAzureRequestsThrottled(rerr) {
+		if scaleSet.manager.azureCache.observeThrottling(rerr) {
 			// Log a warning and update the instance refresh time so that it would retry after cache expiration
 			klog.Warningf("GetFlexibleScaleSetVms() is throttled with message %v, would return the cached instances", rerr)
 			scaleSet.lastInstanceRefresh = lastRefresh
@@ -607,15 +898,119 @@ func (scaleSet *ScaleSet) buildScaleSetCacheForFlex(lastRefresh time.Time) error
 		return rerr.Error()
 	}
 
-	scaleSet.instanceCache = buildInstanceCache(vms)
+	// Flexible scale set VMs are listed without instance view, evictions can't be detected.
+	scaleSet.instanceCache = buildFlexInstanceCache(vms, scaleSet.instanceCache)
 	scaleSet.lastInstanceRefresh = lastRefresh
+	scaleSet.lastInstanceListing = lastRefresh
 
 	return nil
 }
 
+// buildFlexInstanceCache builds the instance cache of a Flexible scale set. Its VMs are listed without
+// instance view, so their power state is unknown: the VMs failing provisioning that weren't seen running
+// in the previous cache are reported as failed creations, so that scale-ups which can't be fulfilled are
+// backed off. On the first listing, all VMs are assumed to be running.
+func buildFlexInstanceCache(vms []compute.VirtualMachine, previous []cloudprovider.Instance) []cloudprovider.Instance {
+	running := make(map[string]bool, len(previous))
+	for _, instance := range previous {
+		if instance.Status == nil || instance.Status.State == cloudprovider.InstanceRunning {
+			running[strings.ToLower(instance.Id)] = true
+		}
+	}
+
+	instances := []cloudprovider.Instance{}
+	for _, vm := range vms {
+		if vm.ID == nil {
+			continue
+		}
+		powerState := vmPowerStateRunning
+		if vm.InstanceView != nil && vm.InstanceView.Statuses != nil {
+			powerState = vmPowerStateFromStatuses(*vm.InstanceView.Statuses)
+		} else if previous != nil && !running[strings.ToLower("azure://"+*vm.ID)] {
+			powerState = vmPowerStateUnknown
+		}
+		addInstanceToCache(&instances, vm.ID, vm.ProvisioningState, powerState, false)
+	}
+	return instances
+}
+
+// countScaleSetFaultDomains returns the numbers of instances per platform fault domain.
+func countScaleSetFaultDomains(vms []compute.VirtualMachineScaleSetVM) map[int32]int {
+	faultDomains := make(map[int32]int)
+	for _, vm := range vms {
+		if vm.VirtualMachineScaleSetVMProperties == nil || vm.InstanceView == nil || vm.InstanceView.PlatformFaultDomain == nil {
+			continue
+		}
+		faultDomains[*vm.InstanceView.PlatformFaultDomain]++
+	}
+	return faultDomains
+}
+
+// nextFaultDomain returns the fault domain the next instance of a scale set outside of availability zones is
+// expected to be placed in. Azure spreads instances evenly, so this is its least populated fault domain.
+func (scaleSet *ScaleSet) nextFaultDomain(template compute.VirtualMachineScaleSet) int32 {
+	faultDomainCount := int32(defaultScaleSetFaultDomainCount)
+	if template.VirtualMachineScaleSetProperties != nil && template.PlatformFaultDomainCount != nil && *template.PlatformFaultDomainCount > 0 {
+		faultDomainCount = *template.PlatformFaultDomainCount
+	}
+
+	scaleSet.instanceMutex.Lock()
+	defer scaleSet.instanceMutex.Unlock()
+	return leastPopulatedDomain(scaleSet.faultDomainCounts, faultDomainCount)
+}
+
+// countInstanceZones returns the numbers of instances per availability zone.
+func countInstanceZones(vms []compute.VirtualMachineScaleSetVM) map[string]int {
+	zones := make(map[string]int)
+	for _, vm := range vms {
+		if vm.Zones == nil {
+			continue
+		}
+		for _, zone := range *vm.Zones {
+			zones[zone]++
+		}
+	}
+	return zones
+}
+
+// nextZone returns the availability zone the next instance of a zonal scale set is expected to be placed in.
+// Azure balances instances across the zones of a scale set, so this is its least populated zone.
+func (scaleSet *ScaleSet) nextZone(template compute.VirtualMachineScaleSet) (string, bool) {
+	if template.Zones == nil || len(*template.Zones) == 0 {
+		return "", false
+	}
+
+	scaleSet.instanceMutex.Lock()
+	defer scaleSet.instanceMutex.Unlock()
+	var leastPopulated []string
+	for _, zone := range *template.Zones {
+		if len(leastPopulated) == 0 || scaleSet.zoneCounts[zone] < scaleSet.zoneCounts[leastPopulated[0]] {
+			leastPopulated = []string{zone}
+		} else if scaleSet.zoneCounts[zone] == scaleSet.zoneCounts[leastPopulated[0]] {
+			leastPopulated = append(leastPopulated, zone)
+		}
+	}
+	// Azure may place the instance in any of the least populated zones, e.g. in any zone of an empty scale set.
+	// Successive templates rotate between them, so that pods requiring any of these zones can trigger a scale-up.
+	next := leastPopulated[scaleSet.zoneRotation%len(leastPopulated)]
+	scaleSet.zoneRotation++
+	return next, true
+}
+
+// leastPopulatedDomain returns the domain with the fewest instances, the lowest one on ties.
+func leastPopulatedDomain(counts map[int32]int, domainCount int32) int32 {
+	least := int32(0)
+	for domain := int32(1); domain < domainCount; domain++ {
+		if counts[domain] < counts[least] {
+			least = domain
+		}
+	}
+	return least
+}
+
 // Note that the GetScaleSetVms() results is not used directly because for the List endpoint,
 // their resource ID format is not consistent with Get endpoint
-func buildInstanceCache(vmList interface{}) []cloudprovider.Instance {
+func buildInstanceCache(vmList interface{}, spot bool) []cloudprovider.Instance {
 	instances := []cloudprovider.Instance{}
 
 	switch vms := vmList.(type) {
@@ -625,7 +1020,7 @@ func buildInstanceCache(vmList interface{}) []cloudprovider.Instance {
 			if vm.InstanceView != nil && vm.InstanceView.Statuses != nil {
 				powerState = vmPowerStateFromStatuses(*vm.InstanceView.Statuses)
 			}
-			addInstanceToCache(&instances, vm.ID, vm.ProvisioningState, powerState)
+			addInstanceToCache(&instances, vm.ID, vm.ProvisioningState, powerState, spot)
 		}
 	case []compute.VirtualMachine:
 		for _, vm := range vms {
@@ -633,14 +1028,14 @@ func buildInstanceCache(vmList interface{}) []cloudprovider.Instance {
 			if vm.InstanceView != nil && vm.InstanceView.Statuses != nil {
 				powerState = vmPowerStateFromStatuses(*vm.InstanceView.Statuses)
 			}
-			addInstanceToCache(&instances, vm.ID, vm.ProvisioningState, powerState)
+			addInstanceToCache(&instances, vm.ID, vm.ProvisioningState, powerState, spot)
 		}
 	}
 
 	return instances
 }
 
-func addInstanceToCache(instances *[]cloudprovider.Instance, id *string, provisioningState *string, powerState string) {
+func addInstanceToCache(instances *[]cloudprovider.Instance, id *string, provisioningState *string, powerState string, spot bool) {
 	// The resource ID is empty string, which indicates the instance may be in deleting state.
 	if len(*id) == 0 {
 		return
@@ -653,29 +1048,230 @@ func addInstanceToCache(instances *[]cloudprovider.Instance, id *string, provisi
 		return
 	}
 
+	status := instanceStatusFromProvisioningStateAndPowerState(resourceID, provisioningState, powerState)
+	if spot && status != nil && status.State == cloudprovider.InstanceRunning && isSpotEvictedPowerState(powerState) {
+		klogx.ProviderAzure.V(4).Infof("Spot VM %s was evicted (%s)", resourceID, powerState)
+		status = spotEvictedInstanceStatus()
+	}
+
 	*instances = append(*instances, cloudprovider.Instance{
 		Id:     "azure://" + resourceID,
-		Status: instanceStatusFromProvisioningStateAndPowerState(resourceID, provisioningState, powerState),
+		Status: status,
 	})
 }
 
+// isSpotEvictedPowerState returns true if the power state is the one Spot VMs are left in by an eviction
+// with the Deallocate policy.
+func isSpotEvictedPowerState(powerState string) bool {
+	return powerState == vmPowerStateDeallocating || powerState == vmPowerStateDeallocated
+}
+
+// spotEvictedInstanceStatus reports evicted Spot instances as failed creations, so that they are removed instead of
+// lingering as unready nodes, and scale-ups in progress are backed off.
+func spotEvictedInstanceStatus() *cloudprovider.InstanceStatus {
+	return &cloudprovider.InstanceStatus{
+		State: cloudprovider.InstanceCreating,
+		ErrorInfo: &cloudprovider.InstanceErrorInfo{
+			ErrorClass:   cloudprovider.OutOfResourcesErrorClass,
+			ErrorCode:    "spot-eviction",
+			ErrorMessage: "Azure evicted a Spot instance of this node group",
+		},
+	}
+}
+
+func countSpotEvictedInstances(instances []cloudprovider.Instance) int {
+	evicted := 0
+	for _, instance := range instances {
+		if instance.Status != nil && instance.Status.ErrorInfo != nil && instance.Status.ErrorInfo.ErrorCode == "spot-eviction" {
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// isSpot returns true if the scale set runs Spot VMs.
+func (scaleSet *ScaleSet) isSpot() bool {
+	vmss, err := scaleSet.getVMSSFromCache()
+	return err == nil && isSpotScaleSet(vmss)
+}
+
+// isSpotScaleSet returns true if the instances of the given scale set are Spot VMs.
+func isSpotScaleSet(vmss compute.VirtualMachineScaleSet) bool {
+	return vmss.VirtualMachineScaleSetProperties != nil && vmss.VirtualMachineProfile != nil &&
+		vmss.VirtualMachineProfile.Priority == compute.Spot
+}
+
+// checkSpotEvictionCooldown refuses scale-ups of a Spot scale set for the duration of its spot-eviction-cooldown tag
+// after an eviction. The failed scale-up backs the scale set off, so similar node groups (e.g. in other zones) are
+// scaled up instead.
+func (scaleSet *ScaleSet) checkSpotEvictionCooldown() error {
+	vmss, err := scaleSet.getVMSSFromCache()
+	if err != nil {
+		return nil
+	}
+	raw, found := vmss.Tags[spotEvictionCooldownTag]
+	if !found || raw == nil {
+		return nil
+	}
+	cooldown, err := time.ParseDuration(*raw)
+	if err != nil {
+		klog.Warningf("failed to parse vmss %q tag %s value %q as duration: %v", scaleSet.Name, spotEvictionCooldownTag, *raw, err)
+		return nil
+	}
+
+	scaleSet.instanceMutex.Lock()
+	lastEviction := scaleSet.lastSpotEviction
+	scaleSet.instanceMutex.Unlock()
+	if !lastEviction.IsZero() && time.Since(lastEviction) < cooldown {
+		return fmt.Errorf("vmss %s had Spot instances evicted at %v, not scaling it up for %v", scaleSet.Name, lastEviction, cooldown)
+	}
+	return nil
+}
+
+// outOfCapacityErrorCodes are the codes, or parts of the codes, of the errors Azure returns when it can't allocate
+// the VMs of a scale-up: capacity stock-outs, and exhaustion of the capacity reservation group or dedicated host group
+// the scale set is pinned to.
+var outOfCapacityErrorCodes = []string{
+	"AllocationFailed",
+	"OverconstrainedAllocationRequest",
+	"OverconstrainedZonalAllocationRequest",
+	"CapacityReservation",
+	"DedicatedHost",
+}
+
+// isOutOfCapacityError returns true if the error is Azure failing to allocate VMs for lack of capacity.
+func isOutOfCapacityError(err error) bool {
+	if err == nil {
+		return false
+	}
+	message := strings.ToLower(err.Error())
+	for _, code := range outOfCapacityErrorCodes {
+		if strings.Contains(message, strings.ToLower(code)) {
+			return true
+		}
+	}
+	return false
+}
+
+// addFailedScaleUps adds count placeholders for instances a scale-up failed to create because Azure ran out of capacity.
+// They are reported as instances failing to be created with an OutOfResources error, for the scale set to be backed off
+// and the pending pods to be scheduled on other node groups.
+func (scaleSet *ScaleSet) addFailedScaleUps(count int, err error) {
+	resourceGroup := scaleSet.resourceGroup()
+	scaleSet.instanceMutex.Lock()
+	defer scaleSet.instanceMutex.Unlock()
+
+	klog.Warningf("Azure ran out of capacity to add %d instances to vmss %q, reporting them as failed scale-ups", count, scaleSet.Name)
+	for i := 0; i < count; i++ {
+		scaleSet.failedScaleUpCount++
+		scaleSet.failedScaleUps = append(scaleSet.failedScaleUps, cloudprovider.Instance{
+			Id: fmt.Sprintf("azure:///subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/virtualMachineScaleSets/%s/virtualMachines/%s%d",
+				scaleSet.manager.config.SubscriptionID, strings.ToLower(resourceGroup), scaleSet.Name,
+				failedScaleUpInstancePrefix, scaleSet.failedScaleUpCount),
+			Status: &cloudprovider.InstanceStatus{
+				State: cloudprovider.InstanceCreating,
+				ErrorInfo: &cloudprovider.InstanceErrorInfo{
+					ErrorClass:   cloudprovider.OutOfResourcesErrorClass,
+					ErrorCode:    "out-of-capacity",
+					ErrorMessage: fmt.Sprintf("Azure ran out of capacity for this node group: %v", err),
+				},
+			},
+		})
+	}
+}
+
+// scaleUpFallback adds the instances Azure ran out of capacity for to the fallback node group of the scale set,
+// the registered scale set named by its fallback-node-group tag, e.g. an on-demand scale set backing a scale set
+// pinned to a capacity reservation group.
+func (scaleSet *ScaleSet) scaleUpFallback(count int) {
+	vmss, err := scaleSet.getVMSSFromCache()
+	if err != nil {
+		return
+	}
+	raw, found := vmss.Tags[fallbackNodeGroupTag]
+	if !found || raw == nil || *raw == "" {
+		return
+	}
+	for _, nodeGroup := range scaleSet.manager.getNodeGroups() {
+		fallback, ok := nodeGroup.(*ScaleSet)
+		if !ok || fallback == scaleSet || !strings.EqualFold(fallback.Name, *raw) {
+			continue
+		}
+		if err := fallback.increaseSize(count, false); err != nil {
+			klog.Errorf("Failed to scale up fallback vmss %q of vmss %q by %d instances: %v", fallback.Name, scaleSet.Name, count, err)
+			return
+		}
+		klog.V(2).Infof("Azure ran out of capacity for vmss %q, scaled up its fallback vmss %q by %d instances", scaleSet.Name, fallback.Name, count)
+		return
+	}
+	klog.Warningf("Fallback node group %q of vmss %q is not a registered scale set", *raw, scaleSet.Name)
+}
+
+// instancesWithFailedScaleUps returns the cached instances, but the ones stopped by scale-downs, followed by
+// the failed scale-up placeholders. instanceMutex must be held.
+func (scaleSet *ScaleSet) instancesWithFailedScaleUps() []cloudprovider.Instance {
+	if len(scaleSet.failedScaleUps) == 0 && len(scaleSet.stoppedInstances) == 0 {
+		return scaleSet.instanceCache
+	}
+	instances := make([]cloudprovider.Instance, 0, len(scaleSet.instanceCache)+len(scaleSet.failedScaleUps))
+	for _, instance := range scaleSet.instanceCache {
+		if !scaleSet.stoppedInstances[strings.ToLower(instance.Id)] {
+			instances = append(instances, instance)
+		}
+	}
+	return append(instances, scaleSet.failedScaleUps...)
+}
+
+// deleteFailedScaleUps drops the failed scale-up placeholders among the given nodes, which have no VM to delete,
+// and returns the other nodes.
+func (scaleSet *ScaleSet) deleteFailedScaleUps(nodes []*apiv1.Node) []*apiv1.Node {
+	scaleSet.instanceMutex.Lock()
+	defer scaleSet.instanceMutex.Unlock()
+
+	if len(scaleSet.failedScaleUps) == 0 {
+		return nodes
+	}
+	remaining := make([]*apiv1.Node, 0, len(nodes))
+	for _, node := range nodes {
+		placeholder := false
+		for i, instance := range scaleSet.failedScaleUps {
+			if strings.EqualFold(instance.Id, node.Spec.ProviderID) {
+				scaleSet.failedScaleUps = append(scaleSet.failedScaleUps[:i], scaleSet.failedScaleUps[i+1:]...)
+				placeholder = true
+				break
+			}
+		}
+		if !placeholder {
+			remaining = append(remaining, node)
+		}
+	}
+	return remaining
+}
+
 func (scaleSet *ScaleSet) getInstanceByProviderID(providerID string) (cloudprovider.Instance, bool) {
 	scaleSet.instanceMutex.Lock()
 	defer scaleSet.instanceMutex.Unlock()
 	for _, instance := range scaleSet.instanceCache {
-		if instance.Id == providerID {
+		if strings.EqualFold(instance.Id, providerID) {
 			return instance, true
 		}
 	}
 	return cloudprovider.Instance{}, false
 }
 
+// listedSince returns true if the scale set VMs were listed after the given time.
+func (scaleSet *ScaleSet) listedSince(t time.Time) bool {
+	scaleSet.instanceMutex.Lock()
+	defer scaleSet.instanceMutex.Unlock()
+	return scaleSet.lastInstanceListing.After(t)
+}
+
 func (scaleSet *ScaleSet) setInstanceStatusByProviderID(providerID string, status cloudprovider.InstanceStatus) {
 	scaleSet.instanceMutex.Lock()
 	defer scaleSet.instanceMutex.Unlock()
 	for k, instance := range scaleSet.instanceCache {
-		if instance.Id == providerID {
-			klog.V(5).Infof("Setting instance %s status to %v", instance.Id, status)
+		if strings.EqualFold(instance.Id, providerID) {
+			klogx.ProviderAzure.V(5).Infof("Setting instance %s status to %v", instance.Id, status)
 			scaleSet.instanceCache[k].Status = &status
 		}
 	}
@@ -688,7 +1284,7 @@ func instanceStatusFromProvisioningStateAndPowerState(resourceId string, provisi
 		return nil
 	}
 
-	klog.V(5).Infof("Getting vm instance provisioning state %s for %s", *provisioningState, resourceId)
+	klogx.ProviderAzure.V(5).Infof("Getting vm instance provisioning state %s for %s", *provisioningState, resourceId)
 
 	status := &cloudprovider.InstanceStatus{}
 	switch *provisioningState {
@@ -702,7 +1298,7 @@ func instanceStatusFromProvisioningStateAndPowerState(resourceId string, provisi
 		// ProvisioningState represents the most recent provisioning state, therefore only report
 		// InstanceCreating errors when the power state indicates the instance has not yet started running
 		if !isRunningVmPowerState(powerState) {
-			klog.V(4).Infof("VM %s reports failed provisioning state with non-running power state: %s", resourceId, powerState)
+			klogx.ProviderAzure.V(4).Infof("VM %s reports failed provisioning state with non-running power state: %s", resourceId, powerState)
 			status.State = cloudprovider.InstanceCreating
 			status.ErrorInfo = &cloudprovider.InstanceErrorInfo{
 				ErrorClass:   cloudprovider.OutOfResourcesErrorClass,
@@ -710,7 +1306,7 @@ func instanceStatusFromProvisioningStateAndPowerState(resourceId string, provisi
 				ErrorMessage: "Azure failed to provision a node for this node group",
 			}
 		} else {
-			klog.V(5).Infof("VM %s reports a failed provisioning state but is running (%s)", resourceId, powerState)
+			klogx.ProviderAzure.V(5).Infof("VM %s reports a failed provisioning state but is running (%s)", resourceId, powerState)
 			status.State = cloudprovider.InstanceRunning
 		}
 	default:
@@ -720,6 +1316,24 @@ func instanceStatusFromProvisioningStateAndPowerState(resourceId string, provisi
 	return status
 }
 
+// refreshAfterScaleFailure gets the scale set again after one of its write operations failed, e.g. with a 409 conflict,
+// and invalidates its cached size and instances, so that they are listed again by the next Nodes call rather than
+// served stale until vmssVmsCacheTTL expires. The caches of other scale sets are left untouched. It returns false if
+// refreshes on scale failures are disabled, for the caller to fall back to its own invalidation.
+func (scaleSet *ScaleSet) refreshAfterScaleFailure(operation string, err error) bool {
+	if !scaleSet.manager.config.EnableRefreshOnScaleFailure {
+		return false
+	}
+
+	klog.Warningf("%s failed for vmss %q, refreshing its size and instances: %v", operation, scaleSet.Name, err)
+	if err := scaleSet.manager.azureCache.refreshScaleSet(scaleSet.resourceGroup(), scaleSet.Name); err != nil {
+		klog.Errorf("Failed to refresh vmss %q: %v", scaleSet.Name, err)
+	}
+	scaleSet.invalidateLastSizeRefreshWithLock()
+	scaleSet.invalidateInstanceCache()
+	return true
+}
+
 func (scaleSet *ScaleSet) invalidateInstanceCache() {
 	scaleSet.instanceMutex.Lock()
 	// Set the instanceCache as outdated.
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2022-08-01/compute"
 	"github.com/Azure/go-autorest/autorest/to"
@@ -164,6 +165,33 @@ func TestTargetSize(t *testing.T) {
 	}
 }
 
+func TestRefreshTargetSize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	provider := newTestProvider(t)
+	mockVMSSClient := mockvmssclient.NewMockInterface(ctrl)
+	mockVMSSClient.EXPECT().List(gomock.Any(), provider.azureManager.config.ResourceGroup).Return(newTestVMSSList(3, "test-asg", "eastus", compute.Uniform), nil).AnyTimes()
+	provider.azureManager.azClient.virtualMachineScaleSetsClient = mockVMSSClient
+	mockVMSSVMClient := mockvmssvmclient.NewMockInterface(ctrl)
+	mockVMSSVMClient.EXPECT().List(gomock.Any(), provider.azureManager.config.ResourceGroup, "test-asg", gomock.Any()).Return(newTestVMSSVMList(3), nil).AnyTimes()
+	provider.azureManager.azClient.virtualMachineScaleSetVMsClient = mockVMSSVMClient
+	assert.NoError(t, provider.azureManager.forceRefresh())
+
+	scaleSet := newTestScaleSet(provider.azureManager, "test-asg")
+	assert.True(t, provider.azureManager.RegisterNodeGroup(scaleSet))
+	targetSize, err := scaleSet.TargetSize()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, targetSize)
+
+	// The scale set was resized outside of the autoscaler.
+	mockVMSSClient.EXPECT().Get(gomock.Any(), provider.azureManager.config.ResourceGroup, "test-asg").Return(newTestVMSSList(5, "test-asg", "eastus", compute.Uniform)[0], nil)
+	assert.NoError(t, scaleSet.RefreshTargetSize())
+	targetSize, err = scaleSet.TargetSize()
+	assert.NoError(t, err)
+	assert.Equal(t, 5, targetSize)
+}
+
 func TestIncreaseSize(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -177,6 +205,8 @@ func TestIncreaseSize(t *testing.T) {
 
 		provider := newTestProvider(t)
 		expectedScaleSets := newTestVMSSList(3, "test-asg", "eastus", orchMode)
+		// Flexible scale sets create VMs from their VM profile when their capacity is increased.
+		expectedScaleSets[0].VirtualMachineProfile = &compute.VirtualMachineScaleSetVMProfile{}
 
 		mockVMSSClient := mockvmssclient.NewMockInterface(ctrl)
 		mockVMSSClient.EXPECT().List(gomock.Any(), provider.azureManager.config.ResourceGroup).Return(expectedScaleSets, nil).AnyTimes()
@@ -501,6 +531,69 @@ func TestDeleteNodes(t *testing.T) {
 	}
 }
 
+func TestForceDeleteNodes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	vmssName := "test-asg"
+	var vmssCapacity int64 = 3
+	expectedVMSSVMs := newTestVMSSVMList(3)
+
+	manager := newTestAzureManager(t)
+	manager.config.EnableForceDelete = true
+	expectedScaleSets := newTestVMSSList(vmssCapacity, vmssName, "eastus", compute.Uniform)
+
+	mockVMSSClient := mockvmssclient.NewMockInterface(ctrl)
+	mockVMSSClient.EXPECT().List(gomock.Any(), manager.config.ResourceGroup).Return(expectedScaleSets, nil).AnyTimes()
+	// all the instances are removed with a single forced DeleteInstances call
+	mockVMSSClient.EXPECT().DeleteInstancesAsync(gomock.Any(), manager.config.ResourceGroup, vmssName, compute.VirtualMachineScaleSetVMInstanceRequiredIDs{
+		InstanceIds: &[]string{"0", "1", "2"},
+	}, true).Return(nil, nil).Times(1)
+	mockVMSSClient.EXPECT().WaitForDeleteInstancesResult(gomock.Any(), gomock.Any(), manager.config.ResourceGroup).Return(&http.Response{StatusCode: http.StatusOK}, nil).AnyTimes()
+	manager.azClient.virtualMachineScaleSetsClient = mockVMSSClient
+	mockVMSSVMClient := mockvmssvmclient.NewMockInterface(ctrl)
+	mockVMSSVMClient.EXPECT().List(gomock.Any(), manager.config.ResourceGroup, vmssName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
+	manager.azClient.virtualMachineScaleSetVMsClient = mockVMSSVMClient
+
+	resourceLimiter := cloudprovider.NewResourceLimiter(
+		map[string]int64{cloudprovider.ResourceNameCores: 1, cloudprovider.ResourceNameMemory: 10000000},
+		map[string]int64{cloudprovider.ResourceNameCores: 10, cloudprovider.ResourceNameMemory: 100000000})
+	provider, err := BuildAzureCloudProvider(manager, resourceLimiter)
+	assert.NoError(t, err)
+
+	registered := manager.RegisterNodeGroup(newTestScaleSet(manager, vmssName))
+	manager.explicitlyConfigured[vmssName] = true
+	assert.True(t, registered)
+	err = manager.forceRefresh()
+	assert.NoError(t, err)
+
+	scaleSet, ok := provider.NodeGroups()[0].(*ScaleSet)
+	assert.True(t, ok)
+	scaleSet.setSizeLimits(3, 5)
+
+	nodesToDelete := []*apiv1.Node{
+		newApiNode(compute.Uniform, 0),
+		newApiNode(compute.Uniform, 1),
+		newApiNode(compute.Uniform, 2),
+	}
+
+	// regular deletion respects the min size
+	err = scaleSet.DeleteNodes(nodesToDelete)
+	assert.Error(t, err)
+
+	err = scaleSet.ForceDeleteNodes(nodesToDelete)
+	assert.NoError(t, err)
+
+	targetSize, err := scaleSet.TargetSize()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, targetSize)
+	for _, node := range nodesToDelete {
+		instance, found := scaleSet.getInstanceByProviderID(node.Spec.ProviderID)
+		assert.True(t, found)
+		assert.Equal(t, cloudprovider.InstanceDeleting, instance.Status.State)
+	}
+}
+
 func TestDeleteNodeUnregistered(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -850,3 +943,270 @@ func TestTemplateNodeInfo(t *testing.T) {
 		assert.NotEmpty(t, nodeInfo.Pods)
 	})
 }
+
+func TestBuildInstanceCacheSpotEviction(t *testing.T) {
+	vms := newTestVMSSVMList(3)
+	powerStates := []string{vmPowerStateRunning, vmPowerStateDeallocated, vmPowerStateDeallocating}
+	for i := range vms {
+		vms[i].ProvisioningState = to.StringPtr(provisioningStateSucceeded)
+		vms[i].InstanceView = &compute.VirtualMachineScaleSetVMInstanceView{
+			Statuses: &[]compute.InstanceViewStatus{{Code: to.StringPtr(powerStates[i])}},
+		}
+	}
+
+	instances := buildInstanceCache(vms, true)
+	assert.Equal(t, 3, len(instances))
+	assert.Equal(t, cloudprovider.InstanceRunning, instances[0].Status.State)
+	for _, instance := range instances[1:] {
+		assert.Equal(t, cloudprovider.InstanceCreating, instance.Status.State)
+		assert.Equal(t, cloudprovider.OutOfResourcesErrorClass, instance.Status.ErrorInfo.ErrorClass)
+		assert.Equal(t, "spot-eviction", instance.Status.ErrorInfo.ErrorCode)
+	}
+	assert.Equal(t, 2, countSpotEvictedInstances(instances))
+
+	// Deallocated instances of regular scale sets are left alone.
+	instances = buildInstanceCache(vms, false)
+	assert.Equal(t, 0, countSpotEvictedInstances(instances))
+	for _, instance := range instances {
+		assert.Equal(t, cloudprovider.InstanceRunning, instance.Status.State)
+	}
+}
+
+func TestBuildFlexInstanceCache(t *testing.T) {
+	vms := newTestVMList(3)
+	vms[0].ProvisioningState = to.StringPtr(provisioningStateSucceeded)
+	vms[1].ProvisioningState = to.StringPtr(provisioningStateCreating)
+	vms[2].ProvisioningState = to.StringPtr(provisioningStateFailed)
+
+	// On the first listing, failed VMs are assumed to be running.
+	instances := buildFlexInstanceCache(vms, nil)
+	assert.Equal(t, 3, len(instances))
+	assert.Equal(t, cloudprovider.InstanceRunning, instances[0].Status.State)
+	assert.Equal(t, cloudprovider.InstanceCreating, instances[1].Status.State)
+	assert.Equal(t, cloudprovider.InstanceRunning, instances[2].Status.State)
+
+	// A VM failing provisioning while it was being created is a failed creation.
+	previous := []cloudprovider.Instance{instances[0], instances[1]}
+	vms[1].ProvisioningState = to.StringPtr(provisioningStateFailed)
+	instances = buildFlexInstanceCache(vms[:2], previous)
+	assert.Equal(t, 2, len(instances))
+	assert.Equal(t, cloudprovider.InstanceRunning, instances[0].Status.State)
+	assert.Equal(t, cloudprovider.InstanceCreating, instances[1].Status.State)
+	assert.Equal(t, "provisioning-state-failed", instances[1].Status.ErrorInfo.ErrorCode)
+
+	// A VM failing provisioning after it was seen running is left alone.
+	vms[0].ProvisioningState = to.StringPtr(provisioningStateFailed)
+	instances = buildFlexInstanceCache(vms[:1], previous)
+	assert.Equal(t, cloudprovider.InstanceRunning, instances[0].Status.State)
+	assert.Nil(t, instances[0].Status.ErrorInfo)
+}
+
+func TestIncreaseSizeFlexWithoutVMProfile(t *testing.T) {
+	vmss := newTestVMSSList(3, "flex-vmss", "eastus", compute.Flexible)[0]
+	manager := &AzureManager{config: &Config{}, azureCache: &azureCache{
+		scaleSets: map[string]compute.VirtualMachineScaleSet{"flex-vmss": vmss},
+	}}
+	scaleSet := newTestScaleSet(manager, "flex-vmss")
+
+	err := scaleSet.checkFlexScaleUp()
+	assert.EqualError(t, err, `vmss - "flex-vmss" with Flexible orchestration detected but 'enableVmssFlex' feature flag is turned off`)
+
+	manager.config.EnableVmssFlex = true
+	err = scaleSet.checkFlexScaleUp()
+	assert.EqualError(t, err, `vmss - "flex-vmss" with Flexible orchestration has no virtual machine profile, its capacity can't be increased`)
+
+	vmss.VirtualMachineProfile = &compute.VirtualMachineScaleSetVMProfile{}
+	manager.azureCache.scaleSets["flex-vmss"] = vmss
+	assert.NoError(t, scaleSet.checkFlexScaleUp())
+}
+
+func TestSpotEvictionCooldown(t *testing.T) {
+	vmss := newTestVMSSList(3, "spot-vmss", "eastus", compute.Uniform)[0]
+	vmss.VirtualMachineProfile = &compute.VirtualMachineScaleSetVMProfile{Priority: compute.Spot}
+	manager := &AzureManager{azureCache: &azureCache{
+		scaleSets: map[string]compute.VirtualMachineScaleSet{"spot-vmss": vmss},
+	}}
+	scaleSet := newTestScaleSet(manager, "spot-vmss")
+	assert.True(t, scaleSet.isSpot())
+
+	// No cooldown configured.
+	scaleSet.lastSpotEviction = time.Now()
+	assert.NoError(t, scaleSet.checkSpotEvictionCooldown())
+
+	vmss.Tags = map[string]*string{spotEvictionCooldownTag: to.StringPtr("30m")}
+	manager.azureCache.scaleSets["spot-vmss"] = vmss
+	assert.Error(t, scaleSet.checkSpotEvictionCooldown())
+
+	scaleSet.lastSpotEviction = time.Now().Add(-time.Hour)
+	assert.NoError(t, scaleSet.checkSpotEvictionCooldown())
+}
+
+func TestIsOutOfCapacityError(t *testing.T) {
+	assert.False(t, isOutOfCapacityError(nil))
+	assert.False(t, isOutOfCapacityError(fmt.Errorf("Code=\"OperationNotAllowed\" Message=\"Operation could not be completed as it results in exceeding approved Total Regional Cores quota\"")))
+	assert.True(t, isOutOfCapacityError(fmt.Errorf("Code=\"ZonalAllocationFailed\" Message=\"Allocation failed. We do not have sufficient capacity for the requested VM size in this zone.\"")))
+	assert.True(t, isOutOfCapacityError(fmt.Errorf("Code=\"CapacityReservationGroupExhausted\" Message=\"No capacity left in the reservation\"")))
+}
+
+func TestFailedScaleUps(t *testing.T) {
+	manager := &AzureManager{config: &Config{SubscriptionID: "sub", ResourceGroup: "RG"}}
+	scaleSet := newTestScaleSet(manager, "reserved-vmss")
+	scaleSet.instanceCache = []cloudprovider.Instance{{Id: "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/reserved-vmss/virtualMachines/0"}}
+
+	scaleSet.addFailedScaleUps(2, fmt.Errorf("Code=\"AllocationFailed\""))
+	instances := scaleSet.instancesWithFailedScaleUps()
+	assert.Len(t, instances, 3)
+	assert.Len(t, scaleSet.instanceCache, 1)
+	for _, instance := range instances[1:] {
+		assert.Equal(t, cloudprovider.InstanceCreating, instance.Status.State)
+		assert.Equal(t, cloudprovider.OutOfResourcesErrorClass, instance.Status.ErrorInfo.ErrorClass)
+	}
+	assert.Equal(t, "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/reserved-vmss/virtualMachines/failed-scale-up-1", instances[1].Id)
+
+	// Placeholders have no VM to delete, only the other nodes are left to delete.
+	node := &apiv1.Node{Spec: apiv1.NodeSpec{ProviderID: instances[0].Id}}
+	placeholder := &apiv1.Node{Spec: apiv1.NodeSpec{ProviderID: instances[1].Id}}
+	assert.Equal(t, []*apiv1.Node{node}, scaleSet.deleteFailedScaleUps([]*apiv1.Node{node, placeholder}))
+	assert.Equal(t, []cloudprovider.Instance{instances[0], instances[2]}, scaleSet.instancesWithFailedScaleUps())
+}
+
+func TestScaleUpFallback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager := newTestAzureManager(t)
+	reservedVMSS := newTestVMSSList(3, "reserved-asg", "eastus", compute.Uniform)[0]
+	reservedVMSS.Tags = map[string]*string{fallbackNodeGroupTag: to.StringPtr("ondemand-asg")}
+	// Scale sets falling back to each other don't scale each other up in turn.
+	ondemandVMSS := newTestVMSSList(2, "ondemand-asg", "eastus", compute.Uniform)[0]
+	ondemandVMSS.Tags = map[string]*string{fallbackNodeGroupTag: to.StringPtr("reserved-asg")}
+	allocationFailed := fmt.Errorf("Code=\"CapacityReservationGroupExhausted\" Message=\"No capacity left in the reservation\"")
+
+	mockVMSSClient := mockvmssclient.NewMockInterface(ctrl)
+	mockVMSSClient.EXPECT().List(gomock.Any(), manager.config.ResourceGroup).Return([]compute.VirtualMachineScaleSet{reservedVMSS, ondemandVMSS}, nil).AnyTimes()
+	mockVMSSClient.EXPECT().CreateOrUpdateAsync(gomock.Any(), manager.config.ResourceGroup, "ondemand-asg", gomock.Any()).Return(nil, nil).Times(1)
+	mockVMSSClient.EXPECT().WaitForCreateOrUpdateResult(gomock.Any(), gomock.Any(), manager.config.ResourceGroup).Return(nil, allocationFailed).AnyTimes()
+	manager.azClient.virtualMachineScaleSetsClient = mockVMSSClient
+	assert.NoError(t, manager.forceRefresh())
+
+	reserved := newTestScaleSet(manager, "reserved-asg")
+	ondemand := newTestScaleSet(manager, "ondemand-asg")
+	assert.True(t, manager.RegisterNodeGroup(reserved))
+	assert.True(t, manager.RegisterNodeGroup(ondemand))
+
+	// The failed scale-up is reported, backing the scale set off, and the fallback scale set is scaled up instead.
+	reserved.updateVMSSCapacity(nil, 2, true)
+	assert.Len(t, reserved.instancesWithFailedScaleUps(), 2)
+	targetSize, err := ondemand.TargetSize()
+	assert.NoError(t, err)
+	assert.Equal(t, 4, targetSize)
+
+	// The fallback scale-up failing too is reported without falling back to the first scale set.
+	assert.Eventually(t, func() bool {
+		ondemand.instanceMutex.Lock()
+		defer ondemand.instanceMutex.Unlock()
+		return len(ondemand.failedScaleUps) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestNextFaultDomain(t *testing.T) {
+	vmInFaultDomain := func(faultDomain int32) compute.VirtualMachineScaleSetVM {
+		return compute.VirtualMachineScaleSetVM{
+			VirtualMachineScaleSetVMProperties: &compute.VirtualMachineScaleSetVMProperties{
+				InstanceView: &compute.VirtualMachineScaleSetVMInstanceView{
+					PlatformFaultDomain: to.Int32Ptr(faultDomain),
+				},
+			},
+		}
+	}
+	vms := []compute.VirtualMachineScaleSetVM{
+		vmInFaultDomain(0),
+		vmInFaultDomain(1),
+		vmInFaultDomain(0),
+		vmInFaultDomain(2),
+	}
+
+	scaleSet := newTestScaleSet(newTestAzureManager(t), "test-asg")
+	scaleSet.faultDomainCounts = countScaleSetFaultDomains(vms)
+
+	template := compute.VirtualMachineScaleSet{
+		VirtualMachineScaleSetProperties: &compute.VirtualMachineScaleSetProperties{},
+	}
+	assert.Equal(t, int32(3), scaleSet.nextFaultDomain(template))
+
+	template.PlatformFaultDomainCount = to.Int32Ptr(3)
+	assert.Equal(t, int32(1), scaleSet.nextFaultDomain(template))
+}
+
+func TestNextZone(t *testing.T) {
+	vmInZone := func(zone string) compute.VirtualMachineScaleSetVM {
+		return compute.VirtualMachineScaleSetVM{Zones: &[]string{zone}}
+	}
+
+	scaleSet := newTestScaleSet(newTestAzureManager(t), "test-asg")
+	template := compute.VirtualMachineScaleSet{}
+	_, found := scaleSet.nextZone(template)
+	assert.False(t, found)
+
+	// Templates of an empty scale set rotate between all its zones.
+	template.Zones = &[]string{"1", "2", "3"}
+	var zones []string
+	for i := 0; i < 4; i++ {
+		zone, found := scaleSet.nextZone(template)
+		assert.True(t, found)
+		zones = append(zones, zone)
+	}
+	assert.Equal(t, []string{"1", "2", "3", "1"}, zones)
+
+	scaleSet.zoneCounts = countInstanceZones([]compute.VirtualMachineScaleSetVM{vmInZone("1"), vmInZone("2"), vmInZone("1")})
+	zone, found := scaleSet.nextZone(template)
+	assert.True(t, found)
+	assert.Equal(t, "3", zone)
+	zone, _ = scaleSet.nextZone(template)
+	assert.Equal(t, "3", zone)
+
+	// Ties between the least populated zones rotate too.
+	scaleSet.zoneCounts = countInstanceZones([]compute.VirtualMachineScaleSetVM{vmInZone("1")})
+	zones = nil
+	for i := 0; i < 2; i++ {
+		zone, _ := scaleSet.nextZone(template)
+		zones = append(zones, zone)
+	}
+	assert.ElementsMatch(t, []string{"2", "3"}, zones)
+}
+
+func TestRefreshAfterScaleFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager := newTestAzureManager(t)
+	scaleSets := append(newTestVMSSList(3, "test-asg", "eastus", compute.Uniform), newTestVMSSList(2, "other-asg", "eastus", compute.Uniform)...)
+	mockVMSSClient := mockvmssclient.NewMockInterface(ctrl)
+	mockVMSSClient.EXPECT().List(gomock.Any(), manager.config.ResourceGroup).Return(scaleSets, nil).Times(1)
+	manager.azClient.virtualMachineScaleSetsClient = mockVMSSClient
+	mockVMSSVMClient := mockvmssvmclient.NewMockInterface(ctrl)
+	manager.azClient.virtualMachineScaleSetVMsClient = mockVMSSVMClient
+	assert.NoError(t, manager.forceRefresh())
+
+	scaleSet := newTestScaleSet(manager, "test-asg")
+	failure := fmt.Errorf("Code=\"Conflict\" Message=\"The request failed due to conflict with a concurrent request\"")
+
+	// Disabled, the caller falls back to its own invalidation.
+	assert.False(t, scaleSet.refreshAfterScaleFailure("VirtualMachineScaleSets.DeleteInstances", failure))
+
+	// Only the failed scale set is fetched again, and its instances are listed by the next Nodes call.
+	manager.config.EnableRefreshOnScaleFailure = true
+	mockVMSSClient.EXPECT().Get(gomock.Any(), manager.config.ResourceGroup, "test-asg").Return(newTestVMSSList(2, "test-asg", "eastus", compute.Uniform)[0], nil)
+	assert.True(t, scaleSet.refreshAfterScaleFailure("VirtualMachineScaleSets.DeleteInstances", failure))
+
+	size, err := scaleSet.GetScaleSetSize()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), size)
+
+	mockVMSSVMClient.EXPECT().List(gomock.Any(), manager.config.ResourceGroup, "test-asg", gomock.Any()).Return(newTestVMSSVMList(2), nil)
+	instances, err := scaleSet.Nodes()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(instances))
+	other := manager.azureCache.getScaleSets()["other-asg"]
+	assert.Equal(t, int64(2), *other.Sku.Capacity)
+}
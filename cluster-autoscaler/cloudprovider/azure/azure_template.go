@@ -20,22 +20,57 @@ import (
 	"fmt"
 	"math/rand"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2022-08-01/compute"
+	"github.com/Azure/azure-sdk-for-go/services/containerservice/mgmt/2021-10-01/containerservice"
+	"github.com/Azure/go-autorest/autorest/to"
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/gpu"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
 	cloudvolume "k8s.io/cloud-provider/volume"
 	"k8s.io/klog/v2"
 )
 
 const (
 	azureDiskTopologyKey string = "topology.disk.csi.azure.com/zone"
+
+	aksAgentPoolLegacyLabel  = "agentpool"
+	aksAgentPoolLabel        = "kubernetes.azure.com/agentpool"
+	aksModeLabel             = "kubernetes.azure.com/mode"
+	aksNodeImageVersionLabel = "kubernetes.azure.com/node-image-version"
+	aksDefaultMaxPods        = 110
+
+	// spotPriorityLabel marks the nodes of Spot scale sets, as AKS does.
+	spotPriorityLabel = "kubernetes.azure.com/scalesetpriority"
+	spotPriorityValue = "spot"
+
+	// defaultScaleSetFaultDomainCount is the number of fault domains of scale sets outside of availability
+	// zones that don't set it.
+	defaultScaleSetFaultDomainCount = 5
+
+	// proximityPlacementGroupLabel exposes the name of the proximity placement group the VMs are placed in, for
+	// latency-sensitive workloads to select on, and to keep from balancing across groups in different ones.
+	proximityPlacementGroupLabel = "kubernetes.azure.com/proximity-placement-group"
+
+	// ephemeralOSDiskLabel marks the nodes whose OS disk is an ephemeral OS disk, on the local storage of the VM.
+	ephemeralOSDiskLabel = "kubernetes.azure.com/ephemeral-os-disk"
+	// securityTypeLabel exposes the security type of the VMs, e.g. TrustedLaunch.
+	securityTypeLabel = "kubernetes.azure.com/security-type"
+
+	// skuCachedDiskBytesCapability and skuMaxResourceVolumeMBCapability are the SKU capabilities holding the
+	// sizes of the cache and resource disks.
+	skuCachedDiskBytesCapability     = "CachedDiskBytes"
+	skuMaxResourceVolumeMBCapability = "MaxResourceVolumeMB"
+	// trustedLaunchGuestStateGb is the local disk space taken by the VM guest state of Trusted Launch VMs with
+	// an ephemeral OS disk, which isn't available to the OS disk.
+	trustedLaunchGuestStateGb = 1
 )
 
 func buildInstanceOS(template compute.VirtualMachineScaleSet) string {
@@ -69,10 +104,33 @@ func buildGenericLabels(template compute.VirtualMachineScaleSet, nodeName string
 		result[azureDiskTopologyKey] = ""
 	}
 
+	if template.VirtualMachineScaleSetProperties != nil && template.VirtualMachineProfile != nil && template.VirtualMachineProfile.Priority == compute.Spot {
+		result[spotPriorityLabel] = spotPriorityValue
+	}
+
+	if ppg := buildProximityPlacementGroupName(template); ppg != "" {
+		result[proximityPlacementGroupLabel] = ppg
+	}
+
 	result[apiv1.LabelHostname] = nodeName
 	return result
 }
 
+// buildProximityPlacementGroupName returns the lowercased name of the proximity placement group of the template's
+// VMs, or an empty string if they aren't placed in one.
+func buildProximityPlacementGroupName(template compute.VirtualMachineScaleSet) string {
+	if template.VirtualMachineScaleSetProperties == nil || template.ProximityPlacementGroup == nil ||
+		template.ProximityPlacementGroup.ID == nil {
+		return ""
+	}
+	name, err := getLastSegment(*template.ProximityPlacementGroup.ID)
+	if err != nil {
+		klog.Warningf("ignoring invalid proximity placement group %q: %v", *template.ProximityPlacementGroup.ID, err)
+		return ""
+	}
+	return strings.ToLower(name)
+}
+
 func buildNodeFromTemplate(scaleSetName string, template compute.VirtualMachineScaleSet, manager *AzureManager) (*apiv1.Node, error) {
 	node := apiv1.Node{}
 	nodeName := fmt.Sprintf("%s-asg-%d", scaleSetName, rand.Int63())
@@ -87,36 +145,11 @@ func buildNodeFromTemplate(scaleSetName string, template compute.VirtualMachineS
 		Capacity: apiv1.ResourceList{},
 	}
 
-	var vcpu, gpuCount, memoryMb int64
-
-	// Fetching SKU information from SKU API if enableDynamicInstanceList is true.
-	var dynamicErr error
-	if manager.config.EnableDynamicInstanceList {
-		var vmssTypeDynamic InstanceType
-		klog.V(1).Infof("Fetching instance information for SKU: %s from SKU API", *template.Sku.Name)
-		vmssTypeDynamic, dynamicErr = GetVMSSTypeDynamically(template, manager.azureCache)
-		if dynamicErr == nil {
-			vcpu = vmssTypeDynamic.VCPU
-			gpuCount = vmssTypeDynamic.GPU
-			memoryMb = vmssTypeDynamic.MemoryMb
-		} else {
-			klog.Errorf("Dynamically fetching of instance information from SKU api failed with error: %v", dynamicErr)
-		}
-	}
-	if !manager.config.EnableDynamicInstanceList || dynamicErr != nil {
-		klog.V(1).Infof("Falling back to static SKU list for SKU: %s", *template.Sku.Name)
-		// fall-back on static list of vmss if dynamic workflow fails.
-		vmssTypeStatic, staticErr := GetVMSSTypeStatically(template)
-		if staticErr == nil {
-			vcpu = vmssTypeStatic.VCPU
-			gpuCount = vmssTypeStatic.GPU
-			memoryMb = vmssTypeStatic.MemoryMb
-		} else {
-			// return error if neither of the workflows results with vmss data.
-			klog.V(1).Infof("Instance type %q not supported, err: %v", *template.Sku.Name, staticErr)
-			return nil, staticErr
-		}
+	instanceType, err := getInstanceTypeForTemplate(template, manager)
+	if err != nil {
+		return nil, err
 	}
+	vcpu, gpuCount, memoryMb := instanceType.VCPU, instanceType.GPU, instanceType.MemoryMb
 
 	node.Status.Capacity[apiv1.ResourcePods] = *resource.NewQuantity(110, resource.DecimalSI)
 	node.Status.Capacity[apiv1.ResourceCPU] = *resource.NewQuantity(vcpu, resource.DecimalSI)
@@ -128,11 +161,25 @@ func buildNodeFromTemplate(scaleSetName string, template compute.VirtualMachineS
 
 	node.Status.Capacity[apiv1.ResourceMemory] = *resource.NewQuantity(memoryMb*1024*1024, resource.DecimalSI)
 
+	if osDiskGb := buildOSDiskSizeGb(template, instanceType); osDiskGb > 0 {
+		node.Status.Capacity[apiv1.ResourceEphemeralStorage] = *resource.NewQuantity(osDiskGb*1024*1024*1024, resource.BinarySI)
+	}
+
 	resourcesFromTags := extractAllocatableResourcesFromScaleSet(template.Tags)
 	for resourceName, val := range resourcesFromTags {
 		node.Status.Capacity[apiv1.ResourceName(resourceName)] = *val
 	}
 
+	// MIG profiles the GPUs are sliced into
+	if migProfiles, found := template.Tags[nodeMigProfilesTag]; found && migProfiles != nil {
+		profiles, err := gpu.ParseMigProfiles(*migProfiles)
+		if err != nil {
+			klog.Warningf("ignoring invalid %s tag of scale set %s: %v", nodeMigProfilesTag, scaleSetName, err)
+		} else {
+			gpu.ApplyMigProfiles(node.Status.Capacity, profiles)
+		}
+	}
+
 	// TODO: set real allocatable.
 	node.Status.Allocatable = node.Status.Capacity
 
@@ -150,16 +197,251 @@ func buildNodeFromTemplate(scaleSetName string, template compute.VirtualMachineS
 
 	// GenericLabels
 	node.Labels = cloudprovider.JoinStringMaps(node.Labels, buildGenericLabels(template, nodeName))
+	node.Labels = cloudprovider.JoinStringMaps(node.Labels, buildStorageAndSecurityLabels(template))
+	// GPU type, for the node group to be recognized as a GPU one before any node exists
+	if gpuCount > 0 && !isNPSeries(*template.Sku.Name) {
+		node.Labels[GPULabel] = getGpuTypeForSku(*template.Sku.Name)
+	}
 	// Labels from the Scale Set's Tags
 	node.Labels = cloudprovider.JoinStringMaps(node.Labels, extractLabelsFromScaleSet(template.Tags))
 
 	// Taints from the Scale Set's Tags
 	node.Spec.Taints = extractTaintsFromScaleSet(template.Tags)
 
+	// Images pre-baked in the machine image, from the Scale Set's Tags
+	node.Status.Images = extractImagesFromScaleSet(template.Tags)
+
 	node.Status.Conditions = cloudprovider.BuildReadyConditions()
 	return &node, nil
 }
 
+// buildOSDiskSizeGb returns the size of the OS disk of the template's VMs, which backs the ephemeral-storage of the
+// nodes, or 0 if it is unknown. Ephemeral OS disks without an explicit size take the whole local disk they are placed
+// on, less the VM guest state of Trusted Launch VMs.
+func buildOSDiskSizeGb(template compute.VirtualMachineScaleSet, instanceType InstanceType) int64 {
+	if template.VirtualMachineScaleSetProperties == nil || template.VirtualMachineProfile == nil ||
+		template.VirtualMachineProfile.StorageProfile == nil || template.VirtualMachineProfile.StorageProfile.OsDisk == nil {
+		return 0
+	}
+	osDisk := template.VirtualMachineProfile.StorageProfile.OsDisk
+	if osDisk.DiskSizeGB != nil && *osDisk.DiskSizeGB > 0 {
+		return int64(*osDisk.DiskSizeGB)
+	}
+	if !isEphemeralOSDisk(template) {
+		return 0
+	}
+
+	localDiskGb := instanceType.CacheDiskGb
+	if osDisk.DiffDiskSettings.Placement == compute.DiffDiskPlacementResourceDisk {
+		localDiskGb = instanceType.ResourceDiskGb
+	}
+	if localDiskGb > 0 && isTrustedLaunch(template) {
+		localDiskGb -= trustedLaunchGuestStateGb
+	}
+	if localDiskGb < 0 {
+		return 0
+	}
+	return localDiskGb
+}
+
+// buildStorageAndSecurityLabels returns the labels describing the OS disk type and the security type of the
+// template's VMs.
+func buildStorageAndSecurityLabels(template compute.VirtualMachineScaleSet) map[string]string {
+	result := make(map[string]string)
+	if isEphemeralOSDisk(template) {
+		result[ephemeralOSDiskLabel] = "true"
+	}
+	if template.VirtualMachineScaleSetProperties != nil && template.VirtualMachineProfile != nil &&
+		template.VirtualMachineProfile.SecurityProfile != nil && template.VirtualMachineProfile.SecurityProfile.SecurityType != "" {
+		result[securityTypeLabel] = string(template.VirtualMachineProfile.SecurityProfile.SecurityType)
+	}
+	return result
+}
+
+// isEphemeralOSDisk returns true if the template's VMs have an ephemeral OS disk.
+func isEphemeralOSDisk(template compute.VirtualMachineScaleSet) bool {
+	if template.VirtualMachineScaleSetProperties == nil || template.VirtualMachineProfile == nil ||
+		template.VirtualMachineProfile.StorageProfile == nil || template.VirtualMachineProfile.StorageProfile.OsDisk == nil {
+		return false
+	}
+	diffDiskSettings := template.VirtualMachineProfile.StorageProfile.OsDisk.DiffDiskSettings
+	return diffDiskSettings != nil && diffDiskSettings.Option == compute.DiffDiskOptionsLocal
+}
+
+// isTrustedLaunch returns true if the template's VMs use Trusted Launch.
+func isTrustedLaunch(template compute.VirtualMachineScaleSet) bool {
+	return template.VirtualMachineScaleSetProperties != nil && template.VirtualMachineProfile != nil &&
+		template.VirtualMachineProfile.SecurityProfile != nil &&
+		template.VirtualMachineProfile.SecurityProfile.SecurityType == compute.SecurityTypesTrustedLaunch
+}
+
+// buildNodeFromAKSAgentPool builds a node template from the profile AKS keeps for an agent pool,
+// rather than from the scale set backing it, so it matches the nodes AKS creates for the pool.
+func buildNodeFromAKSAgentPool(poolName string, pool containerservice.ManagedClusterAgentPoolProfile, manager *AzureManager) (*apiv1.Node, error) {
+	if pool.VMSize == nil {
+		return nil, fmt.Errorf("agent pool %s has no VM size", poolName)
+	}
+
+	// agent pools are backed by scale sets, describe the pool as one to share the scale set logic.
+	template := compute.VirtualMachineScaleSet{
+		Sku:                              &compute.Sku{Name: pool.VMSize},
+		Location:                         to.StringPtr(manager.config.Location),
+		Zones:                            pool.AvailabilityZones,
+		VirtualMachineScaleSetProperties: &compute.VirtualMachineScaleSetProperties{},
+	}
+	if pool.OsType == containerservice.OSTypeWindows {
+		template.VirtualMachineProfile = &compute.VirtualMachineScaleSetVMProfile{
+			OsProfile: &compute.VirtualMachineScaleSetOSProfile{
+				WindowsConfiguration: &compute.WindowsConfiguration{},
+			},
+		}
+	}
+	if pool.ScaleSetPriority == containerservice.ScaleSetPrioritySpot {
+		if template.VirtualMachineProfile == nil {
+			template.VirtualMachineProfile = &compute.VirtualMachineScaleSetVMProfile{}
+		}
+		template.VirtualMachineProfile.Priority = compute.Spot
+	}
+	if pool.ProximityPlacementGroupID != nil && *pool.ProximityPlacementGroupID != "" {
+		template.ProximityPlacementGroup = &compute.SubResource{ID: pool.ProximityPlacementGroupID}
+	}
+	if pool.OsDiskType == containerservice.OSDiskTypeEphemeral || pool.OsDiskSizeGB != nil {
+		if template.VirtualMachineProfile == nil {
+			template.VirtualMachineProfile = &compute.VirtualMachineScaleSetVMProfile{}
+		}
+		osDisk := &compute.VirtualMachineScaleSetOSDisk{DiskSizeGB: pool.OsDiskSizeGB}
+		if pool.OsDiskType == containerservice.OSDiskTypeEphemeral {
+			osDisk.DiffDiskSettings = &compute.DiffDiskSettings{Option: compute.DiffDiskOptionsLocal}
+		}
+		template.VirtualMachineProfile.StorageProfile = &compute.VirtualMachineScaleSetStorageProfile{OsDisk: osDisk}
+	}
+
+	node := apiv1.Node{}
+	nodeName := fmt.Sprintf("%s-%d", poolName, rand.Int63())
+
+	node.ObjectMeta = metav1.ObjectMeta{
+		Name:     nodeName,
+		SelfLink: fmt.Sprintf("/api/v1/nodes/%s", nodeName),
+		Labels:   map[string]string{},
+	}
+
+	node.Status = apiv1.NodeStatus{
+		Capacity: apiv1.ResourceList{},
+	}
+
+	instanceType, err := getInstanceTypeForTemplate(template, manager)
+	if err != nil {
+		return nil, err
+	}
+
+	maxPods := int64(aksDefaultMaxPods)
+	if pool.MaxPods != nil {
+		maxPods = int64(*pool.MaxPods)
+	}
+	node.Status.Capacity[apiv1.ResourcePods] = *resource.NewQuantity(maxPods, resource.DecimalSI)
+	node.Status.Capacity[apiv1.ResourceCPU] = *resource.NewQuantity(instanceType.VCPU, resource.DecimalSI)
+	if !isNPSeries(*pool.VMSize) {
+		node.Status.Capacity[gpu.ResourceNvidiaGPU] = *resource.NewQuantity(instanceType.GPU, resource.DecimalSI)
+	}
+	node.Status.Capacity[apiv1.ResourceMemory] = *resource.NewQuantity(instanceType.MemoryMb*1024*1024, resource.DecimalSI)
+	if osDiskGb := buildOSDiskSizeGb(template, instanceType); osDiskGb > 0 {
+		node.Status.Capacity[apiv1.ResourceEphemeralStorage] = *resource.NewQuantity(osDiskGb*1024*1024*1024, resource.BinarySI)
+	}
+
+	// TODO: set real allocatable.
+	node.Status.Allocatable = node.Status.Capacity
+
+	node.Labels = cloudprovider.JoinStringMaps(node.Labels, buildGenericLabels(template, nodeName))
+	node.Labels = cloudprovider.JoinStringMaps(node.Labels, buildStorageAndSecurityLabels(template))
+	node.Labels[aksAgentPoolLegacyLabel] = poolName
+	node.Labels[aksAgentPoolLabel] = poolName
+	if instanceType.GPU > 0 && !isNPSeries(*pool.VMSize) {
+		node.Labels[GPULabel] = getGpuTypeForSku(*pool.VMSize)
+	}
+	if pool.Mode != "" {
+		node.Labels[aksModeLabel] = strings.ToLower(string(pool.Mode))
+	}
+	if pool.NodeImageVersion != nil {
+		node.Labels[aksNodeImageVersionLabel] = *pool.NodeImageVersion
+	}
+	for k, v := range pool.NodeLabels {
+		if v != nil {
+			node.Labels[k] = *v
+		} else {
+			node.Labels[k] = ""
+		}
+	}
+
+	node.Spec.Taints = extractTaintsFromAKSAgentPool(poolName, pool.NodeTaints)
+
+	// checked against the control plane version before scaling the pool up
+	if pool.OrchestratorVersion != nil && *pool.OrchestratorVersion != "" {
+		node.Status.NodeInfo.KubeletVersion = "v" + strings.TrimPrefix(*pool.OrchestratorVersion, "v")
+	}
+
+	node.Status.Conditions = cloudprovider.BuildReadyConditions()
+	return &node, nil
+}
+
+// extractTaintsFromAKSAgentPool parses the agent pool node taints, which have the
+// key=value:effect format used by kubectl.
+func extractTaintsFromAKSAgentPool(poolName string, nodeTaints *[]string) []apiv1.Taint {
+	taints := make([]apiv1.Taint, 0)
+	if nodeTaints == nil {
+		return taints
+	}
+
+	for _, nodeTaint := range *nodeTaints {
+		keyValue, effect, found := strings.Cut(nodeTaint, ":")
+		if !found || effect == "" {
+			klog.Warningf("ignoring invalid taint %q of agent pool %s", nodeTaint, poolName)
+			continue
+		}
+		key, value, _ := strings.Cut(keyValue, "=")
+		taints = append(taints, apiv1.Taint{
+			Key:    key,
+			Value:  value,
+			Effect: apiv1.TaintEffect(effect),
+		})
+	}
+
+	return taints
+}
+
+// getInstanceTypeForTemplate returns the capacity of the template's VM size. When the dynamic instance list
+// is enabled the SKU API is preferred and the static list is only a fallback for when the API is unavailable.
+// Otherwise the static list is used first, and the SKU API is only consulted for sizes it doesn't know about yet.
+func getInstanceTypeForTemplate(template compute.VirtualMachineScaleSet, manager *AzureManager) (InstanceType, error) {
+	if manager.config.EnableDynamicInstanceList {
+		klogx.ProviderAzure.V(1).Infof("Fetching instance information for SKU: %s from SKU API", *template.Sku.Name)
+		vmssTypeDynamic, dynamicErr := GetVMSSTypeDynamically(template, manager.azureCache)
+		if dynamicErr == nil {
+			return vmssTypeDynamic, nil
+		}
+		klog.Errorf("Dynamically fetching of instance information from SKU api failed with error: %v", dynamicErr)
+		klogx.ProviderAzure.V(1).Infof("Falling back to static SKU list for SKU: %s", *template.Sku.Name)
+	}
+
+	vmssTypeStatic, staticErr := GetVMSSTypeStatically(template)
+	if staticErr == nil {
+		return *vmssTypeStatic, nil
+	}
+
+	// the static list lags behind new VM sizes, ask the SKU API about the ones it misses.
+	if !manager.config.EnableDynamicInstanceList && manager.azureCache != nil {
+		klogx.ProviderAzure.V(1).Infof("SKU %s missing from static SKU list, fetching instance information from SKU API", *template.Sku.Name)
+		vmssTypeDynamic, dynamicErr := GetVMSSTypeDynamically(template, manager.azureCache)
+		if dynamicErr == nil {
+			return vmssTypeDynamic, nil
+		}
+		klogx.ProviderAzure.V(1).Infof("Fetching instance information for SKU: %s from SKU API failed: %v", *template.Sku.Name, dynamicErr)
+	}
+
+	// return error if neither of the workflows results with vmss data.
+	klogx.ProviderAzure.V(1).Infof("Instance type %q not supported, err: %v", *template.Sku.Name, staticErr)
+	return InstanceType{}, staticErr
+}
+
 func extractLabelsFromScaleSet(tags map[string]*string) map[string]string {
 	result := make(map[string]string)
 
@@ -204,6 +486,24 @@ func extractTaintsFromScaleSet(tags map[string]*string) []apiv1.Taint {
 	return taints
 }
 
+// extractImagesFromScaleSet returns the images declared as pre-baked in the machine image of a scale set,
+// with one tag per image digest.
+func extractImagesFromScaleSet(tags map[string]*string) []apiv1.ContainerImage {
+	var digests []string
+	for tagName, tagValue := range tags {
+		if strings.HasPrefix(tagName, nodeImageTagName) && tagValue != nil && *tagValue != "" {
+			digests = append(digests, *tagValue)
+		}
+	}
+	sort.Strings(digests)
+
+	var images []apiv1.ContainerImage
+	for _, digest := range digests {
+		images = append(images, apiv1.ContainerImage{Names: []string{digest}})
+	}
+	return images
+}
+
 func extractAutoscalingOptionsFromScaleSetTags(tags map[string]*string) map[string]string {
 	options := make(map[string]string)
 	for tagName, tagValue := range tags {
@@ -18,11 +18,14 @@ package azure
 
 import (
 	"fmt"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2022-08-01/compute"
 	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/stretchr/testify/assert"
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
-	"testing"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/gpu"
 )
 
 func TestExtractLabelsFromScaleSet(t *testing.T) {
@@ -126,3 +129,234 @@ func makeTaintSet(taints []apiv1.Taint) map[apiv1.Taint]bool {
 	}
 	return set
 }
+
+func TestBuildNodeFromTemplateWithMigProfiles(t *testing.T) {
+	tags := map[string]*string{
+		nodeMigProfilesTag: to.StringPtr("1g.5gb x 7"),
+	}
+	template := compute.VirtualMachineScaleSet{
+		Name:     to.StringPtr("gpu-vmss"),
+		Sku:      &compute.Sku{Name: to.StringPtr("Standard_NC24ads_A100_v4")},
+		Location: to.StringPtr("westus2"),
+		Tags:     tags,
+	}
+	manager := &AzureManager{config: &Config{}}
+
+	node, err := buildNodeFromTemplate("gpu-vmss", template, manager)
+	assert.NoError(t, err)
+	migQuantity := node.Status.Allocatable["nvidia.com/mig-1g.5gb"]
+	gpuQuantity := node.Status.Allocatable[gpu.ResourceNvidiaGPU]
+	assert.Equal(t, int64(7), migQuantity.Value())
+	assert.Equal(t, int64(0), gpuQuantity.Value())
+}
+
+func TestBuildNodeFromTemplateWithGPU(t *testing.T) {
+	manager := &AzureManager{config: &Config{}}
+	for _, tc := range []struct {
+		desc          string
+		sku           string
+		tags          map[string]*string
+		expectedGPUs  int64
+		expectedLabel string
+	}{
+		{
+			desc:          "gpu size",
+			sku:           "Standard_NC24ads_A100_v4",
+			expectedGPUs:  1,
+			expectedLabel: "nvidia-tesla-a100",
+		},
+		{
+			desc:          "gpu type overridden by tag",
+			sku:           "Standard_NC24ads_A100_v4",
+			tags:          map[string]*string{nodeLabelTagName + GPULabel: to.StringPtr("nvidia")},
+			expectedGPUs:  1,
+			expectedLabel: "nvidia",
+		},
+		{
+			desc: "fpga size",
+			sku:  "Standard_NP10s",
+		},
+		{
+			desc: "cpu size",
+			sku:  "Standard_D2s_v3",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			template := compute.VirtualMachineScaleSet{
+				Name:     to.StringPtr("vmss"),
+				Sku:      &compute.Sku{Name: to.StringPtr(tc.sku)},
+				Location: to.StringPtr("westus2"),
+				Tags:     tc.tags,
+			}
+			node, err := buildNodeFromTemplate("vmss", template, manager)
+			assert.NoError(t, err)
+			gpuQuantity := node.Status.Capacity[gpu.ResourceNvidiaGPU]
+			assert.Equal(t, tc.expectedGPUs, gpuQuantity.Value())
+			gpuLabel, found := node.Labels[GPULabel]
+			assert.Equal(t, tc.expectedLabel != "", found)
+			assert.Equal(t, tc.expectedLabel, gpuLabel)
+		})
+	}
+}
+
+func newTestOSDiskTemplate(diskSizeGb *int32, diffDiskSettings *compute.DiffDiskSettings, securityType compute.SecurityTypes) compute.VirtualMachineScaleSet {
+	profile := &compute.VirtualMachineScaleSetVMProfile{
+		StorageProfile: &compute.VirtualMachineScaleSetStorageProfile{
+			OsDisk: &compute.VirtualMachineScaleSetOSDisk{DiskSizeGB: diskSizeGb, DiffDiskSettings: diffDiskSettings},
+		},
+	}
+	if securityType != "" {
+		profile.SecurityProfile = &compute.SecurityProfile{SecurityType: securityType}
+	}
+	return compute.VirtualMachineScaleSet{
+		Name:                             to.StringPtr("vmss"),
+		Sku:                              &compute.Sku{Name: to.StringPtr("Standard_D4s_v3")},
+		Location:                         to.StringPtr("westus2"),
+		VirtualMachineScaleSetProperties: &compute.VirtualMachineScaleSetProperties{VirtualMachineProfile: profile},
+	}
+}
+
+func TestBuildOSDiskSizeGb(t *testing.T) {
+	instanceType := InstanceType{CacheDiskGb: 100, ResourceDiskGb: 32}
+	ephemeral := &compute.DiffDiskSettings{Option: compute.DiffDiskOptionsLocal}
+	ephemeralOnResourceDisk := &compute.DiffDiskSettings{Option: compute.DiffDiskOptionsLocal, Placement: compute.DiffDiskPlacementResourceDisk}
+
+	for _, tc := range []struct {
+		desc     string
+		template compute.VirtualMachineScaleSet
+		expected int64
+	}{
+		{desc: "no storage profile", template: compute.VirtualMachineScaleSet{}, expected: 0},
+		{desc: "managed disk with size", template: newTestOSDiskTemplate(to.Int32Ptr(128), nil, ""), expected: 128},
+		{desc: "managed disk without size", template: newTestOSDiskTemplate(nil, nil, ""), expected: 0},
+		{desc: "ephemeral disk with size", template: newTestOSDiskTemplate(to.Int32Ptr(64), ephemeral, compute.SecurityTypesTrustedLaunch), expected: 64},
+		{desc: "ephemeral disk on cache disk", template: newTestOSDiskTemplate(nil, ephemeral, ""), expected: 100},
+		{desc: "ephemeral disk on resource disk", template: newTestOSDiskTemplate(nil, ephemeralOnResourceDisk, ""), expected: 32},
+		{desc: "trusted launch ephemeral disk", template: newTestOSDiskTemplate(nil, ephemeral, compute.SecurityTypesTrustedLaunch), expected: 99},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.expected, buildOSDiskSizeGb(tc.template, instanceType))
+		})
+	}
+
+	// local disk sizes are unknown to the static instance types.
+	assert.Equal(t, int64(0), buildOSDiskSizeGb(newTestOSDiskTemplate(nil, ephemeral, compute.SecurityTypesTrustedLaunch), InstanceType{}))
+}
+
+func TestBuildNodeFromTemplateWithEphemeralOSDisk(t *testing.T) {
+	manager := &AzureManager{config: &Config{}}
+	template := newTestOSDiskTemplate(to.Int32Ptr(64), &compute.DiffDiskSettings{Option: compute.DiffDiskOptionsLocal}, compute.SecurityTypesTrustedLaunch)
+
+	node, err := buildNodeFromTemplate("vmss", template, manager)
+	assert.NoError(t, err)
+	ephemeralStorage := node.Status.Capacity[apiv1.ResourceEphemeralStorage]
+	assert.Equal(t, int64(64*1024*1024*1024), ephemeralStorage.Value())
+	assert.Equal(t, "true", node.Labels[ephemeralOSDiskLabel])
+	assert.Equal(t, string(compute.SecurityTypesTrustedLaunch), node.Labels[securityTypeLabel])
+
+	// resources set by tags take precedence.
+	template.Tags = map[string]*string{nodeResourcesTagName + "ephemeral-storage": to.StringPtr("30Gi")}
+	node, err = buildNodeFromTemplate("vmss", template, manager)
+	assert.NoError(t, err)
+	ephemeralStorage = node.Status.Capacity[apiv1.ResourceEphemeralStorage]
+	assert.Equal(t, int64(30*1024*1024*1024), ephemeralStorage.Value())
+
+	template = newTestOSDiskTemplate(nil, nil, "")
+	node, err = buildNodeFromTemplate("vmss", template, manager)
+	assert.NoError(t, err)
+	_, found := node.Status.Capacity[apiv1.ResourceEphemeralStorage]
+	assert.False(t, found)
+	assert.NotContains(t, node.Labels, ephemeralOSDiskLabel)
+	assert.NotContains(t, node.Labels, securityTypeLabel)
+}
+
+func TestBuildNodeFromTemplateWithProximityPlacementGroup(t *testing.T) {
+	manager := &AzureManager{config: &Config{}}
+	template := newTestOSDiskTemplate(nil, nil, "")
+	template.ProximityPlacementGroup = &compute.SubResource{
+		ID: to.StringPtr("/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/proximityPlacementGroups/PPG-1"),
+	}
+
+	node, err := buildNodeFromTemplate("vmss", template, manager)
+	assert.NoError(t, err)
+	assert.Equal(t, "ppg-1", node.Labels[proximityPlacementGroupLabel])
+
+	template.ProximityPlacementGroup = nil
+	node, err = buildNodeFromTemplate("vmss", template, manager)
+	assert.NoError(t, err)
+	assert.NotContains(t, node.Labels, proximityPlacementGroupLabel)
+}
+
+func TestGetGpuTypeForSku(t *testing.T) {
+	for sku, expected := range map[string]string{
+		"Standard_NC6":              "nvidia-tesla-k80",
+		"Standard_NC24r":            "nvidia-tesla-k80",
+		"Standard_NC6s_v2":          "nvidia-tesla-p100",
+		"Standard_NC24rs_v3":        "nvidia-tesla-v100",
+		"Standard_NC6s_v3_Promo":    "nvidia-tesla-v100",
+		"Standard_NC4as_T4_v3":      "nvidia-tesla-t4",
+		"Standard_NC24ads_A100_v4":  "nvidia-tesla-a100",
+		"Standard_NC40ads_H100_v5":  "nvidia-h100",
+		"Standard_ND6s":             "nvidia-tesla-p40",
+		"Standard_ND40rs_v2":        "nvidia-tesla-v100",
+		"Standard_ND96asr_v4":       "nvidia-tesla-a100",
+		"Standard_ND96amsr_A100_v4": "nvidia-tesla-a100",
+		"Standard_ND96isr_H100_v5":  "nvidia-h100",
+		"Standard_NV12":             "nvidia-tesla-m60",
+		"Standard_NV12s_v3":         "nvidia-tesla-m60",
+		"Standard_NV36ads_A10_v5":   "nvidia-a10",
+		"Standard_NV4as_v4":         defaultGPUType,
+	} {
+		assert.Equal(t, expected, getGpuTypeForSku(sku), sku)
+	}
+}
+
+func TestExtractImagesFromScaleSet(t *testing.T) {
+	tags := map[string]*string{
+		nodeImageTagName + "trainer": to.StringPtr("sha256:2222222222222222222222222222222222222222222222222222222222222222"),
+		nodeImageTagName + "sidecar": to.StringPtr("sha256:1111111111111111111111111111111111111111111111111111111111111111"),
+		nodeImageTagName + "empty":   to.StringPtr(""),
+		"foo":                        to.StringPtr("bar"),
+	}
+
+	images := extractImagesFromScaleSet(tags)
+	assert.Equal(t, []apiv1.ContainerImage{
+		{Names: []string{"sha256:1111111111111111111111111111111111111111111111111111111111111111"}},
+		{Names: []string{"sha256:2222222222222222222222222222222222222222222222222222222222222222"}},
+	}, images)
+}
+
+func TestGetInstanceTypeForTemplate(t *testing.T) {
+	staticFn, dynamicFn := GetVMSSTypeStatically, GetVMSSTypeDynamically
+	defer func() {
+		GetVMSSTypeStatically, GetVMSSTypeDynamically = staticFn, dynamicFn
+	}()
+
+	template := compute.VirtualMachineScaleSet{
+		Sku:      &compute.Sku{Name: to.StringPtr("Standard_New_v9")},
+		Location: to.StringPtr("westus2"),
+	}
+	GetVMSSTypeStatically = func(template compute.VirtualMachineScaleSet) (*InstanceType, error) {
+		return nil, fmt.Errorf("instance type %q not supported", *template.Sku.Name)
+	}
+	GetVMSSTypeDynamically = func(template compute.VirtualMachineScaleSet, azCache *azureCache) (InstanceType, error) {
+		return InstanceType{VCPU: 4, MemoryMb: 16384}, nil
+	}
+
+	t.Run("sizes missing from the static list are fetched from the SKU API", func(t *testing.T) {
+		manager := &AzureManager{config: &Config{}, azureCache: &azureCache{}}
+		instanceType, err := getInstanceTypeForTemplate(template, manager)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), instanceType.VCPU)
+		assert.Equal(t, int64(16384), instanceType.MemoryMb)
+	})
+
+	t.Run("static list error is returned when the SKU API fails too", func(t *testing.T) {
+		GetVMSSTypeDynamically = func(template compute.VirtualMachineScaleSet, azCache *azureCache) (InstanceType, error) {
+			return InstanceType{}, fmt.Errorf("sku api unavailable")
+		}
+		manager := &AzureManager{config: &Config{}, azureCache: &azureCache{}}
+		_, err := getInstanceTypeForTemplate(template, manager)
+		assert.Equal(t, fmt.Errorf("instance type %q not supported", "Standard_New_v9"), err)
+	})
+}
@@ -0,0 +1,459 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2022-08-01/compute"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmssclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmssvmclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
+)
+
+const (
+	// authorizerTokenLifetime is the lifetime reported for the tokens of authorizerCredential. The bearer token
+	// policy of the track 2 clients refreshes tokens 5 minutes before they expire, so they are reused for a minute.
+	authorizerTokenLifetime = 6 * time.Minute
+	// track2PollingFrequency is how often the track 2 clients poll long-running operations, like the track 1 clients.
+	track2PollingFrequency = 5 * time.Second
+)
+
+// authorizerCredential is an azcore.TokenCredential getting its tokens from the autorest.Authorizer of the track 1
+// clients, so that the track 2 clients support the same authentication methods and follow credential rotations.
+type authorizerCredential struct {
+	authorizer autorest.Authorizer
+	endpoint   string
+}
+
+// GetToken implements azcore.TokenCredential.
+func (c *authorizerCredential) GetToken(ctx context.Context, _ policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint, nil)
+	if err != nil {
+		return azcore.AccessToken{}, err
+	}
+	req, err = autorest.Prepare(req, c.authorizer.WithAuthorization())
+	if err != nil {
+		return azcore.AccessToken{}, fmt.Errorf("failed to get a token from the authorizer: %v", err)
+	}
+	header := req.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return azcore.AccessToken{}, fmt.Errorf("the authorizer didn't set a bearer token")
+	}
+	return azcore.AccessToken{Token: strings.TrimPrefix(header, "Bearer "), ExpiresOn: time.Now().Add(authorizerTokenLifetime)}, nil
+}
+
+// newTrack2ClientOptions returns the options of the track 2 clients for the given environment.
+func newTrack2ClientOptions(env *azure.Environment) *arm.ClientOptions {
+	audience := env.TokenAudience
+	if audience == "" {
+		audience = env.ResourceManagerEndpoint
+	}
+	return &arm.ClientOptions{
+		ClientOptions: policy.ClientOptions{
+			Cloud: cloud.Configuration{
+				ActiveDirectoryAuthorityHost: env.ActiveDirectoryEndpoint,
+				Services: map[cloud.ServiceName]cloud.ServiceConfiguration{
+					cloud.ResourceManager: {
+						Endpoint: env.ResourceManagerEndpoint,
+						Audience: audience,
+					},
+				},
+			},
+		},
+		// Resource providers are registered by the cluster operator, like with the track 1 clients.
+		DisableRPRegistration: true,
+	}
+}
+
+// newTrack2ScaleSetClients returns the scale set and scale set VM clients built on the track 2 SDK.
+func newTrack2ScaleSetClients(subscriptionID string, env *azure.Environment, authorizer autorest.Authorizer) (vmssclient.Interface, vmssvmclient.Interface, error) {
+	credential := &authorizerCredential{authorizer: authorizer, endpoint: env.ResourceManagerEndpoint}
+	options := newTrack2ClientOptions(env)
+	scaleSetsClient, err := armcompute.NewVirtualMachineScaleSetsClient(subscriptionID, credential, options)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create the track 2 scale set client: %v", err)
+	}
+	scaleSetVMsClient, err := armcompute.NewVirtualMachineScaleSetVMsClient(subscriptionID, credential, options)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create the track 2 scale set vm client: %v", err)
+	}
+	operations := newTrack2Operations()
+	return &azTrack2ScaleSetsClient{client: scaleSetsClient, operations: operations},
+		&azTrack2ScaleSetVMsClient{client: scaleSetVMsClient, operations: operations}, nil
+}
+
+// track2Poller waits for a long-running operation of the track 2 clients to complete, returning its result and
+// the last response received.
+type track2Poller func(ctx context.Context) (interface{}, *http.Response, error)
+
+// track2Operations keeps the pollers of the long-running operations started by the *Async methods of the track 2
+// clients, by the future returned to the caller in their place. The futures only identify the operations, which
+// the callers wait for by passing them back to the Wait* methods.
+type track2Operations struct {
+	mutex   sync.Mutex
+	pollers map[*azure.Future]track2Poller
+}
+
+func newTrack2Operations() *track2Operations {
+	return &track2Operations{pollers: make(map[*azure.Future]track2Poller)}
+}
+
+// add returns the future identifying the operation of the poller.
+func (o *track2Operations) add(poller track2Poller) *azure.Future {
+	future := &azure.Future{}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.pollers[future] = poller
+	return future
+}
+
+// wait waits for the operation identified by the future to complete, and forgets it.
+func (o *track2Operations) wait(ctx context.Context, future *azure.Future) (interface{}, *http.Response, error) {
+	o.mutex.Lock()
+	poller, found := o.pollers[future]
+	delete(o.pollers, future)
+	o.mutex.Unlock()
+	if !found {
+		return nil, nil, fmt.Errorf("unknown operation, it was either not started by the track 2 clients or already waited for")
+	}
+	return poller(ctx)
+}
+
+// waitResponse waits for the operation identified by the future to complete, and returns its last response.
+func (o *track2Operations) waitResponse(ctx context.Context, future *azure.Future) (*http.Response, error) {
+	_, resp, err := o.wait(ctx, future)
+	return resp, err
+}
+
+// convertModel converts between the track 1 and track 2 models of a resource, through the ARM JSON representation
+// they share. The track 1 models leave their read-only properties out, so that they can be sent as parameters.
+func convertModel(from, to interface{}) error {
+	data, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, to)
+}
+
+// track2Error converts an error of the track 2 clients to the retry.Error returned by the track 1 clients.
+func track2Error(err error) *retry.Error {
+	if err == nil {
+		return nil
+	}
+	var responseError *azcore.ResponseError
+	if errors.As(err, &responseError) {
+		return retry.GetError(responseError.RawResponse, err)
+	}
+	return retry.GetError(nil, err)
+}
+
+// conversionError returns the retry.Error of a failed model conversion, which retries don't fix.
+func conversionError(err error) *retry.Error {
+	return retry.NewError(false, fmt.Errorf("failed to convert between track 1 and track 2 models: %v", err))
+}
+
+// azTrack2ScaleSetsClient implements the vmssclient.Interface of the track 1 scale set client with the track 2 SDK.
+type azTrack2ScaleSetsClient struct {
+	client     *armcompute.VirtualMachineScaleSetsClient
+	operations *track2Operations
+}
+
+// Get gets a VirtualMachineScaleSet.
+func (c *azTrack2ScaleSetsClient) Get(ctx context.Context, resourceGroupName string, VMScaleSetName string) (compute.VirtualMachineScaleSet, *retry.Error) {
+	var result compute.VirtualMachineScaleSet
+	resp, err := c.client.Get(ctx, resourceGroupName, VMScaleSetName, nil)
+	if err != nil {
+		return result, track2Error(err)
+	}
+	if err := convertModel(resp.VirtualMachineScaleSet, &result); err != nil {
+		return result, conversionError(err)
+	}
+	return result, nil
+}
+
+// List gets a list of VirtualMachineScaleSets in the resource group.
+func (c *azTrack2ScaleSetsClient) List(ctx context.Context, resourceGroupName string) ([]compute.VirtualMachineScaleSet, *retry.Error) {
+	var result []compute.VirtualMachineScaleSet
+	pager := c.client.NewListPager(resourceGroupName, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, track2Error(err)
+		}
+		var scaleSets []compute.VirtualMachineScaleSet
+		if err := convertModel(page.Value, &scaleSets); err != nil {
+			return nil, conversionError(err)
+		}
+		result = append(result, scaleSets...)
+	}
+	return result, nil
+}
+
+// CreateOrUpdate creates or updates a VirtualMachineScaleSet.
+func (c *azTrack2ScaleSetsClient) CreateOrUpdate(ctx context.Context, resourceGroupName string, VMScaleSetName string, parameters compute.VirtualMachineScaleSet) *retry.Error {
+	future, rerr := c.CreateOrUpdateAsync(ctx, resourceGroupName, VMScaleSetName, parameters)
+	if rerr != nil {
+		return rerr
+	}
+	_, err := c.operations.waitResponse(ctx, future)
+	return track2Error(err)
+}
+
+// CreateOrUpdateAsync sends the request to the ARM client and doesn't wait for the response.
+func (c *azTrack2ScaleSetsClient) CreateOrUpdateAsync(ctx context.Context, resourceGroupName string, VMScaleSetName string, parameters compute.VirtualMachineScaleSet) (*azure.Future, *retry.Error) {
+	var scaleSet armcompute.VirtualMachineScaleSet
+	if err := convertModel(parameters, &scaleSet); err != nil {
+		return nil, conversionError(err)
+	}
+	poller, err := c.client.BeginCreateOrUpdate(ctx, resourceGroupName, VMScaleSetName, scaleSet, nil)
+	if err != nil {
+		return nil, track2Error(err)
+	}
+	return c.operations.add(func(ctx context.Context) (interface{}, *http.Response, error) {
+		var resp *http.Response
+		result, err := poller.PollUntilDone(runtime.WithCaptureResponse(ctx, &resp), &runtime.PollUntilDoneOptions{Frequency: track2PollingFrequency})
+		return result, resp, err
+	}), nil
+}
+
+// WaitForAsyncOperationResult waits for the response of the request.
+func (c *azTrack2ScaleSetsClient) WaitForAsyncOperationResult(ctx context.Context, future *azure.Future, resourceGroupName, request, asyncOpName string) (*http.Response, error) {
+	return c.operations.waitResponse(ctx, future)
+}
+
+// DeleteInstances deletes the instances for a VirtualMachineScaleSet.
+func (c *azTrack2ScaleSetsClient) DeleteInstances(ctx context.Context, resourceGroupName string, vmScaleSetName string, vmInstanceIDs compute.VirtualMachineScaleSetVMInstanceRequiredIDs) *retry.Error {
+	future, rerr := c.DeleteInstancesAsync(ctx, resourceGroupName, vmScaleSetName, vmInstanceIDs, false)
+	if rerr != nil {
+		return rerr
+	}
+	_, err := c.operations.waitResponse(ctx, future)
+	return track2Error(err)
+}
+
+// DeleteInstancesAsync sends the delete request to the ARM client and doesn't wait on the future.
+func (c *azTrack2ScaleSetsClient) DeleteInstancesAsync(ctx context.Context, resourceGroupName string, vmScaleSetName string, vmInstanceIDs compute.VirtualMachineScaleSetVMInstanceRequiredIDs, forceDelete bool) (*azure.Future, *retry.Error) {
+	var instanceIDs armcompute.VirtualMachineScaleSetVMInstanceRequiredIDs
+	if err := convertModel(vmInstanceIDs, &instanceIDs); err != nil {
+		return nil, conversionError(err)
+	}
+	poller, err := c.client.BeginDeleteInstances(ctx, resourceGroupName, vmScaleSetName, instanceIDs, &armcompute.VirtualMachineScaleSetsClientBeginDeleteInstancesOptions{ForceDeletion: &forceDelete})
+	if err != nil {
+		return nil, track2Error(err)
+	}
+	return c.operations.add(func(ctx context.Context) (interface{}, *http.Response, error) {
+		var resp *http.Response
+		result, err := poller.PollUntilDone(runtime.WithCaptureResponse(ctx, &resp), &runtime.PollUntilDoneOptions{Frequency: track2PollingFrequency})
+		return result, resp, err
+	}), nil
+}
+
+// WaitForCreateOrUpdateResult waits for the response of the create or update request.
+func (c *azTrack2ScaleSetsClient) WaitForCreateOrUpdateResult(ctx context.Context, future *azure.Future, resourceGroupName string) (*http.Response, error) {
+	return c.operations.waitResponse(ctx, future)
+}
+
+// WaitForDeleteInstancesResult waits for the response of the delete instances request.
+func (c *azTrack2ScaleSetsClient) WaitForDeleteInstancesResult(ctx context.Context, future *azure.Future, resourceGroupName string) (*http.Response, error) {
+	return c.operations.waitResponse(ctx, future)
+}
+
+// DeallocateInstancesAsync sends the deallocate request to the ARM client and doesn't wait on the future.
+func (c *azTrack2ScaleSetsClient) DeallocateInstancesAsync(ctx context.Context, resourceGroupName string, vmScaleSetName string, vmInstanceIDs compute.VirtualMachineScaleSetVMInstanceRequiredIDs) (*azure.Future, *retry.Error) {
+	var instanceIDs armcompute.VirtualMachineScaleSetVMInstanceIDs
+	if err := convertModel(vmInstanceIDs, &instanceIDs); err != nil {
+		return nil, conversionError(err)
+	}
+	poller, err := c.client.BeginDeallocate(ctx, resourceGroupName, vmScaleSetName, &armcompute.VirtualMachineScaleSetsClientBeginDeallocateOptions{VMInstanceIDs: &instanceIDs})
+	if err != nil {
+		return nil, track2Error(err)
+	}
+	return c.operations.add(func(ctx context.Context) (interface{}, *http.Response, error) {
+		var resp *http.Response
+		result, err := poller.PollUntilDone(runtime.WithCaptureResponse(ctx, &resp), &runtime.PollUntilDoneOptions{Frequency: track2PollingFrequency})
+		return result, resp, err
+	}), nil
+}
+
+// WaitForDeallocateInstancesResult waits for the response of the deallocate instances request.
+func (c *azTrack2ScaleSetsClient) WaitForDeallocateInstancesResult(ctx context.Context, future *azure.Future, resourceGroupName string) (*http.Response, error) {
+	return c.operations.waitResponse(ctx, future)
+}
+
+// StartInstancesAsync starts the instances for a VirtualMachineScaleSet.
+func (c *azTrack2ScaleSetsClient) StartInstancesAsync(ctx context.Context, resourceGroupName string, vmScaleSetName string, vmInstanceIDs compute.VirtualMachineScaleSetVMInstanceRequiredIDs) (*azure.Future, *retry.Error) {
+	var instanceIDs armcompute.VirtualMachineScaleSetVMInstanceIDs
+	if err := convertModel(vmInstanceIDs, &instanceIDs); err != nil {
+		return nil, conversionError(err)
+	}
+	poller, err := c.client.BeginStart(ctx, resourceGroupName, vmScaleSetName, &armcompute.VirtualMachineScaleSetsClientBeginStartOptions{VMInstanceIDs: &instanceIDs})
+	if err != nil {
+		return nil, track2Error(err)
+	}
+	return c.operations.add(func(ctx context.Context) (interface{}, *http.Response, error) {
+		var resp *http.Response
+		result, err := poller.PollUntilDone(runtime.WithCaptureResponse(ctx, &resp), &runtime.PollUntilDoneOptions{Frequency: track2PollingFrequency})
+		return result, resp, err
+	}), nil
+}
+
+// WaitForStartInstancesResult waits for the response of the start instances request.
+func (c *azTrack2ScaleSetsClient) WaitForStartInstancesResult(ctx context.Context, future *azure.Future, resourceGroupName string) (*http.Response, error) {
+	return c.operations.waitResponse(ctx, future)
+}
+
+// azTrack2ScaleSetVMsClient implements the vmssvmclient.Interface of the track 1 scale set VM client with the
+// track 2 SDK.
+type azTrack2ScaleSetVMsClient struct {
+	client     *armcompute.VirtualMachineScaleSetVMsClient
+	operations *track2Operations
+}
+
+// Get gets a VirtualMachineScaleSetVM.
+func (c *azTrack2ScaleSetVMsClient) Get(ctx context.Context, resourceGroupName string, VMScaleSetName string, instanceID string, expand compute.InstanceViewTypes) (compute.VirtualMachineScaleSetVM, *retry.Error) {
+	var result compute.VirtualMachineScaleSetVM
+	options := &armcompute.VirtualMachineScaleSetVMsClientGetOptions{}
+	if expand != "" {
+		instanceViewTypes := armcompute.InstanceViewTypes(expand)
+		options.Expand = &instanceViewTypes
+	}
+	resp, err := c.client.Get(ctx, resourceGroupName, VMScaleSetName, instanceID, options)
+	if err != nil {
+		return result, track2Error(err)
+	}
+	if err := convertModel(resp.VirtualMachineScaleSetVM, &result); err != nil {
+		return result, conversionError(err)
+	}
+	return result, nil
+}
+
+// List gets a list of VirtualMachineScaleSetVMs in the virtualMachineScaleSet.
+func (c *azTrack2ScaleSetVMsClient) List(ctx context.Context, resourceGroupName string, virtualMachineScaleSetName string, expand string) ([]compute.VirtualMachineScaleSetVM, *retry.Error) {
+	var result []compute.VirtualMachineScaleSetVM
+	options := &armcompute.VirtualMachineScaleSetVMsClientListOptions{}
+	if expand != "" {
+		options.Expand = &expand
+	}
+	pager := c.client.NewListPager(resourceGroupName, virtualMachineScaleSetName, options)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, track2Error(err)
+		}
+		var vms []compute.VirtualMachineScaleSetVM
+		if err := convertModel(page.Value, &vms); err != nil {
+			return nil, conversionError(err)
+		}
+		result = append(result, vms...)
+	}
+	return result, nil
+}
+
+// Update updates a VirtualMachineScaleSetVM.
+func (c *azTrack2ScaleSetVMsClient) Update(ctx context.Context, resourceGroupName string, VMScaleSetName string, instanceID string, parameters compute.VirtualMachineScaleSetVM, source string) (*compute.VirtualMachineScaleSetVM, *retry.Error) {
+	future, rerr := c.UpdateAsync(ctx, resourceGroupName, VMScaleSetName, instanceID, parameters, source)
+	if rerr != nil {
+		return nil, rerr
+	}
+	return c.WaitForUpdateResult(ctx, future, resourceGroupName, source)
+}
+
+// UpdateAsync updates a VirtualMachineScaleSetVM asynchronously.
+func (c *azTrack2ScaleSetVMsClient) UpdateAsync(ctx context.Context, resourceGroupName string, VMScaleSetName string, instanceID string, parameters compute.VirtualMachineScaleSetVM, source string) (*azure.Future, *retry.Error) {
+	var vm armcompute.VirtualMachineScaleSetVM
+	if err := convertModel(parameters, &vm); err != nil {
+		return nil, conversionError(err)
+	}
+	poller, err := c.client.BeginUpdate(ctx, resourceGroupName, VMScaleSetName, instanceID, vm, nil)
+	if err != nil {
+		return nil, track2Error(err)
+	}
+	return c.operations.add(func(ctx context.Context) (interface{}, *http.Response, error) {
+		var resp *http.Response
+		result, err := poller.PollUntilDone(runtime.WithCaptureResponse(ctx, &resp), &runtime.PollUntilDoneOptions{Frequency: track2PollingFrequency})
+		return result.VirtualMachineScaleSetVM, resp, err
+	}), nil
+}
+
+// WaitForUpdateResult waits for the response of the update request.
+func (c *azTrack2ScaleSetVMsClient) WaitForUpdateResult(ctx context.Context, future *azure.Future, resourceGroupName, source string) (*compute.VirtualMachineScaleSetVM, *retry.Error) {
+	vm, _, err := c.operations.wait(ctx, future)
+	if err != nil {
+		return nil, track2Error(err)
+	}
+	result := &compute.VirtualMachineScaleSetVM{}
+	if err := convertModel(vm, result); err != nil {
+		return nil, conversionError(err)
+	}
+	return result, nil
+}
+
+// UpdateVMs updates a list of VirtualMachineScaleSetVM from map[instanceID]compute.VirtualMachineScaleSetVM,
+// batchSize instances at a time, or all at once if batchSize isn't positive.
+func (c *azTrack2ScaleSetVMsClient) UpdateVMs(ctx context.Context, resourceGroupName string, VMScaleSetName string, instances map[string]compute.VirtualMachineScaleSetVM, source string, batchSize int) *retry.Error {
+	var futures []*azure.Future
+	var errs []error
+	retriable := false
+	addError := func(rerr *retry.Error) {
+		errs = append(errs, rerr.Error())
+		retriable = retriable || rerr.Retriable
+	}
+	wait := func() {
+		for _, future := range futures {
+			if _, rerr := c.WaitForUpdateResult(ctx, future, resourceGroupName, source); rerr != nil {
+				addError(rerr)
+			}
+		}
+		futures = nil
+	}
+
+	for instanceID, vm := range instances {
+		future, rerr := c.UpdateAsync(ctx, resourceGroupName, VMScaleSetName, instanceID, vm, source)
+		if rerr != nil {
+			addError(rerr)
+			continue
+		}
+		futures = append(futures, future)
+		if batchSize > 0 && len(futures) >= batchSize {
+			wait()
+		}
+	}
+	wait()
+
+	if len(errs) > 0 {
+		return retry.NewError(retriable, utilerrors.NewAggregate(errs))
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2022-08-01/compute"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
+)
+
+const track2TestScaleSetPath = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss"
+
+// newTestTrack2ScaleSetsClient returns a track 2 scale set client sending its requests to a fake ARM server.
+func newTestTrack2ScaleSetsClient(t *testing.T, handler http.HandlerFunc) *azTrack2ScaleSetsClient {
+	server := httptest.NewTLSServer(handler)
+	t.Cleanup(server.Close)
+
+	env := &azure.Environment{ResourceManagerEndpoint: server.URL, TokenAudience: server.URL}
+	authorizer := autorest.NewBearerAuthorizer(&adal.Token{AccessToken: "token"})
+	options := newTrack2ClientOptions(env)
+	options.Transport = server.Client()
+	client, err := armcompute.NewVirtualMachineScaleSetsClient("sub", &authorizerCredential{authorizer: authorizer, endpoint: server.URL}, options)
+	assert.NoError(t, err)
+	return &azTrack2ScaleSetsClient{client: client, operations: newTrack2Operations()}
+}
+
+func TestTrack2ScaleSetsClient(t *testing.T) {
+	var requests []string
+	var capacity int64
+	client := newTestTrack2ScaleSetsClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == track2TestScaleSetPath:
+			scaleSet := armcompute.VirtualMachineScaleSet{}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&scaleSet))
+			capacity = *scaleSet.SKU.Capacity
+			fallthrough
+		case r.Method == http.MethodGet && r.URL.Path == track2TestScaleSetPath:
+			json.NewEncoder(w).Encode(armcompute.VirtualMachineScaleSet{
+				Name: to.StringPtr("vmss"),
+				SKU:  &armcompute.SKU{Name: to.StringPtr("Standard_D2s_v3"), Capacity: &capacity},
+				Properties: &armcompute.VirtualMachineScaleSetProperties{
+					ProvisioningState: to.StringPtr("Succeeded"),
+				},
+			})
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/virtualMachineScaleSets"):
+			json.NewEncoder(w).Encode(armcompute.VirtualMachineScaleSetListResult{
+				Value: []*armcompute.VirtualMachineScaleSet{{Name: to.StringPtr("vmss1")}, {Name: to.StringPtr("vmss2")}},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"code": "NotFound", "message": "not found"}}`))
+		}
+	})
+	ctx := context.Background()
+
+	rerr := client.CreateOrUpdate(ctx, "rg", "vmss", compute.VirtualMachineScaleSet{
+		Sku: &compute.Sku{Name: to.StringPtr("Standard_D2s_v3"), Capacity: to.Int64Ptr(3)},
+	})
+	assert.Nil(t, rerr)
+	assert.Equal(t, int64(3), capacity)
+
+	scaleSet, rerr := client.Get(ctx, "rg", "vmss")
+	assert.Nil(t, rerr)
+	assert.Equal(t, "vmss", *scaleSet.Name)
+	assert.Equal(t, int64(3), *scaleSet.Sku.Capacity)
+	assert.Equal(t, "Succeeded", *scaleSet.ProvisioningState)
+
+	scaleSets, rerr := client.List(ctx, "rg")
+	assert.Nil(t, rerr)
+	assert.Len(t, scaleSets, 2)
+	assert.Equal(t, "vmss2", *scaleSets[1].Name)
+
+	_, rerr = client.Get(ctx, "rg", "missing")
+	assert.NotNil(t, rerr)
+	assert.True(t, rerr.IsNotFound())
+
+	assert.Equal(t, []string{
+		"PUT " + track2TestScaleSetPath,
+		"GET " + track2TestScaleSetPath,
+		"GET /subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets",
+		"GET /subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/missing",
+	}, requests)
+}
+
+func TestTrack2Operations(t *testing.T) {
+	operations := newTrack2Operations()
+	future := operations.add(func(ctx context.Context) (interface{}, *http.Response, error) {
+		return nil, &http.Response{StatusCode: http.StatusOK}, nil
+	})
+
+	resp, err := operations.waitResponse(context.Background(), future)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Operations are forgotten once waited for.
+	_, err = operations.waitResponse(context.Background(), future)
+	assert.Error(t, err)
+	_, err = operations.waitResponse(context.Background(), &azure.Future{})
+	assert.Error(t, err)
+}
+
+func TestNewTrack2ClientOptions(t *testing.T) {
+	options := newTrack2ClientOptions(&azure.PublicCloud)
+	assert.True(t, options.DisableRPRegistration)
+	assert.Equal(t, azure.PublicCloud.ActiveDirectoryEndpoint, options.Cloud.ActiveDirectoryAuthorityHost)
+	assert.Equal(t, azure.PublicCloud.ResourceManagerEndpoint, options.Cloud.Services[cloud.ResourceManager].Endpoint)
+	assert.Equal(t, azure.PublicCloud.TokenAudience, options.Cloud.Services[cloud.ResourceManager].Audience)
+}
@@ -38,6 +38,7 @@ import (
 
 	"golang.org/x/crypto/pkcs12"
 
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
 	"k8s.io/autoscaler/cluster-autoscaler/version"
 	klog "k8s.io/klog/v2"
 	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
@@ -82,6 +83,17 @@ const (
 	nodeTaintTagName     = "k8s.io_cluster-autoscaler_node-template_taint_"
 	nodeResourcesTagName = "k8s.io_cluster-autoscaler_node-template_resources_"
 	nodeOptionsTagName   = "k8s.io_cluster-autoscaler_node-template_autoscaling-options_"
+	nodeMigProfilesTag   = "k8s.io_cluster-autoscaler_node-template_gpu-mig-profiles"
+	// nodeImageTagName prefixes tags whose value is the digest of an image pre-baked in the scale set's machine image.
+	nodeImageTagName = "k8s.io_cluster-autoscaler_node-template_image_"
+	// spotEvictionCooldownTag is how long a Spot scale set isn't scaled up after one of its instances was evicted.
+	spotEvictionCooldownTag = "k8s.io_cluster-autoscaler_spot-eviction-cooldown"
+	// fallbackNodeGroupTag is the name of the scale set scaled up instead when Azure runs out of capacity for a scale-up.
+	fallbackNodeGroupTag = "k8s.io_cluster-autoscaler_fallback-node-group"
+	// scaleDownModeTag is how the instances removed by scale-downs are disposed of: delete, deallocate or hibernate.
+	scaleDownModeTag = "k8s.io_cluster-autoscaler_scale-down-mode"
+	// autoprovisionedTag marks the scale sets created by node auto-provisioning, which are deleted once empty.
+	autoprovisionedTag = "k8s.io_cluster-autoscaler_autoprovisioned"
 
 	// PowerStates reflect the operational state of a VM
 	// From https://learn.microsoft.com/en-us/java/api/com.microsoft.azure.management.compute.powerstate?view=azure-java-stable
@@ -98,7 +110,7 @@ var (
 	vmnameLinuxRegexp        = regexp.MustCompile(k8sLinuxVMNamingFormat)
 	vmnameWindowsRegexp      = regexp.MustCompile(k8sWindowsVMNamingFormat)
 	oldvmnameWindowsRegexp   = regexp.MustCompile(k8sWindowsOldVMNamingFormat)
-	azureResourceGroupNameRE = regexp.MustCompile(`.*/subscriptions/(?:.*)/resourceGroups/(.+)/providers/(?:.*)`)
+	azureResourceGroupNameRE = regexp.MustCompile(`(?i).*/subscriptions/(?:.*)/resourceGroups/(.+)/providers/(?:.*)`)
 )
 
 // AzUtil consists of utility functions which utilizes clients to different services.
@@ -113,7 +125,9 @@ func (util *AzUtil) DeleteBlob(accountName, vhdContainer, vhdBlob string) error
 	ctx, cancel := getContextWithCancel()
 	defer cancel()
 
+	start := time.Now()
 	storageKeysResult, rerr := util.manager.azClient.storageAccountsClient.ListKeys(ctx, util.manager.config.SubscriptionID, util.manager.config.ResourceGroup, accountName)
+	observeARMRequest("StorageAccounts.ListKeys", start, rerr)
 	if rerr != nil {
 		return rerr.Error()
 	}
@@ -136,10 +150,12 @@ func (util *AzUtil) DeleteVirtualMachine(rg string, name string) error {
 	ctx, cancel := getContextWithCancel()
 	defer cancel()
 
+	start := time.Now()
 	vm, rerr := util.manager.azClient.virtualMachinesClient.Get(ctx, rg, name, "")
+	observeARMRequest("VirtualMachines.Get", start, rerr)
 	if rerr != nil {
 		if exists, _ := checkResourceExistsFromRetryError(rerr); !exists {
-			klog.V(2).Infof("VirtualMachine %s/%s has already been removed", rg, name)
+			klogx.ProviderAzure.V(2).Infof("VirtualMachine %s/%s has already been removed", rg, name)
 			return nil
 		}
 
@@ -173,24 +189,28 @@ func (util *AzUtil) DeleteVirtualMachine(rg string, name string) error {
 	defer deleteCancel()
 
 	klog.Infof("waiting for VirtualMachine deletion: %s/%s", rg, name)
+	start = time.Now()
 	rerr = util.manager.azClient.virtualMachinesClient.Delete(deleteCtx, rg, name)
+	observeARMRequest("VirtualMachines.Delete", start, rerr)
 	_, realErr := checkResourceExistsFromRetryError(rerr)
 	if realErr != nil {
 		return realErr
 	}
-	klog.V(2).Infof("VirtualMachine %s/%s removed", rg, name)
+	klogx.ProviderAzure.V(2).Infof("VirtualMachine %s/%s removed", rg, name)
 
 	if len(nicName) > 0 {
 		klog.Infof("deleting nic: %s/%s", rg, nicName)
 		interfaceCtx, interfaceCancel := getContextWithCancel()
 		defer interfaceCancel()
 		klog.Infof("waiting for nic deletion: %s/%s", rg, nicName)
+		start = time.Now()
 		nicErr := util.manager.azClient.interfacesClient.Delete(interfaceCtx, rg, nicName)
+		observeARMRequest("Interfaces.Delete", start, nicErr)
 		_, realErr := checkResourceExistsFromRetryError(nicErr)
 		if realErr != nil {
 			return realErr
 		}
-		klog.V(2).Infof("interface %s/%s removed", rg, nicName)
+		klogx.ProviderAzure.V(2).Infof("interface %s/%s removed", rg, nicName)
 	}
 
 	if vhd != nil {
@@ -207,7 +227,7 @@ func (util *AzUtil) DeleteVirtualMachine(rg string, name string) error {
 			if realErr != nil {
 				return realErr
 			}
-			klog.V(2).Infof("Blob %s/%s removed", rg, vhdBlob)
+			klogx.ProviderAzure.V(2).Infof("Blob %s/%s removed", rg, vhdBlob)
 		}
 	} else if managedDisk != nil {
 		if osDiskName == nil {
@@ -216,12 +236,14 @@ func (util *AzUtil) DeleteVirtualMachine(rg string, name string) error {
 			klog.Infof("deleting managed disk: %s/%s", rg, *osDiskName)
 			disksCtx, disksCancel := getContextWithCancel()
 			defer disksCancel()
+			start = time.Now()
 			diskErr := util.manager.azClient.disksClient.Delete(disksCtx, util.manager.config.SubscriptionID, rg, *osDiskName)
+			observeARMRequest("Disks.Delete", start, diskErr)
 			_, realErr := checkResourceExistsFromRetryError(diskErr)
 			if realErr != nil {
 				return realErr
 			}
-			klog.V(2).Infof("disk %s/%s removed", rg, *osDiskName)
+			klogx.ProviderAzure.V(2).Infof("disk %s/%s removed", rg, *osDiskName)
 		}
 	}
 
@@ -607,7 +629,7 @@ func convertResourceGroupNameToLower(resourceID string) (string, error) {
 // isAzureRequestsThrottled returns true when the err is http.StatusTooManyRequests (429),
 // and when err shows the requests was not executed due to an ongoing throttling period.
 func isAzureRequestsThrottled(rerr *retry.Error) bool {
-	klog.V(6).Infof("isAzureRequestsThrottled: starts for error %v", rerr)
+	klogx.ProviderAzure.V(6).Infof("isAzureRequestsThrottled: starts for error %v", rerr)
 	if rerr == nil {
 		return false
 	}
@@ -238,6 +238,11 @@ func TestConvertResourceGroupNameToLower(t *testing.T) {
 			resourceID: "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/myResourceGroupName/providers/Microsoft.Compute/virtualMachineScaleSets/myScaleSetName/virtualMachines/156",
 			expected:   "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/myresourcegroupname/providers/Microsoft.Compute/virtualMachineScaleSets/myScaleSetName/virtualMachines/156",
 		},
+		{
+			desc:       "resource group name in lower case VMSS providerID should be converted",
+			resourceID: "azure:///subscriptions/00000000-0000-0000-0000-000000000000/resourcegroups/myResourceGroupName/providers/microsoft.compute/virtualmachinescalesets/myScaleSetName/virtualmachines/156",
+			expected:   "azure:///subscriptions/00000000-0000-0000-0000-000000000000/resourcegroups/myresourcegroupname/providers/microsoft.compute/virtualmachinescalesets/myScaleSetName/virtualmachines/156",
+		},
 	}
 
 	for _, test := range tests {
@@ -310,6 +310,11 @@ func (asg *Asg) DeleteNodes(nodes []*apiv1.Node) error {
 	return asg.baiducloudManager.ScaleDownCluster(nodeID)
 }
 
+// ForceDeleteNodes deletes nodes from the group regardless of constraints.
+func (asg *Asg) ForceDeleteNodes(nodes []*apiv1.Node) error {
+	return cloudprovider.ErrNotImplemented
+}
+
 // Belongs returns true if the given node belongs to the NodeGroup.
 func (asg *Asg) Belongs(instanceID string) (bool, error) {
 	targetAsg, err := asg.baiducloudManager.GetAsgForInstance(instanceID)
@@ -183,6 +183,12 @@ type NodeGroup interface {
 	// should wait until node group size is updated. Implementation required.
 	DeleteNodes([]*apiv1.Node) error
 
+	// ForceDeleteNodes deletes nodes from this node group, without checking for
+	// constraints like minimal size validation etc. Error is returned either on
+	// failure or if the given node doesn't belong to this node group. This function
+	// should wait until node group size is updated.
+	ForceDeleteNodes([]*apiv1.Node) error
+
 	// DecreaseTargetSize decreases the target size of the node group. This function
 	// doesn't permit to delete any existing node and can be used only to reduce the
 	// request for new nodes that have not been yet fulfilled. Delta should be negative.
@@ -232,6 +238,81 @@ type NodeGroup interface {
 	GetOptions(defaults config.NodeGroupAutoscalingOptions) (*config.NodeGroupAutoscalingOptions, error)
 }
 
+// DrainAwareNodeGroup is an optional interface of node groups that need to prepare their nodes
+// before they are drained for scale-down, e.g. to keep the cloud provider from removing them
+// while they are drained.
+type DrainAwareNodeGroup interface {
+	// BeforeNodeDrain is called before the node is drained for deletion, or before its
+	// deletion if it is empty.
+	BeforeNodeDrain(node *apiv1.Node) error
+
+	// AfterNodeDrain is called once the drain of the node is over, whether it succeeded or
+	// not, before the node is deleted.
+	AfterNodeDrain(node *apiv1.Node) error
+}
+
+// BillingPeriodNodeGroup is an optional interface of node groups whose nodes are billed per started
+// period, e.g. per hour, rather than for the time they actually run.
+type BillingPeriodNodeGroup interface {
+	// BillingPeriod returns the billing granularity of the nodes of the node group.
+	BillingPeriod() time.Duration
+}
+
+// WarmCapacityNodeGroup is an optional interface of node groups keeping pre-initialized instances, e.g.
+// AWS warm pools, from which they are scaled up faster than by launching new instances.
+type WarmCapacityNodeGroup interface {
+	// WarmCapacity returns the number of pre-initialized instances the node group can be scaled up from.
+	WarmCapacity() (int, error)
+
+	// PreferWarmCapacity returns true if the node group should be preferred by the warm-capacity
+	// expander when its pre-initialized instances can absorb a scale-up.
+	PreferWarmCapacity() bool
+}
+
+// AtomicScaleUpNodeGroup is an optional interface of node groups able to scale up atomically, keeping
+// either all the requested nodes or none of them. It is used instead of IncreaseSize for the node
+// groups scaled atomically through the ZeroOrMaxNodeScaling option.
+type AtomicScaleUpNodeGroup interface {
+	// AtomicIncreaseSize increases the size of the node group by delta. Unlike IncreaseSize, the
+	// nodes already created are removed if not all of them can be, so that the node group is never
+	// left partially scaled up.
+	AtomicIncreaseSize(delta int) error
+}
+
+// CapacityType is the kind of capacity backing the nodes of a node group.
+type CapacityType string
+
+const (
+	// OnDemandCapacity is regular capacity, only removed by the autoscaler.
+	OnDemandCapacity CapacityType = "on-demand"
+	// SpotCapacity is spot or preemptible capacity, cheaper but interrupted whenever the cloud provider
+	// needs it back.
+	SpotCapacity CapacityType = "spot"
+)
+
+// CapacityTypeNodeGroup is an optional interface of node groups reporting the kind of capacity backing
+// their nodes. Node groups not implementing it are assumed to be backed by OnDemandCapacity.
+type CapacityTypeNodeGroup interface {
+	// CapacityType returns the kind of capacity backing the nodes of the node group.
+	CapacityType() CapacityType
+}
+
+// NodeGroupCapacityType returns the kind of capacity backing the nodes of the node group.
+func NodeGroupCapacityType(nodeGroup NodeGroup) CapacityType {
+	if group, ok := nodeGroup.(CapacityTypeNodeGroup); ok && group.CapacityType() != "" {
+		return group.CapacityType()
+	}
+	return OnDemandCapacity
+}
+
+// TargetSizeRefreshingNodeGroup is an optional interface of node groups caching their target size. The core
+// calls RefreshTargetSize to re-read the target size from the cloud provider API before verifying it, so that
+// resizes made outside of the autoscaler are noticed without waiting for the next full cache refresh.
+type TargetSizeRefreshingNodeGroup interface {
+	// RefreshTargetSize re-reads the target size of the node group from the cloud provider API.
+	RefreshTargetSize() error
+}
+
 // Instance represents a cloud-provider node. The node does not necessarily map to k8s node
 // i.e it does not have to be registered in k8s cluster despite being returned by NodeGroup.Nodes()
 // method. Also it is sane to have Instance object for nodes which are being created or deleted.
@@ -273,6 +354,17 @@ type InstanceErrorInfo struct {
 	ErrorMessage string
 }
 
+// ScaleUpError can be returned by IncreaseSize to tell why the node group can't be scaled up. The node group
+// is then backed off according to the error class, like when its instances fail to be created.
+type ScaleUpError struct {
+	InstanceErrorInfo
+}
+
+// Error implements the error interface.
+func (e *ScaleUpError) Error() string {
+	return e.ErrorMessage
+}
+
 // InstanceErrorClass defines class of error condition
 type InstanceErrorClass int
 
@@ -19,12 +19,15 @@ package clusterapi
 import (
 	"fmt"
 	"math/rand"
+	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
 	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
 
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
@@ -168,6 +171,11 @@ func (ng *nodegroup) DeleteNodes(nodes []*corev1.Node) error {
 	return nil
 }
 
+// ForceDeleteNodes deletes nodes from the group regardless of constraints.
+func (ng *nodegroup) ForceDeleteNodes(nodes []*corev1.Node) error {
+	return cloudprovider.ErrNotImplemented
+}
+
 // DecreaseTargetSize decreases the target size of the node group.
 // This function doesn't permit to delete any existing node and can be
 // used only to reduce the request for new nodes that have not been
@@ -328,8 +336,53 @@ func (ng *nodegroup) Autoprovisioned() bool {
 
 // GetOptions returns NodeGroupAutoscalingOptions that should be used for this particular
 // NodeGroup. Returning a nil will result in using default options.
+// Options are overridden by annotations of the scalable resource, e.g.
+// cluster.x-k8s.io/autoscaling-options-scaledownunneededtime: "5m". Invalid values are ignored.
 func (ng *nodegroup) GetOptions(defaults config.NodeGroupAutoscalingOptions) (*config.NodeGroupAutoscalingOptions, error) {
-	return nil, cloudprovider.ErrNotImplemented
+	options := ng.scalableResource.AutoscalingOptions()
+	if len(options) == 0 {
+		return nil, cloudprovider.ErrNotImplemented
+	}
+
+	floatOptions := map[string]*float64{
+		config.DefaultScaleDownUtilizationThresholdKey:    &defaults.ScaleDownUtilizationThreshold,
+		config.DefaultScaleDownGpuUtilizationThresholdKey: &defaults.ScaleDownGpuUtilizationThreshold,
+		config.DefaultRecreationCostKey:                   &defaults.RecreationCost,
+	}
+	for key, opt := range floatOptions {
+		if val, found := options[key]; found {
+			if f, err := strconv.ParseFloat(val, 64); err != nil {
+				klog.Warningf("failed to convert node group %s annotation %s%s to float: %v", ng.Id(), autoscalingOptionsAnnotationKeyPrefix, key, err)
+			} else {
+				*opt = f
+			}
+		}
+	}
+
+	durationOptions := map[string]*time.Duration{
+		config.DefaultScaleDownUnneededTimeKey: &defaults.ScaleDownUnneededTime,
+		config.DefaultScaleDownUnreadyTimeKey:  &defaults.ScaleDownUnreadyTime,
+		config.DefaultMaxNodeProvisionTimeKey:  &defaults.MaxNodeProvisionTime,
+	}
+	for key, opt := range durationOptions {
+		if val, found := options[key]; found {
+			if d, err := time.ParseDuration(val); err != nil {
+				klog.Warningf("failed to convert node group %s annotation %s%s to duration: %v", ng.Id(), autoscalingOptionsAnnotationKeyPrefix, key, err)
+			} else {
+				*opt = d
+			}
+		}
+	}
+
+	if val, found := options[config.DefaultIgnoreDaemonSetsUtilizationKey]; found {
+		if b, err := strconv.ParseBool(val); err != nil {
+			klog.Warningf("failed to convert node group %s annotation %s%s to bool: %v", ng.Id(), autoscalingOptionsAnnotationKeyPrefix, config.DefaultIgnoreDaemonSetsUtilizationKey, err)
+		} else {
+			defaults.IgnoreDaemonSetsUtilization = b
+		}
+	}
+
+	return &defaults, nil
 }
 
 func newNodeGroupFromScalableResource(controller *machineController, unstructuredScalableResource *unstructured.Unstructured) (*nodegroup, error) {
@@ -343,6 +396,13 @@ func newNodeGroupFromScalableResource(controller *machineController, unstructure
 		return nil, err
 	}
 
+	// MachinePools whose replicas are managed by another system, e.g. the autoscaler of a
+	// managed node pool, must not be scaled by the autoscaler.
+	if managedBy := scalableResource.ReplicasManagedBy(); managedBy != "" && managedBy != replicasManagedByAutoscaler {
+		klog.V(4).Infof("Skipping %s %s/%s, its replicas are managed by %q", scalableResource.Kind(), scalableResource.Namespace(), scalableResource.Name(), managedBy)
+		return nil, nil
+	}
+
 	replicas, found, err := unstructured.NestedInt64(unstructuredScalableResource.UnstructuredContent(), "spec", "replicas")
 	if err != nil {
 		return nil, err
@@ -32,7 +32,10 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/util/wait"
 
+	"github.com/stretchr/testify/assert"
+
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
 	gpuapis "k8s.io/autoscaler/cluster-autoscaler/utils/gpu"
 )
 
@@ -216,6 +219,40 @@ func TestNodeGroupNewNodeGroupConstructor(t *testing.T) {
 	})
 }
 
+func TestNodeGroupNewNodeGroupWithExternallyManagedReplicas(t *testing.T) {
+	testConfig := createMachineDeploymentTestConfig(RandomString(6), RandomString(6), RandomString(6), 1, nil, nil)
+	controller, stop := mustCreateTestController(t, testConfig)
+	defer stop()
+
+	for _, tc := range []struct {
+		description string
+		managedBy   string
+		expectNil   bool
+	}{{
+		description: "replicas managed by cluster-api",
+	}, {
+		description: "replicas managed by the autoscaler",
+		managedBy:   replicasManagedByAutoscaler,
+	}, {
+		description: "replicas managed by another system",
+		managedBy:   "external-autoscaler",
+		expectNil:   true,
+	}} {
+		t.Run(tc.description, func(t *testing.T) {
+			annotations := map[string]string{
+				nodeGroupMinSizeAnnotationKey: "1",
+				nodeGroupMaxSizeAnnotationKey: "10",
+			}
+			if tc.managedBy != "" {
+				annotations[replicasManagedByAnnotationKey] = tc.managedBy
+			}
+			ng, err := newNodeGroupFromScalableResource(controller, buildTestMachinePool(annotations))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectNil, ng == nil)
+		})
+	}
+}
+
 func TestNodeGroupIncreaseSizeErrors(t *testing.T) {
 	type testCase struct {
 		description string
@@ -1368,6 +1405,32 @@ func TestNodeGroupTemplateNodeInfo(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "When the NodeGroup can scale from zero, the extended resources capacity annotation adds to the capacity",
+			nodeGroupAnnotations: map[string]string{
+				memoryKey:            "2048Mi",
+				cpuKey:               "2",
+				diskCapacityKey:      "100Gi",
+				maxPodsKey:           "42",
+				extendedResourcesKey: "example.com/dongle=2,example.com/fpga=1",
+			},
+			config: testCaseConfig{
+				expectedErr: nil,
+				expectedCapacity: map[corev1.ResourceName]int64{
+					corev1.ResourceCPU:              2,
+					corev1.ResourceMemory:           2048 * 1024 * 1024,
+					corev1.ResourceEphemeralStorage: 100 * 1024 * 1024 * 1024,
+					corev1.ResourcePods:             42,
+					"example.com/dongle":            2,
+					"example.com/fpga":              1,
+				},
+				expectedNodeLabels: map[string]string{
+					"kubernetes.io/os":       "linux",
+					"kubernetes.io/arch":     "amd64",
+					"kubernetes.io/hostname": "random value",
+				},
+			},
+		},
 		{
 			name: "When the NodeGroup can scale from zero and the Node still exists, it includes the known node labels",
 			nodeGroupAnnotations: map[string]string{
@@ -1495,3 +1558,96 @@ func TestNodeGroupTemplateNodeInfo(t *testing.T) {
 	}
 
 }
+
+func TestNodeGroupGetOptions(t *testing.T) {
+	enableScaleAnnotations := map[string]string{
+		nodeGroupMinSizeAnnotationKey: "1",
+		nodeGroupMaxSizeAnnotationKey: "10",
+	}
+
+	defaultOptions := config.NodeGroupAutoscalingOptions{
+		ScaleDownUtilizationThreshold:    0.1,
+		ScaleDownGpuUtilizationThreshold: 0.2,
+		ScaleDownUnneededTime:            time.Second,
+		ScaleDownUnreadyTime:             time.Minute,
+		MaxNodeProvisionTime:             15 * time.Minute,
+	}
+
+	cases := []struct {
+		desc        string
+		annotations map[string]string
+		expected    *config.NodeGroupAutoscalingOptions
+		expectedErr error
+	}{
+		{
+			desc:        "return not implemented without options annotations",
+			annotations: map[string]string{},
+			expectedErr: cloudprovider.ErrNotImplemented,
+		},
+		{
+			desc: "return specified options",
+			annotations: map[string]string{
+				autoscalingOptionsAnnotationKeyPrefix + config.DefaultScaleDownGpuUtilizationThresholdKey: "0.6",
+				autoscalingOptionsAnnotationKeyPrefix + config.DefaultScaleDownUtilizationThresholdKey:    "0.7",
+				autoscalingOptionsAnnotationKeyPrefix + config.DefaultScaleDownUnneededTimeKey:            "1h",
+				autoscalingOptionsAnnotationKeyPrefix + config.DefaultScaleDownUnreadyTimeKey:             "30m",
+				autoscalingOptionsAnnotationKeyPrefix + config.DefaultMaxNodeProvisionTimeKey:             "60m",
+				autoscalingOptionsAnnotationKeyPrefix + config.DefaultIgnoreDaemonSetsUtilizationKey:      "true",
+			},
+			expected: &config.NodeGroupAutoscalingOptions{
+				ScaleDownGpuUtilizationThreshold: 0.6,
+				ScaleDownUtilizationThreshold:    0.7,
+				ScaleDownUnneededTime:            time.Hour,
+				ScaleDownUnreadyTime:             30 * time.Minute,
+				MaxNodeProvisionTime:             60 * time.Minute,
+				IgnoreDaemonSetsUtilization:      true,
+			},
+		},
+		{
+			desc: "complete partial options with defaults and ignore invalid values",
+			annotations: map[string]string{
+				autoscalingOptionsAnnotationKeyPrefix + config.DefaultScaleDownUtilizationThresholdKey: "0.3",
+				autoscalingOptionsAnnotationKeyPrefix + config.DefaultScaleDownUnneededTimeKey:         "not-a-duration",
+			},
+			expected: &config.NodeGroupAutoscalingOptions{
+				ScaleDownUtilizationThreshold:    0.3,
+				ScaleDownGpuUtilizationThreshold: 0.2,
+				ScaleDownUnneededTime:            time.Second,
+				ScaleDownUnreadyTime:             time.Minute,
+				MaxNodeProvisionTime:             15 * time.Minute,
+			},
+		},
+	}
+
+	test := func(t *testing.T, testConfig *testConfig, expected *config.NodeGroupAutoscalingOptions, expectedErr error) {
+		controller, stop := mustCreateTestController(t, testConfig)
+		defer stop()
+
+		nodegroups, err := controller.nodeGroups()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if l := len(nodegroups); l != 1 {
+			t.Fatalf("expected 1 nodegroup, got %d", l)
+		}
+
+		opts, err := nodegroups[0].GetOptions(defaultOptions)
+		assert.Equal(t, expectedErr, err)
+		assert.Equal(t, expected, opts)
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			t.Run("MachineSet", func(t *testing.T) {
+				test(t, createMachineSetTestConfig(testNamespace, RandomString(6), RandomString(6), 10,
+					cloudprovider.JoinStringMaps(enableScaleAnnotations, c.annotations), nil), c.expected, c.expectedErr)
+			})
+
+			t.Run("MachineDeployment", func(t *testing.T) {
+				test(t, createMachineDeploymentTestConfig(testNamespace, RandomString(6), RandomString(6), 10,
+					cloudprovider.JoinStringMaps(enableScaleAnnotations, c.annotations), nil), c.expected, c.expectedErr)
+			})
+		})
+	}
+}
@@ -34,6 +34,10 @@ import (
 	klog "k8s.io/klog/v2"
 )
 
+// replicasManagedByAutoscaler is the value of the replicas-managed-by annotation of
+// MachinePools whose externally managed replicas are scaled by the autoscaler.
+const replicasManagedByAutoscaler = "cluster-autoscaler"
+
 type unstructuredScalableResource struct {
 	controller   *machineController
 	unstructured *unstructured.Unstructured
@@ -87,13 +91,42 @@ func (r unstructuredScalableResource) ProviderIDs() ([]string, error) {
 	return providerIds, nil
 }
 
+// ReplicasManagedBy returns the system managing the replicas of a MachinePool, as set by the
+// replicas-managed-by annotation, or an empty string if they are managed by cluster-api.
+func (r unstructuredScalableResource) ReplicasManagedBy() string {
+	if r.Kind() != machinePoolKind {
+		return ""
+	}
+	return r.unstructured.GetAnnotations()[replicasManagedByAnnotationKey]
+}
+
+// scaleTarget returns the resource whose scale subresource holds the replicas of the scalable
+// resource. The replicas of MachinePools managed by the autoscaler through the
+// replicas-managed-by annotation are held by their infrastructure machine pool, cluster-api
+// only reflects them on the MachinePool.
+func (r unstructuredScalableResource) scaleTarget() (schema.GroupResource, string, error) {
+	if r.ReplicasManagedBy() == "" {
+		gvr, err := r.GroupVersionResource()
+		if err != nil {
+			return schema.GroupResource{}, "", err
+		}
+		return gvr.GroupResource(), r.Name(), nil
+	}
+
+	gvr, name, found := r.infrastructureReference()
+	if !found {
+		return schema.GroupResource{}, "", fmt.Errorf("%s %s/%s has externally managed replicas but no infrastructure reference", r.Kind(), r.Namespace(), r.Name())
+	}
+	return gvr.GroupResource(), name, nil
+}
+
 func (r unstructuredScalableResource) Replicas() (int, error) {
-	gvr, err := r.GroupVersionResource()
+	gr, name, err := r.scaleTarget()
 	if err != nil {
 		return 0, err
 	}
 
-	s, err := r.controller.managementScaleClient.Scales(r.Namespace()).Get(context.TODO(), gvr.GroupResource(), r.Name(), metav1.GetOptions{})
+	s, err := r.controller.managementScaleClient.Scales(r.Namespace()).Get(context.TODO(), gr, name, metav1.GetOptions{})
 	if err != nil {
 		return 0, err
 	}
@@ -112,12 +145,12 @@ func (r unstructuredScalableResource) SetSize(nreplicas int) error {
 		return fmt.Errorf("size decrease too large - desired:%d min:%d", nreplicas, r.minSize)
 	}
 
-	gvr, err := r.GroupVersionResource()
+	gr, name, err := r.scaleTarget()
 	if err != nil {
 		return err
 	}
 
-	s, err := r.controller.managementScaleClient.Scales(r.Namespace()).Get(context.TODO(), gvr.GroupResource(), r.Name(), metav1.GetOptions{})
+	s, err := r.controller.managementScaleClient.Scales(r.Namespace()).Get(context.TODO(), gr, name, metav1.GetOptions{})
 	if err != nil {
 		return err
 	}
@@ -127,7 +160,7 @@ func (r unstructuredScalableResource) SetSize(nreplicas int) error {
 	}
 
 	s.Spec.Replicas = int32(nreplicas)
-	_, updateErr := r.controller.managementScaleClient.Scales(r.Namespace()).Update(context.TODO(), gvr.GroupResource(), s, metav1.UpdateOptions{})
+	_, updateErr := r.controller.managementScaleClient.Scales(r.Namespace()).Update(context.TODO(), gr, s, metav1.UpdateOptions{})
 
 	if updateErr == nil {
 		updateErr = unstructured.SetNestedField(r.unstructured.UnstructuredContent(), int64(nreplicas), "spec", "replicas")
@@ -269,6 +302,14 @@ func (r unstructuredScalableResource) InstanceCapacity() (map[corev1.ResourceNam
 	}
 	capacityAnnotations[corev1.ResourcePods] = maxPods
 
+	extendedResources, err := r.InstanceExtendedResourcesAnnotation()
+	if err != nil {
+		return nil, err
+	}
+	for name, quantity := range extendedResources {
+		capacityAnnotations[name] = quantity
+	}
+
 	infraObj, err := r.readInfrastructureReferenceResource()
 	if err != nil || infraObj == nil {
 		// because it is possible that the infrastructure provider does not implement
@@ -320,28 +361,46 @@ func (r unstructuredScalableResource) InstanceMaxPodsCapacityAnnotation() (resou
 	return parseMaxPodsCapacity(r.unstructured.GetAnnotations())
 }
 
-func (r unstructuredScalableResource) readInfrastructureReferenceResource() (*unstructured.Unstructured, error) {
+func (r unstructuredScalableResource) InstanceExtendedResourcesAnnotation() (map[corev1.ResourceName]resource.Quantity, error) {
+	return parseExtendedResources(r.unstructured.GetAnnotations())
+}
+
+// AutoscalingOptions returns the autoscaling options overridden by annotations, keyed by option name.
+func (r unstructuredScalableResource) AutoscalingOptions() map[string]string {
+	return parseAutoscalingOptions(r.unstructured.GetAnnotations())
+}
+
+// infrastructureReference returns the resource and name of the infrastructure template, or
+// infrastructure machine pool for MachinePools, referenced by the scalable resource.
+func (r unstructuredScalableResource) infrastructureReference() (schema.GroupVersionResource, string, bool) {
 	infraref, found, err := unstructured.NestedStringMap(r.unstructured.Object, "spec", "template", "spec", "infrastructureRef")
 	if !found || err != nil {
-		return nil, nil
+		return schema.GroupVersionResource{}, "", false
 	}
 
 	apiversion, ok := infraref["apiVersion"]
 	if !ok {
-		return nil, nil
+		return schema.GroupVersionResource{}, "", false
 	}
 	kind, ok := infraref["kind"]
 	if !ok {
-		return nil, nil
+		return schema.GroupVersionResource{}, "", false
 	}
 	name, ok := infraref["name"]
 	if !ok {
-		return nil, nil
+		return schema.GroupVersionResource{}, "", false
 	}
 	// kind needs to be lower case and plural
 	kind = fmt.Sprintf("%ss", strings.ToLower(kind))
 	gvk := schema.FromAPIVersionAndKind(apiversion, kind)
-	res := schema.GroupVersionResource{Group: gvk.Group, Version: gvk.Version, Resource: gvk.Kind}
+	return schema.GroupVersionResource{Group: gvk.Group, Version: gvk.Version, Resource: gvk.Kind}, name, true
+}
+
+func (r unstructuredScalableResource) readInfrastructureReferenceResource() (*unstructured.Unstructured, error) {
+	res, name, found := r.infrastructureReference()
+	if !found {
+		return nil, nil
+	}
 
 	infra, err := r.controller.getInfrastructureResource(res, name, r.Namespace())
 	if err != nil {
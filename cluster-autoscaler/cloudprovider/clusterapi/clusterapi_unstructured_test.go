@@ -506,3 +506,86 @@ func TestCanScaleFromZero(t *testing.T) {
 		})
 	}
 }
+
+// buildTestMachinePool returns a MachinePool referencing an AzureManagedMachinePool.
+func buildTestMachinePool(annotations map[string]string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind":       machinePoolKind,
+			"apiVersion": "cluster.x-k8s.io/v1beta1",
+			"metadata": map[string]interface{}{
+				"name":        "pool",
+				"namespace":   "default",
+				"annotations": map[string]interface{}{},
+			},
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"infrastructureRef": map[string]interface{}{
+							"apiVersion": "infrastructure.cluster.x-k8s.io/v1beta1",
+							"kind":       "AzureManagedMachinePool",
+							"name":       "pool-infra",
+						},
+					},
+				},
+			},
+		},
+	}
+	u.SetAnnotations(annotations)
+	return u
+}
+
+func TestScaleTarget(t *testing.T) {
+	testConfig := createMachineDeploymentTestConfig(RandomString(6), RandomString(6), RandomString(6), 1, nil, nil)
+	controller, stop := mustCreateTestController(t, testConfig)
+	defer stop()
+
+	for _, tc := range []struct {
+		name         string
+		resource     *unstructured.Unstructured
+		expectedGR   string
+		expectedName string
+	}{
+		{
+			name:         "MachineDeployment is scaled directly",
+			resource:     testConfig.machineDeployment,
+			expectedGR:   controller.machineDeploymentResource.GroupResource().String(),
+			expectedName: testConfig.machineDeployment.GetName(),
+		},
+		{
+			name:         "MachinePool managed by cluster-api is scaled directly",
+			resource:     buildTestMachinePool(nil),
+			expectedGR:   controller.machinePoolResource.GroupResource().String(),
+			expectedName: "pool",
+		},
+		{
+			name:         "MachinePool with replicas managed by the autoscaler is scaled through its infrastructure",
+			resource:     buildTestMachinePool(map[string]string{replicasManagedByAnnotationKey: replicasManagedByAutoscaler}),
+			expectedGR:   "azuremanagedmachinepools.infrastructure.cluster.x-k8s.io",
+			expectedName: "pool-infra",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sr := unstructuredScalableResource{controller: controller, unstructured: tc.resource}
+			gr, name, err := sr.scaleTarget()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedGR, gr.String())
+			assert.Equal(t, tc.expectedName, name)
+		})
+	}
+
+	t.Run("MachinePool with replicas managed by the autoscaler requires an infrastructure reference", func(t *testing.T) {
+		u := buildTestMachinePool(map[string]string{replicasManagedByAnnotationKey: replicasManagedByAutoscaler})
+		unstructured.RemoveNestedField(u.Object, "spec", "template")
+		sr := unstructuredScalableResource{controller: controller, unstructured: u}
+		_, _, err := sr.scaleTarget()
+		assert.Error(t, err)
+	})
+
+	t.Run("replicas-managed-by is ignored on other kinds", func(t *testing.T) {
+		u := testConfig.machineDeployment.DeepCopy()
+		u.SetAnnotations(map[string]string{replicasManagedByAnnotationKey: "external-autoscaler"})
+		sr := unstructuredScalableResource{controller: controller, unstructured: u}
+		assert.Equal(t, "", sr.ReplicasManagedBy())
+	})
+}
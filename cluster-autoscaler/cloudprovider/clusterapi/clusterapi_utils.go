@@ -22,6 +22,7 @@ import (
 	"strings"
 
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -36,6 +37,8 @@ const (
 	maxPodsKey      = "capacity.cluster-autoscaler.kubernetes.io/maxPods"
 	taintsKey       = "capacity.cluster-autoscaler.kubernetes.io/taints"
 	labelsKey       = "capacity.cluster-autoscaler.kubernetes.io/labels"
+	// extendedResourcesKey holds the capacity of extended resources, of the form "name1=quantity1,name2=quantity2"
+	extendedResourcesKey = "capacity.cluster-autoscaler.kubernetes.io/extended-resources"
 )
 
 var (
@@ -72,6 +75,16 @@ var (
 	// by the CAPI_GROUP env variable, it is initialized here.
 	machineAnnotationKey = getMachineAnnotationKey()
 
+	// autoscalingOptionsAnnotationKeyPrefix is the prefix of the annotations overriding the
+	// autoscaling options of a node group, e.g. <prefix>scaledownunneededtime.
+	autoscalingOptionsAnnotationKeyPrefix = getAutoscalingOptionsAnnotationKeyPrefix()
+
+	// replicasManagedByAnnotationKey is the annotation used by cluster-api on MachinePools
+	// whose replicas are managed by a system other than cluster-api, e.g. a managed node pool
+	// of the infrastructure provider. Because this key can be affected by the CAPI_GROUP env
+	// variable, it is initialized here.
+	replicasManagedByAnnotationKey = getReplicasManagedByAnnotationKey()
+
 	// nodeGroupMinSizeAnnotationKey and nodeGroupMaxSizeAnnotationKey are the keys
 	// used in MachineSet and MachineDeployment annotations to specify the limits
 	// for the node group. Because the keys can be affected by the CAPI_GROUP env
@@ -221,6 +234,39 @@ func parseMaxPodsCapacity(annotations map[string]string) (resource.Quantity, err
 	return parseIntKey(annotations, maxPodsKey)
 }
 
+// parseExtendedResources returns the capacity of extended resources, from an annotation
+// value of the form "name1=quantity1,name2=quantity2".
+func parseExtendedResources(annotations map[string]string) (map[corev1.ResourceName]resource.Quantity, error) {
+	val, found := annotations[extendedResourcesKey]
+	if !found || val == "" {
+		return nil, nil
+	}
+	resources := make(map[corev1.ResourceName]resource.Quantity)
+	for _, item := range strings.Split(val, ",") {
+		split := strings.SplitN(item, "=", 2)
+		if len(split) != 2 || split[0] == "" {
+			return nil, fmt.Errorf("invalid extended resource %q in annotation %q, expected name=quantity", item, extendedResourcesKey)
+		}
+		quantity, err := resource.ParseQuantity(split[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity of extended resource %q in annotation %q: %v", split[0], extendedResourcesKey, err)
+		}
+		resources[corev1.ResourceName(split[0])] = quantity
+	}
+	return resources, nil
+}
+
+// parseAutoscalingOptions returns the autoscaling options set by annotations, keyed by option name.
+func parseAutoscalingOptions(annotations map[string]string) map[string]string {
+	options := map[string]string{}
+	for key, val := range annotations {
+		if name := strings.TrimPrefix(key, autoscalingOptionsAnnotationKeyPrefix); name != key {
+			options[name] = val
+		}
+	}
+	return options
+}
+
 func clusterNameFromResource(r *unstructured.Unstructured) string {
 	// Use Spec.ClusterName if defined (only available on v1alpha3+ types)
 	clusterName, found, err := unstructured.NestedString(r.Object, "spec", "clusterName")
@@ -256,6 +302,22 @@ func getNodeGroupMaxSizeAnnotationKey() string {
 	return key
 }
 
+// getAutoscalingOptionsAnnotationKeyPrefix returns the prefix of the annotations used to
+// override the autoscaling options of a node group. This function is needed because the user can
+// change the default group name by using the CAPI_GROUP environment variable.
+func getAutoscalingOptionsAnnotationKeyPrefix() string {
+	key := fmt.Sprintf("%s/autoscaling-options-", getCAPIGroup())
+	return key
+}
+
+// getReplicasManagedByAnnotationKey returns the key that is used by cluster-api for marking
+// MachinePools with externally managed replicas. This function is needed because the user can
+// change the default group name by using the CAPI_GROUP environment variable.
+func getReplicasManagedByAnnotationKey() string {
+	key := fmt.Sprintf("%s/replicas-managed-by", getCAPIGroup())
+	return key
+}
+
 // getMachineDeleteAnnotationKey returns the key that is used by cluster-api for marking
 // machines to be deleted. This function is needed because the user can change the default
 // group name by using the CAPI_GROUP environment variable.
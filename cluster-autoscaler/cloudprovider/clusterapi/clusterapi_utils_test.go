@@ -22,6 +22,7 @@ import (
 	"strings"
 	"testing"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -612,6 +613,56 @@ func TestParseMaxPodsCapacity(t *testing.T) {
 	}
 }
 
+func TestParseExtendedResources(t *testing.T) {
+	for _, tc := range []struct {
+		description       string
+		annotations       map[string]string
+		expectedResources map[corev1.ResourceName]resource.Quantity
+		expectedError     bool
+	}{{
+		description: "nil annotations",
+	}, {
+		description: "empty annotation",
+		annotations: map[string]string{extendedResourcesKey: ""},
+	}, {
+		description: "valid resources",
+		annotations: map[string]string{extendedResourcesKey: "example.com/dongle=2,example.com/memory=1Gi"},
+		expectedResources: map[corev1.ResourceName]resource.Quantity{
+			"example.com/dongle": resource.MustParse("2"),
+			"example.com/memory": resource.MustParse("1Gi"),
+		},
+	}, {
+		description:   "missing quantity",
+		annotations:   map[string]string{extendedResourcesKey: "example.com/dongle"},
+		expectedError: true,
+	}, {
+		description:   "bad quantity",
+		annotations:   map[string]string{extendedResourcesKey: "example.com/dongle=many"},
+		expectedError: true,
+	}} {
+		t.Run(tc.description, func(t *testing.T) {
+			got, err := parseExtendedResources(tc.annotations)
+			if tc.expectedError {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tc.expectedResources) {
+				t.Fatalf("expected %v, got %v", tc.expectedResources, got)
+			}
+			for name, quantity := range tc.expectedResources {
+				if quantity.Cmp(got[name]) != 0 {
+					t.Errorf("expected %v of %s, got %v", quantity.String(), name, got[name])
+				}
+			}
+		})
+	}
+}
+
 func Test_clusterNameFromResource(t *testing.T) {
 	for _, tc := range []struct {
 		name     string
@@ -797,6 +848,11 @@ func Test_getKeyHelpers(t *testing.T) {
 			expected: fmt.Sprintf("%s/machine", defaultCAPIGroup),
 			testfunc: getMachineAnnotationKey,
 		},
+		{
+			name:     "default group, replicas managed by annotation key",
+			expected: fmt.Sprintf("%s/replicas-managed-by", defaultCAPIGroup),
+			testfunc: getReplicasManagedByAnnotationKey,
+		},
 		{
 			name:     "default group, cluster name label",
 			expected: fmt.Sprintf("%s/cluster-name", defaultCAPIGroup),
@@ -839,6 +895,11 @@ func Test_getKeyHelpers(t *testing.T) {
 			expected: fmt.Sprintf("%s/machine", testgroup),
 			testfunc: getMachineAnnotationKey,
 		},
+		{
+			name:     "test group, replicas managed by annotation key",
+			expected: fmt.Sprintf("%s/replicas-managed-by", testgroup),
+			testfunc: getReplicasManagedByAnnotationKey,
+		},
 		{
 			name:     "test group, cluster name label",
 			expected: fmt.Sprintf("%s/cluster-name", testgroup),
@@ -0,0 +1,345 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package conformance checks that a cloud provider implements the NodeGroup semantics the autoscaler core
+// relies on. It is meant for out-of-tree and external gRPC providers, to be run from a Go test:
+//
+//	func TestConformance(t *testing.T) {
+//		conformance.Run(t, conformance.Config{Provider: provider, AllowResize: true})
+//	}
+package conformance
+
+import (
+	"fmt"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+)
+
+// foreignInstanceId is the id of an instance that belongs to no node group.
+const foreignInstanceId = "conformance-foreign-instance"
+
+// Check is a conformance check of a node group.
+type Check struct {
+	// Name of the check, used to name its test.
+	Name string
+	// Resize is true for checks that resize the node group. They only run with Config.AllowResize.
+	Resize bool
+	// Run runs the check against the node group with the given id.
+	Run func(core *Core, id string) error
+}
+
+// Checks are the checks run against each node group.
+var Checks = []Check{
+	{Name: "SizeLimits", Run: CheckSizeLimits},
+	{Name: "Instances", Run: CheckInstances},
+	{Name: "Template", Run: CheckTemplate},
+	{Name: "InvalidResizes", Resize: true, Run: CheckInvalidResizes},
+	{Name: "DeleteForeignNode", Resize: true, Run: CheckDeleteForeignNode},
+	{Name: "ScaleUpAndDelete", Resize: true, Run: CheckScaleUpAndDelete},
+}
+
+// skipError is returned by checks that don't apply to a node group.
+type skipError string
+
+func (e skipError) Error() string {
+	return string(e)
+}
+
+// Skipf returns an error telling that a check doesn't apply to a node group.
+func Skipf(format string, args ...interface{}) error {
+	return skipError(fmt.Sprintf(format, args...))
+}
+
+// IsSkipped returns true if the error returned by a check means that the check doesn't apply to the node group.
+func IsSkipped(err error) bool {
+	_, ok := err.(skipError)
+	return ok
+}
+
+// Run runs the conformance checks against the provider, one test per node group and check.
+func Run(t *testing.T, config Config) {
+	core := NewCore(config)
+	if err := core.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	t.Run("NodeGroupIds", func(t *testing.T) {
+		report(t, CheckNodeGroupIds(core))
+	})
+	for _, nodeGroup := range core.NodeGroups() {
+		id := nodeGroup.Id()
+		for _, check := range Checks {
+			check := check
+			t.Run(id+"/"+check.Name, func(t *testing.T) {
+				if check.Resize && !config.AllowResize {
+					t.Skip("resizing node groups is not allowed")
+				}
+				report(t, check.Run(core, id))
+			})
+		}
+	}
+}
+
+func report(t *testing.T, err error) {
+	if IsSkipped(err) {
+		t.Skip(err.Error())
+	}
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// CheckNodeGroupIds checks that the provider has node groups, with unique non-empty ids.
+func CheckNodeGroupIds(core *Core) error {
+	nodeGroups := core.NodeGroups()
+	if len(nodeGroups) == 0 {
+		return fmt.Errorf("no node groups to check")
+	}
+	var errs []error
+	seen := make(map[string]bool, len(nodeGroups))
+	for _, nodeGroup := range nodeGroups {
+		id := nodeGroup.Id()
+		if id == "" {
+			errs = append(errs, fmt.Errorf("node group with empty id: %s", nodeGroup.Debug()))
+		} else if seen[id] {
+			errs = append(errs, fmt.Errorf("duplicate node group id %s", id))
+		}
+		seen[id] = true
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// CheckSizeLimits checks that the node group exists and that its size limits and target size are consistent.
+func CheckSizeLimits(core *Core, id string) error {
+	nodeGroup, err := core.NodeGroup(id)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	if !nodeGroup.Exist() {
+		errs = append(errs, fmt.Errorf("node group %s is listed but doesn't exist", id))
+	}
+	if nodeGroup.MinSize() < 0 {
+		errs = append(errs, fmt.Errorf("node group %s has negative min size %d", id, nodeGroup.MinSize()))
+	}
+	if nodeGroup.MinSize() > nodeGroup.MaxSize() {
+		errs = append(errs, fmt.Errorf("node group %s min size %d is above its max size %d", id, nodeGroup.MinSize(), nodeGroup.MaxSize()))
+	}
+	size, err := nodeGroup.TargetSize()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to get target size of node group %s: %v", id, err))
+	} else if size < 0 {
+		errs = append(errs, fmt.Errorf("node group %s has negative target size %d", id, size))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// CheckInstances checks that the instances of the node group have unique non-empty ids, and that the nodes
+// they register as are mapped back to the node group.
+func CheckInstances(core *Core, id string) error {
+	nodeGroup, err := core.NodeGroup(id)
+	if err != nil {
+		return err
+	}
+	instances, err := nodeGroup.Nodes()
+	if err != nil {
+		return fmt.Errorf("failed to list instances of node group %s: %v", id, err)
+	}
+	var errs []error
+	seen := make(map[string]bool, len(instances))
+	for _, instance := range instances {
+		if instance.Id == "" {
+			errs = append(errs, fmt.Errorf("node group %s has an instance with empty id", id))
+			continue
+		}
+		if seen[instance.Id] {
+			errs = append(errs, fmt.Errorf("node group %s lists instance %s twice", id, instance.Id))
+		}
+		seen[instance.Id] = true
+		if instance.Status != nil && instance.Status.State == cloudprovider.InstanceDeleting {
+			continue
+		}
+		node := core.Node(instance)
+		nodeGroupId, err := core.NodeGroupForNode(node)
+		if err != nil {
+			errs = append(errs, err)
+		} else if nodeGroupId != id {
+			errs = append(errs, fmt.Errorf("node %s of instance %s of node group %s is mapped to node group %q", node.Name, instance.Id, id, nodeGroupId))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// CheckTemplate checks that the template node of the node group, if implemented, is usable in scale-up
+// simulations.
+func CheckTemplate(core *Core, id string) error {
+	nodeGroup, err := core.NodeGroup(id)
+	if err != nil {
+		return err
+	}
+	nodeInfo, err := nodeGroup.TemplateNodeInfo()
+	if err == cloudprovider.ErrNotImplemented {
+		return Skipf("node group %s has no template", id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get template of node group %s: %v", id, err)
+	}
+	if nodeInfo == nil || nodeInfo.Node() == nil {
+		return fmt.Errorf("node group %s template has no node", id)
+	}
+	node := nodeInfo.Node()
+	var errs []error
+	if node.Name == "" {
+		errs = append(errs, fmt.Errorf("node group %s template node has no name", id))
+	}
+	for _, resourceName := range []apiv1.ResourceName{apiv1.ResourceCPU, apiv1.ResourceMemory} {
+		capacity, found := node.Status.Capacity[resourceName]
+		if !found || capacity.Sign() <= 0 {
+			errs = append(errs, fmt.Errorf("node group %s template node has no %s capacity", id, resourceName))
+			continue
+		}
+		allocatable, found := node.Status.Allocatable[resourceName]
+		if !found || allocatable.Sign() <= 0 {
+			errs = append(errs, fmt.Errorf("node group %s template node has no allocatable %s", id, resourceName))
+		} else if allocatable.Cmp(capacity) > 0 {
+			errs = append(errs, fmt.Errorf("node group %s template node allocatable %s %s is above its capacity %s", id, resourceName, allocatable.String(), capacity.String()))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// CheckInvalidResizes checks that the node group rejects resizes the core never requests, without changing
+// its target size: non-positive increases, increases above the max size and positive decreases.
+func CheckInvalidResizes(core *Core, id string) error {
+	nodeGroup, err := core.NodeGroup(id)
+	if err != nil {
+		return err
+	}
+	size, err := nodeGroup.TargetSize()
+	if err != nil {
+		return fmt.Errorf("failed to get target size of node group %s: %v", id, err)
+	}
+	var errs []error
+	if err := nodeGroup.IncreaseSize(0); err == nil {
+		errs = append(errs, fmt.Errorf("node group %s accepted a size increase of 0", id))
+	}
+	if delta := nodeGroup.MaxSize() - size + 1; nodeGroup.IncreaseSize(delta) == nil {
+		errs = append(errs, fmt.Errorf("node group %s accepted a size increase by %d above its max size %d", id, delta, nodeGroup.MaxSize()))
+	}
+	if err := nodeGroup.DecreaseTargetSize(1); err == nil {
+		errs = append(errs, fmt.Errorf("node group %s accepted a positive target size decrease", id))
+	}
+	if current, err := core.TargetSize(id); err != nil {
+		errs = append(errs, err)
+	} else if current != size {
+		errs = append(errs, fmt.Errorf("node group %s target size changed from %d to %d after invalid resizes", id, size, current))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// CheckDeleteForeignNode checks that the node group refuses to delete a node that doesn't belong to it,
+// without changing its target size.
+func CheckDeleteForeignNode(core *Core, id string) error {
+	nodeGroup, err := core.NodeGroup(id)
+	if err != nil {
+		return err
+	}
+	size, err := nodeGroup.TargetSize()
+	if err != nil {
+		return fmt.Errorf("failed to get target size of node group %s: %v", id, err)
+	}
+	var errs []error
+	node := core.Node(cloudprovider.Instance{Id: foreignInstanceId})
+	if err := nodeGroup.DeleteNodes([]*apiv1.Node{node}); err == nil {
+		errs = append(errs, fmt.Errorf("node group %s accepted to delete node %s, not in the node group", id, node.Name))
+	}
+	if current, err := core.TargetSize(id); err != nil {
+		errs = append(errs, err)
+	} else if current != size {
+		errs = append(errs, fmt.Errorf("node group %s target size changed from %d to %d after deleting a node not in the node group", id, size, current))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// CheckScaleUpAndDelete scales the node group up by one instance and deletes it, as the core does for an
+// unneeded node. It checks that the target size follows, and that deleting the node again doesn't decrease
+// the target size a second time.
+func CheckScaleUpAndDelete(core *Core, id string) error {
+	nodeGroup, err := core.NodeGroup(id)
+	if err != nil {
+		return err
+	}
+	size, err := nodeGroup.TargetSize()
+	if err != nil {
+		return fmt.Errorf("failed to get target size of node group %s: %v", id, err)
+	}
+	if size >= nodeGroup.MaxSize() {
+		return Skipf("node group %s is at its max size %d", id, nodeGroup.MaxSize())
+	}
+	if size < nodeGroup.MinSize() {
+		return Skipf("node group %s is below its min size %d", id, nodeGroup.MinSize())
+	}
+	instances, err := nodeGroup.Nodes()
+	if err != nil {
+		return fmt.Errorf("failed to list instances of node group %s: %v", id, err)
+	}
+	existing := make(map[string]bool, len(instances))
+	for _, instance := range instances {
+		existing[instance.Id] = true
+	}
+
+	if err := nodeGroup.IncreaseSize(1); err != nil {
+		return fmt.Errorf("failed to increase size of node group %s: %v", id, err)
+	}
+	if err := core.WaitForTargetSize(id, size+1); err != nil {
+		return err
+	}
+	instance, err := core.WaitForNewInstance(id, existing)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	node := core.Node(instance)
+	if nodeGroupId, err := core.NodeGroupForNode(node); err != nil {
+		errs = append(errs, err)
+	} else if nodeGroupId != id {
+		errs = append(errs, fmt.Errorf("new node %s of node group %s is mapped to node group %q", node.Name, id, nodeGroupId))
+	}
+	if nodeGroup, err = core.NodeGroup(id); err != nil {
+		return utilerrors.NewAggregate(append(errs, err))
+	}
+	if err := nodeGroup.DeleteNodes([]*apiv1.Node{node}); err != nil {
+		return utilerrors.NewAggregate(append(errs, fmt.Errorf("failed to delete node %s of node group %s: %v", node.Name, id, err)))
+	}
+	if err := core.WaitForTargetSize(id, size); err != nil {
+		return utilerrors.NewAggregate(append(errs, err))
+	}
+
+	// The core may retry deleting a node, e.g. after a failed drain or a restart.
+	if nodeGroup, err = core.NodeGroup(id); err != nil {
+		return utilerrors.NewAggregate(append(errs, err))
+	}
+	_ = nodeGroup.DeleteNodes([]*apiv1.Node{node})
+	if current, err := core.TargetSize(id); err != nil {
+		errs = append(errs, err)
+	} else if current != size {
+		errs = append(errs, fmt.Errorf("node group %s target size changed from %d to %d after deleting node %s twice", id, size, current, node.Name))
+	}
+	return utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conformance
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// fakeProvider is an in-memory provider. It is conformant unless broken is set, in which case its node
+// groups don't validate resizes and deletions.
+type fakeProvider struct {
+	cloudprovider.CloudProvider
+	groups []*fakeNodeGroup
+	broken bool
+}
+
+func (p *fakeProvider) Refresh() error {
+	return nil
+}
+
+func (p *fakeProvider) NodeGroups() []cloudprovider.NodeGroup {
+	var result []cloudprovider.NodeGroup
+	for _, group := range p.groups {
+		result = append(result, group)
+	}
+	return result
+}
+
+func (p *fakeProvider) NodeGroupForNode(node *apiv1.Node) (cloudprovider.NodeGroup, error) {
+	for _, group := range p.groups {
+		if group.instances[node.Spec.ProviderID] {
+			return group, nil
+		}
+	}
+	return nil, nil
+}
+
+type fakeNodeGroup struct {
+	cloudprovider.NodeGroup
+	provider  *fakeProvider
+	id        string
+	min, max  int
+	instances map[string]bool
+	target    int
+	created   int
+}
+
+func (p *fakeProvider) addNodeGroup(id string, min, max, size int) {
+	group := &fakeNodeGroup{provider: p, id: id, min: min, max: max, instances: make(map[string]bool)}
+	p.groups = append(p.groups, group)
+	group.IncreaseSize(size)
+}
+
+func (g *fakeNodeGroup) Id() string {
+	return g.id
+}
+
+func (g *fakeNodeGroup) MinSize() int {
+	return g.min
+}
+
+func (g *fakeNodeGroup) MaxSize() int {
+	return g.max
+}
+
+func (g *fakeNodeGroup) Exist() bool {
+	return true
+}
+
+func (g *fakeNodeGroup) TargetSize() (int, error) {
+	return g.target, nil
+}
+
+func (g *fakeNodeGroup) IncreaseSize(delta int) error {
+	if !g.provider.broken && (delta <= 0 || g.target+delta > g.max) {
+		return fmt.Errorf("invalid size increase %d", delta)
+	}
+	for i := 0; i < delta; i++ {
+		g.created++
+		g.instances[fmt.Sprintf("%s-%d", g.id, g.created)] = true
+	}
+	g.target += delta
+	return nil
+}
+
+func (g *fakeNodeGroup) DecreaseTargetSize(delta int) error {
+	if !g.provider.broken && (delta >= 0 || g.target+delta < len(g.instances)) {
+		return fmt.Errorf("invalid target size decrease %d", delta)
+	}
+	g.target += delta
+	return nil
+}
+
+func (g *fakeNodeGroup) DeleteNodes(nodes []*apiv1.Node) error {
+	for _, node := range nodes {
+		if !g.instances[node.Spec.ProviderID] && !g.provider.broken {
+			return fmt.Errorf("node %s doesn't belong to %s", node.Name, g.id)
+		}
+		delete(g.instances, node.Spec.ProviderID)
+		g.target--
+	}
+	return nil
+}
+
+func (g *fakeNodeGroup) Nodes() ([]cloudprovider.Instance, error) {
+	var result []cloudprovider.Instance
+	for id := range g.instances {
+		result = append(result, cloudprovider.Instance{Id: id, Status: &cloudprovider.InstanceStatus{State: cloudprovider.InstanceRunning}})
+	}
+	return result, nil
+}
+
+func (g *fakeNodeGroup) TemplateNodeInfo() (*schedulerframework.NodeInfo, error) {
+	nodeInfo := schedulerframework.NewNodeInfo()
+	nodeInfo.SetNode(BuildTestNode(g.id+"-template", 1000, 1000))
+	return nodeInfo, nil
+}
+
+func newFakeProvider(broken bool) *fakeProvider {
+	provider := &fakeProvider{broken: broken}
+	provider.addNodeGroup("ng1", 0, 3, 1)
+	provider.addNodeGroup("ng2", 1, 2, 2)
+	return provider
+}
+
+func TestRun(t *testing.T) {
+	Run(t, Config{Provider: newFakeProvider(false), AllowResize: true, Timeout: time.Second, PollInterval: time.Millisecond})
+}
+
+func TestChecks(t *testing.T) {
+	for _, tc := range []struct {
+		desc      string
+		broken    bool
+		check     func(core *Core, id string) error
+		id        string
+		expectErr bool
+		skipped   bool
+	}{
+		{desc: "size limits", check: CheckSizeLimits, id: "ng1"},
+		{desc: "instances", check: CheckInstances, id: "ng2"},
+		{desc: "template", check: CheckTemplate, id: "ng1"},
+		{desc: "invalid resizes", check: CheckInvalidResizes, id: "ng1"},
+		{desc: "invalid resizes accepted", broken: true, check: CheckInvalidResizes, id: "ng1", expectErr: true},
+		{desc: "foreign node", check: CheckDeleteForeignNode, id: "ng1"},
+		{desc: "foreign node deleted", broken: true, check: CheckDeleteForeignNode, id: "ng1", expectErr: true},
+		{desc: "scale up and delete", check: CheckScaleUpAndDelete, id: "ng1"},
+		{desc: "node deleted twice", broken: true, check: CheckScaleUpAndDelete, id: "ng1", expectErr: true},
+		{desc: "scale up at max size", check: CheckScaleUpAndDelete, id: "ng2", skipped: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			provider := newFakeProvider(tc.broken)
+			core := NewCore(Config{Provider: provider, Timeout: time.Second, PollInterval: time.Millisecond})
+			err := tc.check(core, tc.id)
+			_, skipped := err.(skipError)
+			assert.Equal(t, tc.skipped, skipped)
+			if tc.expectErr {
+				assert.Error(t, err)
+			} else if !tc.skipped {
+				assert.NoError(t, err)
+			}
+			if !tc.broken {
+				// Checks leave the node groups at their original target size.
+				size, err := core.TargetSize(tc.id)
+				assert.NoError(t, err)
+				assert.Equal(t, map[string]int{"ng1": 1, "ng2": 2}[tc.id], size)
+			}
+		})
+	}
+}
+
+func TestNodeGroupIds(t *testing.T) {
+	provider := newFakeProvider(false)
+	assert.NoError(t, CheckNodeGroupIds(NewCore(Config{Provider: provider})))
+	assert.NoError(t, CheckNodeGroupIds(NewCore(Config{Provider: provider, NodeGroups: []string{"ng2"}})))
+	assert.Error(t, CheckNodeGroupIds(NewCore(Config{Provider: provider, NodeGroups: []string{"ng3"}})))
+
+	provider.addNodeGroup("ng1", 0, 1, 0)
+	assert.Error(t, CheckNodeGroupIds(NewCore(Config{Provider: provider})))
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conformance
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+)
+
+const (
+	defaultTimeout      = 10 * time.Minute
+	defaultPollInterval = 5 * time.Second
+)
+
+// Config configures a conformance run against a cloud provider.
+type Config struct {
+	// Provider is the cloud provider under test.
+	Provider cloudprovider.CloudProvider
+	// NodeGroups are the ids of the node groups to check. All the node groups of the provider are checked if empty.
+	NodeGroups []string
+	// AllowResize enables the checks that resize node groups. They restore the original target sizes, but
+	// create and delete instances on the way.
+	AllowResize bool
+	// NodeForInstance builds the node an instance registers as. Defaults to a node named after the instance,
+	// with the instance id as provider ID.
+	NodeForInstance func(instance cloudprovider.Instance) *apiv1.Node
+	// Timeout bounds how long a check waits for the provider to reflect a change. Defaults to 10 minutes.
+	Timeout time.Duration
+	// PollInterval is how often the provider is refreshed while waiting. Defaults to 5 seconds.
+	PollInterval time.Duration
+}
+
+// Core plays the part of the autoscaler core for the checks: it refreshes the provider before reading node
+// groups, and looks node groups up again by id after each refresh, as the core does in each loop.
+type Core struct {
+	config Config
+}
+
+// NewCore returns a Core for the config, with defaults set.
+func NewCore(config Config) *Core {
+	if config.NodeForInstance == nil {
+		config.NodeForInstance = DefaultNodeForInstance
+	}
+	if config.Timeout == 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.PollInterval == 0 {
+		config.PollInterval = defaultPollInterval
+	}
+	return &Core{config: config}
+}
+
+// DefaultNodeForInstance returns a node named after the instance, with the instance id as provider ID.
+func DefaultNodeForInstance(instance cloudprovider.Instance) *apiv1.Node {
+	return &apiv1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: instance.Id},
+		Spec:       apiv1.NodeSpec{ProviderID: instance.Id},
+	}
+}
+
+// Refresh refreshes the provider, as done at the beginning of each autoscaler loop.
+func (c *Core) Refresh() error {
+	if err := c.config.Provider.Refresh(); err != nil {
+		return fmt.Errorf("failed to refresh provider: %v", err)
+	}
+	return nil
+}
+
+// NodeGroups returns the node groups to check.
+func (c *Core) NodeGroups() []cloudprovider.NodeGroup {
+	nodeGroups := c.config.Provider.NodeGroups()
+	if len(c.config.NodeGroups) == 0 {
+		return nodeGroups
+	}
+	ids := make(map[string]bool, len(c.config.NodeGroups))
+	for _, id := range c.config.NodeGroups {
+		ids[id] = true
+	}
+	var result []cloudprovider.NodeGroup
+	for _, nodeGroup := range nodeGroups {
+		if ids[nodeGroup.Id()] {
+			result = append(result, nodeGroup)
+		}
+	}
+	return result
+}
+
+// NodeGroup refreshes the provider and returns its node group with the given id.
+func (c *Core) NodeGroup(id string) (cloudprovider.NodeGroup, error) {
+	if err := c.Refresh(); err != nil {
+		return nil, err
+	}
+	for _, nodeGroup := range c.config.Provider.NodeGroups() {
+		if nodeGroup.Id() == id {
+			return nodeGroup, nil
+		}
+	}
+	return nil, fmt.Errorf("node group %s not found after refresh", id)
+}
+
+// TargetSize refreshes the provider and returns the target size of the node group with the given id.
+func (c *Core) TargetSize(id string) (int, error) {
+	nodeGroup, err := c.NodeGroup(id)
+	if err != nil {
+		return 0, err
+	}
+	size, err := nodeGroup.TargetSize()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get target size of node group %s: %v", id, err)
+	}
+	return size, nil
+}
+
+// Node returns the node the instance registers as.
+func (c *Core) Node(instance cloudprovider.Instance) *apiv1.Node {
+	return c.config.NodeForInstance(instance)
+}
+
+// NodeGroupForNode returns the id of the node group of the node, or an empty string if the node doesn't
+// belong to any node group.
+func (c *Core) NodeGroupForNode(node *apiv1.Node) (string, error) {
+	nodeGroup, err := c.config.Provider.NodeGroupForNode(node)
+	if err != nil {
+		return "", fmt.Errorf("failed to get node group for node %s: %v", node.Name, err)
+	}
+	if nodeGroup == nil || reflect.ValueOf(nodeGroup).IsNil() {
+		return "", nil
+	}
+	return nodeGroup.Id(), nil
+}
+
+// WaitForTargetSize waits for the node group with the given id to report the given target size.
+func (c *Core) WaitForTargetSize(id string, size int) error {
+	var current int
+	err := c.poll(func() (bool, error) {
+		var err error
+		current, err = c.TargetSize(id)
+		return current == size, err
+	})
+	if err != nil {
+		return fmt.Errorf("node group %s target size is %d, expected %d: %v", id, current, size, err)
+	}
+	return nil
+}
+
+// WaitForNewInstance waits for a running instance of the node group with the given id that is not in
+// existing, and returns it.
+func (c *Core) WaitForNewInstance(id string, existing map[string]bool) (cloudprovider.Instance, error) {
+	var found cloudprovider.Instance
+	err := c.poll(func() (bool, error) {
+		nodeGroup, err := c.NodeGroup(id)
+		if err != nil {
+			return false, err
+		}
+		instances, err := nodeGroup.Nodes()
+		if err != nil {
+			return false, fmt.Errorf("failed to list instances of node group %s: %v", id, err)
+		}
+		for _, instance := range instances {
+			if !existing[instance.Id] && (instance.Status == nil || instance.Status.State == cloudprovider.InstanceRunning) {
+				found = instance
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return found, fmt.Errorf("no new instance running in node group %s: %v", id, err)
+	}
+	return found, nil
+}
+
+// poll calls condition until it returns true or an error, or the timeout expires.
+func (c *Core) poll(condition func() (bool, error)) error {
+	deadline := time.Now().Add(c.config.Timeout)
+	for {
+		done, err := condition()
+		if err != nil || done {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %v", c.config.Timeout)
+		}
+		time.Sleep(c.config.PollInterval)
+	}
+}
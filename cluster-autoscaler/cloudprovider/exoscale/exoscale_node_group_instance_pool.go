@@ -104,10 +104,22 @@ func (n *instancePoolNodeGroup) DeleteNodes(nodes []*apiv1.Node) error {
 		return err
 	}
 
+	members := make(map[string]bool)
+	if n.instancePool.InstanceIDs != nil {
+		for _, id := range *n.instancePool.InstanceIDs {
+			members[id] = true
+		}
+	}
+
 	instanceIDs := make([]string, len(nodes))
 	for i, node := range nodes {
 		instanceIDs[i] = toNodeID(node.Spec.ProviderID)
+		if !members[instanceIDs[i]] {
+			// The node may have been evicted already, evicting it again would shrink the Instance Pool twice.
+			return fmt.Errorf("node %s is not a member of Instance Pool %s", node.Name, *n.instancePool.ID)
+		}
 	}
+	newSize := *n.instancePool.Size - int64(len(instanceIDs))
 
 	infof("evicting Instance Pool %s members: %v", *n.instancePool.ID, instanceIDs)
 
@@ -120,18 +132,26 @@ func (n *instancePoolNodeGroup) DeleteNodes(nodes []*apiv1.Node) error {
 		return err
 	}
 
-	newSize := *n.instancePool.Size - int64(len(instanceIDs))
 	n.instancePool.Size = &newSize
 
 	return nil
 }
 
+// ForceDeleteNodes deletes nodes from the group regardless of constraints.
+func (n *instancePoolNodeGroup) ForceDeleteNodes(nodes []*apiv1.Node) error {
+	return cloudprovider.ErrNotImplemented
+}
+
 // DecreaseTargetSize decreases the target size of the node group. This function
 // doesn't permit to delete any existing node and can be used only to reduce the
 // request for new nodes that have not been yet fulfilled. Delta should be negative.
 // It is assumed that cloud provider will not delete the existing nodes when there
 // is an option to just decrease the target. Implementation required.
-func (n *instancePoolNodeGroup) DecreaseTargetSize(_ int) error {
+func (n *instancePoolNodeGroup) DecreaseTargetSize(delta int) error {
+	if delta >= 0 {
+		return fmt.Errorf("delta must be negative, have: %d", delta)
+	}
+
 	// Exoscale Instance Pools don't support down-sizing without deleting members,
 	// so it is not possible to implement it according to the documented behavior.
 	return nil
@@ -214,6 +234,7 @@ func (n *instancePoolNodeGroup) waitUntilRunning(ctx context.Context) error {
 		}
 
 		if *instancePool.State == "running" {
+			n.instancePool = instancePool
 			return true, nil
 		}
 
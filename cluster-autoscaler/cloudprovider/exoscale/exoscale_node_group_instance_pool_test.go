@@ -128,10 +128,11 @@ func (ts *cloudProviderTestSuite) TestInstancePoolNodeGroup_DeleteNodes() {
 	ts.p.manager.client.(*exoscaleClientMock).
 		On("GetInstancePool", ts.p.manager.ctx, ts.p.manager.zone, testInstancePoolID).
 		Return(&egoscale.InstancePool{
-			ID:    &testInstancePoolID,
-			Name:  &testInstancePoolName,
-			Size:  &testInstancePoolSize,
-			State: &testInstancePoolState,
+			ID:          &testInstancePoolID,
+			InstanceIDs: &[]string{testInstanceID},
+			Name:        &testInstancePoolName,
+			Size:        &testInstancePoolSize,
+			State:       &testInstancePoolState,
 		}, nil)
 
 	node := &apiv1.Node{
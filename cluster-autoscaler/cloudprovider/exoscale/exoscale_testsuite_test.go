@@ -0,0 +1,156 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exoscale
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	egoscale "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/exoscale/internal/github.com/exoscale/egoscale/v2"
+	exoapi "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/exoscale/internal/github.com/exoscale/egoscale/v2/api"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/testsuite"
+)
+
+// fakeExoscaleClient is an in-memory exoscaleClient with a single standalone Instance Pool.
+type fakeExoscaleClient struct {
+	instancePool *egoscale.InstancePool
+	created      int
+}
+
+func newFakeExoscaleClient(size int) *fakeExoscaleClient {
+	c := &fakeExoscaleClient{
+		instancePool: &egoscale.InstancePool{
+			ID:          &testInstancePoolID,
+			Name:        &testInstancePoolName,
+			InstanceIDs: &[]string{},
+			Size:        new(int64),
+		},
+	}
+	c.addInstances(size)
+	return c
+}
+
+func (c *fakeExoscaleClient) addInstances(count int) {
+	for i := 0; i < count; i++ {
+		c.created++
+		*c.instancePool.InstanceIDs = append(*c.instancePool.InstanceIDs, fmt.Sprintf("instance-%d", c.created))
+		*c.instancePool.Size++
+	}
+}
+
+func (c *fakeExoscaleClient) EvictInstancePoolMembers(_ context.Context, _ string, _ *egoscale.InstancePool, members []string) error {
+	evicted := make(map[string]bool, len(members))
+	for _, id := range members {
+		evicted[id] = true
+	}
+	var instanceIDs []string
+	for _, id := range *c.instancePool.InstanceIDs {
+		if !evicted[id] {
+			instanceIDs = append(instanceIDs, id)
+		}
+	}
+	*c.instancePool.Size -= int64(len(*c.instancePool.InstanceIDs) - len(instanceIDs))
+	*c.instancePool.InstanceIDs = instanceIDs
+	return nil
+}
+
+func (c *fakeExoscaleClient) EvictSKSNodepoolMembers(_ context.Context, _ string, _ *egoscale.SKSCluster, _ *egoscale.SKSNodepool, _ []string) error {
+	return errors.New("no SKS Nodepool")
+}
+
+func (c *fakeExoscaleClient) GetInstance(_ context.Context, _, id string) (*egoscale.Instance, error) {
+	for _, instanceID := range *c.instancePool.InstanceIDs {
+		if instanceID == id {
+			return &egoscale.Instance{
+				ID:      &id,
+				Manager: &egoscale.InstanceManager{ID: *c.instancePool.ID, Type: "instance-pool"},
+				State:   &testInstanceState,
+			}, nil
+		}
+	}
+	return nil, exoapi.ErrNotFound
+}
+
+func (c *fakeExoscaleClient) GetInstancePool(_ context.Context, _, id string) (*egoscale.InstancePool, error) {
+	if id != *c.instancePool.ID {
+		return nil, exoapi.ErrNotFound
+	}
+	size := *c.instancePool.Size
+	instanceIDs := append([]string{}, *c.instancePool.InstanceIDs...)
+	return &egoscale.InstancePool{
+		ID:          c.instancePool.ID,
+		Name:        c.instancePool.Name,
+		InstanceIDs: &instanceIDs,
+		Size:        &size,
+		State:       &testInstancePoolState,
+	}, nil
+}
+
+func (c *fakeExoscaleClient) GetQuota(_ context.Context, _ string, resource string) (*egoscale.Quota, error) {
+	limit := int64(5)
+	usage := int64(len(*c.instancePool.InstanceIDs))
+	return &egoscale.Quota{Resource: &resource, Limit: &limit, Usage: &usage}, nil
+}
+
+func (c *fakeExoscaleClient) ListSKSClusters(_ context.Context, _ string) ([]*egoscale.SKSCluster, error) {
+	return nil, nil
+}
+
+func (c *fakeExoscaleClient) ScaleInstancePool(_ context.Context, _ string, _ *egoscale.InstancePool, size int64) error {
+	c.addInstances(int(size - *c.instancePool.Size))
+	return nil
+}
+
+func (c *fakeExoscaleClient) ScaleSKSNodepool(_ context.Context, _ string, _ *egoscale.SKSCluster, _ *egoscale.SKSNodepool, _ int64) error {
+	return errors.New("no SKS Nodepool")
+}
+
+func TestInstancePoolNodeGroupSuite(t *testing.T) {
+	var nodes []*apiv1.Node
+	for _, id := range *newFakeExoscaleClient(2).instancePool.InstanceIDs {
+		nodes = append(nodes, &apiv1.Node{
+			ObjectMeta: v1.ObjectMeta{Name: id},
+			Spec:       apiv1.NodeSpec{ProviderID: toProviderID(id)},
+		})
+	}
+
+	testsuite.Suite{
+		NewProvider: func(t *testing.T) cloudprovider.CloudProvider {
+			t.Setenv("EXOSCALE_ZONE", testZone)
+			t.Setenv("EXOSCALE_API_KEY", "x")
+			t.Setenv("EXOSCALE_API_SECRET", "x")
+
+			manager, err := newManager()
+			if err != nil {
+				t.Fatalf("error initializing cloud provider manager: %v", err)
+			}
+			manager.client = newFakeExoscaleClient(2)
+
+			provider, err := newExoscaleCloudProvider(manager, &cloudprovider.ResourceLimiter{})
+			if err != nil {
+				t.Fatalf("error initializing cloud provider: %v", err)
+			}
+			return provider
+		},
+		Nodes: nodes,
+	}.Run(t)
+}
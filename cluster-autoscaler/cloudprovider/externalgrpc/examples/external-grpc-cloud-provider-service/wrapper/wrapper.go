@@ -358,11 +358,14 @@ func (w *Wrapper) NodeGroupGetOptions(_ context.Context, req *protos.NodeGroupAu
 		return nil, fmt.Errorf("request fields were nil")
 	}
 	defaults := config.NodeGroupAutoscalingOptions{
-		ScaleDownUtilizationThreshold:    pbDefaults.GetScaleDownGpuUtilizationThreshold(),
+		ScaleDownUtilizationThreshold:    pbDefaults.GetScaleDownUtilizationThreshold(),
 		ScaleDownGpuUtilizationThreshold: pbDefaults.GetScaleDownGpuUtilizationThreshold(),
 		ScaleDownUnneededTime:            pbDefaults.GetScaleDownUnneededTime().Duration,
-		ScaleDownUnreadyTime:             pbDefaults.GetScaleDownUnneededTime().Duration,
+		ScaleDownUnreadyTime:             pbDefaults.GetScaleDownUnreadyTime().Duration,
 		MaxNodeProvisionTime:             pbDefaults.GetMaxNodeProvisionTime().Duration,
+		ZeroOrMaxNodeScaling:             pbDefaults.GetZeroOrMaxNodeScaling(),
+		IgnoreDaemonSetsUtilization:      pbDefaults.GetIgnoreDaemonSetsUtilization(),
+		RecreationCost:                   pbDefaults.GetRecreationCost(),
 	}
 	opts, err := ng.GetOptions(defaults)
 	if err != nil {
@@ -387,6 +390,9 @@ func (w *Wrapper) NodeGroupGetOptions(_ context.Context, req *protos.NodeGroupAu
 			MaxNodeProvisionTime: &metav1.Duration{
 				Duration: opts.MaxNodeProvisionTime,
 			},
+			ZeroOrMaxNodeScaling:        &opts.ZeroOrMaxNodeScaling,
+			IgnoreDaemonSetsUtilization: &opts.IgnoreDaemonSetsUtilization,
+			RecreationCost:              &opts.RecreationCost,
 		},
 	}, nil
 }
@@ -18,8 +18,6 @@ package externalgrpc
 
 import (
 	"context"
-	"crypto/tls"
-	"crypto/x509"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -333,10 +331,11 @@ func BuildExternalGrpc(
 
 // cloudConfig is the struct hoding the configs to connect to the external cluster autoscaler provider service.
 type cloudConfig struct {
-	Address string `yaml:"address"` // external cluster autoscaler provider address of the form "host:port", "host%zone:port", "[host]:port" or "[host%zone]:port"
-	Key     string `yaml:"key"`     // path to file containing the tls key
-	Cert    string `yaml:"cert"`    // path to file containing the tls certificate
-	Cacert  string `yaml:"cacert"`  // path to file containing the CA certificate
+	Address   string `yaml:"address"`   // external cluster autoscaler provider address of the form "host:port", "host%zone:port", "[host]:port" or "[host%zone]:port"
+	Key       string `yaml:"key"`       // path to file containing the tls key
+	Cert      string `yaml:"cert"`      // path to file containing the tls certificate
+	Cacert    string `yaml:"cacert"`    // path to file containing the CA certificate
+	TokenFile string `yaml:"tokenFile"` // path to file containing the bearer token sent on each call
 }
 
 func newExternalGrpcCloudProviderClient(config []byte) (protos.CloudProviderClient, error) {
@@ -349,40 +348,34 @@ func newExternalGrpcCloudProviderClient(config []byte) (protos.CloudProviderClie
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse address: %v", err)
 	}
-	var dialOpt grpc.DialOption
-	if len(yamlConfig.Cert) == 0 {
+	var dialOpts []grpc.DialOption
+	if len(yamlConfig.Cert) == 0 && len(yamlConfig.Key) == 0 && len(yamlConfig.Cacert) == 0 {
+		if len(yamlConfig.TokenFile) != 0 {
+			return nil, fmt.Errorf("token authentication requires TLS, please specify cacert")
+		}
 		klog.V(5).Info("No certs specified in external gRPC provider config, using insecure mode")
-		dialOpt = grpc.WithInsecure()
+		dialOpts = append(dialOpts, grpc.WithInsecure())
 	} else {
-		certFile, err := ioutil.ReadFile(yamlConfig.Cert)
-		if err != nil {
-			return nil, fmt.Errorf("could not open Cert configuration file %q: %v", yamlConfig.Cert, err)
-		}
-		keyFile, err := ioutil.ReadFile(yamlConfig.Key)
-		if err != nil {
-			return nil, fmt.Errorf("could not open Key configuration file %q: %v", yamlConfig.Key, err)
+		files := &tlsFiles{
+			serverName: host,
+			cert:       yamlConfig.Cert,
+			key:        yamlConfig.Key,
+			cacert:     yamlConfig.Cacert,
 		}
-		cacertFile, err := ioutil.ReadFile(yamlConfig.Cacert)
+		tlsConfig, err := files.tlsConfig()
 		if err != nil {
-			return nil, fmt.Errorf("could not open Cacert configuration file %q: %v", yamlConfig.Cacert, err)
+			return nil, err
 		}
-		cert, err := tls.X509KeyPair(certFile, keyFile)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse cert key pair: %v", err)
-		}
-		certPool := x509.NewCertPool()
-		ok := certPool.AppendCertsFromPEM(cacertFile)
-		if !ok {
-			return nil, fmt.Errorf("failed to parse ca: %v", err)
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	}
+	if len(yamlConfig.TokenFile) != 0 {
+		token := &tokenFile{path: yamlConfig.TokenFile}
+		if _, err := token.read(); err != nil {
+			return nil, err
 		}
-		transportCreds := credentials.NewTLS(&tls.Config{
-			ServerName:   host,
-			Certificates: []tls.Certificate{cert},
-			RootCAs:      certPool,
-		})
-		dialOpt = grpc.WithTransportCredentials(transportCreds)
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(token))
 	}
-	conn, err := grpc.Dial(yamlConfig.Address, dialOpt)
+	conn, err := grpc.Dial(yamlConfig.Address, dialOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to dial server: %v", err)
 	}
@@ -0,0 +1,163 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externalgrpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tlsFiles are the files of the TLS configuration of the connection to the external gRPC cloud provider service.
+// They are read on each TLS handshake, so that rotated certificates are used for the next connections without
+// restarting the autoscaler.
+type tlsFiles struct {
+	serverName string
+	cert       string // client certificate, only set when using mTLS
+	key        string // client key, only set when using mTLS
+	cacert     string // CA certificate of the server, system roots are used when not set
+}
+
+// tlsConfig returns a TLS config using the files, after checking that they can be loaded.
+func (f *tlsFiles) tlsConfig() (*tls.Config, error) {
+	if (len(f.cert) == 0) != (len(f.key) == 0) {
+		return nil, fmt.Errorf("cert and key must be specified together")
+	}
+	config := &tls.Config{
+		ServerName: f.serverName,
+		// The server certificate is verified by verifyConnection, against the current CA certificate.
+		InsecureSkipVerify: true,
+		VerifyConnection:   f.verifyConnection,
+	}
+	if len(f.cert) != 0 {
+		if _, err := f.clientCertificate(nil); err != nil {
+			return nil, err
+		}
+		config.GetClientCertificate = f.clientCertificate
+	}
+	if _, err := f.rootCAs(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (f *tlsFiles) clientCertificate(_ *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	certFile, err := ioutil.ReadFile(f.cert)
+	if err != nil {
+		return nil, fmt.Errorf("could not open Cert configuration file %q: %v", f.cert, err)
+	}
+	keyFile, err := ioutil.ReadFile(f.key)
+	if err != nil {
+		return nil, fmt.Errorf("could not open Key configuration file %q: %v", f.key, err)
+	}
+	cert, err := tls.X509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cert key pair: %v", err)
+	}
+	return &cert, nil
+}
+
+// rootCAs returns the CA certificates of the server, nil for the system roots.
+func (f *tlsFiles) rootCAs() (*x509.CertPool, error) {
+	if len(f.cacert) == 0 {
+		return nil, nil
+	}
+	cacertFile, err := ioutil.ReadFile(f.cacert)
+	if err != nil {
+		return nil, fmt.Errorf("could not open Cacert configuration file %q: %v", f.cacert, err)
+	}
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(cacertFile) {
+		return nil, fmt.Errorf("failed to parse ca from %q", f.cacert)
+	}
+	return certPool, nil
+}
+
+func (f *tlsFiles) verifyConnection(state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return fmt.Errorf("no server certificate")
+	}
+	roots, err := f.rootCAs()
+	if err != nil {
+		return err
+	}
+	opts := x509.VerifyOptions{
+		DNSName:       f.serverName,
+		Roots:         roots,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range state.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err = state.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// tokenFile authenticates each call to the external gRPC cloud provider service with the bearer token of a file.
+// The file is read again whenever it is modified, so that rotated tokens are used without restarting the
+// autoscaler.
+type tokenFile struct {
+	path string
+
+	mutex   sync.Mutex
+	modTime time.Time
+	token   string
+}
+
+// GetRequestMetadata returns the authorization header of a call.
+func (t *tokenFile) GetRequestMetadata(_ context.Context, _ ...string) (map[string]string, error) {
+	token, err := t.read()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"authorization": "Bearer " + token}, nil
+}
+
+// RequireTransportSecurity returns true, bearer tokens must not be sent in clear text.
+func (t *tokenFile) RequireTransportSecurity() bool {
+	return true
+}
+
+func (t *tokenFile) read() (string, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	info, err := os.Stat(t.path)
+	if err != nil {
+		return "", fmt.Errorf("could not open token file %q: %v", t.path, err)
+	}
+	if t.token != "" && info.ModTime().Equal(t.modTime) {
+		return t.token, nil
+	}
+	contents, err := ioutil.ReadFile(t.path)
+	if err != nil {
+		return "", fmt.Errorf("could not open token file %q: %v", t.path, err)
+	}
+	token := strings.TrimSpace(string(contents))
+	if token == "" {
+		return "", fmt.Errorf("token file %q is empty", t.path)
+	}
+	t.token = token
+	t.modTime = info.ModTime()
+	return t.token, nil
+}
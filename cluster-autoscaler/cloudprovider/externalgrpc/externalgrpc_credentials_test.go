@@ -0,0 +1,223 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externalgrpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCert returns a certificate signed by parent, self-signed CA certificate if parent is nil.
+func newTestCert(t *testing.T, name string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func writeFile(t *testing.T, path string, contents []byte) {
+	require.NoError(t, ioutil.WriteFile(path, contents, 0600))
+}
+
+func TestTLSFiles(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "ca", nil)
+	otherCa := newTestCert(t, "other-ca", nil)
+	server := newTestCert(t, "server", ca)
+	client := newTestCert(t, "client", ca)
+	otherClient := newTestCert(t, "other-client", otherCa)
+
+	serverCert, err := tls.X509KeyPair(server.certPEM, server.keyPEM)
+	require.NoError(t, err)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	})
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	files := &tlsFiles{
+		serverName: "127.0.0.1",
+		cert:       filepath.Join(dir, "tls.crt"),
+		key:        filepath.Join(dir, "tls.key"),
+		cacert:     filepath.Join(dir, "ca.crt"),
+	}
+	writeFile(t, files.cert, client.certPEM)
+	writeFile(t, files.key, client.keyPEM)
+	writeFile(t, files.cacert, ca.certPEM)
+	config, err := files.tlsConfig()
+	require.NoError(t, err)
+
+	handshake := func() error {
+		conn, err := tls.Dial("tcp", listener.Addr().String(), config)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		// TLS 1.3 servers report client certificate errors after the handshake.
+		_, err = conn.Read(make([]byte, 1))
+		if err != nil && err != io.EOF {
+			return err
+		}
+		return nil
+	}
+	assert.NoError(t, handshake())
+
+	// A client certificate from another CA is rejected by the server.
+	writeFile(t, files.cert, otherClient.certPEM)
+	writeFile(t, files.key, otherClient.keyPEM)
+	assert.Error(t, handshake())
+	writeFile(t, files.cert, client.certPEM)
+	writeFile(t, files.key, client.keyPEM)
+	assert.NoError(t, handshake())
+
+	// The server certificate isn't trusted once the CA certificate is rotated.
+	writeFile(t, files.cacert, otherCa.certPEM)
+	assert.Error(t, handshake())
+}
+
+func TestTLSFilesErrors(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "ca", nil)
+	client := newTestCert(t, "client", ca)
+	writeFile(t, filepath.Join(dir, "tls.crt"), client.certPEM)
+	writeFile(t, filepath.Join(dir, "tls.key"), client.keyPEM)
+	writeFile(t, filepath.Join(dir, "ca.crt"), ca.certPEM)
+	writeFile(t, filepath.Join(dir, "invalid"), []byte("invalid"))
+
+	for _, tc := range []struct {
+		desc  string
+		files tlsFiles
+		valid bool
+	}{
+		{desc: "mTLS", files: tlsFiles{cert: "tls.crt", key: "tls.key", cacert: "ca.crt"}, valid: true},
+		{desc: "server TLS", files: tlsFiles{cacert: "ca.crt"}, valid: true},
+		{desc: "system roots", files: tlsFiles{cert: "tls.crt", key: "tls.key"}, valid: true},
+		{desc: "cert without key", files: tlsFiles{cert: "tls.crt", cacert: "ca.crt"}},
+		{desc: "key without cert", files: tlsFiles{key: "tls.key", cacert: "ca.crt"}},
+		{desc: "missing cacert", files: tlsFiles{cacert: "missing"}},
+		{desc: "invalid cacert", files: tlsFiles{cacert: "invalid"}},
+		{desc: "invalid key", files: tlsFiles{cert: "tls.crt", key: "invalid"}},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			files := tc.files
+			for _, path := range []*string{&files.cert, &files.key, &files.cacert} {
+				if *path != "" {
+					*path = filepath.Join(dir, *path)
+				}
+			}
+			_, err := files.tlsConfig()
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestTokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	token := &tokenFile{path: path}
+
+	_, err := token.GetRequestMetadata(context.Background())
+	assert.Error(t, err)
+
+	writeFile(t, path, []byte("token-1\n"))
+	metadata, err := token.GetRequestMetadata(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"authorization": "Bearer token-1"}, metadata)
+
+	// A rotated token is used once the file is modified.
+	writeFile(t, path, []byte("token-2"))
+	modTime := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+	metadata, err = token.GetRequestMetadata(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"authorization": "Bearer token-2"}, metadata)
+
+	writeFile(t, path, []byte(""))
+	modTime = modTime.Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+	_, err = token.GetRequestMetadata(context.Background())
+	assert.Error(t, err)
+
+	assert.True(t, token.RequireTransportSecurity())
+}
@@ -115,6 +115,11 @@ func (n *NodeGroup) DeleteNodes(nodes []*apiv1.Node) error {
 	return nil
 }
 
+// ForceDeleteNodes deletes nodes from the group regardless of constraints.
+func (n *NodeGroup) ForceDeleteNodes(nodes []*apiv1.Node) error {
+	return cloudprovider.ErrNotImplemented
+}
+
 // DecreaseTargetSize decreases the target size of the node group. This function
 // doesn't permit to delete any existing node and can be used only to reduce the
 // request for new nodes that have not been yet fulfilled. Delta should be negative.
@@ -259,20 +264,8 @@ func (n *NodeGroup) GetOptions(defaults config.NodeGroupAutoscalingOptions) (*co
 	defer cancel()
 	klog.V(5).Infof("Performing gRPC call NodeGroupGetOptions for node group %v", n.id)
 	res, err := n.client.NodeGroupGetOptions(ctx, &protos.NodeGroupAutoscalingOptionsRequest{
-		Id: n.id,
-		Defaults: &protos.NodeGroupAutoscalingOptions{
-			ScaleDownUtilizationThreshold:    defaults.ScaleDownUtilizationThreshold,
-			ScaleDownGpuUtilizationThreshold: defaults.ScaleDownGpuUtilizationThreshold,
-			ScaleDownUnneededTime: &metav1.Duration{
-				Duration: defaults.ScaleDownUnneededTime,
-			},
-			ScaleDownUnreadyTime: &metav1.Duration{
-				Duration: defaults.ScaleDownUnreadyTime,
-			},
-			MaxNodeProvisionTime: &metav1.Duration{
-				Duration: defaults.MaxNodeProvisionTime,
-			},
-		},
+		Id:       n.id,
+		Defaults: toProtoAutoscalingOptions(defaults),
 	})
 	if err != nil {
 		st, ok := status.FromError(err)
@@ -286,12 +279,47 @@ func (n *NodeGroup) GetOptions(defaults config.NodeGroupAutoscalingOptions) (*co
 	if pbOpts == nil {
 		return nil, nil
 	}
-	opts := &config.NodeGroupAutoscalingOptions{
-		ScaleDownUtilizationThreshold:    pbOpts.GetScaleDownUtilizationThreshold(),
-		ScaleDownGpuUtilizationThreshold: pbOpts.GetScaleDownGpuUtilizationThreshold(),
-		ScaleDownUnneededTime:            pbOpts.GetScaleDownUnneededTime().Duration,
-		ScaleDownUnreadyTime:             pbOpts.GetScaleDownUnreadyTime().Duration,
-		MaxNodeProvisionTime:             pbOpts.GetMaxNodeProvisionTime().Duration,
+	opts := fromProtoAutoscalingOptions(pbOpts, defaults)
+	return &opts, nil
+}
+
+// toProtoAutoscalingOptions converts autoscaling options to their protobuf message.
+func toProtoAutoscalingOptions(opts config.NodeGroupAutoscalingOptions) *protos.NodeGroupAutoscalingOptions {
+	return &protos.NodeGroupAutoscalingOptions{
+		ScaleDownUtilizationThreshold:    opts.ScaleDownUtilizationThreshold,
+		ScaleDownGpuUtilizationThreshold: opts.ScaleDownGpuUtilizationThreshold,
+		ScaleDownUnneededTime:            &metav1.Duration{Duration: opts.ScaleDownUnneededTime},
+		ScaleDownUnreadyTime:             &metav1.Duration{Duration: opts.ScaleDownUnreadyTime},
+		MaxNodeProvisionTime:             &metav1.Duration{Duration: opts.MaxNodeProvisionTime},
+		ZeroOrMaxNodeScaling:             &opts.ZeroOrMaxNodeScaling,
+		IgnoreDaemonSetsUtilization:      &opts.IgnoreDaemonSetsUtilization,
+		RecreationCost:                   &opts.RecreationCost,
+	}
+}
+
+// fromProtoAutoscalingOptions converts a protobuf message to autoscaling options. Durations and optional
+// fields left unset by the provider, e.g. because it predates them, keep their default values.
+func fromProtoAutoscalingOptions(pbOpts *protos.NodeGroupAutoscalingOptions, defaults config.NodeGroupAutoscalingOptions) config.NodeGroupAutoscalingOptions {
+	opts := defaults
+	opts.ScaleDownUtilizationThreshold = pbOpts.GetScaleDownUtilizationThreshold()
+	opts.ScaleDownGpuUtilizationThreshold = pbOpts.GetScaleDownGpuUtilizationThreshold()
+	if d := pbOpts.GetScaleDownUnneededTime(); d != nil {
+		opts.ScaleDownUnneededTime = d.Duration
+	}
+	if d := pbOpts.GetScaleDownUnreadyTime(); d != nil {
+		opts.ScaleDownUnreadyTime = d.Duration
+	}
+	if d := pbOpts.GetMaxNodeProvisionTime(); d != nil {
+		opts.MaxNodeProvisionTime = d.Duration
+	}
+	if pbOpts.ZeroOrMaxNodeScaling != nil {
+		opts.ZeroOrMaxNodeScaling = *pbOpts.ZeroOrMaxNodeScaling
+	}
+	if pbOpts.IgnoreDaemonSetsUtilization != nil {
+		opts.IgnoreDaemonSetsUtilization = *pbOpts.IgnoreDaemonSetsUtilization
+	}
+	if pbOpts.RecreationCost != nil {
+		opts.RecreationCost = *pbOpts.RecreationCost
 	}
-	return opts, nil
+	return opts
 }
@@ -238,6 +238,7 @@ func TestCloudProvider_GetOptions(t *testing.T) {
 	defer teardown()
 
 	// test correct call
+	zeroOrMaxNodeScaling, ignoreDaemonSetsUtilization, recreationCost := true, true, 2.0
 	m.On(
 		"NodeGroupGetOptions", mock.Anything, mock.MatchedBy(func(req *protos.NodeGroupAutoscalingOptionsRequest) bool {
 			return req.Id == "nodeGroup1"
@@ -250,6 +251,9 @@ func TestCloudProvider_GetOptions(t *testing.T) {
 				ScaleDownUnneededTime:            &v1.Duration{Duration: time.Minute},
 				ScaleDownUnreadyTime:             &v1.Duration{Duration: time.Hour},
 				MaxNodeProvisionTime:             &v1.Duration{Duration: time.Minute},
+				ZeroOrMaxNodeScaling:             &zeroOrMaxNodeScaling,
+				IgnoreDaemonSetsUtilization:      &ignoreDaemonSetsUtilization,
+				RecreationCost:                   &recreationCost,
 			},
 		},
 		nil,
@@ -274,6 +278,9 @@ func TestCloudProvider_GetOptions(t *testing.T) {
 	assert.Equal(t, time.Minute, opts.ScaleDownUnneededTime)
 	assert.Equal(t, time.Hour, opts.ScaleDownUnreadyTime)
 	assert.Equal(t, time.Minute, opts.MaxNodeProvisionTime)
+	assert.True(t, opts.ZeroOrMaxNodeScaling)
+	assert.True(t, opts.IgnoreDaemonSetsUtilization)
+	assert.Equal(t, 2.0, opts.RecreationCost)
 
 	// test grpc error
 	m.On(
@@ -332,6 +339,38 @@ func TestCloudProvider_GetOptions(t *testing.T) {
 	assert.Error(t, err)
 	assert.Equal(t, cloudprovider.ErrNotImplemented, err)
 
+	// test unset durations and optional fields keep their defaults
+	m.On(
+		"NodeGroupGetOptions", mock.Anything, mock.MatchedBy(func(req *protos.NodeGroupAutoscalingOptionsRequest) bool {
+			return req.Id == "nodeGroup5"
+		}),
+	).Return(
+		&protos.NodeGroupAutoscalingOptionsResponse{
+			NodeGroupAutoscalingOptions: &protos.NodeGroupAutoscalingOptions{
+				ScaleDownUtilizationThreshold: 0.3,
+				ScaleDownUnneededTime:         &v1.Duration{Duration: 2 * time.Minute},
+			},
+		},
+		nil,
+	)
+
+	ng5 := NodeGroup{
+		id:     "nodeGroup5",
+		client: client,
+	}
+
+	defaultsOpts.IgnoreDaemonSetsUtilization = true
+	defaultsOpts.RecreationCost = 1.5
+	opts, err = ng5.GetOptions(defaultsOpts)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.3, opts.ScaleDownUtilizationThreshold)
+	assert.Equal(t, 2*time.Minute, opts.ScaleDownUnneededTime)
+	assert.Equal(t, time.Hour, opts.ScaleDownUnreadyTime)
+	assert.Equal(t, time.Minute, opts.MaxNodeProvisionTime)
+	assert.False(t, opts.ZeroOrMaxNodeScaling)
+	assert.True(t, opts.IgnoreDaemonSetsUtilization)
+	assert.Equal(t, 1.5, opts.RecreationCost)
+
 }
 
 func TestCloudProvider_TargetSize(t *testing.T) {
@@ -68,6 +68,10 @@ const (
 	// be scaled up because the associated reservation was not ready.
 	ErrorReservationNotReady = "RESERVATION_NOT_READY"
 
+	// ErrorReservationExhausted is an error code used if the node group couldn't be scaled up
+	// because the specific reservations its instances must consume don't have enough capacity left.
+	ErrorReservationExhausted = "RESERVATION_EXHAUSTED"
+
 	// ErrorCodeOther is an error code used in InstanceErrorInfo if other error occurs.
 	ErrorCodeOther = "OTHER"
 )
@@ -94,12 +98,15 @@ type AutoscalingGceClient interface {
 	FetchMachineType(zone, machineType string) (*gce.MachineType, error)
 	FetchMachineTypes(zone string) ([]*gce.MachineType, error)
 	FetchAllMigs(zone string) ([]*gce.InstanceGroupManager, error)
+	FetchAllRegionalMigs(region string) ([]*gce.InstanceGroupManager, error)
 	FetchMigTargetSize(GceRef) (int64, error)
 	FetchMigBasename(GceRef) (string, error)
 	FetchMigInstances(GceRef) ([]cloudprovider.Instance, error)
 	FetchMigTemplateName(migRef GceRef) (string, error)
 	FetchMigTemplate(migRef GceRef, templateName string) (*gce.InstanceTemplate, error)
 	FetchMigsWithName(zone string, filter *regexp.Regexp) ([]string, error)
+	FetchRegionalMigsWithName(region string, filter *regexp.Regexp) ([]string, error)
+	FetchMigDistribution(migRef GceRef) (MigDistribution, error)
 	FetchZones(region string) ([]string, error)
 	FetchAvailableCpuPlatforms() (map[string][]string, error)
 	FetchReservations() ([]*gce.Reservation, error)
@@ -194,9 +201,27 @@ func (client *autoscalingGceClientV1) FetchAllMigs(zone string) ([]*gce.Instance
 	return migs, nil
 }
 
+// FetchAllRegionalMigs returns the regional MIGs of the region.
+func (client *autoscalingGceClientV1) FetchAllRegionalMigs(region string) ([]*gce.InstanceGroupManager, error) {
+	registerRequest("region_instance_group_managers", "list")
+	var migs []*gce.InstanceGroupManager
+	err := client.gceService.RegionInstanceGroupManagers.List(client.projectId, region).Pages(
+		context.TODO(),
+		func(page *gce.RegionInstanceGroupManagerList) error {
+			migs = append(migs, page.Items...)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return migs, nil
+}
+
 func (client *autoscalingGceClientV1) FetchMigTargetSize(migRef GceRef) (int64, error) {
-	registerRequest("instance_group_managers", "get")
-	igm, err := client.gceService.InstanceGroupManagers.Get(migRef.Project, migRef.Zone, migRef.Name).Do()
+	if migRef.isMigZone() {
+		return client.fetchMigZoneSize(migRef)
+	}
+	igm, err := client.getMig(migRef)
 	if err != nil {
 		if err, ok := err.(*googleapi.Error); ok {
 			if err.Code == http.StatusNotFound {
@@ -208,9 +233,34 @@ func (client *autoscalingGceClientV1) FetchMigTargetSize(migRef GceRef) (int64,
 	return igm.TargetSize, nil
 }
 
-func (client *autoscalingGceClientV1) FetchMigBasename(migRef GceRef) (string, error) {
+// fetchMigZoneSize returns the number of instances of the zone of a regional MIG, not being deleted. GCE
+// only tracks the target size of regional MIGs as a whole, so this is the target size of the zone.
+func (client *autoscalingGceClientV1) fetchMigZoneSize(migRef GceRef) (int64, error) {
+	instances, err := client.FetchMigInstances(migRef)
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	for _, instance := range instances {
+		if instance.Status == nil || instance.Status.State != cloudprovider.InstanceDeleting {
+			size++
+		}
+	}
+	return size, nil
+}
+
+// getMig returns the instance group manager of a zonal or regional MIG.
+func (client *autoscalingGceClientV1) getMig(migRef GceRef) (*gce.InstanceGroupManager, error) {
+	if migRef.Region != "" {
+		registerRequest("region_instance_group_managers", "get")
+		return client.gceService.RegionInstanceGroupManagers.Get(migRef.Project, migRef.Region, migRef.Name).Do()
+	}
 	registerRequest("instance_group_managers", "get")
-	igm, err := client.gceService.InstanceGroupManagers.Get(migRef.Project, migRef.Zone, migRef.Name).Do()
+	return client.gceService.InstanceGroupManagers.Get(migRef.Project, migRef.Zone, migRef.Name).Do()
+}
+
+func (client *autoscalingGceClientV1) FetchMigBasename(migRef GceRef) (string, error) {
+	igm, err := client.getMig(migRef)
 	if err != nil {
 		if err, ok := err.(*googleapi.Error); ok && err.Code == http.StatusNotFound {
 			return "", errors.NewAutoscalerError(errors.NodeGroupDoesNotExistError, "%s", err.Error())
@@ -221,6 +271,14 @@ func (client *autoscalingGceClientV1) FetchMigBasename(migRef GceRef) (string, e
 }
 
 func (client *autoscalingGceClientV1) ResizeMig(migRef GceRef, size int64) error {
+	if migRef.Region != "" {
+		registerRequest("region_instance_group_managers", "resize")
+		op, err := client.gceService.RegionInstanceGroupManagers.Resize(migRef.Project, migRef.Region, migRef.Name, size).Do()
+		if err != nil {
+			return err
+		}
+		return client.waitForRegionOp(op, migRef.Project, migRef.Region, false)
+	}
 	registerRequest("instance_group_managers", "resize")
 	op, err := client.gceService.InstanceGroupManagers.Resize(migRef.Project, migRef.Zone, migRef.Name, size).Do()
 	if err != nil {
@@ -239,6 +297,15 @@ func (client *autoscalingGceClientV1) CreateInstances(migRef GceRef, baseName st
 		instanceNames[newInstanceName] = true
 		req.Instances = append(req.Instances, &gce.PerInstanceConfig{Name: newInstanceName})
 	}
+	if migRef.Region != "" {
+		// GCE picks the zones of the new instances according to the distribution policy of the MIG.
+		regionReq := gce.RegionInstanceGroupManagersCreateInstancesRequest{Instances: req.Instances}
+		op, err := client.gceService.RegionInstanceGroupManagers.CreateInstances(migRef.Project, migRef.Region, migRef.Name, &regionReq).Do()
+		if err != nil {
+			return err
+		}
+		return client.waitForRegionOp(op, migRef.Project, migRef.Region, false)
+	}
 	op, err := client.gceService.InstanceGroupManagers.CreateInstances(migRef.Project, migRef.Zone, migRef.Name, &req).Do()
 	if err != nil {
 		return err
@@ -261,15 +328,28 @@ func instanceIdsToNamesMap(instanceProviderIds []string) map[string]bool {
 }
 
 func (client *autoscalingGceClientV1) waitForOp(operation *gce.Operation, project, zone string, isDeletion bool) error {
+	return client.waitForOperation(operation, project, zone, isDeletion, func() (*gce.Operation, error) {
+		registerRequest("zone_operations", "get")
+		return client.gceService.ZoneOperations.Get(project, zone, operation.Name).Do()
+	})
+}
+
+func (client *autoscalingGceClientV1) waitForRegionOp(operation *gce.Operation, project, region string, isDeletion bool) error {
+	return client.waitForOperation(operation, project, region, isDeletion, func() (*gce.Operation, error) {
+		registerRequest("region_operations", "get")
+		return client.gceService.RegionOperations.Get(project, region, operation.Name).Do()
+	})
+}
+
+func (client *autoscalingGceClientV1) waitForOperation(operation *gce.Operation, project, location string, isDeletion bool, getOp func() (*gce.Operation, error)) error {
 	pollInterval := client.operationPollInterval
 	if isDeletion {
 		pollInterval = client.operationDeletionPollInterval
 	}
 	for start := time.Now(); time.Since(start) < client.operationWaitTimeout; time.Sleep(pollInterval) {
-		klog.V(4).Infof("Waiting for operation %s %s %s", project, zone, operation.Name)
-		registerRequest("zone_operations", "get")
-		if op, err := client.gceService.ZoneOperations.Get(project, zone, operation.Name).Do(); err == nil {
-			klog.V(4).Infof("Operation %s %s %s status: %s", project, zone, operation.Name, op.Status)
+		klog.V(4).Infof("Waiting for operation %s %s %s", project, location, operation.Name)
+		if op, err := getOp(); err == nil {
+			klog.V(4).Infof("Operation %s %s %s status: %s", project, location, operation.Name, op.Status)
 			if op.Status == "DONE" {
 				if op.Error != nil {
 					errBytes, err := op.Error.MarshalJSON()
@@ -297,6 +377,17 @@ func (client *autoscalingGceClientV1) DeleteInstances(migRef GceRef, instances [
 	for _, i := range instances {
 		req.Instances = append(req.Instances, GenerateInstanceUrl(i))
 	}
+	if migRef.Region != "" {
+		regionReq := gce.RegionInstanceGroupManagersDeleteInstancesRequest{
+			Instances:                      req.Instances,
+			SkipInstancesOnValidationError: true,
+		}
+		op, err := client.gceService.RegionInstanceGroupManagers.DeleteInstances(migRef.Project, migRef.Region, migRef.Name, &regionReq).Do()
+		if err != nil {
+			return err
+		}
+		return client.waitForRegionOp(op, migRef.Project, migRef.Region, true)
+	}
 	op, err := client.gceService.InstanceGroupManagers.DeleteInstances(migRef.Project, migRef.Zone, migRef.Name, &req).Do()
 	if err != nil {
 		return err
@@ -304,9 +395,30 @@ func (client *autoscalingGceClientV1) DeleteInstances(migRef GceRef, instances [
 	return client.waitForOp(op, migRef.Project, migRef.Zone, true)
 }
 
-func (client *autoscalingGceClientV1) FetchMigInstances(migRef GceRef) ([]cloudprovider.Instance, error) {
+// listManagedInstances returns the instances of a zonal or regional MIG.
+func (client *autoscalingGceClientV1) listManagedInstances(migRef GceRef) ([]*gce.ManagedInstance, error) {
+	if migRef.Region != "" {
+		registerRequest("region_instance_group_managers", "list_managed_instances")
+		var managedInstances []*gce.ManagedInstance
+		err := client.gceService.RegionInstanceGroupManagers.ListManagedInstances(migRef.Project, migRef.Region, migRef.Name).Pages(
+			context.TODO(),
+			func(page *gce.RegionInstanceGroupManagersListInstancesResponse) error {
+				managedInstances = append(managedInstances, page.ManagedInstances...)
+				return nil
+			})
+		return managedInstances, err
+	}
 	registerRequest("instance_group_managers", "list_managed_instances")
 	gceInstances, err := client.gceService.InstanceGroupManagers.ListManagedInstances(migRef.Project, migRef.Zone, migRef.Name).Do()
+	if err != nil {
+		return nil, err
+	}
+	return gceInstances.ManagedInstances, nil
+}
+
+// FetchMigInstances returns the instances of a MIG. For zones of regional MIGs, only the instances of the zone are returned.
+func (client *autoscalingGceClientV1) FetchMigInstances(migRef GceRef) ([]cloudprovider.Instance, error) {
+	managedInstances, err := client.listManagedInstances(migRef)
 	if err != nil {
 		klog.V(4).Infof("Failed MIG info request for %s %s %s: %v", migRef.Project, migRef.Zone, migRef.Name, err)
 		return nil, err
@@ -314,12 +426,15 @@ func (client *autoscalingGceClientV1) FetchMigInstances(migRef GceRef) ([]cloudp
 	infos := []cloudprovider.Instance{}
 	errorCodeCounts := make(map[string]int)
 	errorLoggingQuota := klogx.NewLoggingQuota(100)
-	for _, gceInstance := range gceInstances.ManagedInstances {
+	for _, gceInstance := range managedInstances {
 		ref, err := ParseInstanceUrlRef(gceInstance.Instance)
 		if err != nil {
 			klog.Errorf("Received error while parsing of the instance url: %v", err)
 			continue
 		}
+		if migRef.isMigZone() && ref.Zone != migRef.Zone {
+			continue
+		}
 
 		instance := cloudprovider.Instance{
 			Id: ref.ToProviderId(),
@@ -519,8 +634,7 @@ func (client *autoscalingGceClientV1) FetchAvailableCpuPlatforms() (map[string][
 }
 
 func (client *autoscalingGceClientV1) FetchMigTemplateName(migRef GceRef) (string, error) {
-	registerRequest("instance_group_managers", "get")
-	igm, err := client.gceService.InstanceGroupManagers.Get(migRef.Project, migRef.Zone, migRef.Name).Do()
+	igm, err := client.getMig(migRef)
 	if err != nil {
 		if err, ok := err.(*googleapi.Error); ok {
 			if err.Code == http.StatusNotFound {
@@ -559,6 +673,66 @@ func (client *autoscalingGceClientV1) FetchMigsWithName(zone string, name *regex
 	return links, nil
 }
 
+// FetchRegionalMigsWithName returns the links of the regional MIGs of the region matching the filter.
+func (client *autoscalingGceClientV1) FetchRegionalMigsWithName(region string, name *regexp.Regexp) ([]string, error) {
+	filter := fmt.Sprintf("name eq %s", name)
+	links := make([]string, 0)
+	registerRequest("region_instance_groups", "list")
+	req := client.gceService.RegionInstanceGroups.List(client.projectId, region).Filter(filter)
+	if err := req.Pages(context.TODO(), func(page *gce.RegionInstanceGroupList) error {
+		for _, ig := range page.Items {
+			links = append(links, ig.SelfLink)
+			klog.V(3).Infof("found regional managed instance group %s matching regexp %s", ig.Name, name)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("cannot list regional managed instance groups: %v", err)
+	}
+	return links, nil
+}
+
+// MigDistribution describes how a regional MIG distributes its instances across zones.
+type MigDistribution struct {
+	// Zones are the zones of the distribution policy of the MIG.
+	Zones []string
+	// Even is true if the MIG uses the EVEN target shape without proactive instance redistribution:
+	// GCE then creates new instances in the zones with the fewest instances first, and never moves
+	// existing instances between zones.
+	Even bool
+}
+
+// FetchMigDistribution returns how a regional MIG distributes its instances across zones.
+func (client *autoscalingGceClientV1) FetchMigDistribution(migRef GceRef) (MigDistribution, error) {
+	igm, err := client.getMig(migRef)
+	if err != nil {
+		if err, ok := err.(*googleapi.Error); ok && err.Code == http.StatusNotFound {
+			return MigDistribution{}, errors.NewAutoscalerError(errors.NodeGroupDoesNotExistError, "%s", err.Error())
+		}
+		return MigDistribution{}, err
+	}
+	distribution := migDistribution(igm)
+	if len(distribution.Zones) == 0 {
+		return MigDistribution{}, fmt.Errorf("mig %s has no distribution policy zones", migRef.Name)
+	}
+	return distribution, nil
+}
+
+// migDistribution returns the distribution of the instances of a regional MIG across zones.
+func migDistribution(igm *gce.InstanceGroupManager) MigDistribution {
+	if igm.DistributionPolicy == nil {
+		return MigDistribution{}
+	}
+	zones := make([]string, 0, len(igm.DistributionPolicy.Zones))
+	for _, zone := range igm.DistributionPolicy.Zones {
+		zones = append(zones, path.Base(zone.Zone))
+	}
+	return MigDistribution{
+		Zones: zones,
+		Even: igm.DistributionPolicy.TargetShape == "EVEN" &&
+			igm.UpdatePolicy != nil && igm.UpdatePolicy.InstanceRedistributionType == "NONE",
+	}
+}
+
 func (client *autoscalingGceClientV1) FetchReservations() ([]*gce.Reservation, error) {
 	return client.FetchReservationsInProject(client.projectId)
 }
@@ -68,6 +68,7 @@ type GceCache struct {
 	migBaseNameCache          map[GceRef]string
 	instanceTemplateNameCache map[GceRef]string
 	instanceTemplatesCache    map[GceRef]*gce.InstanceTemplate
+	regionalMigZonesCache     map[GceRef][]string
 }
 
 // NewGceCache creates empty GceCache.
@@ -84,6 +85,7 @@ func NewGceCache() *GceCache {
 		migBaseNameCache:          map[GceRef]string{},
 		instanceTemplateNameCache: map[GceRef]string{},
 		instanceTemplatesCache:    map[GceRef]*gce.InstanceTemplate{},
+		regionalMigZonesCache:     map[GceRef][]string{},
 	}
 }
 
@@ -117,6 +119,7 @@ func (gc *GceCache) UnregisterMig(toBeRemoved Mig) bool {
 		klog.V(1).Infof("Unregistered Mig %s", toBeRemoved.GceRef().String())
 		delete(gc.migs, toBeRemoved.GceRef())
 		gc.removeMigInstances(toBeRemoved.GceRef())
+		delete(gc.regionalMigZonesCache, toBeRemoved.GceRef())
 		return true
 	}
 	return false
@@ -473,3 +476,18 @@ func (gc *GceCache) InvalidateAllMigBasenames() {
 	defer gc.cacheMutex.Unlock()
 	gc.migBaseNameCache = make(map[GceRef]string)
 }
+
+// SetRegionalMigZones sets the zones of the distribution policy of the given regional mig in cache.
+func (gc *GceCache) SetRegionalMigZones(migRef GceRef, zones []string) {
+	gc.cacheMutex.Lock()
+	defer gc.cacheMutex.Unlock()
+	gc.regionalMigZonesCache[migRef] = zones
+}
+
+// GetRegionalMigZones gets the zones of the distribution policy of the given regional mig from cache.
+func (gc *GceCache) GetRegionalMigZones(migRef GceRef) (zones []string, found bool) {
+	gc.cacheMutex.Lock()
+	defer gc.cacheMutex.Unlock()
+	zones, found = gc.regionalMigZonesCache[migRef]
+	return
+}
@@ -153,12 +153,31 @@ type GceRef struct {
 	Project string
 	Zone    string
 	Name    string
+	// Region is set for regional MIGs, in which case Zone is either empty, for the MIG as a whole,
+	// or the zone of the node group handling the instances of the MIG in that zone.
+	Region string
 }
 
 func (ref GceRef) String() string {
+	if ref.Region != "" && ref.Zone != "" {
+		return fmt.Sprintf("%s/%s/%s/%s", ref.Project, ref.Region, ref.Name, ref.Zone)
+	}
+	if ref.Region != "" {
+		return fmt.Sprintf("%s/%s/%s", ref.Project, ref.Region, ref.Name)
+	}
 	return fmt.Sprintf("%s/%s/%s", ref.Project, ref.Zone, ref.Name)
 }
 
+// isMigZone returns true if the reference is the one of the node group of a zone of a regional MIG.
+func (ref GceRef) isMigZone() bool {
+	return ref.Region != "" && ref.Zone != ""
+}
+
+// regionalMig returns the reference of the regional MIG of a zone node group.
+func (ref GceRef) regionalMig() GceRef {
+	return GceRef{Project: ref.Project, Region: ref.Region, Name: ref.Name}
+}
+
 // ToProviderId converts GceRef to string in format used as ProviderId in Node object.
 func (ref GceRef) ToProviderId() string {
 	return fmt.Sprintf("gce://%s/%s/%s", ref.Project, ref.Zone, ref.Name)
@@ -221,6 +240,11 @@ func (mig *gceMig) TargetSize() (int, error) {
 	return int(size), err
 }
 
+// RefreshTargetSize re-reads Mig target size from the GCE API.
+func (mig *gceMig) RefreshTargetSize() error {
+	return mig.gceManager.RefreshMigSize(mig)
+}
+
 // IncreaseSize increases Mig size
 func (mig *gceMig) IncreaseSize(delta int) error {
 	if delta <= 0 {
@@ -305,6 +329,11 @@ func (mig *gceMig) DeleteNodes(nodes []*apiv1.Node) error {
 	return mig.gceManager.DeleteInstances(refs)
 }
 
+// ForceDeleteNodes deletes nodes from the group regardless of constraints.
+func (mig *gceMig) ForceDeleteNodes(nodes []*apiv1.Node) error {
+	return cloudprovider.ErrNotImplemented
+}
+
 // Id returns mig url.
 func (mig *gceMig) Id() string {
 	return GenerateMigUrl(mig.gceRef)
@@ -43,6 +43,11 @@ func (m *gceManagerMock) GetMigSize(mig Mig) (int64, error) {
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *gceManagerMock) RefreshMigSize(mig Mig) error {
+	args := m.Called(mig)
+	return args.Error(0)
+}
+
 func (m *gceManagerMock) SetMigSize(mig Mig, size int64) error {
 	args := m.Called(mig, size)
 	return args.Error(0)
@@ -374,10 +379,10 @@ func TestMig(t *testing.T) {
 	// Test DeleteNodes.
 	n1 := BuildTestNode("gke-cluster-1-default-pool-f7607aac-9j4g", 1000, 1000)
 	n1.Spec.ProviderID = "gce://project1/us-central1-b/gke-cluster-1-default-pool-f7607aac-9j4g"
-	n1ref := GceRef{"project1", "us-central1-b", "gke-cluster-1-default-pool-f7607aac-9j4g"}
+	n1ref := GceRef{Project: "project1", Zone: "us-central1-b", Name: "gke-cluster-1-default-pool-f7607aac-9j4g"}
 	n2 := BuildTestNode("gke-cluster-1-default-pool-f7607aac-dck1", 1000, 1000)
 	n2.Spec.ProviderID = "gce://project1/us-central1-b/gke-cluster-1-default-pool-f7607aac-dck1"
-	n2ref := GceRef{"project1", "us-central1-b", "gke-cluster-1-default-pool-f7607aac-dck1"}
+	n2ref := GceRef{Project: "project1", Zone: "us-central1-b", Name: "gke-cluster-1-default-pool-f7607aac-dck1"}
 	gceManagerMock.On("GetMigSize", mock.AnythingOfType("*gce.gceMig")).Return(int64(2), nil).Once()
 	gceManagerMock.On("GetMigForInstance", n1ref).Return(mig1, nil).Once()
 	gceManagerMock.On("GetMigForInstance", n2ref).Return(mig1, nil).Once()
@@ -431,7 +436,7 @@ func TestMig(t *testing.T) {
 func TestGceRefFromProviderId(t *testing.T) {
 	ref, err := GceRefFromProviderId("gce://project1/us-central1-b/name1")
 	assert.NoError(t, err)
-	assert.Equal(t, GceRef{"project1", "us-central1-b", "name1"}, ref)
+	assert.Equal(t, GceRef{Project: "project1", Zone: "us-central1-b", Name: "name1"}, ref)
 }
 
 func createString(s string) *string {
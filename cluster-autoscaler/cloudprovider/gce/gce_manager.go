@@ -26,6 +26,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -91,6 +92,8 @@ type GceManager interface {
 	GetResourceLimiter() (*cloudprovider.ResourceLimiter, error)
 	// GetMigSize gets MIG size.
 	GetMigSize(mig Mig) (int64, error)
+	// RefreshMigSize re-reads MIG size from the GCE API.
+	RefreshMigSize(mig Mig) error
 	// GetMigOptions returns MIG's NodeGroupAutoscalingOptions
 	GetMigOptions(mig Mig, defaults config.NodeGroupAutoscalingOptions) *config.NodeGroupAutoscalingOptions
 
@@ -120,6 +123,9 @@ type gceManagerImpl struct {
 	explicitlyConfigured  map[GceRef]bool
 	migAutoDiscoverySpecs []migAutoDiscoveryConfig
 	reserved              *GceReserved
+	// migZonesMutex serializes the changes of the sizes of the zones of regional MIGs, which all share
+	// the target size of their MIG.
+	migZonesMutex sync.Mutex
 }
 
 // CreateGceManager constructs GceManager object.
@@ -239,9 +245,22 @@ func (m *gceManagerImpl) GetMigSize(mig Mig) (int64, error) {
 	return m.migInfoProvider.GetMigTargetSize(mig.GceRef())
 }
 
+// RefreshMigSize re-reads MIG size from the GCE API, bypassing the cache.
+func (m *gceManagerImpl) RefreshMigSize(mig Mig) error {
+	size, err := m.GceService.FetchMigTargetSize(mig.GceRef())
+	if err != nil {
+		return err
+	}
+	m.cache.SetMigTargetSize(mig.GceRef(), size)
+	return nil
+}
+
 // SetMigSize sets MIG size.
 func (m *gceManagerImpl) SetMigSize(mig Mig, size int64) error {
 	klog.V(0).Infof("Setting mig size %s to %d", mig.Id(), size)
+	if mig.GceRef().isMigZone() {
+		return m.setMigZoneSize(mig, size)
+	}
 	m.cache.InvalidateMigTargetSize(mig.GceRef())
 	err := m.GceService.ResizeMig(mig.GceRef(), size)
 	if err != nil {
@@ -304,6 +323,11 @@ func (m *gceManagerImpl) CreateInstances(mig Mig, delta int64) error {
 	if delta == 0 {
 		return nil
 	}
+	if mig.GceRef().isMigZone() {
+		m.migZonesMutex.Lock()
+		defer m.migZonesMutex.Unlock()
+		return m.createMigZoneInstances(mig, delta)
+	}
 	instances, err := m.GetMigNodes(mig)
 	if err != nil {
 		return err
@@ -316,10 +340,167 @@ func (m *gceManagerImpl) CreateInstances(mig Mig, delta int64) error {
 	if err != nil {
 		return fmt.Errorf("can't upscale %s: failed to collect BaseInstanceName: %w", mig.GceRef(), err)
 	}
+	if err := m.checkReservationCapacity(mig, delta); err != nil {
+		return err
+	}
 	m.cache.InvalidateMigTargetSize(mig.GceRef())
 	return m.GceService.CreateInstances(mig.GceRef(), baseName, delta, instancesNames)
 }
 
+// setMigZoneSize sets the size of the node group of a zone of a regional MIG. Scale-ups are coordinated with
+// the other zones of the MIG, scale-downs delete the instances of the zone which are still being created.
+func (m *gceManagerImpl) setMigZoneSize(mig Mig, size int64) error {
+	m.migZonesMutex.Lock()
+	defer m.migZonesMutex.Unlock()
+
+	instances, err := m.GceService.FetchMigInstances(mig.GceRef())
+	if err != nil {
+		return err
+	}
+	var current int64
+	var creating []GceRef
+	for _, instance := range instances {
+		if instance.Status != nil && instance.Status.State == cloudprovider.InstanceDeleting {
+			continue
+		}
+		current++
+		if instance.Status != nil && instance.Status.State == cloudprovider.InstanceCreating {
+			ref, err := GceRefFromProviderId(instance.Id)
+			if err != nil {
+				return err
+			}
+			creating = append(creating, ref)
+		}
+	}
+	if size > current {
+		return m.createMigZoneInstances(mig, size-current)
+	}
+	if size == current {
+		return nil
+	}
+	if int64(len(creating)) < current-size {
+		return fmt.Errorf("can't decrease the size of %s from %d to %d: only %d of its instances are being created", mig.GceRef(), current, size, len(creating))
+	}
+	m.cache.InvalidateMigTargetSize(mig.GceRef())
+	return m.GceService.DeleteInstances(mig.GceRef().regionalMig(), creating[:current-size])
+}
+
+// createMigZoneInstances creates delta new instances in the zone of a regional MIG, and needs to be called with
+// migZonesMutex locked. The zones of new instances can't be picked: GCE creates them in the zones of the MIG with
+// the fewest instances first, as the MIG uses the EVEN target shape. Instances are therefore also created in all
+// the zones with fewer instances than the scaled-up zone will have, up to its new size. None are created in the
+// zones with more instances, so that all the instances that GCE creates in the zone add up to delta.
+func (m *gceManagerImpl) createMigZoneInstances(mig Mig, delta int64) error {
+	regionalRef := mig.GceRef().regionalMig()
+	instances, err := m.GceService.FetchMigInstances(regionalRef)
+	if err != nil {
+		return err
+	}
+	zoneMigs := m.migZones(regionalRef)
+	zoneSizes := make(map[string]int64, len(zoneMigs))
+	for _, zoneMig := range zoneMigs {
+		zoneSizes[zoneMig.GceRef().Zone] = 0
+	}
+	instancesNames := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		instancesNames = append(instancesNames, instance.Id)
+		if instance.Status != nil && instance.Status.State == cloudprovider.InstanceDeleting {
+			continue
+		}
+		ref, err := GceRefFromProviderId(instance.Id)
+		if err != nil {
+			return err
+		}
+		if _, found := zoneSizes[ref.Zone]; found {
+			zoneSizes[ref.Zone]++
+		}
+	}
+	baseName, err := m.migInfoProvider.GetMigBasename(mig.GceRef())
+	if err != nil {
+		return fmt.Errorf("can't upscale %s: failed to collect BaseInstanceName: %w", mig.GceRef(), err)
+	}
+	size := evenScaleUpSize(zoneSizes, mig.GceRef().Zone, delta)
+	klog.V(1).Infof("Creating %d instances in regional mig %s for %d new instances in zone %s", size, regionalRef, delta, mig.GceRef().Zone)
+	for _, zoneMig := range zoneMigs {
+		m.cache.InvalidateMigTargetSize(zoneMig.GceRef())
+	}
+	return m.GceService.CreateInstances(regionalRef, baseName, size, instancesNames)
+}
+
+// migZones returns the node groups of the zones of a regional MIG.
+func (m *gceManagerImpl) migZones(regionalRef GceRef) []Mig {
+	var migs []Mig
+	for _, mig := range m.GetMigs() {
+		if mig.GceRef().isMigZone() && mig.GceRef().regionalMig() == regionalRef {
+			migs = append(migs, mig)
+		}
+	}
+	return migs
+}
+
+// evenScaleUpSize returns how many instances a regional MIG with the EVEN target shape must create for delta
+// of them to be created in the given zone, given the current sizes of its zones: all the zones with fewer
+// instances are brought to the new size of the zone first.
+func evenScaleUpSize(zoneSizes map[string]int64, zone string, delta int64) int64 {
+	newZoneSize := zoneSizes[zone] + delta
+	var size int64
+	for _, zoneSize := range zoneSizes {
+		if zoneSize < newZoneSize {
+			size += newZoneSize - zoneSize
+		}
+	}
+	return size
+}
+
+// checkReservationCapacity fails the scale-up of MIGs whose instances must consume specific reservations
+// if these reservations don't have enough capacity left, so that the MIG is backed off right away instead of
+// waiting for the instances to fail to be created. Reservations that can't be checked don't block the scale-up,
+// neither do the ones of regional MIGs, whose new instances may be created in any zone.
+func (m *gceManagerImpl) checkReservationCapacity(mig Mig, delta int64) error {
+	if mig.GceRef().Region != "" {
+		return nil
+	}
+	template, err := m.migInfoProvider.GetMigInstanceTemplate(mig.GceRef())
+	if err != nil {
+		klog.Warningf("Failed to get instance template of %s to check its reservations: %v", mig.GceRef(), err)
+		return nil
+	}
+	refs := specificReservations(template, mig.GceRef().Project)
+	if len(refs) == 0 {
+		return nil
+	}
+	namesByProject := make(map[string]map[string]bool)
+	for _, ref := range refs {
+		if namesByProject[ref.Project] == nil {
+			namesByProject[ref.Project] = make(map[string]bool)
+		}
+		namesByProject[ref.Project][ref.Name] = true
+	}
+	var capacity int64
+	for project, names := range namesByProject {
+		reservations, err := m.GceService.FetchReservationsInProject(project)
+		if err != nil {
+			klog.Warningf("Failed to fetch reservations of project %s to check capacity for %s: %v", project, mig.GceRef(), err)
+			return nil
+		}
+		projectCapacity, found := reservationCapacity(reservations, names, mig.GceRef().Zone)
+		if !found {
+			// Let GCE report the missing reservation when creating the instances.
+			return nil
+		}
+		capacity += projectCapacity
+	}
+	if capacity < delta {
+		return &cloudprovider.ScaleUpError{InstanceErrorInfo: cloudprovider.InstanceErrorInfo{
+			ErrorClass: cloudprovider.OutOfResourcesErrorClass,
+			ErrorCode:  ErrorReservationExhausted,
+			ErrorMessage: fmt.Sprintf("can't upscale %s by %d: %s: its specific reservations have capacity for %d more instances",
+				mig.GceRef(), delta, ErrorReservationExhausted, capacity),
+		}}
+	}
+	return nil
+}
+
 func (m *gceManagerImpl) forceRefresh() error {
 	m.clearMachinesCache()
 	if err := m.fetchAutoMigs(); err != nil {
@@ -365,14 +546,16 @@ func (m *gceManagerImpl) refreshAutoscalingOptions() {
 func (m *gceManagerImpl) fetchExplicitMigs(specs []string) error {
 	changed := false
 	for _, spec := range specs {
-		mig, err := m.buildMigFromFlag(spec)
+		migs, err := m.buildMigsFromFlag(spec)
 		if err != nil {
 			return err
 		}
-		if m.registerMig(mig) {
-			changed = true
+		for _, mig := range migs {
+			if m.registerMig(mig) {
+				changed = true
+			}
+			m.explicitlyConfigured[mig.GceRef()] = true
 		}
-		m.explicitlyConfigured[mig.GceRef()] = true
 	}
 
 	if changed {
@@ -381,43 +564,70 @@ func (m *gceManagerImpl) fetchExplicitMigs(specs []string) error {
 	return nil
 }
 
-func (m *gceManagerImpl) buildMigFromFlag(flag string) (Mig, error) {
+func (m *gceManagerImpl) buildMigsFromFlag(flag string) ([]Mig, error) {
 	s, err := dynamic.SpecFromString(flag, scaleToZeroSupported)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse node group spec: %v", err)
 	}
-	return m.buildMigFromSpec(s)
+	return m.buildMigsFromSpec(s)
 }
 
-func (m *gceManagerImpl) buildMigFromAutoCfg(link string, cfg migAutoDiscoveryConfig) (Mig, error) {
+func (m *gceManagerImpl) buildMigsFromAutoCfg(link string, cfg migAutoDiscoveryConfig) ([]Mig, error) {
 	s := &dynamic.NodeGroupSpec{
 		Name:               link,
 		MinSize:            cfg.MinSize,
 		MaxSize:            cfg.MaxSize,
 		SupportScaleToZero: scaleToZeroSupported,
 	}
-	return m.buildMigFromSpec(s)
+	return m.buildMigsFromSpec(s)
 }
 
-func (m *gceManagerImpl) buildMigFromSpec(s *dynamic.NodeGroupSpec) (Mig, error) {
+// buildMigsFromSpec builds the node groups of a MIG. Regional MIGs using the EVEN target shape without
+// proactive instance redistribution are split into a node group per zone, sharing the min and max sizes
+// of the spec. Other regional MIGs are a single node group, as the zones of their instances can't be
+// told apart before GCE creates them.
+func (m *gceManagerImpl) buildMigsFromSpec(s *dynamic.NodeGroupSpec) ([]Mig, error) {
 	if err := s.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid node group spec: %v", err)
 	}
-	project, zone, name, err := ParseMigUrl(s.Name)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse mig url: %s got error: %v", s.Name, err)
+	var ref GceRef
+	if IsRegionalMigUrl(s.Name) {
+		project, region, name, err := ParseRegionalMigUrl(s.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse mig url: %s got error: %v", s.Name, err)
+		}
+		ref = GceRef{Project: project, Name: name, Region: region}
+	} else {
+		project, zone, name, err := ParseMigUrl(s.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse mig url: %s got error: %v", s.Name, err)
+		}
+		ref = GceRef{Project: project, Name: name, Zone: zone}
 	}
-	mig := &gceMig{
-		gceRef: GceRef{
-			Project: project,
-			Name:    name,
-			Zone:    zone,
-		},
-		gceManager: m,
-		minSize:    s.MinSize,
-		maxSize:    s.MaxSize,
+	if ref.Region == "" {
+		return []Mig{&gceMig{gceRef: ref, gceManager: m, minSize: s.MinSize, maxSize: s.MaxSize}}, nil
 	}
-	return mig, nil
+	distribution, err := m.GceService.FetchMigDistribution(ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get distribution policy of mig %s: %v", s.Name, err)
+	}
+	if !distribution.Even {
+		klog.V(1).Infof("Regional mig %s isn't split into zones: it doesn't use the EVEN target shape without proactive instance redistribution", ref)
+		return []Mig{&gceMig{gceRef: ref, gceManager: m, minSize: s.MinSize, maxSize: s.MaxSize}}, nil
+	}
+	// The sizes of the zones are split evenly, so that the other zones scaled up along with one
+	// never exceed the max size of the MIG.
+	zones := len(distribution.Zones)
+	migs := make([]Mig, 0, zones)
+	for _, zone := range distribution.Zones {
+		migs = append(migs, &gceMig{
+			gceRef:     GceRef{Project: ref.Project, Region: ref.Region, Zone: zone, Name: ref.Name},
+			gceManager: m,
+			minSize:    s.MinSize / zones,
+			maxSize:    s.MaxSize / zones,
+		})
+	}
+	return migs, nil
 }
 
 // Fetch automatically discovered MIGs. These MIGs should be unregistered if
@@ -434,19 +644,21 @@ func (m *gceManagerImpl) fetchAutoMigs() error {
 		}
 
 		for _, link := range links {
-			mig, err := m.buildMigFromAutoCfg(link, cfg)
+			migs, err := m.buildMigsFromAutoCfg(link, cfg)
 			if err != nil {
 				return err
 			}
-			exists[mig.GceRef()] = true
-			if m.explicitlyConfigured[mig.GceRef()] {
-				// This MIG was explicitly configured, but would also be
-				// autodiscovered. We want the explicitly configured min and max
-				// nodes to take precedence.
-				klog.V(3).Infof("Ignoring explicitly configured MIG %s in autodiscovery.", mig.GceRef().String())
-				continue
+			for _, mig := range migs {
+				exists[mig.GceRef()] = true
+				if m.explicitlyConfigured[mig.GceRef()] {
+					// This MIG was explicitly configured, but would also be
+					// autodiscovered. We want the explicitly configured min and max
+					// nodes to take precedence.
+					klog.V(3).Infof("Ignoring explicitly configured MIG %s in autodiscovery.", mig.GceRef().String())
+					continue
+				}
+				toRegister = append(toRegister, mig)
 			}
-			toRegister = append(toRegister, mig)
 		}
 	}
 
@@ -552,6 +764,12 @@ func (m *gceManagerImpl) findMigsInRegion(region string, name *regexp.Regexp) ([
 		}
 	}
 
+	regionalLinks, err := m.GceService.FetchRegionalMigsWithName(region, name)
+	if err != nil {
+		return nil, err
+	}
+	links = append(links, regionalLinks...)
+
 	return links, nil
 }
 
@@ -578,6 +796,9 @@ func (m *gceManagerImpl) GetMigOptions(mig Mig, defaults config.NodeGroupAutosca
 	if opt, ok := getDurationOption(options, migRef.Name, config.DefaultMaxNodeProvisionTimeKey); ok {
 		defaults.MaxNodeProvisionTime = opt
 	}
+	if opt, ok := getFloat64Option(options, migRef.Name, config.DefaultRecreationCostKey); ok {
+		defaults.RecreationCost = opt
+	}
 
 	return &defaults
 }
@@ -677,6 +677,25 @@ func TestGetMigSizeListCallFails(t *testing.T) {
 	mock.AssertExpectationsForObjects(t, server)
 }
 
+func TestRefreshMigSize(t *testing.T) {
+	server := NewHttpServerMock()
+	defer server.Close()
+	g := newTestGceManager(t, server.URL, false)
+
+	defaultPoolMig := setupTestDefaultPool(g, true)
+	g.cache.SetMigTargetSize(defaultPoolMig.GceRef(), 7)
+
+	// The MIG was resized outside of the autoscaler: the size is fetched from the API, not the cache.
+	server.On("handle", fmt.Sprintf("/projects/project1/zones/us-central1-b/instanceGroupManagers/%s", defaultPoolMigName)).Return(buildInstanceGroupManagerResponse(zoneB, defaultPoolMigName, 9)).Once()
+
+	err := g.RefreshMigSize(defaultPoolMig)
+	assert.NoError(t, err)
+	defaultPoolMigSize, err := g.GetMigSize(defaultPoolMig)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), defaultPoolMigSize)
+	mock.AssertExpectationsForObjects(t, server)
+}
+
 func TestGetMigForInstance(t *testing.T) {
 	server := NewHttpServerMock()
 	defer server.Close()
@@ -1144,6 +1163,41 @@ const getRegionResponse = `{
  "selfLink": "https://www.googleapis.com/compute/v1/projects/project1/regions/us-central1"
 }`
 
+const emptyRegionalInstanceGroupsResponse = `{
+  "kind": "compute#regionInstanceGroupList",
+  "id": "projects/project1/regions/us-central1/instanceGroups",
+  "items": [],
+  "selfLink": "https://www.googleapis.com/compute/v1/projects/project1/regions/us-central1/instanceGroups"
+}`
+
+const regionalInstanceGroupManagerResponseTemplate = `{
+  "kind": "compute#instanceGroupManager",
+  "id": "3213213219",
+  "creationTimestamp": "2017-09-15T04:47:24.687-07:00",
+  "name": "%s",
+  "region": "https://www.googleapis.com/compute/v1/projects/project1/regions/us-central1",
+  "distributionPolicy": {
+    "zones": [
+      {"zone": "https://www.googleapis.com/compute/v1/projects/project1/zones/us-central1-b"},
+      {"zone": "https://www.googleapis.com/compute/v1/projects/project1/zones/us-central1-c"}
+    ],
+    "targetShape": "EVEN"
+  },
+  "updatePolicy": {
+    "instanceRedistributionType": "%s"
+  },
+  "instanceTemplate": "https://www.googleapis.com/compute/v1/projects/project1/global/instanceTemplates/%s",
+  "instanceGroup": "https://www.googleapis.com/compute/v1/projects/project1/regions/us-central1/instanceGroups/%s",
+  "baseInstanceName": "%s",
+  "fingerprint": "kfdsuH",
+  "targetSize": 4,
+  "selfLink": "https://www.googleapis.com/compute/v1/projects/project1/regions/us-central1/instanceGroupManagers/%s"
+}`
+
+func buildRegionalInstanceGroupManagerResponse(name, instanceRedistributionType string) string {
+	return fmt.Sprintf(regionalInstanceGroupManagerResponseTemplate, name, instanceRedistributionType, name, name, name, name)
+}
+
 func TestFetchAutoMigsZonal(t *testing.T) {
 	server := NewHttpServerMock()
 	defer server.Close()
@@ -1222,6 +1276,7 @@ func TestFetchAutoMigsRegional(t *testing.T) {
 
 	server.On("handle", "/projects/project1/regions/us-central1").Return(getRegionResponse).Once()
 	server.On("handle", "/projects/project1/zones/"+zoneB+"/instanceGroups").Return(buildListInstanceGroupsResponse(zoneB, gceMigA, gceMigB)).Once()
+	server.On("handle", "/projects/project1/regions/us-central1/instanceGroups").Return(emptyRegionalInstanceGroupsResponse).Once()
 	server.On("handle", "/projects/project1/zones/"+zoneB+"/instanceGroupManagers/"+gceMigA).Return(buildInstanceGroupManagerResponse(zoneB, gceMigA, 3)).Once()
 	server.On("handle", "/projects/project1/zones/"+zoneB+"/instanceGroupManagers/"+gceMigB).Return(buildInstanceGroupManagerResponse(zoneB, gceMigB, 3)).Once()
 
@@ -1280,6 +1335,175 @@ func TestFetchExplicitMigs(t *testing.T) {
 	mock.AssertExpectationsForObjects(t, server)
 }
 
+func TestFetchExplicitRegionalMigs(t *testing.T) {
+	server := NewHttpServerMock()
+	defer server.Close()
+
+	server.On("handle", "/projects/project1/regions/us-central1/instanceGroupManagers/"+gceMigA).Return(buildRegionalInstanceGroupManagerResponse(gceMigA, "NONE"))
+	server.On("handle", "/projects/project1/global/instanceTemplates/"+gceMigA).Return(instanceTemplate)
+	server.On("handle", "/projects/project1/regions/us-central1/instanceGroupManagers/"+gceMigA+"/listManagedInstances").Return(buildFourRunningInstancesManagedInstancesResponse(zoneB, gceMigA))
+
+	regional := false
+	g := newTestGceManager(t, server.URL, regional)
+
+	min, max := 3, 5
+	specs := []string{
+		fmt.Sprintf("%d:%d:https://content.googleapis.com/compute/v1/projects/project1/regions/us-central1/instanceGroups/%s", min, max, gceMigA),
+	}
+
+	assert.NoError(t, g.fetchExplicitMigs(specs))
+
+	// The regional MIG is split into a node group per zone of its distribution policy, sharing its min and max sizes.
+	migs := g.GetMigs()
+	assert.Equal(t, 2, len(migs))
+	for _, zone := range []string{zoneB, zoneC} {
+		ref := GceRef{Project: projectId, Region: region, Zone: zone, Name: gceMigA}
+		mig, found := g.cache.GetMig(ref)
+		if assert.True(t, found, "no node group for zone %s", zone) {
+			assert.Equal(t, 1, mig.MinSize())
+			assert.Equal(t, 2, mig.MaxSize())
+			assert.Equal(t, "https://www.googleapis.com/compute/v1/projects/project1/regions/us-central1/instanceGroups/"+gceMigA+"?zone="+zone, mig.Id())
+		}
+	}
+
+	// The instances of the regional MIG belong to the node group of their zone.
+	nodes, err := g.GetMigNodes(migs[0])
+	assert.NoError(t, err)
+	if migs[0].GceRef().Zone == zoneB {
+		assert.Equal(t, 4, len(nodes))
+	} else {
+		assert.Equal(t, 0, len(nodes))
+	}
+	mock.AssertExpectationsForObjects(t, server)
+}
+
+func TestFetchExplicitRegionalMigsRedistributed(t *testing.T) {
+	server := NewHttpServerMock()
+	defer server.Close()
+
+	server.On("handle", "/projects/project1/regions/us-central1/instanceGroupManagers/"+gceMigA).Return(buildRegionalInstanceGroupManagerResponse(gceMigA, "PROACTIVE"))
+	server.On("handle", "/projects/project1/global/instanceTemplates/"+gceMigA).Return(instanceTemplate)
+	server.On("handle", "/projects/project1/regions/us-central1/instanceGroupManagers/"+gceMigA+"/listManagedInstances").Return(buildFourRunningInstancesManagedInstancesResponse(zoneB, gceMigA))
+
+	regional := false
+	g := newTestGceManager(t, server.URL, regional)
+
+	min, max := 0, 5
+	specs := []string{
+		fmt.Sprintf("%d:%d:https://content.googleapis.com/compute/v1/projects/project1/regions/us-central1/instanceGroups/%s", min, max, gceMigA),
+	}
+
+	assert.NoError(t, g.fetchExplicitMigs(specs))
+
+	// GCE may move the instances of the regional MIG between zones, so it's a single node group.
+	migs := g.GetMigs()
+	assert.Equal(t, 1, len(migs))
+	assert.Equal(t, GceRef{Project: projectId, Region: region, Name: gceMigA}, migs[0].GceRef())
+	assert.Equal(t, min, migs[0].MinSize())
+	assert.Equal(t, max, migs[0].MaxSize())
+	assert.Equal(t, "https://www.googleapis.com/compute/v1/projects/project1/regions/us-central1/instanceGroups/"+gceMigA, migs[0].Id())
+	mock.AssertExpectationsForObjects(t, server)
+}
+
+func TestEvenScaleUpSize(t *testing.T) {
+	testCases := []struct {
+		name      string
+		zoneSizes map[string]int64
+		zone      string
+		delta     int64
+		want      int64
+	}{
+		{
+			name:      "balanced zones are all scaled up",
+			zoneSizes: map[string]int64{"a": 2, "b": 2, "c": 2},
+			zone:      "a",
+			delta:     1,
+			want:      3,
+		},
+		{
+			name:      "larger zones are left alone",
+			zoneSizes: map[string]int64{"a": 1, "b": 4, "c": 3},
+			zone:      "a",
+			delta:     2,
+			want:      2,
+		},
+		{
+			name:      "smaller zones are brought to the new size",
+			zoneSizes: map[string]int64{"a": 3, "b": 1, "c": 5},
+			zone:      "a",
+			delta:     1,
+			want:      4,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, evenScaleUpSize(tc.zoneSizes, tc.zone, tc.delta))
+		})
+	}
+}
+
+func TestSetMigZoneSize(t *testing.T) {
+	regionalRef := GceRef{Project: projectId, Region: region, Name: gceMigA}
+	running := &cloudprovider.InstanceStatus{State: cloudprovider.InstanceRunning}
+	instances := []cloudprovider.Instance{
+		{Id: "gce://project1/us-central1-b/b1", Status: running},
+		{Id: "gce://project1/us-central1-b/b2", Status: running},
+		{Id: "gce://project1/us-central1-c/c1", Status: running},
+		{Id: "gce://project1/us-central1-c/c2", Status: &cloudprovider.InstanceStatus{State: cloudprovider.InstanceDeleting}},
+		{Id: "gce://project1/us-central1-f/f1", Status: &cloudprovider.InstanceStatus{State: cloudprovider.InstanceCreating}},
+	}
+	var createdRef, deletedRef GceRef
+	var created int64
+	var deleted []GceRef
+	client := &mockAutoscalingGceClient{
+		fetchMigInstances: func(ref GceRef) ([]cloudprovider.Instance, error) {
+			var result []cloudprovider.Instance
+			for _, instance := range instances {
+				instanceRef, _ := GceRefFromProviderId(instance.Id)
+				if !ref.isMigZone() || instanceRef.Zone == ref.Zone {
+					result = append(result, instance)
+				}
+			}
+			return result, nil
+		},
+		createInstances: func(ref GceRef, _ string, delta int64, _ []string) error {
+			createdRef, created = ref, delta
+			return nil
+		},
+		deleteInstances: func(ref GceRef, refs []GceRef) error {
+			deletedRef, deleted = ref, refs
+			return nil
+		},
+	}
+	cache := NewGceCache()
+	migLister := NewMigLister(cache)
+	g := &gceManagerImpl{
+		cache:           cache,
+		GceService:      client,
+		migLister:       migLister,
+		migInfoProvider: NewCachingMigInfoProvider(cache, migLister, client, projectId, 1, 0),
+	}
+	zoneMigs := map[string]Mig{}
+	for _, zone := range []string{zoneB, zoneC, zoneF} {
+		zoneMigs[zone] = &gceMig{gceRef: GceRef{Project: projectId, Region: region, Zone: zone, Name: gceMigA}, gceManager: g, maxSize: 10}
+		cache.RegisterMig(zoneMigs[zone])
+		cache.SetMigBasename(zoneMigs[zone].GceRef(), gceMigA)
+	}
+
+	// Scaling up zone c from 1 to 3 instances also scales up zone b from 2 to 3 instances and zone f from 1 to 3 instances.
+	assert.NoError(t, g.SetMigSize(zoneMigs[zoneC], 3))
+	assert.Equal(t, regionalRef, createdRef)
+	assert.Equal(t, int64(5), created)
+
+	// Scaling down removes the instances being created.
+	assert.NoError(t, g.SetMigSize(zoneMigs[zoneF], 0))
+	assert.Equal(t, regionalRef, deletedRef)
+	assert.Equal(t, []GceRef{{Project: projectId, Zone: zoneF, Name: "f1"}}, deleted)
+
+	// Running instances can't be removed by scaling down.
+	assert.Error(t, g.SetMigSize(zoneMigs[zoneB], 1))
+}
+
 const listMachineTypesResponse = `{
  "kind": "compute#machineTypeList",
  "id": "projects/project1/zones/us-central1-c/machineTypes",
@@ -1347,9 +1571,9 @@ func TestGetMigTemplateNode(t *testing.T) {
 
 func validateMigExists(t *testing.T, migs []Mig, zone string, name string, minSize int, maxSize int) {
 	ref := GceRef{
-		projectId,
-		zone,
-		name,
+		Project: projectId,
+		Zone:    zone,
+		Name:    name,
 	}
 	for _, mig := range migs {
 		if mig.GceRef() == ref {
@@ -1507,6 +1731,7 @@ func TestAppendInstances(t *testing.T) {
 	g := newTestGceManager(t, server.URL, false)
 
 	defaultPoolMig := setupTestDefaultPool(g, true)
+	setupTestInstanceTemplate(g, defaultPoolMig, nil)
 	server.On("handle", "/projects/project1/zones/us-central1-b/instanceGroupManagers/gke-cluster-1-default-pool/listManagedInstances").Return(buildFourRunningInstancesOnDefaultMigManagedInstancesResponse(zoneB)).Once()
 	server.On("handle", fmt.Sprintf("/projects/project1/zones/us-central1-b/instanceGroupManagers/%v/createInstances", defaultPoolMig.gceRef.Name)).Return(createInstancesResponse).Once()
 	server.On("handle", "/projects/project1/zones/us-central1-b/operations/operation-1624366531120-5c55a4e128c15-fc5daa90-e1ef6c32").Return(createInstancesOperationResponse).Once()
@@ -1515,6 +1740,69 @@ func TestAppendInstances(t *testing.T) {
 	mock.AssertExpectationsForObjects(t, server)
 }
 
+const listReservationsResponse = `{
+  "kind": "compute#reservationAggregatedList",
+  "items": {
+    "zones/us-central1-b": {
+      "reservations": [
+        {
+          "kind": "compute#reservation",
+          "name": "reservation-1",
+          "zone": "https://www.googleapis.com/compute/v1/projects/project1/zones/us-central1-b",
+          "specificReservation": {"count": "5", "inUseCount": "4"},
+          "status": "READY"
+        },
+        {
+          "kind": "compute#reservation",
+          "name": "reservation-2",
+          "zone": "https://www.googleapis.com/compute/v1/projects/project1/zones/us-central1-b",
+          "specificReservation": {"count": "3", "inUseCount": "3"},
+          "status": "READY"
+        }
+      ]
+    }
+  }
+}`
+
+func setupTestInstanceTemplate(manager *gceManagerImpl, mig *gceMig, affinity *gce.ReservationAffinity) {
+	manager.cache.SetMigInstanceTemplateName(mig.GceRef(), mig.GceRef().Name)
+	manager.cache.SetMigInstanceTemplate(mig.GceRef(), &gce.InstanceTemplate{
+		Name:       mig.GceRef().Name,
+		Properties: &gce.InstanceProperties{ReservationAffinity: affinity},
+	})
+}
+
+func TestAppendInstancesReservationExhausted(t *testing.T) {
+	server := NewHttpServerMock()
+	defer server.Close()
+	g := newTestGceManager(t, server.URL, false)
+
+	defaultPoolMig := setupTestDefaultPool(g, true)
+	setupTestInstanceTemplate(g, defaultPoolMig, &gce.ReservationAffinity{
+		ConsumeReservationType: "SPECIFIC_RESERVATION",
+		Key:                    "compute.googleapis.com/reservation-name",
+		Values:                 []string{"reservation-1", "projects/project1/reservations/reservation-2"},
+	})
+	server.On("handle", "/projects/project1/zones/us-central1-b/instanceGroupManagers/gke-cluster-1-default-pool/listManagedInstances").Return(buildFourRunningInstancesOnDefaultMigManagedInstancesResponse(zoneB)).Once()
+	server.On("handle", "/projects/project1/aggregated/reservations").Return(listReservationsResponse).Twice()
+
+	// Only one instance is left in the reservations.
+	err := g.CreateInstances(defaultPoolMig, 2)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), ErrorReservationExhausted)
+	var scaleUpErr *cloudprovider.ScaleUpError
+	if assert.ErrorAs(t, err, &scaleUpErr) {
+		assert.Equal(t, cloudprovider.OutOfResourcesErrorClass, scaleUpErr.ErrorClass)
+		assert.Equal(t, ErrorReservationExhausted, scaleUpErr.ErrorCode)
+	}
+
+	server.On("handle", fmt.Sprintf("/projects/project1/zones/us-central1-b/instanceGroupManagers/%v/createInstances", defaultPoolMig.gceRef.Name)).Return(createInstancesResponse).Once()
+	server.On("handle", "/projects/project1/zones/us-central1-b/operations/operation-1624366531120-5c55a4e128c15-fc5daa90-e1ef6c32").Return(createInstancesOperationResponse).Once()
+	err = g.CreateInstances(defaultPoolMig, 1)
+	assert.NoError(t, err)
+	mock.AssertExpectationsForObjects(t, server)
+}
+
 func TestGetMigOptions(t *testing.T) {
 	defaultOptions := &config.NodeGroupAutoscalingOptions{
 		ScaleDownUtilizationThreshold:    0.1,
@@ -28,6 +28,8 @@ const (
 	gcePrefix           = gceUrlSchema + "://www." + gceDomainSuffix
 	instanceUrlTemplate = gcePrefix + "%s/zones/%s/instances/%s"
 	migUrlTemplate      = gcePrefix + "%s/zones/%s/instanceGroups/%s"
+	regionalMigTemplate = gcePrefix + "%s/regions/%s/instanceGroups/%s"
+	migZoneTemplate     = regionalMigTemplate + "?zone=%s"
 )
 
 // ParseMigUrl expects url in format:
@@ -36,6 +38,17 @@ func ParseMigUrl(url string) (project string, zone string, name string, err erro
 	return parseGceUrl(url, "instanceGroups")
 }
 
+// ParseRegionalMigUrl expects url in format:
+// https://www.googleapis.com/compute/v1/projects/<project-id>/regions/<region>/instanceGroups/<name>
+func ParseRegionalMigUrl(url string) (project string, region string, name string, err error) {
+	return parseGceLocationUrl(url, "regions", "instanceGroups")
+}
+
+// IsRegionalMigUrl returns true if the url refers to a regional MIG.
+func IsRegionalMigUrl(url string) bool {
+	return strings.Contains(url, "/regions/")
+}
+
 // ParseIgmUrl expects url in format:
 // https://www.googleapis.com/compute/v1/projects/<project-id>/zones/<zone>/instanceGroupManagers/<name>
 func ParseIgmUrl(url string) (project string, zone string, name string, err error) {
@@ -70,11 +83,21 @@ func GenerateInstanceUrl(ref GceRef) string {
 
 // GenerateMigUrl generates url for instance.
 func GenerateMigUrl(ref GceRef) string {
+	if ref.isMigZone() {
+		return fmt.Sprintf(migZoneTemplate, ref.Project, ref.Region, ref.Name, ref.Zone)
+	}
+	if ref.Region != "" {
+		return fmt.Sprintf(regionalMigTemplate, ref.Project, ref.Region, ref.Name)
+	}
 	return fmt.Sprintf(migUrlTemplate, ref.Project, ref.Zone, ref.Name)
 }
 
 func parseGceUrl(url, expectedResource string) (project string, zone string, name string, err error) {
-	errMsg := fmt.Errorf("wrong url: expected format https://www.googleapis.com/compute/v1/projects/<project-id>/zones/<zone>/%s/<name>, got %s", expectedResource, url)
+	return parseGceLocationUrl(url, "zones", expectedResource)
+}
+
+func parseGceLocationUrl(url, locationType, expectedResource string) (project string, location string, name string, err error) {
+	errMsg := fmt.Errorf("wrong url: expected format https://www.googleapis.com/compute/v1/projects/<project-id>/%s/<%s>/%s/<name>, got %s", locationType, strings.TrimSuffix(locationType, "s"), expectedResource, url)
 	if !strings.Contains(url, gceDomainSuffix) {
 		return "", "", "", errMsg
 	}
@@ -82,14 +105,14 @@ func parseGceUrl(url, expectedResource string) (project string, zone string, nam
 		return "", "", "", errMsg
 	}
 	splitted := strings.Split(strings.Split(url, gceDomainSuffix)[1], "/")
-	if len(splitted) != 5 || splitted[1] != "zones" {
+	if len(splitted) != 5 || splitted[1] != locationType {
 		return "", "", "", errMsg
 	}
 	if splitted[3] != expectedResource {
 		return "", "", "", fmt.Errorf("wrong resource in url: expected %s, got %s", expectedResource, splitted[3])
 	}
 	project = splitted[0]
-	zone = splitted[2]
+	location = splitted[2]
 	name = splitted[4]
-	return project, zone, name, nil
+	return project, location, name, nil
 }
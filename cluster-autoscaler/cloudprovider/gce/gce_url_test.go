@@ -43,3 +43,20 @@ func TestParseUrl(t *testing.T) {
 	_, _, _, err = parseGceUrl("https://www.googleapis.com/compute/vabc/projects/mwielgus-proj/zones/us-central1-b/instanceGroups/kubernetes-minion-group", "instanceGroups")
 	assert.NotNil(t, err)
 }
+
+func TestParseRegionalMigUrl(t *testing.T) {
+	url := "https://www.googleapis.com/compute/v1/projects/mwielgus-proj/regions/us-central1/instanceGroups/kubernetes-minion-group"
+	assert.True(t, IsRegionalMigUrl(url))
+	proj, region, name, err := ParseRegionalMigUrl(url)
+	assert.Nil(t, err)
+	assert.Equal(t, "mwielgus-proj", proj)
+	assert.Equal(t, "us-central1", region)
+	assert.Equal(t, "kubernetes-minion-group", name)
+	assert.Equal(t, url, GenerateMigUrl(GceRef{Project: proj, Region: region, Name: name}))
+	assert.Equal(t, url+"?zone=us-central1-b", GenerateMigUrl(GceRef{Project: proj, Region: region, Zone: "us-central1-b", Name: name}))
+
+	zonalUrl := "https://www.googleapis.com/compute/v1/projects/mwielgus-proj/zones/us-central1-b/instanceGroups/kubernetes-minion-group"
+	assert.False(t, IsRegionalMigUrl(zonalUrl))
+	_, _, _, err = ParseRegionalMigUrl(zonalUrl)
+	assert.NotNil(t, err)
+}
@@ -165,13 +165,21 @@ func (c *cachingMigInfoProvider) findMigWithMatchingBasename(instanceRef GceRef)
 	for _, mig := range c.migLister.GetMigs() {
 		migRef := mig.GceRef()
 		basename, err := c.GetMigBasename(migRef)
-		if err == nil && migRef.Project == instanceRef.Project && migRef.Zone == instanceRef.Zone && strings.HasPrefix(instanceRef.Name, basename) {
+		if err == nil && migRef.Project == instanceRef.Project && inMigLocation(migRef, instanceRef.Zone) && strings.HasPrefix(instanceRef.Name, basename) {
 			return mig
 		}
 	}
 	return nil
 }
 
+// inMigLocation returns true if the zone is the one of the zonal MIG, or one of the region of the regional MIG.
+func inMigLocation(migRef GceRef, zone string) bool {
+	if migRef.Region != "" && migRef.Zone == "" {
+		return strings.HasPrefix(zone, migRef.Region+"-")
+	}
+	return migRef.Zone == zone
+}
+
 func (c *cachingMigInfoProvider) fillMigInstances(migRef GceRef) error {
 	if val, ok := c.cache.GetMigInstancesUpdateTime(migRef); ok {
 		// do not regenerate MIG instances cache if last refresh happened recently.
@@ -199,14 +207,17 @@ func (c *cachingMigInfoProvider) GetMigTargetSize(migRef GceRef) (int64, error)
 		return targetSize, nil
 	}
 
-	err := c.fillMigInfoCache()
-	targetSize, found = c.cache.GetMigTargetSize(migRef)
-	if err == nil && found {
-		return targetSize, nil
+	// GCE only tracks the target size of regional MIGs as a whole, the ones of their zones are counted from their instances.
+	if !migRef.isMigZone() {
+		err := c.fillMigInfoCache()
+		targetSize, found = c.cache.GetMigTargetSize(migRef)
+		if err == nil && found {
+			return targetSize, nil
+		}
 	}
 
 	// fallback to querying for single mig
-	targetSize, err = c.gceClient.FetchMigTargetSize(migRef)
+	targetSize, err := c.gceClient.FetchMigTargetSize(migRef)
 	if err != nil {
 		c.migLister.HandleMigIssue(migRef, err)
 		return 0, err
@@ -287,56 +298,90 @@ func (c *cachingMigInfoProvider) GetMigInstanceTemplate(migRef GceRef) (*gce.Ins
 
 // filMigInfoCache needs to be called with migInfoMutex locked
 func (c *cachingMigInfoProvider) fillMigInfoCache() error {
-	var zones []string
-	for zone := range c.listAllZonesWithMigs() {
+	zonesWithMigs, regionsWithMigs := c.listAllLocationsWithMigs()
+	var zones, regions []string
+	for zone := range zonesWithMigs {
 		zones = append(zones, zone)
 	}
+	for region := range regionsWithMigs {
+		regions = append(regions, region)
+	}
+	locations := len(zones) + len(regions)
 
-	migs := make([][]*gce.InstanceGroupManager, len(zones))
-	errors := make([]error, len(zones))
-	workqueue.ParallelizeUntil(context.Background(), len(zones), len(zones), func(piece int) {
-		migs[piece], errors[piece] = c.gceClient.FetchAllMigs(zones[piece])
+	// Regional MIGs are listed after the zonal ones, their location is their region.
+	migs := make([][]*gce.InstanceGroupManager, locations)
+	errors := make([]error, locations)
+	workqueue.ParallelizeUntil(context.Background(), locations, locations, func(piece int) {
+		if piece < len(zones) {
+			migs[piece], errors[piece] = c.gceClient.FetchAllMigs(zones[piece])
+		} else {
+			migs[piece], errors[piece] = c.gceClient.FetchAllRegionalMigs(regions[piece-len(zones)])
+		}
 	})
+	location := func(idx int) string {
+		if idx < len(zones) {
+			return zones[idx]
+		}
+		return regions[idx-len(zones)]
+	}
 
-	failedZones := map[string]error{}
-	failedZoneCount := 0
+	failedLocations := map[string]error{}
+	failedLocationCount := 0
 	for idx, err := range errors {
 		if err != nil {
-			klog.Errorf("Error listing migs from zone %v; err=%v", zones[idx], err)
-			failedZones[zones[idx]] = err
-			failedZoneCount++
+			klog.Errorf("Error listing migs from %v; err=%v", location(idx), err)
+			failedLocations[location(idx)] = err
+			failedLocationCount++
 		}
 	}
 
-	if failedZoneCount > 0 && failedZoneCount == len(zones) {
+	if failedLocationCount > 0 && failedLocationCount == locations {
 		return fmt.Errorf("%v", errors)
 	}
 
 	registeredMigRefs := c.getRegisteredMigRefs()
+	// Regional MIGs split into zones are listed once, and their info is shared by the node groups of their zones.
+	migZoneRefs := map[GceRef][]GceRef{}
+	for migRef := range registeredMigRefs {
+		if migRef.isMigZone() {
+			migZoneRefs[migRef.regionalMig()] = append(migZoneRefs[migRef.regionalMig()], migRef)
+		}
+	}
 
 	for migRef := range registeredMigRefs {
-		err, ok := failedZones[migRef.Zone]
+		err, ok := failedLocations[migLocation(migRef)]
 		if ok {
 			c.migLister.HandleMigIssue(migRef, err)
 		}
 	}
 
-	for idx, zone := range zones {
-		for _, zoneMig := range migs[idx] {
-			zoneMigRef := GceRef{
-				c.projectId,
-				zone,
-				zoneMig.Name,
+	for idx := range migs {
+		for _, locationMig := range migs[idx] {
+			locationMigRef := GceRef{
+				Project: c.projectId,
+				Name:    locationMig.Name,
+			}
+			if idx < len(zones) {
+				locationMigRef.Zone = zones[idx]
+			} else {
+				locationMigRef.Region = regions[idx-len(zones)]
 			}
 
-			if registeredMigRefs[zoneMigRef] {
-				c.cache.SetMigTargetSize(zoneMigRef, zoneMig.TargetSize)
-				c.cache.SetMigBasename(zoneMigRef, zoneMig.BaseInstanceName)
+			refs := migZoneRefs[locationMigRef]
+			if registeredMigRefs[locationMigRef] {
+				c.cache.SetMigTargetSize(locationMigRef, locationMig.TargetSize)
+				if migZones := migDistribution(locationMig).Zones; locationMigRef.Region != "" && len(migZones) > 0 {
+					c.cache.SetRegionalMigZones(locationMigRef, migZones)
+				}
+				refs = append(refs, locationMigRef)
+			}
+			for _, ref := range refs {
+				c.cache.SetMigBasename(ref, locationMig.BaseInstanceName)
 
-				templateUrl, err := url.Parse(zoneMig.InstanceTemplate)
+				templateUrl, err := url.Parse(locationMig.InstanceTemplate)
 				if err == nil {
 					_, templateName := path.Split(templateUrl.EscapedPath())
-					c.cache.SetMigInstanceTemplateName(zoneMigRef, templateName)
+					c.cache.SetMigInstanceTemplateName(ref, templateName)
 				}
 			}
 		}
@@ -353,12 +398,47 @@ func (c *cachingMigInfoProvider) getRegisteredMigRefs() map[GceRef]bool {
 	return migRefs
 }
 
-func (c *cachingMigInfoProvider) listAllZonesWithMigs() map[string]bool {
-	zones := map[string]bool{}
+// listAllLocationsWithMigs returns the zones with zonal MIGs and the regions with regional MIGs.
+func (c *cachingMigInfoProvider) listAllLocationsWithMigs() (zones map[string]bool, regions map[string]bool) {
+	zones = map[string]bool{}
+	regions = map[string]bool{}
 	for _, mig := range c.migLister.GetMigs() {
-		zones[mig.GceRef().Zone] = true
+		if mig.GceRef().Region != "" {
+			regions[mig.GceRef().Region] = true
+		} else {
+			zones[mig.GceRef().Zone] = true
+		}
+	}
+	return zones, regions
+}
+
+// migLocation returns the zone of zonal MIGs and the region of regional MIGs.
+func migLocation(migRef GceRef) string {
+	if migRef.Region != "" {
+		return migRef.Region
+	}
+	return migRef.Zone
+}
+
+// machineTypeZone returns the zone in which the machine type of the MIG is looked up: its zone, or for
+// regional MIGs not split into zones the first zone of their distribution policy.
+func (c *cachingMigInfoProvider) machineTypeZone(migRef GceRef) (string, error) {
+	if migRef.Zone != "" {
+		return migRef.Zone, nil
+	}
+	zones, found := c.cache.GetRegionalMigZones(migRef)
+	if !found {
+		distribution, err := c.gceClient.FetchMigDistribution(migRef)
+		if err != nil {
+			return "", err
+		}
+		zones = distribution.Zones
+		c.cache.SetRegionalMigZones(migRef, zones)
+	}
+	if len(zones) == 0 {
+		return "", fmt.Errorf("mig %s has no distribution policy zones", migRef.String())
 	}
-	return zones
+	return zones[0], nil
 }
 
 func (c *cachingMigInfoProvider) GetMigMachineType(migRef GceRef) (MachineType, error) {
@@ -370,7 +450,11 @@ func (c *cachingMigInfoProvider) GetMigMachineType(migRef GceRef) (MachineType,
 	if IsCustomMachine(machineName) {
 		return NewCustomMachineType(machineName)
 	}
-	zone := migRef.Zone
+	zone, err := c.machineTypeZone(migRef)
+	if err != nil {
+		c.migLister.HandleMigIssue(migRef, err)
+		return MachineType{}, err
+	}
 	machine, found := c.cache.GetMachine(machineName, zone)
 	if !found {
 		rawMachine, err := c.gceClient.FetchMachineType(zone, machineName)
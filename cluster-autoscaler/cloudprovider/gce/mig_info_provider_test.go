@@ -49,12 +49,15 @@ var (
 
 type mockAutoscalingGceClient struct {
 	fetchMigs            func(string) ([]*gce.InstanceGroupManager, error)
+	fetchRegionalMigs    func(string) ([]*gce.InstanceGroupManager, error)
 	fetchMigTargetSize   func(GceRef) (int64, error)
 	fetchMigBasename     func(GceRef) (string, error)
 	fetchMigInstances    func(GceRef) ([]cloudprovider.Instance, error)
 	fetchMigTemplateName func(GceRef) (string, error)
 	fetchMigTemplate     func(GceRef, string) (*gce.InstanceTemplate, error)
 	fetchMachineType     func(string, string) (*gce.MachineType, error)
+	createInstances      func(GceRef, string, int64, []string) error
+	deleteInstances      func(GceRef, []GceRef) error
 }
 
 func (client *mockAutoscalingGceClient) FetchMachineType(zone, machineName string) (*gce.MachineType, error) {
@@ -69,6 +72,10 @@ func (client *mockAutoscalingGceClient) FetchAllMigs(zone string) ([]*gce.Instan
 	return client.fetchMigs(zone)
 }
 
+func (client *mockAutoscalingGceClient) FetchAllRegionalMigs(region string) ([]*gce.InstanceGroupManager, error) {
+	return client.fetchRegionalMigs(region)
+}
+
 func (client *mockAutoscalingGceClient) FetchMigTargetSize(migRef GceRef) (int64, error) {
 	return client.fetchMigTargetSize(migRef)
 }
@@ -93,6 +100,14 @@ func (client *mockAutoscalingGceClient) FetchMigsWithName(_ string, _ *regexp.Re
 	return nil, nil
 }
 
+func (client *mockAutoscalingGceClient) FetchRegionalMigsWithName(_ string, _ *regexp.Regexp) ([]string, error) {
+	return nil, nil
+}
+
+func (client *mockAutoscalingGceClient) FetchMigDistribution(_ GceRef) (MigDistribution, error) {
+	return MigDistribution{}, nil
+}
+
 func (client *mockAutoscalingGceClient) FetchZones(_ string) ([]string, error) {
 	return nil, nil
 }
@@ -113,12 +128,18 @@ func (client *mockAutoscalingGceClient) ResizeMig(_ GceRef, _ int64) error {
 	return nil
 }
 
-func (client *mockAutoscalingGceClient) DeleteInstances(_ GceRef, _ []GceRef) error {
-	return nil
+func (client *mockAutoscalingGceClient) DeleteInstances(migRef GceRef, instances []GceRef) error {
+	if client.deleteInstances == nil {
+		return nil
+	}
+	return client.deleteInstances(migRef, instances)
 }
 
-func (client *mockAutoscalingGceClient) CreateInstances(_ GceRef, _ string, _ int64, _ []string) error {
-	return nil
+func (client *mockAutoscalingGceClient) CreateInstances(migRef GceRef, baseName string, delta int64, existingInstanceProviderIds []string) error {
+	if client.createInstances == nil {
+		return nil
+	}
+	return client.createInstances(migRef, baseName, delta, existingInstanceProviderIds)
 }
 
 func TestFillMigInstances(t *testing.T) {
@@ -677,6 +698,44 @@ func TestGetMigTargetSize(t *testing.T) {
 	}
 }
 
+func TestGetRegionalMigTargetSize(t *testing.T) {
+	regionalMig := &gceMig{
+		gceRef: GceRef{
+			Project: "project",
+			Region:  "us-test1",
+			Name:    "regional-mig",
+		},
+	}
+	targetSize := int64(42)
+	instanceGroupManager := &gce.InstanceGroupManager{
+		Region:     regionalMig.GceRef().Region,
+		Name:       regionalMig.GceRef().Name,
+		TargetSize: targetSize,
+		DistributionPolicy: &gce.DistributionPolicy{
+			Zones: []*gce.DistributionPolicyZoneConfiguration{
+				{Zone: "https://www.googleapis.com/compute/v1/projects/project/zones/us-test1-b"},
+				{Zone: "https://www.googleapis.com/compute/v1/projects/project/zones/us-test1-c"},
+			},
+		},
+	}
+
+	cache := emptyCache()
+	cache.migs = map[GceRef]Mig{mig.GceRef(): mig, regionalMig.GceRef(): regionalMig}
+	client := &mockAutoscalingGceClient{
+		fetchMigs:         fetchMigsConst([]*gce.InstanceGroupManager{}),
+		fetchRegionalMigs: fetchMigsConst([]*gce.InstanceGroupManager{instanceGroupManager}),
+	}
+	migLister := NewMigLister(cache)
+	provider := NewCachingMigInfoProvider(cache, migLister, client, regionalMig.GceRef().Project, 1, 0*time.Second)
+
+	size, err := provider.GetMigTargetSize(regionalMig.GceRef())
+	assert.NoError(t, err)
+	assert.Equal(t, targetSize, size)
+	zones, found := cache.GetRegionalMigZones(regionalMig.GceRef())
+	assert.True(t, found)
+	assert.Equal(t, []string{"us-test1-b", "us-test1-c"}, zones)
+}
+
 func TestGetMigBasename(t *testing.T) {
 	basename := "base-instance-name"
 	instanceGroupManager := &gce.InstanceGroupManager{
@@ -1134,6 +1193,7 @@ func emptyCache() *GceCache {
 		instanceTemplateNameCache: make(map[GceRef]string),
 		instanceTemplatesCache:    make(map[GceRef]*gce.InstanceTemplate),
 		instancesFromUnknownMig:   make(map[GceRef]bool),
+		regionalMigZonesCache:     make(map[GceRef][]string),
 	}
 }
 
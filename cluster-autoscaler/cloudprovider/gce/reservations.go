@@ -0,0 +1,74 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gce
+
+import (
+	"path"
+	"strings"
+
+	gce "google.golang.org/api/compute/v1"
+)
+
+const (
+	specificReservationType = "SPECIFIC_RESERVATION"
+	reservationNameKey      = "compute.googleapis.com/reservation-name"
+)
+
+// reservationRef identifies a reservation targeted by the reservation affinity of an instance template.
+type reservationRef struct {
+	Project string
+	Name    string
+}
+
+// specificReservations returns the reservations the instances created from the template must consume,
+// or nil if they may be created outside of reservations. Shared reservations are referenced as
+// projects/<project>/reservations/<name>, the other ones belong to defaultProject.
+func specificReservations(template *gce.InstanceTemplate, defaultProject string) []reservationRef {
+	if template == nil || template.Properties == nil || template.Properties.ReservationAffinity == nil {
+		return nil
+	}
+	affinity := template.Properties.ReservationAffinity
+	if affinity.ConsumeReservationType != specificReservationType || affinity.Key != reservationNameKey {
+		return nil
+	}
+	refs := make([]reservationRef, 0, len(affinity.Values))
+	for _, value := range affinity.Values {
+		ref := reservationRef{Project: defaultProject, Name: value}
+		if parts := strings.Split(value, "/"); len(parts) == 4 && parts[0] == "projects" && parts[2] == "reservations" {
+			ref = reservationRef{Project: parts[1], Name: parts[3]}
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// reservationCapacity returns the number of instances that can still be created in the given zone from the
+// reservations, and whether any of them was found there.
+func reservationCapacity(reservations []*gce.Reservation, names map[string]bool, zone string) (int64, bool) {
+	var capacity int64
+	found := false
+	for _, reservation := range reservations {
+		if !names[reservation.Name] || path.Base(reservation.Zone) != zone || reservation.SpecificReservation == nil {
+			continue
+		}
+		found = true
+		if free := reservation.SpecificReservation.Count - reservation.SpecificReservation.InUseCount; free > 0 {
+			capacity += free
+		}
+	}
+	return capacity, found
+}
@@ -238,6 +238,7 @@ func (t *GceTemplateBuilder) BuildNodeFromTemplate(mig Mig, migOsInfo MigOsInfo,
 	if err != nil {
 		return nil, err
 	}
+	gpu.ApplyMigProfiles(capacity, extractMigProfilesFromKubeEnv(kubeEnvValue))
 
 	node.Status = apiv1.NodeStatus{
 		Capacity: capacity,
@@ -372,6 +373,12 @@ func BuildGenericLabels(ref GceRef, machineType string, nodeName string, os Oper
 	result[apiv1.LabelOSStable] = string(os)
 
 	result[apiv1.LabelInstanceTypeStable] = machineType
+	result[apiv1.LabelHostname] = nodeName
+	if ref.Region != "" && ref.Zone == "" {
+		// The zone of the instances of regional MIGs not split into zones is only known once GCE created them.
+		result[apiv1.LabelTopologyRegion] = ref.Region
+		return result, nil
+	}
 	ix := strings.LastIndex(ref.Zone, "-")
 	if ix == -1 {
 		return nil, fmt.Errorf("unexpected zone: %s", ref.Zone)
@@ -379,7 +386,6 @@ func BuildGenericLabels(ref GceRef, machineType string, nodeName string, os Oper
 	result[apiv1.LabelTopologyRegion] = ref.Zone[:ix]
 	result[apiv1.LabelTopologyZone] = ref.Zone
 	result[gceCSITopologyKeyZone] = ref.Zone
-	result[apiv1.LabelHostname] = nodeName
 	return result, nil
 }
 
@@ -509,6 +515,25 @@ func extractExtendedResourcesFromKubeEnv(kubeEnvValue string) (apiv1.ResourceLis
 	return extendedResources, nil
 }
 
+// extractMigProfilesFromKubeEnv returns the MIG profiles GPUs are sliced into, defined
+// by the gpu_mig_profiles variable in AUTOSCALER_ENV_VARS, e.g. gpu_mig_profiles=1g.5gb x 7.
+func extractMigProfilesFromKubeEnv(kubeEnvValue string) []gpu.MigProfile {
+	migProfiles, found, err := extractAutoscalerVarFromKubeEnv(kubeEnvValue, "gpu_mig_profiles")
+	if err != nil {
+		klog.Warningf("error while obtaining gpu_mig_profiles from AUTOSCALER_ENV_VARS; %v", err)
+		return nil
+	}
+	if !found {
+		return nil
+	}
+	profiles, err := gpu.ParseMigProfiles(migProfiles)
+	if err != nil {
+		klog.Warningf("ignoring invalid gpu_mig_profiles defined in AUTOSCALER_ENV_VARS; %v", err)
+		return nil
+	}
+	return profiles
+}
+
 // OperatingSystem denotes operating system used by nodes coming from node group
 type OperatingSystem string
 
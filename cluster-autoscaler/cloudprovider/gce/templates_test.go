@@ -383,6 +383,25 @@ func TestBuildGenericLabels(t *testing.T) {
 	}
 }
 
+func TestBuildGenericLabelsRegional(t *testing.T) {
+	labels, err := BuildGenericLabels(GceRef{
+		Name:    "kubernetes-minion-group",
+		Project: "mwielgus-proj",
+		Region:  "us-central1"},
+		"n1-standard-8",
+		"sillyname",
+		OperatingSystemLinux,
+		Amd64)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		apiv1.LabelTopologyRegion:     "us-central1",
+		apiv1.LabelHostname:           "sillyname",
+		apiv1.LabelInstanceTypeStable: "n1-standard-8",
+		apiv1.LabelArchStable:         "amd64",
+		apiv1.LabelOSStable:           "linux",
+	}, labels)
+}
+
 func TestCalculateAllocatable(t *testing.T) {
 	type testCase struct {
 		scenario                    string
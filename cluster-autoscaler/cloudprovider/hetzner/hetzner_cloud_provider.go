@@ -212,7 +212,8 @@ func BuildHetzner(_ config.AutoscalingOptions, do cloudprovider.NodeGroupDiscove
 			minSize:            spec.minSize,
 			maxSize:            spec.maxSize,
 			instanceType:       strings.ToLower(spec.instanceType),
-			region:             strings.ToLower(spec.region),
+			region:             placementLocation(spec.placements[0]),
+			placements:         spec.placements,
 			targetSize:         len(servers),
 			clusterUpdateMutex: &clusterUpdateLock,
 		}
@@ -229,9 +230,14 @@ func createNodePoolSpec(groupSpec string) (*hetznerNodeGroupSpec, error) {
 
 	definition := hetznerNodeGroupSpec{
 		instanceType: tokens[2],
-		region:       tokens[3],
+		placements:   strings.Split(strings.ToLower(tokens[3]), ","),
 		name:         tokens[4],
 	}
+	for _, placement := range definition.placements {
+		if placement == "" {
+			return nil, fmt.Errorf("failed to set region: %s, expected comma separated locations or datacenters", tokens[3])
+		}
+	}
 	if size, err := strconv.Atoi(tokens[0]); err == nil {
 		definition.minSize = size
 	} else {
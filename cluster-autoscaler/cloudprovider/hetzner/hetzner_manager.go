@@ -50,6 +50,7 @@ type hetznerManager struct {
 	sshKey           *hcloud.SSHKey
 	network          *hcloud.Network
 	firewall         *hcloud.Firewall
+	placementGroup   *hcloud.PlacementGroup
 	createTimeout    time.Duration
 	publicIPv4       bool
 	publicIPv6       bool
@@ -138,6 +139,18 @@ func newManager() (*hetznerManager, error) {
 		}
 	}
 
+	var placementGroup *hcloud.PlacementGroup
+	placementGroupName := os.Getenv("HCLOUD_PLACEMENT_GROUP")
+	if placementGroupName != "" {
+		placementGroup, _, err = client.PlacementGroup.Get(ctx, placementGroupName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get placement group error: %s", err)
+		}
+		if placementGroup == nil {
+			return nil, fmt.Errorf("placement group %s not found", placementGroupName)
+		}
+	}
+
 	m := &hetznerManager{
 		client:           client,
 		nodeGroups:       make(map[string]*hetznerNodeGroup),
@@ -146,6 +159,7 @@ func newManager() (*hetznerManager, error) {
 		sshKey:           sshKey,
 		network:          network,
 		firewall:         firewall,
+		placementGroup:   placementGroup,
 		createTimeout:    createTimeout,
 		apiCallContext:   ctx,
 		publicIPv4:       publicIPv4,
@@ -158,6 +172,7 @@ func newManager() (*hetznerManager, error) {
 		manager:      m,
 		instanceType: "cx11",
 		region:       "fsn1",
+		placements:   []string{"fsn1"},
 		targetSize:   0,
 		maxSize:      0,
 		minSize:      0,
@@ -18,10 +18,12 @@ package hetzner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	apiv1 "k8s.io/api/core/v1"
@@ -45,6 +47,10 @@ type hetznerNodeGroup struct {
 	targetSize   int
 	region       string
 	instanceType string
+	// placements are the locations, or datacenters, servers are created in, in turn. region is the
+	// location of the first one.
+	placements    []string
+	nextPlacement int
 
 	clusterUpdateMutex *sync.Mutex
 }
@@ -53,7 +59,7 @@ type hetznerNodeGroupSpec struct {
 	name         string
 	minSize      int
 	maxSize      int
-	region       string
+	placements   []string
 	instanceType string
 }
 
@@ -100,27 +106,35 @@ func (n *hetznerNodeGroup) IncreaseSize(delta int) error {
 	n.clusterUpdateMutex.Lock()
 	defer n.clusterUpdateMutex.Unlock()
 
-	available, err := serverTypeAvailable(n.manager, n.instanceType, n.region)
-	if err != nil {
-		return fmt.Errorf("failed to check if type %s is available in region %s error: %v", n.instanceType, n.region, err)
+	var placements []string
+	for _, placement := range n.placements {
+		available, err := serverTypeAvailable(n.manager, n.instanceType, placementLocation(placement))
+		if err != nil {
+			return fmt.Errorf("failed to check if type %s is available in region %s error: %v", n.instanceType, placementLocation(placement), err)
+		}
+		if available {
+			placements = append(placements, placement)
+		}
 	}
-	if !available {
-		return fmt.Errorf("server type %s not available in region %s", n.instanceType, n.region)
+	if len(placements) == 0 {
+		return fmt.Errorf("server type %s not available in region %s", n.instanceType, strings.Join(n.placements, ","))
 	}
 
+	var failed int32
 	waitGroup := sync.WaitGroup{}
 	for i := 0; i < delta; i++ {
 		waitGroup.Add(1)
-		go func() {
+		go func(placements []string) {
 			defer waitGroup.Done()
-			err := createServer(n)
+			err := createServerInPlacements(n, placements)
 			if err != nil {
-				targetSize--
+				atomic.AddInt32(&failed, 1)
 				klog.Errorf("failed to create error: %v", err)
 			}
-		}()
+		}(n.placementOrder(placements))
 	}
 	waitGroup.Wait()
+	targetSize -= int(failed)
 
 	n.targetSize = targetSize
 
@@ -172,6 +186,11 @@ func (n *hetznerNodeGroup) DeleteNodes(nodes []*apiv1.Node) error {
 	return nil
 }
 
+// ForceDeleteNodes deletes nodes from the group regardless of constraints.
+func (n *hetznerNodeGroup) ForceDeleteNodes(nodes []*apiv1.Node) error {
+	return cloudprovider.ErrNotImplemented
+}
+
 // DecreaseTargetSize decreases the target size of the node group. This function
 // doesn't permit to delete any existing node and can be used only to reduce the
 // request for new nodes that have not been yet fulfilled. Delta should be negative.
@@ -278,6 +297,12 @@ func (n *hetznerNodeGroup) Autoprovisioned() bool {
 	return false
 }
 
+// BillingPeriod returns the billing granularity of the servers of the node group, which are billed
+// per started hour.
+func (n *hetznerNodeGroup) BillingPeriod() time.Duration {
+	return time.Hour
+}
+
 func toInstance(vm *hcloud.Server) cloudprovider.Instance {
 	return cloudprovider.Instance{
 		Id:     toProviderID(vm.ID),
@@ -381,7 +406,51 @@ func instanceTypeArch(manager *hetznerManager, instanceType string) (string, err
 	}
 }
 
-func createServer(n *hetznerNodeGroup) error {
+// placementOrder returns the placements to try for the next server, starting with the next one in
+// turn and followed by the others to fail over to.
+func (n *hetznerNodeGroup) placementOrder(placements []string) []string {
+	start := n.nextPlacement % len(placements)
+	n.nextPlacement++
+	return append(append([]string{}, placements[start:]...), placements[:start]...)
+}
+
+// placementLocation returns the location of a placement, either a location (e.g. fsn1) or a
+// datacenter (e.g. fsn1-dc14).
+func placementLocation(placement string) string {
+	if i := strings.Index(placement, "-"); i > 0 {
+		return placement[:i]
+	}
+	return placement
+}
+
+// isResourceExhausted returns true if a server couldn't be created because its location or
+// datacenter is out of resources, in which case it may be created in another one.
+func isResourceExhausted(err error) bool {
+	var code string
+	var apiErr hcloud.Error
+	var actionErr hcloud.ActionError
+	if errors.As(err, &apiErr) {
+		code = string(apiErr.Code)
+	} else if errors.As(err, &actionErr) {
+		code = actionErr.Code
+	}
+	return code == string(hcloud.ErrorCodeResourceUnavailable) || code == string(hcloud.ErrorCodePlacementError)
+}
+
+// createServerInPlacements creates a server in the first of the placements not out of resources.
+func createServerInPlacements(n *hetznerNodeGroup, placements []string) error {
+	var err error
+	for _, placement := range placements {
+		err = createServer(n, placement)
+		if err == nil || !isResourceExhausted(err) {
+			return err
+		}
+		klog.Warningf("Placement %s of node group %s is out of resources, failing over: %v", placement, n.id, err)
+	}
+	return err
+}
+
+func createServer(n *hetznerNodeGroup, placement string) error {
 	serverType, err := n.manager.cachedServerType.getServerType(n.instanceType)
 	if err != nil {
 		return err
@@ -396,7 +465,6 @@ func createServer(n *hetznerNodeGroup) error {
 	opts := hcloud.ServerCreateOpts{
 		Name:             newNodeName(n),
 		UserData:         n.manager.cloudInit,
-		Location:         &hcloud.Location{Name: n.region},
 		ServerType:       serverType,
 		Image:            image,
 		StartAfterCreate: &StartAfterCreate,
@@ -408,6 +476,14 @@ func createServer(n *hetznerNodeGroup) error {
 			EnableIPv6: n.manager.publicIPv6,
 		},
 	}
+	if placementLocation(placement) != placement {
+		opts.Datacenter = &hcloud.Datacenter{Name: placement}
+	} else {
+		opts.Location = &hcloud.Location{Name: placement}
+	}
+	if n.manager.placementGroup != nil {
+		opts.PlacementGroup = n.manager.placementGroup
+	}
 	if n.manager.sshKey != nil {
 		opts.SSHKeys = []*hcloud.SSHKey{n.manager.sshKey}
 	}
@@ -421,7 +497,7 @@ func createServer(n *hetznerNodeGroup) error {
 
 	serverCreateResult, _, err := n.manager.client.Server.Create(n.manager.apiCallContext, opts)
 	if err != nil {
-		return fmt.Errorf("could not create server type %s in region %s: %v", n.instanceType, n.region, err)
+		return fmt.Errorf("could not create server type %s in region %s: %w", n.instanceType, placement, err)
 	}
 
 	action := serverCreateResult.Action
@@ -429,7 +505,7 @@ func createServer(n *hetznerNodeGroup) error {
 	err = waitForServerAction(n.manager, server.Name, action)
 	if err != nil {
 		_ = n.manager.deleteServer(server)
-		return fmt.Errorf("failed to start server %s error: %v", server.Name, err)
+		return fmt.Errorf("failed to start server %s error: %w", server.Name, err)
 	}
 
 	return nil
@@ -498,7 +574,7 @@ func waitForServerAction(m *hetznerManager, serverName string, action *hcloud.Ac
 	select {
 	case err := <-errChan:
 		if err != nil {
-			return fmt.Errorf("error while waiting for server action: %s: %v", serverName, err)
+			return fmt.Errorf("error while waiting for server action: %s: %w", serverName, err)
 		}
 		return nil
 	case <-time.After(m.createTimeout):
@@ -0,0 +1,62 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hetzner
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/hetzner/hcloud-go/hcloud"
+)
+
+func TestCreateNodePoolSpecPlacements(t *testing.T) {
+	spec, err := createNodePoolSpec("1:10:CPX51:FSN1,nbg1-dc3:pool1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fsn1", "nbg1-dc3"}, spec.placements)
+
+	_, err = createNodePoolSpec("1:10:CPX51:fsn1,:pool1")
+	assert.Error(t, err)
+}
+
+func TestPlacementOrder(t *testing.T) {
+	n := &hetznerNodeGroup{}
+	placements := []string{"fsn1", "nbg1", "hel1"}
+
+	// Servers are placed in turn, failing over to the following placements.
+	assert.Equal(t, []string{"fsn1", "nbg1", "hel1"}, n.placementOrder(placements))
+	assert.Equal(t, []string{"nbg1", "hel1", "fsn1"}, n.placementOrder(placements))
+	assert.Equal(t, []string{"hel1", "fsn1", "nbg1"}, n.placementOrder(placements))
+	assert.Equal(t, []string{"fsn1", "nbg1", "hel1"}, n.placementOrder(placements))
+}
+
+func TestPlacementLocation(t *testing.T) {
+	assert.Equal(t, "fsn1", placementLocation("fsn1"))
+	assert.Equal(t, "fsn1", placementLocation("fsn1-dc14"))
+}
+
+func TestIsResourceExhausted(t *testing.T) {
+	unavailable := hcloud.Error{Code: hcloud.ErrorCodeResourceUnavailable, Message: "resource unavailable"}
+	assert.True(t, isResourceExhausted(unavailable))
+	assert.True(t, isResourceExhausted(fmt.Errorf("could not create server: %w", unavailable)))
+	placement := hcloud.ActionError{Code: string(hcloud.ErrorCodePlacementError), Message: "placement failed"}
+	assert.True(t, isResourceExhausted(fmt.Errorf("failed to start server: %w", placement)))
+
+	assert.False(t, isResourceExhausted(hcloud.Error{Code: hcloud.ErrorCodeInvalidInput, Message: "invalid input"}))
+	assert.False(t, isResourceExhausted(fmt.Errorf("timeout waiting for server")))
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fixtures
+
+import "fmt"
+
+// The test-ng node group is backed by a Senlin cluster whose ID is the
+// stack ID of the node group, TestNodeGroupStackUUID.
+const (
+	SenlinProfileUUID = "9dbb3f6f-5e2e-4d8a-a1e6-1d3c5c1c0a52"
+
+	SenlinActiveNodeUUID     = "5b9a2f4e-6a39-4d84-bb49-bd0f2c8e6a1c"
+	SenlinActiveServerUUID   = "0e4de0b1-7e50-4a1c-9a7f-5b0c1a2f0d55"
+	SenlinCreatingNodeUUID   = "a2c0d8f5-4b1e-4e2f-8d7b-8b3f36e2a6d1"
+	SenlinErrorNodeUUID      = "c7f1e9b2-3d6a-4f0e-9e15-2b8d4a7c9f03"
+	SenlinDeletingNodeUUID   = "e3b6a1d9-8c2f-4a5b-b7e0-6f9d2c4a1b87"
+	SenlinDeletingServerUUID = "1f8c3a2e-9b4d-4e6f-a0c1-7d5e3b9f2a46"
+)
+
+// GetSenlinClusterResponse is a response for a Get request for the Senlin cluster of test-ng.
+var GetSenlinClusterResponse = fmt.Sprintf(`
+{
+  "cluster": {
+    "id": "%s",
+    "name": "kube-test-ng",
+    "profile_id": "%s",
+    "profile_name": "kube-test-ng-profile",
+    "desired_capacity": 3,
+    "min_size": 0,
+    "max_size": 4,
+    "nodes": ["%s", "%s", "%s", "%s"],
+    "metadata": {},
+    "status": "RESIZING",
+    "status_reason": "Cluster resizing"
+  }
+}
+`, TestNodeGroupStackUUID, SenlinProfileUUID, SenlinActiveNodeUUID, SenlinCreatingNodeUUID, SenlinErrorNodeUUID, SenlinDeletingNodeUUID)
+
+// ListSenlinNodesResponse is a response for a List request for the nodes of the Senlin cluster of test-ng.
+var ListSenlinNodesResponse = fmt.Sprintf(`
+{
+  "nodes": [
+    {
+      "id": "%s",
+      "name": "node-active",
+      "cluster_id": "%s",
+      "physical_id": "%s",
+      "index": 1,
+      "status": "ACTIVE",
+      "status_reason": "Creation succeeded"
+    },
+    {
+      "id": "%s",
+      "name": "node-creating",
+      "cluster_id": "%s",
+      "physical_id": "",
+      "index": 2,
+      "status": "CREATING",
+      "status_reason": "Creation in progress"
+    },
+    {
+      "id": "%s",
+      "name": "node-error",
+      "cluster_id": "%s",
+      "physical_id": "",
+      "index": 3,
+      "status": "ERROR",
+      "status_reason": "Failed in creating server: Quota exceeded for cores"
+    },
+    {
+      "id": "%s",
+      "name": "node-deleting",
+      "cluster_id": "%s",
+      "physical_id": "%s",
+      "index": 4,
+      "status": "DELETING",
+      "status_reason": "Deletion in progress"
+    }
+  ]
+}
+`, SenlinActiveNodeUUID, TestNodeGroupStackUUID, SenlinActiveServerUUID,
+	SenlinCreatingNodeUUID, TestNodeGroupStackUUID,
+	SenlinErrorNodeUUID, TestNodeGroupStackUUID,
+	SenlinDeletingNodeUUID, TestNodeGroupStackUUID, SenlinDeletingServerUUID)
+
+// GetSenlinProfileResponse is a response for a Get request for the Senlin profile of test-ng.
+var GetSenlinProfileResponse = fmt.Sprintf(`
+{
+  "profile": {
+    "id": "%s",
+    "name": "kube-test-ng-profile",
+    "type": "os.nova.server-1.0",
+    "spec": {
+      "type": "os.nova.server",
+      "version": "1.0",
+      "properties": {
+        "flavor": "m2.medium",
+        "image": "fedora-coreos",
+        "availability_zone": "nova-2"
+      }
+    },
+    "metadata": {}
+  }
+}
+`, SenlinProfileUUID)
+
+// SenlinActionResponse is a response for a request running an action on the Senlin cluster of test-ng.
+var SenlinActionResponse = `{"action": "2a0ff107-e789-4660-a122-3816c43af703"}`
@@ -31,7 +31,9 @@ const (
 var GetTestNGNodeGroupResponse = fmt.Sprintf(`
 {
   "links":[],
-  "labels":{},
+  "labels":{
+    "availability_zone":"nova-1"
+  },
   "updated_at":"2020-05-05T14:33:19+00:00",
   "cluster_id":"91444514-b8db-4314-849c-650df15e8e83",
   "min_node_count":1,
@@ -203,3 +205,21 @@ var GetTestNGKubeMinionsStackResponse = fmt.Sprintf(`
   }
 }
 `)
+
+// GetM2MediumFlavorResponse is a response for a Get request for the flavor of this node group.
+var GetM2MediumFlavorResponse = `
+{
+  "flavor":{
+    "id":"m2.medium",
+    "name":"m2.medium",
+    "vcpus":4,
+    "ram":8192,
+    "disk":40,
+    "swap":"",
+    "rxtx_factor":1.0,
+    "os-flavor-access:is_public":true,
+    "OS-FLV-EXT-DATA:ephemeral":0,
+    "links":[]
+  }
+}
+`
@@ -0,0 +1,47 @@
+/*
+Package clusters provides methods for interacting with the clusters of the
+Senlin clustering service: getting a cluster, resizing it, and removing
+given nodes from it.
+
+Create a client to use:
+
+	client, err := openstack.NewClusteringV1(provider, gophercloud.EndpointOpts{Region: os.Getenv("OS_REGION_NAME")})
+	if err != nil {
+	    panic(err)
+	}
+
+Example of Getting a cluster:
+
+	cluster, err := clusters.Get(client, clusterID).Extract()
+	if err != nil {
+	    panic(err)
+	}
+	fmt.Printf("%#v\n", cluster)
+
+Example of Resizing a cluster to an exact number of nodes:
+
+	number := 3
+	resizeOpts := clusters.ResizeOpts{
+	    AdjustmentType: clusters.ExactCapacityAdjustment,
+	    Number:         &number,
+	    Strict:         true,
+	}
+
+	actionID, err := clusters.Resize(client, clusterID, resizeOpts).Extract()
+	if err != nil {
+	    panic(err)
+	}
+
+Example of Removing nodes from a cluster:
+
+	removeOpts := clusters.RemoveNodesOpts{
+	    Nodes:                []string{nodeID},
+	    DestroyAfterDeletion: true,
+	}
+
+	actionID, err := clusters.RemoveNodes(client, clusterID, removeOpts).Extract()
+	if err != nil {
+	    panic(err)
+	}
+*/
+package clusters
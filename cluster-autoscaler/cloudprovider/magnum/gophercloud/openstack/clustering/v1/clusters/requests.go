@@ -0,0 +1,111 @@
+package clusters
+
+import (
+	"net/http"
+
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/magnum/gophercloud"
+)
+
+// Get retrieves details of a single cluster.
+// Use the Extract method of the returned GetResult to extract the cluster.
+func Get(client *gophercloud.ServiceClient, id string) (r GetResult) {
+	var result *http.Response
+	result, r.Err = client.Get(getURL(client, id), &r.Body, &gophercloud.RequestOpts{OkCodes: []int{200}})
+	if r.Err == nil {
+		r.Header = result.Header
+	}
+	return
+}
+
+// AdjustmentType is the way the number of a resize request is interpreted.
+type AdjustmentType string
+
+const (
+	// ExactCapacityAdjustment resizes the cluster to the given number of nodes.
+	ExactCapacityAdjustment AdjustmentType = "EXACT_CAPACITY"
+	// ChangeInCapacityAdjustment adds the given number of nodes to the cluster, or removes them if it is negative.
+	ChangeInCapacityAdjustment AdjustmentType = "CHANGE_IN_CAPACITY"
+	// ChangeInPercentageAdjustment changes the size of the cluster by the given percentage.
+	ChangeInPercentageAdjustment AdjustmentType = "CHANGE_IN_PERCENTAGE"
+)
+
+// ResizeOptsBuilder allows extensions to add additional parameters to the
+// Resize request.
+type ResizeOptsBuilder interface {
+	ToClusterResizeMap() (map[string]interface{}, error)
+}
+
+// ResizeOpts params
+type ResizeOpts struct {
+	AdjustmentType AdjustmentType `json:"adjustment_type,omitempty"`
+	Number         *int           `json:"number,omitempty"`
+	MinSize        *int           `json:"min_size,omitempty"`
+	MaxSize        *int           `json:"max_size,omitempty"`
+	MinStep        *int           `json:"min_step,omitempty"`
+	// Strict fails the resize instead of adjusting it when it would break the size constraints of the cluster.
+	Strict bool `json:"strict"`
+}
+
+// ToClusterResizeMap constructs a request body from ResizeOpts.
+func (opts ResizeOpts) ToClusterResizeMap() (map[string]interface{}, error) {
+	return gophercloud.BuildRequestBody(opts, "resize")
+}
+
+// Resize changes the number of nodes of a cluster. The resize is run
+// asynchronously by Senlin, use the Extract method of the returned
+// ActionResult to get the ID of its action.
+func Resize(client *gophercloud.ServiceClient, id string, opts ResizeOptsBuilder) (r ActionResult) {
+	b, err := opts.ToClusterResizeMap()
+	if err != nil {
+		r.Err = err
+		return
+	}
+
+	var result *http.Response
+	result, r.Err = client.Post(actionsURL(client, id), b, &r.Body, &gophercloud.RequestOpts{
+		OkCodes: []int{200, 201, 202},
+	})
+	if r.Err == nil {
+		r.Header = result.Header
+	}
+	return
+}
+
+// RemoveNodesOptsBuilder allows extensions to add additional parameters to the
+// RemoveNodes request.
+type RemoveNodesOptsBuilder interface {
+	ToClusterRemoveNodesMap() (map[string]interface{}, error)
+}
+
+// RemoveNodesOpts params
+type RemoveNodesOpts struct {
+	// Nodes are the IDs or names of the Senlin nodes to remove.
+	Nodes []string `json:"nodes" required:"true"`
+	// DestroyAfterDeletion deletes the nodes, and their servers, once they are removed from the cluster.
+	DestroyAfterDeletion bool `json:"destroy_after_deletion"`
+}
+
+// ToClusterRemoveNodesMap constructs a request body from RemoveNodesOpts.
+func (opts RemoveNodesOpts) ToClusterRemoveNodesMap() (map[string]interface{}, error) {
+	return gophercloud.BuildRequestBody(opts, "del_nodes")
+}
+
+// RemoveNodes removes the given nodes from a cluster, decreasing its desired
+// capacity accordingly. The removal is run asynchronously by Senlin, use the
+// Extract method of the returned ActionResult to get the ID of its action.
+func RemoveNodes(client *gophercloud.ServiceClient, id string, opts RemoveNodesOptsBuilder) (r ActionResult) {
+	b, err := opts.ToClusterRemoveNodesMap()
+	if err != nil {
+		r.Err = err
+		return
+	}
+
+	var result *http.Response
+	result, r.Err = client.Post(actionsURL(client, id), b, &r.Body, &gophercloud.RequestOpts{
+		OkCodes: []int{200, 201, 202},
+	})
+	if r.Err == nil {
+		r.Header = result.Header
+	}
+	return
+}
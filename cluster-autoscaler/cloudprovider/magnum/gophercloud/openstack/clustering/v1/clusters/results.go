@@ -0,0 +1,50 @@
+package clusters
+
+import (
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/magnum/gophercloud"
+)
+
+// Cluster is the API representation of a Senlin cluster.
+type Cluster struct {
+	ID              string                 `json:"id"`
+	Name            string                 `json:"name"`
+	ProfileID       string                 `json:"profile_id"`
+	ProfileName     string                 `json:"profile_name"`
+	DesiredCapacity int                    `json:"desired_capacity"`
+	MinSize         int                    `json:"min_size"`
+	MaxSize         int                    `json:"max_size"`
+	Nodes           []string               `json:"nodes"`
+	Metadata        map[string]interface{} `json:"metadata"`
+	Status          string                 `json:"status"`
+	StatusReason    string                 `json:"status_reason"`
+}
+
+// GetResult is the response from a Get request.
+// Use the Extract method to retrieve the Cluster itself.
+type GetResult struct {
+	gophercloud.Result
+}
+
+// Extract returns the cluster of a Get request.
+func (r GetResult) Extract() (*Cluster, error) {
+	var s struct {
+		Cluster *Cluster `json:"cluster"`
+	}
+	err := r.ExtractInto(&s)
+	return s.Cluster, err
+}
+
+// ActionResult is the response from a request running a cluster action.
+// Use the Extract method to retrieve the ID of the action.
+type ActionResult struct {
+	gophercloud.Result
+}
+
+// Extract returns the ID of the action run by the request.
+func (r ActionResult) Extract() (string, error) {
+	var s struct {
+		Action string `json:"action"`
+	}
+	err := r.ExtractInto(&s)
+	return s.Action, err
+}
@@ -0,0 +1,25 @@
+/*
+Package nodes provides methods for listing the nodes of the Senlin clustering
+service. Each node is backed by a physical resource, e.g. a Nova server.
+
+Example of Listing the nodes of a cluster:
+
+	listOpts := nodes.ListOpts{
+	    ClusterID: clusterID,
+	}
+
+	allPages, err := nodes.List(client, listOpts).AllPages()
+	if err != nil {
+	    panic(err)
+	}
+
+	allNodes, err := nodes.ExtractNodes(allPages)
+	if err != nil {
+	    panic(err)
+	}
+
+	for _, node := range allNodes {
+	    fmt.Printf("%+v\n", node)
+	}
+*/
+package nodes
@@ -0,0 +1,54 @@
+package nodes
+
+import (
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/magnum/gophercloud"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/magnum/gophercloud/pagination"
+)
+
+// ListOptsBuilder allows extensions to add additional parameters to the
+// List request.
+type ListOptsBuilder interface {
+	ToNodeListQuery() (string, error)
+}
+
+// ListOpts is used to filter and sort the nodes when using List.
+type ListOpts struct {
+	// Pagination marker for large data sets. (ID field from node).
+	Marker string `q:"marker"`
+	// Maximum number of resources to return in a single page.
+	Limit int `q:"limit"`
+	// Column to sort results by.
+	Sort string `q:"sort"`
+	// List only the nodes of the cluster with the given ID or name.
+	ClusterID string `q:"cluster_id"`
+	// List only the nodes with the given name.
+	Name string `q:"name"`
+	// List only the nodes with the given status.
+	Status string `q:"status"`
+}
+
+// ToNodeListQuery formats a ListOpts into a query string.
+func (opts ListOpts) ToNodeListQuery() (string, error) {
+	q, err := gophercloud.BuildQueryString(opts)
+	return q.String(), err
+}
+
+// List makes a request to the Senlin API to retrieve nodes. The request
+// can be modified to filter or sort the list using the options available
+// in ListOpts.
+//
+// Use the AllPages method of the returned Pager to ensure that all nodes
+// are returned.
+func List(client *gophercloud.ServiceClient, opts ListOptsBuilder) pagination.Pager {
+	url := listURL(client)
+	if opts != nil {
+		query, err := opts.ToNodeListQuery()
+		if err != nil {
+			return pagination.Pager{Err: err}
+		}
+		url += query
+	}
+	return pagination.NewPager(client, url, func(r pagination.PageResult) pagination.Page {
+		return NodePage{pagination.LinkedPageBase{PageResult: r}}
+	})
+}
@@ -0,0 +1,55 @@
+package nodes
+
+import (
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/magnum/gophercloud"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/magnum/gophercloud/pagination"
+)
+
+// Node is the API representation of a Senlin node.
+type Node struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	ClusterID string `json:"cluster_id"`
+	ProfileID string `json:"profile_id"`
+	// PhysicalID is the ID of the resource backing the node, e.g. a Nova server.
+	// It is empty until the resource is created.
+	PhysicalID   string                 `json:"physical_id"`
+	Index        int                    `json:"index"`
+	Role         string                 `json:"role"`
+	Metadata     map[string]interface{} `json:"metadata"`
+	Status       string                 `json:"status"`
+	StatusReason string                 `json:"status_reason"`
+}
+
+// NodePage contains a single page of all nodes from a List call.
+type NodePage struct {
+	pagination.LinkedPageBase
+}
+
+// NextPageURL returns the URL of the next page of nodes, if any.
+func (r NodePage) NextPageURL() (string, error) {
+	var s struct {
+		Links []gophercloud.Link `json:"nodes_links"`
+	}
+	err := r.ExtractInto(&s)
+	if err != nil {
+		return "", err
+	}
+	return gophercloud.ExtractNextURL(s.Links)
+}
+
+// IsEmpty determines if a NodePage contains any results.
+func (r NodePage) IsEmpty() (bool, error) {
+	s, err := ExtractNodes(r)
+	return len(s) == 0, err
+}
+
+// ExtractNodes takes a Page of nodes as returned from List
+// or from AllPages and extracts it as a slice of Nodes.
+func ExtractNodes(r pagination.Page) ([]Node, error) {
+	var s struct {
+		Nodes []Node `json:"nodes"`
+	}
+	err := (r.(NodePage)).ExtractInto(&s)
+	return s.Nodes, err
+}
@@ -0,0 +1,9 @@
+package nodes
+
+import (
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/magnum/gophercloud"
+)
+
+func listURL(client *gophercloud.ServiceClient) string {
+	return client.ServiceURL("v1", "nodes")
+}
@@ -0,0 +1,14 @@
+/*
+Package profiles provides methods for getting the profiles of the Senlin
+clustering service, which describe the resources backing the nodes of a
+cluster, e.g. the flavor and availability zone of Nova servers.
+
+Example of Getting a profile:
+
+	profile, err := profiles.Get(client, profileID).Extract()
+	if err != nil {
+	    panic(err)
+	}
+	fmt.Printf("%+v\n", profile.Spec.Properties)
+*/
+package profiles
@@ -0,0 +1,39 @@
+package profiles
+
+import (
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/magnum/gophercloud"
+)
+
+// Spec is the specification of the resources created for the nodes using a profile.
+type Spec struct {
+	// Type is the type of the profile, e.g. os.nova.server.
+	Type    string `json:"type"`
+	Version string `json:"version"`
+	// Properties are the properties of the resources, e.g. the flavor and
+	// availability_zone of os.nova.server profiles.
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// Profile is the API representation of a Senlin profile.
+type Profile struct {
+	ID       string                 `json:"id"`
+	Name     string                 `json:"name"`
+	Type     string                 `json:"type"`
+	Spec     Spec                   `json:"spec"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+// GetResult is the response from a Get request.
+// Use the Extract method to retrieve the Profile itself.
+type GetResult struct {
+	gophercloud.Result
+}
+
+// Extract returns the profile of a Get request.
+func (r GetResult) Extract() (*Profile, error) {
+	var s struct {
+		Profile *Profile `json:"profile"`
+	}
+	err := r.ExtractInto(&s)
+	return s.Profile, err
+}
@@ -0,0 +1,18 @@
+/*
+Package flavors provides information and interaction with the flavor API
+in the OpenStack Compute service.
+
+A flavor is an available hardware configuration for a server. Each flavor
+has a unique combination of disk space, memory capacity and priority for CPU
+time.
+
+Example to Get a Flavor
+
+	flavorID := "42"
+
+	flavor, err := flavors.Get(computeClient, flavorID).Extract()
+	if err != nil {
+		panic(err)
+	}
+*/
+package flavors
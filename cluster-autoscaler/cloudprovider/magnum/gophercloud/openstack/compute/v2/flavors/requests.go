@@ -0,0 +1,18 @@
+package flavors
+
+import (
+	"net/http"
+
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/magnum/gophercloud"
+)
+
+// Get retrieves details of a single flavor. Use Extract to convert its
+// result into a Flavor.
+func Get(client *gophercloud.ServiceClient, id string) (r GetResult) {
+	var response *http.Response
+	response, r.Err = client.Get(getURL(client, id), &r.Body, &gophercloud.RequestOpts{OkCodes: []int{200}})
+	if r.Err == nil {
+		r.Header = response.Header
+	}
+	return
+}
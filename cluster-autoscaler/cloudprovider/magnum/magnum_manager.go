@@ -22,6 +22,7 @@ import (
 
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/magnum/gophercloud"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/magnum/gophercloud/openstack/containerinfra/v1/nodegroups"
 	"k8s.io/autoscaler/cluster-autoscaler/config"
 )
@@ -45,6 +46,7 @@ type magnumManager interface {
 	fetchNodeGroupStackIDs(nodegroup string) (nodeGroupStacks, error)
 	uniqueNameAndIDForNodeGroup(nodegroup string) (string, string, error)
 	nodeGroupForNode(node *apiv1.Node) (string, error)
+	nodeGroupTemplate(nodegroup string) (*nodeTemplate, error)
 }
 
 // createMagnumManager creates the necessary OpenStack clients and returns
@@ -73,10 +75,24 @@ func createMagnumManager(configReader io.Reader, discoverOpts cloudprovider.Node
 		return nil, fmt.Errorf("could not check cluster UUID: %v", err)
 	}
 
-	heatClient, err := createHeatClient(cfg, provider, opts)
+	// Clusters deployed with a Senlin driver have no Heat stacks.
+	var heatClient, senlinClient *gophercloud.ServiceClient
+	if cfg.Magnum.UseSenlin {
+		senlinClient, err = createSenlinClient(cfg, provider, opts)
+		if err != nil {
+			return nil, fmt.Errorf("could not create senlin client: %v", err)
+		}
+	} else {
+		heatClient, err = createHeatClient(cfg, provider, opts)
+		if err != nil {
+			return nil, fmt.Errorf("could not create heat client: %v", err)
+		}
+	}
+
+	computeClient, err := createComputeClient(cfg, provider, opts)
 	if err != nil {
-		return nil, fmt.Errorf("could not create heat client: %v", err)
+		return nil, fmt.Errorf("could not create compute client: %v", err)
 	}
 
-	return createMagnumManagerImpl(clusterClient, heatClient, opts)
+	return createMagnumManagerImpl(clusterClient, heatClient, computeClient, senlinClient, opts)
 }
@@ -19,6 +19,7 @@ package magnum
 import (
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/gofrs/uuid"
@@ -27,6 +28,10 @@ import (
 
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/magnum/gophercloud"
+	senlinclusters "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/magnum/gophercloud/openstack/clustering/v1/clusters"
+	senlinnodes "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/magnum/gophercloud/openstack/clustering/v1/nodes"
+	senlinprofiles "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/magnum/gophercloud/openstack/clustering/v1/profiles"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/magnum/gophercloud/openstack/compute/v2/flavors"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/magnum/gophercloud/openstack/containerinfra/v1/clusters"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/magnum/gophercloud/openstack/containerinfra/v1/nodegroups"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/magnum/gophercloud/openstack/orchestration/v1/stackresources"
@@ -47,20 +52,30 @@ type nodeGroupStacks struct {
 type magnumManagerImpl struct {
 	clusterClient *gophercloud.ServiceClient
 	heatClient    *gophercloud.ServiceClient
+	computeClient *gophercloud.ServiceClient
+	// senlinClient is only set for clusters deployed with a Senlin driver,
+	// in which case the stack of each node group is a Senlin cluster.
+	senlinClient *gophercloud.ServiceClient
 
 	clusterName string
 
 	stackInfo                  map[string]nodeGroupStacks
 	providerIDToNodeGroupCache map[string]string
+	flavorCache                map[string]*flavors.Flavor
+	templateCache              map[string]*nodeTemplate
 }
 
 // createMagnumManagerImpl creates an instance of magnumManagerImpl.
-func createMagnumManagerImpl(clusterClient, heatClient *gophercloud.ServiceClient, opts config.AutoscalingOptions) (*magnumManagerImpl, error) {
+func createMagnumManagerImpl(clusterClient, heatClient, computeClient, senlinClient *gophercloud.ServiceClient, opts config.AutoscalingOptions) (*magnumManagerImpl, error) {
 	manager := magnumManagerImpl{
 		clusterClient: clusterClient,
 		heatClient:    heatClient,
+		computeClient: computeClient,
+		senlinClient:  senlinClient,
 		clusterName:   opts.ClusterName,
 		stackInfo:     make(map[string]nodeGroupStacks),
+		flavorCache:   make(map[string]*flavors.Flavor),
+		templateCache: make(map[string]*nodeTemplate),
 
 		providerIDToNodeGroupCache: make(map[string]string),
 	}
@@ -91,6 +106,8 @@ func (mgr *magnumManagerImpl) uniqueNameAndIDForNodeGroup(nodegroup string) (str
 
 // fetchNodeGroupStackIDs fetches and caches the IDs and names of the
 // nodegroup Heat stack and the related kube_minions stack.
+// For Senlin-backed node groups only the ID of the Senlin cluster
+// of the node group is fetched, as the stack ID.
 //
 // Calling this a second time for the same node group will return the
 // cached result.
@@ -107,6 +124,12 @@ func (mgr *magnumManagerImpl) fetchNodeGroupStackIDs(nodegroup string) (nodeGrou
 	}
 	stackID := ng.StackID
 
+	if mgr.senlinClient != nil {
+		// The node group has no Heat stacks, its stack is a Senlin cluster.
+		mgr.stackInfo[nodegroup] = nodeGroupStacks{stackID: stackID}
+		return mgr.stackInfo[nodegroup], nil
+	}
+
 	// Get the Heat stack for the node group.
 	stack, err := stacks.Find(mgr.heatClient, stackID).Extract()
 	if err != nil {
@@ -189,6 +212,16 @@ func (mgr *magnumManagerImpl) autoDiscoverNodeGroups(cfgs []magnumAutoDiscoveryC
 			continue
 		}
 
+		if mgr.senlinClient != nil {
+			// Senlin clusters are resized directly, so the node count
+			// of the node group in Magnum may be outdated.
+			cluster, err := senlinclusters.Get(mgr.senlinClient, detail.StackID).Extract()
+			if err != nil {
+				return nil, fmt.Errorf("could not get Senlin cluster of node group %s: %v", detail.Name, err)
+			}
+			detail.NodeCount = cluster.DesiredCapacity
+		}
+
 		ngs = append(ngs, detail)
 	}
 
@@ -197,6 +230,10 @@ func (mgr *magnumManagerImpl) autoDiscoverNodeGroups(cfgs []magnumAutoDiscoveryC
 
 // nodeGroupSize gets the current node count of the given node group.
 func (mgr *magnumManagerImpl) nodeGroupSize(nodegroup string) (int, error) {
+	if mgr.senlinClient != nil {
+		return mgr.senlinNodeGroupSize(nodegroup)
+	}
+
 	ng, err := nodegroups.Get(mgr.clusterClient, mgr.clusterName, nodegroup).Extract()
 	if err != nil {
 		return 0, fmt.Errorf("could not get node group: %v", err)
@@ -206,6 +243,10 @@ func (mgr *magnumManagerImpl) nodeGroupSize(nodegroup string) (int, error) {
 
 // updateNodeCount performs a cluster resize targeting the given node group.
 func (mgr *magnumManagerImpl) updateNodeCount(nodegroup string, nodes int) error {
+	if mgr.senlinClient != nil {
+		return mgr.updateSenlinNodeCount(nodegroup, nodes)
+	}
+
 	resizeOpts := clusters.ResizeOpts{
 		NodeCount: &nodes,
 		NodeGroup: nodegroup,
@@ -222,6 +263,10 @@ func (mgr *magnumManagerImpl) updateNodeCount(nodegroup string, nodes int) error
 // getNodes returns Instances with ProviderIDs and running states
 // of all nodes that exist in OpenStack for a node group.
 func (mgr *magnumManagerImpl) getNodes(nodegroup string) ([]cloudprovider.Instance, error) {
+	if mgr.senlinClient != nil {
+		return mgr.getSenlinNodes(nodegroup)
+	}
+
 	var nodes []cloudprovider.Instance
 
 	stackInfo, err := mgr.fetchNodeGroupStackIDs(nodegroup)
@@ -374,6 +419,10 @@ func (mgr *magnumManagerImpl) getNodes(nodegroup string) ([]cloudprovider.Instan
 // The nodes are referenced by server ID for nodes which have them,
 // or by the minion index for nodes which are creating or in an error state.
 func (mgr *magnumManagerImpl) deleteNodes(nodegroup string, nodes []NodeRef, updatedNodeCount int) error {
+	if mgr.senlinClient != nil {
+		return mgr.deleteSenlinNodes(nodegroup, nodes)
+	}
+
 	var nodesToRemove []string
 	for _, nodeRef := range nodes {
 		if nodeRef.IsFake {
@@ -456,6 +505,18 @@ func (mgr *magnumManagerImpl) nodeGroupForNode(node *apiv1.Node) (string, error)
 	for _, ngUUID := range allNodeGroupUUIDs {
 		klog.V(5).Infof("Checking node group %s, size %d", ngUUID, nodeGroupSizes[ngUUID])
 
+		if mgr.senlinClient != nil {
+			found, err := mgr.cacheSenlinNodeGroupProviderIDs(ngUUID, node.Spec.ProviderID)
+			if err != nil {
+				return "", err
+			}
+			if found {
+				klog.V(5).Infof("nodeGroupForNode: found node %s in node group %s", node.Spec.ProviderID, ngUUID)
+				return ngUUID, nil
+			}
+			continue
+		}
+
 		stackInfo, err := mgr.fetchNodeGroupStackIDs(ngUUID)
 		if err != nil {
 			return "", fmt.Errorf("could not fetch stack IDs for node group %s: %v", ngUUID, err)
@@ -508,3 +569,275 @@ func (mgr *magnumManagerImpl) nodeGroupForNode(node *apiv1.Node) (string, error)
 
 	return "", fmt.Errorf("could not find node group for node %s", node.Spec.ProviderID)
 }
+
+// nodeGroupTemplate returns the properties of the nodes of the given node group:
+// the resources of its flavor, its availability zone and its role.
+//
+// Templates and flavors are cached, as the flavor and labels of a node group
+// can not be modified once it is created.
+func (mgr *magnumManagerImpl) nodeGroupTemplate(nodegroup string) (*nodeTemplate, error) {
+	if template, ok := mgr.templateCache[nodegroup]; ok {
+		return template, nil
+	}
+
+	ng, err := nodegroups.Get(mgr.clusterClient, mgr.clusterName, nodegroup).Extract()
+	if err != nil {
+		return nil, fmt.Errorf("could not get node group: %v", err)
+	}
+
+	flavor, ok := mgr.flavorCache[ng.FlavorID]
+	if !ok {
+		flavor, err = flavors.Get(mgr.computeClient, ng.FlavorID).Extract()
+		if err != nil {
+			return nil, fmt.Errorf("could not get flavor %s of node group %s: %v", ng.FlavorID, ng.Name, err)
+		}
+		mgr.flavorCache[ng.FlavorID] = flavor
+	}
+
+	diskGB := flavor.Disk
+	if bootVolumeSize, err := strconv.Atoi(ng.Labels[bootVolumeSizeLabel]); err == nil && bootVolumeSize > 0 {
+		// Servers boot from a volume instead of the flavor disk.
+		diskGB = bootVolumeSize
+	}
+
+	availabilityZone := ng.Labels[availabilityZoneLabel]
+	if mgr.senlinClient != nil {
+		// The servers are created in the availability zone of the Senlin profile.
+		zone, err := mgr.senlinProfileAvailabilityZone(ng.StackID)
+		if err != nil {
+			return nil, fmt.Errorf("could not get availability zone of node group %s: %v", ng.Name, err)
+		}
+		if zone != "" {
+			availabilityZone = zone
+		}
+	}
+
+	template := &nodeTemplate{
+		nodeGroupName:    ng.Name,
+		role:             ng.Role,
+		flavorName:       flavor.Name,
+		vcpus:            flavor.VCPUs,
+		ramMB:            flavor.RAM,
+		diskGB:           diskGB,
+		availabilityZone: availabilityZone,
+	}
+	mgr.templateCache[nodegroup] = template
+
+	return template, nil
+}
+
+// senlinClusterID returns the ID of the Senlin cluster of a Senlin-backed node group.
+func (mgr *magnumManagerImpl) senlinClusterID(nodegroup string) (string, error) {
+	stackInfo, err := mgr.fetchNodeGroupStackIDs(nodegroup)
+	if err != nil {
+		return "", fmt.Errorf("could not fetch Senlin cluster ID for node group %s: %v", nodegroup, err)
+	}
+	return stackInfo.stackID, nil
+}
+
+// senlinNodeGroupSize gets the desired capacity of the Senlin cluster of the given node group.
+func (mgr *magnumManagerImpl) senlinNodeGroupSize(nodegroup string) (int, error) {
+	clusterID, err := mgr.senlinClusterID(nodegroup)
+	if err != nil {
+		return 0, err
+	}
+
+	cluster, err := senlinclusters.Get(mgr.senlinClient, clusterID).Extract()
+	if err != nil {
+		return 0, fmt.Errorf("could not get Senlin cluster: %v", err)
+	}
+	return cluster.DesiredCapacity, nil
+}
+
+// updateSenlinNodeCount resizes the Senlin cluster of the given node group.
+func (mgr *magnumManagerImpl) updateSenlinNodeCount(nodegroup string, nodes int) error {
+	clusterID, err := mgr.senlinClusterID(nodegroup)
+	if err != nil {
+		return err
+	}
+
+	resizeOpts := senlinclusters.ResizeOpts{
+		AdjustmentType: senlinclusters.ExactCapacityAdjustment,
+		Number:         &nodes,
+		Strict:         true,
+	}
+
+	_, err = senlinclusters.Resize(mgr.senlinClient, clusterID, resizeOpts).Extract()
+	if err != nil {
+		return fmt.Errorf("could not resize Senlin cluster: %v", err)
+	}
+	return nil
+}
+
+// listSenlinNodes lists the nodes of the Senlin cluster of the given node group.
+func (mgr *magnumManagerImpl) listSenlinNodes(nodegroup string) ([]senlinnodes.Node, error) {
+	clusterID, err := mgr.senlinClusterID(nodegroup)
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := senlinnodes.List(mgr.senlinClient, senlinnodes.ListOpts{ClusterID: clusterID}).AllPages()
+	if err != nil {
+		return nil, fmt.Errorf("could not list Senlin nodes: %v", err)
+	}
+	nodes, err := senlinnodes.ExtractNodes(pages)
+	if err != nil {
+		return nil, fmt.Errorf("could not extract Senlin nodes: %v", err)
+	}
+	return nodes, nil
+}
+
+// getSenlinNodes returns Instances with ProviderIDs and running states
+// of all nodes of the Senlin cluster of a node group.
+func (mgr *magnumManagerImpl) getSenlinNodes(nodegroup string) ([]cloudprovider.Instance, error) {
+	senlinNodes, err := mgr.listSenlinNodes(nodegroup)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []cloudprovider.Instance
+	for _, node := range senlinNodes {
+		// Nodes which do not have a server yet get a fake provider ID
+		// in the format "fake:///nodegroup/senlinNodeID".
+		instance := cloudprovider.Instance{
+			Id:     fmt.Sprintf("fake:///%s/%s", nodegroup, node.ID),
+			Status: &cloudprovider.InstanceStatus{},
+		}
+		if node.PhysicalID != "" {
+			instance.Id = fmt.Sprintf("openstack:///%s", node.PhysicalID)
+		}
+
+		switch node.Status {
+		case "INIT", "CREATING":
+			instance.Status.State = cloudprovider.InstanceCreating
+		case "DELETING":
+			if node.PhysicalID == "" {
+				// There is no server left to delete.
+				continue
+			}
+			instance.Status.State = cloudprovider.InstanceDeleting
+		case "ERROR":
+			instance.Status.State = cloudprovider.InstanceCreating
+
+			errorClass := cloudprovider.OtherErrorClass
+
+			// Check if the error message is for exceeding the project quota.
+			if strings.Contains(strings.ToLower(node.StatusReason), "quota") {
+				errorClass = cloudprovider.OutOfResourcesErrorClass
+			}
+
+			instance.Status.ErrorInfo = &cloudprovider.InstanceErrorInfo{
+				ErrorClass:   errorClass,
+				ErrorMessage: node.StatusReason,
+			}
+
+			klog.V(3).Infof("Senlin node %s failed with reason: %s", node.Name, node.StatusReason)
+		case "ACTIVE", "WARNING", "UPDATING", "OPERATING", "RECOVERING":
+			instance.Status.State = cloudprovider.InstanceRunning
+		default:
+			klog.V(3).Infof("Ignoring Senlin node %s in state %s", node.Name, node.Status)
+			continue
+		}
+
+		nodes = append(nodes, instance)
+	}
+
+	return nodes, nil
+}
+
+// deleteSenlinNodes removes the given nodes from the Senlin cluster of a node group,
+// which also decreases the desired capacity of the cluster, and deletes their servers.
+//
+// The nodes are referenced by the ID of their Senlin node, which is found from the
+// server ID for nodes which have them, or from the fake provider ID for nodes which
+// are creating or in an error state.
+func (mgr *magnumManagerImpl) deleteSenlinNodes(nodegroup string, nodes []NodeRef) error {
+	senlinNodes, err := mgr.listSenlinNodes(nodegroup)
+	if err != nil {
+		return err
+	}
+	senlinNodeIDs := make(map[string]string)
+	for _, node := range senlinNodes {
+		if node.PhysicalID != "" {
+			senlinNodeIDs[node.PhysicalID] = node.ID
+		}
+	}
+
+	var nodesToRemove []string
+	for _, nodeRef := range nodes {
+		if nodeRef.IsFake && strings.HasPrefix(nodeRef.ProviderID, "fake:///") {
+			_, senlinNodeID, err := parseFakeProviderID(nodeRef.ProviderID)
+			if err != nil {
+				return fmt.Errorf("error handling fake node: %v", err)
+			}
+			nodesToRemove = append(nodesToRemove, senlinNodeID)
+			continue
+		}
+		serverID := strings.TrimPrefix(nodeRef.ProviderID, "openstack:///")
+		senlinNodeID, ok := senlinNodeIDs[serverID]
+		if !ok {
+			return fmt.Errorf("could not find Senlin node of node %s", nodeRef.Name)
+		}
+		klog.V(2).Infof("manager deleting node: %s", nodeRef.Name)
+		nodesToRemove = append(nodesToRemove, senlinNodeID)
+	}
+
+	clusterID, err := mgr.senlinClusterID(nodegroup)
+	if err != nil {
+		return err
+	}
+
+	removeOpts := senlinclusters.RemoveNodesOpts{
+		Nodes:                nodesToRemove,
+		DestroyAfterDeletion: true,
+	}
+
+	klog.V(2).Infof("Removing Senlin nodes %v from cluster %s", nodesToRemove, clusterID)
+
+	_, err = senlinclusters.RemoveNodes(mgr.senlinClient, clusterID, removeOpts).Extract()
+	if err != nil {
+		return fmt.Errorf("could not remove nodes from Senlin cluster: %v", err)
+	}
+
+	return nil
+}
+
+// cacheSenlinNodeGroupProviderIDs caches the provider IDs of all servers of the
+// Senlin cluster of the given node group, and returns whether one of them is
+// the given provider ID.
+func (mgr *magnumManagerImpl) cacheSenlinNodeGroupProviderIDs(nodegroup string, providerID string) (bool, error) {
+	senlinNodes, err := mgr.listSenlinNodes(nodegroup)
+	if err != nil {
+		return false, err
+	}
+
+	var found bool
+	for _, node := range senlinNodes {
+		if node.PhysicalID == "" {
+			continue
+		}
+		nodeProviderID := fmt.Sprintf("openstack:///%s", node.PhysicalID)
+		mgr.providerIDToNodeGroupCache[nodeProviderID] = nodegroup
+		if nodeProviderID == providerID {
+			found = true
+		}
+	}
+	return found, nil
+}
+
+// senlinProfileAvailabilityZone returns the availability zone of the
+// profile of the given Senlin cluster, if it has one.
+func (mgr *magnumManagerImpl) senlinProfileAvailabilityZone(clusterID string) (string, error) {
+	cluster, err := senlinclusters.Get(mgr.senlinClient, clusterID).Extract()
+	if err != nil {
+		return "", fmt.Errorf("could not get Senlin cluster: %v", err)
+	}
+
+	profile, err := senlinprofiles.Get(mgr.senlinClient, cluster.ProfileID).Extract()
+	if err != nil {
+		return "", fmt.Errorf("could not get Senlin profile %s: %v", cluster.ProfileID, err)
+	}
+
+	zone, _ := profile.Spec.Properties["availability_zone"].(string)
+	return zone, nil
+}
@@ -31,6 +31,7 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/magnum/fixtures"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/magnum/gophercloud"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/magnum/gophercloud/openstack/compute/v2/flavors"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/magnum/gophercloud/openstack/containerinfra/v1/nodegroups"
 	th "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/magnum/gophercloud/testhelper"
 )
@@ -47,11 +48,25 @@ func createTestMagnumManager(client *gophercloud.ServiceClient) *magnumManagerIm
 		clusterName:                fixtures.ClusterUUID,
 		clusterClient:              client,
 		heatClient:                 client,
+		computeClient:              client,
 		stackInfo:                  make(map[string]nodeGroupStacks),
 		providerIDToNodeGroupCache: make(map[string]string),
+		flavorCache:                make(map[string]*flavors.Flavor),
+		templateCache:              make(map[string]*nodeTemplate),
 	}
 }
 
+func createTestSenlinMagnumManager(client *gophercloud.ServiceClient) *magnumManagerImpl {
+	manager := createTestMagnumManager(client)
+	manager.heatClient = nil
+	// Senlin URLs include the API version.
+	manager.senlinClient = &gophercloud.ServiceClient{
+		ProviderClient: client.ProviderClient,
+		Endpoint:       th.Endpoint(),
+	}
+	return manager
+}
+
 // setupFetchNodeGroupStackIDs sets up handlers for the default-worker and
 // test-ng node groups, and their stacks.
 func setupFetchNodeGroupStackIDs() {
@@ -702,6 +717,52 @@ func TestNodeGroupSize(t *testing.T) {
 	assert.Equal(t, 1, n)
 }
 
+// TestNodeGroupTemplate checks that the template of a node group
+// is built from its flavor and labels, and that templates are cached.
+func TestNodeGroupTemplate(t *testing.T) {
+	th.SetupHTTP()
+	defer th.TeardownHTTP()
+
+	sc := createTestServiceClient()
+	manager := createTestMagnumManager(sc)
+
+	path := fmt.Sprintf("/v1/clusters/%s/nodegroups/%s", fixtures.ClusterUUID, fixtures.TestNodeGroupUUID)
+	nodeGroupRequests := 0
+	th.Mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		nodeGroupRequests++
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		fmt.Fprint(w, fixtures.GetTestNGNodeGroupResponse)
+	})
+
+	flavorRequests := 0
+	th.Mux.HandleFunc("/v1/flavors/m2.medium", func(w http.ResponseWriter, r *http.Request) {
+		flavorRequests++
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		fmt.Fprint(w, fixtures.GetM2MediumFlavorResponse)
+	})
+
+	expected := &nodeTemplate{
+		nodeGroupName:    "test-ng",
+		role:             "autoscaling",
+		flavorName:       "m2.medium",
+		vcpus:            4,
+		ramMB:            8192,
+		diskGB:           40,
+		availabilityZone: "nova-1",
+	}
+	for i := 0; i < 2; i++ {
+		template, err := manager.nodeGroupTemplate(fixtures.TestNodeGroupUUID)
+		require.NoError(t, err)
+		assert.Equal(t, expected, template)
+	}
+	assert.Equal(t, 1, nodeGroupRequests, "node group template should have been cached")
+	assert.Equal(t, 1, flavorRequests, "flavor should have been cached")
+}
+
 // TestUpdateNodeCountSuccess checks that updateNodeCount
 // correctly makes the resize request to Magnum and returns
 // no error if the resize was accepted.
@@ -798,3 +859,225 @@ func TestManagerDeleteNodes(t *testing.T) {
 	err := manager.deleteNodes(fixtures.DefaultWorkerNodeGroupUUID, instances, 1)
 	assert.NoError(t, err)
 }
+
+// setupSenlinNodeGroups sets up handlers for the Senlin cluster backing
+// the test-ng node group, its nodes and its profile, as well as for the
+// nodes of the empty Senlin cluster backing the default-worker node group.
+func setupSenlinNodeGroups(t *testing.T) {
+	setupFetchNodeGroupStackIDs()
+
+	path := fmt.Sprintf("/v1/clusters/%s", fixtures.TestNodeGroupStackUUID)
+	th.Mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(t, r, "GET")
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		fmt.Fprint(w, fixtures.GetSenlinClusterResponse)
+	})
+
+	th.Mux.HandleFunc("/v1/nodes", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		if r.URL.Query().Get("cluster_id") == fixtures.TestNodeGroupStackUUID {
+			fmt.Fprint(w, fixtures.ListSenlinNodesResponse)
+			return
+		}
+		fmt.Fprint(w, `{"nodes": []}`)
+	})
+
+	path = fmt.Sprintf("/v1/profiles/%s", fixtures.SenlinProfileUUID)
+	th.Mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		fmt.Fprint(w, fixtures.GetSenlinProfileResponse)
+	})
+}
+
+// TestSenlinFetchNodeGroupStackIDs checks that the Senlin cluster ID
+// of a node group is its stack ID, without looking up Heat stacks.
+func TestSenlinFetchNodeGroupStackIDs(t *testing.T) {
+	th.SetupHTTP()
+	defer th.TeardownHTTP()
+
+	th.Mux.HandleFunc(fmt.Sprintf("/v1/clusters/%s/nodegroups/%s", fixtures.ClusterUUID, fixtures.TestNodeGroupUUID), func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		fmt.Fprint(w, fixtures.GetTestNGNodeGroupResponse)
+	})
+
+	manager := createTestSenlinMagnumManager(createTestServiceClient())
+
+	clusterID, err := manager.senlinClusterID(fixtures.TestNodeGroupUUID)
+	require.NoError(t, err)
+	assert.Equal(t, fixtures.TestNodeGroupStackUUID, clusterID)
+}
+
+func TestSenlinNodeGroupSize(t *testing.T) {
+	th.SetupHTTP()
+	defer th.TeardownHTTP()
+
+	setupSenlinNodeGroups(t)
+
+	manager := createTestSenlinMagnumManager(createTestServiceClient())
+
+	size, err := manager.nodeGroupSize(fixtures.TestNodeGroupUUID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, size, "size should be the desired capacity of the Senlin cluster")
+}
+
+// TestSenlinUpdateNodeCount checks that updateNodeCount
+// resizes the Senlin cluster of the node group.
+func TestSenlinUpdateNodeCount(t *testing.T) {
+	th.SetupHTTP()
+	defer th.TeardownHTTP()
+
+	setupSenlinNodeGroups(t)
+
+	path := fmt.Sprintf("/v1/clusters/%s/actions", fixtures.TestNodeGroupStackUUID)
+	th.Mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(t, r, "POST")
+		th.TestJSONRequest(t, r, `{"resize": {"adjustment_type": "EXACT_CAPACITY", "number": 4, "strict": true}}`)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+
+		fmt.Fprint(w, fixtures.SenlinActionResponse)
+	})
+
+	manager := createTestSenlinMagnumManager(createTestServiceClient())
+
+	err := manager.updateNodeCount(fixtures.TestNodeGroupUUID, 4)
+	assert.NoError(t, err)
+}
+
+// TestSenlinGetNodes checks that getNodes returns an instance for
+// each node of the Senlin cluster of the node group, in the right state.
+func TestSenlinGetNodes(t *testing.T) {
+	th.SetupHTTP()
+	defer th.TeardownHTTP()
+
+	setupSenlinNodeGroups(t)
+
+	manager := createTestSenlinMagnumManager(createTestServiceClient())
+
+	expected := []cloudprovider.Instance{
+		{
+			Id:     "openstack:///" + fixtures.SenlinActiveServerUUID,
+			Status: &cloudprovider.InstanceStatus{State: cloudprovider.InstanceRunning},
+		},
+		{
+			Id:     fmt.Sprintf("fake:///%s/%s", fixtures.TestNodeGroupUUID, fixtures.SenlinCreatingNodeUUID),
+			Status: &cloudprovider.InstanceStatus{State: cloudprovider.InstanceCreating},
+		},
+		{
+			Id: fmt.Sprintf("fake:///%s/%s", fixtures.TestNodeGroupUUID, fixtures.SenlinErrorNodeUUID),
+			Status: &cloudprovider.InstanceStatus{
+				State: cloudprovider.InstanceCreating,
+				ErrorInfo: &cloudprovider.InstanceErrorInfo{
+					ErrorClass:   cloudprovider.OutOfResourcesErrorClass,
+					ErrorMessage: "Failed in creating server: Quota exceeded for cores",
+				},
+			},
+		},
+		{
+			Id:     "openstack:///" + fixtures.SenlinDeletingServerUUID,
+			Status: &cloudprovider.InstanceStatus{State: cloudprovider.InstanceDeleting},
+		},
+	}
+
+	instances, err := manager.getNodes(fixtures.TestNodeGroupUUID)
+	require.NoError(t, err)
+	assert.Equal(t, expected, instances)
+}
+
+// TestSenlinDeleteNodes checks that deleteNodes removes the Senlin nodes
+// of the deleted servers and fake nodes from the Senlin cluster.
+func TestSenlinDeleteNodes(t *testing.T) {
+	th.SetupHTTP()
+	defer th.TeardownHTTP()
+
+	setupSenlinNodeGroups(t)
+
+	path := fmt.Sprintf("/v1/clusters/%s/actions", fixtures.TestNodeGroupStackUUID)
+	th.Mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(t, r, "POST")
+		th.TestJSONRequest(t, r, fmt.Sprintf(`{"del_nodes": {"nodes": ["%s", "%s"], "destroy_after_deletion": true}}`, fixtures.SenlinActiveNodeUUID, fixtures.SenlinErrorNodeUUID))
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+
+		fmt.Fprint(w, fixtures.SenlinActionResponse)
+	})
+
+	manager := createTestSenlinMagnumManager(createTestServiceClient())
+
+	fakeProviderID := fmt.Sprintf("fake:///%s/%s", fixtures.TestNodeGroupUUID, fixtures.SenlinErrorNodeUUID)
+
+	instances := []NodeRef{
+		{Name: "kube-test-ng-node-1", SystemUUID: fixtures.SenlinActiveServerUUID, ProviderID: "openstack:///" + fixtures.SenlinActiveServerUUID},
+		{Name: fakeProviderID, ProviderID: fakeProviderID, IsFake: true},
+	}
+
+	err := manager.deleteNodes(fixtures.TestNodeGroupUUID, instances, 1)
+	assert.NoError(t, err)
+}
+
+// TestSenlinNodeGroupForNode checks that the node group of a node is found
+// from the servers of the Senlin clusters of the node groups.
+func TestSenlinNodeGroupForNode(t *testing.T) {
+	th.SetupHTTP()
+	defer th.TeardownHTTP()
+
+	setupSenlinNodeGroups(t)
+
+	path := fmt.Sprintf("/v1/clusters/%s/nodegroups", fixtures.ClusterUUID)
+	th.Mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		fmt.Fprint(w, fixtures.ListNodeGroupsResponse)
+	})
+
+	manager := createTestSenlinMagnumManager(createTestServiceClient())
+
+	node := &apiv1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "kube-test-ng-node-1",
+			UID:  "a5d8f2c4-3b19-4e7a-9c06-1f2e8d4b7a30",
+		},
+		Spec: apiv1.NodeSpec{
+			ProviderID: "openstack:///" + fixtures.SenlinActiveServerUUID,
+		},
+	}
+
+	group, err := manager.nodeGroupForNode(node)
+	require.NoError(t, err)
+	assert.Equal(t, fixtures.TestNodeGroupUUID, group)
+
+	assert.Equal(t, fixtures.TestNodeGroupUUID, manager.providerIDToNodeGroupCache["openstack:///"+fixtures.SenlinDeletingServerUUID],
+		"all servers of the Senlin cluster should have been cached")
+}
+
+// TestSenlinNodeGroupTemplate checks that node templates of Senlin-backed
+// node groups use the availability zone of the Senlin profile.
+func TestSenlinNodeGroupTemplate(t *testing.T) {
+	th.SetupHTTP()
+	defer th.TeardownHTTP()
+
+	setupSenlinNodeGroups(t)
+
+	th.Mux.HandleFunc("/v1/flavors/m2.medium", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		fmt.Fprint(w, fixtures.GetM2MediumFlavorResponse)
+	})
+
+	manager := createTestSenlinMagnumManager(createTestServiceClient())
+
+	template, err := manager.nodeGroupTemplate(fixtures.TestNodeGroupUUID)
+	require.NoError(t, err)
+	assert.Equal(t, "nova-2", template.availabilityZone)
+	assert.Equal(t, "m2.medium", template.flavorName)
+}
@@ -18,10 +18,13 @@ package magnum
 
 import (
 	"fmt"
+	"math/rand"
 	"sync"
 	"time"
 
 	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	"k8s.io/autoscaler/cluster-autoscaler/config"
 	klog "k8s.io/klog/v2"
@@ -134,6 +137,11 @@ func (ng *magnumNodeGroup) DeleteNodes(nodes []*apiv1.Node) error {
 	return nil
 }
 
+// ForceDeleteNodes deletes nodes from the group regardless of constraints.
+func (ng *magnumNodeGroup) ForceDeleteNodes(nodes []*apiv1.Node) error {
+	return cloudprovider.ErrNotImplemented
+}
+
 // DecreaseTargetSize decreases the cluster node_count in magnum.
 func (ng *magnumNodeGroup) DecreaseTargetSize(delta int) error {
 	ng.clusterUpdateLock.Lock()
@@ -200,9 +208,71 @@ func (ng *magnumNodeGroup) Nodes() ([]cloudprovider.Instance, error) {
 	return instances, nil
 }
 
-// TemplateNodeInfo returns a node template for this node group.
+// TemplateNodeInfo returns a node template for this node group,
+// built from its flavor and availability zone.
+//
+// If the template can not be built, ErrNotImplemented is returned
+// so that the template is built from an existing node instead.
 func (ng *magnumNodeGroup) TemplateNodeInfo() (*schedulerframework.NodeInfo, error) {
-	return nil, cloudprovider.ErrNotImplemented
+	template, err := ng.magnumManager.nodeGroupTemplate(ng.UUID)
+	if err != nil {
+		klog.Warningf("Could not get template of node group %s: %v", ng.id, err)
+		return nil, cloudprovider.ErrNotImplemented
+	}
+
+	node := buildNodeFromTemplate(fmt.Sprintf("%s-template-%d", ng.id, rand.Int63()), template)
+	nodeInfo := schedulerframework.NewNodeInfo(cloudprovider.BuildKubeProxy(ng.id))
+	nodeInfo.SetNode(node)
+
+	return nodeInfo, nil
+}
+
+// nodeTemplate holds the properties of the nodes of a node group.
+type nodeTemplate struct {
+	nodeGroupName    string
+	role             string
+	flavorName       string
+	vcpus            int
+	ramMB            int
+	diskGB           int
+	availabilityZone string
+}
+
+// buildNodeFromTemplate returns a ready node with the resources and labels
+// that a node of the template would register with.
+func buildNodeFromTemplate(name string, template *nodeTemplate) *apiv1.Node {
+	capacity := apiv1.ResourceList{
+		apiv1.ResourcePods:   *resource.NewQuantity(defaultMaxPods, resource.DecimalSI),
+		apiv1.ResourceCPU:    *resource.NewQuantity(int64(template.vcpus), resource.DecimalSI),
+		apiv1.ResourceMemory: *resource.NewQuantity(int64(template.ramMB)*1024*1024, resource.BinarySI),
+	}
+	if template.diskGB > 0 {
+		capacity[apiv1.ResourceEphemeralStorage] = *resource.NewQuantity(int64(template.diskGB)*1024*1024*1024, resource.BinarySI)
+	}
+
+	labels := map[string]string{
+		apiv1.LabelOSStable:     cloudprovider.DefaultOS,
+		apiv1.LabelArchStable:   cloudprovider.DefaultArch,
+		apiv1.LabelHostname:     name,
+		apiv1.LabelInstanceType: template.flavorName,
+		nodeGroupRoleLabel:      template.role,
+		nodeGroupNameLabel:      template.nodeGroupName,
+	}
+	if template.availabilityZone != "" {
+		labels[apiv1.LabelTopologyZone] = template.availabilityZone
+	}
+
+	return &apiv1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+		Status: apiv1.NodeStatus{
+			Capacity:    capacity,
+			Allocatable: capacity,
+			Conditions:  cloudprovider.BuildReadyConditions(),
+		},
+	}
 }
 
 // Exist returns if this node group exists.
@@ -83,6 +83,11 @@ func (m *magnumManagerMock) nodeGroupForNode(node *apiv1.Node) (string, error) {
 	return args.String(0), args.Error(1)
 }
 
+func (m *magnumManagerMock) nodeGroupTemplate(nodegroup string) (*nodeTemplate, error) {
+	args := m.Called(nodegroup)
+	return args.Get(0).(*nodeTemplate), args.Error(1)
+}
+
 func createTestNodeGroup(manager magnumManager) *magnumNodeGroup {
 	ng := magnumNodeGroup{
 		magnumManager:     manager,
@@ -319,3 +324,34 @@ func TestNodes(t *testing.T) {
 	assert.ElementsMatch(t, runningNodes, nodes)
 	assert.Equalf(t, 0, len(ng.deletedNodes), "node group deletedNodes map was not cleaned")
 }
+
+func TestTemplateNodeInfo(t *testing.T) {
+	manager := &magnumManagerMock{}
+	ng := createTestNodeGroup(manager)
+
+	template := &nodeTemplate{
+		nodeGroupName:    "test-ng",
+		role:             "autoscaling",
+		flavorName:       "m2.medium",
+		vcpus:            4,
+		ramMB:            8192,
+		diskGB:           40,
+		availabilityZone: "nova-1",
+	}
+	manager.On("nodeGroupTemplate", testNodeGroupUUID).Return(template, nil).Once()
+
+	nodeInfo, err := ng.TemplateNodeInfo()
+	require.NoError(t, err)
+	node := nodeInfo.Node()
+	assert.Equal(t, int64(4), node.Status.Allocatable.Cpu().Value())
+	assert.Equal(t, int64(8*1024*1024*1024), node.Status.Allocatable.Memory().Value())
+	assert.Equal(t, int64(40*1024*1024*1024), node.Status.Allocatable.StorageEphemeral().Value())
+	assert.Equal(t, "m2.medium", node.Labels[apiv1.LabelInstanceType])
+	assert.Equal(t, "nova-1", node.Labels[apiv1.LabelTopologyZone])
+	assert.Equal(t, "autoscaling", node.Labels[nodeGroupRoleLabel])
+	assert.Equal(t, "test-ng", node.Labels[nodeGroupNameLabel])
+
+	manager.On("nodeGroupTemplate", testNodeGroupUUID).Return((*nodeTemplate)(nil), errors.New("flavor not found")).Once()
+	_, err = ng.TemplateNodeInfo()
+	assert.Equal(t, cloudprovider.ErrNotImplemented, err)
+}
@@ -89,6 +89,13 @@ type MetadataOpts struct {
 	RequestTimeout MyDuration `gcfg:"request-timeout"`
 }
 
+// MagnumOpts is used to configure how the node groups of the cluster are managed
+type MagnumOpts struct {
+	// UseSenlin is set for clusters deployed with a Senlin driver, whose node groups
+	// are listed and resized through the Senlin clusters backing them instead of Heat stacks.
+	UseSenlin bool `gcfg:"use-senlin"`
+}
+
 // Config is used to read and store information from the cloud configuration file
 //
 // Taken from kubernetes/pkg/cloudprovider/providers/openstack/openstack.go
@@ -116,6 +123,7 @@ type Config struct {
 	BlockStorage BlockStorageOpts
 	Route        RouterOpts
 	Metadata     MetadataOpts
+	Magnum       MagnumOpts
 }
 
 func toAuthOptsExt(cfg Config) trusts.AuthOptsExt {
@@ -231,3 +239,23 @@ func createHeatClient(cfg *Config, provider *gophercloud.ProviderClient, opts co
 
 	return heatClient, nil
 }
+
+// createComputeClient creates a gophercloud service client for communicating with Nova.
+func createComputeClient(cfg *Config, provider *gophercloud.ProviderClient, opts config.AutoscalingOptions) (*gophercloud.ServiceClient, error) {
+	computeClient, err := openstack.NewComputeV2(provider, gophercloud.EndpointOpts{Type: "compute", Name: "nova", Region: cfg.Global.Region})
+	if err != nil {
+		return nil, fmt.Errorf("could not create compute client: %v", err)
+	}
+
+	return computeClient, nil
+}
+
+// createSenlinClient creates a gophercloud service client for communicating with Senlin.
+func createSenlinClient(cfg *Config, provider *gophercloud.ProviderClient, opts config.AutoscalingOptions) (*gophercloud.ServiceClient, error) {
+	senlinClient, err := openstack.NewClusteringV1(provider, gophercloud.EndpointOpts{Type: "clustering", Name: "senlin", Region: cfg.Global.Region})
+	if err != nil {
+		return nil, fmt.Errorf("could not create clustering client: %v", err)
+	}
+
+	return senlinClient, nil
+}
@@ -25,6 +25,18 @@ import (
 
 const (
 	scaleToZeroSupported = true
+
+	// Maximum number of pods of the nodes, the kubelet default.
+	defaultMaxPods = 110
+
+	// Magnum node group label setting the availability zone of its servers.
+	availabilityZoneLabel = "availability_zone"
+	// Magnum node group label setting the size in GB of the volume its servers boot from.
+	bootVolumeSizeLabel = "boot_volume_size"
+
+	// Labels Magnum sets on the nodes of a node group.
+	nodeGroupRoleLabel = "magnum.openstack.org/role"
+	nodeGroupNameLabel = "magnum.openstack.org/nodegroup"
 )
 
 // NodeRef stores the name, systemUUID and providerID of a node.
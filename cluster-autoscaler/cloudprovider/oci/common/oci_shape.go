@@ -55,6 +55,9 @@ type Shape struct {
 	CPU           float32
 	GPU           int
 	MemoryInBytes float32
+	// BaselineOcpuUtilization is the fraction of the CPU guaranteed to burstable instances, 0 for instances
+	// that aren't burstable. Burstable instances still report all their vCPUs to the kubelet.
+	BaselineOcpuUtilization float32
 }
 
 // CreateShapeGetter creates a new oci shape getter.
@@ -80,7 +83,7 @@ func (osf *shapeGetterImpl) Refresh() {
 // GetNodePoolShape gets the shape by querying the node pool's configuration
 func (osf *shapeGetterImpl) GetNodePoolShape(np *oke.NodePool) (*Shape, error) {
 	shapeName := *np.NodeShape
-	if np.NodeShapeConfig != nil {
+	if np.NodeShapeConfig != nil && np.NodeShapeConfig.Ocpus != nil && np.NodeShapeConfig.MemoryInGBs != nil {
 		return &Shape{
 			CPU: *np.NodeShapeConfig.Ocpus * 2,
 			// num_bytes * kilo * mega * giga
@@ -151,55 +154,20 @@ func (osf *shapeGetterImpl) GetInstancePoolShape(ip *core.InstancePool) (*Shape,
 		return nil, fmt.Errorf("instance configuration details for instance %s has not been set", *ip.Id)
 	}
 
-	if instanceDetails, ok := instanceConfig.InstanceDetails.(core.ComputeInstanceDetails); ok {
-		// flexible shape use details or look up the static shape details below.
-		if instanceDetails.LaunchDetails != nil && instanceDetails.LaunchDetails.ShapeConfig != nil {
-			if instanceDetails.LaunchDetails.Shape != nil {
-				shape.Name = *instanceDetails.LaunchDetails.Shape
-			}
-			if instanceDetails.LaunchDetails.ShapeConfig.Ocpus != nil {
-				shape.CPU = *instanceDetails.LaunchDetails.ShapeConfig.Ocpus
-				// Minimum amount of memory unless explicitly set higher
-				shape.MemoryInBytes = *instanceDetails.LaunchDetails.ShapeConfig.Ocpus * 1024 * 1024 * 1024
-			}
-			if instanceDetails.LaunchDetails.ShapeConfig.MemoryInGBs != nil {
-				shape.MemoryInBytes = *instanceDetails.LaunchDetails.ShapeConfig.MemoryInGBs * 1024 * 1024 * 1024
-			}
+	if instanceDetails, ok := instanceConfig.InstanceDetails.(core.ComputeInstanceDetails); ok && instanceDetails.LaunchDetails != nil && instanceDetails.LaunchDetails.Shape != nil {
+		launchDetails := instanceDetails.LaunchDetails
+		shapeConfig := launchDetails.ShapeConfig
+		if shapeConfig != nil && shapeConfig.Ocpus != nil && shapeConfig.MemoryInGBs != nil {
+			// The flexible shape is fully configured, no need to look up the shape defaults.
+			shape = instanceShape(core.Shape{Shape: launchDetails.Shape}, shapeConfig)
 		} else {
-			// Fetch the shape object by name
-			var page *string
-			var everyShape []core.Shape
-			for {
-				// List all available shapes
-				lisShapesReq := core.ListShapesRequest{}
-				lisShapesReq.CompartmentId = instanceConfig.CompartmentId
-				lisShapesReq.Page = page
-				lisShapesReq.Limit = common.Int(50)
-
-				listShapes, err := osf.shapeClient.ListShapes(context.Background(), lisShapesReq)
-				if err != nil {
-					return nil, err
-				}
-
-				everyShape = append(everyShape, listShapes.Items...)
-
-				if page = listShapes.OpcNextPage; listShapes.OpcNextPage == nil {
-					break
-				}
+			everyShape, err := osf.listShapes(instanceConfig.CompartmentId)
+			if err != nil {
+				return nil, err
 			}
-
 			for _, nextShape := range everyShape {
-				if *nextShape.Shape == *instanceDetails.LaunchDetails.Shape {
-					shape.Name = *nextShape.Shape
-					if nextShape.Ocpus != nil {
-						shape.CPU = *nextShape.Ocpus
-					}
-					if nextShape.MemoryInGBs != nil {
-						shape.MemoryInBytes = *nextShape.MemoryInGBs * 1024 * 1024 * 1024
-					}
-					if nextShape.Gpus != nil {
-						shape.GPU = *nextShape.Gpus
-					}
+				if nextShape.Shape != nil && *nextShape.Shape == *launchDetails.Shape {
+					shape = instanceShape(nextShape, shapeConfig)
 				}
 			}
 		}
@@ -216,6 +184,72 @@ func (osf *shapeGetterImpl) GetInstancePoolShape(ip *core.InstancePool) (*Shape,
 	return shape, nil
 }
 
+// listShapes lists all the shapes available in the compartment.
+func (osf *shapeGetterImpl) listShapes(compartmentId *string) ([]core.Shape, error) {
+	var page *string
+	var everyShape []core.Shape
+	for {
+		// List all available shapes
+		lisShapesReq := core.ListShapesRequest{}
+		lisShapesReq.CompartmentId = compartmentId
+		lisShapesReq.Page = page
+		lisShapesReq.Limit = common.Int(50)
+
+		listShapes, err := osf.shapeClient.ListShapes(context.Background(), lisShapesReq)
+		if err != nil {
+			return nil, err
+		}
+
+		everyShape = append(everyShape, listShapes.Items...)
+
+		if page = listShapes.OpcNextPage; listShapes.OpcNextPage == nil {
+			break
+		}
+	}
+	return everyShape, nil
+}
+
+// instanceShape returns the resources of instances of the shape launched with the shape config, which is
+// nil for fixed shapes. Flexible shapes get the default memory per OCPU of the shape unless the config sets
+// the memory explicitly.
+func instanceShape(s core.Shape, shapeConfig *core.InstanceConfigurationLaunchInstanceShapeConfigDetails) *Shape {
+	ocpus := getFloat32(s.Ocpus)
+	memoryInGBs := getFloat32(s.MemoryInGBs)
+	if shapeConfig != nil {
+		if shapeConfig.Ocpus != nil {
+			ocpus = *shapeConfig.Ocpus
+			if s.MemoryOptions != nil && s.MemoryOptions.DefaultPerOcpuInGBs != nil {
+				memoryInGBs = ocpus * *s.MemoryOptions.DefaultPerOcpuInGBs
+			}
+		}
+		if shapeConfig.MemoryInGBs != nil {
+			memoryInGBs = *shapeConfig.MemoryInGBs
+		}
+	}
+	return &Shape{
+		Name:                    *s.Shape,
+		CPU:                     ocpus * 2, // convert ocpu to vcpu
+		GPU:                     getInt(s.Gpus),
+		MemoryInBytes:           memoryInGBs * 1024 * 1024 * 1024,
+		BaselineOcpuUtilization: baselineOcpuUtilization(shapeConfig),
+	}
+}
+
+// baselineOcpuUtilization returns the fraction of the CPU guaranteed to burstable instances launched with
+// the shape config, or 0 if they aren't burstable.
+func baselineOcpuUtilization(shapeConfig *core.InstanceConfigurationLaunchInstanceShapeConfigDetails) float32 {
+	if shapeConfig == nil {
+		return 0
+	}
+	switch shapeConfig.BaselineOcpuUtilization {
+	case core.InstanceConfigurationLaunchInstanceShapeConfigDetailsBaselineOcpuUtilization8:
+		return 0.125
+	case core.InstanceConfigurationLaunchInstanceShapeConfigDetailsBaselineOcpuUtilization2:
+		return 0.5
+	}
+	return 0
+}
+
 // getFloat32 is a helper to get a float32 pointer value or default to 0.
 func getFloat32(f *float32) float32 {
 	if f == nil {
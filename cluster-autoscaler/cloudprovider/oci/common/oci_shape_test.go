@@ -153,7 +153,7 @@ func TestGetInstancePoolShape(t *testing.T) {
 			shape: "VM.Standard.E3.Flex",
 			expected: &Shape{
 				Name:          "VM.Standard.E3.Flex",
-				CPU:           8,
+				CPU:           16,
 				MemoryInBytes: float32(128) * 1024 * 1024 * 1024,
 				GPU:           0,
 			},
@@ -188,3 +188,89 @@ func TestGetInstancePoolShape(t *testing.T) {
 		})
 	}
 }
+
+func TestGetInstancePoolShapeConfig(t *testing.T) {
+	shapeClient := &mockShapeClient{
+		listShapeResp: core.ListShapesResponse{
+			Items: []core.Shape{
+				{
+					Shape:       common.String("VM.Standard2.8"),
+					Ocpus:       common.Float32(8),
+					MemoryInGBs: common.Float32(120),
+				},
+				{
+					Shape:         common.String("VM.Standard.E4.Flex"),
+					Ocpus:         common.Float32(1),
+					MemoryInGBs:   common.Float32(16),
+					MemoryOptions: &core.ShapeMemoryOptions{DefaultPerOcpuInGBs: common.Float32(16)},
+				},
+			},
+		},
+	}
+
+	testCases := map[string]struct {
+		shape       string
+		shapeConfig *core.InstanceConfigurationLaunchInstanceShapeConfigDetails
+		expected    *Shape
+	}{
+		"fixed shape": {
+			shape: "VM.Standard2.8",
+			expected: &Shape{
+				Name:          "VM.Standard2.8",
+				CPU:           16,
+				MemoryInBytes: 120 * 1024 * 1024 * 1024,
+			},
+		},
+		"flex shape with default memory": {
+			shape: "VM.Standard.E4.Flex",
+			shapeConfig: &core.InstanceConfigurationLaunchInstanceShapeConfigDetails{
+				Ocpus: common.Float32(4),
+			},
+			expected: &Shape{
+				Name:          "VM.Standard.E4.Flex",
+				CPU:           8,
+				MemoryInBytes: 4 * 16 * 1024 * 1024 * 1024,
+			},
+		},
+		"burstable flex shape": {
+			shape: "VM.Standard.E4.Flex",
+			shapeConfig: &core.InstanceConfigurationLaunchInstanceShapeConfigDetails{
+				Ocpus:                   common.Float32(2),
+				MemoryInGBs:             common.Float32(8),
+				BaselineOcpuUtilization: core.InstanceConfigurationLaunchInstanceShapeConfigDetailsBaselineOcpuUtilization8,
+			},
+			expected: &Shape{
+				Name:                    "VM.Standard.E4.Flex",
+				CPU:                     4,
+				MemoryInBytes:           8 * 1024 * 1024 * 1024,
+				BaselineOcpuUtilization: 0.125,
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			shapeClient.getInstanceConfigResp = core.GetInstanceConfigurationResponse{
+				InstanceConfiguration: core.InstanceConfiguration{
+					Id: common.String("ocid1.instanceconfiguration.oc1.phx.aaaaaaaa1"),
+					InstanceDetails: core.ComputeInstanceDetails{
+						LaunchDetails: &core.InstanceConfigurationLaunchInstanceDetails{
+							Shape:       common.String(tc.shape),
+							ShapeConfig: tc.shapeConfig,
+						},
+					},
+				},
+			}
+			shapeGetter := CreateShapeGetter(shapeClient)
+
+			shape, err := shapeGetter.GetInstancePoolShape(&core.InstancePool{Id: common.String("ocid1.instancepool.oc1.phx.aaaaaaaa1"), InstanceConfigurationId: common.String("ocid1.instanceconfiguration.oc1.phx.aaaaaaaa1")})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(shape, tc.expected) {
+				t.Errorf("wanted %+v ; got %+v", tc.expected, shape)
+			}
+		})
+	}
+}
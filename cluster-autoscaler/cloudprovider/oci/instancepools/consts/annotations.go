@@ -29,6 +29,8 @@ const (
 
 	// OciAnnotationCompartmentID the well known annotation string for compartment ids
 	OciAnnotationCompartmentID = "oci.oraclecloud.com/compartment-id"
+	// OciBaselineOcpuUtilizationAnnotation the annotation string for the baseline CPU utilization of burstable instances
+	OciBaselineOcpuUtilizationAnnotation = "oci.oraclecloud.com/baseline-ocpu-utilization"
 	// OciInstanceIDAnnotation the well known annotation string for instance ids
 	OciInstanceIDAnnotation = "oci.oraclecloud.com/instance-id"
 	// InstanceIDLabelPrefix the prefix of the instance ocid
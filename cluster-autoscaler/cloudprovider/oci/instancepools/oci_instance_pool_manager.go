@@ -439,11 +439,14 @@ func (m *InstancePoolManagerImpl) buildNodeFromTemplate(instancePool *core.Insta
 	}
 
 	node.Status.Capacity[apiv1.ResourcePods] = *resource.NewQuantity(110, resource.DecimalSI)
-	node.Status.Capacity[apiv1.ResourceCPU] = *resource.NewQuantity(int64(shape.CPU), resource.DecimalSI)
+	node.Status.Capacity[apiv1.ResourceCPU] = *resource.NewMilliQuantity(int64(shape.CPU*1000), resource.DecimalSI)
 	node.Status.Capacity[apiv1.ResourceMemory] = *resource.NewQuantity(int64(shape.MemoryInBytes), resource.DecimalSI)
 	node.Status.Capacity[consts.ResourceGPU] = *resource.NewQuantity(int64(shape.GPU), resource.DecimalSI)
 
 	node.Status.Allocatable = node.Status.Capacity
+	if shape.BaselineOcpuUtilization > 0 {
+		node.Annotations[consts.OciBaselineOcpuUtilizationAnnotation] = strconv.FormatFloat(float64(shape.BaselineOcpuUtilization), 'f', -1, 32)
+	}
 
 	availabilityDomain, err := getInstancePoolAvailabilityDomain(instancePool)
 	if err != nil {
@@ -166,6 +166,11 @@ func (np *nodePool) DeleteNodes(nodes []*apiv1.Node) (err error) {
 	return deleteInstancesErr
 }
 
+// ForceDeleteNodes deletes nodes from the group regardless of constraints.
+func (np *nodePool) ForceDeleteNodes(nodes []*apiv1.Node) error {
+	return cloudprovider.ErrNotImplemented
+}
+
 // DecreaseTargetSize decreases the target size of the node group. This function
 // doesn't permit to delete any existing node and can be used only to reduce the
 // request for new nodes that have not been yet fulfilled. Delta should be negative.
@@ -221,6 +221,11 @@ func (ng *packetNodeGroup) DeleteNodes(nodes []*apiv1.Node) error {
 	return nil
 }
 
+// ForceDeleteNodes deletes nodes from the group regardless of constraints.
+func (ng *packetNodeGroup) ForceDeleteNodes(nodes []*apiv1.Node) error {
+	return cloudprovider.ErrNotImplemented
+}
+
 // DecreaseTargetSize decreases the cluster node_count in packet.
 func (ng *packetNodeGroup) DecreaseTargetSize(delta int) error {
 	if delta >= 0 {
@@ -333,6 +333,8 @@ type TestNodeGroup struct {
 	labels          map[string]string
 	taints          []apiv1.Taint
 	opts            *config.NodeGroupAutoscalingOptions
+	capacityType    cloudprovider.CapacityType
+	refreshes       int
 }
 
 // NewTestNodeGroup creates a TestNodeGroup without setting up the realted TestCloudProvider.
@@ -455,6 +457,11 @@ func (tng *TestNodeGroup) DeleteNodes(nodes []*apiv1.Node) error {
 	return nil
 }
 
+// ForceDeleteNodes deletes nodes from the group regardless of constraints.
+func (tng *TestNodeGroup) ForceDeleteNodes(nodes []*apiv1.Node) error {
+	return tng.DeleteNodes(nodes)
+}
+
 // Id returns an unique identifier of the node group.
 func (tng *TestNodeGroup) Id() string {
 	tng.Lock()
@@ -525,6 +532,31 @@ func (tng *TestNodeGroup) SetOptions(opts *config.NodeGroupAutoscalingOptions) {
 	tng.opts = opts
 }
 
+// CapacityType returns the kind of capacity backing the test node group, on-demand unless set otherwise.
+func (tng *TestNodeGroup) CapacityType() cloudprovider.CapacityType {
+	return tng.capacityType
+}
+
+// SetCapacityType allows changing the kind of capacity backing the test node group.
+func (tng *TestNodeGroup) SetCapacityType(capacityType cloudprovider.CapacityType) {
+	tng.capacityType = capacityType
+}
+
+// RefreshTargetSize counts the forced target size refreshes of the test node group.
+func (tng *TestNodeGroup) RefreshTargetSize() error {
+	tng.Lock()
+	defer tng.Unlock()
+	tng.refreshes++
+	return nil
+}
+
+// TargetSizeRefreshes returns the number of forced target size refreshes of the test node group.
+func (tng *TestNodeGroup) TargetSizeRefreshes() int {
+	tng.Lock()
+	defer tng.Unlock()
+	return tng.refreshes
+}
+
 // Labels returns labels passed to the test node group when it was created.
 func (tng *TestNodeGroup) Labels() map[string]string {
 	return tng.labels
@@ -0,0 +1,220 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package testsuite is a table-driven suite checking the NodeGroup semantics the autoscaler core relies on.
+// It is meant to be embedded in the unit tests of in-tree cloud providers, run against a fake or mocked cloud
+// API:
+//
+//	func TestSuite(t *testing.T) {
+//		testsuite.Suite{NewProvider: newTestProvider}.Run(t)
+//	}
+//
+// Unlike the conformance package it builds on, it runs each case against a fresh provider, so cases are free
+// to resize node groups and leave them resized.
+package testsuite
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/conformance"
+)
+
+const (
+	timeout      = 10 * time.Second
+	pollInterval = 10 * time.Millisecond
+)
+
+// Suite configures a run of the cases against a cloud provider.
+type Suite struct {
+	// NewProvider returns the provider under test, in its initial state. It is called once per case.
+	NewProvider func(t *testing.T) cloudprovider.CloudProvider
+	// Nodes are the nodes of the cluster. They are looked up with NodeGroupForNode on every refresh, as the
+	// core does in each loop, for providers discovering or refreshing node groups from nodes.
+	Nodes []*apiv1.Node
+	// NodeForInstance builds the node an instance registers as. Defaults to conformance.DefaultNodeForInstance.
+	NodeForInstance func(instance cloudprovider.Instance) *apiv1.Node
+	// Skip maps the names of the cases that don't apply to the provider to the reason why.
+	Skip map[string]string
+}
+
+// Case is a case of the suite.
+type Case struct {
+	// Name of the case, used to name its test.
+	Name string
+	// Run runs the case against the node group with the given id.
+	Run func(core *conformance.Core, id string) error
+}
+
+// Cases are the cases run against each node group.
+var Cases = []Case{
+	{Name: "TargetSize", Run: CheckTargetSize},
+	{Name: "Nodes", Run: conformance.CheckInstances},
+	{Name: "TemplateNodeInfo", Run: conformance.CheckTemplate},
+	{Name: "InvalidResizes", Run: conformance.CheckInvalidResizes},
+	{Name: "DeleteForeignNode", Run: conformance.CheckDeleteForeignNode},
+	{Name: "DeleteNodesIdempotency", Run: CheckDeleteNodesIdempotency},
+	{Name: "ScaleUpAndDelete", Run: conformance.CheckScaleUpAndDelete},
+}
+
+// Run runs the cases against each node group of the provider, one test per node group and case.
+func (s Suite) Run(t *testing.T) {
+	t.Run("NodeGroups", func(t *testing.T) {
+		report(t, conformance.CheckNodeGroupIds(s.newCore(t)))
+	})
+	var ids []string
+	for _, nodeGroup := range s.newCore(t).NodeGroups() {
+		ids = append(ids, nodeGroup.Id())
+	}
+	for _, id := range ids {
+		for _, c := range Cases {
+			c := c
+			id := id
+			t.Run(id+"/"+c.Name, func(t *testing.T) {
+				if reason, found := s.Skip[c.Name]; found {
+					t.Skip(reason)
+				}
+				report(t, c.Run(s.newCore(t), id))
+			})
+		}
+	}
+}
+
+// newCore returns a refreshed core for a new provider.
+func (s Suite) newCore(t *testing.T) *conformance.Core {
+	provider := &nodeLookupProvider{CloudProvider: s.NewProvider(t), nodes: s.Nodes}
+	core := conformance.NewCore(conformance.Config{
+		Provider:        provider,
+		AllowResize:     true,
+		NodeForInstance: s.NodeForInstance,
+		Timeout:         timeout,
+		PollInterval:    pollInterval,
+	})
+	if err := core.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	return core
+}
+
+func report(t *testing.T, err error) {
+	if conformance.IsSkipped(err) {
+		t.Skip(err.Error())
+	}
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// nodeLookupProvider looks the nodes up after each refresh of the wrapped provider.
+type nodeLookupProvider struct {
+	cloudprovider.CloudProvider
+	nodes []*apiv1.Node
+}
+
+// Refresh refreshes the wrapped provider and looks the nodes up. Lookup errors are ignored, as nodes may
+// have been deleted by a case.
+func (p *nodeLookupProvider) Refresh() error {
+	if err := p.CloudProvider.Refresh(); err != nil {
+		return err
+	}
+	for _, node := range p.nodes {
+		_, _ = p.CloudProvider.NodeGroupForNode(node)
+	}
+	return nil
+}
+
+// CheckTargetSize checks that the size limits and target size of the node group are consistent, and that
+// the target size matches the number of instances not being deleted. The provider is expected to be stable.
+func CheckTargetSize(core *conformance.Core, id string) error {
+	if err := conformance.CheckSizeLimits(core, id); err != nil {
+		return err
+	}
+	nodeGroup, err := core.NodeGroup(id)
+	if err != nil {
+		return err
+	}
+	size, err := nodeGroup.TargetSize()
+	if err != nil {
+		return fmt.Errorf("failed to get target size of node group %s: %v", id, err)
+	}
+	instances, err := nodeGroup.Nodes()
+	if err != nil {
+		return fmt.Errorf("failed to list instances of node group %s: %v", id, err)
+	}
+	count := 0
+	for _, instance := range instances {
+		if instance.Status == nil || instance.Status.State != cloudprovider.InstanceDeleting {
+			count++
+		}
+	}
+	if count != size {
+		return fmt.Errorf("node group %s target size is %d but it has %d instances", id, size, count)
+	}
+	return nil
+}
+
+// CheckDeleteNodesIdempotency deletes an existing node of the node group twice, as the core may after a
+// failed drain or a restart. It checks that the first deletion decreases the target size by one, and that
+// the second one doesn't decrease it again.
+func CheckDeleteNodesIdempotency(core *conformance.Core, id string) error {
+	nodeGroup, err := core.NodeGroup(id)
+	if err != nil {
+		return err
+	}
+	size, err := nodeGroup.TargetSize()
+	if err != nil {
+		return fmt.Errorf("failed to get target size of node group %s: %v", id, err)
+	}
+	if size <= nodeGroup.MinSize() {
+		return conformance.Skipf("node group %s is at its min size %d", id, nodeGroup.MinSize())
+	}
+	instances, err := nodeGroup.Nodes()
+	if err != nil {
+		return fmt.Errorf("failed to list instances of node group %s: %v", id, err)
+	}
+	var node *apiv1.Node
+	for _, instance := range instances {
+		if instance.Status == nil || instance.Status.State == cloudprovider.InstanceRunning {
+			node = core.Node(instance)
+			break
+		}
+	}
+	if node == nil {
+		return conformance.Skipf("node group %s has no running instance", id)
+	}
+
+	if err := nodeGroup.DeleteNodes([]*apiv1.Node{node}); err != nil {
+		return fmt.Errorf("failed to delete node %s of node group %s: %v", node.Name, id, err)
+	}
+	if err := core.WaitForTargetSize(id, size-1); err != nil {
+		return err
+	}
+	if nodeGroup, err = core.NodeGroup(id); err != nil {
+		return err
+	}
+	_ = nodeGroup.DeleteNodes([]*apiv1.Node{node})
+	current, err := core.TargetSize(id)
+	if err != nil {
+		return err
+	}
+	if current != size-1 {
+		return fmt.Errorf("node group %s target size changed from %d to %d after deleting node %s twice", id, size-1, current, node.Name)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testsuite
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/conformance"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// fakeProvider is an in-memory provider with a single node group. Unless strict is set, its node group
+// deletes nodes that don't belong to it and reports a target size ahead of its instances.
+type fakeProvider struct {
+	cloudprovider.CloudProvider
+	group *fakeNodeGroup
+}
+
+func newFakeProvider(strict bool) *fakeProvider {
+	group := &fakeNodeGroup{strict: strict, instances: map[string]bool{"ng1-1": true, "ng1-2": true}, target: 2, created: 2}
+	if !strict {
+		group.target = 3
+	}
+	return &fakeProvider{group: group}
+}
+
+func (p *fakeProvider) Refresh() error {
+	return nil
+}
+
+func (p *fakeProvider) NodeGroups() []cloudprovider.NodeGroup {
+	return []cloudprovider.NodeGroup{p.group}
+}
+
+func (p *fakeProvider) NodeGroupForNode(node *apiv1.Node) (cloudprovider.NodeGroup, error) {
+	if p.group.instances[node.Spec.ProviderID] {
+		return p.group, nil
+	}
+	return nil, nil
+}
+
+type fakeNodeGroup struct {
+	cloudprovider.NodeGroup
+	strict    bool
+	instances map[string]bool
+	target    int
+	created   int
+}
+
+func (g *fakeNodeGroup) Id() string {
+	return "ng1"
+}
+
+func (g *fakeNodeGroup) MinSize() int {
+	return 1
+}
+
+func (g *fakeNodeGroup) MaxSize() int {
+	return 5
+}
+
+func (g *fakeNodeGroup) Exist() bool {
+	return true
+}
+
+func (g *fakeNodeGroup) TargetSize() (int, error) {
+	return g.target, nil
+}
+
+func (g *fakeNodeGroup) IncreaseSize(delta int) error {
+	if delta <= 0 || g.target+delta > g.MaxSize() {
+		return fmt.Errorf("invalid size increase %d", delta)
+	}
+	for i := 0; i < delta; i++ {
+		g.created++
+		g.instances[fmt.Sprintf("ng1-%d", g.created)] = true
+	}
+	g.target += delta
+	return nil
+}
+
+func (g *fakeNodeGroup) DecreaseTargetSize(delta int) error {
+	if delta >= 0 || g.target+delta < len(g.instances) {
+		return fmt.Errorf("invalid target size decrease %d", delta)
+	}
+	g.target += delta
+	return nil
+}
+
+func (g *fakeNodeGroup) DeleteNodes(nodes []*apiv1.Node) error {
+	for _, node := range nodes {
+		if !g.instances[node.Spec.ProviderID] && g.strict {
+			return fmt.Errorf("node %s doesn't belong to ng1", node.Name)
+		}
+		delete(g.instances, node.Spec.ProviderID)
+		g.target--
+	}
+	return nil
+}
+
+func (g *fakeNodeGroup) Nodes() ([]cloudprovider.Instance, error) {
+	var result []cloudprovider.Instance
+	for id := range g.instances {
+		result = append(result, cloudprovider.Instance{Id: id, Status: &cloudprovider.InstanceStatus{State: cloudprovider.InstanceRunning}})
+	}
+	return result, nil
+}
+
+func (g *fakeNodeGroup) TemplateNodeInfo() (*schedulerframework.NodeInfo, error) {
+	nodeInfo := schedulerframework.NewNodeInfo()
+	nodeInfo.SetNode(BuildTestNode("ng1-template", 1000, 1000))
+	return nodeInfo, nil
+}
+
+func TestSuite(t *testing.T) {
+	Suite{
+		NewProvider: func(t *testing.T) cloudprovider.CloudProvider { return newFakeProvider(true) },
+	}.Run(t)
+}
+
+func TestChecks(t *testing.T) {
+	for _, tc := range []struct {
+		desc      string
+		strict    bool
+		check     func(core *conformance.Core, id string) error
+		expectErr bool
+	}{
+		{desc: "target size", strict: true, check: CheckTargetSize},
+		{desc: "target size ahead of instances", check: CheckTargetSize, expectErr: true},
+		{desc: "delete nodes twice", strict: true, check: CheckDeleteNodesIdempotency},
+		{desc: "node deleted twice", check: CheckDeleteNodesIdempotency, expectErr: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			core := Suite{NewProvider: func(t *testing.T) cloudprovider.CloudProvider { return newFakeProvider(tc.strict) }}.newCore(t)
+			err := tc.check(core, "ng1")
+			assert.False(t, conformance.IsSkipped(err))
+			if tc.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -50,3 +50,30 @@ func TestJoinStringMaps(t *testing.T) {
 	result := JoinStringMaps(map1, map2, map3)
 	assert.Equal(t, map[string]string{"1": "a", "2": "d", "3": "c", "5": "e"}, result)
 }
+
+func TestNormalizeProviderID(t *testing.T) {
+	testCases := []struct {
+		providerID string
+		expected   string
+	}{
+		{
+			providerID: "azure:///subscriptions/sub/resourceGroups/MC_RG/providers/Microsoft.Compute/virtualMachineScaleSets/aks-Pool/virtualMachines/0",
+			expected:   "azure:///subscriptions/sub/resourcegroups/mc_rg/providers/microsoft.compute/virtualmachinescalesets/aks-pool/virtualmachines/0",
+		},
+		{
+			providerID: "Azure:///subscriptions/sub/resourcegroups/mc_rg/providers/microsoft.compute/virtualmachinescalesets/aks-pool/virtualmachines/0 ",
+			expected:   "azure:///subscriptions/sub/resourcegroups/mc_rg/providers/microsoft.compute/virtualmachinescalesets/aks-pool/virtualmachines/0",
+		},
+		{
+			providerID: "GCE://project/us-central1-b/Node-1",
+			expected:   "gce://project/us-central1-b/Node-1",
+		},
+		{
+			providerID: "Node-1",
+			expected:   "Node-1",
+		},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, NormalizeProviderID(tc.providerID))
+	}
+}
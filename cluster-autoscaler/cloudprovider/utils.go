@@ -19,6 +19,7 @@ package cloudprovider
 import (
 	"fmt"
 	"math/rand"
+	"strings"
 	"time"
 
 	apiv1 "k8s.io/api/core/v1"
@@ -125,3 +126,21 @@ func JoinStringMaps(items ...map[string]string) map[string]string {
 	}
 	return result
 }
+
+// NormalizeProviderID returns a canonical form of a provider ID, so that provider IDs
+// differing only in formatting details, e.g. the casing of case-insensitive parts, can be
+// compared. Node provider IDs are set by kubelet or the cloud controller manager and node
+// group instance IDs by the cloud provider, which doesn't guarantee they're formatted alike.
+func NormalizeProviderID(providerID string) string {
+	providerID = strings.TrimSpace(providerID)
+	scheme, rest, found := strings.Cut(providerID, "://")
+	if !found {
+		return providerID
+	}
+	scheme = strings.ToLower(scheme)
+	// Azure resource IDs are case-insensitive as a whole.
+	if scheme == AzureProviderName {
+		rest = strings.ToLower(rest)
+	}
+	return scheme + "://" + rest
+}
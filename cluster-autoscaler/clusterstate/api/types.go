@@ -35,6 +35,9 @@ const (
 	// ClusterAutoscalerScaleUp is a condition that explains what is the current status
 	// of a node group with regard to scale up activities.
 	ClusterAutoscalerScaleUp ClusterAutoscalerConditionType = "ScaleUp"
+	// ClusterAutoscalerOrphaned is a condition that explains how ClusterAutoscaler treats
+	// a node group that is no longer returned by the cloud provider, but still has nodes.
+	ClusterAutoscalerOrphaned ClusterAutoscalerConditionType = "Orphaned"
 )
 
 // ClusterAutoscalerConditionStatus is a status of ClusterAutoscalerCondition.
@@ -67,6 +70,15 @@ const (
 	ClusterAutoscalerNoActivity ClusterAutoscalerConditionStatus = "NoActivity"
 	// ClusterAutoscalerBackoff status means that due to a recently failed scale-up no further scale-ups attempts will be made for some time.
 	ClusterAutoscalerBackoff ClusterAutoscalerConditionStatus = "Backoff"
+
+	// Statuses for Orphaned condition type.
+
+	// ClusterAutoscalerUnmanaged status means that nodes of the orphaned node group are only reported.
+	ClusterAutoscalerUnmanaged ClusterAutoscalerConditionStatus = "Unmanaged"
+	// ClusterAutoscalerAdopted status means that nodes of the orphaned node group are kept read-only.
+	ClusterAutoscalerAdopted ClusterAutoscalerConditionStatus = "Adopted"
+	// ClusterAutoscalerDraining status means that the orphaned node group is being drained toward zero.
+	ClusterAutoscalerDraining ClusterAutoscalerConditionStatus = "Draining"
 )
 
 // ClusterAutoscalerCondition describes some aspect of ClusterAutoscaler work.
@@ -92,6 +104,8 @@ type ClusterAutoscalerStatus struct {
 	NodeGroupStatuses []NodeGroupStatus `json:"nodeGroupStatuses,omitempty"`
 	// ClusterwideConditions contains conditions that apply to the whole autoscaler.
 	ClusterwideConditions []ClusterAutoscalerCondition `json:"clusterwideConditions,omitempty"`
+	// PendingPodGroups contains the estimated time to schedule of groups of equivalent pending pods.
+	PendingPodGroups []PendingPodGroupStatus `json:"pendingPodGroups,omitempty"`
 }
 
 // NodeGroupStatus contains status of a group of nodes controlled by ClusterAutoscaler.
@@ -102,3 +116,42 @@ type NodeGroupStatus struct {
 	// Conditions is a list of conditions that describe the state of the node group.
 	Conditions []ClusterAutoscalerCondition `json:"conditions,omitempty"`
 }
+
+// PendingPodGroupReason explains what a group of pending pods is waiting for.
+type PendingPodGroupReason string
+
+const (
+	// PendingPodGroupSchedulable means that the pods fit on existing nodes and only wait for
+	// the scheduler.
+	PendingPodGroupSchedulable PendingPodGroupReason = "Schedulable"
+	// PendingPodGroupWaitingForUpcomingNodes means that the pods wait for nodes from a scale-up
+	// in progress.
+	PendingPodGroupWaitingForUpcomingNodes PendingPodGroupReason = "WaitingForUpcomingNodes"
+	// PendingPodGroupScaleUpTriggered means that the pods triggered a scale-up in the last loop.
+	PendingPodGroupScaleUpTriggered PendingPodGroupReason = "ScaleUpTriggered"
+	// PendingPodGroupBackedOff means that the pods can only be helped by node groups which are
+	// backed off after failed scale-ups.
+	PendingPodGroupBackedOff PendingPodGroupReason = "BackedOff"
+	// PendingPodGroupNoScaleUpOption means that no node group can help the pods.
+	PendingPodGroupNoScaleUpOption PendingPodGroupReason = "NoScaleUpOption"
+	// PendingPodGroupNotEvaluated means that the pods weren't considered for a scale-up in the
+	// last loop, e.g. because they are too young or the loop ended early.
+	PendingPodGroupNotEvaluated PendingPodGroupReason = "NotEvaluated"
+)
+
+// PendingPodGroupStatus contains the estimated time to schedule of a group of equivalent pending pods.
+type PendingPodGroupStatus struct {
+	// Namespace is the namespace of the pods.
+	Namespace string `json:"namespace"`
+	// Owner identifies the controller of the pods as kind/name, or the pod if it has no controller.
+	Owner string `json:"owner"`
+	// Pods is the number of pending pods in the group.
+	Pods int `json:"pods"`
+	// Reason explains what the pods are waiting for.
+	Reason PendingPodGroupReason `json:"reason"`
+	// NodeGroups are the node groups expected to provide nodes for the pods.
+	NodeGroups []string `json:"nodeGroups,omitempty"`
+	// TimeToSchedule is the estimated time until the pods can be scheduled. It is unset if the
+	// pods can't be scheduled in a predictable time.
+	TimeToSchedule *metav1.Duration `json:"timeToSchedule,omitempty"`
+}
@@ -19,6 +19,7 @@ package api
 import (
 	"bytes"
 	"fmt"
+	"strings"
 )
 
 // GetConditionByType gets condition by type.
@@ -74,19 +75,41 @@ func getConditionsString(autoscalerConditions []ClusterAutoscalerCondition, pref
 	return buffer.String()
 }
 
+func getPendingPodGroupString(group PendingPodGroupStatus, prefix string) string {
+	timeToSchedule := "<unknown>"
+	if group.TimeToSchedule != nil {
+		timeToSchedule = group.TimeToSchedule.Duration.String()
+	}
+	var buffer bytes.Buffer
+	buffer.WriteString(fmt.Sprintf("%vName:           %v/%v\n", prefix, group.Namespace, group.Owner))
+	buffer.WriteString(fmt.Sprintf("%vPods:           %v\n", prefix, group.Pods))
+	buffer.WriteString(fmt.Sprintf("%vReason:         %v\n", prefix, group.Reason))
+	if len(group.NodeGroups) > 0 {
+		buffer.WriteString(fmt.Sprintf("%vNodeGroups:     %v\n", prefix, strings.Join(group.NodeGroups, ", ")))
+	}
+	buffer.WriteString(fmt.Sprintf("%vTimeToSchedule: %v\n", prefix, timeToSchedule))
+	buffer.WriteString("\n")
+	return buffer.String()
+}
+
 // GetReadableString produces human-readable description of status.
 func (status ClusterAutoscalerStatus) GetReadableString() string {
 	var buffer bytes.Buffer
 	buffer.WriteString("Cluster-wide:\n")
 	buffer.WriteString(getConditionsString(status.ClusterwideConditions, "  "))
-	if len(status.NodeGroupStatuses) == 0 {
-		return buffer.String()
+	if len(status.NodeGroupStatuses) > 0 {
+		buffer.WriteString("\nNodeGroups:\n")
+		for _, nodeGroupStatus := range status.NodeGroupStatuses {
+			buffer.WriteString(fmt.Sprintf("  Name:        %v\n", nodeGroupStatus.ProviderID))
+			buffer.WriteString(getConditionsString(nodeGroupStatus.Conditions, "  "))
+			buffer.WriteString("\n")
+		}
 	}
-	buffer.WriteString("\nNodeGroups:\n")
-	for _, nodeGroupStatus := range status.NodeGroupStatuses {
-		buffer.WriteString(fmt.Sprintf("  Name:        %v\n", nodeGroupStatus.ProviderID))
-		buffer.WriteString(getConditionsString(nodeGroupStatus.Conditions, "  "))
-		buffer.WriteString("\n")
+	if len(status.PendingPodGroups) > 0 {
+		buffer.WriteString("\nPendingPodGroups:\n")
+		for _, group := range status.PendingPodGroups {
+			buffer.WriteString(getPendingPodGroupString(group, "  "))
+		}
 	}
 	return buffer.String()
 }
@@ -20,8 +20,10 @@ import (
 	"fmt"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func prepareConditions() (health, scaleUp ClusterAutoscalerCondition) {
@@ -89,3 +91,29 @@ func TestGetStringNodeGroups(t *testing.T) {
 	assert.Regexp(t, regexp.MustCompile("(?ms)NodeGroups:.*Name:\\s*ng1"), result)
 	assert.Regexp(t, regexp.MustCompile("(?ms)NodeGroups:.*Name:\\s*ng2"), result)
 }
+
+func TestGetStringPendingPodGroups(t *testing.T) {
+	var status ClusterAutoscalerStatus
+	healthCondition, _ := prepareConditions()
+	status.ClusterwideConditions = append(status.ClusterwideConditions, healthCondition)
+	status.PendingPodGroups = []PendingPodGroupStatus{
+		{
+			Namespace:      "default",
+			Owner:          "ReplicaSet/web",
+			Pods:           3,
+			Reason:         PendingPodGroupScaleUpTriggered,
+			NodeGroups:     []string{"ng1"},
+			TimeToSchedule: &metav1.Duration{Duration: 5 * time.Minute},
+		},
+		{
+			Namespace: "default",
+			Owner:     "Pod/huge",
+			Pods:      1,
+			Reason:    PendingPodGroupNoScaleUpOption,
+		},
+	}
+	result := status.GetReadableString()
+	assert.NotRegexp(t, regexp.MustCompile("NodeGroups:\\n"), result)
+	assert.Regexp(t, regexp.MustCompile("(?ms)PendingPodGroups:.*Name:\\s*default/ReplicaSet/web.*Pods:\\s*3.*Reason:\\s*ScaleUpTriggered.*NodeGroups:\\s*ng1.*TimeToSchedule:\\s*5m0s"), result)
+	assert.Regexp(t, regexp.MustCompile("(?ms)Name:\\s*default/Pod/huge.*Reason:\\s*NoScaleUpOption.*TimeToSchedule:\\s*<unknown>"), result)
+}
@@ -82,6 +82,9 @@ type ClusterStateRegistryConfig struct {
 	// Minimum number of nodes that must be unready for MaxTotalUnreadyPercentage to apply.
 	// This is to ensure that in very small clusters (e.g. 2 nodes) a single node's failure doesn't disable autoscaling.
 	OkTotalUnreadyCount int
+	// TargetSizeRefreshInterval is how often the target size of each node group is forcefully refreshed
+	// and verified, to detect resizes made outside of the autoscaler. Zero disables the verification.
+	TargetSizeRefreshInterval time.Duration
 }
 
 // IncorrectNodeGroupSize contains information about how much the current size of the node group
@@ -141,6 +144,20 @@ type ClusterStateRegistry struct {
 	// scaleUpFailures contains information about scale-up failures for each node group. It should be
 	// cleared periodically to avoid unnecessary accumulation.
 	scaleUpFailures map[string][]ScaleUpFailure
+
+	// scaleUpHints contains scale-ups restored from a previous run, for which the cloud provider
+	// doesn't report the increased target size yet.
+	scaleUpHints map[string]ScaleUpHint
+
+	// provisioningLatencies contains the provisioning latency learned from successful scale-ups
+	// of each node group.
+	provisioningLatencies map[string]time.Duration
+
+	// backoffUntil contains the time until which each node group was last backed off.
+	backoffUntil map[string]time.Time
+
+	// targetSizeRefreshes contains the state of the target size verification of each node group.
+	targetSizeRefreshes map[string]*targetSizeRefresh
 }
 
 // NewClusterStateRegistry creates new ClusterStateRegistry.
@@ -168,6 +185,10 @@ func NewClusterStateRegistry(cloudProvider cloudprovider.CloudProvider, config C
 		cloudProviderNodeInstancesCache: utils.NewCloudProviderNodeInstancesCache(cloudProvider),
 		interrupt:                       make(chan struct{}),
 		scaleUpFailures:                 make(map[string][]ScaleUpFailure),
+		scaleUpHints:                    make(map[string]ScaleUpHint),
+		provisioningLatencies:           make(map[string]time.Duration),
+		backoffUntil:                    make(map[string]time.Time),
+		targetSizeRefreshes:             make(map[string]*targetSizeRefresh),
 		nodeGroupConfigProcessor:        nodeGroupConfigProcessor,
 	}
 }
@@ -206,6 +227,7 @@ func (csr *ClusterStateRegistry) registerOrUpdateScaleUpNoLock(nodeGroup cloudpr
 		klog.Warningf("Couldn't update scale up request: failed to get maxNodeProvisionTime for node group %s: %w", nodeGroup.Id(), err)
 		return
 	}
+	csr.registerResize(nodeGroup.Id())
 
 	scaleUpRequest, found := csr.scaleUpRequests[nodeGroup.Id()]
 	if !found && delta > 0 {
@@ -244,6 +266,7 @@ func (csr *ClusterStateRegistry) RegisterScaleDown(request *ScaleDownRequest) {
 	csr.Lock()
 	defer csr.Unlock()
 	csr.scaleDownRequests = append(csr.scaleDownRequests, request)
+	csr.registerResize(request.NodeGroup.Id())
 }
 
 // To be executed under a lock.
@@ -257,6 +280,8 @@ func (csr *ClusterStateRegistry) updateScaleRequests(currentTime time.Time) {
 			// remove it and reset node group backoff
 			delete(csr.scaleUpRequests, nodeGroupName)
 			csr.backoff.RemoveBackoff(scaleUpRequest.NodeGroup, csr.nodeInfosForGroups[scaleUpRequest.NodeGroup.Id()])
+			delete(csr.backoffUntil, nodeGroupName)
+			csr.recordProvisioningLatency(nodeGroupName, currentTime.Sub(scaleUpRequest.Time))
 			klog.V(4).Infof("Scale up in group %v finished successfully in %v",
 				nodeGroupName, currentTime.Sub(scaleUpRequest.Time))
 			continue
@@ -294,6 +319,7 @@ func (csr *ClusterStateRegistry) updateScaleRequests(currentTime time.Time) {
 func (csr *ClusterStateRegistry) backoffNodeGroup(nodeGroup cloudprovider.NodeGroup, errorClass cloudprovider.InstanceErrorClass, errorCode string, currentTime time.Time) {
 	nodeGroupInfo := csr.nodeInfosForGroups[nodeGroup.Id()]
 	backoffUntil := csr.backoff.Backoff(nodeGroup, nodeGroupInfo, errorClass, errorCode, currentTime)
+	csr.backoffUntil[nodeGroup.Id()] = backoffUntil
 	klog.Warningf("Disabling scale-up for node group %v until %v; errorClass=%v; errorCode=%v", nodeGroup.Id(), backoffUntil, errorClass, errorCode)
 }
 
@@ -306,15 +332,38 @@ func (csr *ClusterStateRegistry) RegisterFailedScaleUp(nodeGroup cloudprovider.N
 	csr.registerFailedScaleUpNoLock(nodeGroup, reason, cloudprovider.OtherErrorClass, string(reason), gpuResourceName, gpuType, currentTime)
 }
 
+// RegisterFailedScaleUpError should be called after getting the error from cloudprovider when trying to
+// scale-up node group. It works like RegisterFailedScaleUp, except that the node group is backed off
+// according to the error class if the cloud provider returned a cloudprovider.ScaleUpError.
+func (csr *ClusterStateRegistry) RegisterFailedScaleUpError(nodeGroup cloudprovider.NodeGroup, reason metrics.FailedScaleUpReason, err error, gpuResourceName, gpuType string, currentTime time.Time) {
+	errorClass, errorCode := cloudprovider.OtherErrorClass, string(reason)
+	var scaleUpErr *cloudprovider.ScaleUpError
+	if errors.As(err, &scaleUpErr) {
+		errorClass, errorCode = scaleUpErr.ErrorClass, scaleUpErr.ErrorCode
+	}
+	csr.Lock()
+	defer csr.Unlock()
+	csr.registerFailedScaleUpNoLock(nodeGroup, reason, errorClass, errorCode, gpuResourceName, gpuType, currentTime)
+}
+
 func (csr *ClusterStateRegistry) registerFailedScaleUpNoLock(nodeGroup cloudprovider.NodeGroup, reason metrics.FailedScaleUpReason, errorClass cloudprovider.InstanceErrorClass, errorCode string, gpuResourceName, gpuType string, currentTime time.Time) {
 	csr.scaleUpFailures[nodeGroup.Id()] = append(csr.scaleUpFailures[nodeGroup.Id()], ScaleUpFailure{NodeGroup: nodeGroup, Reason: reason, Time: currentTime})
 	metrics.RegisterFailedScaleUp(reason, gpuResourceName, gpuType)
 	csr.backoffNodeGroup(nodeGroup, errorClass, errorCode, currentTime)
 }
 
+// RegisterNodeGroupPanic should be called after recovering from a panic while processing
+// the node group. It will mark this group as not safe to autoscale for some time.
+func (csr *ClusterStateRegistry) RegisterNodeGroupPanic(nodeGroup cloudprovider.NodeGroup, currentTime time.Time) {
+	csr.Lock()
+	defer csr.Unlock()
+	csr.backoffNodeGroup(nodeGroup, cloudprovider.OtherErrorClass, "panic", currentTime)
+}
+
 // UpdateNodes updates the state of the nodes in the ClusterStateRegistry and recalculates the stats
 func (csr *ClusterStateRegistry) UpdateNodes(nodes []*apiv1.Node, nodeInfosForGroups map[string]*schedulerframework.NodeInfo, currentTime time.Time) error {
 	csr.updateNodeGroupMetrics()
+	verified := csr.refreshTargetSizes(currentTime)
 	targetSizes, err := getTargetSizes(csr.cloudProvider)
 	if err != nil {
 		return err
@@ -338,6 +387,8 @@ func (csr *ClusterStateRegistry) UpdateNodes(nodes []*apiv1.Node, nodeInfosForGr
 	csr.updateUnregisteredNodes(notRegistered)
 	csr.updateCloudProviderDeletedNodes(cloudProviderNodesRemoved)
 	csr.updateReadinessStats(currentTime)
+	csr.reconcileTargetSizes(verified, targetSizes, currentTime)
+	csr.applyScaleUpHints(targetSizes, currentTime)
 
 	// update acceptable ranges based on requests from last loop and targetSizes
 	// updateScaleRequests relies on acceptableRanges being up to date
@@ -359,6 +410,7 @@ func (csr *ClusterStateRegistry) Recalculate() {
 
 	csr.Lock()
 	defer csr.Unlock()
+	csr.applyScaleUpHints(targetSizes, time.Now())
 	csr.updateAcceptableRanges(targetSizes)
 }
 
@@ -563,6 +615,11 @@ type Readiness struct {
 	// This field is only used for exposing information externally and
 	// doesn't influence CA behavior.
 	ResourceUnready []string
+	// Names of nodes that are being drained or deleted by CA, with the
+	// ToBeDeleted taint. They are also counted in their readiness state.
+	// This field is only used for exposing information externally and
+	// doesn't influence CA behavior.
+	BeingDeleted []string
 }
 
 func (csr *ClusterStateRegistry) updateReadinessStats(currentTime time.Time) {
@@ -571,6 +628,9 @@ func (csr *ClusterStateRegistry) updateReadinessStats(currentTime time.Time) {
 
 	update := func(current Readiness, node *apiv1.Node, nr kube_util.NodeReadiness) Readiness {
 		current.Registered = append(current.Registered, node.Name)
+		if taints.HasToBeDeletedTaint(node) {
+			current.BeingDeleted = append(current.BeingDeleted, node.Name)
+		}
 		if _, isDeleted := csr.deletedNodes[node.Name]; isDeleted {
 			current.Deleted = append(current.Deleted, node.Name)
 		} else if nr.Ready {
@@ -777,6 +837,11 @@ func (csr *ClusterStateRegistry) GetClusterReadiness() Readiness {
 	return csr.totalReadiness
 }
 
+// GetNodeGroupsReadiness returns current readiness stats of all node groups, by node group id
+func (csr *ClusterStateRegistry) GetNodeGroupsReadiness() map[string]Readiness {
+	return csr.perNodeGroupReadiness
+}
+
 func buildHealthStatusNodeGroup(isReady bool, readiness Readiness, acceptable AcceptableRange, minSize, maxSize int) api.ClusterAutoscalerCondition {
 	condition := api.ClusterAutoscalerCondition{
 		Type: api.ClusterAutoscalerHealth,
@@ -991,14 +1056,15 @@ func (csr *ClusterStateRegistry) getCloudProviderNodeInstances() (map[string][]c
 // As we are expecting for those instances to be Ready soon (O(~minutes)), to speed up the scaling process,
 // we are injecting a temporary, fake nodes to continue scaling based on in-memory cluster state.
 func getNotRegisteredNodes(allNodes []*apiv1.Node, cloudProviderNodeInstances map[string][]cloudprovider.Instance, time time.Time) []UnregisteredNode {
+	// Provider IDs are normalized, as nodes and instances may format them differently.
 	registered := sets.NewString()
 	for _, node := range allNodes {
-		registered.Insert(node.Spec.ProviderID)
+		registered.Insert(cloudprovider.NormalizeProviderID(node.Spec.ProviderID))
 	}
 	notRegistered := make([]UnregisteredNode, 0)
 	for _, instances := range cloudProviderNodeInstances {
 		for _, instance := range instances {
-			if !registered.Has(instance.Id) && expectedToRegister(instance) {
+			if !registered.Has(cloudprovider.NormalizeProviderID(instance.Id)) && expectedToRegister(instance) {
 				notRegistered = append(notRegistered, UnregisteredNode{
 					Node:              FakeNode(instance, cloudprovider.FakeNodeUnregistered),
 					UnregisteredSince: time,
@@ -1194,7 +1260,11 @@ func (csr *ClusterStateRegistry) RefreshCloudProviderNodeInstancesCache() {
 }
 
 // InvalidateNodeInstancesCacheEntry removes a node group from the cloud provider node instances cache.
+// It is called after the autoscaler resized the node group.
 func (csr *ClusterStateRegistry) InvalidateNodeInstancesCacheEntry(nodeGroup cloudprovider.NodeGroup) {
+	csr.Lock()
+	csr.registerResize(nodeGroup.Id())
+	csr.Unlock()
 	csr.cloudProviderNodeInstancesCache.InvalidateCacheEntry(nodeGroup)
 }
 
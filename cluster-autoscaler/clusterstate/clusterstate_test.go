@@ -35,6 +35,7 @@ import (
 	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
 	"k8s.io/client-go/kubernetes/fake"
 	kube_record "k8s.io/client-go/tools/record"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
 
 	"github.com/stretchr/testify/assert"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/backoff"
@@ -573,6 +574,10 @@ func TestTaintBasedNodeDeletion(t *testing.T) {
 	upcomingNodes, upcomingRegistered := clusterstate.GetUpcomingNodes()
 	assert.Equal(t, 1, upcomingNodes["ng1"])
 	assert.Empty(t, upcomingRegistered["ng1"]) // Only unregistered.
+
+	// The tainted node is reported as being deleted.
+	assert.Equal(t, []string{"ng1-2"}, clusterstate.GetClusterReadiness().BeingDeleted)
+	assert.Equal(t, []string{"ng1-2"}, clusterstate.GetNodeGroupsReadiness()["ng1"].BeingDeleted)
 }
 
 func TestIncorrectSize(t *testing.T) {
@@ -647,6 +652,22 @@ func TestUnregisteredNodes(t *testing.T) {
 	assert.Equal(t, 0, len(clusterstate.GetUnregisteredNodes()))
 }
 
+func TestNotRegisteredNodesMixedCaseProviderIDs(t *testing.T) {
+	node := BuildTestNode("vmss-0", 1000, 1000)
+	node.Spec.ProviderID = "azure:///subscriptions/sub/resourceGroups/MC_RG/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0"
+	running := &cloudprovider.InstanceStatus{State: cloudprovider.InstanceRunning}
+	instances := map[string][]cloudprovider.Instance{
+		"vmss": {
+			{Id: "azure:///subscriptions/sub/resourcegroups/mc_rg/providers/microsoft.compute/virtualMachineScaleSets/vmss/virtualMachines/0", Status: running},
+			{Id: "azure:///subscriptions/sub/resourcegroups/mc_rg/providers/microsoft.compute/virtualMachineScaleSets/vmss/virtualMachines/1", Status: running},
+		},
+	}
+
+	notRegistered := getNotRegisteredNodes([]*apiv1.Node{node}, instances, time.Now())
+	assert.Equal(t, 1, len(notRegistered))
+	assert.Equal(t, instances["vmss"][1].Id, notRegistered[0].Node.Spec.ProviderID)
+}
+
 func TestCloudProviderDeletedNodes(t *testing.T) {
 	now := time.Now()
 	ng1_1 := BuildTestNode("ng1-1", 1000, 1000)
@@ -1046,6 +1067,50 @@ func TestScaleUpFailures(t *testing.T) {
 	assert.Empty(t, clusterstate.GetScaleUpFailures())
 }
 
+// errorClassRecordingBackoff records the error classes node groups are backed off with.
+type errorClassRecordingBackoff struct {
+	backoff.Backoff
+	errorClasses map[string]cloudprovider.InstanceErrorClass
+	errorCodes   map[string]string
+}
+
+func (b *errorClassRecordingBackoff) Backoff(nodeGroup cloudprovider.NodeGroup, nodeInfo *schedulerframework.NodeInfo, errorClass cloudprovider.InstanceErrorClass, errorCode string, currentTime time.Time) time.Time {
+	b.errorClasses[nodeGroup.Id()] = errorClass
+	b.errorCodes[nodeGroup.Id()] = errorCode
+	return b.Backoff.Backoff(nodeGroup, nodeInfo, errorClass, errorCode, currentTime)
+}
+
+func TestRegisterFailedScaleUpError(t *testing.T) {
+	now := time.Now()
+
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 0, 10, 0)
+	provider.AddNodeGroup("ng2", 0, 10, 0)
+
+	fakeClient := &fake.Clientset{}
+	fakeLogRecorder, _ := utils.NewStatusMapRecorder(fakeClient, "kube-system", kube_record.NewFakeRecorder(5), false, "my-cool-configmap")
+	recordingBackoff := &errorClassRecordingBackoff{
+		Backoff:      newBackoff(),
+		errorClasses: make(map[string]cloudprovider.InstanceErrorClass),
+		errorCodes:   make(map[string]string),
+	}
+	clusterstate := NewClusterStateRegistry(provider, ClusterStateRegistryConfig{}, fakeLogRecorder, recordingBackoff, nodegroupconfig.NewDefaultNodeGroupConfigProcessor(config.NodeGroupAutoscalingOptions{MaxNodeProvisionTime: 15 * time.Minute}))
+
+	outOfResources := &cloudprovider.ScaleUpError{InstanceErrorInfo: cloudprovider.InstanceErrorInfo{
+		ErrorClass:   cloudprovider.OutOfResourcesErrorClass,
+		ErrorCode:    "RESERVATION_EXHAUSTED",
+		ErrorMessage: "reservation exhausted",
+	}}
+	clusterstate.RegisterFailedScaleUpError(provider.GetNodeGroup("ng1"), metrics.CloudProviderError, fmt.Errorf("failed: %w", outOfResources), "", "", now)
+	clusterstate.RegisterFailedScaleUpError(provider.GetNodeGroup("ng2"), metrics.CloudProviderError, fmt.Errorf("failed"), "", "", now)
+
+	assert.Equal(t, cloudprovider.OutOfResourcesErrorClass, recordingBackoff.errorClasses["ng1"])
+	assert.Equal(t, "RESERVATION_EXHAUSTED", recordingBackoff.errorCodes["ng1"])
+	assert.Equal(t, cloudprovider.OtherErrorClass, recordingBackoff.errorClasses["ng2"])
+	assert.Equal(t, string(metrics.CloudProviderError), recordingBackoff.errorCodes["ng2"])
+	assert.Len(t, clusterstate.GetScaleUpFailures(), 2)
+}
+
 func newBackoff() backoff.Backoff {
 	return backoff.NewIdBasedExponentialBackoff(5*time.Minute, /*InitialNodeGroupBackoffDuration*/
 		30*time.Minute /*MaxNodeGroupBackoffDuration*/, 3*time.Hour /*NodeGroupBackoffResetTimeout*/)
@@ -0,0 +1,189 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterstate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	kube_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	kube_client "k8s.io/client-go/kubernetes"
+	v1lister "k8s.io/client-go/listers/core/v1"
+
+	klog "k8s.io/klog/v2"
+)
+
+const (
+	// scaleUpHintsKey is the ConfigMap data key under which scale-up hints are stored.
+	scaleUpHintsKey = "scaleUpHints"
+)
+
+// ScaleUpHint describes an in-flight scale-up. Hints are persisted so that a restarted
+// autoscaler accounts for nodes that are still coming up, instead of requesting them again
+// before the cloud provider reports the increased target size.
+type ScaleUpHint struct {
+	// NodeGroup is the id of the node group being scaled up.
+	NodeGroup string `json:"nodeGroup"`
+	// Increase is the number of nodes requested.
+	Increase int `json:"increase"`
+	// TargetSize is the node group target size after the scale-up.
+	TargetSize int `json:"targetSize"`
+	// Time is the time when the scale-up was requested.
+	Time time.Time `json:"time"`
+	// Deadline is the time at which the scale-up should be fulfilled.
+	Deadline time.Time `json:"deadline"`
+}
+
+// GetScaleUpHints returns hints describing the scale-ups that are currently in progress.
+func (csr *ClusterStateRegistry) GetScaleUpHints() []ScaleUpHint {
+	csr.Lock()
+	defer csr.Unlock()
+
+	hints := make([]ScaleUpHint, 0, len(csr.scaleUpRequests))
+	for id, request := range csr.scaleUpRequests {
+		hints = append(hints, ScaleUpHint{
+			NodeGroup:  id,
+			Increase:   request.Increase,
+			TargetSize: csr.acceptableRanges[id].CurrentTarget,
+			Time:       request.Time,
+			Deadline:   request.ExpectedAddTime,
+		})
+	}
+	sort.Slice(hints, func(i, j int) bool { return hints[i].NodeGroup < hints[j].NodeGroup })
+	return hints
+}
+
+// RestoreScaleUpHints registers scale-ups described by hints persisted by a previous run.
+// Until the cloud provider reports the hinted target size or the hint deadline passes,
+// the hinted target size is used, so that the missing nodes are considered upcoming.
+// Hints for unknown node groups, expired hints and hints for node groups that already
+// have a registered scale-up are ignored.
+func (csr *ClusterStateRegistry) RestoreScaleUpHints(hints []ScaleUpHint, currentTime time.Time) {
+	nodeGroups := make(map[string]cloudprovider.NodeGroup)
+	for _, nodeGroup := range csr.cloudProvider.NodeGroups() {
+		nodeGroups[nodeGroup.Id()] = nodeGroup
+	}
+
+	csr.Lock()
+	defer csr.Unlock()
+
+	for _, hint := range hints {
+		nodeGroup, found := nodeGroups[hint.NodeGroup]
+		if !found || hint.Increase <= 0 || !hint.Deadline.After(currentTime) {
+			continue
+		}
+		if _, found := csr.scaleUpRequests[hint.NodeGroup]; found {
+			continue
+		}
+		klog.V(2).Infof("Restoring scale-up of node group %s by %d to %d, expected by %v", hint.NodeGroup, hint.Increase, hint.TargetSize, hint.Deadline)
+		csr.scaleUpRequests[hint.NodeGroup] = &ScaleUpRequest{
+			NodeGroup:       nodeGroup,
+			Increase:        hint.Increase,
+			Time:            hint.Time,
+			ExpectedAddTime: hint.Deadline,
+		}
+		csr.scaleUpHints[hint.NodeGroup] = hint
+	}
+}
+
+// applyScaleUpHints raises target sizes to the ones of restored hints, dropping hints that
+// are fulfilled, expired or whose scale-up is no longer registered.
+// To be executed under a lock.
+func (csr *ClusterStateRegistry) applyScaleUpHints(targetSizes map[string]int, currentTime time.Time) {
+	for id, hint := range csr.scaleUpHints {
+		_, registered := csr.scaleUpRequests[id]
+		targetSize, found := targetSizes[id]
+		if !found {
+			// Target size unknown, e.g. the cloud provider failed to report it.
+			continue
+		}
+		if !registered || targetSize >= hint.TargetSize || !hint.Deadline.After(currentTime) {
+			delete(csr.scaleUpHints, id)
+			continue
+		}
+		targetSizes[id] = hint.TargetSize
+	}
+}
+
+// WriteScaleUpHintsConfigMap stores scale-up hints in the given ConfigMap, creating it if needed. The ConfigMap
+// is read from the lister, so that it is only written to the API server when the hints change.
+func WriteScaleUpHintsConfigMap(kubeClient kube_client.Interface, configMapLister v1lister.ConfigMapNamespaceLister, namespace, configMapName string, hints []ScaleUpHint) error {
+	data, err := json.Marshal(hints)
+	if err != nil {
+		return fmt.Errorf("failed to encode scale-up hints: %v", err)
+	}
+	maps := kubeClient.CoreV1().ConfigMaps(namespace)
+	cached, err := configMapLister.Get(configMapName)
+	if kube_errors.IsNotFound(err) {
+		configMap := &apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      configMapName,
+			},
+			Data: map[string]string{
+				scaleUpHintsKey: string(data),
+			},
+		}
+		_, err = maps.Create(context.TODO(), configMap, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to create scale-up hints configmap: %v", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to retrieve scale-up hints configmap: %v", err)
+	}
+	if cached.Data[scaleUpHintsKey] == string(data) {
+		return nil
+	}
+	configMap := cached.DeepCopy()
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
+	configMap.Data[scaleUpHintsKey] = string(data)
+	if _, err = maps.Update(context.TODO(), configMap, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update scale-up hints configmap: %v", err)
+	}
+	return nil
+}
+
+// ReadScaleUpHintsConfigMap reads scale-up hints from the given ConfigMap. A missing ConfigMap
+// yields no hints.
+func ReadScaleUpHintsConfigMap(configMapLister v1lister.ConfigMapNamespaceLister, configMapName string) ([]ScaleUpHint, error) {
+	configMap, err := configMapLister.Get(configMapName)
+	if kube_errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve scale-up hints configmap: %v", err)
+	}
+	data, found := configMap.Data[scaleUpHintsKey]
+	if !found || data == "" {
+		return nil, nil
+	}
+	var hints []ScaleUpHint
+	if err := json.Unmarshal([]byte(data), &hints); err != nil {
+		return nil, fmt.Errorf("failed to decode scale-up hints: %v", err)
+	}
+	return hints, nil
+}
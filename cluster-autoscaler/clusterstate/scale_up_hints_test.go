@@ -0,0 +1,108 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterstate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate/utils"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupconfig"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	"k8s.io/client-go/kubernetes/fake"
+	kube_record "k8s.io/client-go/tools/record"
+)
+
+func TestRestoreScaleUpHints(t *testing.T) {
+	now := time.Now()
+
+	ng1_1 := BuildTestNode("ng1-1", 1000, 1000)
+	SetNodeReadyState(ng1_1, true, now.Add(-time.Minute))
+	ng2_1 := BuildTestNode("ng2-1", 1000, 1000)
+	SetNodeReadyState(ng2_1, true, now.Add(-time.Minute))
+
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 1)
+	provider.AddNodeGroup("ng2", 1, 10, 1)
+	provider.AddNode("ng1", ng1_1)
+	provider.AddNode("ng2", ng2_1)
+
+	fakeClient := &fake.Clientset{}
+	fakeLogRecorder, _ := utils.NewStatusMapRecorder(fakeClient, "kube-system", kube_record.NewFakeRecorder(5), false, "my-cool-configmap")
+	clusterstate := NewClusterStateRegistry(provider, ClusterStateRegistryConfig{
+		MaxTotalUnreadyPercentage: 10,
+		OkTotalUnreadyCount:       1,
+	}, fakeLogRecorder, newBackoff(), nodegroupconfig.NewDefaultNodeGroupConfigProcessor(config.NodeGroupAutoscalingOptions{MaxNodeProvisionTime: time.Minute}))
+
+	clusterstate.RestoreScaleUpHints([]ScaleUpHint{
+		{NodeGroup: "ng1", Increase: 3, TargetSize: 4, Time: now.Add(-time.Minute), Deadline: now.Add(time.Minute)},
+		{NodeGroup: "ng2", Increase: 2, TargetSize: 3, Time: now.Add(-time.Hour), Deadline: now.Add(-time.Minute)},
+		{NodeGroup: "unknown", Increase: 1, TargetSize: 1, Time: now, Deadline: now.Add(time.Minute)},
+	}, now)
+	err := clusterstate.UpdateNodes([]*apiv1.Node{ng1_1, ng2_1}, nil, now)
+	assert.NoError(t, err)
+
+	upcoming, _ := clusterstate.GetUpcomingNodes()
+	assert.Equal(t, map[string]int{"ng1": 3}, upcoming)
+	assert.True(t, clusterstate.IsNodeGroupScalingUp("ng1"))
+	assert.False(t, clusterstate.IsNodeGroupScalingUp("ng2"))
+
+	hints := clusterstate.GetScaleUpHints()
+	assert.Equal(t, 1, len(hints))
+	assert.Equal(t, "ng1", hints[0].NodeGroup)
+	assert.Equal(t, 4, hints[0].TargetSize)
+
+	// Once the hint expires, the target size reported by the cloud provider is used again.
+	err = clusterstate.UpdateNodes([]*apiv1.Node{ng1_1, ng2_1}, nil, now.Add(2*time.Minute))
+	assert.NoError(t, err)
+	upcoming, _ = clusterstate.GetUpcomingNodes()
+	assert.Empty(t, upcoming)
+	assert.False(t, clusterstate.IsNodeGroupScalingUp("ng1"))
+}
+
+func TestScaleUpHintsConfigMap(t *testing.T) {
+	now := time.Now().Round(time.Second)
+	fakeClient := fake.NewSimpleClientset()
+	lister := kube_util.NewSyncedConfigMapListerForNamespace(fakeClient, "kube-system")
+	readHints := func() []ScaleUpHint {
+		hints, err := ReadScaleUpHintsConfigMap(lister, "hints")
+		assert.NoError(t, err)
+		return hints
+	}
+
+	assert.Empty(t, readHints())
+
+	written := []ScaleUpHint{{NodeGroup: "ng1", Increase: 3, TargetSize: 4, Time: now, Deadline: now.Add(time.Minute)}}
+	assert.NoError(t, WriteScaleUpHintsConfigMap(fakeClient, lister, "kube-system", "hints", written))
+	assert.Eventually(t, func() bool { return len(readHints()) == 1 }, time.Second, 10*time.Millisecond)
+	hints := readHints()
+	assert.Equal(t, "ng1", hints[0].NodeGroup)
+	assert.True(t, now.Add(time.Minute).Equal(hints[0].Deadline))
+
+	// Unchanged hints aren't written again.
+	actions := len(fakeClient.Actions())
+	assert.NoError(t, WriteScaleUpHintsConfigMap(fakeClient, lister, "kube-system", "hints", written))
+	assert.Equal(t, actions, len(fakeClient.Actions()))
+
+	assert.NoError(t, WriteScaleUpHintsConfigMap(fakeClient, lister, "kube-system", "hints", []ScaleUpHint{}))
+	assert.Eventually(t, func() bool { return len(readHints()) == 0 }, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterstate
+
+import (
+	"sort"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+
+	klog "k8s.io/klog/v2"
+)
+
+// targetSizeRefresh tracks the verification of the target size of a node group.
+type targetSizeRefresh struct {
+	// next is the time at which the target size of the node group is due to be verified.
+	next time.Time
+	// targetSize is the target size of the node group at the last verification.
+	targetSize int
+	// resized is set when the autoscaler resized the node group since the last verification.
+	resized bool
+}
+
+// refreshTargetSizes forcefully refreshes the target sizes of the node groups due to be verified
+// and returns them. Node groups are verified once per TargetSizeRefreshInterval, with node groups
+// seen for the first time spread over the interval, so that the cloud provider API isn't called
+// for all of them in the same loop.
+func (csr *ClusterStateRegistry) refreshTargetSizes(currentTime time.Time) []cloudprovider.NodeGroup {
+	if csr.config.TargetSizeRefreshInterval <= 0 {
+		return nil
+	}
+	nodeGroups := csr.cloudProvider.NodeGroups()
+	sort.Slice(nodeGroups, func(i, j int) bool { return nodeGroups[i].Id() < nodeGroups[j].Id() })
+
+	csr.Lock()
+	var due []cloudprovider.NodeGroup
+	existing := make(map[string]bool, len(nodeGroups))
+	var added []string
+	for _, nodeGroup := range nodeGroups {
+		existing[nodeGroup.Id()] = true
+		refresh, found := csr.targetSizeRefreshes[nodeGroup.Id()]
+		if !found {
+			added = append(added, nodeGroup.Id())
+			continue
+		}
+		if !refresh.next.After(currentTime) {
+			due = append(due, nodeGroup)
+		}
+	}
+	for i, id := range added {
+		offset := csr.config.TargetSizeRefreshInterval * time.Duration(i+1) / time.Duration(len(added))
+		// The target size is recorded by reconcileTargetSizes once known.
+		csr.targetSizeRefreshes[id] = &targetSizeRefresh{next: currentTime.Add(offset), targetSize: -1}
+	}
+	for id := range csr.targetSizeRefreshes {
+		if !existing[id] {
+			delete(csr.targetSizeRefreshes, id)
+		}
+	}
+	csr.Unlock()
+
+	for _, nodeGroup := range due {
+		if group, ok := nodeGroup.(cloudprovider.TargetSizeRefreshingNodeGroup); ok {
+			if err := group.RefreshTargetSize(); err != nil {
+				klog.Warningf("Failed to refresh target size of node group %s: %v", nodeGroup.Id(), err)
+			}
+		}
+	}
+	return due
+}
+
+// registerResize records that the autoscaler resized the node group, so that the change of its
+// target size isn't reported as external.
+// To be executed under a lock.
+func (csr *ClusterStateRegistry) registerResize(nodeGroupId string) {
+	if refresh, found := csr.targetSizeRefreshes[nodeGroupId]; found {
+		refresh.resized = true
+	}
+}
+
+// reconcileTargetSizes compares the target sizes of the verified node groups with the ones of their
+// previous verification. A change not caused by the autoscaler is reported with an event, and the
+// bookkeeping of the node group is reconciled with it: its instances are listed again, its incorrect
+// size is forgotten and a pending scale-up is reduced by an external decrease.
+// To be executed under a lock.
+func (csr *ClusterStateRegistry) reconcileTargetSizes(verified []cloudprovider.NodeGroup, targetSizes map[string]int, currentTime time.Time) {
+	for id, refresh := range csr.targetSizeRefreshes {
+		if targetSize, found := targetSizes[id]; found && refresh.targetSize < 0 {
+			refresh.targetSize = targetSize
+			refresh.resized = false
+		}
+	}
+	for _, nodeGroup := range verified {
+		id := nodeGroup.Id()
+		refresh, found := csr.targetSizeRefreshes[id]
+		if !found {
+			continue
+		}
+		targetSize, found := targetSizes[id]
+		if !found {
+			continue
+		}
+		if refresh.targetSize >= 0 && !refresh.resized && targetSize != refresh.targetSize {
+			delta := targetSize - refresh.targetSize
+			klog.Warningf("Node group %s was resized outside of the autoscaler, target size changed from %d to %d", id, refresh.targetSize, targetSize)
+			csr.logRecorder.Eventf(apiv1.EventTypeNormal, "NodeGroupResizedExternally",
+				"Node group %s target size changed from %d to %d outside of the autoscaler", id, refresh.targetSize, targetSize)
+			csr.cloudProviderNodeInstancesCache.InvalidateCacheEntry(nodeGroup)
+			delete(csr.incorrectNodeGroupSizes, id)
+			if delta < 0 {
+				csr.registerOrUpdateScaleUpNoLock(nodeGroup, delta, currentTime)
+			}
+		}
+		refresh.targetSize = targetSize
+		refresh.resized = false
+		refresh.next = currentTime.Add(csr.config.TargetSizeRefreshInterval)
+	}
+}
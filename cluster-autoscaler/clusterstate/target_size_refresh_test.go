@@ -0,0 +1,114 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterstate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate/utils"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupconfig"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	"k8s.io/client-go/kubernetes/fake"
+	kube_record "k8s.io/client-go/tools/record"
+)
+
+func TestTargetSizeRefresh(t *testing.T) {
+	now := time.Now()
+
+	ng1_1 := BuildTestNode("ng1-1", 1000, 1000)
+	SetNodeReadyState(ng1_1, true, now.Add(-time.Minute))
+	ng2_1 := BuildTestNode("ng2-1", 1000, 1000)
+	SetNodeReadyState(ng2_1, true, now.Add(-time.Minute))
+	nodes := []*apiv1.Node{ng1_1, ng2_1}
+
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 1)
+	provider.AddNodeGroup("ng2", 1, 10, 1)
+	provider.AddNode("ng1", ng1_1)
+	provider.AddNode("ng2", ng2_1)
+	ng1 := provider.GetNodeGroup("ng1").(*testprovider.TestNodeGroup)
+	ng2 := provider.GetNodeGroup("ng2").(*testprovider.TestNodeGroup)
+
+	fakeRecorder := kube_record.NewFakeRecorder(5)
+	fakeLogRecorder, err := utils.NewStatusMapRecorder(fake.NewSimpleClientset(), "kube-system", fakeRecorder, true, "my-cool-configmap")
+	assert.NoError(t, err)
+	clusterstate := NewClusterStateRegistry(provider, ClusterStateRegistryConfig{
+		MaxTotalUnreadyPercentage: 10,
+		OkTotalUnreadyCount:       1,
+		TargetSizeRefreshInterval: 10 * time.Minute,
+	}, fakeLogRecorder, newBackoff(), nodegroupconfig.NewDefaultNodeGroupConfigProcessor(config.NodeGroupAutoscalingOptions{MaxNodeProvisionTime: time.Hour}))
+
+	// Node groups seen for the first time are spread over the refresh interval.
+	assert.NoError(t, clusterstate.UpdateNodes(nodes, nil, now))
+	assert.Equal(t, 0, ng1.TargetSizeRefreshes())
+	assert.Equal(t, 0, ng2.TargetSizeRefreshes())
+
+	// An external resize is detected once the node group is verified.
+	ng1.SetTargetSize(3)
+	assert.NoError(t, clusterstate.UpdateNodes(nodes, nil, now.Add(time.Minute)))
+	assert.Equal(t, 0, ng1.TargetSizeRefreshes())
+	assert.Empty(t, fakeRecorder.Events)
+
+	assert.NoError(t, clusterstate.UpdateNodes(nodes, nil, now.Add(5*time.Minute)))
+	assert.Equal(t, 1, ng1.TargetSizeRefreshes())
+	assert.Equal(t, 0, ng2.TargetSizeRefreshes())
+	assert.Equal(t, 1, len(fakeRecorder.Events))
+	event := <-fakeRecorder.Events
+	assert.Contains(t, event, "NodeGroupResizedExternally")
+	assert.Contains(t, event, "ng1")
+
+	// A resize made by the autoscaler isn't reported.
+	ng2.SetTargetSize(3)
+	clusterstate.RegisterOrUpdateScaleUp(ng2, 2, now.Add(6*time.Minute))
+	assert.NoError(t, clusterstate.UpdateNodes(nodes, nil, now.Add(10*time.Minute)))
+	assert.Equal(t, 1, ng1.TargetSizeRefreshes())
+	assert.Equal(t, 1, ng2.TargetSizeRefreshes())
+	assert.Empty(t, fakeRecorder.Events)
+
+	// An external decrease reduces the pending scale-up.
+	ng2.SetTargetSize(2)
+	assert.NoError(t, clusterstate.UpdateNodes(nodes, nil, now.Add(20*time.Minute)))
+	assert.Equal(t, 2, ng1.TargetSizeRefreshes())
+	assert.Equal(t, 2, ng2.TargetSizeRefreshes())
+	assert.Equal(t, 1, len(fakeRecorder.Events))
+	upcoming, _ := clusterstate.GetUpcomingNodes()
+	assert.Equal(t, 1, upcoming["ng2"])
+}
+
+func TestTargetSizeRefreshDisabled(t *testing.T) {
+	now := time.Now()
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 1)
+	ng1 := provider.GetNodeGroup("ng1").(*testprovider.TestNodeGroup)
+
+	fakeClient := &fake.Clientset{}
+	fakeLogRecorder, _ := utils.NewStatusMapRecorder(fakeClient, "kube-system", kube_record.NewFakeRecorder(5), false, "my-cool-configmap")
+	clusterstate := NewClusterStateRegistry(provider, ClusterStateRegistryConfig{
+		MaxTotalUnreadyPercentage: 10,
+		OkTotalUnreadyCount:       1,
+	}, fakeLogRecorder, newBackoff(), nodegroupconfig.NewDefaultNodeGroupConfigProcessor(config.NodeGroupAutoscalingOptions{MaxNodeProvisionTime: time.Minute}))
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, clusterstate.UpdateNodes(nil, nil, now.Add(time.Duration(i)*time.Hour)))
+	}
+	assert.Equal(t, 0, ng1.TargetSizeRefreshes())
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterstate
+
+import (
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+
+	klog "k8s.io/klog/v2"
+)
+
+const (
+	// provisioningLatencySmoothing is the weight given to the latest successful scale-up
+	// when updating the learned provisioning latency of a node group.
+	provisioningLatencySmoothing = 0.25
+)
+
+// recordProvisioningLatency folds the duration of a successful scale-up into the learned
+// provisioning latency of the node group.
+// To be executed under a lock.
+func (csr *ClusterStateRegistry) recordProvisioningLatency(nodeGroupId string, latency time.Duration) {
+	learned, found := csr.provisioningLatencies[nodeGroupId]
+	if !found {
+		csr.provisioningLatencies[nodeGroupId] = latency
+		return
+	}
+	csr.provisioningLatencies[nodeGroupId] = learned + time.Duration(provisioningLatencySmoothing*float64(latency-learned))
+}
+
+// provisioningLatency returns the learned provisioning latency of the node group. Until a
+// scale-up of the node group succeeds, its max node provision time is used instead.
+// To be executed under a lock.
+func (csr *ClusterStateRegistry) provisioningLatency(nodeGroup cloudprovider.NodeGroup) time.Duration {
+	if latency, found := csr.provisioningLatencies[nodeGroup.Id()]; found {
+		return latency
+	}
+	maxNodeProvisionTime, err := csr.MaxNodeProvisionTime(nodeGroup)
+	if err != nil {
+		klog.Warningf("Failed to get maxNodeProvisionTime for node group %s: %v", nodeGroup.Id(), err)
+		return 0
+	}
+	return maxNodeProvisionTime
+}
+
+// EstimateTimeToSchedule estimates how long it takes until pods can be scheduled on new nodes
+// of the given node group. For a scale-up in progress, this is the time left until the nodes
+// are expected to be ready based on the learned provisioning latency. Otherwise, it is the
+// learned provisioning latency, delayed by the remaining backoff of the node group.
+func (csr *ClusterStateRegistry) EstimateTimeToSchedule(nodeGroup cloudprovider.NodeGroup, currentTime time.Time) time.Duration {
+	csr.Lock()
+	defer csr.Unlock()
+
+	latency := csr.provisioningLatency(nodeGroup)
+	if request, found := csr.scaleUpRequests[nodeGroup.Id()]; found {
+		expected := request.Time.Add(latency)
+		if expected.Before(currentTime) || expected.After(request.ExpectedAddTime) {
+			// The scale-up takes longer than usual, it can still succeed until it times out.
+			expected = request.ExpectedAddTime
+		}
+		if expected.Before(currentTime) {
+			return 0
+		}
+		return expected.Sub(currentTime)
+	}
+
+	start := currentTime
+	if csr.backoff.IsBackedOff(nodeGroup, csr.nodeInfosForGroups[nodeGroup.Id()], currentTime) {
+		if until, found := csr.backoffUntil[nodeGroup.Id()]; found && until.After(currentTime) {
+			start = until
+		}
+	}
+	return start.Sub(currentTime) + latency
+}
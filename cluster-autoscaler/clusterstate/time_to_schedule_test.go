@@ -0,0 +1,97 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterstate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate/utils"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/metrics"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupconfig"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	"k8s.io/client-go/kubernetes/fake"
+	kube_record "k8s.io/client-go/tools/record"
+)
+
+func TestEstimateTimeToSchedule(t *testing.T) {
+	now := time.Now()
+
+	ng1_1 := BuildTestNode("ng1-1", 1000, 1000)
+	SetNodeReadyState(ng1_1, true, now.Add(-time.Minute))
+	ng2_1 := BuildTestNode("ng2-1", 1000, 1000)
+	SetNodeReadyState(ng2_1, true, now.Add(-time.Minute))
+
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 2)
+	provider.AddNodeGroup("ng2", 1, 10, 1)
+	provider.AddNode("ng1", ng1_1)
+	provider.AddNode("ng2", ng2_1)
+	ng1 := provider.GetNodeGroup("ng1")
+	ng2 := provider.GetNodeGroup("ng2")
+
+	fakeClient := &fake.Clientset{}
+	fakeLogRecorder, _ := utils.NewStatusMapRecorder(fakeClient, "kube-system", kube_record.NewFakeRecorder(5), false, "my-cool-configmap")
+	clusterstate := NewClusterStateRegistry(provider, ClusterStateRegistryConfig{
+		MaxTotalUnreadyPercentage: 10,
+		OkTotalUnreadyCount:       1,
+	}, fakeLogRecorder, newBackoff(), nodegroupconfig.NewDefaultNodeGroupConfigProcessor(config.NodeGroupAutoscalingOptions{MaxNodeProvisionTime: 15 * time.Minute}))
+
+	// Without a learned latency, the max node provision time is expected.
+	assert.Equal(t, 15*time.Minute, clusterstate.EstimateTimeToSchedule(ng1, now))
+
+	clusterstate.RegisterOrUpdateScaleUp(ng1, 1, now)
+	err := clusterstate.UpdateNodes([]*apiv1.Node{ng1_1, ng2_1}, nil, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 13*time.Minute, clusterstate.EstimateTimeToSchedule(ng1, now.Add(2*time.Minute)))
+
+	// The scale-up finishes after 5 minutes, which becomes the learned latency.
+	ng1_2 := BuildTestNode("ng1-2", 1000, 1000)
+	SetNodeReadyState(ng1_2, true, now.Add(4*time.Minute))
+	provider.AddNode("ng1", ng1_2)
+	err = clusterstate.UpdateNodes([]*apiv1.Node{ng1_1, ng1_2, ng2_1}, nil, now.Add(5*time.Minute))
+	assert.NoError(t, err)
+	assert.False(t, clusterstate.IsNodeGroupScalingUp("ng1"))
+	assert.Equal(t, 5*time.Minute, clusterstate.EstimateTimeToSchedule(ng1, now.Add(5*time.Minute)))
+
+	// A new scale-up is expected to take the learned latency, until it takes longer than that.
+	start := now.Add(10 * time.Minute)
+	clusterstate.RegisterOrUpdateScaleUp(ng1, 1, start)
+	assert.Equal(t, 4*time.Minute, clusterstate.EstimateTimeToSchedule(ng1, start.Add(time.Minute)))
+	assert.Equal(t, 8*time.Minute, clusterstate.EstimateTimeToSchedule(ng1, start.Add(7*time.Minute)))
+	assert.Equal(t, time.Duration(0), clusterstate.EstimateTimeToSchedule(ng1, start.Add(20*time.Minute)))
+
+	// A backed off node group is expected to provide nodes once the backoff is over.
+	clusterstate.RegisterFailedScaleUp(ng2, metrics.Timeout, "", "", now)
+	assert.Equal(t, 20*time.Minute, clusterstate.EstimateTimeToSchedule(ng2, now))
+	assert.Equal(t, 17*time.Minute, clusterstate.EstimateTimeToSchedule(ng2, now.Add(3*time.Minute)))
+	assert.Equal(t, 15*time.Minute, clusterstate.EstimateTimeToSchedule(ng2, now.Add(6*time.Minute)))
+}
+
+func TestRecordProvisioningLatency(t *testing.T) {
+	clusterstate := &ClusterStateRegistry{provisioningLatencies: make(map[string]time.Duration)}
+	clusterstate.recordProvisioningLatency("ng1", 5*time.Minute)
+	assert.Equal(t, 5*time.Minute, clusterstate.provisioningLatencies["ng1"])
+	clusterstate.recordProvisioningLatency("ng1", 10*time.Minute)
+	assert.Equal(t, 6*time.Minute+15*time.Second, clusterstate.provisioningLatencies["ng1"])
+	clusterstate.recordProvisioningLatency("ng1", 10*time.Second)
+	assert.Equal(t, 4*time.Minute+43*time.Second+750*time.Millisecond, clusterstate.provisioningLatencies["ng1"])
+}
@@ -52,6 +52,9 @@ type NodeGroupAutoscalingOptions struct {
 	ZeroOrMaxNodeScaling bool
 	// IgnoreDaemonSetsUtilization sets if daemonsets utilization should be considered during node scale-down
 	IgnoreDaemonSetsUtilization bool
+	// RecreationCost is the relative cost of recreating a node of the node group, e.g. because of a long bootstrap
+	// or large image pulls. All else being equal, nodes of node groups with a lower cost are scaled down first.
+	RecreationCost float64
 }
 
 // GCEOptions contain autoscaling options specific to GCE cloud provider.
@@ -196,6 +199,11 @@ type AutoscalingOptions struct {
 	Regional bool
 	// Pods newer than this will not be considered as unschedulable for scale-up.
 	NewPodScaleUpDelay time.Duration
+	// NewPodScaleUpDelayPerPriorityClass overrides NewPodScaleUpDelay for pods of the given priority classes.
+	NewPodScaleUpDelayPerPriorityClass map[string]time.Duration
+	// AdaptiveNewPodScaleUpDelay shortens the new pod scale-up delays when the number of unschedulable
+	// pods grows between loops, in proportion to the growth.
+	AdaptiveNewPodScaleUpDelay bool
 	// MaxBulkSoftTaint sets the maximum number of nodes that can be (un)tainted PreferNoSchedule during single scaling down run.
 	// Value of 0 turns turn off such tainting.
 	MaxBulkSoftTaintCount int
@@ -219,6 +227,17 @@ type AutoscalingOptions struct {
 	BalancingLabels []string
 	// AWSUseStaticInstanceList tells if AWS cloud provider use static instance type list or dynamically fetch from remote APIs.
 	AWSUseStaticInstanceList bool
+	// AWSEventsQueueURL is the URL of an SQS queue receiving ASG lifecycle and EC2 instance state change events,
+	// used by the AWS cloud provider to refresh its ASG cache on changes rather than periodically.
+	AWSEventsQueueURL string
+	// AWSPriceListPricing enables the AWS pricing model, pricing nodes from the on-demand prices of the Price List API.
+	AWSPriceListPricing bool
+	// AWSCommitmentAwarePricing tells the AWS pricing model to discount the prices of nodes by the unused commitment
+	// of Savings Plans and Reserved Instances, as reported by the Cost Explorer API.
+	AWSCommitmentAwarePricing bool
+	// AWSEKSNodegroupAPI tells the AWS cloud provider to scale the ASGs of EKS managed node groups through the EKS
+	// UpdateNodegroupConfig API rather than the Auto Scaling API.
+	AWSEKSNodegroupAPI bool
 	// GCEOptions contain autoscaling options specific to GCE cloud provider.
 	GCEOptions GCEOptions
 	// Path to kube configuration if available
@@ -232,6 +251,9 @@ type AutoscalingOptions struct {
 	ClusterAPICloudConfigAuthoritative bool
 	// Enable or disable cordon nodes functionality before terminating the node during downscale process
 	CordonNodeBeforeTerminate bool
+	// ToBeDeletedTaintOwner is the identity recorded on ToBeDeleted taints placed by this autoscaler. Only stale
+	// taints with a matching (or no) owner are cleaned up on startup. Empty value disables ownership tracking.
+	ToBeDeletedTaintOwner string
 	// DaemonSetEvictionForEmptyNodes is whether CA will gracefully terminate DaemonSet pods from empty nodes.
 	DaemonSetEvictionForEmptyNodes bool
 	// DaemonSetEvictionForOccupiedNodes is whether CA will gracefully terminate DaemonSet pods from non-empty nodes.
@@ -277,4 +299,111 @@ type AutoscalingOptions struct {
 	// dynamicNodeDeleteDelayAfterTaintEnabled is used to enable/disable dynamic adjustment of NodeDeleteDelayAfterTaint
 	// based on the latency between the CA and the api-server
 	DynamicNodeDeleteDelayAfterTaintEnabled bool
+	// NodeProblemConditions is a list of node condition types (e.g. reported by Node Problem Detector) which make
+	// a node a preferred scale-down candidate regardless of its utilization.
+	NodeProblemConditions []string
+	// MaxNodeProblemRecyclesPerHour is the maximum number of nodes with problem conditions that can be
+	// removed per hour.
+	MaxNodeProblemRecyclesPerHour int
+	// ImageArchitectureInspectionEnabled enables inspecting image manifests of unschedulable pods to restrict
+	// them to node groups of CPU architectures supported by their images.
+	ImageArchitectureInspectionEnabled bool
+	// ImageArchitectureCacheTTL is how long the resolved image architectures are cached.
+	ImageArchitectureCacheTTL time.Duration
+	// RequestlessPodDefaultsEnabled enables giving containers of unschedulable pods without CPU or memory
+	// requests the default requests of the LimitRanges of their namespace in scale-up simulations.
+	RequestlessPodDefaultsEnabled bool
+	// RequestlessPodFallbackCPU and RequestlessPodFallbackMemory are the requests given to containers without
+	// requests in namespaces without LimitRange defaults. Empty values leave such containers without requests.
+	RequestlessPodFallbackCPU    string
+	RequestlessPodFallbackMemory string
+	// OrphanedNodeGroupPolicy defines how nodes of node groups no longer returned by the cloud provider
+	// are handled: alert, adopt or drain.
+	OrphanedNodeGroupPolicy string
+	// ScaleUpHintsConfigMapName is the name of the ConfigMap in which in-flight scale-ups are persisted,
+	// so that they are accounted for after a restart. Empty disables persisting scale-up hints.
+	ScaleUpHintsConfigMapName string
+	// SurgeCapacityEnabled enables surge capacity requests: ConfigMaps in the namespace of the autoscaler
+	// requesting temporary nodes above the target size of a node group, e.g. for the duration of an upgrade.
+	SurgeCapacityEnabled bool
+	// MaxSurgeCapacityDuration is how long surge capacity requests last at most before their nodes are reclaimed.
+	MaxSurgeCapacityDuration time.Duration
+	// AlertPodPendingOnQuotaThreshold is how long a pod has to be unable to trigger a scale-up because of
+	// node group or cluster-wide limits before an alert is raised. 0 disables the alert.
+	AlertPodPendingOnQuotaThreshold time.Duration
+	// AlertNodeGroupBackoffThreshold is how long a node group has to stay in backoff before an alert is raised.
+	// 0 disables the alert.
+	AlertNodeGroupBackoffThreshold time.Duration
+	// AlertScaleDownBlockedThreshold is how long the scale-down of a node has to be blocked by the same reason
+	// before an alert is raised. 0 disables the alert.
+	AlertScaleDownBlockedThreshold time.Duration
+	// AlertWebhookURL is the URL the alerts raised in each iteration are additionally POSTed to as a batch. Empty disables the webhook.
+	AlertWebhookURL string
+	// GpuUtilizationPrometheusURL is the URL of the Prometheus server the GPU utilization of nodes is queried from,
+	// to be used instead of requested GPUs when deciding GPU node scale-down. Empty disables it.
+	GpuUtilizationPrometheusURL string
+	// GpuUtilizationQuery is the Prometheus query returning the GPU utilization of nodes, between 0 and 1.
+	GpuUtilizationQuery string
+	// GpuUtilizationNodeLabel is the label identifying the node in the results of GpuUtilizationQuery.
+	GpuUtilizationNodeLabel string
+	// CloudConfigSecret is the namespace/name of a Secret holding the cloud provider configuration, watched for
+	// changes. Empty string to use CloudConfig instead.
+	CloudConfigSecret string
+	// ClusterSnapshotType is the ClusterSnapshot implementation used for simulations: basic, delta or compact.
+	ClusterSnapshotType string
+	// TerminatingPodThreshold is the time after their deletion timestamp after which terminating pods are ignored
+	// in scale-up fit checks and scale-down utilization. 0 disables it: terminating pods then keep occupying capacity
+	// in simulations and stop counting toward utilization once their termination grace period is over.
+	TerminatingPodThreshold time.Duration
+	// CapacityBrokerURL is the url of the capacity broker consulted by the capacity-broker expander. Empty to always
+	// apply CapacityBrokerFallback.
+	CapacityBrokerURL string
+	// CapacityBrokerTimeout is the timeout of capacity broker calls
+	CapacityBrokerTimeout time.Duration
+	// CapacityBrokerOnPremNodeGroups is a regular expression matching the ids of on-prem node groups
+	CapacityBrokerOnPremNodeGroups string
+	// CapacityBrokerFallback is the policy applied when the capacity broker can't be consulted: on-prem-first,
+	// cloud-first or all.
+	CapacityBrokerFallback string
+	// CapacityBrokerCacheTTL is how long a capacity broker response is reused for the same expansion options
+	CapacityBrokerCacheTTL time.Duration
+	// ExternalDeleteWebhookURL is the url of the webhook called to delete drained nodes that don't belong to any
+	// cloud provider node group and match ExternalDeleteNodeSelector. Empty to never scale such nodes down.
+	ExternalDeleteWebhookURL string
+	// ExternalDeleteNodeSelector is the label selector of the nodes without a cloud provider node group that are
+	// deleted through the external delete webhook. Empty to never scale such nodes down.
+	ExternalDeleteNodeSelector string
+	// ExternalDeleteWebhookTimeout is the timeout of external delete webhook calls
+	ExternalDeleteWebhookTimeout time.Duration
+	// ScaleDownMinReadyNodesPerDomain maps a label key, e.g. topology.kubernetes.io/zone, to the minimum number of Ready
+	// nodes scale-down must leave in each domain of that key
+	ScaleDownMinReadyNodesPerDomain map[string]int
+	// ScaleDownBillingAware delays the deletion of unneeded nodes of node groups billed per started period, e.g. per
+	// hour, until the end of their current billing period is within ScaleDownBillingWindow.
+	ScaleDownBillingAware bool
+	// ScaleDownBillingWindow is how long before the end of their billing period nodes may be deleted when
+	// ScaleDownBillingAware is set. It should cover the time it takes to drain and delete a node.
+	ScaleDownBillingWindow time.Duration
+	// ShadowExpanderNames sets a chain of node group expanders whose decisions are computed and reported along
+	// with the ones of ExpanderNames, without being acted upon. Empty to disable.
+	ShadowExpanderNames string
+	// AnnotateUnremovableNodes annotates nodes that can't be scaled down with the reason why.
+	AnnotateUnremovableNodes bool
+	// UnremovableNodeAnnotationInterval is the minimum time between two updates of the annotation of a node.
+	UnremovableNodeAnnotationInterval time.Duration
+	// NakedPodPolicies maps a namespace to the policy applied by scale-down to its pods without a controller:
+	// block, evict or ignore. The "*" key sets the policy of the other namespaces, which defaults to block.
+	NakedPodPolicies map[string]string
+	// MaxGracefulTerminationSecPerNamespace overrides MaxGracefulTerminationSec for the pods of a namespace.
+	MaxGracefulTerminationSecPerNamespace map[string]int
+	// MaxGracefulTerminationSecPerPriorityClass overrides MaxGracefulTerminationSec for the pods of a priority
+	// class. Namespace overrides take precedence.
+	MaxGracefulTerminationSecPerPriorityClass map[string]int
+	// NodeGroupTargetSizeRefreshInterval is how often the target size of each node group is forcefully
+	// refreshed from the cloud provider and verified, to detect resizes made outside of the autoscaler.
+	// Node groups are spread over the interval. Zero disables the verification.
+	NodeGroupTargetSizeRefreshInterval time.Duration
+	// EmitPerNodeGroupMetrics tells whether per node group metrics are emitted, so that nodes are only priced
+	// for the estimated cost of scale-ups and scale-downs when they are.
+	EmitPerNodeGroupMetrics bool
 }
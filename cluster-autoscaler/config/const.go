@@ -40,6 +40,8 @@ const (
 	DefaultMaxNodeProvisionTimeKey = "maxnodeprovisiontime"
 	// DefaultIgnoreDaemonSetsUtilizationKey identifies IgnoreDaemonSetsUtilization autoscaling option
 	DefaultIgnoreDaemonSetsUtilizationKey = "ignoredaemonsetsutilization"
+	// DefaultRecreationCostKey identifies RecreationCost autoscaling option
+	DefaultRecreationCostKey = "recreationcost"
 	// DefaultScaleDownUnneededTime identifies ScaleDownUnneededTime autoscaling option
 	DefaultScaleDownUnneededTime = 10 * time.Minute
 	// DefaultScaleDownUnreadyTime identifies ScaleDownUnreadyTime autoscaling option
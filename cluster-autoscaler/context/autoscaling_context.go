@@ -22,6 +22,7 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/clusterstate/utils"
 	"k8s.io/autoscaler/cluster-autoscaler/config"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/nodeproblem"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/pdb"
 	"k8s.io/autoscaler/cluster-autoscaler/debuggingsnapshot"
 	"k8s.io/autoscaler/cluster-autoscaler/estimator"
@@ -29,9 +30,12 @@ import (
 	processor_callbacks "k8s.io/autoscaler/cluster-autoscaler/processors/callbacks"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/predicatechecker"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/gpu"
 	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/taints"
 	"k8s.io/client-go/informers"
 	kube_client "k8s.io/client-go/kubernetes"
+	v1lister "k8s.io/client-go/listers/core/v1"
 	kube_record "k8s.io/client-go/tools/record"
 	klog "k8s.io/klog/v2"
 )
@@ -64,6 +68,14 @@ type AutoscalingContext struct {
 	RemainingPdbTracker pdb.RemainingPdbTracker
 	// ClusterStateRegistry tracks the health of the node groups and pending scale-ups and scale-downs
 	ClusterStateRegistry *clusterstate.ClusterStateRegistry
+	// TaintOwner identifies this autoscaler instance on the ToBeDeleted taints it places.
+	TaintOwner taints.TaintOwner
+	// NodeProblemTracker identifies nodes with problem conditions that should be preferably recycled.
+	// Nil if no problem conditions are configured.
+	NodeProblemTracker *nodeproblem.Tracker
+	// GpuUtilizationSource provides the measured GPU utilization of nodes, used instead of requested GPUs
+	// to decide GPU node scale-down. Nil if no source is configured.
+	GpuUtilizationSource gpu.UtilizationSource
 }
 
 // AutoscalingKubeClients contains all Kubernetes API clients,
@@ -77,6 +89,12 @@ type AutoscalingKubeClients struct {
 	Recorder kube_record.EventRecorder
 	// LogRecorder can be used to collect log messages to expose via Events on some central object.
 	LogRecorder *utils.LogEventRecorder
+	// PdbChangeTracker records the namespaces in which PDBs were relaxed, to re-check the nodes they
+	// blocked from being scaled down early. Nil if PDBs aren't watched.
+	PdbChangeTracker *pdb.ChangeTracker
+	// ConfigMapLister lists the ConfigMaps of the namespace of the autoscaler from an informer. Nil unless
+	// a feature reading ConfigMaps every loop is enabled.
+	ConfigMapLister v1lister.ConfigMapNamespaceLister
 }
 
 // NewResourceLimiterFromAutoscalingOptions creates new instance of cloudprovider.ResourceLimiter
@@ -124,9 +142,20 @@ func NewAutoscalingContext(
 		DebuggingSnapshotter:   debuggingSnapshotter,
 		RemainingPdbTracker:    remainingPdbTracker,
 		ClusterStateRegistry:   clusterStateRegistry,
+		TaintOwner:             taints.NewTaintOwner(options.ToBeDeletedTaintOwner),
+		NodeProblemTracker:     nodeproblem.NewTracker(options.NodeProblemConditions, options.MaxNodeProblemRecyclesPerHour),
+		GpuUtilizationSource:   newGpuUtilizationSource(options),
 	}
 }
 
+// newGpuUtilizationSource returns the source of measured GPU utilization configured in options, or nil.
+func newGpuUtilizationSource(options config.AutoscalingOptions) gpu.UtilizationSource {
+	if options.GpuUtilizationPrometheusURL == "" {
+		return nil
+	}
+	return gpu.NewPrometheusUtilizationSource(options.GpuUtilizationPrometheusURL, options.GpuUtilizationQuery, options.GpuUtilizationNodeLabel, gpu.DefaultUtilizationQueryTimeout)
+}
+
 // NewAutoscalingKubeClients builds AutoscalingKubeClients out of basic client.
 func NewAutoscalingKubeClients(opts config.AutoscalingOptions, kubeClient, eventsKubeClient kube_client.Interface, informerFactory informers.SharedInformerFactory) *AutoscalingKubeClients {
 	listerRegistry := kube_util.NewListerRegistryWithDefaultListers(informerFactory)
@@ -139,10 +168,29 @@ func NewAutoscalingKubeClients(opts config.AutoscalingOptions, kubeClient, event
 		logRecorder, _ = utils.NewStatusMapRecorder(eventsKubeClient, opts.ConfigNamespace, kubeEventRecorder, false, opts.StatusConfigMapName)
 	}
 
+	pdbChangeTracker, err := pdb.NewChangeTracker(informerFactory.Policy().V1().PodDisruptionBudgets().Informer())
+	if err != nil {
+		klog.Errorf("Failed to watch PDB changes, nodes blocked by PDBs will only be re-checked periodically: %v", err)
+	}
+
+	var configMapLister v1lister.ConfigMapNamespaceLister
+	if kubeClient != nil && watchesConfigMaps(opts) {
+		configMapLister = kube_util.NewSyncedConfigMapListerForNamespace(kubeClient, opts.ConfigNamespace)
+	}
+
 	return &AutoscalingKubeClients{
-		ListerRegistry: listerRegistry,
-		ClientSet:      kubeClient,
-		Recorder:       kubeEventRecorder,
-		LogRecorder:    logRecorder,
+		ListerRegistry:   listerRegistry,
+		ClientSet:        kubeClient,
+		Recorder:         kubeEventRecorder,
+		LogRecorder:      logRecorder,
+		PdbChangeTracker: pdbChangeTracker,
+		ConfigMapLister:  configMapLister,
 	}
 }
+
+// watchesConfigMaps returns true if a feature reading the ConfigMaps of the namespace of the autoscaler
+// every loop is enabled, so that they are read from an informer rather than from the API server.
+// The status config map is read every loop for its paused annotation.
+func watchesConfigMaps(opts config.AutoscalingOptions) bool {
+	return opts.SurgeCapacityEnabled || opts.WriteStatusConfigMap || opts.ScaleUpHintsConfigMapName != ""
+}
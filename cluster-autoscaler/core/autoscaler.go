@@ -17,19 +17,24 @@ limitations under the License.
 package core
 
 import (
+	"fmt"
 	"strings"
 	"time"
 
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	cloudBuilder "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/builder"
 	"k8s.io/autoscaler/cluster-autoscaler/config"
 	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/externaldelete"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/pdb"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaleup"
 	"k8s.io/autoscaler/cluster-autoscaler/debuggingsnapshot"
 	"k8s.io/autoscaler/cluster-autoscaler/estimator"
 	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	"k8s.io/autoscaler/cluster-autoscaler/expander/broker"
 	"k8s.io/autoscaler/cluster-autoscaler/expander/factory"
+	"k8s.io/autoscaler/cluster-autoscaler/metrics"
 	ca_processors "k8s.io/autoscaler/cluster-autoscaler/processors"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules"
@@ -60,6 +65,7 @@ type AutoscalerOptions struct {
 	ScaleUpOrchestrator    scaleup.Orchestrator
 	DeleteOptions          options.NodeDeleteOptions
 	DrainabilityRules      rules.Rules
+	ReadinessCheck         *metrics.ReadinessCheck
 }
 
 // Autoscaler is the main component of CA which scales up/down node groups according to its configuration
@@ -79,7 +85,7 @@ func NewAutoscaler(opts AutoscalerOptions) (Autoscaler, errors.AutoscalerError)
 	if err != nil {
 		return nil, errors.ToAutoscalerError(errors.InternalError, err)
 	}
-	return NewStaticAutoscaler(
+	autoscaler := NewStaticAutoscaler(
 		opts.AutoscalingOptions,
 		opts.PredicateChecker,
 		opts.ClusterSnapshot,
@@ -94,7 +100,9 @@ func NewAutoscaler(opts AutoscalerOptions) (Autoscaler, errors.AutoscalerError)
 		opts.ScaleUpOrchestrator,
 		opts.DeleteOptions,
 		opts.DrainabilityRules,
-	), nil
+	)
+	autoscaler.readinessCheck = opts.ReadinessCheck
+	return autoscaler, nil
 }
 
 // Initialize default options if not provided.
@@ -113,14 +121,36 @@ func initializeDefaultOptions(opts *AutoscalerOptions) error {
 	}
 	if opts.CloudProvider == nil {
 		opts.CloudProvider = cloudBuilder.NewCloudProvider(opts.AutoscalingOptions)
+		if opts.ExternalDeleteWebhookURL != "" && opts.ExternalDeleteNodeSelector != "" && opts.CloudProvider != nil {
+			selector, err := labels.Parse(opts.ExternalDeleteNodeSelector)
+			if err != nil {
+				return fmt.Errorf("invalid external delete node selector %q: %v", opts.ExternalDeleteNodeSelector, err)
+			}
+			opts.CloudProvider = externaldelete.NewCloudProvider(opts.CloudProvider, opts.AutoscalingKubeClients.AllNodeLister(), selector, opts.ExternalDeleteWebhookURL, opts.ExternalDeleteWebhookTimeout)
+		}
 	}
 	if opts.ExpanderStrategy == nil {
 		expanderFactory := factory.NewFactory()
-		expanderFactory.RegisterDefaultExpanders(opts.CloudProvider, opts.AutoscalingKubeClients, opts.KubeClient, opts.ConfigNamespace, opts.GRPCExpanderCert, opts.GRPCExpanderURL)
+		expanderFactory.RegisterDefaultExpandersWithOptions(opts.CloudProvider, opts.AutoscalingKubeClients, opts.KubeClient, opts.ConfigNamespace, opts.GRPCExpanderCert, opts.GRPCExpanderURL, factory.DefaultExpanderOptions{
+			CapacityBroker: broker.Config{
+				URL:              opts.CapacityBrokerURL,
+				Timeout:          opts.CapacityBrokerTimeout,
+				OnPremNodeGroups: opts.CapacityBrokerOnPremNodeGroups,
+				Fallback:         opts.CapacityBrokerFallback,
+				CacheTTL:         opts.CapacityBrokerCacheTTL,
+			},
+		})
 		expanderStrategy, err := expanderFactory.Build(strings.Split(opts.ExpanderNames, ","))
 		if err != nil {
 			return err
 		}
+		if opts.ShadowExpanderNames != "" {
+			shadowStrategy, err := expanderFactory.Build(strings.Split(opts.ShadowExpanderNames, ","))
+			if err != nil {
+				return err
+			}
+			expanderStrategy = factory.NewShadowStrategy(expanderStrategy, shadowStrategy)
+		}
 		opts.ExpanderStrategy = expanderStrategy
 	}
 	if opts.EstimatorBuilder == nil {
@@ -0,0 +1,237 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package orphans
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate/api"
+	klog "k8s.io/klog/v2"
+)
+
+// Policy defines how nodes of orphaned node groups are handled. A node group is orphaned when
+// the cloud provider stops returning it (e.g. it no longer matches auto-discovery) while it still has nodes.
+type Policy string
+
+const (
+	// AlertPolicy leaves nodes of orphaned node groups unmanaged and reports them with warning events.
+	AlertPolicy Policy = "alert"
+	// AdoptPolicy keeps nodes of orphaned node groups read-only: they are reported and kept in their node
+	// group, but the node group is never scaled up and its nodes are never scaled down.
+	AdoptPolicy Policy = "adopt"
+	// DrainPolicy cordons nodes of orphaned node groups and deletes them once they are empty.
+	DrainPolicy Policy = "drain"
+)
+
+// ParsePolicy validates the name of an orphaned node group policy.
+func ParsePolicy(name string) (Policy, error) {
+	switch policy := Policy(name); policy {
+	case AlertPolicy, AdoptPolicy, DrainPolicy:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown orphaned node group policy %q, expected one of: %s, %s, %s", name, AlertPolicy, AdoptPolicy, DrainPolicy)
+}
+
+// NodeGroup is a node group that is no longer returned by the cloud provider, but still has nodes.
+type NodeGroup struct {
+	// NodeGroup is the last known object representing the node group.
+	cloudprovider.NodeGroup
+	// Nodes are the nodes still belonging to the node group.
+	Nodes []*apiv1.Node
+	// OrphanedSince is the time when the node group was first noticed to be orphaned.
+	OrphanedSince time.Time
+}
+
+// IsOrphanedNodeGroup returns true if the node group is orphaned. Orphaned node groups are not listed by
+// NodeGroups, they are only returned by NodeGroupForNode of the cloud provider wrapped by a Tracker.
+func IsOrphanedNodeGroup(nodeGroup cloudprovider.NodeGroup) bool {
+	_, ok := nodeGroup.(*NodeGroup)
+	return ok
+}
+
+// Tracker remembers which node group each node belonged to in order to detect orphaned node groups.
+// The state is kept in memory only, so node groups orphaned while the autoscaler was down are not detected.
+type Tracker struct {
+	policy    Policy
+	lastKnown map[string]cloudprovider.NodeGroup
+
+	mutex         sync.Mutex
+	orphaned      map[string]*NodeGroup
+	orphanedNodes map[string]*NodeGroup
+}
+
+// NewTracker returns a Tracker applying the given policy. Empty policy defaults to AlertPolicy.
+func NewTracker(policy Policy) *Tracker {
+	if policy == "" {
+		policy = AlertPolicy
+	}
+	return &Tracker{
+		policy:        policy,
+		lastKnown:     make(map[string]cloudprovider.NodeGroup),
+		orphaned:      make(map[string]*NodeGroup),
+		orphanedNodes: make(map[string]*NodeGroup),
+	}
+}
+
+// Policy returns the policy applied to orphaned node groups.
+func (t *Tracker) Policy() Policy {
+	return t.policy
+}
+
+// Update refreshes node to node group assignment and returns orphaned node groups, sorted by id.
+// It should be called after the cloud provider was refreshed.
+func (t *Tracker) Update(cloudProvider cloudprovider.CloudProvider, nodes []*apiv1.Node, now time.Time) []*NodeGroup {
+	if wrapper, ok := cloudProvider.(*orphansCloudProvider); ok && wrapper.tracker == t {
+		// Orphaned node groups are detected with the node groups of the wrapped cloud provider.
+		cloudProvider = wrapper.CloudProvider
+	}
+	current := make(map[string]bool)
+	for _, nodeGroup := range cloudProvider.NodeGroups() {
+		current[nodeGroup.Id()] = true
+	}
+	lastKnown := make(map[string]cloudprovider.NodeGroup, len(nodes))
+	orphanedNodes := make(map[string][]*apiv1.Node)
+	orphanedGroups := make(map[string]cloudprovider.NodeGroup)
+	for _, node := range nodes {
+		previous, known := t.lastKnown[node.Name]
+		nodeGroup, err := cloudProvider.NodeGroupForNode(node)
+		if err != nil {
+			klog.Warningf("Failed to get node group for %s: %v", node.Name, err)
+			if known {
+				lastKnown[node.Name] = previous
+			}
+			continue
+		}
+		if nodeGroup != nil && !reflect.ValueOf(nodeGroup).IsNil() {
+			lastKnown[node.Name] = nodeGroup
+			continue
+		}
+		if !known || current[previous.Id()] {
+			continue
+		}
+		lastKnown[node.Name] = previous
+		orphanedNodes[previous.Id()] = append(orphanedNodes[previous.Id()], node)
+		orphanedGroups[previous.Id()] = previous
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	orphaned := make(map[string]*NodeGroup, len(orphanedNodes))
+	nodeToOrphaned := make(map[string]*NodeGroup)
+	for id, groupNodes := range orphanedNodes {
+		since := now
+		if previous, found := t.orphaned[id]; found {
+			since = previous.OrphanedSince
+		}
+		orphaned[id] = &NodeGroup{NodeGroup: orphanedGroups[id], Nodes: groupNodes, OrphanedSince: since}
+		for _, node := range groupNodes {
+			nodeToOrphaned[node.Name] = orphaned[id]
+		}
+	}
+	t.lastKnown = lastKnown
+	t.orphaned = orphaned
+	t.orphanedNodes = nodeToOrphaned
+	return t.orphanedNodeGroups()
+}
+
+// OrphanedNodeGroups returns node groups found orphaned during the last update, sorted by id.
+func (t *Tracker) OrphanedNodeGroups() []*NodeGroup {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.orphanedNodeGroups()
+}
+
+func (t *Tracker) orphanedNodeGroups() []*NodeGroup {
+	result := make([]*NodeGroup, 0, len(t.orphaned))
+	for _, nodeGroup := range t.orphaned {
+		result = append(result, nodeGroup)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Id() < result[j].Id() })
+	return result
+}
+
+// Status returns the status of orphaned node groups, to be reported along with managed node groups.
+func (t *Tracker) Status(now time.Time) []api.NodeGroupStatus {
+	var result []api.NodeGroupStatus
+	for _, nodeGroup := range t.OrphanedNodeGroups() {
+		result = append(result, api.NodeGroupStatus{
+			ProviderID: nodeGroup.Id(),
+			Conditions: []api.ClusterAutoscalerCondition{{
+				Type:               api.ClusterAutoscalerOrphaned,
+				Status:             t.conditionStatus(),
+				Message:            fmt.Sprintf("nodes=%d policy=%s", len(nodeGroup.Nodes), t.policy),
+				LastProbeTime:      metav1.Time{Time: now},
+				LastTransitionTime: metav1.Time{Time: nodeGroup.OrphanedSince},
+			}},
+		})
+	}
+	return result
+}
+
+// CloudProvider wraps the cloud provider, so that NodeGroupForNode returns the orphaned node group of
+// the nodes of orphaned node groups. Their nodes are then not mistaken for nodes of unmanaged node groups,
+// and can be deleted by the scale-down actuator.
+func (t *Tracker) CloudProvider(wrapped cloudprovider.CloudProvider) cloudprovider.CloudProvider {
+	return &orphansCloudProvider{CloudProvider: wrapped, tracker: t}
+}
+
+// orphanedNodeGroup returns the orphaned node group of the node, if any.
+func (t *Tracker) orphanedNodeGroup(nodeName string) (*NodeGroup, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	nodeGroup, found := t.orphanedNodes[nodeName]
+	return nodeGroup, found
+}
+
+// orphansCloudProvider wraps a cloud provider, placing nodes of orphaned node groups in these node groups.
+type orphansCloudProvider struct {
+	cloudprovider.CloudProvider
+	tracker *Tracker
+}
+
+// NodeGroupForNode returns the node group of the wrapped cloud provider, or the orphaned node group if
+// the node doesn't belong to any and its node group was found orphaned during the last update.
+func (p *orphansCloudProvider) NodeGroupForNode(node *apiv1.Node) (cloudprovider.NodeGroup, error) {
+	nodeGroup, err := p.CloudProvider.NodeGroupForNode(node)
+	if err != nil {
+		return nil, err
+	}
+	if nodeGroup != nil && !reflect.ValueOf(nodeGroup).IsNil() {
+		return nodeGroup, nil
+	}
+	if orphaned, found := p.tracker.orphanedNodeGroup(node.Name); found {
+		return orphaned, nil
+	}
+	return nodeGroup, nil
+}
+
+func (t *Tracker) conditionStatus() api.ClusterAutoscalerConditionStatus {
+	switch t.policy {
+	case AdoptPolicy:
+		return api.ClusterAutoscalerAdopted
+	case DrainPolicy:
+		return api.ClusterAutoscalerDraining
+	}
+	return api.ClusterAutoscalerUnmanaged
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package orphans
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate/api"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func TestParsePolicy(t *testing.T) {
+	for _, name := range []string{"alert", "adopt", "drain"} {
+		policy, err := ParsePolicy(name)
+		assert.NoError(t, err)
+		assert.Equal(t, Policy(name), policy)
+	}
+	_, err := ParsePolicy("ignore")
+	assert.Error(t, err)
+}
+
+func TestTrackerUpdate(t *testing.T) {
+	now := time.Now()
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n2 := BuildTestNode("n2", 1000, 1000)
+	n3 := BuildTestNode("n3", 1000, 1000)
+	unmanaged := BuildTestNode("unmanaged", 1000, 1000)
+	nodes := []*apiv1.Node{n1, n2, n3, unmanaged}
+
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 0, 10, 2)
+	provider.AddNodeGroup("ng2", 0, 10, 1)
+	provider.AddNode("ng1", n1)
+	provider.AddNode("ng1", n2)
+	provider.AddNode("ng2", n3)
+
+	tracker := NewTracker("")
+	assert.Equal(t, AlertPolicy, tracker.Policy())
+	assert.Empty(t, tracker.Update(provider, nodes, now))
+
+	provider.DeleteNodeGroup("ng1")
+	orphaned := tracker.Update(provider, nodes, now.Add(time.Minute))
+	if assert.Len(t, orphaned, 1) {
+		assert.Equal(t, "ng1", orphaned[0].Id())
+		assert.ElementsMatch(t, []*apiv1.Node{n1, n2}, orphaned[0].Nodes)
+		assert.Equal(t, now.Add(time.Minute), orphaned[0].OrphanedSince)
+	}
+
+	// The node group stays orphaned as long as it has nodes, keeping the original timestamp.
+	orphaned = tracker.Update(provider, []*apiv1.Node{n2, n3, unmanaged}, now.Add(2*time.Minute))
+	if assert.Len(t, orphaned, 1) {
+		assert.Equal(t, []*apiv1.Node{n2}, orphaned[0].Nodes)
+		assert.Equal(t, now.Add(time.Minute), orphaned[0].OrphanedSince)
+	}
+	status := tracker.Status(now.Add(2 * time.Minute))
+	if assert.Len(t, status, 1) {
+		assert.Equal(t, "ng1", status[0].ProviderID)
+		assert.Equal(t, api.ClusterAutoscalerOrphaned, status[0].Conditions[0].Type)
+		assert.Equal(t, api.ClusterAutoscalerUnmanaged, status[0].Conditions[0].Status)
+	}
+
+	// Once all nodes are gone, the node group is forgotten.
+	assert.Empty(t, tracker.Update(provider, []*apiv1.Node{n3, unmanaged}, now.Add(3*time.Minute)))
+	assert.Empty(t, tracker.Status(now.Add(3*time.Minute)))
+}
+
+func TestTrackerCloudProvider(t *testing.T) {
+	for _, policy := range []Policy{AdoptPolicy, DrainPolicy} {
+		t.Run(string(policy), func(t *testing.T) {
+			testTrackerCloudProvider(t, policy)
+		})
+	}
+}
+
+func testTrackerCloudProvider(t *testing.T, policy Policy) {
+	now := time.Now()
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n2 := BuildTestNode("n2", 1000, 1000)
+	unmanaged := BuildTestNode("unmanaged", 1000, 1000)
+	nodes := []*apiv1.Node{n1, n2, unmanaged}
+
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 0, 10, 1)
+	provider.AddNodeGroup("ng2", 0, 10, 1)
+	provider.AddNode("ng1", n1)
+	provider.AddNode("ng2", n2)
+
+	tracker := NewTracker(policy)
+	wrapped := tracker.CloudProvider(provider)
+	assert.Empty(t, tracker.Update(wrapped, nodes, now))
+
+	provider.DeleteNodeGroup("ng1")
+	orphaned := tracker.Update(wrapped, nodes, now.Add(time.Minute))
+	assert.Len(t, orphaned, 1)
+
+	// Nodes of orphaned node groups belong to the orphaned node group, the other ones are unaffected.
+	nodeGroup, err := wrapped.NodeGroupForNode(n1)
+	assert.NoError(t, err)
+	assert.True(t, IsOrphanedNodeGroup(nodeGroup))
+	assert.Equal(t, "ng1", nodeGroup.Id())
+	nodeGroup, err = wrapped.NodeGroupForNode(n2)
+	assert.NoError(t, err)
+	assert.False(t, IsOrphanedNodeGroup(nodeGroup))
+	assert.Equal(t, "ng2", nodeGroup.Id())
+	nodeGroup, err = wrapped.NodeGroupForNode(unmanaged)
+	assert.NoError(t, err)
+	assert.Nil(t, nodeGroup)
+
+	// The node group stays orphaned across updates with the wrapped cloud provider.
+	orphaned = tracker.Update(wrapped, nodes, now.Add(2*time.Minute))
+	if assert.Len(t, orphaned, 1) {
+		assert.Equal(t, now.Add(time.Minute), orphaned[0].OrphanedSince)
+	}
+
+	// Once the node is gone, it doesn't belong to the orphaned node group anymore.
+	assert.Empty(t, tracker.Update(wrapped, []*apiv1.Node{n2, unmanaged}, now.Add(3*time.Minute)))
+	nodeGroup, err = wrapped.NodeGroupForNode(n1)
+	assert.NoError(t, err)
+	assert.Nil(t, nodeGroup)
+}
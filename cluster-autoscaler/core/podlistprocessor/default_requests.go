@@ -0,0 +1,138 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podlistprocessor
+
+import (
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
+	v1lister "k8s.io/client-go/listers/core/v1"
+)
+
+// defaultedResources are the resources given a request in simulation when containers don't have one.
+var defaultedResources = []apiv1.ResourceName{apiv1.ResourceCPU, apiv1.ResourceMemory}
+
+type defaultRequestsPodListProcessor struct {
+	limitRangeLister v1lister.LimitRangeLister
+	fallback         apiv1.ResourceList
+}
+
+// NewDefaultRequestsPodListProcessor creates a PodListProcessor giving containers without CPU or memory
+// requests the default requests of the LimitRanges of their namespace, or the fallback requests if there
+// are none. Otherwise such pods are packed on new nodes as if they were free, and too few nodes are added.
+func NewDefaultRequestsPodListProcessor(limitRangeLister v1lister.LimitRangeLister, fallback apiv1.ResourceList) *defaultRequestsPodListProcessor {
+	return &defaultRequestsPodListProcessor{
+		limitRangeLister: limitRangeLister,
+		fallback:         fallback,
+	}
+}
+
+// Process replaces unschedulable pods with containers lacking requests by copies with default requests.
+// Pods are left intact in the cluster, only the simulation sees the defaults.
+func (p *defaultRequestsPodListProcessor) Process(context *context.AutoscalingContext, unschedulablePods []*apiv1.Pod) ([]*apiv1.Pod, error) {
+	result := make([]*apiv1.Pod, 0, len(unschedulablePods))
+	defaulted := 0
+	defaultsByNamespace := make(map[string]apiv1.ResourceList)
+	for _, pod := range unschedulablePods {
+		if !lacksRequests(pod) {
+			result = append(result, pod)
+			continue
+		}
+		defaults, found := defaultsByNamespace[pod.Namespace]
+		if !found {
+			defaults = p.namespaceDefaults(pod.Namespace)
+			defaultsByNamespace[pod.Namespace] = defaults
+		}
+		if len(defaults) == 0 {
+			result = append(result, pod)
+			continue
+		}
+		result = append(result, withDefaultRequests(pod, defaults))
+		defaulted++
+	}
+	klogx.Core.V(4).Infof("Applied default requests to %d unschedulable pods without requests", defaulted)
+	return result, nil
+}
+
+func (p *defaultRequestsPodListProcessor) CleanUp() {
+}
+
+// namespaceDefaults returns the default container requests of the namespace: those of its LimitRanges,
+// falling back to their default limits, which LimitRanger uses as requests, and then to the fallback.
+func (p *defaultRequestsPodListProcessor) namespaceDefaults(namespace string) apiv1.ResourceList {
+	defaults := apiv1.ResourceList{}
+	limitRanges, err := p.limitRangeLister.LimitRanges(namespace).List(labels.Everything())
+	if err != nil {
+		klogx.Core.Warningf("Failed to list LimitRanges of namespace %s: %v", namespace, err)
+	}
+	for _, limitRange := range limitRanges {
+		for _, item := range limitRange.Spec.Limits {
+			if item.Type != apiv1.LimitTypeContainer {
+				continue
+			}
+			for _, name := range defaultedResources {
+				if _, found := defaults[name]; found {
+					continue
+				}
+				if quantity, found := item.DefaultRequest[name]; found {
+					defaults[name] = quantity
+				} else if quantity, found := item.Default[name]; found {
+					defaults[name] = quantity
+				}
+			}
+		}
+	}
+	for _, name := range defaultedResources {
+		if _, found := defaults[name]; found {
+			continue
+		}
+		if quantity, found := p.fallback[name]; found && !quantity.IsZero() {
+			defaults[name] = quantity
+		}
+	}
+	return defaults
+}
+
+func lacksRequests(pod *apiv1.Pod) bool {
+	for _, container := range pod.Spec.Containers {
+		for _, name := range defaultedResources {
+			if _, found := container.Resources.Requests[name]; !found {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// withDefaultRequests returns a copy of the pod in which containers lacking a request get the default one.
+func withDefaultRequests(pod *apiv1.Pod, defaults apiv1.ResourceList) *apiv1.Pod {
+	podCopy := pod.DeepCopy()
+	for i := range podCopy.Spec.Containers {
+		resources := &podCopy.Spec.Containers[i].Resources
+		for name, quantity := range defaults {
+			if _, found := resources.Requests[name]; found {
+				continue
+			}
+			if resources.Requests == nil {
+				resources.Requests = apiv1.ResourceList{}
+			}
+			resources.Requests[name] = quantity.DeepCopy()
+		}
+	}
+	return podCopy
+}
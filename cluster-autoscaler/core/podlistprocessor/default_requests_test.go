@@ -0,0 +1,84 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podlistprocessor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	v1lister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestDefaultRequestsPodListProcessor(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	err := indexer.Add(&apiv1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{Name: "limits", Namespace: "limited"},
+		Spec: apiv1.LimitRangeSpec{Limits: []apiv1.LimitRangeItem{
+			{
+				Type:    apiv1.LimitTypePod,
+				Default: apiv1.ResourceList{apiv1.ResourceCPU: resource.MustParse("4")},
+			},
+			{
+				Type:           apiv1.LimitTypeContainer,
+				DefaultRequest: apiv1.ResourceList{apiv1.ResourceCPU: resource.MustParse("500m")},
+				Default:        apiv1.ResourceList{apiv1.ResourceCPU: resource.MustParse("1"), apiv1.ResourceMemory: resource.MustParse("1Gi")},
+			},
+		}},
+	})
+	assert.NoError(t, err)
+	fallback := apiv1.ResourceList{apiv1.ResourceCPU: resource.MustParse("100m"), apiv1.ResourceMemory: resource.MustParse("128Mi")}
+	processor := NewDefaultRequestsPodListProcessor(v1lister.NewLimitRangeLister(indexer), fallback)
+
+	inNamespace := func(namespace string) func(*apiv1.Pod) {
+		return func(pod *apiv1.Pod) { pod.Namespace = namespace }
+	}
+	withRequests := test.BuildTestPod("with-requests", 200, 1000, inNamespace("limited"))
+	pods := []*apiv1.Pod{
+		withRequests,
+		test.BuildTestPod("limited", -1, -1, inNamespace("limited")),
+		test.BuildTestPod("limited-memory-only", 200, -1, inNamespace("limited")),
+		test.BuildTestPod("unlimited", -1, -1, inNamespace("unlimited")),
+	}
+
+	result, err := processor.Process(nil, pods)
+	assert.NoError(t, err)
+	assert.Len(t, result, 4)
+	assert.Same(t, withRequests, result[0])
+
+	for i, want := range []apiv1.ResourceList{
+		{apiv1.ResourceCPU: resource.MustParse("500m"), apiv1.ResourceMemory: resource.MustParse("1Gi")},
+		{apiv1.ResourceCPU: *resource.NewMilliQuantity(200, resource.DecimalSI), apiv1.ResourceMemory: resource.MustParse("1Gi")},
+		fallback,
+	} {
+		pod := result[i+1]
+		assert.Equal(t, want, pod.Spec.Containers[0].Resources.Requests, pod.Name)
+		// pods in the cluster are left intact.
+		assert.NotEqual(t, want, pods[i+1].Spec.Containers[0].Resources.Requests, pod.Name)
+	}
+
+	// without LimitRange nor fallback, pods are left as they are.
+	processor = NewDefaultRequestsPodListProcessor(v1lister.NewLimitRangeLister(indexer), nil)
+	unlimited := test.BuildTestPod("unlimited", -1, -1, inNamespace("unlimited"))
+	result, err = processor.Process(nil, []*apiv1.Pod{unlimited})
+	assert.NoError(t, err)
+	assert.Same(t, unlimited, result[0])
+}
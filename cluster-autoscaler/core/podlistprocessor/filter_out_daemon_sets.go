@@ -19,8 +19,8 @@ package podlistprocessor
 import (
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
 	podutils "k8s.io/autoscaler/cluster-autoscaler/utils/pod"
-	klog "k8s.io/klog/v2"
 )
 
 type filterOutDaemonSetPodListProcessor struct {
@@ -37,7 +37,7 @@ func (p *filterOutDaemonSetPodListProcessor) Process(context *context.Autoscalin
 	// for scheduling. To improve that we are filtering them here, as the CA won't be
 	// able to help them so there is no point to in passing them to scale-up logic.
 
-	klog.V(4).Infof("Filtering out daemon set pods")
+	klogx.Core.V(4).Infof("Filtering out daemon set pods")
 
 	var nonDaemonSetPods []*apiv1.Pod
 	for _, pod := range unschedulablePods {
@@ -46,7 +46,7 @@ func (p *filterOutDaemonSetPodListProcessor) Process(context *context.Autoscalin
 		}
 	}
 
-	klog.V(4).Infof("Filtered out %v daemon set pods, %v unschedulable pods left", len(unschedulablePods)-len(nonDaemonSetPods), len(nonDaemonSetPods))
+	klogx.Core.V(4).Infof("Filtered out %v daemon set pods, %v unschedulable pods left", len(unschedulablePods)-len(nonDaemonSetPods), len(nonDaemonSetPods))
 	return nonDaemonSetPods, nil
 }
 
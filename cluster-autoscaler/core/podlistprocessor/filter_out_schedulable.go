@@ -27,8 +27,8 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/predicatechecker"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/scheduling"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
 	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
-	klog "k8s.io/klog/v2"
 )
 
 type filterOutSchedulablePodListProcessor struct {
@@ -60,7 +60,7 @@ func (p *filterOutSchedulablePodListProcessor) Process(context *context.Autoscal
 	// With the check enabled the last point won't happen because CA will ignore a pod
 	// which is supposed to schedule on an existing node.
 
-	klog.V(4).Infof("Filtering out schedulables")
+	klogx.Core.V(4).Infof("Filtering out schedulables")
 	filterOutSchedulableStart := time.Now()
 
 	unschedulablePodsToHelp, err := p.filterOutSchedulableByPacking(unschedulablePods, context.ClusterSnapshot)
@@ -72,7 +72,7 @@ func (p *filterOutSchedulablePodListProcessor) Process(context *context.Autoscal
 	metrics.UpdateDurationFromStart(metrics.FilterOutSchedulable, filterOutSchedulableStart)
 
 	if len(unschedulablePodsToHelp) != len(unschedulablePods) {
-		klog.V(2).Info("Schedulable pods present")
+		klogx.Core.V(2).Info("Schedulable pods present")
 
 		if context.DebuggingSnapshotter.IsDataCollectionAllowed() {
 			schedulablePods := findSchedulablePods(unschedulablePods, unschedulablePodsToHelp)
@@ -80,7 +80,7 @@ func (p *filterOutSchedulablePodListProcessor) Process(context *context.Autoscal
 		}
 
 	} else {
-		klog.V(4).Info("No schedulable pods")
+		klogx.Core.V(4).Info("No schedulable pods")
 	}
 	return unschedulablePodsToHelp, nil
 }
@@ -117,7 +117,7 @@ func (p *filterOutSchedulablePodListProcessor) filterOutSchedulableByPacking(uns
 	}
 
 	metrics.UpdateOverflowingControllers(overflowingControllerCount)
-	klog.V(4).Infof("%v pods marked as unschedulable can be scheduled.", len(unschedulableCandidates)-len(unschedulablePods))
+	klogx.Core.V(4).Infof("%v pods marked as unschedulable can be scheduled.", len(unschedulableCandidates)-len(unschedulablePods))
 
 	p.schedulingSimulator.DropOldHints()
 	return unschedulablePods, nil
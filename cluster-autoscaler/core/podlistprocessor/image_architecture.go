@@ -0,0 +1,143 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podlistprocessor
+
+import (
+	"sort"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/imageplatform"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
+)
+
+const betaArchLabel = "beta.kubernetes.io/arch"
+
+type imageArchitecturePodListProcessor struct {
+	resolver imageplatform.Resolver
+}
+
+// NewImageArchitecturePodListProcessor creates a PodListProcessor restricting pods without explicit
+// architecture requirements to the CPU architectures supported by all of their images. This prevents
+// scaling up node groups of an architecture the pod can't run on.
+func NewImageArchitecturePodListProcessor(resolver imageplatform.Resolver) *imageArchitecturePodListProcessor {
+	return &imageArchitecturePodListProcessor{resolver: resolver}
+}
+
+// Process adds a required node affinity on kubernetes.io/arch to copies of pods whose image
+// architectures could be resolved. Pods already constraining the architecture are left intact.
+func (p *imageArchitecturePodListProcessor) Process(context *context.AutoscalingContext, unschedulablePods []*apiv1.Pod) ([]*apiv1.Pod, error) {
+	result := make([]*apiv1.Pod, 0, len(unschedulablePods))
+	restricted := 0
+	for _, pod := range unschedulablePods {
+		if hasArchitectureConstraint(pod) {
+			result = append(result, pod)
+			continue
+		}
+		archs := p.podArchitectures(pod)
+		if len(archs) == 0 {
+			result = append(result, pod)
+			continue
+		}
+		result = append(result, withArchitectureAffinity(pod, archs))
+		restricted++
+	}
+	klogx.Core.V(4).Infof("Restricted %d unschedulable pods to the architectures supported by their images", restricted)
+	return result, nil
+}
+
+func (p *imageArchitecturePodListProcessor) CleanUp() {
+}
+
+// podArchitectures returns the architectures supported by all images of the pod, or nil if
+// they can't be determined.
+func (p *imageArchitecturePodListProcessor) podArchitectures(pod *apiv1.Pod) []string {
+	var common map[string]bool
+	containers := append(append([]apiv1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+	for _, container := range containers {
+		archs, err := p.resolver.Architectures(container.Image)
+		if err != nil {
+			klogx.Core.V(5).Infof("Couldn't resolve architectures of image %s used by pod %s/%s: %v", container.Image, pod.Namespace, pod.Name, err)
+			return nil
+		}
+		if len(archs) == 0 {
+			return nil
+		}
+		supported := make(map[string]bool, len(archs))
+		for _, arch := range archs {
+			if common == nil || common[arch] {
+				supported[arch] = true
+			}
+		}
+		common = supported
+	}
+	var result []string
+	for arch := range common {
+		result = append(result, arch)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func hasArchitectureConstraint(pod *apiv1.Pod) bool {
+	for _, key := range []string{apiv1.LabelArchStable, betaArchLabel} {
+		if _, found := pod.Spec.NodeSelector[key]; found {
+			return true
+		}
+	}
+	affinity := pod.Spec.Affinity
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return false
+	}
+	for _, term := range affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		for _, expr := range term.MatchExpressions {
+			if expr.Key == apiv1.LabelArchStable || expr.Key == betaArchLabel {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// withArchitectureAffinity returns a copy of the pod required to run on one of the given architectures.
+func withArchitectureAffinity(pod *apiv1.Pod, archs []string) *apiv1.Pod {
+	podCopy := pod.DeepCopy()
+	requirement := apiv1.NodeSelectorRequirement{
+		Key:      apiv1.LabelArchStable,
+		Operator: apiv1.NodeSelectorOpIn,
+		Values:   archs,
+	}
+	if podCopy.Spec.Affinity == nil {
+		podCopy.Spec.Affinity = &apiv1.Affinity{}
+	}
+	if podCopy.Spec.Affinity.NodeAffinity == nil {
+		podCopy.Spec.Affinity.NodeAffinity = &apiv1.NodeAffinity{}
+	}
+	nodeAffinity := podCopy.Spec.Affinity.NodeAffinity
+	if nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &apiv1.NodeSelector{}
+	}
+	selector := nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(selector.NodeSelectorTerms) == 0 {
+		selector.NodeSelectorTerms = []apiv1.NodeSelectorTerm{{}}
+	}
+	// Terms are ORed, so the requirement has to be added to each of them.
+	for i := range selector.NodeSelectorTerms {
+		selector.NodeSelectorTerms[i].MatchExpressions = append(selector.NodeSelectorTerms[i].MatchExpressions, requirement)
+	}
+	return podCopy
+}
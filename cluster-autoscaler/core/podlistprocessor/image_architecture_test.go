@@ -0,0 +1,125 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podlistprocessor
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+type staticResolver map[string][]string
+
+func (r staticResolver) Architectures(image string) ([]string, error) {
+	archs, found := r[image]
+	if !found {
+		return nil, fmt.Errorf("unknown image %s", image)
+	}
+	return archs, nil
+}
+
+func podWithImages(name string, images ...string) *apiv1.Pod {
+	pod := test.BuildTestPod(name, 100, 0)
+	pod.Spec.Containers = nil
+	for i, image := range images {
+		pod.Spec.Containers = append(pod.Spec.Containers, apiv1.Container{Name: fmt.Sprintf("c%d", i), Image: image})
+	}
+	return pod
+}
+
+func archRequirement(archs ...string) apiv1.NodeSelectorRequirement {
+	return apiv1.NodeSelectorRequirement{Key: apiv1.LabelArchStable, Operator: apiv1.NodeSelectorOpIn, Values: archs}
+}
+
+func TestImageArchitecturePodListProcessor(t *testing.T) {
+	resolver := staticResolver{
+		"multi":   {"amd64", "arm64"},
+		"amd64":   {"amd64"},
+		"arm64":   {"arm64"},
+		"nothing": {},
+	}
+
+	withSelector := podWithImages("selector", "amd64")
+	withSelector.Spec.NodeSelector = map[string]string{apiv1.LabelArchStable: "arm64"}
+
+	withTerms := podWithImages("terms", "amd64")
+	withTerms.Spec.Affinity = &apiv1.Affinity{NodeAffinity: &apiv1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &apiv1.NodeSelector{NodeSelectorTerms: []apiv1.NodeSelectorTerm{
+			{MatchExpressions: []apiv1.NodeSelectorRequirement{{Key: "zone", Operator: apiv1.NodeSelectorOpIn, Values: []string{"a"}}}},
+			{MatchExpressions: []apiv1.NodeSelectorRequirement{{Key: "zone", Operator: apiv1.NodeSelectorOpIn, Values: []string{"b"}}}},
+		}},
+	}}
+
+	testCases := []struct {
+		name string
+		pod  *apiv1.Pod
+		want []apiv1.NodeSelectorTerm
+	}{
+		{
+			name: "multi-arch image",
+			pod:  podWithImages("p", "multi"),
+			want: []apiv1.NodeSelectorTerm{{MatchExpressions: []apiv1.NodeSelectorRequirement{archRequirement("amd64", "arm64")}}},
+		},
+		{
+			name: "architectures are intersected across containers",
+			pod:  podWithImages("p", "multi", "amd64"),
+			want: []apiv1.NodeSelectorTerm{{MatchExpressions: []apiv1.NodeSelectorRequirement{archRequirement("amd64")}}},
+		},
+		{
+			name: "requirement is added to every term",
+			pod:  withTerms,
+			want: []apiv1.NodeSelectorTerm{
+				{MatchExpressions: []apiv1.NodeSelectorRequirement{{Key: "zone", Operator: apiv1.NodeSelectorOpIn, Values: []string{"a"}}, archRequirement("amd64")}},
+				{MatchExpressions: []apiv1.NodeSelectorRequirement{{Key: "zone", Operator: apiv1.NodeSelectorOpIn, Values: []string{"b"}}, archRequirement("amd64")}},
+			},
+		},
+		{
+			name: "explicit architecture selector is honored",
+			pod:  withSelector,
+		},
+		{
+			name: "unresolvable image",
+			pod:  podWithImages("p", "amd64", "private"),
+		},
+		{
+			name: "no common architecture",
+			pod:  podWithImages("p", "amd64", "arm64"),
+		},
+		{
+			name: "image without architectures",
+			pod:  podWithImages("p", "nothing"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			original := tc.pod.DeepCopy()
+			processor := NewImageArchitecturePodListProcessor(resolver)
+			got, err := processor.Process(nil, []*apiv1.Pod{tc.pod})
+			assert.NoError(t, err)
+			assert.Len(t, got, 1)
+			assert.Equal(t, original, tc.pod, "input pod must not be modified")
+			if tc.want == nil {
+				assert.Same(t, tc.pod, got[0])
+				return
+			}
+			assert.Equal(t, tc.want, got[0].Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms)
+		})
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/deletiontracker"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
+	"k8s.io/autoscaler/cluster-autoscaler/core/utils"
 	"k8s.io/autoscaler/cluster-autoscaler/metrics"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/gpu"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
@@ -150,12 +151,27 @@ func deleteNodesFromCloudProvider(ctx *context.AutoscalingContext, nodes []*apiv
 	if err != nil {
 		return nodeGroup, errors.NewAutoscalerError(errors.CloudProviderError, "failed to find node group for %s: %v", nodes[0].Name, err)
 	}
-	if err := nodeGroup.DeleteNodes(nodes); err != nil {
+	if err := deleteNodesFromNodeGroup(ctx, nodeGroup, nodes); err != nil {
 		return nodeGroup, errors.NewAutoscalerError(errors.CloudProviderError, "failed to delete nodes from group %s: %v", nodeGroup.Id(), err)
 	}
 	return nodeGroup, nil
 }
 
+// deleteNodesFromNodeGroup deletes the nodes of an atomically scaled node group all at once, regardless of
+// its min size, falling back to regular deletion if the cloud provider doesn't support it.
+func deleteNodesFromNodeGroup(ctx *context.AutoscalingContext, nodeGroup cloudprovider.NodeGroup, nodes []*apiv1.Node) error {
+	opts, err := nodeGroup.GetOptions(ctx.NodeGroupDefaults)
+	if err != nil && err != cloudprovider.ErrNotImplemented {
+		return err
+	}
+	if opts != nil && opts.ZeroOrMaxNodeScaling {
+		if err := nodeGroup.ForceDeleteNodes(nodes); err != cloudprovider.ErrNotImplemented {
+			return err
+		}
+	}
+	return nodeGroup.DeleteNodes(nodes)
+}
+
 func nodeScaleDownReason(node *apiv1.Node, drain bool) metrics.NodeScaleDownReason {
 	readiness, err := kubernetes.GetNodeReadiness(node)
 	if err != nil {
@@ -204,6 +220,11 @@ func RegisterAndRecordSuccessfulScaleDownEvent(ctx *context.AutoscalingContext,
 	gpuConfig := ctx.CloudProvider.GetNodeGpuConfig(node)
 	metricResourceName, metricGpuType := gpu.GetGpuInfoForMetrics(gpuConfig, ctx.CloudProvider.GetAvailableGPUTypes(), node, nodeGroup)
 	metrics.RegisterScaleDown(1, metricResourceName, metricGpuType, nodeScaleDownReason(node, drain))
+	if ctx.EmitPerNodeGroupMetrics {
+		if price, found := utils.GetHourlyNodePrice(ctx.CloudProvider, node, time.Now()); found {
+			metrics.RegisterScaleDownEstimatedHourlyCost(nodeGroup.Id(), price)
+		}
+	}
 	if drain {
 		ctx.LogRecorder.Eventf(apiv1.EventTypeNormal, "ScaleDown", "Scale-down: node %s removed with drain", node.Name)
 	} else {
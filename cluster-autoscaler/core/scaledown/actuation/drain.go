@@ -26,12 +26,15 @@ import (
 	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	kube_errors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/integer"
 
 	acontext "k8s.io/autoscaler/cluster-autoscaler/context"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
 	"k8s.io/autoscaler/cluster-autoscaler/metrics"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/options"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/daemonset"
@@ -81,10 +84,10 @@ func NewDefaultEvictor(deleteOptions options.NodeDeleteOptions, drainabilityRule
 }
 
 // DrainNode works like DrainNodeWithPods, but lists of pods to evict don't have to be provided. All non-mirror, non-DS pods on the
-// node are evicted. Mirror pods are not evicted. DaemonSet pods are evicted if DaemonSetEvictionForOccupiedNodes is enabled, or
+// node are evicted, except the ones the drainability rules skip. Mirror pods are not evicted. DaemonSet pods are evicted if DaemonSetEvictionForOccupiedNodes is enabled, or
 // if they have the EnableDsEvictionKey annotation.
 func (e Evictor) DrainNode(ctx *acontext.AutoscalingContext, nodeInfo *framework.NodeInfo) (map[string]status.PodEvictionResult, error) {
-	dsPodsToEvict, nonDsPodsToEvict := podsToEvict(ctx, nodeInfo)
+	dsPodsToEvict, nonDsPodsToEvict := podsToEvict(ctx, nodeInfo, e.deleteOptions, e.drainabilityRules)
 	return e.DrainNodeWithPods(ctx, nodeInfo.Node(), nonDsPodsToEvict, dsPodsToEvict)
 }
 
@@ -139,14 +142,18 @@ func (e Evictor) DrainNodeWithPods(ctx *acontext.AutoscalingContext, node *apiv1
 		return evictionResults, errors.NewAutoscalerError(errors.ApiCallError, "Failed to drain node %s/%s, due to following errors: %v", node.Namespace, node.Name, evictionErrs)
 	}
 
-	// Evictions created successfully, wait maxGracefulTerminationSec + podEvictionHeadroom to see if pods really disappeared.
+	// Evictions created successfully, wait the longest maxGracefulTerminationSec of the pods + podEvictionHeadroom to see if pods really disappeared.
+	maxGracefulTermination := 0
+	for _, pod := range pods {
+		maxGracefulTermination = integer.IntMax(maxGracefulTermination, maxGracefulTerminationSec(ctx, pod))
+	}
 	var allGone bool
-	for start := time.Now(); time.Now().Sub(start) < time.Duration(ctx.MaxGracefulTerminationSec)*time.Second+e.PodEvictionHeadroom; time.Sleep(5 * time.Second) {
+	for start := time.Now(); time.Now().Sub(start) < time.Duration(maxGracefulTermination)*time.Second+e.PodEvictionHeadroom; time.Sleep(5 * time.Second) {
 		allGone = true
 		for _, pod := range pods {
 			podreturned, err := ctx.ClientSet.CoreV1().Pods(pod.Namespace).Get(context.TODO(), pod.Name, metav1.GetOptions{})
 			if err == nil && (podreturned == nil || podreturned.Spec.NodeName == node.Name) {
-				klog.V(1).Infof("Not deleted yet %s/%s", pod.Namespace, pod.Name)
+				klogx.Core.V(1).Infof("Not deleted yet %s/%s", pod.Namespace, pod.Name)
 				allGone = false
 				break
 			}
@@ -157,7 +164,7 @@ func (e Evictor) DrainNodeWithPods(ctx *acontext.AutoscalingContext, node *apiv1
 			}
 		}
 		if allGone {
-			klog.V(1).Infof("All pods removed from %s", node.Name)
+			klogx.Core.V(1).Infof("All pods removed from %s", node.Name)
 			// Let the deferred function know there is no need for cleanup
 			return evictionResults, nil
 		}
@@ -220,10 +227,10 @@ func evictPod(ctx *acontext.AutoscalingContext, podToEvict *apiv1.Pod, isDaemonS
 
 	maxTermination := int64(apiv1.DefaultTerminationGracePeriodSeconds)
 	if podToEvict.Spec.TerminationGracePeriodSeconds != nil {
-		if *podToEvict.Spec.TerminationGracePeriodSeconds < int64(ctx.MaxGracefulTerminationSec) {
+		if *podToEvict.Spec.TerminationGracePeriodSeconds < int64(maxGracefulTerminationSec(ctx, podToEvict)) {
 			maxTermination = *podToEvict.Spec.TerminationGracePeriodSeconds
 		} else {
-			maxTermination = int64(ctx.MaxGracefulTerminationSec)
+			maxTermination = int64(maxGracefulTerminationSec(ctx, podToEvict))
 		}
 	}
 
@@ -254,12 +261,35 @@ func evictPod(ctx *acontext.AutoscalingContext, podToEvict *apiv1.Pod, isDaemonS
 	return status.PodEvictionResult{Pod: podToEvict, TimedOut: true, Err: fmt.Errorf("failed to evict pod %s/%s within allowed timeout (last error: %v)", podToEvict.Namespace, podToEvict.Name, lastError)}
 }
 
-func podsToEvict(ctx *acontext.AutoscalingContext, nodeInfo *framework.NodeInfo) (dsPods, nonDsPods []*apiv1.Pod) {
+// maxGracefulTerminationSec returns the maximum number of seconds scale down waits for the pod to terminate:
+// the override of its namespace, or else of its priority class, or else MaxGracefulTerminationSec.
+func maxGracefulTerminationSec(ctx *acontext.AutoscalingContext, pod *apiv1.Pod) int {
+	if seconds, found := ctx.MaxGracefulTerminationSecPerNamespace[pod.Namespace]; found {
+		return seconds
+	}
+	if pod.Spec.PriorityClassName != "" {
+		if seconds, found := ctx.MaxGracefulTerminationSecPerPriorityClass[pod.Spec.PriorityClassName]; found {
+			return seconds
+		}
+	}
+	return ctx.MaxGracefulTerminationSec
+}
+
+func podsToEvict(ctx *acontext.AutoscalingContext, nodeInfo *framework.NodeInfo, deleteOptions options.NodeDeleteOptions, drainabilityRules rules.Rules) (dsPods, nonDsPods []*apiv1.Pod) {
+	if drainabilityRules == nil {
+		drainabilityRules = rules.Default()
+	}
+	drainCtx := &drainability.DrainContext{
+		DeleteOptions: deleteOptions,
+	}
 	for _, podInfo := range nodeInfo.Pods {
 		if pod_util.IsMirrorPod(podInfo.Pod) {
 			continue
 		} else if pod_util.IsDaemonSetPod(podInfo.Pod) {
 			dsPods = append(dsPods, podInfo.Pod)
+		} else if drainabilityRules.Drainable(drainCtx, podInfo.Pod).Outcome == drainability.SkipDrain {
+			// Pods skipped by the drainability rules, e.g. naked pods with the ignore policy, go away with their node.
+			continue
 		} else {
 			nonDsPods = append(nonDsPods, podInfo.Pod)
 		}
@@ -41,6 +41,9 @@ import (
 	. "k8s.io/autoscaler/cluster-autoscaler/core/test"
 	"k8s.io/autoscaler/cluster-autoscaler/core/utils"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/nakedpod"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/options"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/daemonset"
 	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
 	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
@@ -236,6 +239,53 @@ func TestDrainNodeWithPods(t *testing.T) {
 	assert.Equal(t, p2.Name, deleted[2])
 }
 
+func TestDrainNodeWithPodsMaxGracefulTerminationOverrides(t *testing.T) {
+	gracePeriods := make(map[string]int64)
+	var gracePeriodsLock sync.Mutex
+	fakeClient := &fake.Clientset{}
+	fakeClient.Fake.AddReactor("get", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.NewNotFound(apiv1.Resource("pod"), "whatever")
+	})
+	fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		eviction := action.(core.CreateAction).GetObject().(*policyv1beta1.Eviction)
+		gracePeriodsLock.Lock()
+		defer gracePeriodsLock.Unlock()
+		gracePeriods[eviction.Name] = *eviction.DeleteOptions.GracePeriodSeconds
+		return true, nil, nil
+	})
+
+	pod := func(name, namespace, priorityClass string) *apiv1.Pod {
+		p := BuildTestPod(name, 100, 0)
+		p.Namespace = namespace
+		p.Spec.PriorityClassName = priorityClass
+		gracePeriod := int64(7200)
+		p.Spec.TerminationGracePeriodSeconds = &gracePeriod
+		return p
+	}
+	pods := []*apiv1.Pod{
+		pod("default", "default", ""),
+		pod("database", "databases", ""),
+		pod("batch", "default", "batch"),
+		pod("database-batch", "databases", "batch"),
+	}
+	n1 := BuildTestNode("n1", 1000, 1000)
+	SetNodeReadyState(n1, true, time.Time{})
+
+	options := config.AutoscalingOptions{
+		MaxGracefulTerminationSec:                 20,
+		MaxGracefulTerminationSecPerNamespace:     map[string]int{"databases": 3600},
+		MaxGracefulTerminationSecPerPriorityClass: map[string]int{"batch": 5},
+		MaxPodEvictionTime:                        5 * time.Second,
+	}
+	ctx, err := NewScaleTestAutoscalingContext(options, fakeClient, nil, nil, nil, nil)
+	assert.NoError(t, err)
+
+	evictor := Evictor{EvictionRetryTime: 0, PodEvictionHeadroom: DefaultPodEvictionHeadroom}
+	_, err = evictor.DrainNodeWithPods(&ctx, n1, pods, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"default": 20, "database": 3600, "batch": 5, "database-batch": 3600}, gracePeriods)
+}
+
 func TestDrainNodeWithPodsWithRescheduled(t *testing.T) {
 	deletedPods := make(chan string, 10)
 	fakeClient := &fake.Clientset{}
@@ -527,6 +577,7 @@ func TestPodsToEvict(t *testing.T) {
 		pods               []*apiv1.Pod
 		nodeNameOverwrite  string
 		dsEvictionDisabled bool
+		nakedPodPolicies   map[string]string
 		wantDsPods         []*apiv1.Pod
 		wantNonDsPods      []*apiv1.Pod
 	}{
@@ -562,6 +613,18 @@ func TestPodsToEvict(t *testing.T) {
 			wantDsPods:         []*apiv1.Pod{dsPod("pod-1", true), dsPod("pod-3", true)},
 			wantNonDsPods:      []*apiv1.Pod{},
 		},
+		"naked pods with the ignore policy are not returned": {
+			pods:             []*apiv1.Pod{regularPod("pod-1"), regularPod("pod-2")},
+			nakedPodPolicies: map[string]string{nakedpod.DefaultNamespace: string(nakedpod.IgnorePolicy)},
+			wantDsPods:       []*apiv1.Pod{},
+			wantNonDsPods:    []*apiv1.Pod{},
+		},
+		"naked pods with the evict policy are returned": {
+			pods:             []*apiv1.Pod{regularPod("pod-1"), regularPod("pod-2")},
+			nakedPodPolicies: map[string]string{nakedpod.DefaultNamespace: string(nakedpod.EvictPolicy)},
+			wantDsPods:       []*apiv1.Pod{},
+			wantNonDsPods:    []*apiv1.Pod{regularPod("pod-1"), regularPod("pod-2")},
+		},
 		"all pod kinds are correctly handled together": {
 			pods: []*apiv1.Pod{
 				dsPod("ds-pod-1", false), dsPod("ds-pod-2", false),
@@ -593,7 +656,8 @@ func TestPodsToEvict(t *testing.T) {
 			if err != nil {
 				t.Fatalf("NodeInfos().Get() unexpected error: %v", err)
 			}
-			gotDsPods, gotNonDsPods := podsToEvict(ctx, nodeInfo)
+			deleteOptions := options.NodeDeleteOptions{NakedPodPolicies: tc.nakedPodPolicies}
+			gotDsPods, gotNonDsPods := podsToEvict(ctx, nodeInfo, deleteOptions, rules.Default())
 			if diff := cmp.Diff(tc.wantDsPods, gotDsPods, cmpopts.EquateEmpty()); diff != "" {
 				t.Errorf("podsToEvict dsPods diff (-want +got):\n%s", diff)
 			}
@@ -87,7 +87,18 @@ func (ds *GroupDeletionScheduler) ScheduleDeletion(nodeInfo *framework.NodeInfo,
 		opts = &config.NodeGroupAutoscalingOptions{}
 	}
 
+	drainAware, isDrainAware := nodeGroup.(cloudprovider.DrainAwareNodeGroup)
+	if isDrainAware {
+		if err := drainAware.BeforeNodeDrain(nodeInfo.Node()); err != nil {
+			klog.Warningf("Error while preparing node %q of node group %s for drain: %v", nodeInfo.Node().Name, nodeGroup.Id(), err)
+		}
+	}
 	nodeDeleteResult := ds.prepareNodeForDeletion(nodeInfo, drain)
+	if isDrainAware {
+		if err := drainAware.AfterNodeDrain(nodeInfo.Node()); err != nil {
+			klog.Warningf("Error while finishing the drain of node %q of node group %s: %v", nodeInfo.Node().Name, nodeGroup.Id(), err)
+		}
+	}
 	if nodeDeleteResult.Err != nil {
 		ds.AbortNodeDeletion(nodeInfo.Node(), nodeGroup.Id(), drain, "prepareNodeForDeletion failed", nodeDeleteResult)
 		return
@@ -152,6 +152,58 @@ func TestScheduleDeletion(t *testing.T) {
 	}
 }
 
+func TestScheduleDeletionDrainAwareNodeGroup(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	ng := &drainAwareNodeGroup{TestNodeGroup: testprovider.NewTestNodeGroup("test", 100, 0, 3, true, false, "n1-standard-2", nil, nil)}
+	ng.SetCloudProvider(provider)
+	provider.InsertNodeGroup(ng)
+	nodes := generateNodes(0, 2, "test")
+	for _, node := range nodes {
+		provider.AddNode(ng.Id(), node)
+	}
+
+	batcher := &countingBatcher{}
+	podLister := kube_util.NewTestPodLister([]*apiv1.Pod{})
+	pdbLister := kube_util.NewTestPodDisruptionBudgetLister([]*policyv1.PodDisruptionBudget{})
+	dsLister, err := kube_util.NewTestDaemonSetLister([]*appsv1.DaemonSet{})
+	if err != nil {
+		t.Fatalf("Couldn't create daemonset lister")
+	}
+	registry := kube_util.NewListerRegistry(nil, nil, podLister, pdbLister, dsLister, nil, nil, nil, nil)
+	ctx, err := NewScaleTestAutoscalingContext(config.AutoscalingOptions{}, &fake.Clientset{}, registry, provider, nil, nil)
+	if err != nil {
+		t.Fatalf("Couldn't set up autoscaling context: %v", err)
+	}
+	scheduler := NewGroupDeletionScheduler(&ctx, deletiontracker.NewNodeDeletionTracker(0), batcher, Evictor{EvictionRetryTime: 0, DsEvictionRetryTime: 0, DsEvictionEmptyNodeTimeout: 0, PodEvictionHeadroom: DefaultPodEvictionHeadroom})
+
+	for _, node := range nodes {
+		scheduler.ScheduleDeletion(infoForNode(node), ng, 1, false)
+	}
+
+	want := []string{"before test-node-0", "after test-node-0", "before test-node-1", "after test-node-1"}
+	if diff := cmp.Diff(want, ng.calls); diff != "" {
+		t.Errorf("Drain hook calls diff (-want +got):\n%s", diff)
+	}
+	if batcher.addedNodes != 2 {
+		t.Errorf("Incorrect number of deleted nodes, want 2 but got %v", batcher.addedNodes)
+	}
+}
+
+type drainAwareNodeGroup struct {
+	*testprovider.TestNodeGroup
+	calls []string
+}
+
+func (ng *drainAwareNodeGroup) BeforeNodeDrain(node *apiv1.Node) error {
+	ng.calls = append(ng.calls, "before "+node.Name)
+	return nil
+}
+
+func (ng *drainAwareNodeGroup) AfterNodeDrain(node *apiv1.Node) error {
+	ng.calls = append(ng.calls, "after "+node.Name)
+	return fmt.Errorf("simulated error")
+}
+
 type countingBatcher struct {
 	addedNodes int
 }
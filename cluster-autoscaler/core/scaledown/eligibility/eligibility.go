@@ -37,6 +37,9 @@ import (
 const (
 	// ScaleDownDisabledKey is the name of annotation marking node as not eligible for scale down.
 	ScaleDownDisabledKey = "cluster-autoscaler.kubernetes.io/scale-down-disabled"
+	// ScaleDownUrgentKey is the name of annotation marking node as to be scaled down as soon as it is unneeded,
+	// without waiting for the end of its billing period.
+	ScaleDownUrgentKey = "cluster-autoscaler.kubernetes.io/scale-down-urgent"
 )
 
 // Checker is responsible for deciding which nodes pass the criteria for scale down.
@@ -71,6 +74,8 @@ func (c *Checker) FilterOutUnremovable(context *context.AutoscalingContext, scal
 	utilizationMap := make(map[string]utilization.Info)
 	currentlyUnneededNodeNames := make([]string, 0, len(scaleDownCandidates))
 	utilLogsQuota := klogx.NewLoggingQuota(20)
+	problemNodesQuota := context.NodeProblemTracker.RemainingRecycles(timestamp)
+	gpuUtilization := measuredGpuUtilization(context)
 
 	for _, node := range scaleDownCandidates {
 		nodeInfo, err := context.ClusterSnapshot.NodeInfos().Get(node.Name)
@@ -87,7 +92,7 @@ func (c *Checker) FilterOutUnremovable(context *context.AutoscalingContext, scal
 			continue
 		}
 
-		reason, utilInfo := c.unremovableReasonAndNodeUtilization(context, timestamp, nodeInfo, utilLogsQuota)
+		reason, utilInfo := c.unremovableReasonAndNodeUtilization(context, timestamp, nodeInfo, utilLogsQuota, &problemNodesQuota, gpuUtilization)
 		if utilInfo != nil {
 			utilizationMap[node.Name] = *utilInfo
 		}
@@ -101,22 +106,22 @@ func (c *Checker) FilterOutUnremovable(context *context.AutoscalingContext, scal
 
 	klogx.V(4).Over(utilLogsQuota).Infof("Skipped logging utilization for %d other nodes", -utilLogsQuota.Left())
 	if skipped > 0 {
-		klog.V(1).Infof("Scale-down calculation: ignoring %v nodes unremovable in the last %v", skipped, context.AutoscalingOptions.UnremovableNodeRecheckTimeout)
+		klogx.Core.V(1).Infof("Scale-down calculation: ignoring %v nodes unremovable in the last %v", skipped, context.AutoscalingOptions.UnremovableNodeRecheckTimeout)
 	}
 	return currentlyUnneededNodeNames, utilizationMap, ineligible
 }
 
-func (c *Checker) unremovableReasonAndNodeUtilization(context *context.AutoscalingContext, timestamp time.Time, nodeInfo *schedulerframework.NodeInfo, utilLogsQuota *klogx.Quota) (simulator.UnremovableReason, *utilization.Info) {
+func (c *Checker) unremovableReasonAndNodeUtilization(context *context.AutoscalingContext, timestamp time.Time, nodeInfo *schedulerframework.NodeInfo, utilLogsQuota *klogx.Quota, problemNodesQuota *int, gpuUtilization map[string]float64) (simulator.UnremovableReason, *utilization.Info) {
 	node := nodeInfo.Node()
 
 	if actuation.IsNodeBeingDeleted(node, timestamp) {
-		klog.V(1).Infof("Skipping %s from delete consideration - the node is currently being deleted", node.Name)
+		klogx.Core.V(1).Infof("Skipping %s from delete consideration - the node is currently being deleted", node.Name)
 		return simulator.CurrentlyBeingDeleted, nil
 	}
 
 	// Skip nodes marked with no scale down annotation
 	if HasNoScaleDownAnnotation(node) {
-		klog.V(1).Infof("Skipping %s from delete consideration - the node is marked as no scale down", node.Name)
+		klogx.Core.V(1).Infof("Skipping %s from delete consideration - the node is marked as no scale down", node.Name)
 		return simulator.ScaleDownDisabledAnnotation, nil
 	}
 
@@ -139,27 +144,42 @@ func (c *Checker) unremovableReasonAndNodeUtilization(context *context.Autoscali
 	}
 
 	gpuConfig := context.CloudProvider.GetNodeGpuConfig(node)
-	utilInfo, err := utilization.Calculate(nodeInfo, ignoreDaemonSetsUtilization, context.IgnoreMirrorPodsUtilization, gpuConfig, timestamp)
+	utilInfo, err := utilization.Calculate(nodeInfo, ignoreDaemonSetsUtilization, context.IgnoreMirrorPodsUtilization, gpuConfig, timestamp, context.TerminatingPodThreshold)
 	if err != nil {
 		klog.Warningf("Failed to calculate utilization for %s: %v", node.Name, err)
 	}
+	if measured, found := gpuUtilization[node.Name]; found && gpuConfig != nil {
+		klogx.Core.V(4).Infof("Node %s - using measured GPU utilization %f instead of requested %f", node.Name, measured, utilInfo.GpuUtil)
+		utilInfo = utilization.Info{GpuUtil: measured, ResourceName: gpuConfig.ResourceName, Utilization: measured}
+	}
 
 	// If scale down of unready nodes is disabled, skip the node if it is unready
 	if !context.ScaleDownUnreadyEnabled {
 		ready, _, _ := kube_util.GetReadinessState(node)
 		if !ready {
-			klog.V(4).Infof("Skipping unready node %s from delete consideration - scale-down of unready nodes is disabled", node.Name)
+			klogx.Core.V(4).Infof("Skipping unready node %s from delete consideration - scale-down of unready nodes is disabled", node.Name)
 			return simulator.ScaleDownUnreadyDisabled, nil
 		}
 	}
 
+	// Nodes reporting a problem condition are removed regardless of their utilization, as long as the hourly
+	// recycling limit allows it.
+	if condition, found := context.NodeProblemTracker.ProblemCondition(node); found {
+		if *problemNodesQuota > 0 {
+			*problemNodesQuota--
+			klogx.Core.V(2).Infof("Node %s has problem condition %s, considering it for removal regardless of utilization", node.Name, condition)
+			return simulator.NoReason, &utilInfo
+		}
+		klogx.Core.V(2).Infof("Node %s has problem condition %s, but the limit of recycled problem nodes per hour has been reached", node.Name, condition)
+	}
+
 	underutilized, err := c.isNodeBelowUtilizationThreshold(context, node, nodeGroup, utilInfo)
 	if err != nil {
 		klog.Warningf("Failed to check utilization thresholds for %s: %v", node.Name, err)
 		return simulator.UnexpectedError, nil
 	}
 	if !underutilized {
-		klog.V(4).Infof("Node %s is not suitable for removal - %s utilization too big (%f)", node.Name, utilInfo.ResourceName, utilInfo.Utilization)
+		klogx.Core.V(4).Infof("Node %s is not suitable for removal - %s utilization too big (%f)", node.Name, utilInfo.ResourceName, utilInfo.Utilization)
 		return simulator.NotUnderutilized, &utilInfo
 	}
 
@@ -168,6 +188,20 @@ func (c *Checker) unremovableReasonAndNodeUtilization(context *context.Autoscali
 	return simulator.NoReason, &utilInfo
 }
 
+// measuredGpuUtilization returns the measured GPU utilization of nodes by node name, or nil if no source is
+// configured or it fails, in which case the utilization of GPU nodes is computed from GPU requests.
+func measuredGpuUtilization(context *context.AutoscalingContext) map[string]float64 {
+	if context.GpuUtilizationSource == nil {
+		return nil
+	}
+	gpuUtilization, err := context.GpuUtilizationSource.NodeUtilization()
+	if err != nil {
+		klog.Warningf("Failed to get measured GPU utilization, falling back to GPU requests: %v", err)
+		return nil
+	}
+	return gpuUtilization
+}
+
 // isNodeBelowUtilizationThreshold determines if a given node utilization is below threshold.
 func (c *Checker) isNodeBelowUtilizationThreshold(context *context.AutoscalingContext, node *apiv1.Node, nodeGroup cloudprovider.NodeGroup, utilInfo utilization.Info) (bool, error) {
 	var threshold float64
@@ -194,3 +228,9 @@ func (c *Checker) isNodeBelowUtilizationThreshold(context *context.AutoscalingCo
 func HasNoScaleDownAnnotation(node *apiv1.Node) bool {
 	return node.Annotations[ScaleDownDisabledKey] == "true"
 }
+
+// HasUrgentScaleDownAnnotation checks whether the node has an annotation requesting its scale down regardless of its
+// billing period.
+func HasUrgentScaleDownAnnotation(node *apiv1.Node) bool {
+	return node.Annotations[ScaleDownUrgentKey] == "true"
+}
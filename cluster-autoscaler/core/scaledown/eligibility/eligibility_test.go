@@ -23,6 +23,7 @@ import (
 
 	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
 	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/nodeproblem"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/unremovable"
 	. "k8s.io/autoscaler/cluster-autoscaler/core/test"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupconfig"
@@ -183,3 +184,110 @@ func TestFilterOutUnremovable(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterOutUnremovableProblemNodes(t *testing.T) {
+	now := time.Now()
+	problemNode := func(name string) *apiv1.Node {
+		node := BuildTestNode(name, 1000, 10)
+		SetNodeReadyState(node, true, time.Time{})
+		node.Status.Conditions = append(node.Status.Conditions, apiv1.NodeCondition{Type: "KernelDeadlock", Status: apiv1.ConditionTrue})
+		return node
+	}
+	p1 := problemNode("p1")
+	p2 := problemNode("p2")
+	nodes := []*apiv1.Node{p1, p2}
+	bigPod1 := BuildTestPod("bigPod1", 600, 0)
+	bigPod1.Spec.NodeName = "p1"
+	bigPod2 := BuildTestPod("bigPod2", 600, 0)
+	bigPod2.Spec.NodeName = "p2"
+
+	options := config.AutoscalingOptions{
+		UnremovableNodeRecheckTimeout: 5 * time.Minute,
+		NodeGroupDefaults: config.NodeGroupAutoscalingOptions{
+			ScaleDownUtilizationThreshold:    config.DefaultScaleDownUtilizationThreshold,
+			ScaleDownGpuUtilizationThreshold: config.DefaultScaleDownGpuUtilizationThreshold,
+		},
+	}
+	c := NewChecker(nodegroupconfig.NewDefaultNodeGroupConfigProcessor(options.NodeGroupDefaults))
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 2)
+	for _, n := range nodes {
+		provider.AddNode("ng1", n)
+	}
+	context, err := NewScaleTestAutoscalingContext(options, &fake.Clientset{}, nil, provider, nil, nil)
+	if err != nil {
+		t.Fatalf("Could not create autoscaling context: %v", err)
+	}
+	clustersnapshot.InitializeClusterSnapshotOrDie(t, context.ClusterSnapshot, nodes, []*apiv1.Pod{bigPod1, bigPod2})
+
+	// Highly utilized nodes are not removable without problem conditions configured.
+	got, _, _ := c.FilterOutUnremovable(&context, nodes, now, unremovable.NewNodes())
+	assert.Equal(t, []string{}, got)
+
+	// Only one problem node is allowed by the hourly limit.
+	context.NodeProblemTracker = nodeproblem.NewTracker([]string{"KernelDeadlock"}, 1)
+	got, _, _ = c.FilterOutUnremovable(&context, nodes, now, unremovable.NewNodes())
+	assert.Equal(t, []string{"p1"}, got)
+
+	// No problem nodes are allowed once the limit is used up.
+	context.NodeProblemTracker.RegisterRecycled(now)
+	got, _, _ = c.FilterOutUnremovable(&context, nodes, now, unremovable.NewNodes())
+	assert.Equal(t, []string{}, got)
+}
+
+type fakeGpuUtilizationSource map[string]float64
+
+func (f fakeGpuUtilizationSource) NodeUtilization() (map[string]float64, error) {
+	return f, nil
+}
+
+func TestFilterOutUnremovableMeasuredGpuUtilization(t *testing.T) {
+	now := time.Now()
+	gpuNode := func(name string) *apiv1.Node {
+		node := BuildTestNode(name, 1000, 10)
+		AddGpusToNode(node, 1)
+		SetNodeReadyState(node, true, time.Time{})
+		return node
+	}
+	idle := gpuNode("idle")
+	busy := gpuNode("busy")
+	unmeasured := gpuNode("unmeasured")
+	nodes := []*apiv1.Node{idle, busy, unmeasured}
+	var pods []*apiv1.Pod
+	for _, node := range nodes {
+		pod := BuildTestPod("gpu-pod-"+node.Name, 100, 0)
+		RequestGpuForPod(pod, 1)
+		pod.Spec.NodeName = node.Name
+		pods = append(pods, pod)
+	}
+
+	options := config.AutoscalingOptions{
+		UnremovableNodeRecheckTimeout: 5 * time.Minute,
+		NodeGroupDefaults: config.NodeGroupAutoscalingOptions{
+			ScaleDownUtilizationThreshold:    config.DefaultScaleDownUtilizationThreshold,
+			ScaleDownGpuUtilizationThreshold: config.DefaultScaleDownGpuUtilizationThreshold,
+		},
+	}
+	c := NewChecker(nodegroupconfig.NewDefaultNodeGroupConfigProcessor(options.NodeGroupDefaults))
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 3)
+	for _, n := range nodes {
+		provider.AddNode("ng1", n)
+	}
+	context, err := NewScaleTestAutoscalingContext(options, &fake.Clientset{}, nil, provider, nil, nil)
+	if err != nil {
+		t.Fatalf("Could not create autoscaling context: %v", err)
+	}
+	clustersnapshot.InitializeClusterSnapshotOrDie(t, context.ClusterSnapshot, nodes, pods)
+
+	// All GPUs are requested, so no node is removable based on requests.
+	got, _, _ := c.FilterOutUnremovable(&context, nodes, now, unremovable.NewNodes())
+	assert.Equal(t, []string{}, got)
+
+	// Nodes with measurements are judged by their measured utilization.
+	context.GpuUtilizationSource = fakeGpuUtilizationSource{"idle": 0.05, "busy": 0.9}
+	got, utilInfo, _ := c.FilterOutUnremovable(&context, nodes, now, unremovable.NewNodes())
+	assert.Equal(t, []string{"idle"}, got)
+	assert.Equal(t, 0.05, utilInfo["idle"].Utilization)
+	assert.Equal(t, 1.0, utilInfo["unmeasured"].Utilization)
+}
@@ -0,0 +1,298 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldelete
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	klog "k8s.io/klog/v2"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const (
+	// NodeGroupId is the id of the node group holding the nodes without a cloud provider node group.
+	NodeGroupId = "external-delete"
+
+	defaultTimeout = 10 * time.Second
+)
+
+// Request is the body POSTed to the webhook for every node to delete.
+type Request struct {
+	// NodeName is the name of the drained node.
+	NodeName string `json:"nodeName"`
+	// ProviderID is the provider id of the node, if any.
+	ProviderID string `json:"providerID,omitempty"`
+	// Labels are the labels of the node.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// IsExternalNodeGroup returns true if the node group is the external node group. The external
+// node group is not listed by NodeGroups, it is only ever returned by NodeGroupForNode, so that
+// scale-up and cluster state ignore it.
+func IsExternalNodeGroup(nodeGroup cloudprovider.NodeGroup) bool {
+	_, ok := nodeGroup.(*externalNodeGroup)
+	return ok
+}
+
+// cloudProvider wraps a cloud provider, placing the nodes that match the node selector and don't
+// belong to any of its node groups in an external node group that deletes nodes by calling a webhook.
+type cloudProvider struct {
+	cloudprovider.CloudProvider
+	selector labels.Selector
+	external *externalNodeGroup
+}
+
+// NewCloudProvider returns a cloud provider that treats the nodes matching the selector without a
+// node group of the wrapped cloud provider as scale-down candidates, deleting them with a POST to
+// the webhook URL. The webhook is expected to remove the machine and its Node object. Nodes that
+// don't match the selector are left alone.
+func NewCloudProvider(wrapped cloudprovider.CloudProvider, nodeLister kube_util.NodeLister, selector labels.Selector, url string, timeout time.Duration) cloudprovider.CloudProvider {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &cloudProvider{
+		CloudProvider: wrapped,
+		selector:      selector,
+		external: &externalNodeGroup{
+			url:        url,
+			client:     &http.Client{Timeout: timeout},
+			nodeLister: nodeLister,
+			nodes:      make(map[string]*apiv1.Node),
+		},
+	}
+}
+
+// NodeGroupForNode returns the node group of the wrapped cloud provider, or the external node
+// group if the node doesn't belong to any and matches the selector.
+func (p *cloudProvider) NodeGroupForNode(node *apiv1.Node) (cloudprovider.NodeGroup, error) {
+	nodeGroup, err := p.CloudProvider.NodeGroupForNode(node)
+	if err != nil {
+		return nil, err
+	}
+	if p.isExternal(node, nodeGroup) {
+		return p.external, nil
+	}
+	return nodeGroup, nil
+}
+
+// isExternal returns true if the node, whose node group in the wrapped cloud provider is given,
+// belongs to the external node group.
+func (p *cloudProvider) isExternal(node *apiv1.Node, nodeGroup cloudprovider.NodeGroup) bool {
+	if nodeGroup != nil && !reflect.ValueOf(nodeGroup).IsNil() {
+		return false
+	}
+	return p.selector.Matches(labels.Set(node.Labels))
+}
+
+// HasInstance returns true for the nodes of the external node group, which are managed outside
+// of the cloud provider.
+func (p *cloudProvider) HasInstance(node *apiv1.Node) (bool, error) {
+	if p.external.has(node.Name) {
+		return true, nil
+	}
+	return p.CloudProvider.HasInstance(node)
+}
+
+// Refresh refreshes the wrapped cloud provider, then the nodes of the external node group.
+func (p *cloudProvider) Refresh() error {
+	if err := p.CloudProvider.Refresh(); err != nil {
+		return err
+	}
+	nodes, err := p.external.nodeLister.List()
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %v", err)
+	}
+	var external []*apiv1.Node
+	for _, node := range nodes {
+		nodeGroup, err := p.CloudProvider.NodeGroupForNode(node)
+		if err != nil {
+			klog.Warningf("Failed to get node group for %s: %v", node.Name, err)
+			continue
+		}
+		if p.isExternal(node, nodeGroup) {
+			external = append(external, node)
+		}
+	}
+	p.external.setNodes(external)
+	return nil
+}
+
+// externalNodeGroup holds the nodes without a cloud provider node group. It can't be scaled up,
+// its nodes are deleted by the webhook.
+type externalNodeGroup struct {
+	url        string
+	client     *http.Client
+	nodeLister kube_util.NodeLister
+
+	sync.Mutex
+	nodes map[string]*apiv1.Node
+}
+
+func (g *externalNodeGroup) setNodes(nodes []*apiv1.Node) {
+	g.Lock()
+	defer g.Unlock()
+	g.nodes = make(map[string]*apiv1.Node, len(nodes))
+	for _, node := range nodes {
+		g.nodes[node.Name] = node
+	}
+}
+
+func (g *externalNodeGroup) has(nodeName string) bool {
+	g.Lock()
+	defer g.Unlock()
+	_, found := g.nodes[nodeName]
+	return found
+}
+
+// MaxSize returns the current size, the external node group can't be scaled up.
+func (g *externalNodeGroup) MaxSize() int {
+	g.Lock()
+	defer g.Unlock()
+	return len(g.nodes)
+}
+
+// MinSize returns 0, all nodes of the external node group can be deleted.
+func (g *externalNodeGroup) MinSize() int {
+	return 0
+}
+
+// TargetSize returns the number of nodes of the external node group.
+func (g *externalNodeGroup) TargetSize() (int, error) {
+	g.Lock()
+	defer g.Unlock()
+	return len(g.nodes), nil
+}
+
+// IncreaseSize is not supported, the external node group can't be scaled up.
+func (g *externalNodeGroup) IncreaseSize(delta int) error {
+	return cloudprovider.ErrNotImplemented
+}
+
+// DeleteNodes deletes the nodes by calling the webhook for each of them.
+func (g *externalNodeGroup) DeleteNodes(nodes []*apiv1.Node) error {
+	for _, node := range nodes {
+		if !g.has(node.Name) {
+			return fmt.Errorf("node %s doesn't belong to node group %s", node.Name, NodeGroupId)
+		}
+		if err := g.callWebhook(node); err != nil {
+			return fmt.Errorf("failed to delete node %s with the external delete webhook: %v", node.Name, err)
+		}
+		klog.V(2).Infof("External delete webhook accepted the deletion of node %s", node.Name)
+		g.Lock()
+		delete(g.nodes, node.Name)
+		g.Unlock()
+	}
+	return nil
+}
+
+// ForceDeleteNodes deletes the nodes like DeleteNodes, there is no size constraint to bypass.
+func (g *externalNodeGroup) ForceDeleteNodes(nodes []*apiv1.Node) error {
+	return g.DeleteNodes(nodes)
+}
+
+func (g *externalNodeGroup) callWebhook(node *apiv1.Node) error {
+	body, err := json.Marshal(Request{
+		NodeName:   node.Name,
+		ProviderID: node.Spec.ProviderID,
+		Labels:     node.Labels,
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := g.client.Post(g.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %s", resp.Status)
+	}
+	return nil
+}
+
+// DecreaseTargetSize is not supported, the external node group has no pending nodes.
+func (g *externalNodeGroup) DecreaseTargetSize(delta int) error {
+	return cloudprovider.ErrNotImplemented
+}
+
+// Id returns the id of the external node group.
+func (g *externalNodeGroup) Id() string {
+	return NodeGroupId
+}
+
+// Debug returns a string describing the external node group.
+func (g *externalNodeGroup) Debug() string {
+	size, _ := g.TargetSize()
+	return fmt.Sprintf("%s (size %d, webhook %s)", NodeGroupId, size, g.url)
+}
+
+// Nodes returns the nodes of the external node group. The instances have no status, so they are
+// never considered as not registered.
+func (g *externalNodeGroup) Nodes() ([]cloudprovider.Instance, error) {
+	g.Lock()
+	defer g.Unlock()
+	instances := make([]cloudprovider.Instance, 0, len(g.nodes))
+	for _, node := range g.nodes {
+		id := node.Spec.ProviderID
+		if id == "" {
+			id = node.Name
+		}
+		instances = append(instances, cloudprovider.Instance{Id: id})
+	}
+	return instances, nil
+}
+
+// TemplateNodeInfo is not supported, the external node group can't be scaled up.
+func (g *externalNodeGroup) TemplateNodeInfo() (*schedulerframework.NodeInfo, error) {
+	return nil, cloudprovider.ErrNotImplemented
+}
+
+// Exist returns true, the external node group always exists.
+func (g *externalNodeGroup) Exist() bool {
+	return true
+}
+
+// Create is not supported.
+func (g *externalNodeGroup) Create() (cloudprovider.NodeGroup, error) {
+	return nil, cloudprovider.ErrNotImplemented
+}
+
+// Delete is not supported.
+func (g *externalNodeGroup) Delete() error {
+	return cloudprovider.ErrNotImplemented
+}
+
+// Autoprovisioned returns false, the external node group is not created by the autoscaler.
+func (g *externalNodeGroup) Autoprovisioned() bool {
+	return false
+}
+
+// GetOptions returns ErrNotImplemented to use the default options.
+func (g *externalNodeGroup) GetOptions(defaults config.NodeGroupAutoscalingOptions) (*config.NodeGroupAutoscalingOptions, error) {
+	return nil, cloudprovider.ErrNotImplemented
+}
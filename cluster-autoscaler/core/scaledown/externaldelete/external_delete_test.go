@@ -0,0 +1,154 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldelete
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+var testSelector = labels.SelectorFromSet(labels.Set{"external-delete": "true"})
+
+func TestExternalNodeGroup(t *testing.T) {
+	managed := BuildTestNode("managed", 1000, 1000)
+	unmanaged := BuildTestNode("unmanaged", 1000, 1000)
+	unmanaged.Spec.ProviderID = "baremetal://rack1/unmanaged"
+	unmanaged.Labels = map[string]string{"external-delete": "true"}
+	// Nodes without a node group that don't match the selector are left alone.
+	unselected := BuildTestNode("unselected", 1000, 1000)
+
+	wrapped := testprovider.NewTestCloudProvider(nil, nil)
+	wrapped.AddNodeGroup("ng1", 0, 10, 1)
+	wrapped.AddNode("ng1", managed)
+
+	provider := NewCloudProvider(wrapped, kube_util.NewTestNodeLister([]*apiv1.Node{managed, unmanaged, unselected}), testSelector, "http://127.0.0.1:1", time.Second)
+	assert.NoError(t, provider.Refresh())
+
+	nodeGroup, err := provider.NodeGroupForNode(unselected)
+	assert.NoError(t, err)
+	assert.Nil(t, nodeGroup)
+
+	nodeGroup, err = provider.NodeGroupForNode(managed)
+	assert.NoError(t, err)
+	assert.Equal(t, "ng1", nodeGroup.Id())
+	assert.False(t, IsExternalNodeGroup(nodeGroup))
+
+	nodeGroup, err = provider.NodeGroupForNode(unmanaged)
+	assert.NoError(t, err)
+	assert.Equal(t, NodeGroupId, nodeGroup.Id())
+	assert.True(t, IsExternalNodeGroup(nodeGroup))
+	assert.Len(t, provider.NodeGroups(), 1)
+
+	size, err := nodeGroup.TargetSize()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, size)
+	assert.Equal(t, 0, nodeGroup.MinSize())
+	assert.Equal(t, 1, nodeGroup.MaxSize())
+	assert.Error(t, nodeGroup.IncreaseSize(1))
+
+	instances, err := nodeGroup.Nodes()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"baremetal://rack1/unmanaged"}, instanceIds(instances))
+
+	hasInstance, err := provider.HasInstance(unmanaged)
+	assert.NoError(t, err)
+	assert.True(t, hasInstance)
+}
+
+func TestExternalNodeGroupDeleteNodes(t *testing.T) {
+	testCases := []struct {
+		name        string
+		status      int
+		wantErr     bool
+		wantRemoved bool
+	}{
+		{
+			name:        "webhook accepts the deletion",
+			status:      http.StatusOK,
+			wantRemoved: true,
+		},
+		{
+			name:    "webhook fails",
+			status:  http.StatusInternalServerError,
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests []Request
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				request := Request{}
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+				requests = append(requests, request)
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			unmanaged := BuildTestNode("unmanaged", 1000, 1000)
+			unmanaged.Labels = map[string]string{"rack": "rack1", "external-delete": "true"}
+			provider := NewCloudProvider(testprovider.NewTestCloudProvider(nil, nil), kube_util.NewTestNodeLister([]*apiv1.Node{unmanaged}), testSelector, server.URL, time.Second)
+			assert.NoError(t, provider.Refresh())
+			nodeGroup, err := provider.NodeGroupForNode(unmanaged)
+			assert.NoError(t, err)
+
+			err = nodeGroup.DeleteNodes([]*apiv1.Node{unmanaged})
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, []Request{{NodeName: "unmanaged", ProviderID: "unmanaged", Labels: map[string]string{"rack": "rack1", "external-delete": "true"}}}, requests)
+
+			size, err := nodeGroup.TargetSize()
+			assert.NoError(t, err)
+			if tc.wantRemoved {
+				assert.Equal(t, 0, size)
+			} else {
+				assert.Equal(t, 1, size)
+			}
+		})
+	}
+}
+
+func TestExternalNodeGroupDeleteUnknownNode(t *testing.T) {
+	provider := NewCloudProvider(testprovider.NewTestCloudProvider(nil, nil), kube_util.NewTestNodeLister(nil), testSelector, "http://127.0.0.1:1", time.Second)
+	assert.NoError(t, provider.Refresh())
+	node := BuildTestNode("new", 1000, 1000)
+	nodeGroup, err := provider.NodeGroupForNode(node)
+	assert.NoError(t, err)
+	assert.Error(t, nodeGroup.DeleteNodes([]*apiv1.Node{node}))
+}
+
+func instanceIds(instances []cloudprovider.Instance) []string {
+	var ids []string
+	for _, instance := range instances {
+		ids = append(ids, instance.Id)
+	}
+	return ids
+}
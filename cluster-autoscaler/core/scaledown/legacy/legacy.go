@@ -27,6 +27,7 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/eligibility"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/resource"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/topology"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/unneeded"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/unremovable"
 	"k8s.io/autoscaler/cluster-autoscaler/metrics"
@@ -39,6 +40,7 @@ import (
 
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
 	klog "k8s.io/klog/v2"
 )
 
@@ -116,6 +118,9 @@ func (sd *ScaleDown) UpdateUnneededNodes(
 
 	// Phase1 - look at the nodes utilization. Calculate the utilization
 	// only for the managed nodes.
+	if sd.context.PdbChangeTracker != nil {
+		sd.unremovableNodes.RecheckBlockedByPdbs(sd.context.PdbChangeTracker.PopRelaxedNamespaces())
+	}
 	sd.unremovableNodes.Update(sd.context.ClusterSnapshot.NodeInfos(), timestamp)
 	currentlyUnneededNodeNames, utilizationMap, ineligible := sd.eligibilityChecker.FilterOutUnremovable(sd.context, scaleDownCandidates, timestamp, sd.unremovableNodes)
 	for _, n := range ineligible {
@@ -165,7 +170,7 @@ func (sd *ScaleDown) UpdateUnneededNodes(
 	}
 	if additionalCandidatesCount > 0 {
 		// Look for additional nodes to remove among the rest of nodes.
-		klog.V(3).Infof("Finding additional %v candidates for scale down.", additionalCandidatesCount)
+		klogx.Core.V(3).Infof("Finding additional %v candidates for scale down.", additionalCandidatesCount)
 		additionalNodesToRemove, additionalUnremovable :=
 			sd.removalSimulator.FindNodesToRemove(
 				currentNonCandidates[:additionalCandidatesPoolSize],
@@ -192,7 +197,7 @@ func (sd *ScaleDown) UpdateUnneededNodes(
 		for _, unremovableNode := range unremovable {
 			sd.unremovableNodes.AddTimeout(unremovableNode, unremovableTimeout)
 		}
-		klog.V(1).Infof("%v nodes found to be unremovable in simulation, will re-check them at %v", len(unremovable), unremovableTimeout)
+		klogx.Core.V(1).Infof("%v nodes found to be unremovable in simulation, will re-check them at %v", len(unremovable), unremovableTimeout)
 	}
 
 	// This method won't always check all nodes, so let's give a generic reason for all nodes that weren't checked.
@@ -282,7 +287,8 @@ func (sd *ScaleDown) NodesToDelete(currentTime time.Time) (_, drain []*apiv1.Nod
 	}
 
 	scaleDownResourcesLeft := sd.resourceLimitsFinder.LimitsLeft(sd.context, allNodes, resourceLimiter, currentTime)
-	empty, nonEmpty, unremovable := sd.unneededNodes.RemovableAt(sd.context, currentTime, scaleDownResourcesLeft, resourceLimiter.GetResources(), sd.nodeDeletionTracker)
+	readyNodesLeft := topology.NewReadyNodesLeft(sd.context.ScaleDownMinReadyNodesPerDomain, allNodes)
+	empty, nonEmpty, unremovable := sd.unneededNodes.RemovableAt(sd.context, currentTime, scaleDownResourcesLeft, resourceLimiter.GetResources(), readyNodesLeft, sd.nodeDeletionTracker)
 	for _, u := range unremovable {
 		sd.unremovableNodes.Add(u)
 	}
@@ -295,7 +301,7 @@ func (sd *ScaleDown) NodesToDelete(currentTime time.Time) (_, drain []*apiv1.Nod
 	}
 
 	if len(candidateNames) == 0 {
-		klog.V(1).Infof("No candidates for scale down")
+		klogx.Core.V(1).Infof("No candidates for scale down")
 		return nil, nil, status.ScaleDownNoUnneeded, nil
 	}
 
@@ -328,7 +334,7 @@ func (sd *ScaleDown) NodesToDelete(currentTime time.Time) (_, drain []*apiv1.Nod
 
 	nodesToRemove = sd.processors.ScaleDownSetProcessor.GetNodesToRemove(sd.context, nodesToRemove, 1)
 	if len(nodesToRemove) == 0 {
-		klog.V(1).Infof("No node to remove")
+		klogx.Core.V(1).Infof("No node to remove")
 		return nil, nil, status.ScaleDownNoNodeDeleted, nil
 	}
 	toRemove := nodesToRemove[0]
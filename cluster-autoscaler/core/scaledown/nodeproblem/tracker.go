@@ -0,0 +1,106 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeproblem
+
+import (
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+const recycleWindow = time.Hour
+
+// Tracker identifies nodes reporting configured problem conditions (e.g. set by
+// Node Problem Detector) and limits how many of them are recycled per hour.
+// A nil Tracker reports no problem nodes.
+type Tracker struct {
+	conditions map[apiv1.NodeConditionType]bool
+	maxPerHour int
+
+	sync.Mutex
+	recycled []time.Time
+}
+
+// NewTracker returns a Tracker for the given condition types. Returns nil if no
+// conditions are configured.
+func NewTracker(conditions []string, maxPerHour int) *Tracker {
+	if len(conditions) == 0 {
+		return nil
+	}
+	t := &Tracker{
+		conditions: make(map[apiv1.NodeConditionType]bool, len(conditions)),
+		maxPerHour: maxPerHour,
+	}
+	for _, c := range conditions {
+		t.conditions[apiv1.NodeConditionType(c)] = true
+	}
+	return t
+}
+
+// ProblemCondition returns the first configured problem condition that is true on the node.
+func (t *Tracker) ProblemCondition(node *apiv1.Node) (apiv1.NodeConditionType, bool) {
+	if t == nil {
+		return "", false
+	}
+	for _, c := range node.Status.Conditions {
+		if t.conditions[c.Type] && c.Status == apiv1.ConditionTrue {
+			return c.Type, true
+		}
+	}
+	return "", false
+}
+
+// RemainingRecycles returns how many more problem nodes can be recycled within the current hour.
+func (t *Tracker) RemainingRecycles(now time.Time) int {
+	if t == nil {
+		return 0
+	}
+	t.Lock()
+	defer t.Unlock()
+	t.dropExpired(now)
+	if remaining := t.maxPerHour - len(t.recycled); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// RegisterRecycled records that a problem node has been scheduled for deletion.
+func (t *Tracker) RegisterRecycled(now time.Time) {
+	if t == nil {
+		return
+	}
+	t.Lock()
+	defer t.Unlock()
+	t.dropExpired(now)
+	t.recycled = append(t.recycled, now)
+}
+
+// ScaleDownEarlierThan return true if node1 reports a problem condition and node2 doesn't.
+func (t *Tracker) ScaleDownEarlierThan(node1, node2 *apiv1.Node) bool {
+	_, problem1 := t.ProblemCondition(node1)
+	_, problem2 := t.ProblemCondition(node2)
+	return problem1 && !problem2
+}
+
+func (t *Tracker) dropExpired(now time.Time) {
+	i := 0
+	for i < len(t.recycled) && now.Sub(t.recycled[i]) >= recycleWindow {
+		i++
+	}
+	t.recycled = t.recycled[i:]
+}
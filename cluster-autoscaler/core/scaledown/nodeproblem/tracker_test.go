@@ -0,0 +1,74 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeproblem
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func nodeWithCondition(name string, conditionType apiv1.NodeConditionType, status apiv1.ConditionStatus) *apiv1.Node {
+	node := BuildTestNode(name, 1000, 1000)
+	node.Status.Conditions = append(node.Status.Conditions, apiv1.NodeCondition{Type: conditionType, Status: status})
+	return node
+}
+
+func TestProblemCondition(t *testing.T) {
+	tracker := NewTracker([]string{"KernelDeadlock", "ReadonlyFilesystem"}, 1)
+
+	condition, found := tracker.ProblemCondition(nodeWithCondition("n1", "KernelDeadlock", apiv1.ConditionTrue))
+	assert.True(t, found)
+	assert.Equal(t, apiv1.NodeConditionType("KernelDeadlock"), condition)
+
+	_, found = tracker.ProblemCondition(nodeWithCondition("n2", "KernelDeadlock", apiv1.ConditionFalse))
+	assert.False(t, found)
+
+	_, found = tracker.ProblemCondition(nodeWithCondition("n3", "FrequentKubeletRestart", apiv1.ConditionTrue))
+	assert.False(t, found)
+
+	disabled := NewTracker(nil, 1)
+	assert.Nil(t, disabled)
+	_, found = disabled.ProblemCondition(nodeWithCondition("n4", "KernelDeadlock", apiv1.ConditionTrue))
+	assert.False(t, found)
+	assert.Equal(t, 0, disabled.RemainingRecycles(time.Now()))
+}
+
+func TestRemainingRecycles(t *testing.T) {
+	now := time.Now()
+	tracker := NewTracker([]string{"KernelDeadlock"}, 2)
+	assert.Equal(t, 2, tracker.RemainingRecycles(now))
+
+	tracker.RegisterRecycled(now)
+	tracker.RegisterRecycled(now.Add(10 * time.Minute))
+	assert.Equal(t, 0, tracker.RemainingRecycles(now.Add(30*time.Minute)))
+	assert.Equal(t, 1, tracker.RemainingRecycles(now.Add(time.Hour)))
+	assert.Equal(t, 2, tracker.RemainingRecycles(now.Add(2*time.Hour)))
+}
+
+func TestScaleDownEarlierThan(t *testing.T) {
+	tracker := NewTracker([]string{"KernelDeadlock"}, 1)
+	problem := nodeWithCondition("problem", "KernelDeadlock", apiv1.ConditionTrue)
+	healthy := BuildTestNode("healthy", 1000, 1000)
+
+	assert.True(t, tracker.ScaleDownEarlierThan(problem, healthy))
+	assert.False(t, tracker.ScaleDownEarlierThan(healthy, problem))
+	assert.False(t, tracker.ScaleDownEarlierThan(problem, problem))
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdb
+
+import (
+	"sync"
+
+	policyv1 "k8s.io/api/policy/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ChangeTracker watches PDBs and records the namespaces in which they were
+// relaxed, i.e. deleted, updated to allow more disruptions, or whose spec
+// changed, so that the nodes they kept from being scaled down can be re-checked
+// right away.
+type ChangeTracker struct {
+	mutex   sync.Mutex
+	relaxed map[string]bool
+}
+
+// NewChangeTracker returns a ChangeTracker watching the PDBs of the given informer.
+func NewChangeTracker(informer cache.SharedIndexInformer) (*ChangeTracker, error) {
+	t := &ChangeTracker{
+		relaxed: make(map[string]bool),
+	}
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: t.onUpdate,
+		DeleteFunc: t.onDelete,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *ChangeTracker) onUpdate(oldObj, newObj interface{}) {
+	oldPdb, ok := oldObj.(*policyv1.PodDisruptionBudget)
+	if !ok {
+		return
+	}
+	newPdb, ok := newObj.(*policyv1.PodDisruptionBudget)
+	if !ok {
+		return
+	}
+	if newPdb.Status.DisruptionsAllowed > oldPdb.Status.DisruptionsAllowed || !apiequality.Semantic.DeepEqual(oldPdb.Spec, newPdb.Spec) {
+		t.markRelaxed(newPdb.Namespace)
+	}
+}
+
+func (t *ChangeTracker) onDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	if pdb, ok := obj.(*policyv1.PodDisruptionBudget); ok {
+		t.markRelaxed(pdb.Namespace)
+	}
+}
+
+func (t *ChangeTracker) markRelaxed(namespace string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.relaxed[namespace] = true
+}
+
+// PopRelaxedNamespaces returns the namespaces in which PDBs were relaxed since
+// the previous call.
+func (t *ChangeTracker) PopRelaxedNamespaces() map[string]bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	relaxed := t.relaxed
+	t.relaxed = make(map[string]bool)
+	return relaxed
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestChangeTracker(t *testing.T) {
+	one := intstr.FromInt(1)
+	two := intstr.FromInt(2)
+	buildPdb := func(namespace string, minAvailable *intstr.IntOrString, disruptionsAllowed int32) *policyv1.PodDisruptionBudget {
+		return &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "pdb", Namespace: namespace},
+			Spec:       policyv1.PodDisruptionBudgetSpec{MinAvailable: minAvailable},
+			Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: disruptionsAllowed},
+		}
+	}
+
+	tracker := &ChangeTracker{relaxed: make(map[string]bool)}
+
+	// fewer disruptions allowed doesn't unblock anything.
+	tracker.onUpdate(buildPdb("ns1", &one, 1), buildPdb("ns1", &one, 0))
+	assert.Empty(t, tracker.PopRelaxedNamespaces())
+
+	tracker.onUpdate(buildPdb("ns1", &one, 0), buildPdb("ns1", &one, 1))
+	tracker.onUpdate(buildPdb("ns2", &two, 0), buildPdb("ns2", &one, 0))
+	assert.Equal(t, map[string]bool{"ns1": true, "ns2": true}, tracker.PopRelaxedNamespaces())
+	assert.Empty(t, tracker.PopRelaxedNamespaces())
+
+	tracker.onDelete(buildPdb("ns3", &one, 0))
+	tracker.onDelete(cache.DeletedFinalStateUnknown{Key: "ns4/pdb", Obj: buildPdb("ns4", &one, 0)})
+	assert.Equal(t, map[string]bool{"ns3": true, "ns4": true}, tracker.PopRelaxedNamespaces())
+}
@@ -28,8 +28,10 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/eligibility"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/pdb"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/resource"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/topology"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/unneeded"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/unremovable"
+	"k8s.io/autoscaler/cluster-autoscaler/metrics"
 	"k8s.io/autoscaler/cluster-autoscaler/processors"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/nodes"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator"
@@ -39,6 +41,7 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/scheduling"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/utilization"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
 	pod_util "k8s.io/autoscaler/cluster-autoscaler/utils/pod"
 	klog "k8s.io/klog/v2"
 )
@@ -147,7 +150,8 @@ func (p *Planner) NodesToDelete(_ time.Time) (empty, needDrain []*apiv1.Node) {
 		return nil, nil
 	}
 	limitsLeft := p.resourceLimitsFinder.LimitsLeft(p.context, nodes, resourceLimiter, p.latestUpdate)
-	emptyRemovable, needDrainRemovable, unremovable := p.unneededNodes.RemovableAt(p.context, p.latestUpdate, limitsLeft, resourceLimiter.GetResources(), p.actuationStatus)
+	readyNodesLeft := topology.NewReadyNodesLeft(p.context.ScaleDownMinReadyNodesPerDomain, nodes)
+	emptyRemovable, needDrainRemovable, unremovable := p.unneededNodes.RemovableAt(p.context, p.latestUpdate, limitsLeft, resourceLimiter.GetResources(), readyNodesLeft, p.actuationStatus)
 	for _, u := range unremovable {
 		p.unremovableNodes.Add(u)
 	}
@@ -261,6 +265,9 @@ func (p *Planner) categorizeNodes(podDestinations map[string]bool, scaleDownCand
 	unremovableTimeout := p.latestUpdate.Add(p.context.AutoscalingOptions.UnremovableNodeRecheckTimeout)
 	unremovableCount := 0
 	var removableList []simulator.NodeToBeRemoved
+	if p.context.PdbChangeTracker != nil {
+		p.unremovableNodes.RecheckBlockedByPdbs(p.context.PdbChangeTracker.PopRelaxedNamespaces())
+	}
 	p.unremovableNodes.Update(p.context.ClusterSnapshot.NodeInfos(), p.latestUpdate)
 	currentlyUnneededNodeNames, utilizationMap, ineligible := p.eligibilityChecker.FilterOutUnremovable(p.context, scaleDownCandidates, p.latestUpdate, p.unremovableNodes)
 	for _, n := range ineligible {
@@ -268,16 +275,27 @@ func (p *Planner) categorizeNodes(podDestinations map[string]bool, scaleDownCand
 	}
 	p.nodeUtilizationMap = utilizationMap
 	timer := time.NewTimer(p.context.ScaleDownSimulationTimeout)
+	unneededLimit := p.unneededNodesLimit()
+	drainLimit := p.unneededDrainNodesLimit()
+	drainCount := 0
+	skippedDrainCount := 0
 
 	for i, node := range currentlyUnneededNodeNames {
 		if timedOut(timer) {
 			klog.Warningf("%d out of %d nodes skipped in scale down simulation due to timeout.", len(currentlyUnneededNodeNames)-i, len(currentlyUnneededNodeNames))
+			metrics.RegisterSkippedScaleDownSimulations(metrics.SimulationTimeout, len(currentlyUnneededNodeNames)-i)
 			break
 		}
-		if len(removableList) >= p.unneededNodesLimit() {
-			klog.V(4).Infof("%d out of %d nodes skipped in scale down simulation: there are already %d unneeded nodes so no point in looking for more.", len(currentlyUnneededNodeNames)-i, len(currentlyUnneededNodeNames), len(removableList))
+		if len(removableList) >= unneededLimit {
+			klogx.Core.V(4).Infof("%d out of %d nodes skipped in scale down simulation: there are already %d unneeded nodes so no point in looking for more.", len(currentlyUnneededNodeNames)-i, len(currentlyUnneededNodeNames), len(removableList))
+			metrics.RegisterSkippedScaleDownSimulations(metrics.UnneededNodesLimit, len(currentlyUnneededNodeNames)-i)
 			break
 		}
+		if drainLimit >= 0 && drainCount >= drainLimit && p.hasPodsToMove(node) {
+			// Only empty nodes can still make a difference.
+			skippedDrainCount++
+			continue
+		}
 		removable, unremovable := p.rs.SimulateNodeRemoval(node, podDestinations, p.latestUpdate, p.context.RemainingPdbTracker)
 		if removable != nil {
 			_, inParallel, _ := p.context.RemainingPdbTracker.CanRemovePods(removable.PodsToReschedule)
@@ -287,15 +305,22 @@ func (p *Planner) categorizeNodes(podDestinations map[string]bool, scaleDownCand
 			delete(podDestinations, removable.Node.Name)
 			p.context.RemainingPdbTracker.RemovePods(removable.PodsToReschedule)
 			removableList = append(removableList, *removable)
+			if len(removable.PodsToReschedule) > 0 {
+				drainCount++
+			}
 		}
 		if unremovable != nil {
 			unremovableCount += 1
 			p.unremovableNodes.AddTimeout(unremovable, unremovableTimeout)
 		}
 	}
+	if skippedDrainCount > 0 {
+		klogx.Core.V(4).Infof("%d nodes needing drain skipped in scale down simulation: there are already %d unneeded nodes needing drain so no point in looking for more.", skippedDrainCount, drainCount)
+		metrics.RegisterSkippedScaleDownSimulations(metrics.DrainLimit, skippedDrainCount)
+	}
 	p.unneededNodes.Update(removableList, p.latestUpdate)
 	if unremovableCount > 0 {
-		klog.V(1).Infof("%v nodes found to be unremovable in simulation, will re-check them at %v", unremovableCount, unremovableTimeout)
+		klogx.Core.V(1).Infof("%v nodes found to be unremovable in simulation, will re-check them at %v", unremovableCount, unremovableTimeout)
 	}
 }
 
@@ -325,9 +350,24 @@ func (p *Planner) categorizeNodes(podDestinations map[string]bool, scaleDownCand
 // of unneeded nodes shouldn't really exceed N*U/I - scale down will not be
 // able to keep up with removing them anyway.
 func (p *Planner) unneededNodesLimit() int {
-	n := p.context.AutoscalingOptions.MaxScaleDownParallelism
+	return p.parallelismLimit(p.context.AutoscalingOptions.MaxScaleDownParallelism, len(p.unneededNodes.AsList()))
+}
+
+// unneededDrainNodesLimit returns the number of nodes needing drain after which
+// calculating more of them is a waste of time, following the same reasoning as
+// unneededNodesLimit, with MaxDrainParallelism as N. Empty nodes are not subject
+// to this limit. Returns -1 if drain parallelism is not limited.
+func (p *Planner) unneededDrainNodesLimit() int {
+	n := p.context.AutoscalingOptions.MaxDrainParallelism
+	if n <= 0 {
+		return -1
+	}
+	return p.parallelismLimit(n, p.unneededNodes.NeedDrainCount())
+}
+
+func (p *Planner) parallelismLimit(n, alreadyUnneeded int) int {
 	extraBuffer := n
-	limit := len(p.unneededNodes.AsList()) + n + extraBuffer
+	limit := alreadyUnneeded + n + extraBuffer
 	// TODO(x13n): Use moving average instead of min.
 	loopInterval := int64(p.minUpdateInterval)
 	u := int64(p.context.AutoscalingOptions.NodeGroupDefaults.ScaleDownUnneededTime)
@@ -341,6 +381,21 @@ func (p *Planner) unneededNodesLimit() int {
 	return limit
 }
 
+// hasPodsToMove returns true if the node runs pods that would have to be
+// rescheduled for the node to be removed.
+func (p *Planner) hasPodsToMove(nodeName string) bool {
+	nodeInfo, err := p.context.ClusterSnapshot.NodeInfos().Get(nodeName)
+	if err != nil {
+		return true
+	}
+	for _, podInfo := range nodeInfo.Pods {
+		if !pod_util.IsDaemonSetPod(podInfo.Pod) && !pod_util.IsMirrorPod(podInfo.Pod) {
+			return true
+		}
+	}
+	return false
+}
+
 // getKnownOwnerRef returns ownerRef that is known by CA and CA knows the logic of how this controller recreates pods.
 func getKnownOwnerRef(ownerRefs []metav1.OwnerReference) *metav1.OwnerReference {
 	for _, ownerRef := range ownerRefs {
@@ -623,6 +623,37 @@ func TestUpdateClusterStatUnneededNodesLimit(t *testing.T) {
 	}
 }
 
+func TestUpdateClusterStateDrainLimit(t *testing.T) {
+	var nodes []*apiv1.Node
+	var pods []*apiv1.Pod
+	for i := 0; i < 6; i++ {
+		node := BuildTestNode(fmt.Sprintf("n%d", i), 1000, 10)
+		nodes = append(nodes, node)
+		pods = append(pods, SetRSPodSpec(BuildScheduledTestPod(fmt.Sprintf("p%d", i), 100, 1, node.Name), "rs"))
+	}
+	nodes = append(nodes, BuildTestNode("e0", 1000, 10), BuildTestNode("e1", 1000, 10))
+
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	context, err := NewScaleTestAutoscalingContext(config.AutoscalingOptions{
+		NodeGroupDefaults: config.NodeGroupAutoscalingOptions{
+			ScaleDownUnneededTime: 1 * time.Minute,
+		},
+		ScaleDownSimulationTimeout: 1 * time.Hour,
+		MaxScaleDownParallelism:    10,
+		MaxDrainParallelism:        1,
+	}, &fake.Clientset{}, nil, provider, nil, nil)
+	assert.NoError(t, err)
+	clustersnapshot.InitializeClusterSnapshotOrDie(t, context.ClusterSnapshot, nodes, pods)
+	deleteOptions := options.NodeDeleteOptions{}
+	p := New(&context, NewTestProcessors(&context), deleteOptions, nil)
+	p.eligibilityChecker = &fakeEligibilityChecker{eligible: asMap(nodeNames(nodes))}
+	p.rs = &fakeRemovalSimulator{nodes: nodes, pods: pods}
+	p.minUpdateInterval = 10 * time.Second
+	assert.NoError(t, p.UpdateClusterState(nodes, nodes, &fakeActuationStatus{}, time.Now()))
+	// Drain limit is 2 (MaxDrainParallelism + extra buffer), empty nodes are not limited.
+	assert.ElementsMatch(t, []string{"n0", "n1", "e0", "e1"}, nodeNames(p.unneededNodes.AsList()))
+}
+
 func TestNodesToDelete(t *testing.T) {
 	testCases := []struct {
 		name      string
@@ -897,6 +928,7 @@ func (f *fakeEligibilityChecker) FilterOutUnremovable(context *context.Autoscali
 
 type fakeRemovalSimulator struct {
 	nodes []*apiv1.Node
+	pods  []*apiv1.Pod
 	sleep time.Duration
 }
 
@@ -910,5 +942,11 @@ func (r *fakeRemovalSimulator) SimulateNodeRemoval(name string, _ map[string]boo
 			node = n
 		}
 	}
-	return &simulator.NodeToBeRemoved{Node: node}, nil
+	var podsToReschedule []*apiv1.Pod
+	for _, pod := range r.pods {
+		if pod.Spec.NodeName == name {
+			podsToReschedule = append(podsToReschedule, pod)
+		}
+	}
+	return &simulator.NodeToBeRemoved{Node: node, PodsToReschedule: podsToReschedule}, nil
 }
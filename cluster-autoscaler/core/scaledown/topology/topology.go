@@ -0,0 +1,118 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package topology
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/taints"
+)
+
+// ParseMinReadyNodes parses specifications in the <label key>=<count> format into the minimum
+// number of Ready nodes each domain of the label key must keep.
+func ParseMinReadyNodes(specs []string) (map[string]int, error) {
+	minReadyNodes := make(map[string]int, len(specs))
+	for _, spec := range specs {
+		separator := strings.LastIndex(spec, "=")
+		if separator <= 0 {
+			return nil, fmt.Errorf("incorrect min ready nodes per domain specification, expected <label key>=<count>: %v", spec)
+		}
+		key := spec[:separator]
+		count, err := strconv.Atoi(spec[separator+1:])
+		if err != nil || count < 0 {
+			return nil, fmt.Errorf("incorrect min ready nodes per domain - count is not a non-negative integer: %v", spec)
+		}
+		if _, found := minReadyNodes[key]; found {
+			return nil, fmt.Errorf("incorrect min ready nodes per domain - label key %s given more than once", key)
+		}
+		minReadyNodes[key] = count
+	}
+	return minReadyNodes, nil
+}
+
+// ReadyNodesLeft tracks how many Ready nodes can still be removed from every domain of the
+// constrained label keys, e.g. every zone, without going below the configured minimum.
+type ReadyNodesLeft struct {
+	// left maps a label key to the number of removable Ready nodes of each of its values.
+	left map[string]map[string]int
+}
+
+// NewReadyNodesLeft counts the Ready nodes of every domain of the label keys of minReadyNodes.
+// Nodes already being deleted are not counted.
+func NewReadyNodesLeft(minReadyNodes map[string]int, nodes []*apiv1.Node) *ReadyNodesLeft {
+	left := make(map[string]map[string]int, len(minReadyNodes))
+	for key := range minReadyNodes {
+		left[key] = make(map[string]int)
+	}
+	for _, node := range nodes {
+		if !countsAsReady(node) {
+			continue
+		}
+		for key := range minReadyNodes {
+			if value, found := node.Labels[key]; found {
+				left[key][value]++
+			}
+		}
+	}
+	for key, min := range minReadyNodes {
+		for value := range left[key] {
+			left[key][value] -= min
+		}
+	}
+	return &ReadyNodesLeft{left: left}
+}
+
+// Exceeded returns the domains of the node, as <label key>=<value>, that would go below their
+// minimum number of Ready nodes if the node was removed.
+func (r *ReadyNodesLeft) Exceeded(node *apiv1.Node) []string {
+	if r == nil || !countsAsReady(node) {
+		return nil
+	}
+	var exceeded []string
+	for key, values := range r.left {
+		if value, found := node.Labels[key]; found && values[value] <= 0 {
+			exceeded = append(exceeded, fmt.Sprintf("%s=%s", key, value))
+		}
+	}
+	sort.Strings(exceeded)
+	return exceeded
+}
+
+// Remove accounts for the removal of the node.
+func (r *ReadyNodesLeft) Remove(node *apiv1.Node) {
+	if r == nil || !countsAsReady(node) {
+		return
+	}
+	for key, values := range r.left {
+		if value, found := node.Labels[key]; found {
+			values[value]--
+		}
+	}
+}
+
+func countsAsReady(node *apiv1.Node) bool {
+	if taints.HasToBeDeletedTaint(node) {
+		return false
+	}
+	ready, _, _ := kube_util.GetReadinessState(node)
+	return ready
+}
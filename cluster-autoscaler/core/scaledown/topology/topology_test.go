@@ -0,0 +1,121 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package topology
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+
+	"k8s.io/autoscaler/cluster-autoscaler/utils/taints"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func TestParseMinReadyNodes(t *testing.T) {
+	testCases := []struct {
+		name    string
+		specs   []string
+		want    map[string]int
+		wantErr bool
+	}{
+		{
+			name: "no specification",
+			want: map[string]int{},
+		},
+		{
+			name:  "several label keys",
+			specs: []string{"topology.kubernetes.io/zone=2", "rack=1"},
+			want:  map[string]int{"topology.kubernetes.io/zone": 2, "rack": 1},
+		},
+		{
+			name:    "missing count",
+			specs:   []string{"topology.kubernetes.io/zone"},
+			wantErr: true,
+		},
+		{
+			name:    "missing label key",
+			specs:   []string{"=2"},
+			wantErr: true,
+		},
+		{
+			name:    "negative count",
+			specs:   []string{"topology.kubernetes.io/zone=-1"},
+			wantErr: true,
+		},
+		{
+			name:    "duplicate label key",
+			specs:   []string{"rack=1", "rack=2"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseMinReadyNodes(tc.specs)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestReadyNodesLeft(t *testing.T) {
+	zoneNode := func(name, zone string, ready bool) *apiv1.Node {
+		node := BuildTestNode(name, 1000, 1000)
+		node.Labels["topology.kubernetes.io/zone"] = zone
+		SetNodeReadyState(node, ready, time.Now())
+		return node
+	}
+	a1 := zoneNode("a1", "a", true)
+	a2 := zoneNode("a2", "a", true)
+	a3 := zoneNode("a3", "a", true)
+	b1 := zoneNode("b1", "b", true)
+	b2 := zoneNode("b2", "b", true)
+	b3 := zoneNode("b3", "b", false)
+	c1 := zoneNode("c1", "c", true)
+	c2 := zoneNode("c2", "c", true)
+	c3 := zoneNode("c3", "c", true)
+	c3.Spec.Taints = []apiv1.Taint{{Key: taints.ToBeDeletedTaint, Effect: apiv1.TaintEffectNoSchedule}}
+	unlabeled := BuildTestNode("unlabeled", 1000, 1000)
+	SetNodeReadyState(unlabeled, true, time.Now())
+
+	left := NewReadyNodesLeft(map[string]int{"topology.kubernetes.io/zone": 2}, []*apiv1.Node{a1, a2, a3, b1, b2, b3, c1, c2, c3, unlabeled})
+
+	// Zone a has one Ready node above the minimum.
+	assert.Empty(t, left.Exceeded(a1))
+	left.Remove(a1)
+	assert.Equal(t, []string{"topology.kubernetes.io/zone=a"}, left.Exceeded(a2))
+
+	// Zone b is at the minimum, only its unready node can be removed.
+	assert.Equal(t, []string{"topology.kubernetes.io/zone=b"}, left.Exceeded(b1))
+	assert.Empty(t, left.Exceeded(b3))
+
+	// The node of zone c being deleted doesn't count as Ready.
+	assert.Equal(t, []string{"topology.kubernetes.io/zone=c"}, left.Exceeded(c1))
+
+	// Nodes without the label are not constrained.
+	assert.Empty(t, left.Exceeded(unlabeled))
+
+	// Without a minimum, nothing is constrained.
+	var noMinimum *ReadyNodesLeft
+	assert.Empty(t, noMinimum.Exceeded(a2))
+	assert.Empty(t, NewReadyNodesLeft(nil, []*apiv1.Node{a2}).Exceeded(a2))
+}
@@ -24,13 +24,16 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/context"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/eligibility"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/externaldelete"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/resource"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/topology"
 	"k8s.io/autoscaler/cluster-autoscaler/metrics"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator"
 	"k8s.io/autoscaler/cluster-autoscaler/utils"
 	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
 
 	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
 	klog "k8s.io/klog/v2"
 )
 
@@ -79,7 +82,7 @@ func (n *Nodes) Update(nodes []simulator.NodeToBeRemoved, ts time.Time) {
 	}
 	n.byName = updated
 	n.cachedList = nil
-	if klog.V(4).Enabled() {
+	if klogx.Core.V(4).Enabled() {
 		for k, v := range n.byName {
 			klog.Infof("%s is unneeded since %s duration %s", k, v.since, ts.Sub(v.since))
 		}
@@ -97,6 +100,17 @@ func (n *Nodes) Contains(nodeName string) bool {
 	return found
 }
 
+// NeedDrainCount returns the number of unneeded nodes that require drain before removal.
+func (n *Nodes) NeedDrainCount() int {
+	count := 0
+	for _, v := range n.byName {
+		if len(v.ntbr.PodsToReschedule) > 0 {
+			count++
+		}
+	}
+	return count
+}
+
 // AsList returns a slice of unneeded Node objects.
 func (n *Nodes) AsList() []*apiv1.Node {
 	if n.cachedList == nil {
@@ -117,22 +131,22 @@ func (n *Nodes) Drop(node string) {
 // RemovableAt returns all nodes that can be removed at a given time, divided
 // into empty and non-empty node lists, as well as a list of nodes that were
 // unneeded, but are not removable, annotated by reason.
-func (n *Nodes) RemovableAt(context *context.AutoscalingContext, ts time.Time, resourcesLeft resource.Limits, resourcesWithLimits []string, as scaledown.ActuationStatus) (empty, needDrain []simulator.NodeToBeRemoved, unremovable []*simulator.UnremovableNode) {
+func (n *Nodes) RemovableAt(context *context.AutoscalingContext, ts time.Time, resourcesLeft resource.Limits, resourcesWithLimits []string, readyNodesLeft *topology.ReadyNodesLeft, as scaledown.ActuationStatus) (empty, needDrain []simulator.NodeToBeRemoved, unremovable []*simulator.UnremovableNode) {
 	nodeGroupSize := utils.GetNodeGroupSizeMap(context.CloudProvider)
 	resourcesLeftCopy := resourcesLeft.DeepCopy()
 	emptyNodes, drainNodes := n.splitEmptyAndNonEmptyNodes()
 
 	for nodeName, v := range emptyNodes {
-		klog.V(2).Infof("%s was unneeded for %s", nodeName, ts.Sub(v.since).String())
-		if r := n.unremovableReason(context, v, ts, nodeGroupSize, resourcesLeftCopy, resourcesWithLimits, as); r != simulator.NoReason {
+		klogx.Core.V(2).Infof("%s was unneeded for %s", nodeName, ts.Sub(v.since).String())
+		if r := n.unremovableReason(context, v, ts, nodeGroupSize, resourcesLeftCopy, resourcesWithLimits, readyNodesLeft, as); r != simulator.NoReason {
 			unremovable = append(unremovable, &simulator.UnremovableNode{Node: v.ntbr.Node, Reason: r})
 			continue
 		}
 		empty = append(empty, v.ntbr)
 	}
 	for nodeName, v := range drainNodes {
-		klog.V(2).Infof("%s was unneeded for %s", nodeName, ts.Sub(v.since).String())
-		if r := n.unremovableReason(context, v, ts, nodeGroupSize, resourcesLeftCopy, resourcesWithLimits, as); r != simulator.NoReason {
+		klogx.Core.V(2).Infof("%s was unneeded for %s", nodeName, ts.Sub(v.since).String())
+		if r := n.unremovableReason(context, v, ts, nodeGroupSize, resourcesLeftCopy, resourcesWithLimits, readyNodesLeft, as); r != simulator.NoReason {
 			unremovable = append(unremovable, &simulator.UnremovableNode{Node: v.ntbr.Node, Reason: r})
 			continue
 		}
@@ -141,11 +155,11 @@ func (n *Nodes) RemovableAt(context *context.AutoscalingContext, ts time.Time, r
 	return
 }
 
-func (n *Nodes) unremovableReason(context *context.AutoscalingContext, v *node, ts time.Time, nodeGroupSize map[string]int, resourcesLeft resource.Limits, resourcesWithLimits []string, as scaledown.ActuationStatus) simulator.UnremovableReason {
+func (n *Nodes) unremovableReason(context *context.AutoscalingContext, v *node, ts time.Time, nodeGroupSize map[string]int, resourcesLeft resource.Limits, resourcesWithLimits []string, readyNodesLeft *topology.ReadyNodesLeft, as scaledown.ActuationStatus) simulator.UnremovableReason {
 	node := v.ntbr.Node
 	// Check if node is marked with no scale down annotation.
 	if eligibility.HasNoScaleDownAnnotation(node) {
-		klog.V(4).Infof("Skipping %s - scale down disabled annotation found", node.Name)
+		klogx.Core.V(4).Infof("Skipping %s - scale down disabled annotation found", node.Name)
 		return simulator.ScaleDownDisabledAnnotation
 	}
 	ready, _, _ := kube_util.GetReadinessState(node)
@@ -156,7 +170,7 @@ func (n *Nodes) unremovableReason(context *context.AutoscalingContext, v *node,
 		return simulator.UnexpectedError
 	}
 	if nodeGroup == nil || reflect.ValueOf(nodeGroup).IsNil() {
-		klog.V(4).Infof("Skipping %s - no node group config", node.Name)
+		klogx.Core.V(4).Infof("Skipping %s - no node group config", node.Name)
 		return simulator.NotAutoscaled
 	}
 
@@ -170,6 +184,12 @@ func (n *Nodes) unremovableReason(context *context.AutoscalingContext, v *node,
 		if !v.since.Add(unneededTime).Before(ts) {
 			return simulator.NotUnneededLongEnough
 		}
+		if context.ScaleDownBillingAware && !eligibility.HasUrgentScaleDownAnnotation(node) {
+			if left, found := billingPeriodLeft(nodeGroup, node, ts); found && left > context.ScaleDownBillingWindow {
+				klogx.Core.V(4).Infof("Skipping %s - %s left in its billing period", node.Name, left)
+				return simulator.NotNearBillingPeriodEnd
+			}
+		}
 	} else {
 		// Unready nodes may be deleted after a different time than underutilized nodes.
 		unreadyTime, err := n.sdtg.GetScaleDownUnreadyTime(nodeGroup)
@@ -186,6 +206,11 @@ func (n *Nodes) unremovableReason(context *context.AutoscalingContext, v *node,
 		return reason
 	}
 
+	if exceeded := readyNodesLeft.Exceeded(node); len(exceeded) > 0 {
+		klogx.Core.V(4).Infof("Skipping %s - minimal number of ready nodes reached in %v", node.Name, exceeded)
+		return simulator.MinReadyNodesPerDomainReached
+	}
+
 	resourceDelta, err := n.limitsFinder.DeltaForNode(context, node, nodeGroup, resourcesWithLimits)
 	if err != nil {
 		klog.Errorf("Error getting node resources: %v", err)
@@ -194,7 +219,7 @@ func (n *Nodes) unremovableReason(context *context.AutoscalingContext, v *node,
 
 	checkResult := resourcesLeft.TryDecrementBy(resourceDelta)
 	if checkResult.Exceeded() {
-		klog.V(4).Infof("Skipping %s - minimal limit exceeded for %v", node.Name, checkResult.ExceededResources)
+		klogx.Core.V(4).Infof("Skipping %s - minimal limit exceeded for %v", node.Name, checkResult.ExceededResources)
 		for _, resource := range checkResult.ExceededResources {
 			switch resource {
 			case cloudprovider.ResourceNameCores:
@@ -208,7 +233,10 @@ func (n *Nodes) unremovableReason(context *context.AutoscalingContext, v *node,
 		return simulator.MinimalResourceLimitExceeded
 	}
 
-	nodeGroupSize[nodeGroup.Id()]--
+	if _, found := nodeGroupSize[nodeGroup.Id()]; found {
+		nodeGroupSize[nodeGroup.Id()]--
+	}
+	readyNodesLeft.Remove(node)
 	return simulator.NoReason
 }
 
@@ -227,14 +255,37 @@ func (n *Nodes) splitEmptyAndNonEmptyNodes() (empty, needDrain map[string]*node)
 
 func verifyMinSize(nodeName string, nodeGroup cloudprovider.NodeGroup, nodeGroupSize map[string]int, as scaledown.ActuationStatus) simulator.UnremovableReason {
 	size, found := nodeGroupSize[nodeGroup.Id()]
+	if !found && externaldelete.IsExternalNodeGroup(nodeGroup) {
+		// The external delete node group is not listed among the node groups, nor in the shared size map.
+		size, _ = nodeGroup.TargetSize()
+		found = true
+	}
 	if !found {
 		klog.Errorf("Error while checking node group size %s: group size not found in cache", nodeGroup.Id())
 		return simulator.UnexpectedError
 	}
 	deletionsInProgress := as.DeletionsCount(nodeGroup.Id())
 	if size-deletionsInProgress <= nodeGroup.MinSize() {
-		klog.V(1).Infof("Skipping %s - node group min size reached", nodeName)
+		klogx.Core.V(1).Infof("Skipping %s - node group min size reached", nodeName)
 		return simulator.NodeGroupMinSizeReached
 	}
 	return simulator.NoReason
 }
+
+// billingPeriodLeft returns how long is left until the end of the current billing period of the node, assuming
+// it has been billed since its creation, if its node group is billed per started period.
+func billingPeriodLeft(nodeGroup cloudprovider.NodeGroup, node *apiv1.Node, ts time.Time) (time.Duration, bool) {
+	billed, ok := nodeGroup.(cloudprovider.BillingPeriodNodeGroup)
+	if !ok {
+		return 0, false
+	}
+	period := billed.BillingPeriod()
+	if period <= 0 || node.CreationTimestamp.IsZero() {
+		return 0, false
+	}
+	elapsed := ts.Sub(node.CreationTimestamp.Time)
+	if elapsed < 0 {
+		return period, true
+	}
+	return period - elapsed%period, true
+}
@@ -22,9 +22,12 @@ import (
 	"time"
 
 	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
 	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/externaldelete"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/resource"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
 	. "k8s.io/autoscaler/cluster-autoscaler/core/test"
@@ -203,7 +206,7 @@ func TestRemovableAt(t *testing.T) {
 
 			n := NewNodes(&fakeScaleDownTimeGetter{}, &resource.LimitsFinder{})
 			n.Update(nodes, time.Now())
-			gotEmptyToRemove, gotDrainToRemove, _ := n.RemovableAt(&ctx, time.Now(), resource.Limits{}, []string{}, as)
+			gotEmptyToRemove, gotDrainToRemove, _ := n.RemovableAt(&ctx, time.Now(), resource.Limits{}, []string{}, nil, as)
 			if len(gotDrainToRemove) != tc.numDrainToRemove || len(gotEmptyToRemove) != tc.numEmptyToRemove {
 				t.Errorf("%s: getNodesToRemove() return %d, %d, want %d, %d", tc.name, len(gotEmptyToRemove), len(gotDrainToRemove), tc.numEmptyToRemove, tc.numDrainToRemove)
 			}
@@ -211,6 +214,57 @@ func TestRemovableAt(t *testing.T) {
 	}
 }
 
+func TestVerifyMinSizeExternalNodeGroup(t *testing.T) {
+	nodes := []*apiv1.Node{BuildTestNode("unmanaged-0", 10, 100), BuildTestNode("unmanaged-1", 10, 100)}
+	for _, node := range nodes {
+		node.Labels = map[string]string{"external-delete": "true"}
+	}
+	selector := labels.SelectorFromSet(labels.Set{"external-delete": "true"})
+	provider := externaldelete.NewCloudProvider(testprovider.NewTestCloudProvider(nil, nil), kube_util.NewTestNodeLister(nodes), selector, "http://127.0.0.1:1", time.Second)
+	assert.NoError(t, provider.Refresh())
+	ng, err := provider.NodeGroupForNode(nodes[0])
+	assert.NoError(t, err)
+
+	nodeGroupSize := map[string]int{}
+	as := &fakeActuationStatus{deletionCount: map[string]int{externaldelete.NodeGroupId: 1}}
+	assert.Equal(t, simulator.NoReason, verifyMinSize("unmanaged-0", ng, nodeGroupSize, as))
+	// The shared size map is left untouched.
+	assert.Empty(t, nodeGroupSize)
+	as.deletionCount[externaldelete.NodeGroupId] = 2
+	assert.Equal(t, simulator.NodeGroupMinSizeReached, verifyMinSize("unmanaged-1", ng, nodeGroupSize, as))
+}
+
+type billedNodeGroup struct {
+	cloudprovider.NodeGroup
+	period time.Duration
+}
+
+func (ng *billedNodeGroup) BillingPeriod() time.Duration {
+	return ng.period
+}
+
+func TestBillingPeriodLeft(t *testing.T) {
+	now := time.Now()
+	ng := testprovider.NewTestNodeGroup("ng", 10, 0, 1, true, false, "", nil, nil)
+	node := BuildTestNode("n", 1000, 1000)
+	node.CreationTimestamp = metav1.NewTime(now.Add(-2*time.Hour - 50*time.Minute))
+
+	// not billed per period.
+	_, found := billingPeriodLeft(ng, node, now)
+	assert.False(t, found)
+
+	left, found := billingPeriodLeft(&billedNodeGroup{NodeGroup: ng, period: time.Hour}, node, now)
+	assert.True(t, found)
+	assert.Equal(t, 10*time.Minute, left)
+
+	left, found = billingPeriodLeft(&billedNodeGroup{NodeGroup: ng, period: 24 * time.Hour}, node, now)
+	assert.True(t, found)
+	assert.Equal(t, 21*time.Hour+10*time.Minute, left)
+
+	_, found = billingPeriodLeft(&billedNodeGroup{NodeGroup: ng, period: 0}, node, now)
+	assert.False(t, found)
+}
+
 type fakeActuationStatus struct {
 	recentEvictions []*apiv1.Pod
 	deletionCount   map[string]int
@@ -20,6 +20,7 @@ import (
 	"time"
 
 	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
 
 	apiv1 "k8s.io/api/core/v1"
 	klog "k8s.io/klog/v2"
@@ -30,6 +31,8 @@ import (
 type Nodes struct {
 	ttls    map[string]time.Time
 	reasons map[string]*simulator.UnremovableNode
+	// blocked holds the nodes with a timeout, to re-check them early when what blocked their removal is gone.
+	blocked map[string]*simulator.UnremovableNode
 }
 
 // NewNodes returns a new initialized Nodes object.
@@ -37,6 +40,7 @@ func NewNodes() *Nodes {
 	return &Nodes{
 		ttls:    make(map[string]time.Time),
 		reasons: make(map[string]*simulator.UnremovableNode),
+		blocked: make(map[string]*simulator.UnremovableNode),
 	}
 }
 
@@ -46,25 +50,68 @@ type NodeInfoGetter interface {
 }
 
 // Update updates the internal structure according to current state of the
-// cluster. Removes the nodes that are no longer in the nodes list.
+// cluster. Removes the nodes that are no longer in the nodes list, as well as
+// the nodes whose blocking pod is no longer running on them.
 func (n *Nodes) Update(nodeInfos NodeInfoGetter, timestamp time.Time) {
 	n.reasons = make(map[string]*simulator.UnremovableNode)
 	if len(n.ttls) <= 0 {
 		return
 	}
 	newTTLs := make(map[string]time.Time, len(n.ttls))
+	newBlocked := make(map[string]*simulator.UnremovableNode, len(n.blocked))
 	for name, ttl := range n.ttls {
-		if _, err := nodeInfos.Get(name); err != nil {
+		nodeInfo, err := nodeInfos.Get(name)
+		if err != nil {
 			// Not logging on error level as most likely cause is that node is no longer in the cluster.
 			klog.Infof("Can't retrieve node %s from snapshot, removing from unremovable nodes, err: %v", name, err)
 			continue
 		}
+		if blocked := n.blocked[name]; blocked != nil && blockingPodGone(blocked, nodeInfo) {
+			klog.V(4).Infof("Pod %s/%s blocking the removal of node %s is gone, re-checking the node", blocked.BlockingPod.Pod.Namespace, blocked.BlockingPod.Pod.Name, name)
+			continue
+		}
 		if ttl.After(timestamp) {
 			// Keep nodes that are still in the cluster and haven't expired yet.
 			newTTLs[name] = ttl
+			newBlocked[name] = n.blocked[name]
 		}
 	}
 	n.ttls = newTTLs
+	n.blocked = newBlocked
+}
+
+// RecheckBlockedByPdbs drops the timeouts of the nodes found unremovable because
+// of a pod without enough PDB left in one of the given namespaces, so that they
+// are re-checked in the next simulation rather than once their timeout expires.
+func (n *Nodes) RecheckBlockedByPdbs(namespaces map[string]bool) {
+	if len(namespaces) == 0 {
+		return
+	}
+	for name, blocked := range n.blocked {
+		if blocked == nil || blocked.BlockingPod == nil || blocked.BlockingPod.Reason != drain.NotEnoughPdb {
+			continue
+		}
+		if namespaces[blocked.BlockingPod.Pod.Namespace] {
+			klog.V(4).Infof("PDBs in namespace %s changed, re-checking node %s", blocked.BlockingPod.Pod.Namespace, name)
+			delete(n.ttls, name)
+			delete(n.blocked, name)
+		}
+	}
+}
+
+// blockingPodGone returns true if the node was blocked by a pod that is no
+// longer running on it, e.g. because it completed.
+func blockingPodGone(blocked *simulator.UnremovableNode, nodeInfo *schedulerframework.NodeInfo) bool {
+	if blocked.BlockingPod == nil || blocked.BlockingPod.Pod == nil || nodeInfo == nil {
+		return false
+	}
+	pod := blocked.BlockingPod.Pod
+	for _, podInfo := range nodeInfo.Pods {
+		if podInfo.Pod.UID == pod.UID && podInfo.Pod.Namespace == pod.Namespace && podInfo.Pod.Name == pod.Name {
+			return podInfo.Pod.Status.Phase == apiv1.PodSucceeded || podInfo.Pod.Status.Phase == apiv1.PodFailed
+		}
+	}
+	return true
 }
 
 // Contains returns true iff a given node is unremovable.
@@ -82,6 +129,7 @@ func (n *Nodes) Add(node *simulator.UnremovableNode) {
 // should be considered unremovable.
 func (n *Nodes) AddTimeout(node *simulator.UnremovableNode, timeout time.Time) {
 	n.ttls[node.Node.Name] = timeout
+	n.blocked[node.Node.Name] = node
 	n.Add(node)
 }
 
@@ -22,7 +22,9 @@ import (
 	"time"
 
 	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
 
+	"github.com/stretchr/testify/assert"
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
@@ -103,6 +105,67 @@ func TestContains(t *testing.T) {
 	}
 }
 
+func TestUpdateBlockingPodGone(t *testing.T) {
+	blockingPod := BuildTestPod("p1", 100, 0)
+	blockingPod.UID = "p1-uid"
+	completedPod := BuildTestPod("p2", 100, 0)
+	completedPod.UID = "p2-uid"
+	completedPod.Status.Phase = apiv1.PodSucceeded
+
+	nodeInfos := map[string]*schedulerframework.NodeInfo{
+		"n1": schedulerframework.NewNodeInfo(blockingPod),
+		"n2": schedulerframework.NewNodeInfo(),
+		"n3": schedulerframework.NewNodeInfo(completedPod),
+	}
+	n := NewNodes()
+	n.AddTimeout(makeBlockedNode("n1", blockingPod, drain.NotReplicated), afterUpdate)
+	n.AddTimeout(makeBlockedNode("n2", blockingPod, drain.NotReplicated), afterUpdate)
+	n.AddTimeout(makeBlockedNode("n3", completedPod, drain.NotReplicated), afterUpdate)
+	n.Update(&nodeInfoGetter{nodeInfos}, updateTime)
+
+	assert.True(t, n.IsRecent("n1"))
+	assert.False(t, n.IsRecent("n2"))
+	assert.False(t, n.IsRecent("n3"))
+}
+
+func TestRecheckBlockedByPdbs(t *testing.T) {
+	pod := BuildTestPod("p1", 100, 0)
+	pod.Namespace = "ns1"
+	otherPod := BuildTestPod("p2", 100, 0)
+	otherPod.Namespace = "ns2"
+
+	n := NewNodes()
+	n.AddTimeout(makeBlockedNode("n1", pod, drain.NotEnoughPdb), afterUpdate)
+	n.AddTimeout(makeBlockedNode("n2", otherPod, drain.NotEnoughPdb), afterUpdate)
+	n.AddTimeout(makeBlockedNode("n3", pod, drain.NotReplicated), afterUpdate)
+	n.AddTimeout(makeUnremovableNode("n4"), afterUpdate)
+
+	n.RecheckBlockedByPdbs(map[string]bool{"ns1": true})
+
+	assert.False(t, n.IsRecent("n1"))
+	assert.True(t, n.IsRecent("n2"))
+	assert.True(t, n.IsRecent("n3"))
+	assert.True(t, n.IsRecent("n4"))
+}
+
+type nodeInfoGetter struct {
+	nodeInfos map[string]*schedulerframework.NodeInfo
+}
+
+func (g *nodeInfoGetter) Get(name string) (*schedulerframework.NodeInfo, error) {
+	if nodeInfo, found := g.nodeInfos[name]; found {
+		return nodeInfo, nil
+	}
+	return nil, fmt.Errorf("not found")
+}
+
+func makeBlockedNode(name string, pod *apiv1.Pod, reason drain.BlockingPodReason) *simulator.UnremovableNode {
+	node := makeUnremovableNode(name)
+	node.Reason = simulator.BlockedByPod
+	node.BlockingPod = &drain.BlockingPod{Pod: pod, Reason: reason}
+	return node
+}
+
 type fakeNodeInfoGetter struct {
 	names map[string]bool
 }
@@ -24,12 +24,14 @@ import (
 	"time"
 
 	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
 	"k8s.io/klog/v2"
 	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
 
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
 	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/core/utils"
 	"k8s.io/autoscaler/cluster-autoscaler/metrics"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupset"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
@@ -144,14 +146,14 @@ func (e *scaleUpExecutor) executeScaleUp(
 ) errors.AutoscalerError {
 	gpuConfig := e.autoscalingContext.CloudProvider.GetNodeGpuConfig(nodeInfo.Node())
 	gpuResourceName, gpuType := gpu.GetGpuInfoForMetrics(gpuConfig, availableGPUTypes, nodeInfo.Node(), nil)
-	klog.V(0).Infof("Scale-up: setting group %s size to %d", info.Group.Id(), info.NewSize)
+	klogx.Core.V(0).Infof("Scale-up: setting group %s size to %d", info.Group.Id(), info.NewSize)
 	e.autoscalingContext.LogRecorder.Eventf(apiv1.EventTypeNormal, "ScaledUpGroup",
 		"Scale-up: setting group %s size to %d instead of %d (max: %d)", info.Group.Id(), info.NewSize, info.CurrentSize, info.MaxSize)
 	increase := info.NewSize - info.CurrentSize
-	if err := info.Group.IncreaseSize(increase); err != nil {
+	if err := e.increaseSize(info.Group, increase); err != nil {
 		e.autoscalingContext.LogRecorder.Eventf(apiv1.EventTypeWarning, "FailedToScaleUpGroup", "Scale-up failed for group %s: %v", info.Group.Id(), err)
 		aerr := errors.ToAutoscalerError(errors.CloudProviderError, err).AddPrefix("failed to increase node group size: ")
-		e.clusterStateRegistry.RegisterFailedScaleUp(info.Group, metrics.FailedScaleUpReason(string(aerr.Type())), gpuResourceName, gpuType, now)
+		e.clusterStateRegistry.RegisterFailedScaleUpError(info.Group, metrics.FailedScaleUpReason(string(aerr.Type())), err, gpuResourceName, gpuType, now)
 		return aerr
 	}
 	e.clusterStateRegistry.RegisterOrUpdateScaleUp(
@@ -159,11 +161,31 @@ func (e *scaleUpExecutor) executeScaleUp(
 		increase,
 		time.Now())
 	metrics.RegisterScaleUp(increase, gpuResourceName, gpuType)
+	if e.autoscalingContext.EmitPerNodeGroupMetrics {
+		if price, found := utils.GetHourlyNodePrice(e.autoscalingContext.CloudProvider, nodeInfo.Node(), now); found {
+			metrics.RegisterScaleUpEstimatedHourlyCost(info.Group.Id(), price*float64(increase))
+		}
+	}
 	e.autoscalingContext.LogRecorder.Eventf(apiv1.EventTypeNormal, "ScaledUpGroup",
 		"Scale-up: group %s size set to %d instead of %d (max: %d)", info.Group.Id(), info.NewSize, info.CurrentSize, info.MaxSize)
 	return nil
 }
 
+// increaseSize increases the size of the node group, atomically for the node groups scaled
+// atomically whose cloud provider supports it.
+func (e *scaleUpExecutor) increaseSize(nodeGroup cloudprovider.NodeGroup, increase int) error {
+	if atomicNodeGroup, ok := nodeGroup.(cloudprovider.AtomicScaleUpNodeGroup); ok {
+		autoscalingOptions, err := nodeGroup.GetOptions(e.autoscalingContext.NodeGroupDefaults)
+		if err != nil {
+			klog.Errorf("Couldn't get autoscaling options for ng: %v", nodeGroup.Id())
+		}
+		if autoscalingOptions != nil && autoscalingOptions.ZeroOrMaxNodeScaling {
+			return atomicNodeGroup.AtomicIncreaseSize(increase)
+		}
+	}
+	return nodeGroup.IncreaseSize(increase)
+}
+
 func combineConcurrentScaleUpErrors(errs []errors.AutoscalerError) errors.AutoscalerError {
 	if len(errs) == 0 {
 		return nil
@@ -19,6 +19,10 @@ package orchestrator
 import (
 	"testing"
 
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
 
 	"github.com/stretchr/testify/assert"
@@ -125,3 +129,36 @@ func TestCombinedConcurrentScaleUpErrors(t *testing.T) {
 		})
 	}
 }
+
+type atomicNodeGroup struct {
+	cloudprovider.NodeGroup
+	atomicIncrease int
+}
+
+func (ng *atomicNodeGroup) AtomicIncreaseSize(delta int) error {
+	ng.atomicIncrease += delta
+	return nil
+}
+
+func TestIncreaseSize(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(func(string, int) error { return nil }, nil)
+	provider.AddNodeGroup("regular", 0, 10, 0)
+	provider.AddNodeGroupWithCustomOptions("atomic", 0, 10, 0, &config.NodeGroupAutoscalingOptions{ZeroOrMaxNodeScaling: true})
+	executor := newScaleUpExecutor(&context.AutoscalingContext{}, nil)
+
+	// Node groups scaled atomically are increased atomically when supported.
+	atomic := &atomicNodeGroup{NodeGroup: provider.GetNodeGroup("atomic")}
+	assert.NoError(t, executor.increaseSize(atomic, 10))
+	assert.Equal(t, 10, atomic.atomicIncrease)
+	targetSize, err := atomic.TargetSize()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, targetSize)
+
+	// Other node groups are increased regularly.
+	regular := &atomicNodeGroup{NodeGroup: provider.GetNodeGroup("regular")}
+	assert.NoError(t, executor.increaseSize(regular, 2))
+	assert.Equal(t, 0, regular.atomicIncrease)
+	targetSize, err = regular.TargetSize()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, targetSize)
+}
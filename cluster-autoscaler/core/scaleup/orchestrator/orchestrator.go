@@ -39,8 +39,11 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroups"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupset"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/predicatechecker"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	pod_util "k8s.io/autoscaler/cluster-autoscaler/utils/pod"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/taints"
 )
 
@@ -52,6 +55,7 @@ type ScaleUpOrchestrator struct {
 	clusterStateRegistry *clusterstate.ClusterStateRegistry
 	scaleUpExecutor      *scaleUpExecutor
 	taintConfig          taints.TaintConfig
+	versionSkewChecker   *kube_util.VersionSkewChecker
 	initialized          bool
 }
 
@@ -75,6 +79,9 @@ func (o *ScaleUpOrchestrator) Initialize(
 	o.taintConfig = taintConfig
 	o.resourceManager = resource.NewManager(processors.CustomResourcesProcessor)
 	o.scaleUpExecutor = newScaleUpExecutor(autoscalingContext, clusterStateRegistry)
+	if autoscalingContext.ClientSet != nil {
+		o.versionSkewChecker = kube_util.NewVersionSkewChecker(autoscalingContext.ClientSet.Discovery())
+	}
 	o.initialized = true
 }
 
@@ -94,7 +101,7 @@ func (o *ScaleUpOrchestrator) ScaleUp(
 	// From now on we only care about unschedulable pods that were marked after the newest
 	// node became available for the scheduler.
 	if len(unschedulablePods) == 0 {
-		klog.V(1).Info("No unschedulable pods")
+		klogx.Core.V(1).Info("No unschedulable pods")
 		return &status.ScaleUpStatus{Result: status.ScaleUpNotNeeded}, nil
 	}
 
@@ -112,7 +119,7 @@ func (o *ScaleUpOrchestrator) ScaleUp(
 	if aErr != nil {
 		return scaleUpError(&status.ScaleUpStatus{}, aErr.AddPrefix("could not get upcoming nodes: "))
 	}
-	klog.V(4).Infof("Upcoming %d nodes", len(upcomingNodes))
+	klogx.Core.V(4).Infof("Upcoming %d nodes", len(upcomingNodes))
 
 	nodeGroups := o.autoscalingContext.CloudProvider.NodeGroups()
 	if o.processors != nil && o.processors.NodeGroupListProcessor != nil {
@@ -146,15 +153,29 @@ func (o *ScaleUpOrchestrator) ScaleUp(
 	var options []expander.Option
 
 	for _, nodeGroup := range validNodeGroups {
-		schedulablePods[nodeGroup.Id()] = o.SchedulablePods(podEquivalenceGroups, nodeGroup, nodeInfos[nodeGroup.Id()])
+		if !o.processNodeGroup(nodeGroup, now, func() {
+			schedulablePods[nodeGroup.Id()] = o.SchedulablePods(podEquivalenceGroups, nodeGroup, nodeInfos[nodeGroup.Id()])
+		}) {
+			skippedNodeGroups[nodeGroup.Id()] = PanicReason
+		}
 	}
 
 	for _, nodeGroup := range validNodeGroups {
-		option := o.ComputeExpansionOption(nodeGroup, schedulablePods, nodeInfos, len(nodes)+len(upcomingNodes), now)
+		if _, found := skippedNodeGroups[nodeGroup.Id()]; found {
+			o.processors.BinpackingLimiter.MarkProcessed(o.autoscalingContext, nodeGroup.Id())
+			continue
+		}
+		var option expander.Option
+		if !o.processNodeGroup(nodeGroup, now, func() {
+			option = o.ComputeExpansionOption(nodeGroup, schedulablePods, nodeInfos, len(nodes)+len(upcomingNodes), now)
+		}) {
+			skippedNodeGroups[nodeGroup.Id()] = PanicReason
+			option = expander.Option{NodeGroup: nodeGroup}
+		}
 		o.processors.BinpackingLimiter.MarkProcessed(o.autoscalingContext, nodeGroup.Id())
 
 		if len(option.Pods) == 0 || option.NodeCount == 0 {
-			klog.V(4).Infof("No pod can fit to %s", nodeGroup.Id())
+			klogx.Core.V(4).Infof("No pod can fit to %s", nodeGroup.Id())
 		} else {
 			options = append(options, option)
 		}
@@ -168,7 +189,7 @@ func (o *ScaleUpOrchestrator) ScaleUp(
 	o.processors.BinpackingLimiter.FinalizeBinpacking(o.autoscalingContext, options)
 
 	if len(options) == 0 {
-		klog.V(1).Info("No expansion options")
+		klogx.Core.V(1).Info("No expansion options")
 		return &status.ScaleUpStatus{
 			Result:                  status.ScaleUpNoOptionsAvailable,
 			PodsRemainUnschedulable: GetRemainingPods(podEquivalenceGroups, skippedNodeGroups),
@@ -185,11 +206,11 @@ func (o *ScaleUpOrchestrator) ScaleUp(
 			ConsideredNodeGroups:    nodeGroups,
 		}, nil
 	}
-	klog.V(1).Infof("Best option to resize: %s", bestOption.NodeGroup.Id())
+	klogx.Core.V(1).Infof("Best option to resize: %s", bestOption.NodeGroup.Id())
 	if len(bestOption.Debug) > 0 {
-		klog.V(1).Info(bestOption.Debug)
+		klogx.Core.V(1).Info(bestOption.Debug)
 	}
-	klog.V(1).Infof("Estimated %d nodes needed in %s", bestOption.NodeCount, bestOption.NodeGroup.Id())
+	klogx.Core.V(1).Infof("Estimated %d nodes needed in %s", bestOption.NodeCount, bestOption.NodeGroup.Id())
 
 	newNodes, aErr := o.GetCappedNewNodeCount(bestOption.NodeCount, len(nodes)+len(upcomingNodes))
 	if aErr != nil {
@@ -250,10 +271,10 @@ func (o *ScaleUpOrchestrator) ScaleUp(
 		for _, sng := range bestOption.SimilarNodeGroups {
 			similarNodeGroupIds = append(similarNodeGroupIds, sng.Id())
 		}
-		klog.V(2).Infof("Found %d similar node groups: %v", len(bestOption.SimilarNodeGroups), similarNodeGroupIds)
+		klogx.Core.V(2).Infof("Found %d similar node groups: %v", len(bestOption.SimilarNodeGroups), similarNodeGroupIds)
 	} else if o.autoscalingContext.BalanceSimilarNodeGroups {
 		// if no similar node groups are found and the flag is enabled, log about it
-		klog.V(2).Info("No similar node groups found")
+		klogx.Core.V(2).Info("No similar node groups found")
 	}
 
 	nodeInfo, found := nodeInfos[bestOption.NodeGroup.Id()]
@@ -285,7 +306,7 @@ func (o *ScaleUpOrchestrator) ScaleUp(
 		for _, ng := range targetNodeGroups {
 			names = append(names, ng.Id())
 		}
-		klog.V(1).Infof("Splitting scale-up between %v similar node groups: {%v}", len(targetNodeGroups), strings.Join(names, ", "))
+		klogx.Core.V(1).Infof("Splitting scale-up between %v similar node groups: {%v}", len(targetNodeGroups), strings.Join(names, ", "))
 	}
 
 	scaleUpInfos, aErr := o.processors.NodeGroupSetProcessor.BalanceScaleUpBetweenGroups(o.autoscalingContext, targetNodeGroups, newNodes)
@@ -295,7 +316,7 @@ func (o *ScaleUpOrchestrator) ScaleUp(
 			aErr)
 	}
 
-	klog.V(1).Infof("Final scale-up plan: %v", scaleUpInfos)
+	klogx.Core.V(1).Infof("Final scale-up plan: %v", scaleUpInfos)
 	aErr, failedNodeGroups := o.scaleUpExecutor.ExecuteScaleUps(scaleUpInfos, nodeInfos, now)
 	if aErr != nil {
 		return scaleUpError(
@@ -353,7 +374,7 @@ func (o *ScaleUpOrchestrator) ScaleUpToNodeGroupMinSize(
 			continue
 		}
 
-		klog.V(4).Infof("ScaleUpToNodeGroupMinSize: NodeGroup %s, TargetSize %d, MinSize %d, MaxSize %d", ng.Id(), targetSize, ng.MinSize(), ng.MaxSize())
+		klogx.Core.V(4).Infof("ScaleUpToNodeGroupMinSize: NodeGroup %s, TargetSize %d, MinSize %d, MaxSize %d", ng.Id(), targetSize, ng.MinSize(), ng.MaxSize())
 		if targetSize >= ng.MinSize() {
 			continue
 		}
@@ -369,6 +390,11 @@ func (o *ScaleUpOrchestrator) ScaleUpToNodeGroupMinSize(
 			continue
 		}
 
+		if skipReason := o.IsNodeGroupVersionIncompatible(ng, nodeInfo, now); skipReason != nil {
+			klog.Warningf("ScaleUpToNodeGroupMinSize: node group version incompatible: %v", skipReason)
+			continue
+		}
+
 		if skipReason := o.IsNodeGroupResourceExceeded(resourcesLeft, ng, nodeInfo, 1); skipReason != nil {
 			klog.Warning("ScaleUpToNodeGroupMinSize: node group resource excceded: %v", skipReason)
 			continue
@@ -397,11 +423,11 @@ func (o *ScaleUpOrchestrator) ScaleUpToNodeGroupMinSize(
 	}
 
 	if len(scaleUpInfos) == 0 {
-		klog.V(1).Info("ScaleUpToNodeGroupMinSize: scale up not needed")
+		klogx.Core.V(1).Info("ScaleUpToNodeGroupMinSize: scale up not needed")
 		return &status.ScaleUpStatus{Result: status.ScaleUpNotNeeded}, nil
 	}
 
-	klog.V(1).Infof("ScaleUpToNodeGroupMinSize: final scale-up plan: %v", scaleUpInfos)
+	klogx.Core.V(1).Infof("ScaleUpToNodeGroupMinSize: final scale-up plan: %v", scaleUpInfos)
 	aErr, failedNodeGroups := o.scaleUpExecutor.ExecuteScaleUps(scaleUpInfos, nodeInfos, now)
 	if aErr != nil {
 		return scaleUpError(
@@ -432,50 +458,82 @@ func (o *ScaleUpOrchestrator) filterValidScaleUpNodeGroups(
 	skippedNodeGroups := map[string]status.Reasons{}
 
 	for _, nodeGroup := range nodeGroups {
-		if skipReason := o.IsNodeGroupReadyToScaleUp(nodeGroup, now); skipReason != nil {
+		var skipReason status.Reasons
+		if !o.processNodeGroup(nodeGroup, now, func() {
+			skipReason = o.validateScaleUpNodeGroup(nodeGroup, nodeInfos, resourcesLeft, currentNodeCount, now)
+		}) {
+			skipReason = PanicReason
+		}
+		if skipReason != nil {
 			skippedNodeGroups[nodeGroup.Id()] = skipReason
 			continue
 		}
+		validNodeGroups = append(validNodeGroups, nodeGroup)
+	}
+	return validNodeGroups, skippedNodeGroups
+}
 
-		currentTargetSize, err := nodeGroup.TargetSize()
-		if err != nil {
-			klog.Errorf("Failed to get node group size: %v", err)
-			skippedNodeGroups[nodeGroup.Id()] = NotReadyReason
-			continue
-		}
-		if currentTargetSize >= nodeGroup.MaxSize() {
-			klog.V(4).Infof("Skipping node group %s - max size reached", nodeGroup.Id())
-			skippedNodeGroups[nodeGroup.Id()] = MaxLimitReachedReason
-			continue
-		}
-		autoscalingOptions, err := nodeGroup.GetOptions(o.autoscalingContext.NodeGroupDefaults)
-		if err != nil {
-			klog.Errorf("Couldn't get autoscaling options for ng: %v", nodeGroup.Id())
-		}
-		numNodes := 1
-		if autoscalingOptions != nil && autoscalingOptions.ZeroOrMaxNodeScaling {
-			numNodes = nodeGroup.MaxSize() - currentTargetSize
-			if o.autoscalingContext.MaxNodesTotal != 0 && currentNodeCount+numNodes > o.autoscalingContext.MaxNodesTotal {
-				klog.V(4).Infof("Skipping node group %s - atomic scale-up exceeds cluster node count limit", nodeGroup.Id())
-				skippedNodeGroups[nodeGroup.Id()] = NewSkippedReasons("atomic scale-up exceeds cluster node count limit")
-				continue
-			}
-		}
+// validateScaleUpNodeGroup returns the reason why the node group isn't valid for scale-up, nil if it is.
+func (o *ScaleUpOrchestrator) validateScaleUpNodeGroup(
+	nodeGroup cloudprovider.NodeGroup,
+	nodeInfos map[string]*schedulerframework.NodeInfo,
+	resourcesLeft resource.Limits,
+	currentNodeCount int,
+	now time.Time,
+) status.Reasons {
+	if skipReason := o.IsNodeGroupReadyToScaleUp(nodeGroup, now); skipReason != nil {
+		return skipReason
+	}
 
-		nodeInfo, found := nodeInfos[nodeGroup.Id()]
-		if !found {
-			klog.Errorf("No node info for: %s", nodeGroup.Id())
-			skippedNodeGroups[nodeGroup.Id()] = NotReadyReason
-			continue
-		}
-		if skipReason := o.IsNodeGroupResourceExceeded(resourcesLeft, nodeGroup, nodeInfo, numNodes); skipReason != nil {
-			skippedNodeGroups[nodeGroup.Id()] = skipReason
-			continue
+	currentTargetSize, err := nodeGroup.TargetSize()
+	if err != nil {
+		klog.Errorf("Failed to get node group size: %v", err)
+		return NotReadyReason
+	}
+	if currentTargetSize >= nodeGroup.MaxSize() {
+		klogx.Core.V(4).Infof("Skipping node group %s - max size reached", nodeGroup.Id())
+		return MaxLimitReachedReason
+	}
+	autoscalingOptions, err := nodeGroup.GetOptions(o.autoscalingContext.NodeGroupDefaults)
+	if err != nil {
+		klog.Errorf("Couldn't get autoscaling options for ng: %v", nodeGroup.Id())
+	}
+	numNodes := 1
+	if autoscalingOptions != nil && autoscalingOptions.ZeroOrMaxNodeScaling {
+		numNodes = nodeGroup.MaxSize() - currentTargetSize
+		if o.autoscalingContext.MaxNodesTotal != 0 && currentNodeCount+numNodes > o.autoscalingContext.MaxNodesTotal {
+			klogx.Core.V(4).Infof("Skipping node group %s - atomic scale-up exceeds cluster node count limit", nodeGroup.Id())
+			return NewSkippedReasons("atomic scale-up exceeds cluster node count limit")
 		}
+	}
 
-		validNodeGroups = append(validNodeGroups, nodeGroup)
+	nodeInfo, found := nodeInfos[nodeGroup.Id()]
+	if !found {
+		klog.Errorf("No node info for: %s", nodeGroup.Id())
+		return NotReadyReason
 	}
-	return validNodeGroups, skippedNodeGroups
+	if skipReason := o.IsNodeGroupVersionIncompatible(nodeGroup, nodeInfo, now); skipReason != nil {
+		return skipReason
+	}
+	if skipReason := o.IsNodeGroupResourceExceeded(resourcesLeft, nodeGroup, nodeInfo, numNodes); skipReason != nil {
+		return skipReason
+	}
+	return nil
+}
+
+// processNodeGroup runs process for the node group, recovering from panics so that a malformed node group
+// doesn't crash the autoscaler. Node groups panicking are backed off, and false is returned.
+func (o *ScaleUpOrchestrator) processNodeGroup(nodeGroup cloudprovider.NodeGroup, now time.Time, process func()) bool {
+	var err errors.AutoscalerError
+	func() {
+		defer utils.RecoverNodeGroupPanic(nodeGroup.Id(), &err)
+		process()
+	}()
+	if err != nil {
+		o.clusterStateRegistry.RegisterNodeGroupPanic(nodeGroup, now)
+		return false
+	}
+	return true
 }
 
 // ComputeExpansionOption computes expansion option based on pending pods and cluster state.
@@ -537,18 +595,26 @@ func (o *ScaleUpOrchestrator) SchedulablePods(
 		return []*apiv1.Pod{}
 	}
 
+	spot := cloudprovider.NodeGroupCapacityType(nodeGroup) == cloudprovider.SpotCapacity
 	var schedulablePods []*apiv1.Pod
 	for _, eg := range podEquivalenceGroups {
 		samplePod := eg.Pods[0]
-		if err := o.autoscalingContext.PredicateChecker.CheckPredicates(o.autoscalingContext.ClusterSnapshot, samplePod, nodeInfo.Node().Name); err == nil {
+		var err *predicatechecker.PredicateError
+		if spot && pod_util.IsInterruptionSensitive(samplePod) {
+			// Interruption-sensitive pods are kept off spot capacity, even if they would fit.
+			err = predicatechecker.NewPredicateError(predicatechecker.NotSchedulablePredicateError, "", "node group is backed by spot capacity and pod is interruption-sensitive", nil, func() string { return "" })
+		} else {
+			err = o.autoscalingContext.PredicateChecker.CheckPredicates(o.autoscalingContext.ClusterSnapshot, samplePod, nodeInfo.Node().Name)
+		}
+		if err == nil {
 			// Add pods to option.
 			schedulablePods = append(schedulablePods, eg.Pods...)
 			// Mark pod group as (theoretically) schedulable.
 			eg.Schedulable = true
 		} else {
-			klog.V(2).Infof("Pod %s/%s can't be scheduled on %s, predicate checking error: %v", samplePod.Namespace, samplePod.Name, nodeGroup.Id(), err.VerboseMessage())
+			klogx.Core.V(2).Infof("Pod %s/%s can't be scheduled on %s, predicate checking error: %v", samplePod.Namespace, samplePod.Name, nodeGroup.Id(), err.VerboseMessage())
 			if podCount := len(eg.Pods); podCount > 1 {
-				klog.V(2).Infof("%d other pods similar to %s can't be scheduled on %s", podCount-1, samplePod.Name, nodeGroup.Id())
+				klogx.Core.V(2).Infof("%d other pods similar to %s can't be scheduled on %s", podCount-1, samplePod.Name, nodeGroup.Id())
 			}
 			eg.SchedulingErrors[nodeGroup.Id()] = err
 		}
@@ -588,6 +654,19 @@ func (o *ScaleUpOrchestrator) IsNodeGroupReadyToScaleUp(nodeGroup cloudprovider.
 	return nil
 }
 
+// IsNodeGroupVersionIncompatible returns nil if the kubelet version of the node group template is allowed by the
+// control plane version skew policy, otherwise a reason is provided. Templates without kubelet version are not checked.
+func (o *ScaleUpOrchestrator) IsNodeGroupVersionIncompatible(nodeGroup cloudprovider.NodeGroup, nodeInfo *schedulerframework.NodeInfo, now time.Time) *SkippedReasons {
+	if o.versionSkewChecker == nil || nodeInfo.Node() == nil {
+		return nil
+	}
+	if err := o.versionSkewChecker.CheckKubeletVersion(nodeInfo.Node().Status.NodeInfo.KubeletVersion, now); err != nil {
+		klog.Warningf("Skipping node group %s: %v", nodeGroup.Id(), err)
+		return IncompatibleVersionReason
+	}
+	return nil
+}
+
 // IsNodeGroupResourceExceeded returns nil if node group resource limits are not exceeded, otherwise a reason is provided.
 func (o *ScaleUpOrchestrator) IsNodeGroupResourceExceeded(resourcesLeft resource.Limits, nodeGroup cloudprovider.NodeGroup, nodeInfo *schedulerframework.NodeInfo, numNodes int) status.Reasons {
 	resourcesDelta, err := o.resourceManager.DeltaForNode(o.autoscalingContext, nodeInfo, nodeGroup)
@@ -602,7 +681,7 @@ func (o *ScaleUpOrchestrator) IsNodeGroupResourceExceeded(resourcesLeft resource
 
 	checkResult := resource.CheckDeltaWithinLimits(resourcesLeft, resourcesDelta)
 	if checkResult.Exceeded {
-		klog.V(4).Infof("Skipping node group %s; maximal limit exceeded for %v", nodeGroup.Id(), checkResult.ExceededResources)
+		klogx.Core.V(4).Infof("Skipping node group %s; maximal limit exceeded for %v", nodeGroup.Id(), checkResult.ExceededResources)
 		for _, resource := range checkResult.ExceededResources {
 			switch resource {
 			case cloudprovider.ResourceNameCores:
@@ -621,7 +700,7 @@ func (o *ScaleUpOrchestrator) IsNodeGroupResourceExceeded(resourcesLeft resource
 // GetCappedNewNodeCount caps resize according to cluster wide node count limit.
 func (o *ScaleUpOrchestrator) GetCappedNewNodeCount(newNodeCount, currentNodeCount int) (int, errors.AutoscalerError) {
 	if o.autoscalingContext.MaxNodesTotal > 0 && newNodeCount+currentNodeCount > o.autoscalingContext.MaxNodesTotal {
-		klog.V(1).Infof("Capping size to max cluster total size (%d)", o.autoscalingContext.MaxNodesTotal)
+		klogx.Core.V(1).Infof("Capping size to max cluster total size (%d)", o.autoscalingContext.MaxNodesTotal)
 		newNodeCount = o.autoscalingContext.MaxNodesTotal - currentNodeCount
 		o.autoscalingContext.LogRecorder.Eventf(apiv1.EventTypeWarning, "MaxNodesTotalReached", "Max total nodes in cluster reached: %v", o.autoscalingContext.MaxNodesTotal)
 		if newNodeCount < 1 {
@@ -666,7 +745,7 @@ func (o *ScaleUpOrchestrator) ComputeSimilarNodeGroups(
 	for _, ng := range similarNodeGroups {
 		// Non-existing node groups are created later so skip check for them.
 		if ng.Exist() && !o.clusterStateRegistry.IsNodeGroupSafeToScaleUp(ng, now) {
-			klog.V(2).Infof("Ignoring node group %s when balancing: group is not ready for scaleup", ng.Id())
+			klogx.Core.V(2).Infof("Ignoring node group %s when balancing: group is not ready for scaleup", ng.Id())
 		} else if similarSchedulablePods, found := schedulablePods[ng.Id()]; found && matchingSchedulablePods(groupSchedulablePods, similarSchedulablePods) {
 			validSimilarNodeGroups = append(validSimilarNodeGroups, ng)
 		}
@@ -46,12 +46,15 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
 	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	pod_util "k8s.io/autoscaler/cluster-autoscaler/utils/pod"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/taints"
 	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/units"
 
 	appsv1 "k8s.io/api/apps/v1"
 	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
 	"k8s.io/client-go/kubernetes/fake"
 	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
 
@@ -1192,6 +1195,106 @@ func TestScaleUpNoHelp(t *testing.T) {
 	assert.Regexp(t, regexp.MustCompile("NotTriggerScaleUp"), event)
 }
 
+func TestScaleUpKeepsInterruptionSensitivePodsOffSpot(t *testing.T) {
+	n1 := BuildTestNode("n1", 100, 1000)
+	n2 := BuildTestNode("n2", 100, 1000)
+	now := time.Now()
+	SetNodeReadyState(n1, true, now.Add(-2*time.Minute))
+	SetNodeReadyState(n2, true, now.Add(-2*time.Minute))
+
+	podLister := kube_util.NewTestPodLister([]*apiv1.Pod{})
+	listers := kube_util.NewListerRegistry(nil, nil, podLister, nil, nil, nil, nil, nil, nil)
+
+	expandedGroups := make(chan string, 10)
+	provider := testprovider.NewTestCloudProvider(func(nodeGroup string, increase int) error {
+		expandedGroups <- fmt.Sprintf("%s-%d", nodeGroup, increase)
+		return nil
+	}, nil)
+	provider.AddNodeGroup("spot", 1, 10, 1)
+	provider.AddNode("spot", n1)
+	provider.AddNodeGroup("on-demand", 1, 10, 1)
+	provider.AddNode("on-demand", n2)
+	provider.GetNodeGroup("spot").(*testprovider.TestNodeGroup).SetCapacityType(cloudprovider.SpotCapacity)
+
+	options := config.AutoscalingOptions{
+		EstimatorName:  estimator.BinpackingEstimatorName,
+		MaxCoresTotal:  config.DefaultMaxClusterCores,
+		MaxMemoryTotal: config.DefaultMaxClusterMemory,
+	}
+	context, err := NewScaleTestAutoscalingContext(options, &fake.Clientset{}, listers, provider, nil, nil)
+	assert.NoError(t, err)
+
+	nodes := []*apiv1.Node{n1, n2}
+	nodeInfos, _ := nodeinfosprovider.NewDefaultTemplateNodeInfoProvider(nil, false).Process(&context, nodes, []*appsv1.DaemonSet{}, taints.TaintConfig{}, now)
+	clusterState := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder, NewBackoff(), nodegroupconfig.NewDefaultNodeGroupConfigProcessor(config.NodeGroupAutoscalingOptions{MaxNodeProvisionTime: 15 * time.Minute}))
+	clusterState.UpdateNodes(nodes, nodeInfos, time.Now())
+	p1 := BuildTestPod("p-new", 80, 0)
+	p1.Annotations = map[string]string{pod_util.InterruptionSensitiveAnnotationKey: "true"}
+
+	processors := NewTestProcessors(&context)
+	suOrchestrator := New()
+	suOrchestrator.Initialize(&context, processors, clusterState, taints.TaintConfig{})
+	scaleUpStatus, err := suOrchestrator.ScaleUp([]*apiv1.Pod{p1}, nodes, []*appsv1.DaemonSet{}, nodeInfos)
+
+	assert.NoError(t, err)
+	assert.True(t, scaleUpStatus.WasSuccessful())
+	assert.Equal(t, "on-demand-1", utils.GetStringFromChan(expandedGroups))
+}
+
+// panickingNodeGroup is a node group whose options can't be read.
+type panickingNodeGroup struct {
+	*testprovider.TestNodeGroup
+}
+
+func (ng *panickingNodeGroup) GetOptions(_ config.NodeGroupAutoscalingOptions) (*config.NodeGroupAutoscalingOptions, error) {
+	panic("malformed node group")
+}
+
+func TestScaleUpSkipsPanickingNodeGroup(t *testing.T) {
+	n1 := BuildTestNode("n1", 100, 1000)
+	n2 := BuildTestNode("n2", 100, 1000)
+	now := time.Now()
+	SetNodeReadyState(n1, true, now.Add(-2*time.Minute))
+	SetNodeReadyState(n2, true, now.Add(-2*time.Minute))
+
+	podLister := kube_util.NewTestPodLister([]*apiv1.Pod{})
+	listers := kube_util.NewListerRegistry(nil, nil, podLister, nil, nil, nil, nil, nil, nil)
+
+	expandedGroups := make(chan string, 10)
+	provider := testprovider.NewTestCloudProvider(func(nodeGroup string, increase int) error {
+		expandedGroups <- fmt.Sprintf("%s-%d", nodeGroup, increase)
+		return nil
+	}, nil)
+	provider.InsertNodeGroup(&panickingNodeGroup{provider.BuildNodeGroup("ng1", 1, 10, 1, false, "", nil)})
+	provider.AddNode("ng1", n1)
+	provider.AddNodeGroup("ng2", 1, 10, 1)
+	provider.AddNode("ng2", n2)
+
+	options := config.AutoscalingOptions{
+		EstimatorName:  estimator.BinpackingEstimatorName,
+		MaxCoresTotal:  config.DefaultMaxClusterCores,
+		MaxMemoryTotal: config.DefaultMaxClusterMemory,
+	}
+	context, err := NewScaleTestAutoscalingContext(options, &fake.Clientset{}, listers, provider, nil, nil)
+	assert.NoError(t, err)
+
+	nodes := []*apiv1.Node{n1, n2}
+	nodeInfos, _ := nodeinfosprovider.NewDefaultTemplateNodeInfoProvider(nil, false).Process(&context, nodes, []*appsv1.DaemonSet{}, taints.TaintConfig{}, now)
+	clusterState := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder, NewBackoff(), nodegroupconfig.NewDefaultNodeGroupConfigProcessor(config.NodeGroupAutoscalingOptions{MaxNodeProvisionTime: 15 * time.Minute}))
+	clusterState.UpdateNodes(nodes, nodeInfos, time.Now())
+	p1 := BuildTestPod("p-new", 80, 0)
+
+	processors := NewTestProcessors(&context)
+	suOrchestrator := New()
+	suOrchestrator.Initialize(&context, processors, clusterState, taints.TaintConfig{})
+	scaleUpStatus, err := suOrchestrator.ScaleUp([]*apiv1.Pod{p1}, nodes, []*appsv1.DaemonSet{}, nodeInfos)
+
+	assert.NoError(t, err)
+	assert.True(t, scaleUpStatus.WasSuccessful())
+	assert.Equal(t, "ng2-1", utils.GetStringFromChan(expandedGroups))
+	assert.False(t, clusterState.IsNodeGroupSafeToScaleUp(provider.GetNodeGroup("ng1"), time.Now()))
+}
+
 type constNodeGroupSetProcessor struct {
 	similarNodeGroups []cloudprovider.NodeGroup
 }
@@ -1417,6 +1520,54 @@ func TestScaleUpBalanceGroups(t *testing.T) {
 	assert.Equal(t, 2, ng3size)
 }
 
+func TestScaleUpSkipsIncompatibleKubeletVersion(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(func(string, int) error {
+		return nil
+	}, nil)
+
+	now := time.Now()
+	kubeletVersions := map[string]string{
+		"ng1": "v1.24.17",
+		"ng2": "v1.28.0",
+	}
+	var nodes []*apiv1.Node
+	var podList []*apiv1.Pod
+	for gid, kubeletVersion := range kubeletVersions {
+		provider.AddNodeGroup(gid, 1, 5, 1)
+		node := BuildTestNode(gid+"-node", 100, 1000)
+		node.Status.NodeInfo.KubeletVersion = kubeletVersion
+		SetNodeReadyState(node, true, now.Add(-2*time.Minute))
+		nodes = append(nodes, node)
+		provider.AddNode(gid, node)
+
+		pod := BuildTestPod(gid+"-pod", 80, 0)
+		pod.Spec.NodeName = node.Name
+		podList = append(podList, pod)
+	}
+
+	podLister := kube_util.NewTestPodLister(podList)
+	listers := kube_util.NewListerRegistry(nil, nil, podLister, nil, nil, nil, nil, nil, nil)
+
+	fakeClient := fake.NewSimpleClientset()
+	fakeClient.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: "v1.28.2"}
+	context, err := NewScaleTestAutoscalingContext(defaultOptions, fakeClient, listers, provider, nil, nil)
+	assert.NoError(t, err)
+
+	nodeInfos, _ := nodeinfosprovider.NewDefaultTemplateNodeInfoProvider(nil, false).Process(&context, nodes, []*appsv1.DaemonSet{}, taints.TaintConfig{}, now)
+	clusterState := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder, NewBackoff(), nodegroupconfig.NewDefaultNodeGroupConfigProcessor(config.NodeGroupAutoscalingOptions{MaxNodeProvisionTime: 15 * time.Minute}))
+	clusterState.UpdateNodes(nodes, nodeInfos, time.Now())
+
+	processors := NewTestProcessors(&context)
+	suOrchestrator := New()
+	suOrchestrator.Initialize(&context, processors, clusterState, taints.TaintConfig{})
+	scaleUpStatus, typedErr := suOrchestrator.ScaleUp([]*apiv1.Pod{BuildTestPod("p1", 80, 0)}, nodes, []*appsv1.DaemonSet{}, nodeInfos)
+
+	assert.NoError(t, typedErr)
+	assert.True(t, scaleUpStatus.WasSuccessful())
+	assert.Len(t, scaleUpStatus.ScaleUpInfos, 1)
+	assert.Equal(t, "ng2", scaleUpStatus.ScaleUpInfos[0].Group.Id())
+}
+
 func TestScaleUpAutoprovisionedNodeGroup(t *testing.T) {
 	createdGroups := make(chan string, 10)
 	expandedGroups := make(chan string, 10)
@@ -1630,7 +1781,7 @@ func TestCheckDeltaWithinLimits(t *testing.T) {
 }
 
 func TestAuthErrorHandling(t *testing.T) {
-	metrics.RegisterAll(false)
+	metrics.RegisterAll(false, false)
 	config := &ScaleUpTestConfig{
 		Groups: []NodeGroupConfig{
 			{Name: "ng1", MaxSize: 2},
@@ -43,6 +43,10 @@ var (
 	MaxLimitReachedReason = NewSkippedReasons("max node group size reached")
 	// NotReadyReason node group is not ready.
 	NotReadyReason = NewSkippedReasons("not ready for scale-up")
+	// IncompatibleVersionReason node group nodes would run a kubelet version incompatible with the control plane.
+	IncompatibleVersionReason = NewSkippedReasons("kubelet version incompatible with control plane")
+	// PanicReason processing the node group panicked.
+	PanicReason = NewSkippedReasons("node group processing panicked")
 )
 
 // MaxResourceLimitReached contains information why given node group was skipped.
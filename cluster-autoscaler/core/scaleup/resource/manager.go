@@ -27,6 +27,7 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/core/utils"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/customresources"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
 	"k8s.io/klog/v2"
 	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
 )
@@ -168,7 +169,7 @@ func (m *Manager) ApplyLimits(ctx *context.AutoscalingContext, newCount int, res
 		}
 
 		newCount = int(limit / resourceDelta)
-		klog.V(1).Infof("Capping scale-up size due to limit for resource %s", resource)
+		klogx.Core.V(1).Infof("Capping scale-up size due to limit for resource %s", resource)
 		if newCount < 1 {
 			// should never happen - checked before
 			return 0, errors.NewAutoscalerError(
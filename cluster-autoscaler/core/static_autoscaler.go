@@ -24,11 +24,13 @@ import (
 
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/autoscaler/cluster-autoscaler/core/orphans"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/pdb"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/planner"
 	scaledownstatus "k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaleup"
 	orchestrator "k8s.io/autoscaler/cluster-autoscaler/core/scaleup/orchestrator"
+	"k8s.io/autoscaler/cluster-autoscaler/core/surge"
 	"k8s.io/autoscaler/cluster-autoscaler/debuggingsnapshot"
 	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/taints"
@@ -55,12 +57,16 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/options"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/predicatechecker"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/backoff"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
 	caerrors "k8s.io/autoscaler/cluster-autoscaler/utils/errors"
+	pod_util "k8s.io/autoscaler/cluster-autoscaler/utils/pod"
 	scheduler_utils "k8s.io/autoscaler/cluster-autoscaler/utils/scheduler"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/tpu"
 	"k8s.io/utils/integer"
 
 	klog "k8s.io/klog/v2"
+
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
 )
 
 const (
@@ -74,6 +80,9 @@ const (
 	// NodeUpcomingAnnotation is an annotation CA adds to nodes which are upcoming.
 	NodeUpcomingAnnotation = "cluster-autoscaler.k8s.io/upcoming-node"
 
+	// NodeUpcomingNodeGroupAnnotation is an annotation CA adds to upcoming nodes with the id of their node group.
+	NodeUpcomingNodeGroupAnnotation = "cluster-autoscaler.k8s.io/upcoming-node-group"
+
 	// podScaleUpDelayAnnotationKey is an annotation how long pod can wait to be scaled up.
 	podScaleUpDelayAnnotationKey = "cluster-autoscaler.kubernetes.io/pod-scale-up-delay"
 )
@@ -94,6 +103,13 @@ type StaticAutoscaler struct {
 	processorCallbacks      *staticAutoscalerProcessorCallbacks
 	initialized             bool
 	taintConfig             taints.TaintConfig
+	orphanedNodeGroups      *orphans.Tracker
+	surgeCapacity           *surge.Tracker
+	scaleUpHintsRestored    bool
+	readinessCheck          *metrics.ReadinessCheck
+	// lastUnschedulablePodCount is the number of unschedulable pods considered for scale-up in the
+	// previous loop, used to adapt the new pod scale-up delays.
+	lastUnschedulablePodCount int
 }
 
 type staticAutoscalerProcessorCallbacks struct {
@@ -147,9 +163,18 @@ func NewStaticAutoscaler(
 	deleteOptions options.NodeDeleteOptions,
 	drainabilityRules rules.Rules) *StaticAutoscaler {
 
+	orphanedNodeGroups := orphans.NewTracker(orphans.Policy(opts.OrphanedNodeGroupPolicy))
+	if policy := orphanedNodeGroups.Policy(); policy == orphans.AdoptPolicy || policy == orphans.DrainPolicy {
+		// Nodes of orphaned node groups are kept in their node group, which excludes them from scale-downs
+		// and, as orphaned node groups aren't listed, from scale-ups. With DrainPolicy, they are deleted by
+		// the scale-down actuator, which needs their node group.
+		cloudProvider = orphanedNodeGroups.CloudProvider(cloudProvider)
+	}
+
 	clusterStateConfig := clusterstate.ClusterStateRegistryConfig{
 		MaxTotalUnreadyPercentage: opts.MaxTotalUnreadyPercentage,
 		OkTotalUnreadyCount:       opts.OkTotalUnreadyCount,
+		TargetSizeRefreshInterval: opts.NodeGroupTargetSizeRefreshInterval,
 	}
 	clusterStateRegistry := clusterstate.NewClusterStateRegistry(cloudProvider, clusterStateConfig, autoscalingKubeClients.LogRecorder, backoff, processors.NodeGroupConfigProcessor)
 	processorCallbacks := newStaticAutoscalerProcessorCallbacks()
@@ -197,6 +222,10 @@ func NewStaticAutoscaler(
 	// Set the initial scale times to be less than the start time so as to
 	// not start in cooldown mode.
 	initialScaleTime := time.Now().Add(-time.Hour)
+	var surgeCapacity *surge.Tracker
+	if opts.SurgeCapacityEnabled {
+		surgeCapacity = surge.NewTracker()
+	}
 	return &StaticAutoscaler{
 		AutoscalingContext:      autoscalingContext,
 		lastScaleUpTime:         initialScaleTime,
@@ -209,6 +238,8 @@ func NewStaticAutoscaler(
 		processorCallbacks:      processorCallbacks,
 		clusterStateRegistry:    clusterStateRegistry,
 		taintConfig:             taintConfig,
+		orphanedNodeGroups:      orphanedNodeGroups,
+		surgeCapacity:           surgeCapacity,
 	}
 }
 
@@ -230,7 +261,7 @@ func (a *StaticAutoscaler) cleanUpIfRequired() {
 		klog.Errorf("Failed to list ready nodes, not cleaning up taints: %v", err)
 	} else {
 		taints.CleanAllToBeDeleted(allNodes,
-			a.AutoscalingContext.ClientSet, a.Recorder, a.CordonNodeBeforeTerminate)
+			a.AutoscalingContext.ClientSet, a.Recorder, a.CordonNodeBeforeTerminate, a.TaintOwner)
 		if a.AutoscalingContext.AutoscalingOptions.MaxBulkSoftTaintCount == 0 {
 			// Clean old taints if soft taints handling is disabled
 			taints.CleanAllDeletionCandidates(allNodes,
@@ -240,7 +271,7 @@ func (a *StaticAutoscaler) cleanUpIfRequired() {
 	a.initialized = true
 }
 
-func (a *StaticAutoscaler) initializeClusterSnapshot(nodes []*apiv1.Node, scheduledPods []*apiv1.Pod) caerrors.AutoscalerError {
+func (a *StaticAutoscaler) initializeClusterSnapshot(nodes []*apiv1.Node, scheduledPods []*apiv1.Pod, currentTime time.Time) caerrors.AutoscalerError {
 	a.ClusterSnapshot.Clear()
 
 	knownNodes := make(map[string]bool)
@@ -252,6 +283,10 @@ func (a *StaticAutoscaler) initializeClusterSnapshot(nodes []*apiv1.Node, schedu
 		knownNodes[node.Name] = true
 	}
 	for _, pod := range scheduledPods {
+		// With a threshold set, long terminating pods don't occupy capacity, consistently with utilization.Calculate.
+		if a.TerminatingPodThreshold > 0 && drain.IsPodLongTerminatingWithThreshold(pod, currentTime, a.TerminatingPodThreshold) {
+			continue
+		}
 		if knownNodes[pod.Spec.NodeName] {
 			if err := a.ClusterSnapshot.AddPod(pod, pod.Spec.NodeName); err != nil {
 				klog.Errorf("Failed to add pod %s scheduled to node %s to cluster snapshot: %v", pod.Name, pod.Spec.NodeName, err)
@@ -279,7 +314,6 @@ func (a *StaticAutoscaler) initializeRemainingPdbTracker() caerrors.AutoscalerEr
 
 // RunOnce iterates over node groups and scales them up/down if necessary
 func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerError {
-	a.cleanUpIfRequired()
 	a.processorCallbacks.reset()
 	a.clusterStateRegistry.PeriodicCleanup()
 	a.DebuggingSnapshotter.StartDataCollection()
@@ -288,7 +322,7 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 	podLister := a.AllPodLister()
 	autoscalingContext := a.AutoscalingContext
 
-	klog.V(4).Info("Starting main loop")
+	klogx.Core.V(4).Info("Starting main loop")
 
 	stateUpdateStart := time.Now()
 
@@ -303,6 +337,10 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 		a.AutoscalingContext, allNodes, readyNodes, currentTime); abortLoop {
 		return err
 	}
+	// Cleaning up needs to come after the abort check, which pauses autoscaling
+	// altogether, including the changes made by the clean up. The autoscaler stays
+	// uninitialized until then, so the clean up runs once autoscaling is resumed.
+	a.cleanUpIfRequired()
 
 	pods, err := podLister.List()
 	if err != nil {
@@ -331,6 +369,9 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 		klog.Errorf("Failed to refresh cloud provider config: %v", err)
 		return caerrors.ToAutoscalerError(caerrors.CloudProviderError, err)
 	}
+	if a.readinessCheck != nil {
+		a.readinessCheck.UpdateLastCloudProviderRefresh(time.Now())
+	}
 
 	// Update node groups min/max and maximum number of nodes being set for all node groups after cloud provider refresh
 	maxNodesCount := 0
@@ -346,7 +387,7 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 	}
 	nonExpendableScheduledPods := core_utils.FilterOutExpendablePods(originalScheduledPods, a.ExpendablePodsPriorityCutoff)
 	// Initialize cluster state to ClusterSnapshot
-	if typedErr := a.initializeClusterSnapshot(allNodes, nonExpendableScheduledPods); typedErr != nil {
+	if typedErr := a.initializeClusterSnapshot(allNodes, nonExpendableScheduledPods, currentTime); typedErr != nil {
 		return typedErr.AddPrefix("failed to initialize ClusterSnapshot: ")
 	}
 	// Initialize Pod Disruption Budget tracking
@@ -368,12 +409,16 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 		return caerrors.ToAutoscalerError(caerrors.InternalError, err)
 	}
 
+	a.restoreScaleUpHints(currentTime)
 	if typedErr := a.updateClusterState(allNodes, nodeInfosForGroups, currentTime); typedErr != nil {
 		klog.Errorf("Failed to update cluster state: %v", typedErr)
 		return typedErr
 	}
 	metrics.UpdateDurationFromStart(metrics.UpdateState, stateUpdateStart)
 
+	a.handleOrphanedNodeGroups(allNodes, currentTime)
+	a.handleSurgeCapacity(allNodes, currentTime)
+
 	scaleUpStatus := &status.ScaleUpStatus{Result: status.ScaleUpNotTried}
 	scaleUpStatusProcessorAlreadyCalled := false
 	scaleDownStatus := &scaledownstatus.ScaleDownStatus{Result: scaledownstatus.ScaleDownNotTried}
@@ -381,11 +426,18 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 
 	defer func() {
 		// Update status information when the loop is done (regardless of reason)
+		pendingPodGroups := a.estimateTimeToSchedule(unschedulablePods, scaleUpStatus, currentTime)
+		updatePendingPodGroupsMetrics(pendingPodGroups)
 		if autoscalingContext.WriteStatusConfigMap {
 			status := a.clusterStateRegistry.GetStatus(currentTime)
+			if a.orphanedNodeGroups != nil {
+				status.NodeGroupStatuses = append(status.NodeGroupStatuses, a.orphanedNodeGroups.Status(currentTime)...)
+			}
+			status.PendingPodGroups = pendingPodGroups
 			utils.WriteStatusConfigMap(autoscalingContext.ClientSet, autoscalingContext.ConfigNamespace,
 				status.GetReadableString(), a.AutoscalingContext.LogRecorder, a.AutoscalingContext.StatusConfigMapName)
 		}
+		a.persistScaleUpHints()
 
 		// This deferred processor execution allows the processors to handle a situation when a scale-(up|down)
 		// wasn't even attempted because e.g. the iteration exited earlier.
@@ -407,7 +459,7 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 	// master.
 	unregisteredNodes := a.clusterStateRegistry.GetUnregisteredNodes()
 	if len(unregisteredNodes) > 0 {
-		klog.V(1).Infof("%d unregistered nodes present", len(unregisteredNodes))
+		klogx.Core.V(1).Infof("%d unregistered nodes present", len(unregisteredNodes))
 		removedAny, err := a.removeOldUnregisteredNodes(unregisteredNodes, autoscalingContext,
 			a.clusterStateRegistry, currentTime, autoscalingContext.LogRecorder)
 		// There was a problem with removing unregistered nodes. Retry in the next loop.
@@ -415,7 +467,7 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 			klog.Warningf("Failed to remove unregistered nodes: %v", err)
 		}
 		if removedAny {
-			klog.V(0).Infof("Some unregistered nodes were removed")
+			klogx.Core.V(0).Infof("Some unregistered nodes were removed")
 		}
 	}
 
@@ -432,7 +484,7 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 		return nil
 	}
 	if danglingNodes {
-		klog.V(0).Infof("Some nodes that failed to create were removed, skipping iteration")
+		klogx.Core.V(0).Infof("Some nodes that failed to create were removed, skipping iteration")
 		return nil
 	}
 
@@ -445,7 +497,7 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 		return caerrors.ToAutoscalerError(caerrors.CloudProviderError, err)
 	}
 	if fixedSomething {
-		klog.V(0).Infof("Some node group target size was fixed, skipping the iteration")
+		klogx.Core.V(0).Infof("Some node group target size was fixed, skipping the iteration")
 		return nil
 	}
 
@@ -549,10 +601,10 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 
 	if len(unschedulablePodsToHelp) == 0 {
 		scaleUpStatus.Result = status.ScaleUpNotNeeded
-		klog.V(1).Info("No unschedulable pods")
+		klogx.Core.V(1).Info("No unschedulable pods")
 	} else if a.MaxNodesTotal > 0 && len(readyNodes) >= a.MaxNodesTotal {
 		scaleUpStatus.Result = status.ScaleUpNoOptionsAvailable
-		klog.V(1).Info("Max total nodes in cluster reached")
+		klogx.Core.V(1).Info("Max total nodes in cluster reached")
 	} else if allPodsAreNew(unschedulablePodsToHelp, currentTime) {
 		// The assumption here is that these pods have been created very recently and probably there
 		// is more pods to come. In theory we could check the newest pod time but then if pod were created
@@ -560,7 +612,7 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 		// We also want to skip a real scale down (just like if the pods were handled).
 		a.processorCallbacks.DisableScaleDownForLoop()
 		scaleUpStatus.Result = status.ScaleUpInCooldown
-		klog.V(1).Info("Unschedulable pods are very new, waiting one iteration for more")
+		klogx.Core.V(1).Info("Unschedulable pods are very new, waiting one iteration for more")
 	} else {
 		scaleUpStart := preScaleUp()
 		scaleUpStatus, typedErr = a.scaleUpOrchestrator.ScaleUp(unschedulablePodsToHelp, readyNodes, daemonsets, nodeInfosForGroups)
@@ -572,7 +624,7 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 	if a.ScaleDownEnabled {
 		unneededStart := time.Now()
 
-		klog.V(4).Infof("Calculating unneeded nodes")
+		klogx.Core.V(4).Infof("Calculating unneeded nodes")
 
 		var scaleDownCandidates []*apiv1.Node
 		var podDestinations []*apiv1.Node
@@ -600,6 +652,7 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 				return err
 			}
 		}
+		scaleDownCandidates = a.filterOutSurgingNodes(scaleDownCandidates)
 
 		typedErr := a.scaleDownPlanner.UpdateClusterState(podDestinations, scaleDownCandidates, scaleDownActuationStatus, currentTime)
 		// Update clusterStateRegistry and metrics regardless of whether ScaleDown was successful or not.
@@ -619,7 +672,7 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 			a.lastScaleDownFailTime.Add(a.ScaleDownDelayAfterFailure).After(currentTime) ||
 			a.lastScaleDownDeleteTime.Add(a.ScaleDownDelayAfterDelete).After(currentTime)
 
-		klog.V(4).Infof("Scale down status: lastScaleUpTime=%s lastScaleDownDeleteTime=%v "+
+		klogx.Core.V(4).Infof("Scale down status: lastScaleUpTime=%s lastScaleDownDeleteTime=%v "+
 			"lastScaleDownFailTime=%s scaleDownForbidden=%v scaleDownInCooldown=%v",
 			a.lastScaleUpTime, a.lastScaleDownDeleteTime, a.lastScaleDownFailTime,
 			a.processorCallbacks.disableScaleDownForLoop, scaleDownInCooldown)
@@ -644,7 +697,7 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 				a.processors.ScaleDownStatusProcessor.Process(autoscalingContext, scaleDownStatus)
 			}
 		} else {
-			klog.V(4).Infof("Starting scale down")
+			klogx.Core.V(4).Infof("Starting scale down")
 
 			scaleDownStart := time.Now()
 			metrics.UpdateLastTime(metrics.ScaleDown, scaleDownStart)
@@ -711,13 +764,14 @@ func fixNodeGroupSize(context *context.AutoscalingContext, clusterStateRegistry
 		if incorrectSize.FirstObserved.Add(maxNodeProvisionTime).Before(currentTime) {
 			delta := incorrectSize.CurrentSize - incorrectSize.ExpectedSize
 			if delta < 0 {
-				klog.V(0).Infof("Decreasing size of %s, expected=%d current=%d delta=%d", nodeGroup.Id(),
+				klogx.Core.V(0).Infof("Decreasing size of %s, expected=%d current=%d delta=%d", nodeGroup.Id(),
 					incorrectSize.ExpectedSize,
 					incorrectSize.CurrentSize,
 					delta)
 				if err := nodeGroup.DecreaseTargetSize(delta); err != nil {
 					return fixed, fmt.Errorf("failed to decrease %s: %v", nodeGroup.Id(), err)
 				}
+				clusterStateRegistry.InvalidateNodeInstancesCacheEntry(nodeGroup)
 				fixed = true
 			}
 		}
@@ -725,6 +779,264 @@ func fixNodeGroupSize(context *context.AutoscalingContext, clusterStateRegistry
 	return fixed, nil
 }
 
+// handleOrphanedNodeGroups reports node groups that are no longer returned by the cloud provider,
+// but still have nodes, and drains them toward zero if requested by the policy.
+func (a *StaticAutoscaler) handleOrphanedNodeGroups(allNodes []*apiv1.Node, currentTime time.Time) {
+	if a.orphanedNodeGroups == nil {
+		return
+	}
+	policy := a.orphanedNodeGroups.Policy()
+	orphaned := a.orphanedNodeGroups.Update(a.CloudProvider, allNodes, currentTime)
+	metrics.UpdateOrphanedNodeGroupsCount(len(orphaned))
+	for _, nodeGroup := range orphaned {
+		if nodeGroup.OrphanedSince.Equal(currentTime) {
+			eventType := apiv1.EventTypeWarning
+			if policy == orphans.AdoptPolicy {
+				eventType = apiv1.EventTypeNormal
+			}
+			klog.Warningf("Node group %s is no longer returned by the cloud provider, but still has %d nodes, applying %s policy", nodeGroup.Id(), len(nodeGroup.Nodes), policy)
+			a.LogRecorder.Eventf(eventType, "OrphanedNodeGroup",
+				"Node group %s is no longer returned by the cloud provider, but still has %d nodes, applying %s policy", nodeGroup.Id(), len(nodeGroup.Nodes), policy)
+		}
+		if policy == orphans.DrainPolicy {
+			a.drainOrphanedNodeGroup(nodeGroup)
+		}
+	}
+}
+
+// handleSurgeCapacity scales node groups up for new surge capacity requests, and reclaims
+// the surge nodes of released requests once they are empty.
+func (a *StaticAutoscaler) handleSurgeCapacity(allNodes []*apiv1.Node, currentTime time.Time) {
+	if a.surgeCapacity == nil || a.ClientSet == nil || a.ConfigMapLister == nil {
+		return
+	}
+	requests, err := surge.ListRequests(a.ConfigMapLister, a.MaxSurgeCapacityDuration)
+	if err != nil {
+		klog.Warningf("Failed to list surge capacity requests: %v", err)
+		return
+	}
+	nodeGroups := a.nodeGroupsById()
+	for _, request := range a.surgeCapacity.Update(requests, currentTime) {
+		nodeGroup, found := nodeGroups[request.NodeGroupId]
+		if !found {
+			continue
+		}
+		targetSize, err := nodeGroup.TargetSize()
+		if err != nil {
+			klog.Warningf("Failed to get target size of node group %s: %v", nodeGroup.Id(), err)
+			continue
+		}
+		if nodes := a.surgeCapacity.Release(request, targetSize); nodes > 0 {
+			klogx.Core.V(0).Infof("Surge capacity request %s released, reclaiming %d nodes of node group %s", request.Key, nodes, nodeGroup.Id())
+		}
+	}
+	for _, request := range a.surgeCapacity.Pending() {
+		nodeGroup, found := nodeGroups[request.NodeGroupId]
+		if !found {
+			klog.Warningf("Surge capacity request %s refers to unknown node group %s", request.Key, request.NodeGroupId)
+			continue
+		}
+		a.grantSurgeCapacity(nodeGroup, request, currentTime)
+	}
+	a.reclaimSurgeCapacity(allNodes, nodeGroups)
+}
+
+// grantSurgeCapacity increases the size of the node group by the nodes of the request, within its max size.
+func (a *StaticAutoscaler) grantSurgeCapacity(nodeGroup cloudprovider.NodeGroup, request *surge.Request, currentTime time.Time) {
+	targetSize, err := nodeGroup.TargetSize()
+	if err != nil {
+		klog.Warningf("Failed to get target size of node group %s: %v", nodeGroup.Id(), err)
+		return
+	}
+	nodes := integer.IntMin(request.Nodes, nodeGroup.MaxSize()-targetSize)
+	if nodes > 0 {
+		if err := nodeGroup.IncreaseSize(nodes); err != nil {
+			klog.Warningf("Failed to add %d surge nodes to node group %s: %v", nodes, nodeGroup.Id(), err)
+			a.LogRecorder.Eventf(apiv1.EventTypeWarning, "FailedToSurgeGroup", "Failed to add %d surge nodes to group %s: %v", nodes, nodeGroup.Id(), err)
+			return
+		}
+		a.clusterStateRegistry.RegisterOrUpdateScaleUp(nodeGroup, nodes, currentTime)
+	} else {
+		nodes = 0
+	}
+	a.surgeCapacity.Grant(request, nodes, targetSize)
+	klogx.Core.V(0).Infof("Surge capacity request %s: added %d of %d requested nodes to node group %s", request.Key, nodes, request.Nodes, nodeGroup.Id())
+	a.LogRecorder.Eventf(apiv1.EventTypeNormal, "SurgedGroup",
+		"Surge capacity: added %d of %d requested nodes to group %s (size: %d, max: %d)", nodes, request.Nodes, nodeGroup.Id(), targetSize+nodes, nodeGroup.MaxSize())
+	if err := surge.RecordGrant(a.ClientSet, a.ConfigMapLister, request); err != nil {
+		klog.Warningf("Failed to record grant of surge capacity request %s: %v", request.Key, err)
+	}
+}
+
+// reclaimSurgeCapacity deletes empty nodes of node groups with surge capacity to reclaim through the
+// scale-down actuator, without going below their min size. Surge nodes that don't get empty are left to
+// regular scale-down.
+func (a *StaticAutoscaler) reclaimSurgeCapacity(allNodes []*apiv1.Node, nodeGroups map[string]cloudprovider.NodeGroup) {
+	toReclaim := a.surgeCapacity.ToReclaim()
+	if len(toReclaim) == 0 {
+		return
+	}
+	emptyNodes := make(map[string][]*apiv1.Node)
+	for _, node := range allNodes {
+		nodeGroup, err := a.CloudProvider.NodeGroupForNode(node)
+		if err != nil || nodeGroup == nil || reflect.ValueOf(nodeGroup).IsNil() {
+			continue
+		}
+		if toReclaim[nodeGroup.Id()] > len(emptyNodes[nodeGroup.Id()]) && !taints.HasToBeDeletedTaint(node) && a.isEmptyNode(node.Name) {
+			emptyNodes[nodeGroup.Id()] = append(emptyNodes[nodeGroup.Id()], node)
+		}
+	}
+	var empty []*apiv1.Node
+	for id, nodes := range toReclaim {
+		nodeGroup, found := nodeGroups[id]
+		if !found {
+			a.surgeCapacity.Forget(id)
+			continue
+		}
+		if a.surgeCapacity.Surging(id) {
+			// A new surge is in progress, reclaim nodes once it's released.
+			continue
+		}
+		targetSize, err := nodeGroup.TargetSize()
+		if err != nil {
+			klog.Warningf("Failed to get target size of node group %s: %v", id, err)
+			continue
+		}
+		// Nodes of the node group already being deleted are on their way out.
+		nodes = integer.IntMin(nodes, targetSize-a.scaleDownActuator.CheckStatus().DeletionsCount(id)-nodeGroup.MinSize())
+		if nodes <= 0 {
+			a.surgeCapacity.Forget(id)
+			continue
+		}
+		candidates := emptyNodes[id]
+		if len(candidates) > nodes {
+			candidates = candidates[:nodes]
+		}
+		empty = append(empty, candidates...)
+	}
+	if len(empty) == 0 {
+		return
+	}
+	// The actuator taints the nodes, tracks their deletion and registers the scale-down, its results are
+	// reported by the next regular scale-down.
+	scaleDownStatus, err := a.scaleDownActuator.StartDeletion(empty, nil)
+	if err != nil {
+		klog.Warningf("Failed to reclaim surge nodes: %v", err)
+	}
+	if scaleDownStatus == nil {
+		return
+	}
+	for _, scaledDown := range scaleDownStatus.ScaledDownNodes {
+		if scaledDown.NodeGroup == nil {
+			continue
+		}
+		a.surgeCapacity.Reclaimed(scaledDown.NodeGroup.Id(), 1)
+		klogx.Core.V(0).Infof("Reclaiming surge node %s of node group %s", scaledDown.Node.Name, scaledDown.NodeGroup.Id())
+		a.LogRecorder.Eventf(apiv1.EventTypeNormal, "ReclaimSurgeNode",
+			"Reclaiming surge node %s of node group %s", scaledDown.Node.Name, scaledDown.NodeGroup.Id())
+	}
+}
+
+// filterOutSurgingNodes removes nodes of node groups with active surge capacity requests from scale-down candidates.
+func (a *StaticAutoscaler) filterOutSurgingNodes(nodes []*apiv1.Node) []*apiv1.Node {
+	if a.surgeCapacity == nil {
+		return nodes
+	}
+	result := make([]*apiv1.Node, 0, len(nodes))
+	for _, node := range nodes {
+		nodeGroup, err := a.CloudProvider.NodeGroupForNode(node)
+		if err == nil && nodeGroup != nil && !reflect.ValueOf(nodeGroup).IsNil() && a.surgeCapacity.Surging(nodeGroup.Id()) {
+			klogx.Core.V(4).Infof("Skipping %s from scale-down considerations as its node group %s is surging", node.Name, nodeGroup.Id())
+			continue
+		}
+		result = append(result, node)
+	}
+	return result
+}
+
+// restoreScaleUpHints registers scale-ups persisted by a previous run, so that nodes
+// still coming up are not requested again. Hints are restored only once per runtime.
+func (a *StaticAutoscaler) restoreScaleUpHints(currentTime time.Time) {
+	if a.scaleUpHintsRestored || a.ScaleUpHintsConfigMapName == "" || a.ConfigMapLister == nil {
+		return
+	}
+	hints, err := clusterstate.ReadScaleUpHintsConfigMap(a.ConfigMapLister, a.ScaleUpHintsConfigMapName)
+	if err != nil {
+		klog.Warningf("Failed to read scale-up hints: %v", err)
+		return
+	}
+	a.clusterStateRegistry.RestoreScaleUpHints(hints, currentTime)
+	a.scaleUpHintsRestored = true
+}
+
+// persistScaleUpHints stores the scale-ups in progress, to be restored after a restart.
+func (a *StaticAutoscaler) persistScaleUpHints() {
+	if !a.scaleUpHintsRestored || a.ScaleUpHintsConfigMapName == "" {
+		// Don't overwrite hints of a previous run before they are restored.
+		return
+	}
+	hints := a.clusterStateRegistry.GetScaleUpHints()
+	if err := clusterstate.WriteScaleUpHintsConfigMap(a.ClientSet, a.ConfigMapLister, a.ConfigNamespace, a.ScaleUpHintsConfigMapName, hints); err != nil {
+		klog.Warningf("Failed to persist scale-up hints: %v", err)
+	}
+}
+
+// drainOrphanedNodeGroup taints all nodes of the orphaned node group, so that no new pods land on them,
+// and deletes the ones that are already empty with the scale-down actuator.
+func (a *StaticAutoscaler) drainOrphanedNodeGroup(nodeGroup *orphans.NodeGroup) {
+	deleting := make(map[string]bool)
+	emptyInProgress, drainInProgress := a.ScaleDownActuator.CheckStatus().DeletionsInProgress()
+	for _, name := range append(emptyInProgress, drainInProgress...) {
+		deleting[name] = true
+	}
+	var emptyNodes []*apiv1.Node
+	for _, node := range nodeGroup.Nodes {
+		if deleting[node.Name] {
+			continue
+		}
+		if !taints.HasToBeDeletedTaint(node) {
+			if err := taints.MarkToBeDeleted(node, a.ClientSet, a.CordonNodeBeforeTerminate, a.TaintOwner); err != nil {
+				klog.Warningf("Failed to taint node %s of orphaned node group %s: %v", node.Name, nodeGroup.Id(), err)
+				continue
+			}
+		}
+		if a.isEmptyNode(node.Name) {
+			emptyNodes = append(emptyNodes, node)
+		}
+	}
+	if len(emptyNodes) == 0 {
+		return
+	}
+	// The actuator limits the deletions to the scale-down parallelism, tracks them and registers the
+	// scale-down, its results are reported by the next regular scale-down.
+	scaleDownStatus, err := a.ScaleDownActuator.StartDeletion(emptyNodes, nil)
+	if err != nil {
+		klog.Warningf("Failed to remove empty nodes of orphaned node group %s: %v", nodeGroup.Id(), err)
+	}
+	if scaleDownStatus == nil {
+		return
+	}
+	for _, scaledDown := range scaleDownStatus.ScaledDownNodes {
+		klogx.Core.V(0).Infof("Removing empty node %s of orphaned node group %s", scaledDown.Node.Name, nodeGroup.Id())
+		a.LogRecorder.Eventf(apiv1.EventTypeNormal, "DeleteOrphanedNode",
+			"Removing empty node %s of orphaned node group %s", scaledDown.Node.Name, nodeGroup.Id())
+	}
+}
+
+// isEmptyNode returns true if only DaemonSet and mirror pods are running on the node in the cluster snapshot.
+func (a *StaticAutoscaler) isEmptyNode(nodeName string) bool {
+	nodeInfo, err := a.ClusterSnapshot.NodeInfos().Get(nodeName)
+	if err != nil {
+		return false
+	}
+	for _, podInfo := range nodeInfo.Pods {
+		if !pod_util.IsDaemonSetPod(podInfo.Pod) && !pod_util.IsMirrorPod(podInfo.Pod) {
+			return false
+		}
+	}
+	return true
+}
+
 // Removes unregistered nodes if needed. Returns true if anything was removed and error if such occurred.
 func (a *StaticAutoscaler) removeOldUnregisteredNodes(allUnregisteredNodes []clusterstate.UnregisteredNode, context *context.AutoscalingContext,
 	csr *clusterstate.ClusterStateRegistry, currentTime time.Time, logRecorder *utils.LogEventRecorder) (bool, error) {
@@ -748,7 +1060,7 @@ func (a *StaticAutoscaler) removeOldUnregisteredNodes(allUnregisteredNodes []clu
 		}
 
 		if unregisteredNode.UnregisteredSince.Add(maxNodeProvisionTime).Before(currentTime) {
-			klog.V(0).Infof("Marking unregistered node %v for removal", unregisteredNode.Node.Name)
+			klogx.Core.V(0).Infof("Marking unregistered node %v for removal", unregisteredNode.Node.Name)
 			nodesToBeDeletedByNodeGroupId[nodeGroup.Id()] = append(nodesToBeDeletedByNodeGroupId[nodeGroup.Id()], unregisteredNode)
 		}
 	}
@@ -757,7 +1069,7 @@ func (a *StaticAutoscaler) removeOldUnregisteredNodes(allUnregisteredNodes []clu
 	for nodeGroupId, unregisteredNodesToDelete := range nodesToBeDeletedByNodeGroupId {
 		nodeGroup := nodeGroups[nodeGroupId]
 
-		klog.V(0).Infof("Removing %v unregistered nodes for node group %v", len(unregisteredNodesToDelete), nodeGroupId)
+		klogx.Core.V(0).Infof("Removing %v unregistered nodes for node group %v", len(unregisteredNodesToDelete), nodeGroupId)
 		size, err := nodeGroup.TargetSize()
 		if err != nil {
 			klog.Warningf("Failed to get node group size; nodeGroup=%v; err=%v", nodeGroup.Id(), err)
@@ -782,15 +1094,16 @@ func (a *StaticAutoscaler) removeOldUnregisteredNodes(allUnregisteredNodes []clu
 		// If a scale-up of "ZeroOrMaxNodeScaling" node group failed, the cleanup
 		// should stick to the all-or-nothing principle. Deleting all nodes.
 		if opts != nil && opts.ZeroOrMaxNodeScaling {
-			instances, err := nodeGroup.Nodes()
-			if err != nil {
-				klog.Warningf("Failed to fill in unregistered nodes from group %s based on ZeroOrMaxNodeScaling option: %s", nodeGroupId, err)
+			instances, nodesErr := nodeGroup.Nodes()
+			if nodesErr != nil {
+				klog.Warningf("Failed to fill in unregistered nodes from group %s based on ZeroOrMaxNodeScaling option: %s", nodeGroupId, nodesErr)
 				continue
 			}
 			nodesToDelete = instancesToFakeNodes(instances)
+			err = forceDeleteNodes(nodeGroup, nodesToDelete)
+		} else {
+			err = nodeGroup.DeleteNodes(nodesToDelete)
 		}
-
-		err = nodeGroup.DeleteNodes(nodesToDelete)
 		csr.InvalidateNodeInstancesCacheEntry(nodeGroup)
 		if err != nil {
 			klog.Warningf("Failed to remove %v unregistered nodes from node group %s: %v", len(nodesToDelete), nodeGroupId, err)
@@ -847,28 +1160,30 @@ func (a *StaticAutoscaler) deleteCreatedNodesWithErrors() (bool, error) {
 
 	for nodeGroupId, nodesToBeDeleted := range nodesToBeDeletedByNodeGroupId {
 		var err error
-		klog.V(1).Infof("Deleting %v from %v node group because of create errors", len(nodesToBeDeleted), nodeGroupId)
+		klogx.Core.V(1).Infof("Deleting %v from %v node group because of create errors", len(nodesToBeDeleted), nodeGroupId)
 
 		nodeGroup := nodeGroups[nodeGroupId]
 		if nodeGroup == nil {
 			err = fmt.Errorf("node group %s not found", nodeGroupId)
 		} else {
-			opts, err := nodeGroup.GetOptions(a.NodeGroupDefaults)
-			if err != nil {
-				klog.Warningf("Failed to get node group options for %s: %s", nodeGroupId, err)
+			opts, optsErr := nodeGroup.GetOptions(a.NodeGroupDefaults)
+			if optsErr != nil {
+				klog.Warningf("Failed to get node group options for %s: %s", nodeGroupId, optsErr)
 				continue
 			}
 			// If a scale-up of "ZeroOrMaxNodeScaling" node group failed, the cleanup
 			// should stick to the all-or-nothing principle. Deleting all nodes.
 			if opts != nil && opts.ZeroOrMaxNodeScaling {
-				instances, err := nodeGroup.Nodes()
-				if err != nil {
-					klog.Warningf("Failed to fill in failed nodes from group %s based on ZeroOrMaxNodeScaling option: %s", nodeGroupId, err)
+				instances, nodesErr := nodeGroup.Nodes()
+				if nodesErr != nil {
+					klog.Warningf("Failed to fill in failed nodes from group %s based on ZeroOrMaxNodeScaling option: %s", nodeGroupId, nodesErr)
 					continue
 				}
 				nodesToBeDeleted = instancesToFakeNodes(instances)
+				err = forceDeleteNodes(nodeGroup, nodesToBeDeleted)
+			} else {
+				err = nodeGroup.DeleteNodes(nodesToBeDeleted)
 			}
-			err = nodeGroup.DeleteNodes(nodesToBeDeleted)
 		}
 
 		if err != nil {
@@ -882,6 +1197,16 @@ func (a *StaticAutoscaler) deleteCreatedNodesWithErrors() (bool, error) {
 	return deletedAny, nil
 }
 
+// forceDeleteNodes deletes the nodes regardless of the node group constraints, falling back
+// to a regular deletion for cloud providers not supporting it.
+func forceDeleteNodes(nodeGroup cloudprovider.NodeGroup, nodes []*apiv1.Node) error {
+	err := nodeGroup.ForceDeleteNodes(nodes)
+	if err == cloudprovider.ErrNotImplemented {
+		err = nodeGroup.DeleteNodes(nodes)
+	}
+	return err
+}
+
 // instancesToNodes returns a list of fake nodes with just names populated,
 // so that they can be passed as nodes to delete
 func instancesToFakeNodes(instances []cloudprovider.Instance) []*apiv1.Node {
@@ -900,21 +1225,25 @@ func (a *StaticAutoscaler) nodeGroupsById() map[string]cloudprovider.NodeGroup {
 	return nodeGroups
 }
 
-// Don't consider pods newer than newPodScaleUpDelay or annotated podScaleUpDelay
-// seconds old as unschedulable.
+// Don't consider pods newer than newPodScaleUpDelay, the delay of their priority class or annotated
+// podScaleUpDelay seconds old as unschedulable.
 func (a *StaticAutoscaler) filterOutYoungPods(allUnschedulablePods []*apiv1.Pod, currentTime time.Time) []*apiv1.Pod {
 	var oldUnschedulablePods []*apiv1.Pod
-	newPodScaleUpDelay := a.AutoscalingOptions.NewPodScaleUpDelay
+	delayFactor := a.newPodScaleUpDelayFactor(len(allUnschedulablePods))
 	for _, pod := range allUnschedulablePods {
 		podAge := currentTime.Sub(pod.CreationTimestamp.Time)
-		podScaleUpDelay := newPodScaleUpDelay
+		newPodScaleUpDelay := a.AutoscalingOptions.NewPodScaleUpDelay
+		if delay, found := a.AutoscalingOptions.NewPodScaleUpDelayPerPriorityClass[pod.Spec.PriorityClassName]; found {
+			newPodScaleUpDelay = delay
+		}
+		podScaleUpDelay := time.Duration(float64(newPodScaleUpDelay) * delayFactor)
 
 		if podScaleUpDelayAnnotationStr, ok := pod.Annotations[podScaleUpDelayAnnotationKey]; ok {
 			podScaleUpDelayAnnotation, err := time.ParseDuration(podScaleUpDelayAnnotationStr)
 			if err != nil {
 				klog.Errorf("Failed to parse pod %q annotation %s: %v", pod.Name, podScaleUpDelayAnnotationKey, err)
 			} else {
-				if podScaleUpDelayAnnotation < podScaleUpDelay {
+				if podScaleUpDelayAnnotation < newPodScaleUpDelay {
 					klog.Errorf("Failed to set pod scale up delay for %q through annotation %s: %d is less then %d", pod.Name, podScaleUpDelayAnnotationKey, podScaleUpDelayAnnotation, newPodScaleUpDelay)
 				} else {
 					podScaleUpDelay = podScaleUpDelayAnnotation
@@ -925,12 +1254,27 @@ func (a *StaticAutoscaler) filterOutYoungPods(allUnschedulablePods []*apiv1.Pod,
 		if podAge > podScaleUpDelay {
 			oldUnschedulablePods = append(oldUnschedulablePods, pod)
 		} else {
-			klog.V(3).Infof("Pod %s is %.3f seconds old, too new to consider unschedulable", pod.Name, podAge.Seconds())
+			klogx.Core.V(3).Infof("Pod %s is %.3f seconds old, too new to consider unschedulable", pod.Name, podAge.Seconds())
 		}
 	}
 	return oldUnschedulablePods
 }
 
+// newPodScaleUpDelayFactor returns the factor applied to the new pod scale-up delays in this loop.
+// With adaptive delays, the delays are shortened in proportion to the growth of the number of
+// unschedulable pods since the previous loop, e.g. halved when it doubled, so that a quickly
+// growing backlog is served faster while a stable one still waits for the full delay.
+func (a *StaticAutoscaler) newPodScaleUpDelayFactor(unschedulablePodCount int) float64 {
+	lastCount := a.lastUnschedulablePodCount
+	a.lastUnschedulablePodCount = unschedulablePodCount
+	if !a.AutoscalingOptions.AdaptiveNewPodScaleUpDelay || lastCount == 0 || unschedulablePodCount <= lastCount {
+		return 1.0
+	}
+	factor := float64(lastCount) / float64(unschedulablePodCount)
+	klog.V(4).Infof("Unschedulable pods grew from %d to %d, shortening new pod scale-up delays by a factor of %.2f", lastCount, unschedulablePodCount, factor)
+	return factor
+}
+
 // ExitCleanUp performs all necessary clean-ups when the autoscaler's exiting.
 func (a *StaticAutoscaler) ExitCleanUp() {
 	a.processors.CleanUp()
@@ -999,6 +1343,7 @@ func getUpcomingNodeInfos(upcomingCounts map[string]int, nodeInfos map[string]*s
 			nodeTemplate.Node().Annotations = make(map[string]string)
 		}
 		nodeTemplate.Node().Annotations[NodeUpcomingAnnotation] = "true"
+		nodeTemplate.Node().Annotations[NodeUpcomingNodeGroupAnnotation] = nodeGroup
 
 		for i := 0; i < numberOfNodes; i++ {
 			// Ensure new nodes have different names because nodeName
@@ -1760,14 +1760,24 @@ func TestFilterOutYoungPods(t *testing.T) {
 	p4.Annotations = map[string]string{
 		podScaleUpDelayAnnotationKey: "error",
 	}
+	p5 := BuildTestPod("p5", 500, 1000)
+	p5.CreationTimestamp = metav1.NewTime(now.Add(-1 * time.Minute))
+	p5.Spec.PriorityClassName = "batch"
+	p6 := BuildTestPod("p6", 500, 1000)
+	p6.CreationTimestamp = metav1.NewTime(now.Add(-1 * time.Minute))
+	p6.Spec.PriorityClassName = "batch"
+	p6.Annotations = map[string]string{
+		podScaleUpDelayAnnotationKey: "3m",
+	}
 
 	tests := []struct {
-		name               string
-		newPodScaleUpDelay time.Duration
-		runTime            time.Time
-		pods               []*apiv1.Pod
-		expectedPods       []*apiv1.Pod
-		expectedError      string
+		name                  string
+		newPodScaleUpDelay    time.Duration
+		delayPerPriorityClass map[string]time.Duration
+		runTime               time.Time
+		pods                  []*apiv1.Pod
+		expectedPods          []*apiv1.Pod
+		expectedError         string
 	}{
 		{
 			name:               "annotation delayed pod checking now",
@@ -1806,13 +1816,38 @@ func TestFilterOutYoungPods(t *testing.T) {
 			expectedPods:       []*apiv1.Pod{p1, p4},
 			expectedError:      "Failed to parse pod",
 		},
+		{
+			name:                  "priority class delayed pods",
+			newPodScaleUpDelay:    0,
+			delayPerPriorityClass: map[string]time.Duration{"batch": 2 * time.Minute},
+			runTime:               now,
+			pods:                  []*apiv1.Pod{p1, p5},
+			expectedPods:          []*apiv1.Pod{p1},
+		},
+		{
+			name:                  "priority class delay shorter than global",
+			newPodScaleUpDelay:    5 * time.Minute,
+			delayPerPriorityClass: map[string]time.Duration{"batch": 0},
+			runTime:               now,
+			pods:                  []*apiv1.Pod{p1, p5},
+			expectedPods:          []*apiv1.Pod{p5},
+		},
+		{
+			name:                  "annotation delay longer than priority class delay",
+			newPodScaleUpDelay:    0,
+			delayPerPriorityClass: map[string]time.Duration{"batch": 2 * time.Minute},
+			runTime:               now.Add(2 * time.Minute),
+			pods:                  []*apiv1.Pod{p5, p6},
+			expectedPods:          []*apiv1.Pod{p5},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			context := context.AutoscalingContext{
 				AutoscalingOptions: config.AutoscalingOptions{
-					NewPodScaleUpDelay: tt.newPodScaleUpDelay,
+					NewPodScaleUpDelay:                 tt.newPodScaleUpDelay,
+					NewPodScaleUpDelayPerPriorityClass: tt.delayPerPriorityClass,
 				},
 			}
 			autoscaler := &StaticAutoscaler{
@@ -1835,6 +1870,49 @@ func TestFilterOutYoungPods(t *testing.T) {
 	}
 }
 
+func TestFilterOutYoungPodsAdaptiveDelay(t *testing.T) {
+	now := time.Now()
+	var pods []*apiv1.Pod
+	for i := 0; i < 4; i++ {
+		pod := BuildTestPod(fmt.Sprintf("p%d", i), 500, 1000)
+		pod.CreationTimestamp = metav1.NewTime(now.Add(-3 * time.Minute))
+		pods = append(pods, pod)
+	}
+
+	for _, tc := range []struct {
+		name         string
+		adaptive     bool
+		expectedPods []*apiv1.Pod
+	}{
+		{
+			name:         "fixed delay",
+			adaptive:     false,
+			expectedPods: []*apiv1.Pod(nil),
+		},
+		{
+			name:         "adaptive delay halved when the unschedulable pods doubled",
+			adaptive:     true,
+			expectedPods: pods,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			autoscaler := &StaticAutoscaler{
+				AutoscalingContext: &context.AutoscalingContext{
+					AutoscalingOptions: config.AutoscalingOptions{
+						NewPodScaleUpDelay:         5 * time.Minute,
+						AdaptiveNewPodScaleUpDelay: tc.adaptive,
+					},
+				},
+			}
+
+			assert.Empty(t, autoscaler.filterOutYoungPods(pods[:2], now))
+			assert.Equal(t, tc.expectedPods, autoscaler.filterOutYoungPods(pods, now))
+			// The number of unschedulable pods is stable, the full delay applies again.
+			assert.Empty(t, autoscaler.filterOutYoungPods(pods, now))
+		})
+	}
+}
+
 func waitForDeleteToFinish(t *testing.T, deleteFinished <-chan bool) {
 	select {
 	case <-deleteFinished:
@@ -0,0 +1,154 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package surge
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	kube_client "k8s.io/client-go/kubernetes"
+	v1lister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	klog "k8s.io/klog/v2"
+)
+
+const (
+	// NodeGroupAnnotation on a ConfigMap in the namespace of the autoscaler requests surge capacity
+	// in the node group with the given id, e.g. for the duration of a rolling upgrade of its nodes.
+	NodeGroupAnnotation = "cluster-autoscaler.kubernetes.io/surge-node-group"
+	// NodesAnnotation is the number of nodes requested above the target size of the node group.
+	NodesAnnotation = "cluster-autoscaler.kubernetes.io/surge-nodes"
+	// ExpiresAnnotation is the RFC 3339 time after which the surge capacity is reclaimed, even if the
+	// request is not deleted. It can't be later than the max surge duration after the request creation.
+	ExpiresAnnotation = "cluster-autoscaler.kubernetes.io/surge-expires"
+	// GrantedAnnotation is set by the autoscaler to the number of nodes added for the request.
+	GrantedAnnotation = "cluster-autoscaler.kubernetes.io/surge-granted"
+	// BaselineAnnotation is set by the autoscaler to the target size of the node group before the surge.
+	BaselineAnnotation = "cluster-autoscaler.kubernetes.io/surge-baseline"
+)
+
+// Request is a request for surge capacity in a node group.
+type Request struct {
+	// Key identifies the ConfigMap holding the request.
+	Key string
+	// UID is the uid of the ConfigMap, to tell a request from a later one with the same key.
+	UID types.UID
+	// NodeGroupId is the id of the node group to surge.
+	NodeGroupId string
+	// Nodes is the number of nodes requested above the target size of the node group.
+	Nodes int
+	// Expires is the time after which the surge capacity is reclaimed.
+	Expires time.Time
+	// Granted is true once the autoscaler increased the size of the node group for the request.
+	Granted bool
+	// GrantedNodes is the number of nodes added for the request, which can be lower than
+	// the number of requested nodes if the node group is close to its max size.
+	GrantedNodes int
+	// Baseline is the target size of the node group before the surge.
+	Baseline int
+}
+
+// ParseRequest returns the surge capacity request of the ConfigMap, or nil if it has none.
+func ParseRequest(configMap *apiv1.ConfigMap, maxDuration time.Duration) (*Request, error) {
+	nodeGroupId := configMap.Annotations[NodeGroupAnnotation]
+	if nodeGroupId == "" {
+		return nil, nil
+	}
+	request := &Request{
+		Key:         configMap.Namespace + "/" + configMap.Name,
+		UID:         configMap.UID,
+		NodeGroupId: nodeGroupId,
+		Expires:     configMap.CreationTimestamp.Add(maxDuration),
+	}
+	nodes, err := strconv.Atoi(configMap.Annotations[NodesAnnotation])
+	if err != nil || nodes <= 0 {
+		return nil, fmt.Errorf("invalid %s annotation %q, expected a positive number of nodes", NodesAnnotation, configMap.Annotations[NodesAnnotation])
+	}
+	request.Nodes = nodes
+	if value, found := configMap.Annotations[ExpiresAnnotation]; found {
+		expires, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation %q: %v", ExpiresAnnotation, value, err)
+		}
+		if expires.Before(request.Expires) {
+			request.Expires = expires
+		}
+	}
+	if value, found := configMap.Annotations[GrantedAnnotation]; found {
+		granted, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation %q: %v", GrantedAnnotation, value, err)
+		}
+		baseline, err := strconv.Atoi(configMap.Annotations[BaselineAnnotation])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation %q: %v", BaselineAnnotation, configMap.Annotations[BaselineAnnotation], err)
+		}
+		request.Granted = true
+		request.GrantedNodes = granted
+		request.Baseline = baseline
+	}
+	return request, nil
+}
+
+// ListRequests returns the surge capacity requests of the ConfigMaps listed by the lister of the namespace.
+// Invalid requests are skipped.
+func ListRequests(configMapLister v1lister.ConfigMapNamespaceLister, maxDuration time.Duration) ([]*Request, error) {
+	configMaps, err := configMapLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	var requests []*Request
+	for _, configMap := range configMaps {
+		request, err := ParseRequest(configMap, maxDuration)
+		if err != nil {
+			klog.Warningf("Ignoring surge capacity request %s/%s: %v", configMap.Namespace, configMap.Name, err)
+			continue
+		}
+		if request != nil {
+			requests = append(requests, request)
+		}
+	}
+	return requests, nil
+}
+
+// RecordGrant annotates the ConfigMap of the granted request with the number of nodes added for it and the
+// baseline size of its node group, so that the upgrade controller can follow it and a restarted autoscaler
+// doesn't grant it again.
+func RecordGrant(kubeClient kube_client.Interface, configMapLister v1lister.ConfigMapNamespaceLister, request *Request) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(request.Key)
+	if err != nil {
+		return err
+	}
+	cached, err := configMapLister.Get(name)
+	if err != nil {
+		return err
+	}
+	configMap := cached.DeepCopy()
+	if configMap.Annotations == nil {
+		configMap.Annotations = make(map[string]string)
+	}
+	configMap.Annotations[GrantedAnnotation] = strconv.Itoa(request.GrantedNodes)
+	configMap.Annotations[BaselineAnnotation] = strconv.Itoa(request.Baseline)
+	_, err = kubeClient.CoreV1().ConfigMaps(namespace).Update(context.TODO(), configMap, metav1.UpdateOptions{})
+	return err
+}
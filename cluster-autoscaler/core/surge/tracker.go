@@ -0,0 +1,142 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package surge
+
+import (
+	"sort"
+	"time"
+)
+
+// Tracker keeps track of surge capacity requests, from their grant until the surge nodes are reclaimed.
+// Grants are kept in memory per request, the annotations recording them on the ConfigMaps are only read
+// for requests granted before a restart. Surge nodes are reclaimed once the request is deleted or expires,
+// but only down to the baseline size of the node group, so that nodes removed by the upgrade are not
+// reclaimed twice. Requests released while the autoscaler was down are not reclaimed explicitly, their
+// nodes are left to regular scale-down.
+type Tracker struct {
+	active   map[string]*Request
+	released map[string]bool
+	reclaim  map[string]int
+}
+
+// NewTracker returns an empty Tracker.
+func NewTracker() *Tracker {
+	return &Tracker{
+		active:   make(map[string]*Request),
+		released: make(map[string]bool),
+		reclaim:  make(map[string]int),
+	}
+}
+
+// Update refreshes the active requests and returns the granted ones released since the last update,
+// because they were deleted or expired.
+func (t *Tracker) Update(requests []*Request, now time.Time) []*Request {
+	active := make(map[string]*Request, len(requests))
+	released := make(map[string]bool)
+	var result []*Request
+	for _, request := range requests {
+		if known, found := t.active[request.Key]; found && known.UID == request.UID && known.Granted {
+			// The ConfigMap may not reflect the grant yet, if recording it failed or the informer lags behind.
+			request.Granted = true
+			request.GrantedNodes = known.GrantedNodes
+			request.Baseline = known.Baseline
+		}
+		if now.Before(request.Expires) {
+			active[request.Key] = request
+			continue
+		}
+		released[request.Key] = true
+		if request.Granted && !t.released[request.Key] {
+			result = append(result, request)
+		}
+	}
+	for key, request := range t.active {
+		if _, found := active[key]; !found && !released[key] && request.Granted {
+			result = append(result, request)
+		}
+	}
+	t.active = active
+	t.released = released
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result
+}
+
+// Pending returns the active requests not granted yet, sorted by key.
+func (t *Tracker) Pending() []*Request {
+	var result []*Request
+	for _, request := range t.active {
+		if !request.Granted {
+			result = append(result, request)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result
+}
+
+// Grant records that the node group of the request was scaled up from the baseline size for it.
+func (t *Tracker) Grant(request *Request, nodes, baseline int) {
+	request.Granted = true
+	request.GrantedNodes = nodes
+	request.Baseline = baseline
+}
+
+// Surging returns true if the node group has active surge capacity requests. Nodes of such
+// node groups are not scaled down, so that the upgrade can move pods to the surge nodes.
+func (t *Tracker) Surging(nodeGroupId string) bool {
+	for _, request := range t.active {
+		if request.NodeGroupId == nodeGroupId {
+			return true
+		}
+	}
+	return false
+}
+
+// Release schedules the reclaim of the nodes granted to the released request, given the current
+// target size of its node group. It returns the number of nodes to reclaim.
+func (t *Tracker) Release(request *Request, targetSize int) int {
+	nodes := request.GrantedNodes
+	if above := targetSize - request.Baseline; above < nodes {
+		nodes = above
+	}
+	if nodes <= 0 {
+		return 0
+	}
+	t.reclaim[request.NodeGroupId] += nodes
+	return nodes
+}
+
+// ToReclaim returns the number of nodes left to reclaim per node group.
+func (t *Tracker) ToReclaim() map[string]int {
+	result := make(map[string]int, len(t.reclaim))
+	for id, nodes := range t.reclaim {
+		result[id] = nodes
+	}
+	return result
+}
+
+// Reclaimed records that nodes of the node group were reclaimed.
+func (t *Tracker) Reclaimed(nodeGroupId string, nodes int) {
+	t.reclaim[nodeGroupId] -= nodes
+	if t.reclaim[nodeGroupId] <= 0 {
+		delete(t.reclaim, nodeGroupId)
+	}
+}
+
+// Forget stops reclaiming nodes of the node group, e.g. once it reached its min size.
+func (t *Tracker) Forget(nodeGroupId string) {
+	delete(t.reclaim, nodeGroupId)
+}
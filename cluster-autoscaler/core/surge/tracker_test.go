@@ -0,0 +1,136 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package surge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func buildRequestConfigMap(name string, created time.Time, annotations map[string]string) *apiv1.ConfigMap {
+	return &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "kube-system",
+			CreationTimestamp: metav1.Time{Time: created},
+			Annotations:       annotations,
+		},
+	}
+}
+
+func TestParseRequest(t *testing.T) {
+	created := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+	maxDuration := 6 * time.Hour
+
+	for _, tc := range []struct {
+		desc        string
+		annotations map[string]string
+		expected    *Request
+		expectErr   bool
+	}{
+		{
+			desc:        "not a request",
+			annotations: map[string]string{"foo": "bar"},
+		},
+		{
+			desc:        "new request",
+			annotations: map[string]string{NodeGroupAnnotation: "ng1", NodesAnnotation: "2"},
+			expected:    &Request{Key: "kube-system/cm", NodeGroupId: "ng1", Nodes: 2, Expires: created.Add(maxDuration)},
+		},
+		{
+			desc:        "request expiring early",
+			annotations: map[string]string{NodeGroupAnnotation: "ng1", NodesAnnotation: "2", ExpiresAnnotation: "2023-06-01T11:00:00Z"},
+			expected:    &Request{Key: "kube-system/cm", NodeGroupId: "ng1", Nodes: 2, Expires: created.Add(time.Hour)},
+		},
+		{
+			desc:        "expiration capped by max duration",
+			annotations: map[string]string{NodeGroupAnnotation: "ng1", NodesAnnotation: "2", ExpiresAnnotation: "2023-06-02T10:00:00Z"},
+			expected:    &Request{Key: "kube-system/cm", NodeGroupId: "ng1", Nodes: 2, Expires: created.Add(maxDuration)},
+		},
+		{
+			desc: "granted request",
+			annotations: map[string]string{NodeGroupAnnotation: "ng1", NodesAnnotation: "2",
+				GrantedAnnotation: "1", BaselineAnnotation: "4"},
+			expected: &Request{Key: "kube-system/cm", NodeGroupId: "ng1", Nodes: 2, Expires: created.Add(maxDuration),
+				Granted: true, GrantedNodes: 1, Baseline: 4},
+		},
+		{
+			desc:        "missing nodes",
+			annotations: map[string]string{NodeGroupAnnotation: "ng1"},
+			expectErr:   true,
+		},
+		{
+			desc:        "invalid expiration",
+			annotations: map[string]string{NodeGroupAnnotation: "ng1", NodesAnnotation: "2", ExpiresAnnotation: "tomorrow"},
+			expectErr:   true,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			request, err := ParseRequest(buildRequestConfigMap("cm", created, tc.annotations), maxDuration)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, request)
+		})
+	}
+}
+
+func TestTracker(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	tracker := NewTracker()
+
+	r1 := &Request{Key: "ns/r1", NodeGroupId: "ng1", Nodes: 2, Expires: later}
+	r2 := &Request{Key: "ns/r2", NodeGroupId: "ng2", Nodes: 3, Expires: later}
+	assert.Empty(t, tracker.Update([]*Request{r1, r2}, now))
+	assert.Equal(t, []*Request{r1, r2}, tracker.Pending())
+	assert.True(t, tracker.Surging("ng1"))
+	assert.False(t, tracker.Surging("ng3"))
+
+	tracker.Grant(r1, 2, 5)
+	tracker.Grant(r2, 1, 9)
+	assert.Empty(t, tracker.Pending())
+
+	// The grants are kept in memory even if the ConfigMaps listed don't record them yet.
+	r1 = &Request{Key: "ns/r1", NodeGroupId: "ng1", Nodes: 2, Expires: later}
+	r2 = &Request{Key: "ns/r2", NodeGroupId: "ng2", Nodes: 3, Expires: later}
+	assert.Empty(t, tracker.Update([]*Request{r1, r2}, now))
+	assert.Empty(t, tracker.Pending())
+	assert.Equal(t, 5, r1.Baseline)
+
+	// r1 is deleted, r2 expires.
+	released := tracker.Update([]*Request{r2}, later)
+	assert.Equal(t, []*Request{r1, r2}, released)
+	assert.False(t, tracker.Surging("ng1"))
+	assert.False(t, tracker.Surging("ng2"))
+	// released requests are reported once.
+	assert.Empty(t, tracker.Update([]*Request{r2}, later))
+
+	// the upgrade removed one node of ng1 already, ng2 is back to its baseline.
+	assert.Equal(t, 1, tracker.Release(r1, 6))
+	assert.Equal(t, 0, tracker.Release(r2, 9))
+	assert.Equal(t, map[string]int{"ng1": 1}, tracker.ToReclaim())
+
+	tracker.Reclaimed("ng1", 1)
+	assert.Empty(t, tracker.ToReclaim())
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate/api"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaleup/equivalence"
+	orchestrator "k8s.io/autoscaler/cluster-autoscaler/core/scaleup/orchestrator"
+	"k8s.io/autoscaler/cluster-autoscaler/metrics"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
+
+	klog "k8s.io/klog/v2"
+)
+
+// podTimeToSchedule is the estimated time to schedule of a single pending pod.
+type podTimeToSchedule struct {
+	reason     api.PendingPodGroupReason
+	nodeGroups []string
+	// timeToSchedule is nil if the time to schedule is unknown.
+	timeToSchedule *time.Duration
+}
+
+// longerThan returns true if the pod is expected to wait longer than the other one.
+// Pods with an unknown time to schedule wait the longest.
+func (p podTimeToSchedule) longerThan(other podTimeToSchedule) bool {
+	if p.timeToSchedule == nil {
+		return other.timeToSchedule != nil
+	}
+	return other.timeToSchedule != nil && *p.timeToSchedule > *other.timeToSchedule
+}
+
+// estimateTimeToSchedule estimates, for each group of equivalent pending pods, how long it
+// takes until the pods can be scheduled. The estimate combines the placement of the pods in
+// the cluster snapshot, the outcome of the scale-up attempt and the provisioning latencies
+// and backoffs of node groups tracked by the cluster state registry.
+func (a *StaticAutoscaler) estimateTimeToSchedule(pendingPods []*apiv1.Pod, scaleUpStatus *status.ScaleUpStatus, currentTime time.Time) []api.PendingPodGroupStatus {
+	if len(pendingPods) == 0 {
+		return nil
+	}
+
+	nodeGroups := make(map[string]cloudprovider.NodeGroup)
+	for _, nodeGroup := range a.CloudProvider.NodeGroups() {
+		nodeGroups[nodeGroup.Id()] = nodeGroup
+	}
+	estimates := make(map[string]time.Duration)
+	estimate := func(nodeGroupId string) *time.Duration {
+		if eta, found := estimates[nodeGroupId]; found {
+			return &eta
+		}
+		nodeGroup, found := nodeGroups[nodeGroupId]
+		if !found {
+			return nil
+		}
+		eta := a.clusterStateRegistry.EstimateTimeToSchedule(nodeGroup, currentTime)
+		estimates[nodeGroupId] = eta
+		return &eta
+	}
+
+	// Pods which fit in the cluster are placed in the snapshot, either on existing or on upcoming nodes.
+	podNodes := make(map[types.UID]*apiv1.Node)
+	nodeInfos, err := a.ClusterSnapshot.NodeInfos().List()
+	if err != nil {
+		klog.Warningf("Failed to list nodes from the cluster snapshot: %v", err)
+	}
+	for _, nodeInfo := range nodeInfos {
+		for _, podInfo := range nodeInfo.Pods {
+			podNodes[podInfo.Pod.UID] = nodeInfo.Node()
+		}
+	}
+
+	var scaledUpNodeGroups []string
+	triggeredScaleUp := make(map[types.UID]bool)
+	remainUnschedulable := make(map[types.UID]status.NoScaleUpInfo)
+	if scaleUpStatus != nil {
+		for _, info := range scaleUpStatus.ScaleUpInfos {
+			scaledUpNodeGroups = append(scaledUpNodeGroups, info.Group.Id())
+		}
+		for _, pod := range scaleUpStatus.PodsTriggeredScaleUp {
+			triggeredScaleUp[pod.UID] = true
+		}
+		for _, noScaleUpInfo := range scaleUpStatus.PodsRemainUnschedulable {
+			remainUnschedulable[noScaleUpInfo.Pod.UID] = noScaleUpInfo
+		}
+	}
+
+	estimatePod := func(pod *apiv1.Pod) podTimeToSchedule {
+		if node, found := podNodes[pod.UID]; found {
+			if nodeGroupId, upcoming := node.Annotations[NodeUpcomingNodeGroupAnnotation]; upcoming {
+				return podTimeToSchedule{reason: api.PendingPodGroupWaitingForUpcomingNodes, nodeGroups: []string{nodeGroupId}, timeToSchedule: estimate(nodeGroupId)}
+			}
+			var zero time.Duration
+			return podTimeToSchedule{reason: api.PendingPodGroupSchedulable, timeToSchedule: &zero}
+		}
+		if triggeredScaleUp[pod.UID] {
+			// The pod can land on any of the scaled up node groups, so expect the slowest one.
+			result := podTimeToSchedule{reason: api.PendingPodGroupScaleUpTriggered, nodeGroups: scaledUpNodeGroups}
+			for _, nodeGroupId := range scaledUpNodeGroups {
+				eta := estimate(nodeGroupId)
+				if eta == nil {
+					return podTimeToSchedule{reason: api.PendingPodGroupScaleUpTriggered, nodeGroups: scaledUpNodeGroups}
+				}
+				if result.timeToSchedule == nil || *eta > *result.timeToSchedule {
+					result.timeToSchedule = eta
+				}
+			}
+			return result
+		}
+		if noScaleUpInfo, found := remainUnschedulable[pod.UID]; found {
+			// The pod will be helped by the first backed off node group to become available again.
+			result := podTimeToSchedule{reason: api.PendingPodGroupNoScaleUpOption}
+			for nodeGroupId, reasons := range noScaleUpInfo.SkippedNodeGroups {
+				if reasons != orchestrator.BackoffReason {
+					continue
+				}
+				eta := estimate(nodeGroupId)
+				if eta == nil {
+					continue
+				}
+				result.reason = api.PendingPodGroupBackedOff
+				result.nodeGroups = append(result.nodeGroups, nodeGroupId)
+				if result.timeToSchedule == nil || *eta < *result.timeToSchedule {
+					result.timeToSchedule = eta
+				}
+			}
+			sort.Strings(result.nodeGroups)
+			return result
+		}
+		return podTimeToSchedule{reason: api.PendingPodGroupNotEvaluated}
+	}
+
+	var result []api.PendingPodGroupStatus
+	for _, group := range equivalence.BuildPodGroups(pendingPods) {
+		var slowest podTimeToSchedule
+		groupNodeGroups := make(map[string]bool)
+		for i, pod := range group.Pods {
+			podEstimate := estimatePod(pod)
+			if i == 0 || podEstimate.longerThan(slowest) {
+				slowest = podEstimate
+			}
+			for _, nodeGroupId := range podEstimate.nodeGroups {
+				groupNodeGroups[nodeGroupId] = true
+			}
+		}
+		groupStatus := api.PendingPodGroupStatus{
+			Namespace: group.Pods[0].Namespace,
+			Owner:     podOwner(group.Pods[0]),
+			Pods:      len(group.Pods),
+			Reason:    slowest.reason,
+		}
+		for nodeGroupId := range groupNodeGroups {
+			groupStatus.NodeGroups = append(groupStatus.NodeGroups, nodeGroupId)
+		}
+		sort.Strings(groupStatus.NodeGroups)
+		if slowest.timeToSchedule != nil {
+			groupStatus.TimeToSchedule = &metav1.Duration{Duration: *slowest.timeToSchedule}
+		}
+		result = append(result, groupStatus)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Owner < result[j].Owner
+	})
+	return result
+}
+
+// podOwner identifies the controller of the pod as kind/name, or the pod itself if it has no controller.
+func podOwner(pod *apiv1.Pod) string {
+	if ref := metav1.GetControllerOf(pod); ref != nil {
+		return fmt.Sprintf("%s/%s", ref.Kind, ref.Name)
+	}
+	return fmt.Sprintf("Pod/%s", pod.Name)
+}
+
+// updatePendingPodGroupsMetrics publishes the estimated time to schedule of pending pods. Groups
+// in the same namespace are reported with the longest estimate among them, per owner estimates are
+// only published in the status config map.
+func updatePendingPodGroupsMetrics(pendingPodGroups []api.PendingPodGroupStatus) {
+	timeToSchedule := make(map[string]time.Duration)
+	reasonCounts := make(map[string]int)
+	for _, group := range pendingPodGroups {
+		reasonCounts[string(group.Reason)]++
+		if group.TimeToSchedule == nil {
+			continue
+		}
+		if current, found := timeToSchedule[group.Namespace]; !found || group.TimeToSchedule.Duration > current {
+			timeToSchedule[group.Namespace] = group.TimeToSchedule.Duration
+		}
+	}
+	metrics.UpdatePendingPodGroupsTimeToSchedule(timeToSchedule)
+	metrics.UpdatePendingPodGroupsCount(reasonCounts)
+}
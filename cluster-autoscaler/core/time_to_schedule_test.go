@@ -0,0 +1,122 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate/api"
+	clusterstate_utils "k8s.io/autoscaler/cluster-autoscaler/clusterstate/utils"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	orchestrator "k8s.io/autoscaler/cluster-autoscaler/core/scaleup/orchestrator"
+	. "k8s.io/autoscaler/cluster-autoscaler/core/test"
+	"k8s.io/autoscaler/cluster-autoscaler/metrics"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupconfig"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupset"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	"k8s.io/client-go/kubernetes/fake"
+	kube_record "k8s.io/client-go/tools/record"
+)
+
+func TestEstimateTimeToSchedule(t *testing.T) {
+	now := time.Now()
+
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 0, 10, 1)
+	provider.AddNodeGroup("ng2", 0, 10, 1)
+	provider.AddNodeGroup("ng3", 0, 10, 0)
+
+	fakeClient := &fake.Clientset{}
+	fakeLogRecorder, _ := clusterstate_utils.NewStatusMapRecorder(fakeClient, "kube-system", kube_record.NewFakeRecorder(5), false, "my-cool-configmap")
+	options := config.AutoscalingOptions{
+		NodeGroupDefaults: config.NodeGroupAutoscalingOptions{
+			MaxNodeProvisionTime: 15 * time.Minute,
+		},
+	}
+	clusterState := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, fakeLogRecorder, NewBackoff(), nodegroupconfig.NewDefaultNodeGroupConfigProcessor(options.NodeGroupDefaults))
+	clusterState.RegisterOrUpdateScaleUp(provider.GetNodeGroup("ng1"), 1, now.Add(-5*time.Minute))
+	clusterState.RegisterOrUpdateScaleUp(provider.GetNodeGroup("ng2"), 1, now)
+	clusterState.RegisterFailedScaleUp(provider.GetNodeGroup("ng3"), metrics.Timeout, "", "", now)
+
+	withOwner := func(name string) func(*apiv1.Pod) {
+		return func(pod *apiv1.Pod) {
+			pod.OwnerReferences = GenerateOwnerReferences(name, "ReplicaSet", "apps/v1", types.UID("uid-"+name))
+		}
+	}
+	scheduled := BuildTestPod("scheduled", 100, 0, withOwner("scheduled"))
+	upcoming := BuildTestPod("upcoming", 100, 0, withOwner("upcoming"))
+	mixedUpcoming := BuildTestPod("mixed-1", 100, 0, withOwner("mixed"))
+	mixedScaleUp := BuildTestPod("mixed-2", 100, 0, withOwner("mixed"))
+	backedOff := BuildTestPod("backed-off", 100, 0)
+	noOption := BuildTestPod("no-option", 100, 0)
+	notEvaluated := BuildTestPod("not-evaluated", 100, 0)
+
+	snapshot := clustersnapshot.NewBasicClusterSnapshot()
+	existingNode := BuildTestNode("n1", 1000, 1000)
+	assert.NoError(t, snapshot.AddNodeWithPods(existingNode, []*apiv1.Pod{scheduled}))
+	upcomingNode := BuildTestNode("template-node-for-ng1-upcoming-0", 1000, 1000)
+	upcomingNode.Annotations = map[string]string{
+		NodeUpcomingAnnotation:          "true",
+		NodeUpcomingNodeGroupAnnotation: "ng1",
+	}
+	assert.NoError(t, snapshot.AddNodeWithPods(upcomingNode, []*apiv1.Pod{upcoming, mixedUpcoming}))
+
+	autoscaler := &StaticAutoscaler{
+		AutoscalingContext: &context.AutoscalingContext{
+			AutoscalingOptions: options,
+			CloudProvider:      provider,
+			ClusterSnapshot:    snapshot,
+		},
+		clusterStateRegistry: clusterState,
+	}
+	scaleUpStatus := &status.ScaleUpStatus{
+		Result:               status.ScaleUpSuccessful,
+		ScaleUpInfos:         []nodegroupset.ScaleUpInfo{{Group: provider.GetNodeGroup("ng2"), CurrentSize: 1, NewSize: 2, MaxSize: 10}},
+		PodsTriggeredScaleUp: []*apiv1.Pod{mixedScaleUp},
+		PodsRemainUnschedulable: []status.NoScaleUpInfo{
+			{Pod: backedOff, SkippedNodeGroups: map[string]status.Reasons{"ng3": orchestrator.BackoffReason}},
+			{Pod: noOption, SkippedNodeGroups: map[string]status.Reasons{"ng1": orchestrator.MaxLimitReachedReason}},
+		},
+	}
+
+	pendingPods := []*apiv1.Pod{scheduled, upcoming, mixedUpcoming, mixedScaleUp, backedOff, noOption, notEvaluated}
+	got := autoscaler.estimateTimeToSchedule(pendingPods, scaleUpStatus, now)
+
+	duration := func(d time.Duration) *metav1.Duration {
+		return &metav1.Duration{Duration: d}
+	}
+	assert.Equal(t, []api.PendingPodGroupStatus{
+		{Namespace: "default", Owner: "Pod/backed-off", Pods: 1, Reason: api.PendingPodGroupBackedOff, NodeGroups: []string{"ng3"}, TimeToSchedule: duration(20 * time.Minute)},
+		{Namespace: "default", Owner: "Pod/no-option", Pods: 1, Reason: api.PendingPodGroupNoScaleUpOption},
+		{Namespace: "default", Owner: "Pod/not-evaluated", Pods: 1, Reason: api.PendingPodGroupNotEvaluated},
+		{Namespace: "default", Owner: "ReplicaSet/mixed", Pods: 2, Reason: api.PendingPodGroupScaleUpTriggered, NodeGroups: []string{"ng1", "ng2"}, TimeToSchedule: duration(15 * time.Minute)},
+		{Namespace: "default", Owner: "ReplicaSet/scheduled", Pods: 1, Reason: api.PendingPodGroupSchedulable, TimeToSchedule: duration(0)},
+		{Namespace: "default", Owner: "ReplicaSet/upcoming", Pods: 1, Reason: api.PendingPodGroupWaitingForUpcomingNodes, NodeGroups: []string{"ng1"}, TimeToSchedule: duration(10 * time.Minute)},
+	}, got)
+
+	assert.Empty(t, autoscaler.estimateTimeToSchedule(nil, scaleUpStatus, now))
+}
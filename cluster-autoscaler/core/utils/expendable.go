@@ -18,7 +18,7 @@ package utils
 
 import (
 	apiv1 "k8s.io/api/core/v1"
-	klog "k8s.io/klog/v2"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
 )
 
 // FilterOutExpendableAndSplit filters out expendable pods and splits into:
@@ -35,13 +35,13 @@ func FilterOutExpendableAndSplit(unschedulableCandidates []*apiv1.Pod, nodes []*
 
 	for _, pod := range unschedulableCandidates {
 		if pod.Spec.Priority != nil && int(*pod.Spec.Priority) < expendablePodsPriorityCutoff {
-			klog.V(4).Infof("Pod %s has priority below %d (%d) and will scheduled when enough resources is free. Ignoring in scale up.", pod.Name, expendablePodsPriorityCutoff, *pod.Spec.Priority)
+			klogx.Core.V(4).Infof("Pod %s has priority below %d (%d) and will scheduled when enough resources is free. Ignoring in scale up.", pod.Name, expendablePodsPriorityCutoff, *pod.Spec.Priority)
 		} else if nominatedNodeName := pod.Status.NominatedNodeName; nominatedNodeName != "" {
 			if nodeNames[nominatedNodeName] {
-				klog.V(4).Infof("Pod %s will be scheduled after low priority pods are preempted on %s. Ignoring in scale up.", pod.Name, nominatedNodeName)
+				klogx.Core.V(4).Infof("Pod %s will be scheduled after low priority pods are preempted on %s. Ignoring in scale up.", pod.Name, nominatedNodeName)
 				waitingForLowerPriorityPreemption = append(waitingForLowerPriorityPreemption, pod)
 			} else {
-				klog.V(4).Infof("Pod %s has nominatedNodeName set to %s but node is gone", pod.Name, nominatedNodeName)
+				klogx.Core.V(4).Infof("Pod %s has nominatedNodeName set to %s but node is gone", pod.Name, nominatedNodeName)
 				unschedulableNonExpendable = append(unschedulableNonExpendable, pod)
 			}
 		} else {
@@ -20,18 +20,23 @@ import (
 	"fmt"
 	"math/rand"
 	"reflect"
+	"runtime/debug"
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	"k8s.io/autoscaler/cluster-autoscaler/core/orphans"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/externaldelete"
 	"k8s.io/autoscaler/cluster-autoscaler/metrics"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/daemonset"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/gpu"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/labels"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/taints"
+	"k8s.io/klog/v2"
 	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
@@ -70,7 +75,8 @@ func isVirtualNode(node *apiv1.Node) bool {
 }
 
 // FilterOutNodesFromNotAutoscaledGroups return subset of input nodes for which cloud provider does not
-// return autoscaled node group.
+// return autoscaled node group. Nodes of the external delete node group and of orphaned node groups
+// can't be scaled up, so they are returned as well.
 func FilterOutNodesFromNotAutoscaledGroups(nodes []*apiv1.Node, cloudProvider cloudprovider.CloudProvider) ([]*apiv1.Node, errors.AutoscalerError) {
 	result := make([]*apiv1.Node, 0)
 
@@ -83,7 +89,7 @@ func FilterOutNodesFromNotAutoscaledGroups(nodes []*apiv1.Node, cloudProvider cl
 		if err != nil {
 			return []*apiv1.Node{}, errors.ToAutoscalerError(errors.CloudProviderError, err)
 		}
-		if nodeGroup == nil || reflect.ValueOf(nodeGroup).IsNil() {
+		if nodeGroup == nil || reflect.ValueOf(nodeGroup).IsNil() || externaldelete.IsExternalNodeGroup(nodeGroup) || orphans.IsOrphanedNodeGroup(nodeGroup) {
 			result = append(result, node)
 		}
 	}
@@ -178,7 +184,60 @@ func UpdateClusterStateMetrics(csr *clusterstate.ClusterStateRegistry) {
 	}
 	metrics.UpdateClusterSafeToAutoscale(csr.IsClusterHealthy())
 	readiness := csr.GetClusterReadiness()
-	metrics.UpdateNodesCount(len(readiness.Ready), len(readiness.Unready), len(readiness.NotStarted), len(readiness.LongUnregistered), len(readiness.Unregistered))
+	metrics.UpdateNodesCount(len(readiness.Ready), len(readiness.Unready), len(readiness.NotStarted), len(readiness.LongUnregistered), len(readiness.Unregistered), len(readiness.Deleted), len(readiness.BeingDeleted))
+	metrics.UpdateNodeStates(nodeStates(readiness), nodeGroupsByNode(csr.GetNodeGroupsReadiness()))
+}
+
+// nodeStates returns the state of each node of the readiness stats. Nodes being deleted
+// are reported in that state rather than in their readiness state.
+func nodeStates(readiness clusterstate.Readiness) map[string]metrics.NodeState {
+	states := make(map[string]metrics.NodeState)
+	for state, nodes := range map[metrics.NodeState][]string{
+		metrics.NodeStateReady:            readiness.Ready,
+		metrics.NodeStateUnready:          readiness.Unready,
+		metrics.NodeStateNotStarted:       readiness.NotStarted,
+		metrics.NodeStateUnregistered:     readiness.Unregistered,
+		metrics.NodeStateLongUnregistered: readiness.LongUnregistered,
+		metrics.NodeStateDeleted:          readiness.Deleted,
+	} {
+		for _, node := range nodes {
+			states[node] = state
+		}
+	}
+	for _, node := range readiness.BeingDeleted {
+		if states[node] != metrics.NodeStateDeleted {
+			states[node] = metrics.NodeStateBeingDeleted
+		}
+	}
+	return states
+}
+
+// nodeGroupsByNode returns the node group of each node of the per node group readiness stats.
+func nodeGroupsByNode(perNodeGroup map[string]clusterstate.Readiness) map[string]string {
+	nodeGroups := make(map[string]string)
+	for nodeGroup, readiness := range perNodeGroup {
+		for _, nodes := range [][]string{readiness.Registered, readiness.Unregistered, readiness.LongUnregistered} {
+			for _, node := range nodes {
+				nodeGroups[node] = nodeGroup
+			}
+		}
+	}
+	return nodeGroups
+}
+
+// GetHourlyNodePrice returns the price of running the given node for an hour, according to
+// the cloud provider pricing model. Returns false if the price can't be estimated.
+func GetHourlyNodePrice(cloudProvider cloudprovider.CloudProvider, node *apiv1.Node, now time.Time) (float64, bool) {
+	pricingModel, err := cloudProvider.Pricing()
+	if err != nil {
+		return 0, false
+	}
+	price, err := pricingModel.NodePrice(node, now, now.Add(time.Hour))
+	if err != nil {
+		klogx.Core.V(4).Infof("Failed to estimate price of node %s: %v", node.Name, err)
+		return 0, false
+	}
+	return price, true
 }
 
 // GetOldestCreateTime returns oldest creation time out of the pods in the set
@@ -206,3 +265,14 @@ func GetOldestCreateTimeWithGpu(pods []*apiv1.Pod) (bool, time.Time) {
 	}
 	return gpuFound, oldest
 }
+
+// RecoverNodeGroupPanic recovers from a panic while processing the node group, e.g. because of a malformed
+// template returned by the cloud provider, so that only this node group is affected instead of the whole
+// autoscaler. It must be deferred directly, and sets err to a NodeGroupPanicError when recovering.
+func RecoverNodeGroupPanic(nodeGroupId string, err *errors.AutoscalerError) {
+	if r := recover(); r != nil {
+		klog.Errorf("Recovered from panic while processing node group %s: %v\n%s", nodeGroupId, r, debug.Stack())
+		metrics.RegisterNodeGroupPanic(nodeGroupId)
+		*err = errors.NewAutoscalerError(errors.NodeGroupPanicError, "panic while processing node group %s: %v", nodeGroupId, r)
+	}
+}
@@ -20,6 +20,12 @@ import (
 	"testing"
 	"time"
 
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/mocks"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	"k8s.io/autoscaler/cluster-autoscaler/core/orphans"
+	"k8s.io/autoscaler/cluster-autoscaler/metrics"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/taints"
 	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
 
@@ -116,3 +122,93 @@ func TestGetOldestPod(t *testing.T) {
 	assert.Equal(t, p1.CreationTimestamp.Time, GetOldestCreateTime([]*apiv1.Pod{p1, p2, p3}))
 	assert.Equal(t, p1.CreationTimestamp.Time, GetOldestCreateTime([]*apiv1.Pod{p3, p2, p1}))
 }
+
+func TestGetHourlyNodePrice(t *testing.T) {
+	now := time.Now()
+	node := BuildTestNode("n1", 1000, 1000)
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+
+	_, found := GetHourlyNodePrice(provider, node, now)
+	assert.False(t, found)
+
+	pricingModel := &mocks.PricingModel{}
+	pricingModel.On("NodePrice", node, now, now.Add(time.Hour)).Return(0.5, nil)
+	provider.SetPricingModel(pricingModel)
+
+	price, found := GetHourlyNodePrice(provider, node, now)
+	assert.True(t, found)
+	assert.Equal(t, 0.5, price)
+}
+
+func TestNodeStates(t *testing.T) {
+	readiness := clusterstate.Readiness{
+		Ready:            []string{"ready", "draining"},
+		Unready:          []string{"unready"},
+		NotStarted:       []string{"starting"},
+		Deleted:          []string{"deleted"},
+		Unregistered:     []string{"unregistered"},
+		LongUnregistered: []string{"long-unregistered"},
+		BeingDeleted:     []string{"draining", "deleted"},
+	}
+	assert.Equal(t, map[string]metrics.NodeState{
+		"ready":             metrics.NodeStateReady,
+		"draining":          metrics.NodeStateBeingDeleted,
+		"unready":           metrics.NodeStateUnready,
+		"starting":          metrics.NodeStateNotStarted,
+		"deleted":           metrics.NodeStateDeleted,
+		"unregistered":      metrics.NodeStateUnregistered,
+		"long-unregistered": metrics.NodeStateLongUnregistered,
+	}, nodeStates(readiness))
+}
+
+func TestFilterOutNodesFromNotAutoscaledGroupsOrphaned(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n2 := BuildTestNode("n2", 1000, 1000)
+	nodes := []*apiv1.Node{n1, n2}
+
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 0, 10, 1)
+	provider.AddNodeGroup("ng2", 0, 10, 1)
+	provider.AddNode("ng1", n1)
+	provider.AddNode("ng2", n2)
+
+	tracker := orphans.NewTracker(orphans.AdoptPolicy)
+	wrapped := tracker.CloudProvider(provider)
+	tracker.Update(wrapped, nodes, time.Now())
+	provider.DeleteNodeGroup("ng1")
+	tracker.Update(wrapped, nodes, time.Now())
+
+	// Nodes of orphaned node groups are counted along with the nodes of node groups that aren't autoscaled.
+	result, err := FilterOutNodesFromNotAutoscaledGroups(nodes, wrapped)
+	assert.NoError(t, err)
+	assert.Equal(t, []*apiv1.Node{n1}, result)
+}
+
+func TestNodeGroupsByNode(t *testing.T) {
+	perNodeGroup := map[string]clusterstate.Readiness{
+		"ng1": {Registered: []string{"n1", "n2"}, Unregistered: []string{"n3"}},
+		"ng2": {Registered: []string{"n4"}, LongUnregistered: []string{"n5"}},
+	}
+	assert.Equal(t, map[string]string{
+		"n1": "ng1",
+		"n2": "ng1",
+		"n3": "ng1",
+		"n4": "ng2",
+		"n5": "ng2",
+	}, nodeGroupsByNode(perNodeGroup))
+}
+
+func TestRecoverNodeGroupPanic(t *testing.T) {
+	process := func(panics bool) (err errors.AutoscalerError) {
+		defer RecoverNodeGroupPanic("ng1", &err)
+		if panics {
+			var node *apiv1.Node
+			_ = node.Name
+		}
+		return nil
+	}
+	assert.NoError(t, process(false))
+	err := process(true)
+	assert.Error(t, err)
+	assert.Equal(t, errors.NodeGroupPanicError, err.Type())
+}
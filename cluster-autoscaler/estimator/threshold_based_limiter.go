@@ -21,7 +21,7 @@ import (
 
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
-	klog "k8s.io/klog/v2"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
 )
 
 type thresholdBasedEstimationLimiter struct {
@@ -57,12 +57,12 @@ func (*thresholdBasedEstimationLimiter) EndEstimation() {}
 
 func (tbel *thresholdBasedEstimationLimiter) PermissionToAddNode() bool {
 	if tbel.maxNodes < 0 || (tbel.maxNodes > 0 && tbel.nodes >= tbel.maxNodes) {
-		klog.V(4).Infof("Capping binpacking after exceeding threshold of %d nodes", tbel.maxNodes)
+		klogx.Estimator.V(4).Infof("Capping binpacking after exceeding threshold of %d nodes", tbel.maxNodes)
 		return false
 	}
 	timeDefined := tbel.maxDuration > 0 && tbel.start != time.Time{}
 	if tbel.maxDuration < 0 || (timeDefined && time.Now().After(tbel.start.Add(tbel.maxDuration))) {
-		klog.V(4).Infof("Capping binpacking after exceeding max duration of %v", tbel.maxDuration)
+		klogx.Estimator.V(4).Infof("Capping binpacking after exceeding max duration of %v", tbel.maxDuration)
 		return false
 	}
 	tbel.nodes++
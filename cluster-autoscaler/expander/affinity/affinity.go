@@ -0,0 +1,88 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package affinity
+
+import (
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	"k8s.io/component-helpers/scheduling/corev1/nodeaffinity"
+	klog "k8s.io/klog/v2"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+type affinity struct {
+}
+
+// NewFilter returns a scale up filter that picks the node groups whose template nodes best satisfy
+// the preferred node affinities of the pods they would schedule.
+func NewFilter() expander.Filter {
+	return &affinity{}
+}
+
+// BestOptions selects the expansion options with the highest total weight of preferred node affinity
+// terms matched by the node group template, summed over the pods the option schedules. Options are
+// left unchanged if none of them matches any preferred term.
+func (a *affinity) BestOptions(expansionOptions []expander.Option, nodeInfo map[string]*schedulerframework.NodeInfo) []expander.Option {
+	var maxScore int64
+	var maxOptions []expander.Option
+
+	for _, option := range expansionOptions {
+		score := optionScore(option, nodeInfo)
+		if score == maxScore {
+			maxOptions = append(maxOptions, option)
+		}
+
+		if score > maxScore {
+			maxScore = score
+			maxOptions = []expander.Option{option}
+		}
+	}
+
+	if len(maxOptions) == 0 {
+		return nil
+	}
+
+	return maxOptions
+}
+
+func optionScore(option expander.Option, nodeInfo map[string]*schedulerframework.NodeInfo) int64 {
+	info, found := nodeInfo[option.NodeGroup.Id()]
+	if !found || info.Node() == nil {
+		return 0
+	}
+	var score int64
+	for _, pod := range option.Pods {
+		score += podScore(pod, info.Node())
+	}
+	return score
+}
+
+func podScore(pod *apiv1.Pod, node *apiv1.Node) int64 {
+	if pod.Spec.Affinity == nil || pod.Spec.Affinity.NodeAffinity == nil {
+		return 0
+	}
+	terms := pod.Spec.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+	if len(terms) == 0 {
+		return 0
+	}
+	preferred, err := nodeaffinity.NewPreferredSchedulingTerms(terms)
+	if err != nil {
+		klog.Warningf("Failed to parse preferred node affinity of pod %s/%s: %v", pod.Namespace, pod.Name, err)
+		return 0
+	}
+	return preferred.Score(node)
+}
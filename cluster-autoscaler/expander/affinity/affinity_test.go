@@ -0,0 +1,93 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package affinity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+func preferZone(pod *apiv1.Pod, weight int32, zone string) *apiv1.Pod {
+	if pod.Spec.Affinity == nil {
+		pod.Spec.Affinity = &apiv1.Affinity{NodeAffinity: &apiv1.NodeAffinity{}}
+	}
+	nodeAffinity := pod.Spec.Affinity.NodeAffinity
+	nodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(nodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution, apiv1.PreferredSchedulingTerm{
+		Weight: weight,
+		Preference: apiv1.NodeSelectorTerm{
+			MatchExpressions: []apiv1.NodeSelectorRequirement{{
+				Key:      apiv1.LabelTopologyZone,
+				Operator: apiv1.NodeSelectorOpIn,
+				Values:   []string{zone},
+			}},
+		},
+	})
+	return pod
+}
+
+func TestPreferredAffinity(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng-a", 0, 10, 0)
+	provider.AddNodeGroup("ng-b", 0, 10, 0)
+	provider.AddNodeGroup("ng-c", 0, 10, 0)
+
+	nodeInfos := map[string]*schedulerframework.NodeInfo{}
+	for id, zone := range map[string]string{"ng-a": "zone-a", "ng-b": "zone-b", "ng-c": "zone-c"} {
+		node := BuildTestNode(id+"-template", 1000, 1000)
+		node.Labels[apiv1.LabelTopologyZone] = zone
+		nodeInfo := schedulerframework.NewNodeInfo()
+		nodeInfo.SetNode(node)
+		nodeInfos[id] = nodeInfo
+	}
+
+	p1 := preferZone(BuildTestPod("p1", 100, 100), 10, "zone-a")
+	p1 = preferZone(p1, 50, "zone-b")
+	p2 := preferZone(BuildTestPod("p2", 100, 100), 20, "zone-a")
+	p3 := BuildTestPod("p3", 100, 100)
+
+	e := NewFilter()
+
+	optionA := expander.Option{NodeGroup: provider.GetNodeGroup("ng-a"), Pods: []*apiv1.Pod{p1, p2}, Debug: "a"}
+	optionB := expander.Option{NodeGroup: provider.GetNodeGroup("ng-b"), Pods: []*apiv1.Pod{p1}, Debug: "b"}
+	optionC := expander.Option{NodeGroup: provider.GetNodeGroup("ng-c"), Pods: []*apiv1.Pod{p1, p2}, Debug: "c"}
+
+	// ng-b scores 50, ng-a scores 10+20, ng-c scores 0.
+	ret := e.BestOptions([]expander.Option{optionA, optionB, optionC}, nodeInfos)
+	assert.Equal(t, []expander.Option{optionB}, ret)
+
+	// Ties are all returned.
+	optionB2 := expander.Option{NodeGroup: provider.GetNodeGroup("ng-b"), Pods: []*apiv1.Pod{p1}, Debug: "b2"}
+	ret = e.BestOptions([]expander.Option{optionB, optionC, optionB2}, nodeInfos)
+	assert.Equal(t, []expander.Option{optionB, optionB2}, ret)
+
+	// Without preferred affinities, all options are equally good.
+	optionA3 := expander.Option{NodeGroup: provider.GetNodeGroup("ng-a"), Pods: []*apiv1.Pod{p3}, Debug: "a3"}
+	optionC3 := expander.Option{NodeGroup: provider.GetNodeGroup("ng-c"), Pods: []*apiv1.Pod{p3}, Debug: "c3"}
+	ret = e.BestOptions([]expander.Option{optionA3, optionC3}, nodeInfos)
+	assert.Equal(t, []expander.Option{optionA3, optionC3}, ret)
+
+	// Options without a template node score 0.
+	ret = e.BestOptions([]expander.Option{optionA3, optionC3}, nil)
+	assert.Equal(t, []expander.Option{optionA3, optionC3}, ret)
+}
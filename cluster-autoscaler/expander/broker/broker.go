@@ -0,0 +1,305 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	klog "k8s.io/klog/v2"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const (
+	// OnPremPool is the pool of node groups matching the on-prem node groups expression.
+	OnPremPool = "on-prem"
+	// CloudPool is the pool of all other node groups.
+	CloudPool = "cloud"
+
+	// FallbackOnPremFirst keeps the on-prem options, or all options if there are none.
+	FallbackOnPremFirst = "on-prem-first"
+	// FallbackCloudFirst keeps the cloud options, or all options if there are none.
+	FallbackCloudFirst = "cloud-first"
+	// FallbackAll keeps all options, leaving the choice to the next expanders.
+	FallbackAll = "all"
+
+	defaultTimeout  = 5 * time.Second
+	defaultCacheTTL = time.Minute
+	// staleResponseTTL is how long broker responses are kept to be reused when the broker can't be consulted.
+	staleResponseTTL = time.Hour
+)
+
+// Config configures the capacity broker expander.
+type Config struct {
+	// URL is the broker endpoint the expansion options are POSTed to. Empty to always apply the fallback.
+	URL string
+	// Timeout is the timeout of broker calls.
+	Timeout time.Duration
+	// OnPremNodeGroups is a regular expression matching the ids of on-prem node groups. All other
+	// node groups are cloud node groups.
+	OnPremNodeGroups string
+	// Fallback is the policy applied when the broker can't be consulted, and has never answered for the
+	// same options, or accepts none of the options. Defaults to FallbackAll.
+	Fallback string
+	// CacheTTL is how long a broker response is reused for the same node groups and node counts
+	// instead of consulting the broker again.
+	CacheTTL time.Duration
+}
+
+// Request is the body POSTed to the broker.
+type Request struct {
+	Options []Option `json:"options"`
+}
+
+// Option describes an expansion option to the broker.
+type Option struct {
+	// NodeGroup is the id of the node group.
+	NodeGroup string `json:"nodeGroup"`
+	// Pool is the pool of the node group, on-prem or cloud.
+	Pool string `json:"pool"`
+	// NodeCount is the number of nodes the scale-up would add.
+	NodeCount int `json:"nodeCount"`
+	// NodeAllocatable is the allocatable resources of a node of the node group.
+	NodeAllocatable apiv1.ResourceList `json:"nodeAllocatable,omitempty"`
+	// Pods are the pending pods the scale-up would schedule, as namespace/name.
+	Pods []string `json:"pods"`
+}
+
+// Response is the body of the broker response.
+type Response struct {
+	// NodeGroups are the ids of the node groups that should absorb the workload, in order of preference.
+	NodeGroups []string `json:"nodeGroups"`
+}
+
+type cachedResponse struct {
+	response *Response
+	time     time.Time
+}
+
+type capacityBroker struct {
+	url              string
+	client           *http.Client
+	timeout          time.Duration
+	cacheTTL         time.Duration
+	onPremNodeGroups *regexp.Regexp
+	fallback         string
+
+	mutex     sync.Mutex
+	responses map[string]cachedResponse
+	now       func() time.Time
+}
+
+// NewFilter returns a scale up filter that asks an external capacity broker which pool, on-prem
+// or cloud, should absorb the workload, applying a local fallback policy when the broker can't be
+// consulted.
+func NewFilter(config Config) (expander.Filter, error) {
+	var onPremNodeGroups *regexp.Regexp
+	if config.OnPremNodeGroups != "" {
+		var err error
+		onPremNodeGroups, err = regexp.Compile(config.OnPremNodeGroups)
+		if err != nil {
+			return nil, fmt.Errorf("invalid on-prem node groups expression %q: %v", config.OnPremNodeGroups, err)
+		}
+	}
+	fallback := config.Fallback
+	switch fallback {
+	case "":
+		fallback = FallbackAll
+	case FallbackOnPremFirst, FallbackCloudFirst, FallbackAll:
+	default:
+		return nil, fmt.Errorf("unknown capacity broker fallback %q, expected one of %s, %s, %s", config.Fallback, FallbackOnPremFirst, FallbackCloudFirst, FallbackAll)
+	}
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	cacheTTL := config.CacheTTL
+	if cacheTTL <= 0 {
+		cacheTTL = defaultCacheTTL
+	}
+	return &capacityBroker{
+		url:              config.URL,
+		client:           &http.Client{},
+		timeout:          timeout,
+		cacheTTL:         cacheTTL,
+		onPremNodeGroups: onPremNodeGroups,
+		fallback:         fallback,
+		responses:        make(map[string]cachedResponse),
+		now:              time.Now,
+	}, nil
+}
+
+// BestOptions returns the options whose node groups the broker accepted, in the broker's order of
+// preference. Broker responses are cached for the same node groups and node counts. If the broker
+// can't be consulted, its last response for the same options is reused, and the fallback policy is
+// applied if there is none or the broker accepts none of the options.
+func (b *capacityBroker) BestOptions(expansionOptions []expander.Option, nodeInfo map[string]*schedulerframework.NodeInfo) []expander.Option {
+	if b.url == "" || len(expansionOptions) == 0 {
+		return b.applyFallback(expansionOptions)
+	}
+	response, err := b.getResponse(expansionOptions, nodeInfo)
+	if err != nil {
+		klog.Warningf("Failed to consult the capacity broker, applying the %s fallback: %v", b.fallback, err)
+		return b.applyFallback(expansionOptions)
+	}
+
+	optionsByNodeGroup := make(map[string]expander.Option)
+	for _, option := range expansionOptions {
+		optionsByNodeGroup[option.NodeGroup.Id()] = option
+	}
+	var options []expander.Option
+	for _, id := range response.NodeGroups {
+		option, found := optionsByNodeGroup[id]
+		if !found {
+			klog.Warningf("Capacity broker returned unknown node group %q", id)
+			continue
+		}
+		options = append(options, option)
+		delete(optionsByNodeGroup, id)
+	}
+	if len(options) == 0 {
+		klog.Warningf("Capacity broker accepted none of the %d options, applying the %s fallback", len(expansionOptions), b.fallback)
+		return b.applyFallback(expansionOptions)
+	}
+	klog.V(4).Infof("Capacity broker accepted %d of %d options", len(options), len(expansionOptions))
+	return options
+}
+
+// getResponse returns the cached broker response for the options if it's fresh, consults the broker
+// otherwise, and falls back to a stale cached response if the broker can't be consulted.
+func (b *capacityBroker) getResponse(expansionOptions []expander.Option, nodeInfo map[string]*schedulerframework.NodeInfo) (*Response, error) {
+	key := cacheKey(expansionOptions)
+	b.mutex.Lock()
+	cached, found := b.responses[key]
+	b.mutex.Unlock()
+	if found && b.now().Sub(cached.time) < b.cacheTTL {
+		return cached.response, nil
+	}
+
+	response, err := b.consult(expansionOptions, nodeInfo)
+	if err != nil {
+		if found {
+			klog.Warningf("Failed to consult the capacity broker, reusing its response from %v: %v", cached.time, err)
+			return cached.response, nil
+		}
+		return nil, err
+	}
+
+	now := b.now()
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for k, r := range b.responses {
+		if now.Sub(r.time) >= staleResponseTTL {
+			delete(b.responses, k)
+		}
+	}
+	b.responses[key] = cachedResponse{response: response, time: now}
+	return response, nil
+}
+
+// cacheKey identifies the options by their node groups and node counts.
+func cacheKey(expansionOptions []expander.Option) string {
+	var parts []string
+	for _, option := range expansionOptions {
+		parts = append(parts, fmt.Sprintf("%s:%d", option.NodeGroup.Id(), option.NodeCount))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func (b *capacityBroker) consult(expansionOptions []expander.Option, nodeInfo map[string]*schedulerframework.NodeInfo) (*Response, error) {
+	request := Request{}
+	for _, option := range expansionOptions {
+		brokerOption := Option{
+			NodeGroup: option.NodeGroup.Id(),
+			Pool:      b.pool(option.NodeGroup.Id()),
+			NodeCount: option.NodeCount,
+		}
+		if info, found := nodeInfo[option.NodeGroup.Id()]; found && info.Node() != nil {
+			brokerOption.NodeAllocatable = info.Node().Status.Allocatable
+		}
+		for _, pod := range option.Pods {
+			brokerOption.Pods = append(brokerOption.Pods, fmt.Sprintf("%s/%s", pod.Namespace, pod.Name))
+		}
+		request.Options = append(request.Options, brokerOption)
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("capacity broker responded with status %s", resp.Status)
+	}
+	response := &Response{}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return nil, fmt.Errorf("failed to decode capacity broker response: %v", err)
+	}
+	return response, nil
+}
+
+// applyFallback keeps the options of the preferred pool of the fallback policy, or all options if
+// there are none.
+func (b *capacityBroker) applyFallback(expansionOptions []expander.Option) []expander.Option {
+	var preferred string
+	switch b.fallback {
+	case FallbackOnPremFirst:
+		preferred = OnPremPool
+	case FallbackCloudFirst:
+		preferred = CloudPool
+	default:
+		return expansionOptions
+	}
+	var options []expander.Option
+	for _, option := range expansionOptions {
+		if b.pool(option.NodeGroup.Id()) == preferred {
+			options = append(options, option)
+		}
+	}
+	if len(options) == 0 {
+		return expansionOptions
+	}
+	return options
+}
+
+func (b *capacityBroker) pool(nodeGroupId string) string {
+	if b.onPremNodeGroups != nil && b.onPremNodeGroups.MatchString(nodeGroupId) {
+		return OnPremPool
+	}
+	return CloudPool
+}
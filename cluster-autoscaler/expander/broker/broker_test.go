@@ -0,0 +1,182 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+func testOptions() (expander.Option, expander.Option, expander.Option) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("dc1-workers", 0, 10, 0)
+	provider.AddNodeGroup("aws-workers", 0, 10, 0)
+	provider.AddNodeGroup("gce-workers", 0, 10, 0)
+
+	p1 := BuildTestPod("p1", 100, 100)
+	onPrem := expander.Option{NodeGroup: provider.GetNodeGroup("dc1-workers"), NodeCount: 1, Pods: []*apiv1.Pod{p1}, Debug: "dc1"}
+	aws := expander.Option{NodeGroup: provider.GetNodeGroup("aws-workers"), NodeCount: 2, Pods: []*apiv1.Pod{p1}, Debug: "aws"}
+	gce := expander.Option{NodeGroup: provider.GetNodeGroup("gce-workers"), NodeCount: 3, Pods: []*apiv1.Pod{p1}, Debug: "gce"}
+	return onPrem, aws, gce
+}
+
+func TestBrokerSelection(t *testing.T) {
+	onPrem, aws, gce := testOptions()
+
+	var received Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		// Burst to the cloud, preferring gce, and return a node group which isn't an option.
+		_ = json.NewEncoder(w).Encode(Response{NodeGroups: []string{"gce-workers", "unknown", "aws-workers"}})
+	}))
+	defer server.Close()
+
+	node := BuildTestNode("dc1-template", 4000, 8000)
+	nodeInfo := schedulerframework.NewNodeInfo()
+	nodeInfo.SetNode(node)
+
+	e, err := NewFilter(Config{URL: server.URL, Timeout: time.Second, OnPremNodeGroups: "^dc[0-9]+-", Fallback: FallbackOnPremFirst})
+	assert.NoError(t, err)
+	ret := e.BestOptions([]expander.Option{onPrem, aws, gce}, map[string]*schedulerframework.NodeInfo{"dc1-workers": nodeInfo})
+	assert.Equal(t, []expander.Option{gce, aws}, ret)
+
+	assert.Equal(t, 3, len(received.Options))
+	assert.Equal(t, Option{
+		NodeGroup:       "dc1-workers",
+		Pool:            OnPremPool,
+		NodeCount:       1,
+		NodeAllocatable: node.Status.Allocatable,
+		Pods:            []string{"default/p1"},
+	}, received.Options[0])
+	assert.Equal(t, CloudPool, received.Options[1].Pool)
+	assert.Equal(t, CloudPool, received.Options[2].Pool)
+}
+
+func TestBrokerFallback(t *testing.T) {
+	onPrem, aws, gce := testOptions()
+	options := []expander.Option{onPrem, aws, gce}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	empty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(Response{})
+	}))
+	defer empty.Close()
+
+	for tn, tc := range map[string]struct {
+		config   Config
+		options  []expander.Option
+		expected []expander.Option
+	}{
+		"no broker, on-prem first": {
+			config:   Config{OnPremNodeGroups: "^dc[0-9]+-", Fallback: FallbackOnPremFirst},
+			options:  options,
+			expected: []expander.Option{onPrem},
+		},
+		"broker failure, on-prem first": {
+			config:   Config{URL: failing.URL, OnPremNodeGroups: "^dc[0-9]+-", Fallback: FallbackOnPremFirst},
+			options:  options,
+			expected: []expander.Option{onPrem},
+		},
+		"broker failure, on-prem first without on-prem options": {
+			config:   Config{URL: failing.URL, OnPremNodeGroups: "^dc[0-9]+-", Fallback: FallbackOnPremFirst},
+			options:  []expander.Option{aws, gce},
+			expected: []expander.Option{aws, gce},
+		},
+		"broker accepts nothing, cloud first": {
+			config:   Config{URL: empty.URL, OnPremNodeGroups: "^dc[0-9]+-", Fallback: FallbackCloudFirst},
+			options:  options,
+			expected: []expander.Option{aws, gce},
+		},
+		"broker accepts nothing, all": {
+			config:   Config{URL: empty.URL, OnPremNodeGroups: "^dc[0-9]+-", Fallback: FallbackAll},
+			options:  options,
+			expected: options,
+		},
+		"no on-prem node groups, on-prem first": {
+			config:   Config{Fallback: FallbackOnPremFirst},
+			options:  options,
+			expected: options,
+		},
+	} {
+		t.Run(tn, func(t *testing.T) {
+			e, err := NewFilter(tc.config)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, e.BestOptions(tc.options, nil))
+		})
+	}
+}
+
+func TestBrokerCache(t *testing.T) {
+	onPrem, aws, gce := testOptions()
+	options := []expander.Option{onPrem, aws, gce}
+
+	calls := 0
+	failing := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(Response{NodeGroups: []string{"aws-workers"}})
+	}))
+	defer server.Close()
+
+	e, err := NewFilter(Config{URL: server.URL, OnPremNodeGroups: "^dc[0-9]+-", Fallback: FallbackOnPremFirst, CacheTTL: time.Minute})
+	assert.NoError(t, err)
+	now := time.Now()
+	e.(*capacityBroker).now = func() time.Time { return now }
+
+	assert.Equal(t, []expander.Option{aws}, e.BestOptions(options, nil))
+	assert.Equal(t, 1, calls)
+
+	// The cached response is reused for the same options, in any order.
+	assert.Equal(t, []expander.Option{aws}, e.BestOptions([]expander.Option{gce, aws, onPrem}, nil))
+	assert.Equal(t, 1, calls)
+
+	// Other node counts are new options.
+	gce.NodeCount = 5
+	assert.Equal(t, []expander.Option{onPrem}, e.BestOptions([]expander.Option{onPrem, gce}, nil))
+	assert.Equal(t, 2, calls)
+
+	// Once expired, the broker is consulted again, and its stale response is reused if it fails.
+	now = now.Add(2 * time.Minute)
+	failing = true
+	assert.Equal(t, []expander.Option{aws}, e.BestOptions(options, nil))
+	assert.Equal(t, 3, calls)
+}
+
+func TestBrokerInvalidConfig(t *testing.T) {
+	_, err := NewFilter(Config{OnPremNodeGroups: "dc[", Fallback: FallbackOnPremFirst})
+	assert.Error(t, err)
+	_, err = NewFilter(Config{Fallback: "unknown"})
+	assert.Error(t, err)
+}
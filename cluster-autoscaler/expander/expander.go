@@ -24,7 +24,7 @@ import (
 
 var (
 	// AvailableExpanders is a list of available expander options
-	AvailableExpanders = []string{RandomExpanderName, MostPodsExpanderName, LeastWasteExpanderName, PriceBasedExpanderName, PriorityBasedExpanderName, GRPCExpanderName}
+	AvailableExpanders = []string{RandomExpanderName, MostPodsExpanderName, LeastWasteExpanderName, PriceBasedExpanderName, PriorityBasedExpanderName, GRPCExpanderName, PreferredAffinityExpanderName, ImageLocalityExpanderName, CapacityBrokerExpanderName, WarmCapacityExpanderName, SpotExpanderName}
 	// RandomExpanderName selects a node group at random
 	RandomExpanderName = "random"
 	// MostPodsExpanderName selects a node group that fits the most pods
@@ -38,6 +38,16 @@ var (
 	PriorityBasedExpanderName = "priority"
 	// GRPCExpanderName uses the gRPC client expander to call to an external gRPC server to select a node group for scale up
 	GRPCExpanderName = "grpc"
+	// PreferredAffinityExpanderName selects a node group whose nodes best satisfy preferred node affinities of the pods
+	PreferredAffinityExpanderName = "preferred-affinity"
+	// ImageLocalityExpanderName selects a node group whose machine image already contains the images of the pods
+	ImageLocalityExpanderName = "image-locality"
+	// CapacityBrokerExpanderName asks an external capacity broker whether on-prem or cloud node groups should absorb the workload
+	CapacityBrokerExpanderName = "capacity-broker"
+	// WarmCapacityExpanderName selects a node group that can be scaled up from pre-initialized instances, e.g. an AWS warm pool
+	WarmCapacityExpanderName = "warm-capacity"
+	// SpotExpanderName selects a node group backed by spot or preemptible capacity
+	SpotExpanderName = "spot"
 )
 
 // Option describes an option to expand the cluster.
@@ -58,3 +68,11 @@ type Strategy interface {
 type Filter interface {
 	BestOptions(options []Option, nodeInfo map[string]*schedulerframework.NodeInfo) []Option
 }
+
+// Scorer is an optional interface of filters able to score options, used when expanders are weighted.
+// Filters not implementing it score the options they select 1 and the other ones 0.
+type Scorer interface {
+	// Scores returns the score of each option it can score, keyed by the id of its node group. Higher scores
+	// are better. Scores are normalized across options before being weighted.
+	Scores(options []Option, nodeInfo map[string]*schedulerframework.NodeInfo) map[string]float64
+}
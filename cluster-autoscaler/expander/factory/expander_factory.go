@@ -17,14 +17,22 @@ limitations under the License.
 package factory
 
 import (
+	"strconv"
+	"strings"
+
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	"k8s.io/autoscaler/cluster-autoscaler/context"
 	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	"k8s.io/autoscaler/cluster-autoscaler/expander/affinity"
+	"k8s.io/autoscaler/cluster-autoscaler/expander/broker"
 	"k8s.io/autoscaler/cluster-autoscaler/expander/grpcplugin"
+	"k8s.io/autoscaler/cluster-autoscaler/expander/imagelocality"
 	"k8s.io/autoscaler/cluster-autoscaler/expander/mostpods"
 	"k8s.io/autoscaler/cluster-autoscaler/expander/price"
 	"k8s.io/autoscaler/cluster-autoscaler/expander/priority"
 	"k8s.io/autoscaler/cluster-autoscaler/expander/random"
+	"k8s.io/autoscaler/cluster-autoscaler/expander/spot"
+	"k8s.io/autoscaler/cluster-autoscaler/expander/warmcapacity"
 	"k8s.io/autoscaler/cluster-autoscaler/expander/waste"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
@@ -35,23 +43,43 @@ import (
 
 // Factory can create expander.Strategy based on provided expander names.
 type Factory struct {
-	createFunc map[string]func() expander.Filter
+	createFunc map[string]func() (expander.Filter, error)
+}
+
+// DefaultExpanderOptions configures the default expanders registered with RegisterDefaultExpandersWithOptions.
+type DefaultExpanderOptions struct {
+	// CapacityBroker configures the capacity-broker expander.
+	CapacityBroker broker.Config
 }
 
 // NewFactory returns a new Factory.
 func NewFactory() *Factory {
 	return &Factory{
-		createFunc: make(map[string]func() expander.Filter),
+		createFunc: make(map[string]func() (expander.Filter, error)),
 	}
 }
 
 // RegisterFilter registers a function that can provision a new expander.Filter under the specified name.
 func (f *Factory) RegisterFilter(name string, createFunc func() expander.Filter) {
+	f.createFunc[name] = func() (expander.Filter, error) { return createFunc(), nil }
+}
+
+// RegisterFilterWithError registers a function that can provision a new expander.Filter under the specified
+// name, or fail if the expander is misconfigured.
+func (f *Factory) RegisterFilterWithError(name string, createFunc func() (expander.Filter, error)) {
 	f.createFunc[name] = createFunc
 }
 
-// Build creates a new expander.Strategy based on a list of expander.Filter names.
+// Build creates a new expander.Strategy based on a list of expander.Filter names. Names may be given
+// weights in the <name>:<weight> format, in which case the option maximizing the weighted sum of the
+// scores of the expanders is selected, instead of applying the expanders in succession.
 func (f *Factory) Build(names []string) (expander.Strategy, errors.AutoscalerError) {
+	for _, name := range names {
+		if strings.Contains(name, ":") {
+			return f.buildWeighted(names)
+		}
+	}
+
 	var filters []expander.Filter
 	seenExpanders := map[string]struct{}{}
 	strategySeen := false
@@ -65,11 +93,14 @@ func (f *Factory) Build(names []string) (expander.Strategy, errors.AutoscalerErr
 		seenExpanders[name] = struct{}{}
 
 		create, known := f.createFunc[name]
-		if known {
-			filters = append(filters, create())
-		} else {
+		if !known {
 			return nil, errors.NewAutoscalerError(errors.InternalError, "Expander %s not supported", name)
 		}
+		filter, err := create()
+		if err != nil {
+			return nil, errors.NewAutoscalerError(errors.ConfigurationError, "Failed to create expander %s: %v", name, err)
+		}
+		filters = append(filters, filter)
 		if _, ok := filters[len(filters)-1].(expander.Strategy); ok {
 			strategySeen = true
 		}
@@ -77,8 +108,46 @@ func (f *Factory) Build(names []string) (expander.Strategy, errors.AutoscalerErr
 	return newChainStrategy(filters, random.NewStrategy()), nil
 }
 
-// RegisterDefaultExpanders is a convenience function, registering all known expanders in the Factory.
+// buildWeighted creates a new expander.Strategy based on a list of <name>:<weight> expander.Filter specifications.
+func (f *Factory) buildWeighted(specs []string) (expander.Strategy, errors.AutoscalerError) {
+	var filters []weightedFilter
+	seenExpanders := map[string]struct{}{}
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, ":", 2)
+		if len(parts) != 2 {
+			return nil, errors.NewAutoscalerError(errors.InternalError, "Expander %s has no weight, either all expanders or none must be given a weight in the <name>:<weight> format", spec)
+		}
+		name := parts[0]
+		weight, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || weight <= 0 {
+			return nil, errors.NewAutoscalerError(errors.InternalError, "Expander %s has an invalid weight %q, expected a positive number", name, parts[1])
+		}
+		if _, ok := seenExpanders[name]; ok {
+			return nil, errors.NewAutoscalerError(errors.InternalError, "Expander %s was specified multiple times, each expander must not be specified more than once", name)
+		}
+		seenExpanders[name] = struct{}{}
+
+		create, known := f.createFunc[name]
+		if !known {
+			return nil, errors.NewAutoscalerError(errors.InternalError, "Expander %s not supported", name)
+		}
+		filter, err := create()
+		if err != nil {
+			return nil, errors.NewAutoscalerError(errors.ConfigurationError, "Failed to create expander %s: %v", name, err)
+		}
+		filters = append(filters, weightedFilter{filter: filter, weight: weight})
+	}
+	return newWeightedStrategy(filters, random.NewStrategy()), nil
+}
+
+// RegisterDefaultExpanders is a convenience function, registering all known expanders in the Factory, including the
+// out-of-tree ones registered with expander.Register.
 func (f *Factory) RegisterDefaultExpanders(cloudProvider cloudprovider.CloudProvider, autoscalingKubeClients *context.AutoscalingKubeClients, kubeClient kube_client.Interface, configNamespace string, GRPCExpanderCert string, GRPCExpanderURL string) {
+	f.RegisterDefaultExpandersWithOptions(cloudProvider, autoscalingKubeClients, kubeClient, configNamespace, GRPCExpanderCert, GRPCExpanderURL, DefaultExpanderOptions{})
+}
+
+// RegisterDefaultExpandersWithOptions is like RegisterDefaultExpanders, configuring the expanders with the given options.
+func (f *Factory) RegisterDefaultExpandersWithOptions(cloudProvider cloudprovider.CloudProvider, autoscalingKubeClients *context.AutoscalingKubeClients, kubeClient kube_client.Interface, configNamespace string, GRPCExpanderCert string, GRPCExpanderURL string, options DefaultExpanderOptions) {
 	f.RegisterFilter(expander.RandomExpanderName, random.NewFilter)
 	f.RegisterFilter(expander.MostPodsExpanderName, mostpods.NewFilter)
 	f.RegisterFilter(expander.LeastWasteExpanderName, waste.NewFilter)
@@ -96,4 +165,13 @@ func (f *Factory) RegisterDefaultExpanders(cloudProvider cloudprovider.CloudProv
 		return priority.NewFilter(lister.ConfigMaps(configNamespace), autoscalingKubeClients.Recorder)
 	})
 	f.RegisterFilter(expander.GRPCExpanderName, func() expander.Filter { return grpcplugin.NewFilter(GRPCExpanderCert, GRPCExpanderURL) })
+	f.RegisterFilter(expander.PreferredAffinityExpanderName, affinity.NewFilter)
+	f.RegisterFilter(expander.ImageLocalityExpanderName, imagelocality.NewFilter)
+	f.RegisterFilterWithError(expander.CapacityBrokerExpanderName, func() (expander.Filter, error) { return broker.NewFilter(options.CapacityBroker) })
+	f.RegisterFilter(expander.WarmCapacityExpanderName, warmcapacity.NewFilter)
+	f.RegisterFilter(expander.SpotExpanderName, spot.NewFilter)
+	for name, createFilter := range expander.RegisteredFilterFactories() {
+		createFilter := createFilter
+		f.RegisterFilter(name, func() expander.Filter { return createFilter(cloudProvider, kubeClient) })
+	}
 }
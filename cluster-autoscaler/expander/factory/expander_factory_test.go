@@ -0,0 +1,87 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package factory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	"k8s.io/autoscaler/cluster-autoscaler/expander/broker"
+	kube_client "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRegisteredExpanders(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	kubeClient := fake.NewSimpleClientset()
+	var gotProvider cloudprovider.CloudProvider
+	var gotKubeClient kube_client.Interface
+	expander.Register("test-out-of-tree", func(cloudProvider cloudprovider.CloudProvider, kubeClient kube_client.Interface) expander.Filter {
+		gotProvider, gotKubeClient = cloudProvider, kubeClient
+		return newSubstringTestFilterStrategy("a")
+	})
+
+	f := NewFactory()
+	f.RegisterDefaultExpanders(provider, nil, kubeClient, "kube-system", "", "")
+	strategy, err := f.Build([]string{expander.LeastWasteExpanderName, "test-out-of-tree"})
+	assert.NoError(t, err)
+	assert.NotNil(t, strategy)
+	assert.Equal(t, provider, gotProvider)
+	assert.Equal(t, kubeClient, gotKubeClient)
+
+	_, err = f.Build([]string{"test-unknown"})
+	assert.Error(t, err)
+}
+
+func TestRegisteredExpandersWithOptions(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	kubeClient := fake.NewSimpleClientset()
+
+	f := NewFactory()
+	f.RegisterDefaultExpandersWithOptions(provider, nil, kubeClient, "kube-system", "", "", DefaultExpanderOptions{
+		CapacityBroker: broker.Config{OnPremNodeGroups: "^dc[0-9]+-", Fallback: broker.FallbackOnPremFirst},
+	})
+	strategy, err := f.Build([]string{expander.CapacityBrokerExpanderName})
+	assert.NoError(t, err)
+	assert.NotNil(t, strategy)
+
+	f.RegisterDefaultExpandersWithOptions(provider, nil, kubeClient, "kube-system", "", "", DefaultExpanderOptions{
+		CapacityBroker: broker.Config{Fallback: "unknown"},
+	})
+	_, err = f.Build([]string{expander.CapacityBrokerExpanderName})
+	assert.Error(t, err)
+	_, err = f.Build([]string{expander.CapacityBrokerExpanderName + ":1"})
+	assert.Error(t, err)
+}
+
+func TestBuildWeighted(t *testing.T) {
+	f := NewFactory()
+	f.RegisterFilter("a", func() expander.Filter { return newSubstringTestFilterStrategy("a") })
+	f.RegisterFilter("b", func() expander.Filter { return newSubstringTestFilterStrategy("b") })
+
+	strategy, err := f.Build([]string{"a:0.7", "b:0.3"})
+	assert.NoError(t, err)
+	assert.IsType(t, &weightedStrategy{}, strategy)
+
+	for _, names := range [][]string{{"a:0.7", "b"}, {"a:x"}, {"a:0"}, {"a:-1"}, {"a:1", "a:2"}, {"c:1"}} {
+		_, err := f.Build(names)
+		assert.Error(t, err, "names: %v", names)
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package factory
+
+import (
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	"k8s.io/autoscaler/cluster-autoscaler/metrics"
+
+	klog "k8s.io/klog/v2"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+type shadowStrategy struct {
+	active expander.Strategy
+	shadow expander.Strategy
+}
+
+// NewShadowStrategy returns a strategy acting on the decisions of the active strategy, that also
+// computes the decisions of the shadow strategy and reports whether they agree, so that switching
+// to the shadow strategy can be evaluated on real scale-ups.
+func NewShadowStrategy(active, shadow expander.Strategy) expander.Strategy {
+	return &shadowStrategy{
+		active: active,
+		shadow: shadow,
+	}
+}
+
+// BestOption returns the option selected by the active strategy.
+func (s *shadowStrategy) BestOption(options []expander.Option, nodeInfo map[string]*schedulerframework.NodeInfo) *expander.Option {
+	// The shadow strategy gets its own copy of the options, so that it can't affect the active decision.
+	shadowOptions := append([]expander.Option(nil), options...)
+	best := s.active.BestOption(options, nodeInfo)
+	shadowBest := s.shadow.BestOption(shadowOptions, nodeInfo)
+
+	activeNodeGroup, shadowNodeGroup := optionNodeGroup(best), optionNodeGroup(shadowBest)
+	agreed := activeNodeGroup == shadowNodeGroup
+	metrics.RegisterShadowExpanderDecision(agreed)
+	if agreed {
+		klog.V(4).Infof("Shadow expander agrees with the active expander on node group %q", activeNodeGroup)
+	} else {
+		klog.V(2).Infof("Shadow expander would have chosen node group %q instead of %q out of %d options", shadowNodeGroup, activeNodeGroup, len(options))
+	}
+	return best
+}
+
+func optionNodeGroup(option *expander.Option) string {
+	if option == nil || option.NodeGroup == nil {
+		return ""
+	}
+	return option.NodeGroup.Id()
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package factory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+type recordingStrategy struct {
+	choice  string
+	options []expander.Option
+}
+
+func (s *recordingStrategy) BestOption(options []expander.Option, nodeInfo map[string]*schedulerframework.NodeInfo) *expander.Option {
+	s.options = options
+	for i := range options {
+		if options[i].NodeGroup.Id() == s.choice {
+			return &options[i]
+		}
+	}
+	return nil
+}
+
+func TestShadowStrategy(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	options := []expander.Option{
+		{NodeGroup: provider.BuildNodeGroup("ng1", 0, 10, 1, false, "", nil), NodeCount: 1},
+		{NodeGroup: provider.BuildNodeGroup("ng2", 0, 10, 1, false, "", nil), NodeCount: 2},
+	}
+
+	for name, tc := range map[string]struct {
+		activeChoice string
+		shadowChoice string
+	}{
+		"shadow agrees":        {activeChoice: "ng1", shadowChoice: "ng1"},
+		"shadow disagrees":     {activeChoice: "ng1", shadowChoice: "ng2"},
+		"shadow finds nothing": {activeChoice: "ng2", shadowChoice: "ng3"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			active := &recordingStrategy{choice: tc.activeChoice}
+			shadow := &recordingStrategy{choice: tc.shadowChoice}
+			best := NewShadowStrategy(active, shadow).BestOption(options, nil)
+			assert.Equal(t, tc.activeChoice, best.NodeGroup.Id())
+			// Both strategies see all the options, the shadow strategy in a copy of the slice.
+			assert.Equal(t, options, active.options)
+			assert.Equal(t, options, shadow.options)
+			assert.NotSame(t, &options[0], &shadow.options[0])
+		})
+	}
+}
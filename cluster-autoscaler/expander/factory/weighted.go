@@ -0,0 +1,104 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package factory
+
+import (
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+
+	klog "k8s.io/klog/v2"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+type weightedFilter struct {
+	filter expander.Filter
+	weight float64
+}
+
+type weightedStrategy struct {
+	filters  []weightedFilter
+	fallback expander.Strategy
+}
+
+func newWeightedStrategy(filters []weightedFilter, fallback expander.Strategy) expander.Strategy {
+	return &weightedStrategy{
+		filters:  filters,
+		fallback: fallback,
+	}
+}
+
+// BestOption selects the option maximizing the weighted sum of the normalized scores given by the filters.
+// Ties are broken by the fallback strategy.
+func (w *weightedStrategy) BestOption(options []expander.Option, nodeInfo map[string]*schedulerframework.NodeInfo) *expander.Option {
+	totals := make([]float64, len(options))
+	for _, f := range w.filters {
+		scores := normalizedScores(f.filter, options, nodeInfo)
+		for i, option := range options {
+			totals[i] += f.weight * scores[option.NodeGroup.Id()]
+		}
+	}
+
+	var bestOptions []expander.Option
+	bestScore := 0.0
+	for i, option := range options {
+		klog.V(4).Infof("Weighted expander score of node group %s: %f", option.NodeGroup.Id(), totals[i])
+		if len(bestOptions) == 0 || totals[i] > bestScore {
+			bestOptions = []expander.Option{option}
+			bestScore = totals[i]
+		} else if totals[i] == bestScore {
+			bestOptions = append(bestOptions, option)
+		}
+	}
+	if len(bestOptions) == 1 {
+		return &bestOptions[0]
+	}
+	return w.fallback.BestOption(bestOptions, nodeInfo)
+}
+
+// normalizedScores returns the scores of the options given by the filter, scaled to [0, 1]. Filters not
+// implementing expander.Scorer score the options they select 1. Options that can't be scored score 0.
+func normalizedScores(filter expander.Filter, options []expander.Option, nodeInfo map[string]*schedulerframework.NodeInfo) map[string]float64 {
+	scorer, ok := filter.(expander.Scorer)
+	if !ok {
+		scores := make(map[string]float64)
+		for _, option := range filter.BestOptions(options, nodeInfo) {
+			scores[option.NodeGroup.Id()] = 1
+		}
+		return scores
+	}
+
+	scores := scorer.Scores(options, nodeInfo)
+	first := true
+	lowest, highest := 0.0, 0.0
+	for _, score := range scores {
+		if first || score < lowest {
+			lowest = score
+		}
+		if first || score > highest {
+			highest = score
+		}
+		first = false
+	}
+	normalized := make(map[string]float64, len(scores))
+	for id, score := range scores {
+		if highest == lowest {
+			normalized[id] = 1
+		} else {
+			normalized[id] = (score - lowest) / (highest - lowest)
+		}
+	}
+	return normalized
+}
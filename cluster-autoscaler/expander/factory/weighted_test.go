@@ -0,0 +1,116 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package factory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	"k8s.io/autoscaler/cluster-autoscaler/expander/random"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// fixedScorer scores options with fixed scores, selecting the ones scoring highest.
+type fixedScorer struct {
+	scores map[string]float64
+}
+
+func (f *fixedScorer) BestOptions(options []expander.Option, nodeInfo map[string]*schedulerframework.NodeInfo) []expander.Option {
+	var best []expander.Option
+	for _, option := range options {
+		if len(best) == 0 || f.scores[option.NodeGroup.Id()] > f.scores[best[0].NodeGroup.Id()] {
+			best = []expander.Option{option}
+		} else if f.scores[option.NodeGroup.Id()] == f.scores[best[0].NodeGroup.Id()] {
+			best = append(best, option)
+		}
+	}
+	return best
+}
+
+func (f *fixedScorer) Scores(options []expander.Option, nodeInfo map[string]*schedulerframework.NodeInfo) map[string]float64 {
+	return f.scores
+}
+
+// fixedFilter selects the options of the given node groups, without scoring them.
+type fixedFilter struct {
+	ids map[string]bool
+}
+
+func (f *fixedFilter) BestOptions(options []expander.Option, nodeInfo map[string]*schedulerframework.NodeInfo) []expander.Option {
+	var best []expander.Option
+	for _, option := range options {
+		if f.ids[option.NodeGroup.Id()] {
+			best = append(best, option)
+		}
+	}
+	return best
+}
+
+func TestWeightedStrategy_BestOption(t *testing.T) {
+	// cheap is the cheapest but wastes the most, lean is a bit more expensive but wastes little.
+	price := &fixedScorer{scores: map[string]float64{"cheap": -1, "lean": -1.2, "pricey": -3}}
+	waste := &fixedScorer{scores: map[string]float64{"cheap": -0.9, "lean": -0.1, "pricey": 0}}
+	options := []expander.Option{
+		{NodeGroup: testprovider.NewTestNodeGroup("cheap", 10, 0, 1, true, false, "", nil, nil)},
+		{NodeGroup: testprovider.NewTestNodeGroup("lean", 10, 0, 1, true, false, "", nil, nil)},
+		{NodeGroup: testprovider.NewTestNodeGroup("pricey", 10, 0, 1, true, false, "", nil, nil)},
+	}
+
+	for name, tc := range map[string]struct {
+		filters  []weightedFilter
+		expected string
+	}{
+		"single scorer": {
+			filters:  []weightedFilter{{filter: price, weight: 1}},
+			expected: "cheap",
+		},
+		"trades off scorers": {
+			filters:  []weightedFilter{{filter: price, weight: 0.7}, {filter: waste, weight: 0.3}},
+			expected: "lean",
+		},
+		"heavily weighted scorer wins": {
+			filters:  []weightedFilter{{filter: price, weight: 0.1}, {filter: waste, weight: 0.9}},
+			expected: "pricey",
+		},
+		"filter without scores": {
+			filters:  []weightedFilter{{filter: price, weight: 1}, {filter: &fixedFilter{ids: map[string]bool{"pricey": true}}, weight: 2}},
+			expected: "pricey",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			subject := newWeightedStrategy(tc.filters, random.NewStrategy())
+			actual := subject.BestOption(options, nil)
+			assert.NotNil(t, actual)
+			assert.Equal(t, tc.expected, actual.NodeGroup.Id())
+		})
+	}
+}
+
+func TestWeightedStrategy_BreaksTies(t *testing.T) {
+	options := []expander.Option{
+		{NodeGroup: testprovider.NewTestNodeGroup("a", 10, 0, 1, true, false, "", nil, nil), Debug: "a"},
+		{NodeGroup: testprovider.NewTestNodeGroup("b", 10, 0, 1, true, false, "", nil, nil), Debug: "b"},
+		{NodeGroup: testprovider.NewTestNodeGroup("c", 10, 0, 1, true, false, "", nil, nil), Debug: "c"},
+	}
+	scorer := &fixedScorer{scores: map[string]float64{"a": 0, "b": 1, "c": 1}}
+	subject := newWeightedStrategy([]weightedFilter{{filter: scorer, weight: 1}}, newSubstringTestFilterStrategy("c"))
+	actual := subject.BestOption(options, nil)
+	assert.NotNil(t, actual)
+	assert.Equal(t, "c", actual.NodeGroup.Id())
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagelocality
+
+import (
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+type imageLocality struct {
+}
+
+// NewFilter returns a scale up filter that picks the node groups whose machine image already
+// contains the images of the pods they would schedule, so that the new nodes get ready faster.
+func NewFilter() expander.Filter {
+	return &imageLocality{}
+}
+
+// BestOptions selects the expansion options with the most containers whose image is listed
+// in the images of the node group template, summed over the pods the option schedules. Options
+// are left unchanged if none of them has any pre-baked image.
+func (i *imageLocality) BestOptions(expansionOptions []expander.Option, nodeInfo map[string]*schedulerframework.NodeInfo) []expander.Option {
+	var maxScore int
+	var maxOptions []expander.Option
+
+	for _, option := range expansionOptions {
+		score := optionScore(option, nodeInfo)
+		if score == maxScore {
+			maxOptions = append(maxOptions, option)
+		}
+
+		if score > maxScore {
+			maxScore = score
+			maxOptions = []expander.Option{option}
+		}
+	}
+
+	if len(maxOptions) == 0 {
+		return nil
+	}
+
+	return maxOptions
+}
+
+func optionScore(option expander.Option, nodeInfo map[string]*schedulerframework.NodeInfo) int {
+	info, found := nodeInfo[option.NodeGroup.Id()]
+	if !found || info.Node() == nil || len(info.Node().Status.Images) == 0 {
+		return 0
+	}
+	images := nodeImages(info.Node())
+	var score int
+	for _, pod := range option.Pods {
+		score += podScore(pod, images)
+	}
+	return score
+}
+
+// nodeImages returns the set of names and digests of the images present on the node.
+func nodeImages(node *apiv1.Node) map[string]bool {
+	images := make(map[string]bool)
+	for _, image := range node.Status.Images {
+		for _, name := range image.Names {
+			images[name] = true
+			if digest := imageDigest(name); digest != "" {
+				images[digest] = true
+			}
+		}
+	}
+	return images
+}
+
+func podScore(pod *apiv1.Pod, images map[string]bool) int {
+	var score int
+	for _, containers := range [][]apiv1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for _, container := range containers {
+			if images[container.Image] {
+				score++
+			} else if digest := imageDigest(container.Image); digest != "" && images[digest] {
+				score++
+			}
+		}
+	}
+	return score
+}
+
+// imageDigest returns the digest of an image reference such as repo@sha256:abc, or of a bare
+// digest, and an empty string for references by tag.
+func imageDigest(image string) string {
+	if i := strings.LastIndex(image, "@"); i >= 0 {
+		return image[i+1:]
+	}
+	if strings.HasPrefix(image, "sha256:") {
+		return image
+	}
+	return ""
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagelocality
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const (
+	trainerDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	sidecarDigest = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+)
+
+func withImages(pod *apiv1.Pod, images ...string) *apiv1.Pod {
+	pod.Spec.Containers = nil
+	for _, image := range images {
+		pod.Spec.Containers = append(pod.Spec.Containers, apiv1.Container{Image: image})
+	}
+	return pod
+}
+
+func TestImageLocality(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng-a", 0, 10, 0)
+	provider.AddNodeGroup("ng-b", 0, 10, 0)
+	provider.AddNodeGroup("ng-c", 0, 10, 0)
+
+	nodeInfos := map[string]*schedulerframework.NodeInfo{}
+	for id, images := range map[string][]apiv1.ContainerImage{
+		"ng-a": {{Names: []string{trainerDigest}}},
+		"ng-b": {{Names: []string{"registry.example.com/trainer@" + trainerDigest}}, {Names: []string{sidecarDigest}}},
+		"ng-c": nil,
+	} {
+		node := BuildTestNode(id+"-template", 1000, 1000)
+		node.Status.Images = images
+		nodeInfo := schedulerframework.NewNodeInfo()
+		nodeInfo.SetNode(node)
+		nodeInfos[id] = nodeInfo
+	}
+
+	p1 := withImages(BuildTestPod("p1", 100, 100), "registry.example.com/trainer@"+trainerDigest, "other.example.com/sidecar@"+sidecarDigest)
+	p2 := withImages(BuildTestPod("p2", 100, 100), "registry.example.com/trainer:latest")
+
+	e := NewFilter()
+
+	optionA := expander.Option{NodeGroup: provider.GetNodeGroup("ng-a"), Pods: []*apiv1.Pod{p1}, Debug: "a"}
+	optionB := expander.Option{NodeGroup: provider.GetNodeGroup("ng-b"), Pods: []*apiv1.Pod{p1}, Debug: "b"}
+	optionC := expander.Option{NodeGroup: provider.GetNodeGroup("ng-c"), Pods: []*apiv1.Pod{p1}, Debug: "c"}
+
+	// ng-b has both images of p1 pre-baked, ng-a only one, ng-c none.
+	ret := e.BestOptions([]expander.Option{optionA, optionB, optionC}, nodeInfos)
+	assert.Equal(t, []expander.Option{optionB}, ret)
+
+	// Images referenced by tag don't match digests, so all options are equally good.
+	optionA2 := expander.Option{NodeGroup: provider.GetNodeGroup("ng-a"), Pods: []*apiv1.Pod{p2}, Debug: "a2"}
+	optionC2 := expander.Option{NodeGroup: provider.GetNodeGroup("ng-c"), Pods: []*apiv1.Pod{p2}, Debug: "c2"}
+	ret = e.BestOptions([]expander.Option{optionA2, optionC2}, nodeInfos)
+	assert.Equal(t, []expander.Option{optionA2, optionC2}, ret)
+
+	// Options without a template node score 0.
+	ret = e.BestOptions([]expander.Option{optionA, optionC}, nil)
+	assert.Equal(t, []expander.Option{optionA, optionC}, ret)
+}
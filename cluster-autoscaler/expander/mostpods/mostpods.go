@@ -51,3 +51,12 @@ func (m *mostpods) BestOptions(expansionOptions []expander.Option, nodeInfo map[
 
 	return maxOptions
 }
+
+// Scores scores the expansion options with the number of pods they schedule
+func (m *mostpods) Scores(expansionOptions []expander.Option, nodeInfo map[string]*schedulerframework.NodeInfo) map[string]float64 {
+	scores := make(map[string]float64, len(expansionOptions))
+	for _, option := range expansionOptions {
+		scores[option.NodeGroup.Id()] = float64(len(option.Pods))
+	}
+	return scores
+}
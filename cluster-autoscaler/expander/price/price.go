@@ -90,6 +90,37 @@ func NewFilter(cloudProvider cloudprovider.CloudProvider,
 func (p *priceBased) BestOptions(expansionOptions []expander.Option, nodeInfos map[string]*schedulerframework.NodeInfo) []expander.Option {
 	var bestOptions []expander.Option
 	bestOptionScore := 0.0
+	for _, scored := range p.scoreOptions(expansionOptions, nodeInfos) {
+		if len(bestOptions) == 0 || bestOptionScore == scored.score {
+			bestOptions = append(bestOptions, scored.option)
+			bestOptionScore = scored.score
+		} else if bestOptionScore > scored.score {
+			bestOptions = []expander.Option{scored.option}
+			bestOptionScore = scored.score
+		}
+	}
+	return bestOptions
+}
+
+// Scores scores options with the opposite of their price based score, so that the most cost-effective
+// option consistent with the preferred node scores highest.
+func (p *priceBased) Scores(expansionOptions []expander.Option, nodeInfos map[string]*schedulerframework.NodeInfo) map[string]float64 {
+	scores := make(map[string]float64, len(expansionOptions))
+	for _, scored := range p.scoreOptions(expansionOptions, nodeInfos) {
+		scores[scored.option.NodeGroup.Id()] = -scored.score
+	}
+	return scores
+}
+
+// scoredOption is an option with its price based score, lower being better.
+type scoredOption struct {
+	option expander.Option
+	score  float64
+}
+
+// scoreOptions computes the price based score of the options that can be priced.
+func (p *priceBased) scoreOptions(expansionOptions []expander.Option, nodeInfos map[string]*schedulerframework.NodeInfo) []scoredOption {
+	var scoredOptions []scoredOption
 	now := time.Now()
 	then := now.Add(time.Hour)
 
@@ -175,15 +206,9 @@ nextoption:
 			Debug:     fmt.Sprintf("%s | price-expander: %s", option.Debug, debug),
 			Pods:      option.Pods,
 		}
-		if len(bestOptions) == 0 || bestOptionScore == optionScore {
-			bestOptions = append(bestOptions, maybeBestOption)
-			bestOptionScore = optionScore
-		} else if bestOptionScore > optionScore {
-			bestOptions = []expander.Option{maybeBestOption}
-			bestOptionScore = optionScore
-		}
+		scoredOptions = append(scoredOptions, scoredOption{option: maybeBestOption, score: optionScore})
 	}
-	return bestOptions
+	return scoredOptions
 }
 
 // buildPod creates a pod with specified resources.
@@ -0,0 +1,64 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expander
+
+import (
+	"fmt"
+	"sync"
+
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	kube_client "k8s.io/client-go/kubernetes"
+)
+
+// FilterFactory creates a Filter of an out-of-tree expander, for the cloud provider and the cluster of the autoscaler.
+type FilterFactory func(cloudProvider cloudprovider.CloudProvider, kubeClient kube_client.Interface) Filter
+
+var (
+	registryLock sync.Mutex
+	registry     = map[string]FilterFactory{}
+)
+
+// Register registers an out-of-tree expander under the given name, making it available to --expander like the
+// built-in ones. It is meant to be called from the init function of a package compiled into the autoscaler binary,
+// so that the expander is listed in the flag help. Register panics if the name is already taken.
+func Register(name string, factory FilterFactory) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	if name == "" || factory == nil {
+		panic("expander: Register requires a name and a factory")
+	}
+	for _, available := range AvailableExpanders {
+		if available == name {
+			panic(fmt.Sprintf("expander: Register called twice for expander %s", name))
+		}
+	}
+	registry[name] = factory
+	AvailableExpanders = append(AvailableExpanders, name)
+}
+
+// RegisteredFilterFactories returns the factories of the out-of-tree expanders, by name.
+func RegisteredFilterFactories() map[string]FilterFactory {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	factories := make(map[string]FilterFactory, len(registry))
+	for name, factory := range registry {
+		factories[name] = factory
+	}
+	return factories
+}
@@ -0,0 +1,38 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expander
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	kube_client "k8s.io/client-go/kubernetes"
+)
+
+func TestRegister(t *testing.T) {
+	factory := func(cloudprovider.CloudProvider, kube_client.Interface) Filter { return nil }
+
+	Register("test-registered", factory)
+	assert.Contains(t, AvailableExpanders, "test-registered")
+	assert.Contains(t, RegisteredFilterFactories(), "test-registered")
+
+	assert.Panics(t, func() { Register("test-registered", factory) })
+	assert.Panics(t, func() { Register(RandomExpanderName, factory) })
+	assert.Panics(t, func() { Register("test-nil", nil) })
+	assert.NotContains(t, RegisteredFilterFactories(), RandomExpanderName)
+}
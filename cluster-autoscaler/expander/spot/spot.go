@@ -0,0 +1,50 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spot
+
+import (
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+type spot struct {
+}
+
+// NewFilter returns a scale up filter that picks the node groups backed by spot or preemptible capacity.
+// Interruption-sensitive pods are never part of the options of such node groups, so only pods tolerating
+// interruptions end up on the cheaper capacity.
+func NewFilter() expander.Filter {
+	return &spot{}
+}
+
+// BestOptions selects the expansion options of node groups backed by spot capacity. Options are left
+// unchanged if none of them is.
+func (s *spot) BestOptions(expansionOptions []expander.Option, nodeInfo map[string]*schedulerframework.NodeInfo) []expander.Option {
+	var spotOptions []expander.Option
+	for _, option := range expansionOptions {
+		if cloudprovider.NodeGroupCapacityType(option.NodeGroup) == cloudprovider.SpotCapacity {
+			spotOptions = append(spotOptions, option)
+		}
+	}
+
+	if len(spotOptions) == 0 {
+		return expansionOptions
+	}
+
+	return spotOptions
+}
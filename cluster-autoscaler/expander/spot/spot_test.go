@@ -0,0 +1,52 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+)
+
+func TestSpot(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	for _, id := range []string{"ng-a", "ng-b", "ng-c", "ng-d"} {
+		provider.AddNodeGroup(id, 0, 10, 0)
+	}
+	provider.GetNodeGroup("ng-a").(*testprovider.TestNodeGroup).SetCapacityType(cloudprovider.SpotCapacity)
+	provider.GetNodeGroup("ng-b").(*testprovider.TestNodeGroup).SetCapacityType(cloudprovider.SpotCapacity)
+	provider.GetNodeGroup("ng-c").(*testprovider.TestNodeGroup).SetCapacityType(cloudprovider.OnDemandCapacity)
+
+	e := NewFilter()
+
+	optionA := expander.Option{NodeGroup: provider.GetNodeGroup("ng-a"), NodeCount: 3, Debug: "a"}
+	optionB := expander.Option{NodeGroup: provider.GetNodeGroup("ng-b"), NodeCount: 2, Debug: "b"}
+	optionC := expander.Option{NodeGroup: provider.GetNodeGroup("ng-c"), NodeCount: 3, Debug: "c"}
+	optionD := expander.Option{NodeGroup: provider.GetNodeGroup("ng-d"), NodeCount: 3, Debug: "d"}
+
+	// ng-a and ng-b are backed by spot capacity, ng-c explicitly isn't and ng-d defaults to on-demand.
+	ret := e.BestOptions([]expander.Option{optionA, optionB, optionC, optionD}, nil)
+	assert.Equal(t, []expander.Option{optionA, optionB}, ret)
+
+	// Without spot capacity, all options are equally good.
+	ret = e.BestOptions([]expander.Option{optionC, optionD}, nil)
+	assert.Equal(t, []expander.Option{optionC, optionD}, ret)
+}
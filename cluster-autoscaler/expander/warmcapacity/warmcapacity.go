@@ -0,0 +1,75 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package warmcapacity
+
+import (
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	"k8s.io/klog/v2"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+type warmCapacity struct {
+}
+
+// NewFilter returns a scale up filter that picks the node groups able to absorb the scale-up
+// with pre-initialized instances, e.g. from an AWS warm pool, so that the new nodes get ready faster.
+func NewFilter() expander.Filter {
+	return &warmCapacity{}
+}
+
+// BestOptions selects the expansion options of node groups preferring their warm capacity that
+// get the most of their new nodes from it. Options are left unchanged if none of them has any.
+func (w *warmCapacity) BestOptions(expansionOptions []expander.Option, nodeInfo map[string]*schedulerframework.NodeInfo) []expander.Option {
+	var maxScore int
+	var maxOptions []expander.Option
+
+	for _, option := range expansionOptions {
+		score := optionScore(option)
+		if score == maxScore {
+			maxOptions = append(maxOptions, option)
+		}
+
+		if score > maxScore {
+			maxScore = score
+			maxOptions = []expander.Option{option}
+		}
+	}
+
+	if len(maxOptions) == 0 {
+		return nil
+	}
+
+	return maxOptions
+}
+
+// optionScore returns the number of the new nodes of the option served from warm capacity.
+func optionScore(option expander.Option) int {
+	group, ok := option.NodeGroup.(cloudprovider.WarmCapacityNodeGroup)
+	if !ok || !group.PreferWarmCapacity() {
+		return 0
+	}
+	warm, err := group.WarmCapacity()
+	if err != nil {
+		klog.Warningf("Failed to get warm capacity of node group %s: %v", option.NodeGroup.Id(), err)
+		return 0
+	}
+	if warm > option.NodeCount {
+		return option.NodeCount
+	}
+	return warm
+}
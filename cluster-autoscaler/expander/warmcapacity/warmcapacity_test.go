@@ -0,0 +1,78 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package warmcapacity
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+)
+
+type warmNodeGroup struct {
+	cloudprovider.NodeGroup
+	warm   int
+	err    error
+	prefer bool
+}
+
+func (ng *warmNodeGroup) WarmCapacity() (int, error) {
+	return ng.warm, ng.err
+}
+
+func (ng *warmNodeGroup) PreferWarmCapacity() bool {
+	return ng.prefer
+}
+
+func TestWarmCapacity(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	for _, id := range []string{"ng-a", "ng-b", "ng-c", "ng-d", "ng-e"} {
+		provider.AddNodeGroup(id, 0, 10, 0)
+	}
+
+	groupA := &warmNodeGroup{NodeGroup: provider.GetNodeGroup("ng-a"), warm: 2, prefer: true}
+	groupB := &warmNodeGroup{NodeGroup: provider.GetNodeGroup("ng-b"), warm: 5, prefer: true}
+	groupC := &warmNodeGroup{NodeGroup: provider.GetNodeGroup("ng-c"), warm: 5, prefer: false}
+	groupD := &warmNodeGroup{NodeGroup: provider.GetNodeGroup("ng-d"), err: fmt.Errorf("unavailable"), prefer: true}
+	groupE := provider.GetNodeGroup("ng-e")
+
+	e := NewFilter()
+
+	optionA := expander.Option{NodeGroup: groupA, NodeCount: 3, Debug: "a"}
+	optionB := expander.Option{NodeGroup: groupB, NodeCount: 3, Debug: "b"}
+	optionC := expander.Option{NodeGroup: groupC, NodeCount: 3, Debug: "c"}
+	optionD := expander.Option{NodeGroup: groupD, NodeCount: 3, Debug: "d"}
+	optionE := expander.Option{NodeGroup: groupE, NodeCount: 3, Debug: "e"}
+
+	// ng-b serves all 3 nodes from its warm pool, ng-a only 2, ng-c doesn't prefer its warm pool.
+	ret := e.BestOptions([]expander.Option{optionA, optionB, optionC, optionD, optionE}, nil)
+	assert.Equal(t, []expander.Option{optionB}, ret)
+
+	// Warm capacity beyond the node count doesn't make an option better.
+	optionA2 := expander.Option{NodeGroup: groupA, NodeCount: 2, Debug: "a2"}
+	optionB2 := expander.Option{NodeGroup: groupB, NodeCount: 2, Debug: "b2"}
+	ret = e.BestOptions([]expander.Option{optionA2, optionB2}, nil)
+	assert.Equal(t, []expander.Option{optionA2, optionB2}, ret)
+
+	// Without warm capacity, all options are equally good.
+	ret = e.BestOptions([]expander.Option{optionC, optionD, optionE}, nil)
+	assert.Equal(t, []expander.Option{optionC, optionD, optionE}, ret)
+}
@@ -35,14 +35,15 @@ type FakeNodeGroup struct {
 	id string
 }
 
-func (f *FakeNodeGroup) MaxSize() int                       { return 2 }
-func (f *FakeNodeGroup) MinSize() int                       { return 1 }
-func (f *FakeNodeGroup) TargetSize() (int, error)           { return 2, nil }
-func (f *FakeNodeGroup) IncreaseSize(delta int) error       { return nil }
-func (f *FakeNodeGroup) DecreaseTargetSize(delta int) error { return nil }
-func (f *FakeNodeGroup) DeleteNodes([]*apiv1.Node) error    { return nil }
-func (f *FakeNodeGroup) Id() string                         { return f.id }
-func (f *FakeNodeGroup) Debug() string                      { return f.id }
+func (f *FakeNodeGroup) MaxSize() int                         { return 2 }
+func (f *FakeNodeGroup) MinSize() int                         { return 1 }
+func (f *FakeNodeGroup) TargetSize() (int, error)             { return 2, nil }
+func (f *FakeNodeGroup) IncreaseSize(delta int) error         { return nil }
+func (f *FakeNodeGroup) DecreaseTargetSize(delta int) error   { return nil }
+func (f *FakeNodeGroup) DeleteNodes([]*apiv1.Node) error      { return nil }
+func (f *FakeNodeGroup) ForceDeleteNodes([]*apiv1.Node) error { return nil }
+func (f *FakeNodeGroup) Id() string                           { return f.id }
+func (f *FakeNodeGroup) Debug() string                        { return f.id }
 func (f *FakeNodeGroup) Nodes() ([]cloudprovider.Instance, error) {
 	return []cloudprovider.Instance{}, nil
 }
@@ -123,4 +124,10 @@ func TestLeastWaste(t *testing.T) {
 	lowcpuOption := expander.Option{NodeGroup: &FakeNodeGroup{"lowcpu"}, NodeCount: 1, Pods: []*apiv1.Pod{pod}}
 	ret = e.BestOptions([]expander.Option{balancedOption, highmemOption, lowcpuOption}, nodeMap)
 	assert.Equal(t, ret, []expander.Option{lowcpuOption})
+
+	// Test scores, the less waste the higher
+	scores := e.(expander.Scorer).Scores([]expander.Option{balancedOption, highmemOption, lowcpuOption}, nodeMap)
+	assert.Equal(t, 3, len(scores))
+	assert.Greater(t, scores["lowcpu"], scores["balanced"])
+	assert.Greater(t, scores["balanced"], scores["highmem"])
 }
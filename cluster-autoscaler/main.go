@@ -31,7 +31,9 @@ import (
 
 	"github.com/spf13/pflag"
 
+	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apiserver/pkg/server/mux"
 	"k8s.io/apiserver/pkg/server/routes"
@@ -40,22 +42,35 @@ import (
 	cloudBuilder "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/builder"
 	"k8s.io/autoscaler/cluster-autoscaler/config"
 	"k8s.io/autoscaler/cluster-autoscaler/core"
+	"k8s.io/autoscaler/cluster-autoscaler/core/orphans"
 	"k8s.io/autoscaler/cluster-autoscaler/core/podlistprocessor"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/topology"
+	"k8s.io/autoscaler/cluster-autoscaler/core/surge"
 	"k8s.io/autoscaler/cluster-autoscaler/debuggingsnapshot"
 	"k8s.io/autoscaler/cluster-autoscaler/estimator"
 	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	"k8s.io/autoscaler/cluster-autoscaler/expander/broker"
 	"k8s.io/autoscaler/cluster-autoscaler/metrics"
 	ca_processors "k8s.io/autoscaler/cluster-autoscaler/processors"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/actionablecluster"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/alerts"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupset"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/nodeinfosprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/pods"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/scaledowncandidates"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/scaledowncandidates/emptycandidates"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/scaledowncandidates/previouscandidates"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/scaledowncandidates/recreationcost"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/nakedpod"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/options"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/predicatechecker"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/gpu"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/imageplatform"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
 	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
 	scheduler_util "k8s.io/autoscaler/cluster-autoscaler/utils/scheduler"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/units"
@@ -175,7 +190,7 @@ var (
 	estimatorFlag = flag.String("estimator", estimator.BinpackingEstimatorName,
 		"Type of resource estimator to be used in scale up. Available values: ["+strings.Join(estimator.AvailableEstimators, ",")+"]")
 
-	expanderFlag = flag.String("expander", expander.RandomExpanderName, "Type of node group expander to be used in scale up. Available values: ["+strings.Join(expander.AvailableExpanders, ",")+"]. Specifying multiple values separated by commas will call the expanders in succession until there is only one option remaining. Ties still existing after this process are broken randomly.")
+	expanderFlag = flag.String("expander", expander.RandomExpanderName, "Type of node group expander to be used in scale up. Available values: ["+strings.Join(expander.AvailableExpanders, ",")+"]. Specifying multiple values separated by commas will call the expanders in succession until there is only one option remaining. Ties still existing after this process are broken randomly. Expanders given weights in the <name>:<weight> format, e.g. price:0.7,least-waste:0.3, are instead combined by selecting the option with the highest weighted sum of their normalized scores.")
 
 	grpcExpanderCert = flag.String("grpc-expander-cert", "", "Path to cert used by gRPC server over TLS")
 	grpcExpanderURL  = flag.String("grpc-expander-url", "", "URL to reach gRPC expander server.")
@@ -184,6 +199,8 @@ var (
 		"Should CA ignore DaemonSet pods when calculating resource utilization for scaling down")
 	ignoreMirrorPodsUtilization = flag.Bool("ignore-mirror-pods-utilization", false,
 		"Should CA ignore Mirror pods when calculating resource utilization for scaling down")
+	recreationCost = flag.Float64("recreation-cost", 1.0,
+		"Relative cost of recreating a node, used unless overridden per node group. All else being equal, nodes of node groups with a lower cost are scaled down first")
 
 	writeStatusConfigMapFlag         = flag.Bool("write-status-configmap", true, "Should CA write status information to a configmap")
 	statusConfigMapName              = flag.String("status-config-map-name", "cluster-autoscaler-status", "Status configmap name")
@@ -197,6 +214,16 @@ var (
 	expendablePodsPriorityCutoff  = flag.Int("expendable-pods-priority-cutoff", -10, "Pods with priority below cutoff will be expendable. They can be killed without any consideration during scale down and they don't cause scale up. Pods with null priority (PodPriority disabled) are non expendable.")
 	regional                      = flag.Bool("regional", false, "Cluster is regional.")
 	newPodScaleUpDelay            = flag.Duration("new-pod-scale-up-delay", 0*time.Second, "Pods less than this old will not be considered for scale-up. Can be increased for individual pods through annotation 'cluster-autoscaler.kubernetes.io/pod-scale-up-delay'.")
+	newPodScaleUpDelayPerPriority = multiStringFlag("new-pod-scale-up-delay-per-priority-class", "Overrides --new-pod-scale-up-delay for pods of a priority class, in the format <priority_class>:<delay>. Can be passed multiple times.")
+	adaptiveNewPodScaleUpDelay    = flag.Bool("adaptive-new-pod-scale-up-delay", false, "Shorten the new pod scale-up delays when the number of unschedulable pods grows between loops, in proportion to the growth. Delays set through pod annotations are not shortened.")
+
+	annotateUnremovableNodes          = flag.Bool("annotate-unremovable-nodes", false, "Annotate nodes that can't be scaled down with the reason why, in the 'cluster-autoscaler.kubernetes.io/unremovable-reason' annotation")
+	unremovableNodeAnnotationInterval = flag.Duration("unremovable-node-annotation-interval", 5*time.Minute, "Minimum time between two updates of the unremovable reason annotation of a node")
+
+	maxGracefulTerminationPerNamespace     = multiStringFlag("max-graceful-termination-sec-per-namespace", "Overrides --max-graceful-termination-sec for pods of a namespace, in the format <namespace>:<seconds>. Can be passed multiple times.")
+	maxGracefulTerminationPerPriorityClass = multiStringFlag("max-graceful-termination-sec-per-priority-class", "Overrides --max-graceful-termination-sec for pods of a priority class, in the format <priority_class>:<seconds>. Namespace overrides take precedence. Can be passed multiple times.")
+
+	nakedPodPolicies = multiStringFlag("naked-pod-policy", "Policy applied by scale-down to the pods without a controller of a namespace, in the format <namespace>=<policy>, where policy is block, evict or ignore. Namespace * sets the policy of the other namespaces, which defaults to block. Can be passed multiple times.")
 
 	ignoreTaintsFlag          = multiStringFlag("ignore-taint", "Specifies a taint to ignore in node templates when considering to scale a node group (Deprecated, use startup-taints instead)")
 	startupTaintsFlag         = multiStringFlag("startup-taint", "Specifies a taint to ignore in node templates when considering to scale a node group (Equivalent to ignore-taint)")
@@ -204,6 +231,10 @@ var (
 	balancingIgnoreLabelsFlag = multiStringFlag("balancing-ignore-label", "Specifies a label to ignore in addition to the basic and cloud-provider set of labels when comparing if two node groups are similar")
 	balancingLabelsFlag       = multiStringFlag("balancing-label", "Specifies a label to use for comparing if two node groups are similar, rather than the built in heuristics. Setting this flag disables all other comparison logic, and cannot be combined with --balancing-ignore-label.")
 	awsUseStaticInstanceList  = flag.Bool("aws-use-static-instance-list", false, "Should CA fetch instance types in runtime or use a static list. AWS only")
+	awsPriceListPricing       = flag.Bool("aws-price-list-pricing", false, "Should CA price nodes from the on-demand prices of the Price List API, for the price expander. AWS only")
+	awsCommitmentAwarePricing = flag.Bool("aws-commitment-aware-pricing", false, "Should the price expander discount the prices of nodes by the unused commitment of Savings Plans and Reserved Instances, as reported by Cost Explorer. Requires --aws-price-list-pricing. AWS only")
+	awsEKSNodegroupAPI        = flag.Bool("aws-eks-nodegroup-api", false, "Should CA set the desired size of EKS managed node groups through the EKS UpdateNodegroupConfig API rather than on their ASG, keeping the node group configuration in sync. AWS only")
+	awsEventsQueueURL         = flag.String("aws-events-queue-url", "", "URL of an SQS queue receiving ASG lifecycle and EC2 instance state change events from EventBridge. If set, ASGs are refreshed when events affect them rather than every minute. AWS only")
 
 	// GCE specific flags
 	concurrentGceRefreshes             = flag.Int("gce-concurrent-refreshes", 1, "Maximum number of concurrent refreshes per cloud object type.")
@@ -213,6 +244,7 @@ var (
 	enableProfiling                    = flag.Bool("profiling", false, "Is debug/pprof endpoint enabled")
 	clusterAPICloudConfigAuthoritative = flag.Bool("clusterapi-cloud-config-authoritative", false, "Treat the cloud-config flag authoritatively (do not fallback to using kubeconfig flag). ClusterAPI only")
 	cordonNodeBeforeTerminate          = flag.Bool("cordon-node-before-terminating", false, "Should CA cordon nodes before terminating during downscale process")
+	toBeDeletedTaintOwner              = flag.String("to-be-deleted-taint-owner", "cluster-autoscaler", "Identity recorded on ToBeDeleted taints placed by this instance. On startup only stale taints with a matching or missing owner are removed. Instances managing disjoint node groups in one cluster should use different values. Empty value disables ownership tracking.")
 	daemonSetEvictionForEmptyNodes     = flag.Bool("daemonset-eviction-for-empty-nodes", false, "DaemonSet pods will be gracefully terminated from empty nodes")
 	daemonSetEvictionForOccupiedNodes  = flag.Bool("daemonset-eviction-for-occupied-nodes", true, "DaemonSet pods will be gracefully terminated from non-empty nodes")
 	userAgent                          = flag.String("user-agent", "cluster-autoscaler", "User agent used for HTTP calls.")
@@ -243,6 +275,46 @@ var (
 	maxAllocatableDifferenceRatio           = flag.Float64("max-allocatable-difference-ratio", config.DefaultMaxAllocatableDifferenceRatio, "Maximum difference in allocatable resources between two similar node groups to be considered for balancing. Value is a ratio of the smaller node group's allocatable resource.")
 	forceDaemonSets                         = flag.Bool("force-ds", false, "Blocks scale-up of node groups too small for all suitable Daemon Sets pods.")
 	dynamicNodeDeleteDelayAfterTaintEnabled = flag.Bool("dynamic-node-delete-delay-after-taint-enabled", false, "Enables dynamic adjustment of NodeDeleteDelayAfterTaint based of the latency between CA and api-server")
+	nodeProblemConditionsFlag               = multiStringFlag("node-problem-condition", "Specifies a node condition type (e.g. KernelDeadlock reported by Node Problem Detector) which, when true, makes the node a preferred scale-down candidate regardless of its utilization. Can be used multiple times.")
+	maxNodeProblemRecyclesPerHour           = flag.Int("max-node-problem-recycles-per-hour", 5, "Maximum number of nodes with problem conditions that can be removed per hour.")
+	imageArchitectureInspectionEnabled      = flag.Bool("image-architecture-inspection-enabled", false, "Whether CA should inspect image manifests of unschedulable pods without kubernetes.io/arch constraints and only scale up node groups of architectures supported by all of their images. Only images accessible anonymously can be inspected.")
+	requestlessPodDefaultsEnabled           = flag.Bool("requestless-pod-defaults-enabled", false, "Whether containers of unschedulable pods without CPU or memory requests should be given the default requests of the LimitRanges of their namespace when simulating scale-up, so that the number of nodes added reflects their actual usage.")
+	requestlessPodFallbackCPU               = flag.String("requestless-pod-fallback-cpu", "", "CPU request given to containers without one in namespaces without LimitRange defaults, if requestless-pod-defaults-enabled is set. Empty leaves them without request.")
+	requestlessPodFallbackMemory            = flag.String("requestless-pod-fallback-memory", "", "Memory request given to containers without one in namespaces without LimitRange defaults, if requestless-pod-defaults-enabled is set. Empty leaves them without request.")
+	imageArchitectureCacheTTL               = flag.Duration("image-architecture-cache-ttl", time.Hour, "How long the architectures resolved from image manifests are cached.")
+	subsystemLogLevels                      = flag.String("subsystem-log-levels", "", "Comma-separated list of subsystem=level log verbosity overrides, e.g. core=5,provider/azure=1. Supported subsystems: "+klogx.FormatSubsystems()+". Subsystems without an override use the global verbosity. Can be changed at runtime with a PUT request to the /loglevels endpoint if it is enabled.")
+	logLevelsEndpointEnabled                = flag.Bool("log-levels-endpoint-enabled", false, "Whether the /loglevels endpoint, which lets anyone with access to the metrics address change subsystem log levels at runtime, is served.")
+	orphanedNodeGroupPolicy                 = flag.String("orphaned-node-group-policy", string(orphans.AlertPolicy), "How to handle nodes of node groups that are no longer returned by the cloud provider (e.g. stopped matching auto-discovery): alert (report only), adopt (keep read-only) or drain (cordon and remove nodes once empty).")
+	scaleUpHintsConfigMapName               = flag.String("scale-up-hints-config-map-name", "", "Name of the configmap in which in-flight scale-ups are persisted, so that a restarted autoscaler accounts for upcoming nodes instead of scaling up again. Empty disables persisting scale-up hints.")
+	surgeCapacityEnabled                    = flag.Bool("surge-capacity-enabled", false, "Should CA grant surge capacity requests: configmaps in its namespace annotated with "+surge.NodeGroupAnnotation+" and "+surge.NodesAnnotation+", for which nodes are added above the target size of the node group until the request is deleted or expires.")
+	maxSurgeCapacityDuration                = flag.Duration("max-surge-capacity-duration", 6*time.Hour, "Maximum time a surge capacity request lasts before its surge nodes are reclaimed.")
+	alertPodPendingOnQuotaThreshold         = flag.Duration("alert-pod-pending-on-quota-threshold", 0, "How long a pod has to be unable to trigger a scale-up because node group or cluster-wide limits were reached before a Warning event is emitted for it. 0 disables the alert.")
+	alertNodeGroupBackoffThreshold          = flag.Duration("alert-node-group-backoff-threshold", 0, "How long a node group has to stay in backoff after failed scale-ups before a Warning event is emitted for it. 0 disables the alert.")
+	alertScaleDownBlockedThreshold          = flag.Duration("alert-scale-down-blocked-threshold", 0, "How long the scale-down of a node has to be blocked by the same reason (e.g. a pod that can't be moved) before a Warning event is emitted for it. 0 disables the alert.")
+	alertWebhookURL                         = flag.String("alert-webhook-url", "", "URL to which the alerts raised in each iteration are additionally sent as a JSON array in a POST request. Empty disables the webhook.")
+	maxCloudProviderRefreshAgeFlag          = flag.Duration("max-cloud-provider-refresh-age", 15*time.Minute, "Maximum time from last successful cloud provider refresh before autoscaler is reported as not ready")
+	gpuUtilizationPrometheusURL             = flag.String("gpu-utilization-prometheus-url", "", "URL of the Prometheus server to query the GPU utilization of nodes from (e.g. DCGM exporter metrics), used instead of requested GPUs to decide GPU node scale-down. Empty disables it.")
+	gpuUtilizationQuery                     = flag.String("gpu-utilization-query", gpu.DefaultUtilizationQuery, "Prometheus query returning the GPU utilization of nodes, between 0 and 1")
+	gpuUtilizationNodeLabel                 = flag.String("gpu-utilization-node-label", gpu.DefaultUtilizationNodeLabel, "Label identifying the node in the results of the GPU utilization query")
+	paused                                  = flag.Bool("paused", false, "Start with autoscaling paused, e.g. during control-plane maintenance: the cluster is observed, but no change is made to it. Autoscaling can be resumed through the /pause endpoint if it is enabled, or by restarting without the flag.")
+	pauseEndpointEnabled                    = flag.Bool("pause-endpoint-enabled", false, "Whether the /pause endpoint, which lets anyone with access to the metrics address pause and resume autoscaling, is served.")
+	cloudConfigSecret                       = flag.String("cloud-config-secret", "", "Namespace/name of a Secret holding the cloud provider configuration in its cloud-config key, used instead of --cloud-config and watched for changes, e.g. credential rotations. Only supported by the Azure cloud provider.")
+	clusterSnapshotType                     = flag.String("cluster-snapshot-type", clustersnapshot.DeltaClusterSnapshotType, "Implementation of the cluster snapshot used for scheduling simulations. One of: basic, delta, compact. Compact reduces memory usage and GC pressure in very large clusters.")
+	terminatingPodThreshold                 = flag.Duration("terminating-pod-threshold", 0, "Time after their deletion timestamp after which terminating pods stop occupying capacity in scale-up simulations and stop counting toward node utilization in scale-down. Disabled with 0: terminating pods then keep occupying capacity in simulations and stop counting toward utilization once their termination grace period is over.")
+	nodeGroupTargetSizeRefreshInterval      = flag.Duration("node-group-target-size-refresh-interval", 0, "How often the target size of each node group is forcefully refreshed from the cloud provider and verified, to detect resizes made outside of the autoscaler. Node groups are spread over the interval. 0 to disable.")
+	capacityBrokerURL                       = flag.String("capacity-broker-url", "", "URL of the external capacity broker consulted by the capacity-broker expander to choose between on-prem and cloud node groups. Empty to always apply --capacity-broker-fallback.")
+	capacityBrokerTimeout                   = flag.Duration("capacity-broker-timeout", 5*time.Second, "Timeout of capacity broker calls, after which --capacity-broker-fallback is applied")
+	capacityBrokerOnPremNodeGroups          = flag.String("capacity-broker-on-prem-node-groups", "", "Regular expression matching the ids of on-prem node groups for the capacity-broker expander. Other node groups are cloud node groups.")
+	capacityBrokerFallback                  = flag.String("capacity-broker-fallback", broker.FallbackOnPremFirst, "Policy applied by the capacity-broker expander when the broker can't be consulted or accepts none of the options. One of: on-prem-first, cloud-first, all.")
+	capacityBrokerCacheTTL                  = flag.Duration("capacity-broker-cache-ttl", time.Minute, "How long a capacity broker response is reused for the same node groups and node counts instead of consulting the broker again")
+	externalDeleteWebhookURL                = flag.String("external-delete-webhook-url", "", "URL of the webhook called to delete drained nodes that don't belong to any cloud provider node group and match --external-delete-node-selector, making them scale-down candidates. The webhook must remove the machine and its Node object. Empty to never scale such nodes down.")
+	externalDeleteNodeSelector              = flag.String("external-delete-node-selector", "", "Label selector of the nodes without a cloud provider node group that are scaled down through --external-delete-webhook-url. Empty to never scale such nodes down.")
+	externalDeleteWebhookTimeout            = flag.Duration("external-delete-webhook-timeout", 10*time.Second, "Timeout of external delete webhook calls")
+	scaleDownMinReadyNodesPerDomain         = multiStringFlag("scale-down-min-ready-nodes-per-domain", "Minimum number of Ready nodes scale-down must leave in each domain of a label key, in the format <label key>=<count>, e.g. topology.kubernetes.io/zone=2. Can be passed multiple times.")
+	scaleDownBillingAware                   = flag.Bool("scale-down-billing-aware", false, "Should CA delay the deletion of unneeded nodes of node groups billed per started period, e.g. per hour, until the end of their current billing period. Nodes annotated with cluster-autoscaler.kubernetes.io/scale-down-urgent=true are deleted as soon as they are unneeded.")
+	scaleDownBillingWindow                  = flag.Duration("scale-down-billing-window", 10*time.Minute, "How long before the end of their billing period nodes may be deleted when --scale-down-billing-aware is set. Should cover the time it takes to drain and delete a node.")
+	emitPerNodeMetrics                      = flag.Bool("emit-per-node-metrics", false, "If true, emit the node_info metric with the state and node group of each node. Its cardinality grows with the number of nodes.")
+	shadowExpanderFlag                      = flag.String("shadow-expander", "", "Type of node group expander whose scale-up decisions are computed, logged and counted in the shadow_expander_decisions_total metric alongside the --expander ones, without being acted upon. Accepts the same values as --expander. Empty to disable.")
 )
 
 func isFlagPassed(name string) bool {
@@ -272,6 +344,29 @@ func createAutoscalingOptions() config.AutoscalingOptions {
 	if err != nil {
 		klog.Fatalf("Failed to parse flags: %v", err)
 	}
+	parsedNewPodScaleUpDelayPerPriority, err := parseNewPodScaleUpDelayPerPriorityClass(*newPodScaleUpDelayPerPriority)
+	if err != nil {
+		klog.Fatalf("Failed to parse flags: %v", err)
+	}
+	if _, err := orphans.ParsePolicy(*orphanedNodeGroupPolicy); err != nil {
+		klog.Fatalf("Failed to parse flags: %v", err)
+	}
+	parsedScaleDownMinReadyNodesPerDomain, err := topology.ParseMinReadyNodes(*scaleDownMinReadyNodesPerDomain)
+	if err != nil {
+		klog.Fatalf("Failed to parse flags: %v", err)
+	}
+	parsedNakedPodPolicies, err := nakedpod.ParsePolicies(*nakedPodPolicies)
+	if err != nil {
+		klog.Fatalf("Failed to parse flags: %v", err)
+	}
+	parsedMaxGracefulTerminationPerNamespace, err := parseMaxGracefulTerminationSecOverrides(*maxGracefulTerminationPerNamespace)
+	if err != nil {
+		klog.Fatalf("Failed to parse flags: %v", err)
+	}
+	parsedMaxGracefulTerminationPerPriorityClass, err := parseMaxGracefulTerminationSecOverrides(*maxGracefulTerminationPerPriorityClass)
+	if err != nil {
+		klog.Fatalf("Failed to parse flags: %v", err)
+	}
 	if *maxDrainParallelismFlag > 1 && !*parallelDrain {
 		klog.Fatalf("Invalid configuration, could not use --max-drain-parallelism > 1 if --parallel-drain is false")
 	}
@@ -303,6 +398,7 @@ func createAutoscalingOptions() config.AutoscalingOptions {
 			ScaleDownUnreadyTime:             *scaleDownUnreadyTime,
 			IgnoreDaemonSetsUtilization:      *ignoreDaemonSetsUtilization,
 			MaxNodeProvisionTime:             *maxNodeProvisionTime,
+			RecreationCost:                   *recreationCost,
 		},
 		CloudConfig:                      *cloudConfig,
 		CloudProviderName:                *cloudProviderFlag,
@@ -358,6 +454,10 @@ func createAutoscalingOptions() config.AutoscalingOptions {
 		KubeClientQPS:                    *kubeClientQPS,
 		NodeDeletionDelayTimeout:         *nodeDeletionDelayTimeout,
 		AWSUseStaticInstanceList:         *awsUseStaticInstanceList,
+		AWSEventsQueueURL:                *awsEventsQueueURL,
+		AWSPriceListPricing:              *awsPriceListPricing,
+		AWSCommitmentAwarePricing:        *awsCommitmentAwarePricing,
+		AWSEKSNodegroupAPI:               *awsEKSNodegroupAPI,
 		GCEOptions: config.GCEOptions{
 			ConcurrentRefreshes:             *concurrentGceRefreshes,
 			MigInstancesMinRefreshWaitTime:  *gceMigInstancesMinRefreshWaitTime,
@@ -365,6 +465,7 @@ func createAutoscalingOptions() config.AutoscalingOptions {
 		},
 		ClusterAPICloudConfigAuthoritative: *clusterAPICloudConfigAuthoritative,
 		CordonNodeBeforeTerminate:          *cordonNodeBeforeTerminate,
+		ToBeDeletedTaintOwner:              *toBeDeletedTaintOwner,
 		DaemonSetEvictionForEmptyNodes:     *daemonSetEvictionForEmptyNodes,
 		DaemonSetEvictionForOccupiedNodes:  *daemonSetEvictionForOccupiedNodes,
 		UserAgent:                          *userAgent,
@@ -389,7 +490,49 @@ func createAutoscalingOptions() config.AutoscalingOptions {
 			MaxAllocatableDifferenceRatio:    *maxAllocatableDifferenceRatio,
 			MaxFreeDifferenceRatio:           *maxFreeDifferenceRatio,
 		},
-		DynamicNodeDeleteDelayAfterTaintEnabled: *dynamicNodeDeleteDelayAfterTaintEnabled,
+		DynamicNodeDeleteDelayAfterTaintEnabled:   *dynamicNodeDeleteDelayAfterTaintEnabled,
+		NodeProblemConditions:                     *nodeProblemConditionsFlag,
+		MaxNodeProblemRecyclesPerHour:             *maxNodeProblemRecyclesPerHour,
+		ImageArchitectureInspectionEnabled:        *imageArchitectureInspectionEnabled,
+		ImageArchitectureCacheTTL:                 *imageArchitectureCacheTTL,
+		RequestlessPodDefaultsEnabled:             *requestlessPodDefaultsEnabled,
+		RequestlessPodFallbackCPU:                 *requestlessPodFallbackCPU,
+		RequestlessPodFallbackMemory:              *requestlessPodFallbackMemory,
+		OrphanedNodeGroupPolicy:                   *orphanedNodeGroupPolicy,
+		ScaleUpHintsConfigMapName:                 *scaleUpHintsConfigMapName,
+		SurgeCapacityEnabled:                      *surgeCapacityEnabled,
+		MaxSurgeCapacityDuration:                  *maxSurgeCapacityDuration,
+		AlertPodPendingOnQuotaThreshold:           *alertPodPendingOnQuotaThreshold,
+		AlertNodeGroupBackoffThreshold:            *alertNodeGroupBackoffThreshold,
+		AlertScaleDownBlockedThreshold:            *alertScaleDownBlockedThreshold,
+		AlertWebhookURL:                           *alertWebhookURL,
+		GpuUtilizationPrometheusURL:               *gpuUtilizationPrometheusURL,
+		GpuUtilizationQuery:                       *gpuUtilizationQuery,
+		GpuUtilizationNodeLabel:                   *gpuUtilizationNodeLabel,
+		CloudConfigSecret:                         *cloudConfigSecret,
+		ClusterSnapshotType:                       *clusterSnapshotType,
+		TerminatingPodThreshold:                   *terminatingPodThreshold,
+		CapacityBrokerURL:                         *capacityBrokerURL,
+		CapacityBrokerTimeout:                     *capacityBrokerTimeout,
+		CapacityBrokerOnPremNodeGroups:            *capacityBrokerOnPremNodeGroups,
+		CapacityBrokerFallback:                    *capacityBrokerFallback,
+		CapacityBrokerCacheTTL:                    *capacityBrokerCacheTTL,
+		ExternalDeleteWebhookURL:                  *externalDeleteWebhookURL,
+		ExternalDeleteNodeSelector:                *externalDeleteNodeSelector,
+		ExternalDeleteWebhookTimeout:              *externalDeleteWebhookTimeout,
+		ScaleDownMinReadyNodesPerDomain:           parsedScaleDownMinReadyNodesPerDomain,
+		ScaleDownBillingAware:                     *scaleDownBillingAware,
+		ScaleDownBillingWindow:                    *scaleDownBillingWindow,
+		ShadowExpanderNames:                       *shadowExpanderFlag,
+		NewPodScaleUpDelayPerPriorityClass:        parsedNewPodScaleUpDelayPerPriority,
+		AdaptiveNewPodScaleUpDelay:                *adaptiveNewPodScaleUpDelay,
+		AnnotateUnremovableNodes:                  *annotateUnremovableNodes,
+		UnremovableNodeAnnotationInterval:         *unremovableNodeAnnotationInterval,
+		NakedPodPolicies:                          parsedNakedPodPolicies,
+		MaxGracefulTerminationSecPerNamespace:     parsedMaxGracefulTerminationPerNamespace,
+		MaxGracefulTerminationSecPerPriorityClass: parsedMaxGracefulTerminationPerPriorityClass,
+		NodeGroupTargetSizeRefreshInterval:        *nodeGroupTargetSizeRefreshInterval,
+		EmitPerNodeGroupMetrics:                   *emitPerNodeGroupMetrics,
 	}
 }
 
@@ -437,7 +580,7 @@ func registerSignalHandlers(autoscaler core.Autoscaler) {
 	}()
 }
 
-func buildAutoscaler(debuggingSnapshotter debuggingsnapshot.DebuggingSnapshotter) (core.Autoscaler, error) {
+func buildAutoscaler(debuggingSnapshotter debuggingsnapshot.DebuggingSnapshotter, readinessCheck *metrics.ReadinessCheck, pauseSwitch *actionablecluster.PauseSwitch) (core.Autoscaler, error) {
 	// Create basic config from flags.
 	autoscalingOptions := createAutoscalingOptions()
 
@@ -462,21 +605,44 @@ func buildAutoscaler(debuggingSnapshotter debuggingsnapshot.DebuggingSnapshotter
 		return nil, err
 	}
 	deleteOptions := options.NewNodeDeleteOptions(autoscalingOptions)
+	clusterSnapshot, err := clustersnapshot.NewClusterSnapshot(autoscalingOptions.ClusterSnapshotType)
+	if err != nil {
+		return nil, err
+	}
 
 	opts := core.AutoscalerOptions{
 		AutoscalingOptions:   autoscalingOptions,
-		ClusterSnapshot:      clustersnapshot.NewDeltaClusterSnapshot(),
+		ClusterSnapshot:      clusterSnapshot,
 		KubeClient:           kubeClient,
 		InformerFactory:      informerFactory,
 		EventsKubeClient:     eventsKubeClient,
 		DebuggingSnapshotter: debuggingSnapshotter,
 		PredicateChecker:     predicateChecker,
 		DeleteOptions:        deleteOptions,
+		ReadinessCheck:       readinessCheck,
 	}
 
 	opts.Processors = ca_processors.DefaultProcessors(autoscalingOptions)
 	opts.Processors.TemplateNodeInfoProvider = nodeinfosprovider.NewDefaultTemplateNodeInfoProvider(nodeInfoCacheExpireTime, *forceDaemonSets)
 	opts.Processors.PodListProcessor = podlistprocessor.NewDefaultPodListProcessor(opts.PredicateChecker)
+	if autoscalingOptions.RequestlessPodDefaultsEnabled {
+		fallback, err := parseFallbackRequests(autoscalingOptions.RequestlessPodFallbackCPU, autoscalingOptions.RequestlessPodFallbackMemory)
+		if err != nil {
+			return nil, err
+		}
+		// Defaults are applied first, so that pods are filtered out as schedulable with the same requests.
+		opts.Processors.PodListProcessor = pods.NewCombinedPodListProcessor([]pods.PodListProcessor{
+			podlistprocessor.NewDefaultRequestsPodListProcessor(informerFactory.Core().V1().LimitRanges().Lister(), fallback),
+			opts.Processors.PodListProcessor,
+		})
+	}
+	if autoscalingOptions.ImageArchitectureInspectionEnabled {
+		resolver := imageplatform.NewCachingResolver(imageplatform.NewRegistryResolver(imageplatform.DefaultRegistryTimeout), autoscalingOptions.ImageArchitectureCacheTTL)
+		opts.Processors.PodListProcessor = pods.NewCombinedPodListProcessor([]pods.PodListProcessor{
+			opts.Processors.PodListProcessor,
+			podlistprocessor.NewImageArchitecturePodListProcessor(resolver),
+		})
+	}
 	scaleDownCandidatesComparers := []scaledowncandidates.CandidatesComparer{}
 	if autoscalingOptions.ParallelDrain {
 		sdCandidatesSorting := previouscandidates.NewPreviousCandidates()
@@ -486,6 +652,8 @@ func buildAutoscaler(debuggingSnapshotter debuggingsnapshot.DebuggingSnapshotter
 		}
 		opts.Processors.ScaleDownCandidatesNotifier.Register(sdCandidatesSorting)
 	}
+	// All else being equal, nodes cheaper to recreate are scaled down first.
+	scaleDownCandidatesComparers = append(scaleDownCandidatesComparers, recreationcost.NewRecreationCostSorting(opts.Processors.NodeGroupConfigProcessor))
 	sdProcessor := scaledowncandidates.NewScaleDownCandidatesSortingProcessor(scaleDownCandidatesComparers)
 	opts.Processors.ScaleDownNodeProcessor = sdProcessor
 
@@ -509,6 +677,20 @@ func buildAutoscaler(debuggingSnapshotter debuggingsnapshot.DebuggingSnapshotter
 	opts.Processors.NodeGroupSetProcessor = &nodegroupset.BalancingNodeGroupSetProcessor{
 		Comparator: nodeInfoComparator,
 	}
+	opts.Processors.ActionableClusterProcessor = actionablecluster.NewPausingProcessor(pauseSwitch, opts.Processors.ActionableClusterProcessor)
+
+	alertsConfig := alerts.Config{
+		PodPendingOnQuotaThreshold: autoscalingOptions.AlertPodPendingOnQuotaThreshold,
+		NodeGroupBackoffThreshold:  autoscalingOptions.AlertNodeGroupBackoffThreshold,
+		ScaleDownBlockedThreshold:  autoscalingOptions.AlertScaleDownBlockedThreshold,
+		WebhookURL:                 autoscalingOptions.AlertWebhookURL,
+	}
+	if autoscalingOptions.AnnotateUnremovableNodes {
+		opts.Processors.ScaleDownStatusProcessor = status.NewUnremovableNodeAnnotator(opts.Processors.ScaleDownStatusProcessor, autoscalingOptions.UnremovableNodeAnnotationInterval)
+	}
+	if alertsConfig.Enabled() {
+		alerts.NewAlerter(alertsConfig).Register(opts.Processors)
+	}
 
 	// These metrics should be published only once.
 	metrics.UpdateNapEnabled(autoscalingOptions.NodeAutoprovisioningEnabled)
@@ -526,13 +708,21 @@ func buildAutoscaler(debuggingSnapshotter debuggingsnapshot.DebuggingSnapshotter
 	stop := make(chan struct{})
 	informerFactory.Start(stop)
 
+	// Don't act on the cluster before the informer caches are populated.
+	for informerType, synced := range informerFactory.WaitForCacheSync(stop) {
+		if !synced {
+			return nil, fmt.Errorf("failed to sync informer cache for %v", informerType)
+		}
+	}
+	readinessCheck.MarkInformersSynced()
+
 	return autoscaler, nil
 }
 
-func run(healthCheck *metrics.HealthCheck, debuggingSnapshotter debuggingsnapshot.DebuggingSnapshotter) {
-	metrics.RegisterAll(*emitPerNodeGroupMetrics)
+func run(healthCheck *metrics.HealthCheck, readinessCheck *metrics.ReadinessCheck, pauseSwitch *actionablecluster.PauseSwitch, debuggingSnapshotter debuggingsnapshot.DebuggingSnapshotter) {
+	metrics.RegisterAll(*emitPerNodeGroupMetrics, *emitPerNodeMetrics)
 
-	autoscaler, err := buildAutoscaler(debuggingSnapshotter)
+	autoscaler, err := buildAutoscaler(debuggingSnapshotter, readinessCheck, pauseSwitch)
 	if err != nil {
 		klog.Fatalf("Failed to create autoscaler: %v", err)
 	}
@@ -593,7 +783,15 @@ func main() {
 		klog.Fatalf("Failed to validate and apply logging configuration: %v", err)
 	}
 
+	levels, err := klogx.ParseSubsystemLevels(*subsystemLogLevels)
+	if err != nil {
+		klog.Fatalf("Failed to parse flags: %v", err)
+	}
+	klogx.SetSubsystemLevels(levels)
+
 	healthCheck := metrics.NewHealthCheck(*maxInactivityTimeFlag, *maxFailingTimeFlag)
+	readinessCheck := metrics.NewReadinessCheck(*maxCloudProviderRefreshAgeFlag)
+	pauseSwitch := actionablecluster.NewPauseSwitch(*paused)
 
 	klog.V(1).Infof("Cluster Autoscaler %s", version.ClusterAutoscalerVersion)
 
@@ -609,6 +807,14 @@ func main() {
 			pathRecorderMux.HandleFunc("/snapshotz", debuggingSnapshotter.ResponseHandler)
 		}
 		pathRecorderMux.HandleFunc("/health-check", healthCheck.ServeHTTP)
+		pathRecorderMux.HandleFunc("/health-check/liveness", healthCheck.ServeHTTP)
+		pathRecorderMux.HandleFunc("/health-check/readiness", readinessCheck.ServeHTTP)
+		if *logLevelsEndpointEnabled {
+			pathRecorderMux.HandleFunc("/loglevels", klogx.SubsystemLevelsHandler)
+		}
+		if *pauseEndpointEnabled {
+			pathRecorderMux.Handle("/pause", pauseSwitch)
+		}
 		if *enableProfiling {
 			routes.Profiling{}.Install(pathRecorderMux)
 		}
@@ -617,7 +823,7 @@ func main() {
 	}()
 
 	if !leaderElection.LeaderElect {
-		run(healthCheck, debuggingSnapshotter)
+		run(healthCheck, readinessCheck, pauseSwitch, debuggingSnapshotter)
 	} else {
 		id, err := os.Hostname()
 		if err != nil {
@@ -647,6 +853,8 @@ func main() {
 			klog.Fatalf("Unable to create leader election lock: %v", err)
 		}
 
+		// Standby replicas don't act on the cluster, so they are ready until they become the leader.
+		readinessCheck.SetStandby(true)
 		leaderelection.RunOrDie(ctx.TODO(), leaderelection.LeaderElectionConfig{
 			Lock:            lock,
 			LeaseDuration:   leaderElection.LeaseDuration.Duration,
@@ -657,7 +865,8 @@ func main() {
 				OnStartedLeading: func(_ ctx.Context) {
 					// Since we are committing a suicide after losing
 					// mastership, we can safely ignore the argument.
-					run(healthCheck, debuggingSnapshotter)
+					readinessCheck.SetStandby(false)
+					run(healthCheck, readinessCheck, pauseSwitch, debuggingSnapshotter)
 				},
 				OnStoppedLeading: func() {
 					klog.Fatalf("lost master")
@@ -764,3 +973,62 @@ func parseSingleGpuLimit(limits string) (config.GpuLimits, error) {
 	}
 	return parsedGpuLimits, nil
 }
+
+// parseNewPodScaleUpDelayPerPriorityClass returns the new pod scale-up delays of priority classes,
+// passed in the format <priority_class>:<delay>.
+func parseNewPodScaleUpDelayPerPriorityClass(flags MultiStringFlag) (map[string]time.Duration, error) {
+	delays := make(map[string]time.Duration, len(flags))
+	for _, flag := range flags {
+		parts := strings.SplitN(flag, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("incorrect new pod scale-up delay specification: %v", flag)
+		}
+		delay, err := time.ParseDuration(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("incorrect new pod scale-up delay - invalid duration: %v", flag)
+		}
+		if delay < 0 {
+			return nil, fmt.Errorf("incorrect new pod scale-up delay - delay is negative: %v", flag)
+		}
+		delays[parts[0]] = delay
+	}
+	return delays, nil
+}
+
+// parseMaxGracefulTerminationSecOverrides returns the max graceful termination overrides of namespaces or
+// priority classes, passed in the format <name>:<seconds>.
+func parseMaxGracefulTerminationSecOverrides(flags MultiStringFlag) (map[string]int, error) {
+	overrides := make(map[string]int, len(flags))
+	for _, flag := range flags {
+		parts := strings.SplitN(flag, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("incorrect max graceful termination specification: %v", flag)
+		}
+		seconds, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("incorrect max graceful termination - invalid number of seconds: %v", flag)
+		}
+		if seconds < 0 {
+			return nil, fmt.Errorf("incorrect max graceful termination - number of seconds is negative: %v", flag)
+		}
+		overrides[parts[0]] = seconds
+	}
+	return overrides, nil
+}
+
+// parseFallbackRequests returns the requests given to containers without requests in namespaces without
+// LimitRange defaults. Empty values are left out.
+func parseFallbackRequests(cpu, memory string) (apiv1.ResourceList, error) {
+	requests := apiv1.ResourceList{}
+	for name, value := range map[apiv1.ResourceName]string{apiv1.ResourceCPU: cpu, apiv1.ResourceMemory: memory} {
+		if value == "" {
+			continue
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fallback %s request %q: %v", name, value, err)
+		}
+		requests[name] = quantity
+	}
+	return requests, nil
+}
@@ -18,6 +18,7 @@ package main
 
 import (
 	"testing"
+	"time"
 
 	"k8s.io/autoscaler/cluster-autoscaler/config"
 
@@ -91,3 +92,25 @@ func TestParseSingleGpuLimit(t *testing.T) {
 		}
 	}
 }
+
+func TestParseNewPodScaleUpDelayPerPriorityClass(t *testing.T) {
+	delays, err := parseNewPodScaleUpDelayPerPriorityClass(MultiStringFlag{"batch:5m", "critical:0s"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]time.Duration{"batch": 5 * time.Minute, "critical": 0}, delays)
+
+	for _, input := range []string{"batch", ":5m", "batch:5", "batch:-1m"} {
+		_, err := parseNewPodScaleUpDelayPerPriorityClass(MultiStringFlag{input})
+		assert.Error(t, err, input)
+	}
+}
+
+func TestParseMaxGracefulTerminationSecOverrides(t *testing.T) {
+	overrides, err := parseMaxGracefulTerminationSecOverrides(MultiStringFlag{"databases:3600", "batch:120"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"databases": 3600, "batch": 120}, overrides)
+
+	for _, input := range []string{"batch", ":120", "batch:2m", "batch:-1"} {
+		_, err := parseMaxGracefulTerminationSecOverrides(MultiStringFlag{input})
+		assert.Error(t, err, input)
+	}
+}
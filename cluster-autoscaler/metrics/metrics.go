@@ -44,6 +44,12 @@ type FunctionLabel string
 // NodeGroupType describes node group relation to CA
 type NodeGroupType string
 
+// SkippedSimulationReason describes why scale-down simulation of candidates was skipped
+type SkippedSimulationReason string
+
+// NodeState describes the state of a node as seen by CA
+type NodeState string
+
 const (
 	caNamespace           = "cluster_autoscaler"
 	readyLabel            = "ready"
@@ -51,6 +57,8 @@ const (
 	startingLabel         = "notStarted"
 	unregisteredLabel     = "unregistered"
 	longUnregisteredLabel = "longUnregistered"
+	deletedLabel          = "deleted"
+	beingDeletedLabel     = "beingDeleted"
 
 	// Underutilized node was removed because of low utilization
 	Underutilized NodeScaleDownReason = "underutilized"
@@ -76,6 +84,28 @@ const (
 	// MemoryResourceLimit minimum or maximum reached, check the direction label to determine min or max
 	MemoryResourceLimit string = "MemoryResourceLimit"
 
+	// SimulationTimeout means the scale-down simulation ran out of time
+	SimulationTimeout SkippedSimulationReason = "timeout"
+	// UnneededNodesLimit means enough unneeded nodes were found to saturate scale-down parallelism
+	UnneededNodesLimit SkippedSimulationReason = "unneededNodesLimit"
+	// DrainLimit means enough unneeded nodes needing drain were found to saturate drain parallelism
+	DrainLimit SkippedSimulationReason = "drainLimit"
+
+	// NodeStateReady is the state of ready nodes
+	NodeStateReady NodeState = readyLabel
+	// NodeStateUnready is the state of nodes which broke down after they started
+	NodeStateUnready NodeState = unreadyLabel
+	// NodeStateNotStarted is the state of nodes which aren't ready yet since their creation
+	NodeStateNotStarted NodeState = startingLabel
+	// NodeStateUnregistered is the state of instances whose node hasn't registered yet
+	NodeStateUnregistered NodeState = unregisteredLabel
+	// NodeStateLongUnregistered is the state of instances whose node failed to register in time
+	NodeStateLongUnregistered NodeState = longUnregisteredLabel
+	// NodeStateDeleted is the state of nodes whose instance no longer exists in the cloud provider
+	NodeStateDeleted NodeState = deletedLabel
+	// NodeStateBeingDeleted is the state of nodes drained or deleted by CA
+	NodeStateBeingDeleted NodeState = beingDeletedLabel
+
 	// autoscaledGroup is managed by CA
 	autoscaledGroup NodeGroupType = "autoscaled"
 	// autoprovisionedGroup have been created by CA (Node Autoprovisioning),
@@ -119,14 +149,30 @@ var (
 		},
 	)
 
+	autoscalingPaused = k8smetrics.NewGauge(
+		&k8smetrics.GaugeOpts{
+			Namespace: caNamespace,
+			Name:      "autoscaling_paused",
+			Help:      "Whether or not autoscaling is paused, e.g. during control-plane maintenance. 1 if it is, 0 otherwise.",
+		},
+	)
+
 	nodesCount = k8smetrics.NewGaugeVec(
 		&k8smetrics.GaugeOpts{
 			Namespace: caNamespace,
 			Name:      "nodes_count",
-			Help:      "Number of nodes in cluster.",
+			Help:      "Number of nodes in cluster. Nodes in the beingDeleted state are also counted in their readiness state.",
 		}, []string{"state"},
 	)
 
+	nodeInfo = k8smetrics.NewGaugeVec(
+		&k8smetrics.GaugeOpts{
+			Namespace: caNamespace,
+			Name:      "node_info",
+			Help:      "State and node group of each node, as seen by CA. Always 1.",
+		}, []string{"node", "node_group", "state"},
+	)
+
 	nodeGroupsCount = k8smetrics.NewGaugeVec(
 		&k8smetrics.GaugeOpts{
 			Namespace: caNamespace,
@@ -135,6 +181,14 @@ var (
 		}, []string{"node_group_type"},
 	)
 
+	orphanedNodeGroupsCount = k8smetrics.NewGauge(
+		&k8smetrics.GaugeOpts{
+			Namespace: caNamespace,
+			Name:      "orphaned_node_groups_count",
+			Help:      "Number of node groups no longer returned by the cloud provider that still have nodes.",
+		},
+	)
+
 	unschedulablePodsCount = k8smetrics.NewGauge(
 		&k8smetrics.GaugeOpts{
 			Namespace: caNamespace,
@@ -234,6 +288,24 @@ var (
 		},
 	)
 
+	pendingPodGroupsTimeToSchedule = k8smetrics.NewGaugeVec(
+		&k8smetrics.GaugeOpts{
+			Namespace: caNamespace,
+			Name:      "pending_pod_groups_time_to_schedule_seconds",
+			Help:      "Longest estimated time until pending pods can be scheduled, per namespace. Pods without an estimate are not reported.",
+		},
+		[]string{"namespace"},
+	)
+
+	pendingPodGroupsCount = k8smetrics.NewGaugeVec(
+		&k8smetrics.GaugeOpts{
+			Namespace: caNamespace,
+			Name:      "pending_pod_groups_count",
+			Help:      "Number of groups of equivalent pending pods, by what they are waiting for.",
+		},
+		[]string{"reason"},
+	)
+
 	/**** Metrics related to autoscaler operations ****/
 	errorsCount = k8smetrics.NewCounterVec(
 		&k8smetrics.CounterOpts{
@@ -243,6 +315,14 @@ var (
 		}, []string{"type"},
 	)
 
+	nodeGroupPanicsCount = k8smetrics.NewCounterVec(
+		&k8smetrics.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "node_group_panics_total",
+			Help:      "Number of panics recovered from while processing a node group, by node group.",
+		}, []string{"node_group"},
+	)
+
 	scaleUpCount = k8smetrics.NewCounter(
 		&k8smetrics.CounterOpts{
 			Namespace: caNamespace,
@@ -349,6 +429,24 @@ var (
 		[]string{"direction", "reason"},
 	)
 
+	skippedScaleDownSimulationsCount = k8smetrics.NewCounterVec(
+		&k8smetrics.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "skipped_scale_down_simulations_count",
+			Help:      "Number of scale-down candidates not simulated because per-loop limits were already saturated.",
+		},
+		[]string{"reason"},
+	)
+
+	shadowExpanderDecisionsCount = k8smetrics.NewCounterVec(
+		&k8smetrics.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "shadow_expander_decisions_total",
+			Help:      "Number of scale-up decisions of the shadow expander, by whether it agreed with the active expander.",
+		},
+		[]string{"outcome"},
+	)
+
 	/**** Metrics related to NodeAutoprovisioning ****/
 	napEnabled = k8smetrics.NewGauge(
 		&k8smetrics.GaugeOpts{
@@ -358,6 +456,22 @@ var (
 		},
 	)
 
+	scaleUpEstimatedHourlyCost = k8smetrics.NewCounterVec(
+		&k8smetrics.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "scaled_up_nodes_estimated_hourly_cost_total",
+			Help:      "Cumulative estimated hourly cost of nodes added by scale up, according to the cloud provider pricing model.",
+		}, []string{"node_group"},
+	)
+
+	scaleDownEstimatedHourlyCost = k8smetrics.NewCounterVec(
+		&k8smetrics.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "scaled_down_nodes_estimated_hourly_cost_total",
+			Help:      "Cumulative estimated hourly cost of nodes removed by scale down (i.e. estimated savings), according to the cloud provider pricing model.",
+		}, []string{"node_group"},
+	)
+
 	nodeGroupCreationCount = k8smetrics.NewCounter(
 		&k8smetrics.CounterOpts{
 			Namespace: caNamespace,
@@ -376,10 +490,12 @@ var (
 )
 
 // RegisterAll registers all metrics.
-func RegisterAll(emitPerNodeGroupMetrics bool) {
+func RegisterAll(emitPerNodeGroupMetrics, emitPerNodeMetrics bool) {
 	legacyregistry.MustRegister(clusterSafeToAutoscale)
+	legacyregistry.MustRegister(autoscalingPaused)
 	legacyregistry.MustRegister(nodesCount)
 	legacyregistry.MustRegister(nodeGroupsCount)
+	legacyregistry.MustRegister(orphanedNodeGroupsCount)
 	legacyregistry.MustRegister(unschedulablePodsCount)
 	legacyregistry.MustRegister(maxNodesCount)
 	legacyregistry.MustRegister(cpuCurrentCores)
@@ -403,14 +519,25 @@ func RegisterAll(emitPerNodeGroupMetrics bool) {
 	legacyregistry.MustRegister(oldUnregisteredNodesRemovedCount)
 	legacyregistry.MustRegister(overflowingControllersCount)
 	legacyregistry.MustRegister(skippedScaleEventsCount)
+	legacyregistry.MustRegister(skippedScaleDownSimulationsCount)
+	legacyregistry.MustRegister(shadowExpanderDecisionsCount)
 	legacyregistry.MustRegister(napEnabled)
 	legacyregistry.MustRegister(nodeGroupCreationCount)
 	legacyregistry.MustRegister(nodeGroupDeletionCount)
 	legacyregistry.MustRegister(pendingNodeDeletions)
+	legacyregistry.MustRegister(pendingPodGroupsTimeToSchedule)
+	legacyregistry.MustRegister(pendingPodGroupsCount)
 
 	if emitPerNodeGroupMetrics {
 		legacyregistry.MustRegister(nodesGroupMinNodes)
 		legacyregistry.MustRegister(nodesGroupMaxNodes)
+		legacyregistry.MustRegister(scaleUpEstimatedHourlyCost)
+		legacyregistry.MustRegister(scaleDownEstimatedHourlyCost)
+		legacyregistry.MustRegister(nodeGroupPanicsCount)
+	}
+
+	if emitPerNodeMetrics {
+		legacyregistry.MustRegister(nodeInfo)
 	}
 }
 
@@ -446,13 +573,33 @@ func UpdateClusterSafeToAutoscale(safe bool) {
 	}
 }
 
+// UpdateAutoscalingPaused records if autoscaling is paused
+func UpdateAutoscalingPaused(paused bool) {
+	if paused {
+		autoscalingPaused.Set(1)
+	} else {
+		autoscalingPaused.Set(0)
+	}
+}
+
 // UpdateNodesCount records the number of nodes in cluster
-func UpdateNodesCount(ready, unready, starting, longUnregistered, unregistered int) {
+func UpdateNodesCount(ready, unready, starting, longUnregistered, unregistered, deleted, beingDeleted int) {
 	nodesCount.WithLabelValues(readyLabel).Set(float64(ready))
 	nodesCount.WithLabelValues(unreadyLabel).Set(float64(unready))
 	nodesCount.WithLabelValues(startingLabel).Set(float64(starting))
 	nodesCount.WithLabelValues(longUnregisteredLabel).Set(float64(longUnregistered))
 	nodesCount.WithLabelValues(unregisteredLabel).Set(float64(unregistered))
+	nodesCount.WithLabelValues(deletedLabel).Set(float64(deleted))
+	nodesCount.WithLabelValues(beingDeletedLabel).Set(float64(beingDeleted))
+}
+
+// UpdateNodeStates records the state and node group of each node, replacing the
+// previously recorded nodes. Nodes missing from nodeGroups aren't autoscaled.
+func UpdateNodeStates(states map[string]NodeState, nodeGroups map[string]string) {
+	nodeInfo.Reset()
+	for node, state := range states {
+		nodeInfo.WithLabelValues(node, nodeGroups[node], string(state)).Set(1)
+	}
 }
 
 // UpdateNodeGroupsCount records the number of node groups managed by CA
@@ -461,6 +608,11 @@ func UpdateNodeGroupsCount(autoscaled, autoprovisioned int) {
 	nodeGroupsCount.WithLabelValues(string(autoprovisionedGroup)).Set(float64(autoprovisioned))
 }
 
+// UpdateOrphanedNodeGroupsCount records the number of node groups no longer returned by the cloud provider that still have nodes
+func UpdateOrphanedNodeGroupsCount(count int) {
+	orphanedNodeGroupsCount.Set(float64(count))
+}
+
 // UpdateUnschedulablePodsCount records number of currently unschedulable pods
 func UpdateUnschedulablePodsCount(podsCount int) {
 	unschedulablePodsCount.Set(float64(podsCount))
@@ -509,6 +661,11 @@ func RegisterError(err errors.AutoscalerError) {
 	errorsCount.WithLabelValues(string(err.Type())).Add(1.0)
 }
 
+// RegisterNodeGroupPanic records a panic recovered from while processing a node group.
+func RegisterNodeGroupPanic(nodeGroup string) {
+	nodeGroupPanicsCount.WithLabelValues(nodeGroup).Inc()
+}
+
 // RegisterScaleUp records number of nodes added by scale up
 func RegisterScaleUp(nodesCount int, gpuResourceName, gpuType string) {
 	scaleUpCount.Add(float64(nodesCount))
@@ -533,6 +690,16 @@ func RegisterScaleDown(nodesCount int, gpuResourceName, gpuType string, reason N
 	}
 }
 
+// RegisterScaleUpEstimatedHourlyCost records the estimated hourly cost of nodes added to a node group by scale up
+func RegisterScaleUpEstimatedHourlyCost(nodeGroup string, hourlyCost float64) {
+	scaleUpEstimatedHourlyCost.WithLabelValues(nodeGroup).Add(hourlyCost)
+}
+
+// RegisterScaleDownEstimatedHourlyCost records the estimated hourly cost of nodes removed from a node group by scale down
+func RegisterScaleDownEstimatedHourlyCost(nodeGroup string, hourlyCost float64) {
+	scaleDownEstimatedHourlyCost.WithLabelValues(nodeGroup).Add(hourlyCost)
+}
+
 // RegisterEvictions records number of evicted pods
 func RegisterEvictions(podsCount int) {
 	evictionsCount.Add(float64(podsCount))
@@ -611,7 +778,39 @@ func RegisterSkippedScaleUpMemory() {
 	skippedScaleEventsCount.WithLabelValues(DirectionScaleUp, MemoryResourceLimit).Add(1.0)
 }
 
+// RegisterSkippedScaleDownSimulations increases the count of scale-down candidates not simulated for the given reason
+func RegisterSkippedScaleDownSimulations(reason SkippedSimulationReason, count int) {
+	skippedScaleDownSimulationsCount.WithLabelValues(string(reason)).Add(float64(count))
+}
+
+// RegisterShadowExpanderDecision records a decision of the shadow expander, and whether it agreed with the active expander
+func RegisterShadowExpanderDecision(agreed bool) {
+	if agreed {
+		shadowExpanderDecisionsCount.WithLabelValues("agree").Inc()
+	} else {
+		shadowExpanderDecisionsCount.WithLabelValues("disagree").Inc()
+	}
+}
+
 // ObservePendingNodeDeletions records the current value of nodes_pending_deletion metric
 func ObservePendingNodeDeletions(value int) {
 	pendingNodeDeletions.Set(float64(value))
 }
+
+// UpdatePendingPodGroupsTimeToSchedule records the longest estimated time to schedule of pending pods
+// per namespace. Previously recorded namespaces are forgotten. Pod owners are not used as a label, as
+// there can be any number of them.
+func UpdatePendingPodGroupsTimeToSchedule(timeToSchedule map[string]time.Duration) {
+	pendingPodGroupsTimeToSchedule.Reset()
+	for namespace, duration := range timeToSchedule {
+		pendingPodGroupsTimeToSchedule.WithLabelValues(namespace).Set(duration.Seconds())
+	}
+}
+
+// UpdatePendingPodGroupsCount records the number of groups of pending pods per reason.
+func UpdatePendingPodGroupsCount(reasonCounts map[string]int) {
+	pendingPodGroupsCount.Reset()
+	for reason, count := range reasonCounts {
+		pendingPodGroupsCount.WithLabelValues(reason).Set(float64(count))
+	}
+}
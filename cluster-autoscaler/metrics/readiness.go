@@ -0,0 +1,99 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ReadinessCheck contains information about whether autoscaler is ready to act on the cluster:
+// its informer caches are synced and the cloud provider was recently refreshed successfully.
+// Standby replicas waiting for the leader election are always ready, so that they don't block
+// rollouts or disruptions of highly available deployments.
+type ReadinessCheck struct {
+	standby                  bool
+	informersSynced          bool
+	lastCloudProviderRefresh time.Time
+	mutex                    *sync.Mutex
+	refreshTimeout           time.Duration
+}
+
+// NewReadinessCheck builds new ReadinessCheck object with given cloud provider refresh timeout
+func NewReadinessCheck(refreshTimeout time.Duration) *ReadinessCheck {
+	return &ReadinessCheck{
+		mutex:          &sync.Mutex{},
+		refreshTimeout: refreshTimeout,
+	}
+}
+
+// ServeHTTP implements http.Handler interface to provide a readiness endpoint
+func (rc *ReadinessCheck) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mutex.Lock()
+	standby := rc.standby
+	informersSynced := rc.informersSynced
+	lastCloudProviderRefresh := rc.lastCloudProviderRefresh
+	rc.mutex.Unlock()
+
+	if standby {
+		w.WriteHeader(200)
+		w.Write([]byte("OK: standby"))
+		return
+	}
+	if !informersSynced {
+		w.WriteHeader(503)
+		w.Write([]byte("Error: informer caches not synced yet"))
+		return
+	}
+	if lastCloudProviderRefresh.IsZero() {
+		w.WriteHeader(503)
+		w.Write([]byte("Error: cloud provider not refreshed yet"))
+		return
+	}
+	if time.Now().After(lastCloudProviderRefresh.Add(rc.refreshTimeout)) {
+		w.WriteHeader(503)
+		w.Write([]byte(fmt.Sprintf("Error: last successful cloud provider refresh more than %v ago", time.Now().Sub(lastCloudProviderRefresh).String())))
+		return
+	}
+	w.WriteHeader(200)
+	w.Write([]byte("OK"))
+}
+
+// SetStandby records whether autoscaler is a standby replica waiting to become the leader
+func (rc *ReadinessCheck) SetStandby(standby bool) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	rc.standby = standby
+}
+
+// MarkInformersSynced records that informer caches have been synced
+func (rc *ReadinessCheck) MarkInformersSynced() {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	rc.informersSynced = true
+}
+
+// UpdateLastCloudProviderRefresh updates last time of successful cloud provider refresh
+func (rc *ReadinessCheck) UpdateLastCloudProviderRefresh(timestamp time.Time) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	if timestamp.After(rc.lastCloudProviderRefresh) {
+		rc.lastCloudProviderRefresh = timestamp
+	}
+}
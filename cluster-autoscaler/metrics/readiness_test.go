@@ -0,0 +1,77 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadinessServeHTTP(t *testing.T) {
+	testCases := []struct {
+		name            string
+		standby         bool
+		informersSynced bool
+		lastRefresh     time.Time
+		expectedCode    int
+	}{
+		{
+			name:         "informers not synced",
+			lastRefresh:  time.Now(),
+			expectedCode: 503,
+		},
+		{
+			name:            "cloud provider never refreshed",
+			informersSynced: true,
+			expectedCode:    503,
+		},
+		{
+			name:            "cloud provider refresh too old",
+			informersSynced: true,
+			lastRefresh:     time.Now().Add(-2 * time.Second),
+			expectedCode:    503,
+		},
+		{
+			name:         "standby",
+			standby:      true,
+			expectedCode: 200,
+		},
+		{
+			name:            "ready",
+			informersSynced: true,
+			lastRefresh:     time.Now(),
+			expectedCode:    200,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			readinessCheck := NewReadinessCheck(time.Second)
+			readinessCheck.SetStandby(tc.standby)
+			if tc.informersSynced {
+				readinessCheck.MarkInformersSynced()
+			}
+			readinessCheck.UpdateLastCloudProviderRefresh(tc.lastRefresh)
+
+			w := httptest.NewRecorder()
+			readinessCheck.ServeHTTP(w, httptest.NewRequest("GET", "/health-check/readiness", nil))
+			assert.Equal(t, tc.expectedCode, w.Code)
+		})
+	}
+}
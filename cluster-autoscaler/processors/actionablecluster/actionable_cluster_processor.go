@@ -69,7 +69,8 @@ func OnEmptyCluster(context *context.AutoscalingContext, status string, emitEven
 	context.ProcessorCallbacks.ResetUnneededNodes()
 	// updates metrics related to empty cluster's state.
 	metrics.UpdateClusterSafeToAutoscale(false)
-	metrics.UpdateNodesCount(0, 0, 0, 0, 0)
+	metrics.UpdateNodesCount(0, 0, 0, 0, 0, 0, 0)
+	metrics.UpdateNodeStates(nil, nil)
 	if context.WriteStatusConfigMap {
 		utils.WriteStatusConfigMap(context.ClientSet, context.ConfigNamespace, status, context.LogRecorder, context.StatusConfigMapName)
 	}
@@ -0,0 +1,134 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actionablecluster
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	kube_errors "k8s.io/apimachinery/pkg/api/errors"
+	acontext "k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/metrics"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
+	"k8s.io/klog/v2"
+)
+
+// PausedAnnotation pauses autoscaling when set to "true" on the status config map.
+const PausedAnnotation = "cluster-autoscaler.kubernetes.io/paused"
+
+// PauseSwitch pauses autoscaling, e.g. during control-plane maintenance.
+type PauseSwitch struct {
+	mutex  sync.Mutex
+	paused bool
+}
+
+// NewPauseSwitch returns a new PauseSwitch, initially paused or not.
+func NewPauseSwitch(paused bool) *PauseSwitch {
+	return &PauseSwitch{paused: paused}
+}
+
+// Paused returns whether autoscaling is paused.
+func (s *PauseSwitch) Paused() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.paused
+}
+
+// SetPaused pauses or resumes autoscaling.
+func (s *PauseSwitch) SetPaused(paused bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.paused = paused
+}
+
+// ServeHTTP reports whether autoscaling is paused on GET requests, and pauses or
+// resumes it on POST requests, according to their "paused" query parameter.
+func (s *PauseSwitch) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		paused, err := strconv.ParseBool(req.URL.Query().Get("paused"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid paused parameter: %v", err), http.StatusBadRequest)
+			return
+		}
+		s.SetPaused(paused)
+		klog.Warningf("Autoscaling paused set to %v through the pause endpoint", paused)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	fmt.Fprintf(w, "paused: %v\n", s.Paused())
+}
+
+// PausingProcessor aborts the autoscaling loops while autoscaling is paused by its
+// PauseSwitch or by the PausedAnnotation of the status config map, which is read from
+// the config map informer of the autoscaling context. Cluster Autoscaler
+// then observes the cluster without making any change: no scaling, no taints, no events
+// and no status config map updates. Otherwise, the wrapped processor decides.
+type PausingProcessor struct {
+	pauseSwitch *PauseSwitch
+	processor   ActionableClusterProcessor
+}
+
+// NewPausingProcessor returns a PausingProcessor wrapping the given processor.
+func NewPausingProcessor(pauseSwitch *PauseSwitch, processor ActionableClusterProcessor) ActionableClusterProcessor {
+	return &PausingProcessor{
+		pauseSwitch: pauseSwitch,
+		processor:   processor,
+	}
+}
+
+// ShouldAbort aborts the loop if autoscaling is paused, otherwise defers to the wrapped processor.
+func (p *PausingProcessor) ShouldAbort(context *acontext.AutoscalingContext, allNodes []*apiv1.Node, readyNodes []*apiv1.Node, currentTime time.Time) (bool, errors.AutoscalerError) {
+	if reason := p.pausedReason(context); reason != "" {
+		klog.Warningf("Autoscaling is paused by %s, not taking any action", reason)
+		metrics.UpdateAutoscalingPaused(true)
+		return true, nil
+	}
+	metrics.UpdateAutoscalingPaused(false)
+	return p.processor.ShouldAbort(context, allNodes, readyNodes, currentTime)
+}
+
+func (p *PausingProcessor) pausedReason(autoscalingContext *acontext.AutoscalingContext) string {
+	if p.pauseSwitch.Paused() {
+		return "the --paused flag or the pause endpoint"
+	}
+	if autoscalingContext.ConfigMapLister == nil {
+		return ""
+	}
+	configMap, err := autoscalingContext.ConfigMapLister.Get(autoscalingContext.StatusConfigMapName)
+	if err != nil {
+		if !kube_errors.IsNotFound(err) {
+			klog.Warningf("Failed to check the %s annotation of the status config map: %v", PausedAnnotation, err)
+		}
+		return ""
+	}
+	if paused, _ := strconv.ParseBool(configMap.Annotations[PausedAnnotation]); paused {
+		return fmt.Sprintf("the %s annotation of config map %s/%s", PausedAnnotation, configMap.Namespace, configMap.Name)
+	}
+	return ""
+}
+
+// CleanUp cleans up the wrapped processor.
+func (p *PausingProcessor) CleanUp() {
+	p.processor.CleanUp()
+}
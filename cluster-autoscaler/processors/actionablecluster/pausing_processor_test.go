@@ -0,0 +1,96 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actionablecluster
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	acontext "k8s.io/autoscaler/cluster-autoscaler/context"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func TestPausingProcessor(t *testing.T) {
+	node := BuildTestNode("n1", 1000, 1000)
+	nodes := []*apiv1.Node{node}
+
+	testCases := []struct {
+		desc        string
+		paused      bool
+		annotations map[string]string
+		wantAbort   bool
+	}{
+		{desc: "not paused"},
+		{desc: "paused by switch", paused: true, wantAbort: true},
+		{desc: "paused by annotation", annotations: map[string]string{PausedAnnotation: "true"}, wantAbort: true},
+		{desc: "annotation set to false", annotations: map[string]string{PausedAnnotation: "false"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			configMap := &apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "kube-system",
+				Name:        "cluster-autoscaler-status",
+				Annotations: tc.annotations,
+			}}
+			lister, err := kube_util.NewTestConfigMapLister([]*apiv1.ConfigMap{configMap})
+			assert.NoError(t, err)
+			context := &acontext.AutoscalingContext{
+				AutoscalingOptions: config.AutoscalingOptions{
+					ConfigNamespace:     "kube-system",
+					StatusConfigMapName: "cluster-autoscaler-status",
+				},
+				AutoscalingKubeClients: acontext.AutoscalingKubeClients{
+					ConfigMapLister: lister.ConfigMaps("kube-system"),
+				},
+			}
+			processor := NewPausingProcessor(NewPauseSwitch(tc.paused), NewDefaultActionableClusterProcessor())
+			abort, err := processor.ShouldAbort(context, nodes, nodes, time.Now())
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantAbort, abort)
+		})
+	}
+}
+
+func TestPauseSwitchServeHTTP(t *testing.T) {
+	pauseSwitch := NewPauseSwitch(false)
+
+	w := httptest.NewRecorder()
+	pauseSwitch.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/pause?paused=true", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, pauseSwitch.Paused())
+
+	w = httptest.NewRecorder()
+	pauseSwitch.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pause", nil))
+	assert.Equal(t, "paused: true\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	pauseSwitch.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/pause?paused=maybe", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.True(t, pauseSwitch.Paused())
+
+	w = httptest.NewRecorder()
+	pauseSwitch.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/pause?paused=false", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, pauseSwitch.Paused())
+}
@@ -0,0 +1,314 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alerts
+
+import (
+	"fmt"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate/api"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	scaledownstatus "k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaleup/orchestrator"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	klog "k8s.io/klog/v2"
+)
+
+// Condition is a situation the autoscaler can't resolve on its own and raises an alert for.
+type Condition string
+
+const (
+	// PodPendingOnQuota means a pod couldn't trigger a scale-up because node group or cluster-wide limits were reached.
+	PodPendingOnQuota Condition = "PodPendingOnQuota"
+	// NodeGroupStuckInBackoff means a node group stayed in backoff after failed scale-ups.
+	NodeGroupStuckInBackoff Condition = "NodeGroupStuckInBackoff"
+	// ScaleDownBlocked means an otherwise removable node was kept by the same blocker.
+	ScaleDownBlocked Condition = "ScaleDownBlocked"
+)
+
+// Config defines after how long each condition raises an alert. A zero threshold disables the condition.
+type Config struct {
+	// PodPendingOnQuotaThreshold is how long a pod has to be pending due to limits before alerting.
+	PodPendingOnQuotaThreshold time.Duration
+	// NodeGroupBackoffThreshold is how long a node group has to stay in backoff before alerting.
+	NodeGroupBackoffThreshold time.Duration
+	// ScaleDownBlockedThreshold is how long scale-down of a node has to be blocked before alerting.
+	ScaleDownBlockedThreshold time.Duration
+	// WebhookURL, if set, receives the alerts raised in each iteration as a JSON array in a single POST
+	// request, in addition to the Warning events.
+	WebhookURL string
+}
+
+// Enabled returns true if any of the conditions is enabled.
+func (c Config) Enabled() bool {
+	return c.PodPendingOnQuotaThreshold > 0 || c.NodeGroupBackoffThreshold > 0 || c.ScaleDownBlockedThreshold > 0
+}
+
+// Alert is raised once a condition has been lasting for longer than its threshold.
+type Alert struct {
+	Condition Condition `json:"condition"`
+	// Object is the name of the pod, node group or node the alert is about.
+	Object  string    `json:"object"`
+	Message string    `json:"message"`
+	Since   time.Time `json:"since"`
+}
+
+// observation is a condition seen in the most recent autoscaling iteration.
+type observation struct {
+	object  string
+	message string
+	// involved is the object Warning events are recorded on, nil for the autoscaler status.
+	involved *apiv1.ObjectReference
+}
+
+type alertKey struct {
+	condition Condition
+	id        string
+}
+
+// Alerter tracks for how long alert conditions have been lasting and raises an alert once per
+// occurrence of a condition, after it exceeded its threshold.
+type Alerter struct {
+	config  Config
+	webhook *webhookClient
+
+	pendingOnQuota map[string]observation
+	blockedNodes   map[string]observation
+	blockedReasons map[string]simulator.UnremovableReason
+	since          map[alertKey]time.Time
+	fired          map[alertKey]bool
+}
+
+// NewAlerter creates an Alerter with the given config.
+func NewAlerter(config Config) *Alerter {
+	a := &Alerter{
+		config:         config,
+		pendingOnQuota: make(map[string]observation),
+		blockedNodes:   make(map[string]observation),
+		blockedReasons: make(map[string]simulator.UnremovableReason),
+		since:          make(map[alertKey]time.Time),
+		fired:          make(map[alertKey]bool),
+	}
+	if config.WebhookURL != "" {
+		a.webhook = newWebhookClient(config.WebhookURL, defaultWebhookTimeout, defaultWebhookRetryBackoff)
+	}
+	return a
+}
+
+// observeScaleUp records pods that didn't trigger a scale-up because limits were reached.
+func (a *Alerter) observeScaleUp(scaleUpStatus *status.ScaleUpStatus) {
+	if a.config.PodPendingOnQuotaThreshold <= 0 || scaleUpStatus == nil {
+		return
+	}
+	switch scaleUpStatus.Result {
+	case status.ScaleUpSuccessful, status.ScaleUpNoOptionsAvailable, status.ScaleUpNotNeeded:
+	default:
+		// Pending pods weren't evaluated in this iteration, keep the previous observations.
+		return
+	}
+
+	pending := make(map[string]observation)
+	for _, info := range scaleUpStatus.PodsRemainUnschedulable {
+		if info.Pod == nil {
+			continue
+		}
+		if limits := limitsReached(info); len(limits) > 0 {
+			pending[string(info.Pod.UID)] = observation{
+				object:   info.Pod.Namespace + "/" + info.Pod.Name,
+				message:  fmt.Sprintf("pod can't trigger scale-up: %v", limits),
+				involved: podReference(info.Pod),
+			}
+		}
+	}
+	a.pendingOnQuota = pending
+}
+
+// limitsReached returns the limit related reasons node groups were skipped for the pod.
+func limitsReached(info status.NoScaleUpInfo) []string {
+	seen := make(map[string]bool)
+	var limits []string
+	for _, reasons := range info.SkippedNodeGroups {
+		if _, ok := reasons.(*orchestrator.MaxResourceLimitReached); !ok && reasons != orchestrator.MaxLimitReachedReason {
+			continue
+		}
+		for _, reason := range reasons.Reasons() {
+			if !seen[reason] {
+				seen[reason] = true
+				limits = append(limits, reason)
+			}
+		}
+	}
+	return limits
+}
+
+// observeScaleDown records nodes whose removal is blocked.
+func (a *Alerter) observeScaleDown(scaleDownStatus *scaledownstatus.ScaleDownStatus) {
+	if a.config.ScaleDownBlockedThreshold <= 0 || scaleDownStatus == nil {
+		return
+	}
+
+	blocked := make(map[string]observation)
+	reasons := make(map[string]simulator.UnremovableReason)
+	for _, unremovable := range scaleDownStatus.UnremovableNodes {
+		if unremovable.Node == nil {
+			continue
+		}
+		name := unremovable.Node.Name
+		reason := unremovable.Reason
+		if reason == simulator.RecentlyUnremovable {
+			// The node isn't rechecked for a while, it is still blocked by whatever blocked it before.
+			previous, found := a.blockedReasons[name]
+			if !found {
+				continue
+			}
+			blocked[name] = a.blockedNodes[name]
+			reasons[name] = previous
+			continue
+		}
+		if !isBlockingReason(reason) {
+			continue
+		}
+		message := fmt.Sprintf("node can't be scaled down: %s", describeReason(reason))
+		if unremovable.BlockingPod != nil && unremovable.BlockingPod.Pod != nil {
+			message = fmt.Sprintf("node can't be scaled down: pod %s/%s can't be moved", unremovable.BlockingPod.Pod.Namespace, unremovable.BlockingPod.Pod.Name)
+		}
+		blocked[name] = observation{
+			object:   name,
+			message:  message,
+			involved: nodeReference(unremovable.Node),
+		}
+		if previous, found := a.blockedReasons[name]; found && previous != reason {
+			// A different blocker, start counting from scratch.
+			delete(a.since, alertKey{ScaleDownBlocked, name})
+			delete(a.fired, alertKey{ScaleDownBlocked, name})
+		}
+		reasons[name] = reason
+	}
+	a.blockedNodes = blocked
+	a.blockedReasons = reasons
+}
+
+func isBlockingReason(reason simulator.UnremovableReason) bool {
+	switch reason {
+	case simulator.BlockedByPod, simulator.NoPlaceToMovePods, simulator.UnexpectedError:
+		return true
+	}
+	return false
+}
+
+func describeReason(reason simulator.UnremovableReason) string {
+	switch reason {
+	case simulator.BlockedByPod:
+		return "a pod can't be moved"
+	case simulator.NoPlaceToMovePods:
+		return "no place to move its pods to"
+	default:
+		return "unexpected error"
+	}
+}
+
+// evaluate updates for how long the observed conditions have been lasting and raises alerts
+// for the ones that exceeded their threshold. Alerts raised in the same iteration are sent to the
+// webhook in a single batch.
+func (a *Alerter) evaluate(ctx *context.AutoscalingContext, csr *clusterstate.ClusterStateRegistry, now time.Time) {
+	current := make(map[alertKey]observation)
+	if a.config.PodPendingOnQuotaThreshold > 0 {
+		for uid, o := range a.pendingOnQuota {
+			current[alertKey{PodPendingOnQuota, uid}] = o
+		}
+	}
+	if a.config.ScaleDownBlockedThreshold > 0 {
+		for name, o := range a.blockedNodes {
+			current[alertKey{ScaleDownBlocked, name}] = o
+		}
+	}
+	if a.config.NodeGroupBackoffThreshold > 0 && csr != nil {
+		for _, ngStatus := range csr.GetStatus(now).NodeGroupStatuses {
+			if ngStatus.ScaleUp.Status == api.ClusterAutoscalerBackoff {
+				current[alertKey{NodeGroupStuckInBackoff, ngStatus.ProviderID}] = observation{
+					object:  ngStatus.ProviderID,
+					message: fmt.Sprintf("node group %s is in backoff: %s", ngStatus.ProviderID, ngStatus.ScaleUp.Message),
+				}
+			}
+		}
+	}
+
+	for key := range a.since {
+		if _, found := current[key]; !found {
+			delete(a.since, key)
+			delete(a.fired, key)
+		}
+	}
+	var raised []Alert
+	for key, o := range current {
+		since, found := a.since[key]
+		if !found {
+			a.since[key] = now
+			since = now
+		}
+		if a.fired[key] || now.Sub(since) < a.threshold(key.condition) {
+			continue
+		}
+		a.fired[key] = true
+		alert := Alert{
+			Condition: key.condition,
+			Object:    o.object,
+			Message:   fmt.Sprintf("%s for %v", o.message, now.Sub(since).Round(time.Second)),
+			Since:     since,
+		}
+		a.raise(ctx, alert, o.involved)
+		raised = append(raised, alert)
+	}
+	if a.webhook != nil && len(raised) > 0 {
+		a.webhook.enqueue(raised)
+	}
+}
+
+func (a *Alerter) threshold(condition Condition) time.Duration {
+	switch condition {
+	case PodPendingOnQuota:
+		return a.config.PodPendingOnQuotaThreshold
+	case NodeGroupStuckInBackoff:
+		return a.config.NodeGroupBackoffThreshold
+	default:
+		return a.config.ScaleDownBlockedThreshold
+	}
+}
+
+func (a *Alerter) raise(ctx *context.AutoscalingContext, alert Alert, involved *apiv1.ObjectReference) {
+	klog.Warningf("Alert %s for %s: %s", alert.Condition, alert.Object, alert.Message)
+	if ctx != nil {
+		if involved != nil && ctx.Recorder != nil {
+			ctx.Recorder.Event(involved, apiv1.EventTypeWarning, string(alert.Condition), alert.Message)
+		} else if ctx.LogRecorder != nil {
+			ctx.LogRecorder.Eventf(apiv1.EventTypeWarning, string(alert.Condition), "%s: %s", alert.Object, alert.Message)
+		}
+	}
+}
+
+func podReference(pod *apiv1.Pod) *apiv1.ObjectReference {
+	return &apiv1.ObjectReference{Kind: "Pod", Namespace: pod.Namespace, Name: pod.Name, UID: pod.UID}
+}
+
+// nodeReference uses the node name as UID, like the kubelet does, so that events show up in node descriptions.
+func nodeReference(node *apiv1.Node) *apiv1.ObjectReference {
+	return &apiv1.ObjectReference{Kind: "Node", Name: node.Name, UID: types.UID(node.Name)}
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alerts
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	scaledownstatus "k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaleup/orchestrator"
+	ca_processors "k8s.io/autoscaler/cluster-autoscaler/processors"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	kube_record "k8s.io/client-go/tools/record"
+)
+
+func TestPodPendingOnQuota(t *testing.T) {
+	recorder := kube_record.NewFakeRecorder(10)
+	ctx := &context.AutoscalingContext{Recorder: recorder}
+	alerter := NewAlerter(Config{PodPendingOnQuotaThreshold: 10 * time.Minute})
+
+	limited := BuildTestPod("limited", 100, 0)
+	limited.UID = "limited"
+	unfit := BuildTestPod("unfit", 100, 0)
+	unfit.UID = "unfit"
+	scaleUpStatus := &status.ScaleUpStatus{
+		Result: status.ScaleUpNoOptionsAvailable,
+		PodsRemainUnschedulable: []status.NoScaleUpInfo{
+			{Pod: limited, SkippedNodeGroups: map[string]status.Reasons{
+				"ng1": orchestrator.MaxLimitReachedReason,
+				"ng2": orchestrator.NewMaxResourceLimitReached([]string{"cpu"}),
+			}},
+			{Pod: unfit, SkippedNodeGroups: map[string]status.Reasons{"ng1": orchestrator.NotReadyReason}},
+		},
+	}
+
+	now := time.Now()
+	alerter.observeScaleUp(scaleUpStatus)
+	alerter.evaluate(ctx, nil, now)
+	assert.Empty(t, recorder.Events)
+
+	// Pending pods aren't evaluated when the scale-up isn't tried, the pod keeps pending.
+	alerter.observeScaleUp(&status.ScaleUpStatus{Result: status.ScaleUpNotTried})
+	alerter.evaluate(ctx, nil, now.Add(5*time.Minute))
+	assert.Empty(t, recorder.Events)
+
+	alerter.observeScaleUp(scaleUpStatus)
+	alerter.evaluate(ctx, nil, now.Add(11*time.Minute))
+	if assert.Len(t, recorder.Events, 1) {
+		event := <-recorder.Events
+		assert.Contains(t, event, "Warning PodPendingOnQuota")
+		assert.Contains(t, event, "max node group size reached")
+		assert.Contains(t, event, "max cluster cpu limit reached")
+	}
+
+	// Alerts are raised once per occurrence.
+	alerter.observeScaleUp(scaleUpStatus)
+	alerter.evaluate(ctx, nil, now.Add(20*time.Minute))
+	assert.Empty(t, recorder.Events)
+
+	// Once the pod is no longer pending, a new occurrence is counted from scratch.
+	alerter.observeScaleUp(&status.ScaleUpStatus{Result: status.ScaleUpNotNeeded})
+	alerter.evaluate(ctx, nil, now.Add(21*time.Minute))
+	alerter.observeScaleUp(scaleUpStatus)
+	alerter.evaluate(ctx, nil, now.Add(22*time.Minute))
+	assert.Empty(t, recorder.Events)
+	alerter.evaluate(ctx, nil, now.Add(33*time.Minute))
+	assert.Len(t, recorder.Events, 1)
+}
+
+func TestScaleDownBlocked(t *testing.T) {
+	recorder := kube_record.NewFakeRecorder(10)
+	ctx := &context.AutoscalingContext{Recorder: recorder}
+	alerter := NewAlerter(Config{ScaleDownBlockedThreshold: time.Hour})
+
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n2 := BuildTestNode("n2", 1000, 1000)
+	blockedStatus := func(n1Reason simulator.UnremovableReason) *scaledownstatus.ScaleDownStatus {
+		return &scaledownstatus.ScaleDownStatus{
+			UnremovableNodes: []*scaledownstatus.UnremovableNode{
+				{Node: n1, Reason: n1Reason},
+				{Node: n2, Reason: simulator.NotUnderutilized},
+			},
+		}
+	}
+
+	now := time.Now()
+	alerter.observeScaleDown(blockedStatus(simulator.NoPlaceToMovePods))
+	alerter.evaluate(ctx, nil, now)
+	// Blocked nodes aren't rechecked for a while, they are still blocked.
+	alerter.observeScaleDown(blockedStatus(simulator.RecentlyUnremovable))
+	alerter.evaluate(ctx, nil, now.Add(30*time.Minute))
+	assert.Empty(t, recorder.Events)
+
+	// A different blocker restarts counting.
+	alerter.observeScaleDown(blockedStatus(simulator.BlockedByPod))
+	alerter.evaluate(ctx, nil, now.Add(61*time.Minute))
+	assert.Empty(t, recorder.Events)
+
+	alerter.observeScaleDown(blockedStatus(simulator.BlockedByPod))
+	alerter.evaluate(ctx, nil, now.Add(122*time.Minute))
+	if assert.Len(t, recorder.Events, 1) {
+		event := <-recorder.Events
+		assert.Contains(t, event, "Warning ScaleDownBlocked")
+		assert.Contains(t, event, "a pod can't be moved")
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	received := make(chan []Alert, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alerts []Alert
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&alerts))
+		received <- alerts
+	}))
+	defer server.Close()
+
+	alerter := NewAlerter(Config{ScaleDownBlockedThreshold: time.Minute, WebhookURL: server.URL})
+	now := time.Now()
+	alerter.observeScaleDown(&scaledownstatus.ScaleDownStatus{
+		UnremovableNodes: []*scaledownstatus.UnremovableNode{
+			{Node: BuildTestNode("n1", 1000, 1000), Reason: simulator.NoPlaceToMovePods},
+			{Node: BuildTestNode("n2", 1000, 1000), Reason: simulator.BlockedByPod},
+		},
+	})
+	alerter.evaluate(nil, nil, now)
+	alerter.evaluate(nil, nil, now.Add(2*time.Minute))
+
+	// The alerts raised in the same iteration are sent in one request.
+	select {
+	case alerts := <-received:
+		if assert.Len(t, alerts, 2) {
+			objects := []string{alerts[0].Object, alerts[1].Object}
+			assert.ElementsMatch(t, []string{"n1", "n2"}, objects)
+			assert.Equal(t, ScaleDownBlocked, alerts[0].Condition)
+			assert.True(t, alerts[0].Since.Equal(now))
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("alerts weren't sent to the webhook")
+	}
+	alerter.evaluate(nil, nil, now.Add(3*time.Minute))
+	assert.Empty(t, received)
+}
+
+func TestWebhookRetries(t *testing.T) {
+	for _, tc := range []struct {
+		desc             string
+		statuses         []int
+		expectedRequests int
+	}{
+		{
+			desc:             "server errors are retried",
+			statuses:         []int{http.StatusServiceUnavailable, http.StatusOK},
+			expectedRequests: 2,
+		},
+		{
+			desc:             "retries are bounded",
+			statuses:         []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK},
+			expectedRequests: webhookMaxAttempts,
+		},
+		{
+			desc:             "client errors aren't retried",
+			statuses:         []int{http.StatusBadRequest, http.StatusOK},
+			expectedRequests: 1,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.statuses[requests])
+				requests++
+			}))
+			defer server.Close()
+
+			webhook := &webhookClient{url: server.URL, client: server.Client(), retryBackoff: time.Millisecond}
+			webhook.deliver([]Alert{{Condition: ScaleDownBlocked, Object: "n1"}})
+			assert.Equal(t, tc.expectedRequests, requests)
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	processors := ca_processors.DefaultProcessors(config.AutoscalingOptions{})
+	NewAlerter(Config{PodPendingOnQuotaThreshold: time.Minute}).Register(processors)
+	_, ok := processors.ScaleUpStatusProcessor.(*scaleUpStatusProcessor)
+	assert.True(t, ok)
+	_, ok = processors.AutoscalingStatusProcessor.(*autoscalingStatusProcessor)
+	assert.True(t, ok)
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alerts
+
+import (
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	scaledownstatus "k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
+	ca_processors "k8s.io/autoscaler/cluster-autoscaler/processors"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
+)
+
+// Register wraps the status processors so that the alerter observes every autoscaling iteration.
+// Alerts are evaluated at the end of the iteration, after the wrapped processors ran.
+func (a *Alerter) Register(processors *ca_processors.AutoscalingProcessors) {
+	processors.ScaleUpStatusProcessor = &scaleUpStatusProcessor{ScaleUpStatusProcessor: processors.ScaleUpStatusProcessor, alerter: a}
+	processors.ScaleDownStatusProcessor = &scaleDownStatusProcessor{ScaleDownStatusProcessor: processors.ScaleDownStatusProcessor, alerter: a}
+	processors.AutoscalingStatusProcessor = &autoscalingStatusProcessor{AutoscalingStatusProcessor: processors.AutoscalingStatusProcessor, alerter: a}
+}
+
+type scaleUpStatusProcessor struct {
+	status.ScaleUpStatusProcessor
+	alerter *Alerter
+}
+
+// Process runs the wrapped processor and records pods pending due to limits.
+func (p *scaleUpStatusProcessor) Process(context *context.AutoscalingContext, scaleUpStatus *status.ScaleUpStatus) {
+	p.ScaleUpStatusProcessor.Process(context, scaleUpStatus)
+	p.alerter.observeScaleUp(scaleUpStatus)
+}
+
+type scaleDownStatusProcessor struct {
+	status.ScaleDownStatusProcessor
+	alerter *Alerter
+}
+
+// Process runs the wrapped processor and records nodes whose scale-down is blocked.
+func (p *scaleDownStatusProcessor) Process(context *context.AutoscalingContext, scaleDownStatus *scaledownstatus.ScaleDownStatus) {
+	p.ScaleDownStatusProcessor.Process(context, scaleDownStatus)
+	p.alerter.observeScaleDown(scaleDownStatus)
+}
+
+type autoscalingStatusProcessor struct {
+	status.AutoscalingStatusProcessor
+	alerter *Alerter
+}
+
+// Process runs the wrapped processor and raises the alerts whose conditions exceeded their thresholds.
+func (p *autoscalingStatusProcessor) Process(context *context.AutoscalingContext, csr *clusterstate.ClusterStateRegistry, now time.Time) error {
+	err := p.AutoscalingStatusProcessor.Process(context, csr, now)
+	p.alerter.evaluate(context, csr, now)
+	return err
+}
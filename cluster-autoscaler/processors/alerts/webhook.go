@@ -0,0 +1,124 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	klog "k8s.io/klog/v2"
+)
+
+const (
+	defaultWebhookTimeout = 10 * time.Second
+	// webhookQueueSize is how many batches of alerts can wait for delivery before new ones are dropped.
+	webhookQueueSize = 10
+	// webhookMaxAttempts is how many times the delivery of a batch is tried before it is dropped.
+	webhookMaxAttempts = 3
+	// defaultWebhookRetryBackoff is the wait before the first retry, doubled on every following one.
+	defaultWebhookRetryBackoff = 5 * time.Second
+)
+
+// webhookClient posts batches of alerts as JSON to a configured URL. Batches are delivered by a
+// background worker so that a slow or unavailable webhook doesn't hold the autoscaling loop.
+type webhookClient struct {
+	url          string
+	client       *http.Client
+	retryBackoff time.Duration
+	queue        chan []Alert
+}
+
+func newWebhookClient(url string, timeout, retryBackoff time.Duration) *webhookClient {
+	w := &webhookClient{
+		url:          url,
+		client:       &http.Client{Timeout: timeout},
+		retryBackoff: retryBackoff,
+		queue:        make(chan []Alert, webhookQueueSize),
+	}
+	go w.run()
+	return w
+}
+
+// enqueue schedules the delivery of the alerts raised in an iteration, dropping them if the queue is full.
+func (w *webhookClient) enqueue(alerts []Alert) {
+	select {
+	case w.queue <- alerts:
+	default:
+		klog.Errorf("Dropping %d alerts, the webhook delivery queue is full", len(alerts))
+	}
+}
+
+func (w *webhookClient) run() {
+	for alerts := range w.queue {
+		w.deliver(alerts)
+	}
+}
+
+// deliver sends a batch of alerts, retrying server side and network errors a bounded number of times.
+func (w *webhookClient) deliver(alerts []Alert) {
+	backoff := w.retryBackoff
+	for attempt := 1; ; attempt++ {
+		err := w.send(alerts)
+		if err == nil {
+			return
+		}
+		if attempt == webhookMaxAttempts || !isRetryable(err) {
+			klog.Errorf("Failed to send %d alerts to webhook after %d attempts: %v", len(alerts), attempt, err)
+			return
+		}
+		klog.Warningf("Failed to send %d alerts to webhook, retrying in %v: %v", len(alerts), backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// statusError is returned when the webhook responds with a non 2xx status.
+type statusError struct {
+	code   int
+	status string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("webhook responded with status %s", e.status)
+}
+
+// isRetryable returns false for client errors, which won't go away by sending the same request again.
+func isRetryable(err error) bool {
+	if statusErr, ok := err.(*statusError); ok {
+		return statusErr.code >= 500 || statusErr.code == http.StatusTooManyRequests
+	}
+	return true
+}
+
+func (w *webhookClient) send(alerts []Alert) error {
+	body, err := json.Marshal(alerts)
+	if err != nil {
+		return err
+	}
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &statusError{code: resp.StatusCode, status: resp.Status}
+	}
+	return nil
+}
@@ -116,6 +116,24 @@ func TestFilterOutNodesWithUnreadyResources(t *testing.T) {
 	nodeDirectXUnready.Status.Capacity[gpu.ResourceDirectX] = *resource.NewQuantity(0, resource.DecimalSI)
 	expectedReadiness[nodeDirectXUnready.Name] = false
 
+	nodeMigReady := &apiv1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "nodeMigReady",
+			Labels:            gpuLabels,
+			CreationTimestamp: metav1.NewTime(start),
+		},
+		Status: apiv1.NodeStatus{
+			Capacity:    apiv1.ResourceList{},
+			Allocatable: apiv1.ResourceList{},
+			Conditions:  []apiv1.NodeCondition{readyCondition},
+		},
+	}
+	nodeMigReady.Status.Allocatable[gpu.ResourceNvidiaGPU] = *resource.NewQuantity(0, resource.DecimalSI)
+	nodeMigReady.Status.Capacity[gpu.ResourceNvidiaGPU] = *resource.NewQuantity(0, resource.DecimalSI)
+	nodeMigReady.Status.Allocatable["nvidia.com/mig-1g.5gb"] = *resource.NewQuantity(7, resource.DecimalSI)
+	nodeMigReady.Status.Capacity["nvidia.com/mig-1g.5gb"] = *resource.NewQuantity(7, resource.DecimalSI)
+	expectedReadiness[nodeMigReady.Name] = true
+
 	nodeGpuUnready2 := &apiv1.Node{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:              "nodeGpuUnready2",
@@ -155,6 +173,7 @@ func TestFilterOutNodesWithUnreadyResources(t *testing.T) {
 	initialReadyNodes := []*apiv1.Node{
 		nodeGpuReady,
 		nodeGpuUnready,
+		nodeMigReady,
 		nodeGpuUnready2,
 		nodeDirectXReady,
 		nodeDirectXUnready,
@@ -163,6 +182,7 @@ func TestFilterOutNodesWithUnreadyResources(t *testing.T) {
 	initialAllNodes := []*apiv1.Node{
 		nodeGpuReady,
 		nodeGpuUnready,
+		nodeMigReady,
 		nodeGpuUnready2,
 		nodeDirectXReady,
 		nodeDirectXUnready,
@@ -37,6 +37,8 @@ type NodeGroupConfigProcessor interface {
 	GetMaxNodeProvisionTime(nodeGroup cloudprovider.NodeGroup) (time.Duration, error)
 	// GetIgnoreDaemonSetsUtilization returns IgnoreDaemonSetsUtilization value that should be used for a given NodeGroup.
 	GetIgnoreDaemonSetsUtilization(nodeGroup cloudprovider.NodeGroup) (bool, error)
+	// GetRecreationCost returns RecreationCost value that should be used for a given NodeGroup.
+	GetRecreationCost(nodeGroup cloudprovider.NodeGroup) (float64, error)
 	// CleanUp cleans up processor's internal structures.
 	CleanUp()
 }
@@ -120,6 +122,18 @@ func (p *DelegatingNodeGroupConfigProcessor) GetIgnoreDaemonSetsUtilization(node
 	return ngConfig.IgnoreDaemonSetsUtilization, nil
 }
 
+// GetRecreationCost returns RecreationCost value that should be used for a given NodeGroup.
+func (p *DelegatingNodeGroupConfigProcessor) GetRecreationCost(nodeGroup cloudprovider.NodeGroup) (float64, error) {
+	ngConfig, err := nodeGroup.GetOptions(p.nodeGroupDefaults)
+	if err != nil && err != cloudprovider.ErrNotImplemented {
+		return 0.0, err
+	}
+	if ngConfig == nil || err == cloudprovider.ErrNotImplemented {
+		return p.nodeGroupDefaults.RecreationCost, nil
+	}
+	return ngConfig.RecreationCost, nil
+}
+
 // CleanUp cleans up processor's internal structures.
 func (p *DelegatingNodeGroupConfigProcessor) CleanUp() {
 }
@@ -30,6 +30,9 @@ const AzureNodepoolLabel = "kubernetes.azure.com/agentpool"
 // AzureDiskTopologyKey is the topology key of Azure Disk CSI driver
 const AzureDiskTopologyKey = "topology.disk.csi.azure.com/zone"
 
+// AzureProximityPlacementGroupLabel is a label specifying the proximity placement group of a node
+const AzureProximityPlacementGroupLabel = "kubernetes.azure.com/proximity-placement-group"
+
 func nodesFromSameAzureNodePool(n1, n2 *schedulerframework.NodeInfo) bool {
 	n1AzureNodePool := n1.Node().Labels[AzureNodepoolLabel]
 	n2AzureNodePool := n2.Node().Labels[AzureNodepoolLabel]
@@ -58,6 +61,10 @@ func CreateAzureNodeInfoComparator(extraIgnoredLabels []string, ratioOpts config
 	}
 
 	return func(n1, n2 *schedulerframework.NodeInfo) bool {
+		// balancing across proximity placement groups would break the latency guarantees of the pods selecting them.
+		if n1.Node().Labels[AzureProximityPlacementGroupLabel] != n2.Node().Labels[AzureProximityPlacementGroupLabel] {
+			return false
+		}
 		if nodesFromSameAzureNodePool(n1, n2) {
 			return true
 		}
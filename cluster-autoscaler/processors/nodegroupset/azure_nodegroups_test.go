@@ -71,6 +71,13 @@ func TestIsAzureNodeInfoSimilar(t *testing.T) {
 	n1.ObjectMeta.Labels["example.com/ready"] = "true"
 	n2.ObjectMeta.Labels["example.com/ready"] = "false"
 	checkNodesSimilar(t, n1, n2, comparator, true)
+	// Different proximity placement groups
+	n1.ObjectMeta.Labels[AzureProximityPlacementGroupLabel] = "ppg1"
+	checkNodesSimilar(t, n1, n2, comparator, false)
+	n2.ObjectMeta.Labels[AzureProximityPlacementGroupLabel] = "ppg2"
+	checkNodesSimilar(t, n1, n2, comparator, false)
+	n2.ObjectMeta.Labels[AzureProximityPlacementGroupLabel] = "ppg1"
+	checkNodesSimilar(t, n1, n2, comparator, true)
 }
 
 func TestFindSimilarNodeGroupsAzureBasic(t *testing.T) {
@@ -152,10 +152,13 @@ func (p *MixedTemplateNodeInfoProvider) Process(ctx *context.AutoscalingContext,
 
 		// No good template, trying to generate one. This is called only if there are no
 		// working nodes in the node groups. By default CA tries to use a real-world example.
-		nodeInfo, err := utils.GetNodeInfoFromTemplate(nodeGroup, daemonsets, taintConfig)
+		nodeInfo, err := getNodeInfoFromTemplate(nodeGroup, daemonsets, taintConfig)
 		if err != nil {
 			if err == cloudprovider.ErrNotImplemented {
 				continue
+			} else if err.Type() == errors.NodeGroupPanicError {
+				// Node groups without a template are skipped by scale-up.
+				continue
 			} else {
 				klog.Errorf("Unable to build proper template node for %s: %v", id, err)
 				return map[string]*schedulerframework.NodeInfo{}, errors.ToAutoscalerError(errors.CloudProviderError, err)
@@ -194,6 +197,12 @@ func (p *MixedTemplateNodeInfoProvider) Process(ctx *context.AutoscalingContext,
 	return result, nil
 }
 
+// getNodeInfoFromTemplate builds the template node info of the node group, recovering from panics.
+func getNodeInfoFromTemplate(nodeGroup cloudprovider.NodeGroup, daemonsets []*appsv1.DaemonSet, taintConfig taints.TaintConfig) (nodeInfo *schedulerframework.NodeInfo, err errors.AutoscalerError) {
+	defer utils.RecoverNodeGroupPanic(nodeGroup.Id(), &err)
+	return utils.GetNodeInfoFromTemplate(nodeGroup, daemonsets, taintConfig)
+}
+
 func getPodsForNodes(listers kube_util.ListerRegistry) (map[string][]*apiv1.Pod, errors.AutoscalerError) {
 	pods, err := listers.AllPodLister().List()
 	if err != nil {
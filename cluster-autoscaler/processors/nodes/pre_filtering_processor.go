@@ -23,6 +23,8 @@ import (
 	klog "k8s.io/klog/v2"
 
 	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/core/orphans"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/externaldelete"
 	"k8s.io/autoscaler/cluster-autoscaler/utils"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
 )
@@ -57,6 +59,16 @@ func (n *PreFilteringScaleDownNodeProcessor) GetScaleDownCandidates(ctx *context
 			klog.V(4).Infof("Node %s should not be processed by cluster autoscaler (no node group config)", node.Name)
 			continue
 		}
+		if externaldelete.IsExternalNodeGroup(nodeGroup) {
+			// The external node group has no minimum size and is not listed among the node groups.
+			result = append(result, node)
+			continue
+		}
+		if orphans.IsOrphanedNodeGroup(nodeGroup) {
+			// Nodes of orphaned node groups are deleted by the orphaned node group policy.
+			klog.V(4).Infof("Skipping %s - node group %s is orphaned", node.Name, nodeGroup.Id())
+			continue
+		}
 		size, found := nodeGroupSize[nodeGroup.Id()]
 		if !found {
 			klog.Errorf("Error while checking node group size %s: group size not found", nodeGroup.Id())
@@ -0,0 +1,74 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recreationcost
+
+import (
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/klog/v2"
+)
+
+type recreationCostGetter interface {
+	// GetRecreationCost returns RecreationCost value that should be used for a given NodeGroup.
+	GetRecreationCost(nodeGroup cloudprovider.NodeGroup) (float64, error)
+}
+
+// RecreationCostSorting is sorting scale down candidates so that nodes of node groups
+// cheaper to recreate appear first.
+type RecreationCostSorting struct {
+	configGetter recreationCostGetter
+	costs        map[string]float64
+}
+
+// NewRecreationCostSorting returns RecreationCostSorting struct.
+func NewRecreationCostSorting(configGetter recreationCostGetter) *RecreationCostSorting {
+	return &RecreationCostSorting{
+		configGetter: configGetter,
+		costs:        make(map[string]float64),
+	}
+}
+
+// Refresh looks up the recreation cost of the node group of each candidate.
+func (p *RecreationCostSorting) Refresh(ctx *context.AutoscalingContext, candidates []*apiv1.Node) {
+	costs := make(map[string]float64, len(candidates))
+	nodeGroupCosts := make(map[string]float64)
+	for _, node := range candidates {
+		nodeGroup, err := ctx.CloudProvider.NodeGroupForNode(node)
+		if err != nil || nodeGroup == nil || nodeGroup.Id() == "" {
+			continue
+		}
+		cost, found := nodeGroupCosts[nodeGroup.Id()]
+		if !found {
+			cost, err = p.configGetter.GetRecreationCost(nodeGroup)
+			if err != nil {
+				klog.Warningf("Failed to get recreation cost of node group %s: %v", nodeGroup.Id(), err)
+				continue
+			}
+			nodeGroupCosts[nodeGroup.Id()] = cost
+		}
+		costs[node.Name] = cost
+	}
+	p.costs = costs
+}
+
+// ScaleDownEarlierThan return true if node1 is cheaper to recreate than node2.
+func (p *RecreationCostSorting) ScaleDownEarlierThan(node1, node2 *apiv1.Node) bool {
+	cost1, found1 := p.costs[node1.Name]
+	cost2, found2 := p.costs[node2.Name]
+	return found1 && found2 && cost1 < cost2
+}
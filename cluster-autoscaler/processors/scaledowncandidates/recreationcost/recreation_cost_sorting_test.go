@@ -0,0 +1,63 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recreationcost
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+type staticCosts map[string]float64
+
+func (c staticCosts) GetRecreationCost(nodeGroup cloudprovider.NodeGroup) (float64, error) {
+	return c[nodeGroup.Id()], nil
+}
+
+func TestRecreationCostSorting(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("cheap", 0, 10, 1)
+	provider.AddNodeGroup("expensive", 0, 10, 2)
+	provider.AddNodeGroup("also-cheap", 0, 10, 1)
+
+	cheap := BuildTestNode("cheap-1", 1000, 1000)
+	expensive1 := BuildTestNode("expensive-1", 1000, 1000)
+	expensive2 := BuildTestNode("expensive-2", 1000, 1000)
+	alsoCheap := BuildTestNode("also-cheap-1", 1000, 1000)
+	unknown := BuildTestNode("unknown", 1000, 1000)
+	provider.AddNode("cheap", cheap)
+	provider.AddNode("expensive", expensive1)
+	provider.AddNode("expensive", expensive2)
+	provider.AddNode("also-cheap", alsoCheap)
+
+	p := NewRecreationCostSorting(staticCosts{"cheap": 1, "also-cheap": 1, "expensive": 5})
+	p.Refresh(&context.AutoscalingContext{CloudProvider: provider}, []*apiv1.Node{cheap, expensive1, expensive2, alsoCheap, unknown})
+
+	assert.True(t, p.ScaleDownEarlierThan(cheap, expensive1))
+	assert.False(t, p.ScaleDownEarlierThan(expensive1, cheap))
+	// same cost
+	assert.False(t, p.ScaleDownEarlierThan(cheap, alsoCheap))
+	assert.False(t, p.ScaleDownEarlierThan(expensive1, expensive2))
+	// nodes without node group are not ordered
+	assert.False(t, p.ScaleDownEarlierThan(cheap, unknown))
+	assert.False(t, p.ScaleDownEarlierThan(unknown, expensive1))
+}
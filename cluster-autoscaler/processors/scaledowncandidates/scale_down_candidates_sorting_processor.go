@@ -45,7 +45,17 @@ func (p *ScaleDownCandidatesSortingProcessor) GetScaleDownCandidates(ctx *contex
 	if err != nil {
 		return candidates, err
 	}
-	n := NodeSorter{nodes: candidates, processors: p.sorting}
+	sorting := p.sorting
+	for _, comparer := range sorting {
+		if refreshable, ok := comparer.(RefreshableCandidatesComparer); ok {
+			refreshable.Refresh(ctx, candidates)
+		}
+	}
+	if ctx.NodeProblemTracker != nil {
+		// Nodes with problem conditions are preferred over any other ordering.
+		sorting = append([]CandidatesComparer{ctx.NodeProblemTracker}, sorting...)
+	}
+	n := NodeSorter{nodes: candidates, processors: sorting}
 	return n.Sort(), err
 }
 
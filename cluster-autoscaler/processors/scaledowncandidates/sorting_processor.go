@@ -20,6 +20,7 @@ import (
 	"sort"
 
 	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
 )
 
 // CandidatesComparer is an  used for sorting scale down candidates.
@@ -28,6 +29,13 @@ type CandidatesComparer interface {
 	ScaleDownEarlierThan(node1, node2 *apiv1.Node) bool
 }
 
+// RefreshableCandidatesComparer is a CandidatesComparer refreshed with the scale down candidates before they are sorted.
+type RefreshableCandidatesComparer interface {
+	CandidatesComparer
+	// Refresh updates the comparer state for the candidates.
+	Refresh(ctx *context.AutoscalingContext, candidates []*apiv1.Node)
+}
+
 // NodeSorter struct contain the list of nodes and the list of processors that should be applied for sorting.
 type NodeSorter struct {
 	nodes      []*apiv1.Node
@@ -39,7 +47,8 @@ func (n *NodeSorter) Sort() []*apiv1.Node {
 	if len(n.processors) == 0 {
 		return n.nodes
 	}
-	sort.Sort(n)
+	// Keep the order of nodes the processors consider equal.
+	sort.Stable(n)
 	return n.nodes
 }
 
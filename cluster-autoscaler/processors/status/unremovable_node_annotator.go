@@ -0,0 +1,229 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	klog "k8s.io/klog/v2"
+
+	ca_context "k8s.io/autoscaler/cluster-autoscaler/context"
+	scaledownstatus "k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
+)
+
+// UnremovableReasonAnnotationKey is the node annotation holding, in JSON, the latest reason why the node
+// can't be scaled down.
+const UnremovableReasonAnnotationKey = "cluster-autoscaler.kubernetes.io/unremovable-reason"
+
+// UnremovableReasonAnnotation is the value of the UnremovableReasonAnnotationKey annotation.
+type UnremovableReasonAnnotation struct {
+	// Reason why the node can't be scaled down, e.g. BlockedByPod.
+	Reason string `json:"reason"`
+	// BlockingPod is the namespace/name of the pod that can't be moved, for the BlockedByPod reason.
+	BlockingPod string `json:"blockingPod,omitempty"`
+	// BlockingPodReason is why the blocking pod can't be moved, e.g. NotEnoughPdb.
+	BlockingPodReason string `json:"blockingPodReason,omitempty"`
+	// Timestamp is when the reason was recorded.
+	Timestamp metav1.Time `json:"timestamp"`
+}
+
+var unremovableReasonNames = map[simulator.UnremovableReason]string{
+	simulator.NoReason:                      "NoReason",
+	simulator.ScaleDownDisabledAnnotation:   "ScaleDownDisabledAnnotation",
+	simulator.ScaleDownUnreadyDisabled:      "ScaleDownUnreadyDisabled",
+	simulator.NotAutoscaled:                 "NotAutoscaled",
+	simulator.NotUnneededLongEnough:         "NotUnneededLongEnough",
+	simulator.NotUnreadyLongEnough:          "NotUnreadyLongEnough",
+	simulator.NodeGroupMinSizeReached:       "NodeGroupMinSizeReached",
+	simulator.MinimalResourceLimitExceeded:  "MinimalResourceLimitExceeded",
+	simulator.CurrentlyBeingDeleted:         "CurrentlyBeingDeleted",
+	simulator.NotUnderutilized:              "NotUnderutilized",
+	simulator.NotUnneededOtherReason:        "NotUnneededOtherReason",
+	simulator.RecentlyUnremovable:           "RecentlyUnremovable",
+	simulator.NoPlaceToMovePods:             "NoPlaceToMovePods",
+	simulator.BlockedByPod:                  "BlockedByPod",
+	simulator.UnexpectedError:               "UnexpectedError",
+	simulator.MinReadyNodesPerDomainReached: "MinReadyNodesPerDomainReached",
+	simulator.NotNearBillingPeriodEnd:       "NotNearBillingPeriodEnd",
+}
+
+var blockingPodReasonNames = map[drain.BlockingPodReason]string{
+	drain.NoReason:                 "NoReason",
+	drain.ControllerNotFound:       "ControllerNotFound",
+	drain.MinReplicasReached:       "MinReplicasReached",
+	drain.NotReplicated:            "NotReplicated",
+	drain.LocalStorageRequested:    "LocalStorageRequested",
+	drain.NotSafeToEvictAnnotation: "NotSafeToEvictAnnotation",
+	drain.UnmovableKubeSystemPod:   "UnmovableKubeSystemPod",
+	drain.NotEnoughPdb:             "NotEnoughPdb",
+	drain.UnexpectedError:          "UnexpectedError",
+}
+
+// UnremovableNodeAnnotator is a ScaleDownStatusProcessor annotating unremovable nodes with the reason why
+// they can't be scaled down, and removing the annotation once they can. A node is annotated again at most
+// once per interval, and only if the reason changed, to limit the load on the API server.
+type UnremovableNodeAnnotator struct {
+	ScaleDownStatusProcessor
+	interval  time.Duration
+	annotated map[string]annotatedReason
+	now       func() time.Time
+}
+
+type annotatedReason struct {
+	reason  UnremovableReasonAnnotation
+	patched time.Time
+}
+
+// NewUnremovableNodeAnnotator returns an UnremovableNodeAnnotator wrapping the given processor.
+func NewUnremovableNodeAnnotator(processor ScaleDownStatusProcessor, interval time.Duration) *UnremovableNodeAnnotator {
+	return &UnremovableNodeAnnotator{
+		ScaleDownStatusProcessor: processor,
+		interval:                 interval,
+		annotated:                make(map[string]annotatedReason),
+		now:                      time.Now,
+	}
+}
+
+// Process runs the wrapped processor and updates the annotations of the nodes.
+func (p *UnremovableNodeAnnotator) Process(autoscalingContext *ca_context.AutoscalingContext, status *scaledownstatus.ScaleDownStatus) {
+	p.ScaleDownStatusProcessor.Process(autoscalingContext, status)
+	if status == nil || status.UnremovableNodes == nil {
+		// Nodes weren't evaluated for scale-down in this loop.
+		return
+	}
+
+	now := p.now()
+	unremovable := make(map[string]bool, len(status.UnremovableNodes))
+	for _, node := range status.UnremovableNodes {
+		if node.Node == nil {
+			continue
+		}
+		name := node.Node.Name
+		unremovable[name] = true
+		if node.Reason == simulator.RecentlyUnremovable {
+			// The node isn't rechecked for a while, it is still blocked by whatever blocked it before.
+			continue
+		}
+		reason := unremovableReasonAnnotation(node)
+		previous, found := p.annotated[name]
+		if !found {
+			previous, found = annotatedReasonOf(node.Node)
+		}
+		if found && (sameReason(previous.reason, reason) || now.Sub(previous.patched) < p.interval) {
+			p.annotated[name] = previous
+			continue
+		}
+		reason.Timestamp = metav1.NewTime(now)
+		if err := p.annotate(autoscalingContext, name, &reason); err != nil {
+			klog.Warningf("Failed to annotate node %s with the reason it can't be scaled down: %v", name, err)
+			continue
+		}
+		p.annotated[name] = annotatedReason{reason: reason, patched: now}
+	}
+
+	// Clear the annotations of the nodes that can be scaled down now, including the ones annotated before
+	// a restart.
+	removable := make(map[string]bool)
+	for name := range p.annotated {
+		if !unremovable[name] {
+			removable[name] = true
+			delete(p.annotated, name)
+		}
+	}
+	nodes, err := autoscalingContext.AllNodeLister().List()
+	if err != nil {
+		klog.Warningf("Failed to list nodes to clear the reasons they can't be scaled down: %v", err)
+	}
+	for _, node := range nodes {
+		if _, found := node.Annotations[UnremovableReasonAnnotationKey]; found && !unremovable[node.Name] {
+			removable[node.Name] = true
+		}
+	}
+	for name := range removable {
+		if err := p.annotate(autoscalingContext, name, nil); err != nil {
+			klog.Warningf("Failed to clear the reason node %s can't be scaled down: %v", name, err)
+		}
+	}
+}
+
+// annotate sets the unremovable reason annotation of the node, or removes it if reason is nil.
+func (p *UnremovableNodeAnnotator) annotate(autoscalingContext *ca_context.AutoscalingContext, name string, reason *UnremovableReasonAnnotation) error {
+	var value interface{}
+	if reason != nil {
+		encoded, err := json.Marshal(reason)
+		if err != nil {
+			return err
+		}
+		value = string(encoded)
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{UnremovableReasonAnnotationKey: value},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = autoscalingContext.ClientSet.CoreV1().Nodes().Patch(context.TODO(), name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// unremovableReasonAnnotation returns the annotation of the unremovable node, without timestamp.
+func unremovableReasonAnnotation(node *scaledownstatus.UnremovableNode) UnremovableReasonAnnotation {
+	reason := UnremovableReasonAnnotation{Reason: unremovableReasonName(node.Reason)}
+	if node.BlockingPod != nil && node.BlockingPod.Pod != nil {
+		reason.BlockingPod = fmt.Sprintf("%s/%s", node.BlockingPod.Pod.Namespace, node.BlockingPod.Pod.Name)
+		reason.BlockingPodReason = blockingPodReasonNames[node.BlockingPod.Reason]
+		if reason.BlockingPodReason == "" {
+			reason.BlockingPodReason = fmt.Sprintf("%d", node.BlockingPod.Reason)
+		}
+	}
+	return reason
+}
+
+func unremovableReasonName(reason simulator.UnremovableReason) string {
+	if name, found := unremovableReasonNames[reason]; found {
+		return name
+	}
+	return fmt.Sprintf("%d", reason)
+}
+
+// annotatedReasonOf returns the reason the node is annotated with, e.g. by a previous autoscaler instance.
+func annotatedReasonOf(node *apiv1.Node) (annotatedReason, bool) {
+	value, found := node.Annotations[UnremovableReasonAnnotationKey]
+	if !found {
+		return annotatedReason{}, false
+	}
+	var reason UnremovableReasonAnnotation
+	if err := json.Unmarshal([]byte(value), &reason); err != nil {
+		klog.Warningf("Ignoring invalid %s annotation of node %s: %v", UnremovableReasonAnnotationKey, node.Name, err)
+		return annotatedReason{}, false
+	}
+	return annotatedReason{reason: reason, patched: reason.Timestamp.Time}, true
+}
+
+func sameReason(a, b UnremovableReasonAnnotation) bool {
+	return a.Reason == b.Reason && a.BlockingPod == b.BlockingPod && a.BlockingPodReason == b.BlockingPodReason
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	ca_context "k8s.io/autoscaler/cluster-autoscaler/context"
+	scaledownstatus "k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func TestUnremovableNodeAnnotator(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n2 := BuildTestNode("n2", 1000, 1000)
+	n3 := BuildTestNode("n3", 1000, 1000)
+	n3.Annotations = map[string]string{UnremovableReasonAnnotationKey: `{"reason":"NotUnderutilized","timestamp":"2023-06-01T09:00:00Z"}`}
+	nodes := []*apiv1.Node{n1, n2, n3}
+	pod := BuildTestPod("p1", 100, 0)
+
+	client := fake.NewSimpleClientset(n1, n2, n3)
+	autoscalingContext := &ca_context.AutoscalingContext{
+		ClientSet:      client,
+		ListerRegistry: kube_util.NewListerRegistry(kube_util.NewTestNodeLister(nodes), nil, nil, nil, nil, nil, nil, nil, nil),
+	}
+	now := time.Date(2023, 6, 1, 10, 0, 0, 0, time.Local)
+	p := NewUnremovableNodeAnnotator(NewDefaultScaleDownStatusProcessor(), 5*time.Minute)
+	p.now = func() time.Time { return now }
+
+	annotation := func(name string) *UnremovableReasonAnnotation {
+		node, err := client.CoreV1().Nodes().Get(context.TODO(), name, metav1.GetOptions{})
+		assert.NoError(t, err)
+		value, found := node.Annotations[UnremovableReasonAnnotationKey]
+		if !found {
+			return nil
+		}
+		reason := &UnremovableReasonAnnotation{}
+		assert.NoError(t, json.Unmarshal([]byte(value), reason))
+		return reason
+	}
+	process := func(unremovable ...*scaledownstatus.UnremovableNode) {
+		p.Process(autoscalingContext, &scaledownstatus.ScaleDownStatus{UnremovableNodes: unremovable})
+	}
+
+	process(
+		&scaledownstatus.UnremovableNode{Node: n1, Reason: simulator.BlockedByPod, BlockingPod: &drain.BlockingPod{Pod: pod, Reason: drain.NotEnoughPdb}},
+		&scaledownstatus.UnremovableNode{Node: n2, Reason: simulator.NotUnderutilized},
+	)
+	assert.Equal(t, &UnremovableReasonAnnotation{Reason: "BlockedByPod", BlockingPod: "default/p1", BlockingPodReason: "NotEnoughPdb", Timestamp: metav1.NewTime(now)}, annotation("n1"))
+	assert.Equal(t, &UnremovableReasonAnnotation{Reason: "NotUnderutilized", Timestamp: metav1.NewTime(now)}, annotation("n2"))
+	// n3 can be scaled down now.
+	assert.Nil(t, annotation("n3"))
+
+	// Nodes not evaluated in this loop keep their annotations.
+	p.Process(autoscalingContext, &scaledownstatus.ScaleDownStatus{Result: scaledownstatus.ScaleDownInCooldown})
+	assert.NotNil(t, annotation("n1"))
+
+	// A new reason is recorded once the interval passed, a recently unremovable node keeps its reason.
+	updated := now
+	now = now.Add(time.Minute)
+	process(
+		&scaledownstatus.UnremovableNode{Node: n1, Reason: simulator.RecentlyUnremovable},
+		&scaledownstatus.UnremovableNode{Node: n2, Reason: simulator.NoPlaceToMovePods},
+	)
+	assert.Equal(t, "BlockedByPod", annotation("n1").Reason)
+	assert.Equal(t, &UnremovableReasonAnnotation{Reason: "NotUnderutilized", Timestamp: metav1.NewTime(updated)}, annotation("n2"))
+
+	now = now.Add(5 * time.Minute)
+	process(
+		&scaledownstatus.UnremovableNode{Node: n1, Reason: simulator.RecentlyUnremovable},
+		&scaledownstatus.UnremovableNode{Node: n2, Reason: simulator.NoPlaceToMovePods},
+	)
+	assert.Equal(t, "BlockedByPod", annotation("n1").Reason)
+	assert.Equal(t, &UnremovableReasonAnnotation{Reason: "NoPlaceToMovePods", Timestamp: metav1.NewTime(now)}, annotation("n2"))
+
+	// n1 is removable again.
+	process(&scaledownstatus.UnremovableNode{Node: n2, Reason: simulator.NoPlaceToMovePods})
+	assert.Nil(t, annotation("n1"))
+	assert.NotNil(t, annotation("n2"))
+}
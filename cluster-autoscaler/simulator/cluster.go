@@ -34,6 +34,8 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 
 	klog "k8s.io/klog/v2"
+
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
 )
 
 // NodeToBeRemoved contain information about a node that can be removed.
@@ -89,6 +91,11 @@ const (
 	BlockedByPod
 	// UnexpectedError - node can't be removed because of an unexpected error.
 	UnexpectedError
+	// MinReadyNodesPerDomainReached - node can't be removed because a topology domain of the node, e.g. its zone, would
+	// be left with less Ready nodes than configured.
+	MinReadyNodesPerDomainReached
+	// NotNearBillingPeriodEnd - node can't be removed yet because the billing period it was paid for isn't about to end.
+	NotNearBillingPeriodEnd
 )
 
 // RemovalSimulator is a helper object for simulating node removal scenarios.
@@ -156,16 +163,16 @@ func (r *RemovalSimulator) SimulateNodeRemoval(
 	if err != nil {
 		klog.Errorf("Can't retrieve node %s from snapshot, err: %v", nodeName, err)
 	}
-	klog.V(2).Infof("Simulating node %s removal", nodeName)
+	klogx.Simulator.V(2).Infof("Simulating node %s removal", nodeName)
 
 	if _, found := destinationMap[nodeName]; !found {
-		klog.V(2).Infof("nodeInfo for %s not found", nodeName)
+		klogx.Simulator.V(2).Infof("nodeInfo for %s not found", nodeName)
 		return nil, &UnremovableNode{Node: nodeInfo.Node(), Reason: UnexpectedError}
 	}
 
 	podsToRemove, daemonSetPods, blockingPod, err := GetPodsToMove(nodeInfo, r.deleteOptions, r.drainabilityRules, r.listers, remainingPdbTracker, timestamp)
 	if err != nil {
-		klog.V(2).Infof("node %s cannot be removed: %v", nodeName, err)
+		klogx.Simulator.V(2).Infof("node %s cannot be removed: %v", nodeName, err)
 		if blockingPod != nil {
 			return nil, &UnremovableNode{Node: nodeInfo.Node(), Reason: BlockedByPod, BlockingPod: blockingPod}
 		}
@@ -176,10 +183,10 @@ func (r *RemovalSimulator) SimulateNodeRemoval(
 		return r.findPlaceFor(nodeName, podsToRemove, destinationMap, timestamp)
 	})
 	if err != nil {
-		klog.V(2).Infof("node %s is not suitable for removal: %v", nodeName, err)
+		klogx.Simulator.V(2).Infof("node %s is not suitable for removal: %v", nodeName, err)
 		return nil, &UnremovableNode{Node: nodeInfo.Node(), Reason: NoPlaceToMovePods}
 	}
-	klog.V(2).Infof("node %s may be removed", nodeName)
+	klogx.Simulator.V(2).Infof("node %s may be removed", nodeName)
 	return &NodeToBeRemoved{
 		Node:             nodeInfo.Node(),
 		PodsToReschedule: podsToRemove,
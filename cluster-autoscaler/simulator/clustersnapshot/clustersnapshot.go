@@ -18,6 +18,7 @@ package clustersnapshot
 
 import (
 	"errors"
+	"fmt"
 
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/klog/v2"
@@ -57,6 +58,29 @@ type ClusterSnapshot interface {
 // ErrNodeNotFound means that a node wasn't found in the snapshot.
 var ErrNodeNotFound = errors.New("node not found")
 
+const (
+	// BasicClusterSnapshotType selects BasicClusterSnapshot.
+	BasicClusterSnapshotType = "basic"
+	// DeltaClusterSnapshotType selects DeltaClusterSnapshot.
+	DeltaClusterSnapshotType = "delta"
+	// CompactClusterSnapshotType selects CompactClusterSnapshot.
+	CompactClusterSnapshotType = "compact"
+)
+
+// NewClusterSnapshot creates a ClusterSnapshot of the given type.
+func NewClusterSnapshot(snapshotType string) (ClusterSnapshot, error) {
+	switch snapshotType {
+	case BasicClusterSnapshotType:
+		return NewBasicClusterSnapshot(), nil
+	case DeltaClusterSnapshotType:
+		return NewDeltaClusterSnapshot(), nil
+	case CompactClusterSnapshotType:
+		return NewCompactClusterSnapshot(), nil
+	default:
+		return nil, fmt.Errorf("unknown cluster snapshot type %q", snapshotType)
+	}
+}
+
 // WithForkedSnapshot is a helper function for snapshot that makes sure all Fork() calls are closed with Commit() or Revert() calls.
 // The function return (error, error) pair. The first error comes from the passed function, the second error indicate the success of the function itself.
 func WithForkedSnapshot(snapshot ClusterSnapshot, f func() (bool, error)) (error, error) {
@@ -267,3 +267,53 @@ func BenchmarkBuildNodeInfoList(b *testing.B) {
 		})
 	}
 }
+
+func BenchmarkLargeClusterSimulation(b *testing.B) {
+	nodeCount := 15000
+	podsPerNode := 30
+
+	for snapshotName, snapshotFactory := range snapshots {
+		nodes := createTestNodes(nodeCount)
+		pods := createTestPods(nodeCount * podsPerNode)
+		assignPodsToNodes(pods, nodes)
+		newPod := BuildTestPod("new-pod", 100, 100)
+
+		b.Run(fmt.Sprintf("%s: fill snapshot (%d nodes, %d pods)", snapshotName, nodeCount, len(pods)), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				clusterSnapshot := snapshotFactory()
+				if err := clusterSnapshot.AddNodes(nodes); err != nil {
+					assert.NoError(b, err)
+				}
+				for _, pod := range pods {
+					if err := clusterSnapshot.AddPod(pod, pod.Spec.NodeName); err != nil {
+						assert.NoError(b, err)
+					}
+				}
+			}
+		})
+
+		clusterSnapshot := snapshotFactory()
+		if err := clusterSnapshot.AddNodes(nodes); err != nil {
+			assert.NoError(b, err)
+		}
+		for _, pod := range pods {
+			if err := clusterSnapshot.AddPod(pod, pod.Spec.NodeName); err != nil {
+				assert.NoError(b, err)
+			}
+		}
+		b.Run(fmt.Sprintf("%s: fork add pod list revert (%d nodes, %d pods)", snapshotName, nodeCount, len(pods)), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				clusterSnapshot.Fork()
+				if err := clusterSnapshot.AddPod(newPod, nodes[i%nodeCount].Name); err != nil {
+					assert.NoError(b, err)
+				}
+				if _, err := clusterSnapshot.NodeInfos().List(); err != nil {
+					assert.NoError(b, err)
+				}
+				clusterSnapshot.Revert()
+			}
+		})
+	}
+}
@@ -30,8 +30,9 @@ import (
 )
 
 var snapshots = map[string]func() ClusterSnapshot{
-	"basic": func() ClusterSnapshot { return NewBasicClusterSnapshot() },
-	"delta": func() ClusterSnapshot { return NewDeltaClusterSnapshot() },
+	"basic":   func() ClusterSnapshot { return NewBasicClusterSnapshot() },
+	"delta":   func() ClusterSnapshot { return NewDeltaClusterSnapshot() },
+	"compact": func() ClusterSnapshot { return NewCompactClusterSnapshot() },
 }
 
 func nodeNames(nodes []*apiv1.Node) []string {
@@ -0,0 +1,577 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clustersnapshot
+
+import (
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// CompactClusterSnapshot is an implementation of ClusterSnapshot aimed at very large clusters (10k+ nodes).
+//
+// Nodes and pods are kept in slabs - flat arrays of small structs indexed by slot - instead of
+// NodeInfo objects, and node names and PVC keys are interned. NodeInfos are only built when
+// requested by the scheduler framework and cached until the node is modified. Slabs are split in
+// fixed size chunks shared between forks: forking copies only the chunk pointers and a chunk is
+// copied when a fork first modifies it, so a fork costs memory proportional to what it changes.
+//
+// Complexity of some notable operations:
+//
+//	fork - O(n / chunk size)
+//	revert - O(1)
+//	commit - O(1)
+//	modification - O(chunk size) on the first modification of a chunk in a fork, O(1) afterwards
+//	get node info - O(pods on node) on first access, cached
+//	list node infos - O(n), cached
+//
+// Watch out for:
+//
+//	NodeInfos returned by the snapshot are not updated when the snapshot is modified,
+//		they have to be retrieved again.
+type CompactClusterSnapshot struct {
+	strings *stringInterner
+	data    []*compactSnapshotData
+}
+
+type compactSnapshotNodeLister CompactClusterSnapshot
+type compactSnapshotStorageLister CompactClusterSnapshot
+
+// compactChunkSize is the number of slots per chunk, the unit of copying on modification in a fork.
+const compactChunkSize = 256
+
+// stringInterner maps strings to dense int32 ids. It is append-only and shared by all forks.
+type stringInterner struct {
+	ids     map[string]int32
+	strings []string
+}
+
+func newStringInterner() *stringInterner {
+	return &stringInterner{ids: make(map[string]int32)}
+}
+
+// intern returns the id of s, assigning a new one if s wasn't seen before.
+func (i *stringInterner) intern(s string) int32 {
+	if id, found := i.ids[s]; found {
+		return id
+	}
+	id := int32(len(i.strings))
+	i.ids[s] = id
+	i.strings = append(i.strings, s)
+	return id
+}
+
+// lookup returns the id of s without interning it.
+func (i *stringInterner) lookup(s string) (int32, bool) {
+	id, found := i.ids[s]
+	return id, found
+}
+
+type compactNode struct {
+	// node is nil for free slots.
+	node *apiv1.Node
+	// pods are the slots of the pods scheduled on the node. The slice may be shared with
+	// other forks, so it is never modified in place.
+	pods                         []int32
+	podsWithAffinity             int32
+	podsWithRequiredAntiAffinity int32
+	// nodeInfo caches the NodeInfo of the node. Cached NodeInfos are never modified, the
+	// entry is dropped instead, so they can be shared between forks.
+	nodeInfo *schedulerframework.NodeInfo
+}
+
+type compactPod struct {
+	// pod is nil for free slots.
+	pod *apiv1.Pod
+	// pvcs are the interned <namespace>/<claim name> keys of PVCs used by the pod.
+	pvcs []int32
+}
+
+// Chunks are only modified by the fork that owns them, other forks copy them first.
+
+type nodeChunk struct {
+	owner *compactSnapshotData
+	nodes [compactChunkSize]compactNode
+}
+
+type podChunk struct {
+	owner *compactSnapshotData
+	pods  [compactChunkSize]compactPod
+}
+
+type counterChunk struct {
+	owner  *compactSnapshotData
+	counts [compactChunkSize]int32
+}
+
+// counterSlab maps dense ids to int32 counters, zero for ids that were never set.
+type counterSlab []*counterChunk
+
+func (slab counterSlab) get(id int32) int32 {
+	if int(id/compactChunkSize) >= len(slab) {
+		return 0
+	}
+	return slab[id/compactChunkSize].counts[id%compactChunkSize]
+}
+
+// mutable returns a pointer to the counter of id in a chunk owned by data, growing the slab if needed.
+func (slab *counterSlab) mutable(data *compactSnapshotData, id int32) *int32 {
+	for int(id/compactChunkSize) >= len(*slab) {
+		*slab = append(*slab, &counterChunk{owner: data})
+	}
+	chunk := (*slab)[id/compactChunkSize]
+	if chunk.owner != data {
+		copied := *chunk
+		copied.owner = data
+		chunk = &copied
+		(*slab)[id/compactChunkSize] = chunk
+	}
+	return &chunk.counts[id%compactChunkSize]
+}
+
+type compactSnapshotData struct {
+	nodes     []*nodeChunk
+	nodeSlots int32
+	nodeCount int
+	pods      []*podChunk
+	podSlots  int32
+	// freeNodes and freePods are copied on the first modification in a fork.
+	freeNodes     []int32
+	freePods      []int32
+	ownsFreeSlots bool
+
+	// nodeIndex maps interned node names to node slots + 1.
+	nodeIndex counterSlab
+	// pvcUsage counts pods using each PVC, by interned <namespace>/<claim name> key.
+	pvcUsage counterSlab
+
+	// nodeInfos caches NodeInfos of nodes in chunks not owned by this fork, so that reads don't copy chunks.
+	nodeInfos                        map[int32]*schedulerframework.NodeInfo
+	nodeInfoList                     []*schedulerframework.NodeInfo
+	havePodsWithAffinity             []*schedulerframework.NodeInfo
+	havePodsWithRequiredAntiAffinity []*schedulerframework.NodeInfo
+}
+
+func newCompactSnapshotData() *compactSnapshotData {
+	return &compactSnapshotData{
+		ownsFreeSlots: true,
+		nodeInfos:     make(map[int32]*schedulerframework.NodeInfo),
+	}
+}
+
+// fork returns data sharing all chunks with this one.
+func (data *compactSnapshotData) fork() *compactSnapshotData {
+	return &compactSnapshotData{
+		nodes:                            append([]*nodeChunk(nil), data.nodes...),
+		nodeSlots:                        data.nodeSlots,
+		nodeCount:                        data.nodeCount,
+		pods:                             append([]*podChunk(nil), data.pods...),
+		podSlots:                         data.podSlots,
+		freeNodes:                        data.freeNodes,
+		freePods:                         data.freePods,
+		nodeIndex:                        append(counterSlab(nil), data.nodeIndex...),
+		pvcUsage:                         append(counterSlab(nil), data.pvcUsage...),
+		nodeInfos:                        make(map[int32]*schedulerframework.NodeInfo),
+		nodeInfoList:                     data.nodeInfoList,
+		havePodsWithAffinity:             data.havePodsWithAffinity,
+		havePodsWithRequiredAntiAffinity: data.havePodsWithRequiredAntiAffinity,
+	}
+}
+
+// node returns the node in the given slot. It must not be modified.
+func (data *compactSnapshotData) node(slot int32) *compactNode {
+	return &data.nodes[slot/compactChunkSize].nodes[slot%compactChunkSize]
+}
+
+// mutableNode returns the node in the given slot from a chunk owned by data and drops cached NodeInfos.
+func (data *compactSnapshotData) mutableNode(slot int32) *compactNode {
+	chunk := data.nodes[slot/compactChunkSize]
+	if chunk.owner != data {
+		copied := *chunk
+		copied.owner = data
+		chunk = &copied
+		data.nodes[slot/compactChunkSize] = chunk
+	}
+	node := &chunk.nodes[slot%compactChunkSize]
+	node.nodeInfo = nil
+	delete(data.nodeInfos, slot)
+	data.nodeInfoList = nil
+	data.havePodsWithAffinity = nil
+	data.havePodsWithRequiredAntiAffinity = nil
+	return node
+}
+
+func (data *compactSnapshotData) pod(slot int32) *compactPod {
+	return &data.pods[slot/compactChunkSize].pods[slot%compactChunkSize]
+}
+
+func (data *compactSnapshotData) mutablePod(slot int32) *compactPod {
+	chunk := data.pods[slot/compactChunkSize]
+	if chunk.owner != data {
+		copied := *chunk
+		copied.owner = data
+		chunk = &copied
+		data.pods[slot/compactChunkSize] = chunk
+	}
+	return &chunk.pods[slot%compactChunkSize]
+}
+
+func (data *compactSnapshotData) ensureOwnsFreeSlots() {
+	if !data.ownsFreeSlots {
+		data.freeNodes = append([]int32(nil), data.freeNodes...)
+		data.freePods = append([]int32(nil), data.freePods...)
+		data.ownsFreeSlots = true
+	}
+}
+
+// allocateNodeSlot returns a free node slot, reusing freed ones first.
+func (data *compactSnapshotData) allocateNodeSlot() int32 {
+	if n := len(data.freeNodes); n > 0 {
+		data.ensureOwnsFreeSlots()
+		slot := data.freeNodes[n-1]
+		data.freeNodes = data.freeNodes[:n-1]
+		return slot
+	}
+	slot := data.nodeSlots
+	if int(slot/compactChunkSize) >= len(data.nodes) {
+		data.nodes = append(data.nodes, &nodeChunk{owner: data})
+	}
+	data.nodeSlots++
+	return slot
+}
+
+// allocatePodSlot returns a free pod slot, reusing freed ones first.
+func (data *compactSnapshotData) allocatePodSlot() int32 {
+	if n := len(data.freePods); n > 0 {
+		data.ensureOwnsFreeSlots()
+		slot := data.freePods[n-1]
+		data.freePods = data.freePods[:n-1]
+		return slot
+	}
+	slot := data.podSlots
+	if int(slot/compactChunkSize) >= len(data.pods) {
+		data.pods = append(data.pods, &podChunk{owner: data})
+	}
+	data.podSlots++
+	return slot
+}
+
+func (data *compactSnapshotData) nodeSlot(strings *stringInterner, nodeName string) (int32, bool) {
+	id, found := strings.lookup(nodeName)
+	if !found {
+		return 0, false
+	}
+	slot := data.nodeIndex.get(id) - 1
+	return slot, slot >= 0
+}
+
+func (data *compactSnapshotData) addNode(strings *stringInterner, node *apiv1.Node) error {
+	id := strings.intern(node.Name)
+	if data.nodeIndex.get(id) > 0 {
+		return fmt.Errorf("node %s already in snapshot", node.Name)
+	}
+	slot := data.allocateNodeSlot()
+	*data.mutableNode(slot) = compactNode{node: node}
+	*data.nodeIndex.mutable(data, id) = slot + 1
+	data.nodeCount++
+	return nil
+}
+
+func (data *compactSnapshotData) removeNode(strings *stringInterner, nodeName string) error {
+	slot, found := data.nodeSlot(strings, nodeName)
+	if !found {
+		return ErrNodeNotFound
+	}
+	for _, podSlot := range data.node(slot).pods {
+		data.freePod(podSlot)
+	}
+	id, _ := strings.lookup(nodeName)
+	*data.nodeIndex.mutable(data, id) = 0
+	*data.mutableNode(slot) = compactNode{}
+	data.ensureOwnsFreeSlots()
+	data.freeNodes = append(data.freeNodes, slot)
+	data.nodeCount--
+	return nil
+}
+
+func (data *compactSnapshotData) addPod(strings *stringInterner, pod *apiv1.Pod, nodeName string) error {
+	slot, found := data.nodeSlot(strings, nodeName)
+	if !found {
+		return ErrNodeNotFound
+	}
+
+	entry := compactPod{pod: pod}
+	for _, volume := range pod.Spec.Volumes {
+		if volume.PersistentVolumeClaim == nil {
+			continue
+		}
+		key := strings.intern(schedulerframework.GetNamespacedName(pod.Namespace, volume.PersistentVolumeClaim.ClaimName))
+		entry.pvcs = append(entry.pvcs, key)
+		*data.pvcUsage.mutable(data, key)++
+	}
+	podSlot := data.allocatePodSlot()
+	*data.mutablePod(podSlot) = entry
+
+	node := data.mutableNode(slot)
+	// Capping the capacity forces a copy, so that forks sharing the slice are not affected.
+	node.pods = append(node.pods[:len(node.pods):len(node.pods)], podSlot)
+	if hasPodAffinity(pod) {
+		node.podsWithAffinity++
+	}
+	if hasRequiredPodAntiAffinity(pod) {
+		node.podsWithRequiredAntiAffinity++
+	}
+	return nil
+}
+
+func (data *compactSnapshotData) removePod(strings *stringInterner, namespace, podName, nodeName string) error {
+	slot, found := data.nodeSlot(strings, nodeName)
+	if !found {
+		return ErrNodeNotFound
+	}
+	for i, podSlot := range data.node(slot).pods {
+		pod := data.pod(podSlot).pod
+		if pod.Namespace != namespace || pod.Name != podName {
+			continue
+		}
+		node := data.mutableNode(slot)
+		pods := make([]int32, 0, len(node.pods)-1)
+		pods = append(pods, node.pods[:i]...)
+		node.pods = append(pods, node.pods[i+1:]...)
+		if hasPodAffinity(pod) {
+			node.podsWithAffinity--
+		}
+		if hasRequiredPodAntiAffinity(pod) {
+			node.podsWithRequiredAntiAffinity--
+		}
+		data.freePod(podSlot)
+		return nil
+	}
+	return fmt.Errorf("pod %s/%s not in snapshot", namespace, podName)
+}
+
+func (data *compactSnapshotData) freePod(podSlot int32) {
+	for _, key := range data.pod(podSlot).pvcs {
+		if usage := data.pvcUsage.mutable(data, key); *usage > 0 {
+			*usage--
+		}
+	}
+	*data.mutablePod(podSlot) = compactPod{}
+	data.ensureOwnsFreeSlots()
+	data.freePods = append(data.freePods, podSlot)
+}
+
+func (data *compactSnapshotData) isPVCUsedByPods(strings *stringInterner, key string) bool {
+	id, found := strings.lookup(key)
+	return found && data.pvcUsage.get(id) > 0
+}
+
+// nodeInfo returns the NodeInfo of the given node slot, building it if it's not cached.
+func (data *compactSnapshotData) nodeInfo(slot int32) *schedulerframework.NodeInfo {
+	node := data.node(slot)
+	if node.nodeInfo != nil {
+		return node.nodeInfo
+	}
+	if nodeInfo, found := data.nodeInfos[slot]; found {
+		return nodeInfo
+	}
+	pods := make([]*apiv1.Pod, len(node.pods))
+	for i, podSlot := range node.pods {
+		pods[i] = data.pod(podSlot).pod
+	}
+	nodeInfo := schedulerframework.NewNodeInfo(pods...)
+	nodeInfo.SetNode(node.node)
+	if data.nodes[slot/compactChunkSize].owner == data {
+		node.nodeInfo = nodeInfo
+	} else {
+		data.nodeInfos[slot] = nodeInfo
+	}
+	return nodeInfo
+}
+
+func (data *compactSnapshotData) getNodeInfo(strings *stringInterner, nodeName string) (*schedulerframework.NodeInfo, error) {
+	slot, found := data.nodeSlot(strings, nodeName)
+	if !found {
+		return nil, ErrNodeNotFound
+	}
+	return data.nodeInfo(slot), nil
+}
+
+func (data *compactSnapshotData) listNodeInfos(filter func(*compactNode) bool) []*schedulerframework.NodeInfo {
+	nodeInfos := make([]*schedulerframework.NodeInfo, 0, data.nodeCount)
+	for slot := int32(0); slot < data.nodeSlots; slot++ {
+		node := data.node(slot)
+		if node.node == nil || (filter != nil && !filter(node)) {
+			continue
+		}
+		nodeInfos = append(nodeInfos, data.nodeInfo(slot))
+	}
+	return nodeInfos
+}
+
+func hasPodAffinity(pod *apiv1.Pod) bool {
+	affinity := pod.Spec.Affinity
+	return affinity != nil && (affinity.PodAffinity != nil || affinity.PodAntiAffinity != nil)
+}
+
+func hasRequiredPodAntiAffinity(pod *apiv1.Pod) bool {
+	affinity := pod.Spec.Affinity
+	return affinity != nil && affinity.PodAntiAffinity != nil && len(affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution) > 0
+}
+
+// NewCompactClusterSnapshot creates instances of CompactClusterSnapshot.
+func NewCompactClusterSnapshot() *CompactClusterSnapshot {
+	snapshot := &CompactClusterSnapshot{}
+	snapshot.Clear()
+	return snapshot
+}
+
+func (snapshot *CompactClusterSnapshot) getInternalData() *compactSnapshotData {
+	return snapshot.data[len(snapshot.data)-1]
+}
+
+// AddNode adds node to the snapshot.
+func (snapshot *CompactClusterSnapshot) AddNode(node *apiv1.Node) error {
+	return snapshot.getInternalData().addNode(snapshot.strings, node)
+}
+
+// AddNodes adds nodes in batch to the snapshot.
+func (snapshot *CompactClusterSnapshot) AddNodes(nodes []*apiv1.Node) error {
+	for _, node := range nodes {
+		if err := snapshot.AddNode(node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddNodeWithPods adds a node and set of pods to be scheduled to this node to the snapshot.
+func (snapshot *CompactClusterSnapshot) AddNodeWithPods(node *apiv1.Node, pods []*apiv1.Pod) error {
+	if err := snapshot.AddNode(node); err != nil {
+		return err
+	}
+	for _, pod := range pods {
+		if err := snapshot.AddPod(pod, node.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveNode removes nodes (and pods scheduled to it) from the snapshot.
+func (snapshot *CompactClusterSnapshot) RemoveNode(nodeName string) error {
+	return snapshot.getInternalData().removeNode(snapshot.strings, nodeName)
+}
+
+// AddPod adds pod to the snapshot and schedules it to given node.
+func (snapshot *CompactClusterSnapshot) AddPod(pod *apiv1.Pod, nodeName string) error {
+	return snapshot.getInternalData().addPod(snapshot.strings, pod, nodeName)
+}
+
+// RemovePod removes pod from the snapshot.
+func (snapshot *CompactClusterSnapshot) RemovePod(namespace, podName, nodeName string) error {
+	return snapshot.getInternalData().removePod(snapshot.strings, namespace, podName, nodeName)
+}
+
+// IsPVCUsedByPods returns if the pvc is used by any pod
+func (snapshot *CompactClusterSnapshot) IsPVCUsedByPods(key string) bool {
+	return snapshot.getInternalData().isPVCUsedByPods(snapshot.strings, key)
+}
+
+// Fork creates a fork of snapshot state. All modifications can later be reverted to moment of forking via Revert()
+func (snapshot *CompactClusterSnapshot) Fork() {
+	snapshot.data = append(snapshot.data, snapshot.getInternalData().fork())
+}
+
+// Revert reverts snapshot state to moment of forking.
+func (snapshot *CompactClusterSnapshot) Revert() {
+	if len(snapshot.data) == 1 {
+		return
+	}
+	snapshot.data = snapshot.data[:len(snapshot.data)-1]
+}
+
+// Commit commits changes done after forking.
+func (snapshot *CompactClusterSnapshot) Commit() error {
+	if len(snapshot.data) <= 1 {
+		// do nothing
+		return nil
+	}
+	snapshot.data = append(snapshot.data[:len(snapshot.data)-2], snapshot.data[len(snapshot.data)-1])
+	return nil
+}
+
+// Clear reset cluster snapshot to empty, unforked state
+func (snapshot *CompactClusterSnapshot) Clear() {
+	snapshot.strings = newStringInterner()
+	snapshot.data = []*compactSnapshotData{newCompactSnapshotData()}
+}
+
+// implementation of SharedLister interface
+
+// NodeInfos exposes snapshot as NodeInfoLister.
+func (snapshot *CompactClusterSnapshot) NodeInfos() schedulerframework.NodeInfoLister {
+	return (*compactSnapshotNodeLister)(snapshot)
+}
+
+// StorageInfos exposes snapshot as StorageInfoLister.
+func (snapshot *CompactClusterSnapshot) StorageInfos() schedulerframework.StorageInfoLister {
+	return (*compactSnapshotStorageLister)(snapshot)
+}
+
+// List returns the list of nodes in the snapshot.
+func (snapshot *compactSnapshotNodeLister) List() ([]*schedulerframework.NodeInfo, error) {
+	data := (*CompactClusterSnapshot)(snapshot).getInternalData()
+	if data.nodeInfoList == nil {
+		data.nodeInfoList = data.listNodeInfos(nil)
+	}
+	return data.nodeInfoList, nil
+}
+
+// HavePodsWithAffinityList returns the list of nodes with at least one pods with inter-pod affinity
+func (snapshot *compactSnapshotNodeLister) HavePodsWithAffinityList() ([]*schedulerframework.NodeInfo, error) {
+	data := (*CompactClusterSnapshot)(snapshot).getInternalData()
+	if data.havePodsWithAffinity == nil {
+		data.havePodsWithAffinity = data.listNodeInfos(func(node *compactNode) bool {
+			return node.podsWithAffinity > 0
+		})
+	}
+	return data.havePodsWithAffinity, nil
+}
+
+// HavePodsWithRequiredAntiAffinityList returns the list of NodeInfos of nodes with pods with required anti-affinity terms.
+func (snapshot *compactSnapshotNodeLister) HavePodsWithRequiredAntiAffinityList() ([]*schedulerframework.NodeInfo, error) {
+	data := (*CompactClusterSnapshot)(snapshot).getInternalData()
+	if data.havePodsWithRequiredAntiAffinity == nil {
+		data.havePodsWithRequiredAntiAffinity = data.listNodeInfos(func(node *compactNode) bool {
+			return node.podsWithRequiredAntiAffinity > 0
+		})
+	}
+	return data.havePodsWithRequiredAntiAffinity, nil
+}
+
+// Get returns the NodeInfo of the given node name.
+func (snapshot *compactSnapshotNodeLister) Get(nodeName string) (*schedulerframework.NodeInfo, error) {
+	snap := (*CompactClusterSnapshot)(snapshot)
+	return snap.getInternalData().getNodeInfo(snap.strings, nodeName)
+}
+
+// IsPVCUsedByPods returns if the pvc is used by any pod, key = <namespace>/<pvc_name>
+func (snapshot *compactSnapshotStorageLister) IsPVCUsedByPods(key string) bool {
+	return (*CompactClusterSnapshot)(snapshot).IsPVCUsedByPods(key)
+}
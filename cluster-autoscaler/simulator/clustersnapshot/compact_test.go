@@ -0,0 +1,106 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clustersnapshot
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func TestCompactClusterSnapshotSlotReuse(t *testing.T) {
+	snapshot := NewCompactClusterSnapshot()
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n2 := BuildTestNode("n2", 1000, 1000)
+	p1 := BuildTestPod("p1", 100, 100)
+	p2 := BuildTestPod("p2", 100, 100)
+	assert.NoError(t, snapshot.AddNodeWithPods(n1, []*apiv1.Pod{p1}))
+
+	snapshot.Fork()
+	assert.NoError(t, snapshot.RemoveNode("n1"))
+	assert.NoError(t, snapshot.AddNodeWithPods(n2, []*apiv1.Pod{p2}))
+	// Slots freed by the removal are reused in the fork.
+	assert.Equal(t, int32(1), snapshot.getInternalData().nodeSlots)
+	assert.Equal(t, int32(1), snapshot.getInternalData().podSlots)
+	nodeInfo, err := snapshot.NodeInfos().Get("n2")
+	assert.NoError(t, err)
+	assert.Equal(t, n2, nodeInfo.Node())
+	assert.Equal(t, p2, nodeInfo.Pods[0].Pod)
+	_, err = snapshot.NodeInfos().Get("n1")
+	assert.Equal(t, ErrNodeNotFound, err)
+
+	// The base is not affected by the reuse.
+	snapshot.Revert()
+	nodeInfo, err = snapshot.NodeInfos().Get("n1")
+	assert.NoError(t, err)
+	assert.Equal(t, n1, nodeInfo.Node())
+	assert.Len(t, nodeInfo.Pods, 1)
+	assert.Equal(t, p1, nodeInfo.Pods[0].Pod)
+	_, err = snapshot.NodeInfos().Get("n2")
+	assert.Equal(t, ErrNodeNotFound, err)
+}
+
+func TestCompactClusterSnapshotForkDoesNotShareNodePods(t *testing.T) {
+	snapshot := NewCompactClusterSnapshot()
+	node := BuildTestNode("n1", 1000, 1000)
+	p1 := BuildTestPod("p1", 100, 100)
+	assert.NoError(t, snapshot.AddNodeWithPods(node, []*apiv1.Pod{p1}))
+
+	snapshot.Fork()
+	assert.NoError(t, snapshot.AddPod(BuildTestPod("p2", 100, 100), "n1"))
+	assert.NoError(t, snapshot.RemovePod(p1.Namespace, p1.Name, "n1"))
+	nodeInfo, err := snapshot.NodeInfos().Get("n1")
+	assert.NoError(t, err)
+	assert.Len(t, nodeInfo.Pods, 1)
+	assert.Equal(t, "p2", nodeInfo.Pods[0].Pod.Name)
+
+	snapshot.Revert()
+	nodeInfo, err = snapshot.NodeInfos().Get("n1")
+	assert.NoError(t, err)
+	assert.Len(t, nodeInfo.Pods, 1)
+	assert.Equal(t, "p1", nodeInfo.Pods[0].Pod.Name)
+}
+
+func TestCompactClusterSnapshotForkCopiesOnlyModifiedChunks(t *testing.T) {
+	snapshot := NewCompactClusterSnapshot()
+	for i := 0; i < 2*compactChunkSize; i++ {
+		node := BuildTestNode(fmt.Sprintf("n%d", i), 1000, 1000)
+		assert.NoError(t, snapshot.AddNodeWithPods(node, []*apiv1.Pod{BuildTestPod(fmt.Sprintf("p%d", i), 100, 100)}))
+	}
+	base := snapshot.getInternalData()
+
+	snapshot.Fork()
+	fork := snapshot.getInternalData()
+	// Reads don't copy chunks.
+	_, err := snapshot.NodeInfos().List()
+	assert.NoError(t, err)
+	for i := range base.nodes {
+		assert.Same(t, base.nodes[i], fork.nodes[i])
+	}
+
+	assert.NoError(t, snapshot.AddPod(BuildTestPod("extra", 100, 100), fmt.Sprintf("n%d", compactChunkSize)))
+	assert.Same(t, base.nodes[0], fork.nodes[0])
+	assert.NotSame(t, base.nodes[1], fork.nodes[1])
+
+	snapshot.Revert()
+	nodeInfo, err := snapshot.NodeInfos().Get(fmt.Sprintf("n%d", compactChunkSize))
+	assert.NoError(t, err)
+	assert.Len(t, nodeInfo.Pods, 1)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nakedpod
+
+import (
+	"fmt"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
+	pod_util "k8s.io/autoscaler/cluster-autoscaler/utils/pod"
+)
+
+// Policy defines how scale-down handles naked pods, i.e. pods without a controller.
+type Policy string
+
+const (
+	// BlockPolicy blocks the scale-down of the node of a naked pod, since nothing would recreate the pod.
+	BlockPolicy Policy = "block"
+	// EvictPolicy evicts naked pods like replicated ones, respecting their termination grace period and PDBs.
+	EvictPolicy Policy = "evict"
+	// IgnorePolicy neither evicts naked pods nor lets them block scale-down: they go away with their node.
+	IgnorePolicy Policy = "ignore"
+)
+
+// DefaultNamespace is the namespace key of the policy applied to namespaces without a policy of their own.
+const DefaultNamespace = "*"
+
+// ParsePolicies parses specifications in the <namespace>=<policy> format into the names of the policies
+// applied to each namespace. The DefaultNamespace key sets the policy of the other namespaces.
+func ParsePolicies(specs []string) (map[string]string, error) {
+	policies := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("incorrect naked pod policy specification, expected <namespace>=<policy>: %v", spec)
+		}
+		switch Policy(parts[1]) {
+		case BlockPolicy, EvictPolicy, IgnorePolicy:
+		default:
+			return nil, fmt.Errorf("unknown naked pod policy %q, expected one of: %s, %s, %s", parts[1], BlockPolicy, EvictPolicy, IgnorePolicy)
+		}
+		if _, found := policies[parts[0]]; found {
+			return nil, fmt.Errorf("incorrect naked pod policy - namespace %s given more than once", parts[0])
+		}
+		policies[parts[0]] = parts[1]
+	}
+	return policies, nil
+}
+
+// Rule is a drainability rule on how to handle naked pods, according to the policy of their namespace.
+type Rule struct{}
+
+// New creates a new Rule.
+func New() *Rule {
+	return &Rule{}
+}
+
+// Drainable decides what to do with naked pods on node drain. Pods of namespaces with the block policy,
+// pods annotated with safe-to-evict, terminating pods and, with --skip-nodes-with-system-pods, kube-system
+// pods are left to the other checks.
+func (Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod) drainability.Status {
+	if drain.ControllerRef(pod) != nil || pod_util.IsDaemonSetPod(pod) || pod.DeletionTimestamp != nil {
+		return drainability.NewUndefinedStatus()
+	}
+	if _, found := pod.GetAnnotations()[drain.PodSafeToEvictKey]; found {
+		return drainability.NewUndefinedStatus()
+	}
+	if pod.Namespace == "kube-system" && drainCtx.DeleteOptions.SkipNodesWithSystemPods {
+		return drainability.NewUndefinedStatus()
+	}
+	switch policyOf(drainCtx.DeleteOptions.NakedPodPolicies, pod.Namespace) {
+	case EvictPolicy:
+		if drainCtx.DeleteOptions.SkipNodesWithLocalStorage && drain.HasBlockingLocalStorage(pod) {
+			return drainability.NewBlockedStatus(drain.LocalStorageRequested, fmt.Errorf("pod with local storage present: %s", pod.Name))
+		}
+		return drainability.NewDrainableStatus()
+	case IgnorePolicy:
+		return drainability.NewSkipStatus()
+	}
+	return drainability.NewUndefinedStatus()
+}
+
+func policyOf(policies map[string]string, namespace string) Policy {
+	if policy, found := policies[namespace]; found {
+		return Policy(policy)
+	}
+	if policy, found := policies[DefaultNamespace]; found {
+		return Policy(policy)
+	}
+	return BlockPolicy
+}
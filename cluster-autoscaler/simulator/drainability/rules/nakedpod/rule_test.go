@@ -0,0 +1,142 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nakedpod
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/options"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func TestRule(t *testing.T) {
+	nakedPod := func(namespace string) *apiv1.Pod {
+		return &apiv1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "nakedPod",
+				Namespace: namespace,
+			},
+		}
+	}
+	rsPod := nakedPod("dev")
+	rsPod.OwnerReferences = GenerateOwnerReferences("rs", "ReplicaSet", "apps/v1", "")
+	notSafeToEvictPod := nakedPod("dev")
+	notSafeToEvictPod.Annotations = map[string]string{drain.PodSafeToEvictKey: "false"}
+	localStoragePod := nakedPod("dev")
+	localStoragePod.Spec.Volumes = []apiv1.Volume{{Name: "scratch", VolumeSource: apiv1.VolumeSource{EmptyDir: &apiv1.EmptyDirVolumeSource{}}}}
+
+	policies := map[string]string{"dev": "evict", "sandbox": "ignore", "prod": "block"}
+	testCases := []struct {
+		desc     string
+		pod      *apiv1.Pod
+		policies map[string]string
+		want     drainability.OutcomeType
+		reason   drain.BlockingPodReason
+	}{
+		{
+			desc:     "no policies",
+			pod:      nakedPod("dev"),
+			policies: nil,
+			want:     drainability.UndefinedOutcome,
+		},
+		{
+			desc:     "evict policy",
+			pod:      nakedPod("dev"),
+			policies: policies,
+			want:     drainability.DrainOk,
+		},
+		{
+			desc:     "ignore policy",
+			pod:      nakedPod("sandbox"),
+			policies: policies,
+			want:     drainability.SkipDrain,
+		},
+		{
+			desc:     "block policy",
+			pod:      nakedPod("prod"),
+			policies: policies,
+			want:     drainability.UndefinedOutcome,
+		},
+		{
+			desc:     "namespace without policy",
+			pod:      nakedPod("other"),
+			policies: policies,
+			want:     drainability.UndefinedOutcome,
+		},
+		{
+			desc:     "default policy",
+			pod:      nakedPod("other"),
+			policies: map[string]string{"prod": "block", DefaultNamespace: "ignore"},
+			want:     drainability.SkipDrain,
+		},
+		{
+			desc:     "replicated pod",
+			pod:      rsPod,
+			policies: policies,
+			want:     drainability.UndefinedOutcome,
+		},
+		{
+			desc:     "not safe to evict pod",
+			pod:      notSafeToEvictPod,
+			policies: policies,
+			want:     drainability.UndefinedOutcome,
+		},
+		{
+			desc:     "kube-system pod",
+			pod:      nakedPod("kube-system"),
+			policies: map[string]string{DefaultNamespace: "evict"},
+			want:     drainability.UndefinedOutcome,
+		},
+		{
+			desc:     "local storage pod",
+			pod:      localStoragePod,
+			policies: policies,
+			want:     drainability.BlockDrain,
+			reason:   drain.LocalStorageRequested,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			drainCtx := &drainability.DrainContext{
+				DeleteOptions: options.NodeDeleteOptions{
+					SkipNodesWithSystemPods:   true,
+					SkipNodesWithLocalStorage: true,
+					NakedPodPolicies:          tc.policies,
+				},
+			}
+			got := New().Drainable(drainCtx, tc.pod)
+			assert.Equal(t, tc.want, got.Outcome)
+			assert.Equal(t, tc.reason, got.BlockingReason)
+		})
+	}
+}
+
+func TestParsePolicies(t *testing.T) {
+	policies, err := ParsePolicies([]string{"dev=evict", "sandbox=ignore", "*=block"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"dev": "evict", "sandbox": "ignore", "*": "block"}, policies)
+
+	for _, specs := range [][]string{{"dev"}, {"=evict"}, {"dev=delete"}, {"dev=evict", "dev=ignore"}} {
+		_, err := ParsePolicies(specs)
+		assert.Error(t, err, "specs: %v", specs)
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/pdb"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/mirror"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/nakedpod"
 )
 
 // Rule determines whether a given pod can be drained or not.
@@ -36,6 +37,7 @@ type Rule interface {
 func Default() Rules {
 	return []Rule{
 		mirror.New(),
+		nakedpod.New(),
 	}
 }
 
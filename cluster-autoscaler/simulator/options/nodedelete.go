@@ -35,6 +35,9 @@ type NodeDeleteOptions struct {
 	// set or replication controller should have to allow pod deletion during
 	// scale down.
 	MinReplicaCount int
+	// NakedPodPolicies maps a namespace to the policy applied to its pods
+	// without a controller. The "*" key sets the policy of the other namespaces.
+	NakedPodPolicies map[string]string
 }
 
 // NewNodeDeleteOptions returns new node delete options extracted from autoscaling options.
@@ -44,5 +47,6 @@ func NewNodeDeleteOptions(opts config.AutoscalingOptions) NodeDeleteOptions {
 		SkipNodesWithLocalStorage:         opts.SkipNodesWithLocalStorage,
 		MinReplicaCount:                   opts.MinReplicaCount,
 		SkipNodesWithCustomControllerPods: opts.SkipNodesWithCustomControllerPods,
+		NakedPodPolicies:                  opts.NakedPodPolicies,
 	}
 }
@@ -28,7 +28,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
 
-	klog "k8s.io/klog/v2"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
 )
 
 // Info contains utilization information for a node.
@@ -45,12 +45,13 @@ type Info struct {
 // Calculate calculates utilization of a node, defined as maximum of (cpu,
 // memory) or gpu utilization based on if the node has GPU or not. Per resource
 // utilization is the sum of requests for it divided by allocatable. It also
-// returns the individual cpu, memory and gpu utilization.
-func Calculate(nodeInfo *schedulerframework.NodeInfo, skipDaemonSetPods, skipMirrorPods bool, gpuConfig *cloudprovider.GpuConfig, currentTime time.Time) (utilInfo Info, err error) {
+// returns the individual cpu, memory and gpu utilization. Terminating pods are
+// ignored once they are long terminating, see drain.IsPodLongTerminatingWithThreshold.
+func Calculate(nodeInfo *schedulerframework.NodeInfo, skipDaemonSetPods, skipMirrorPods bool, gpuConfig *cloudprovider.GpuConfig, currentTime time.Time, terminatingPodThreshold time.Duration) (utilInfo Info, err error) {
 	if gpuConfig != nil {
-		gpuUtil, err := CalculateUtilizationOfResource(nodeInfo, gpuConfig.ResourceName, skipDaemonSetPods, skipMirrorPods, currentTime)
+		gpuUtil, err := CalculateUtilizationOfResource(nodeInfo, gpuConfig.ResourceName, skipDaemonSetPods, skipMirrorPods, currentTime, terminatingPodThreshold)
 		if err != nil {
-			klog.V(3).Infof("node %s has unready GPU resource: %s", nodeInfo.Node().Name, gpuConfig.ResourceName)
+			klogx.Simulator.V(3).Infof("node %s has unready GPU resource: %s", nodeInfo.Node().Name, gpuConfig.ResourceName)
 			// Return 0 if GPU is unready. This will guarantee we can still scale down a node with unready GPU.
 			return Info{GpuUtil: 0, ResourceName: gpuConfig.ResourceName, Utilization: 0}, nil
 		}
@@ -58,11 +59,11 @@ func Calculate(nodeInfo *schedulerframework.NodeInfo, skipDaemonSetPods, skipMir
 		return Info{GpuUtil: gpuUtil, ResourceName: gpuConfig.ResourceName, Utilization: gpuUtil}, err
 	}
 
-	cpu, err := CalculateUtilizationOfResource(nodeInfo, apiv1.ResourceCPU, skipDaemonSetPods, skipMirrorPods, currentTime)
+	cpu, err := CalculateUtilizationOfResource(nodeInfo, apiv1.ResourceCPU, skipDaemonSetPods, skipMirrorPods, currentTime, terminatingPodThreshold)
 	if err != nil {
 		return Info{}, err
 	}
-	mem, err := CalculateUtilizationOfResource(nodeInfo, apiv1.ResourceMemory, skipDaemonSetPods, skipMirrorPods, currentTime)
+	mem, err := CalculateUtilizationOfResource(nodeInfo, apiv1.ResourceMemory, skipDaemonSetPods, skipMirrorPods, currentTime, terminatingPodThreshold)
 	if err != nil {
 		return Info{}, err
 	}
@@ -81,7 +82,7 @@ func Calculate(nodeInfo *schedulerframework.NodeInfo, skipDaemonSetPods, skipMir
 }
 
 // CalculateUtilizationOfResource calculates utilization of a given resource for a node.
-func CalculateUtilizationOfResource(nodeInfo *schedulerframework.NodeInfo, resourceName apiv1.ResourceName, skipDaemonSetPods, skipMirrorPods bool, currentTime time.Time) (float64, error) {
+func CalculateUtilizationOfResource(nodeInfo *schedulerframework.NodeInfo, resourceName apiv1.ResourceName, skipDaemonSetPods, skipMirrorPods bool, currentTime time.Time, terminatingPodThreshold time.Duration) (float64, error) {
 	nodeAllocatable, found := nodeInfo.Node().Status.Allocatable[resourceName]
 	if !found {
 		return 0, fmt.Errorf("failed to get %v from %s", resourceName, nodeInfo.Node().Name)
@@ -115,7 +116,7 @@ func CalculateUtilizationOfResource(nodeInfo *schedulerframework.NodeInfo, resou
 			continue
 		}
 		// ignore Pods that should be terminated
-		if drain.IsPodLongTerminating(podInfo.Pod, currentTime) {
+		if drain.IsPodLongTerminatingWithThreshold(podInfo.Pod, currentTime, terminatingPodThreshold) {
 			continue
 		}
 		for _, container := range podInfo.Pod.Spec.Containers {
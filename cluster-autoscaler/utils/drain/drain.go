@@ -336,3 +336,12 @@ func IsPodLongTerminating(pod *apiv1.Pod, currentTime time.Time) bool {
 	}
 	return pod.DeletionTimestamp.Time.Add(time.Duration(*gracePeriod) * time.Second).Add(PodLongTerminatingExtraThreshold).Before(currentTime)
 }
+
+// IsPodLongTerminatingWithThreshold checks if a pod has been terminating for longer than the given threshold
+// after its deletion timestamp. A non-positive threshold falls back to IsPodLongTerminating.
+func IsPodLongTerminatingWithThreshold(pod *apiv1.Pod, currentTime time.Time, threshold time.Duration) bool {
+	if threshold <= 0 {
+		return IsPodLongTerminating(pod, currentTime)
+	}
+	return pod.DeletionTimestamp != nil && pod.DeletionTimestamp.Time.Add(threshold).Before(currentTime)
+}
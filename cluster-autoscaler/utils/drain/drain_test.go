@@ -1104,3 +1104,18 @@ func TestIsPodLongTerminating(t *testing.T) {
 		})
 	}
 }
+
+func TestIsPodLongTerminatingWithThreshold(t *testing.T) {
+	testTime := time.Date(2020, time.December, 18, 17, 0, 0, 0, time.UTC)
+	pod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			DeletionTimestamp: &metav1.Time{Time: testTime.Add(-5 * time.Minute)},
+		},
+	}
+
+	// Without threshold, the default grace period of 30s and the 30s buffer are over.
+	assert.True(t, IsPodLongTerminatingWithThreshold(pod, testTime, 0))
+	assert.False(t, IsPodLongTerminatingWithThreshold(pod, testTime, 10*time.Minute))
+	assert.True(t, IsPodLongTerminatingWithThreshold(pod, testTime, time.Minute))
+	assert.False(t, IsPodLongTerminatingWithThreshold(&apiv1.Pod{}, testTime, time.Minute))
+}
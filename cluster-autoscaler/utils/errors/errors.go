@@ -65,6 +65,9 @@ const (
 	// scale down is already removing too much and so further node removals
 	// shouldn't be attempted.
 	UnexpectedScaleDownStateError AutoscalerErrorType = "unexpectedScaleDownStateError"
+	// NodeGroupPanicError means that processing a node group panicked. Only
+	// the node group should be affected, the other ones can be processed.
+	NodeGroupPanicError AutoscalerErrorType = "nodeGroupPanicError"
 )
 
 // NewAutoscalerError returns new autoscaler error with a message constructed from format string
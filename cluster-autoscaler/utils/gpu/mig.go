@@ -0,0 +1,98 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gpu
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// ResourceNvidiaMigPrefix is the prefix of resources advertised for MIG devices
+	// by the Nvidia device plugin using the mixed strategy, e.g. nvidia.com/mig-1g.5gb.
+	ResourceNvidiaMigPrefix = "nvidia.com/mig-"
+)
+
+// MigProfile describes how many MIG devices of a given profile each GPU is sliced into.
+type MigProfile struct {
+	// Name is the MIG profile name, e.g. 1g.5gb.
+	Name string
+	// Count is the number of devices of this profile per GPU.
+	Count int64
+}
+
+// ResourceName returns the name of the resource advertised for devices of the profile.
+func (p MigProfile) ResourceName() apiv1.ResourceName {
+	return apiv1.ResourceName(ResourceNvidiaMigPrefix + p.Name)
+}
+
+// ParseMigProfiles parses a comma-separated list of MIG profiles in the
+// <profile> x <count> format, e.g. "1g.5gb x 7" or "3g.20gb x 1, 4g.20gb x 1".
+func ParseMigProfiles(value string) ([]MigProfile, error) {
+	var profiles []MigProfile
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		separator := strings.LastIndex(entry, "x")
+		if separator < 0 {
+			return nil, fmt.Errorf("invalid MIG profile %q, expected <profile> x <count>", entry)
+		}
+		name := strings.TrimSpace(entry[:separator])
+		count, err := strconv.ParseInt(strings.TrimSpace(entry[separator+1:]), 10, 64)
+		if err != nil || count <= 0 {
+			return nil, fmt.Errorf("invalid count in MIG profile %q, expected a positive integer", entry)
+		}
+		if name == "" {
+			return nil, fmt.Errorf("invalid MIG profile %q, profile name is empty", entry)
+		}
+		profiles = append(profiles, MigProfile{Name: name, Count: count})
+	}
+	return profiles, nil
+}
+
+// ApplyMigProfiles replaces whole Nvidia GPUs in the given resources with the MIG devices
+// each of them is sliced into, as advertised by the Nvidia device plugin using the mixed
+// strategy. Resources without GPUs are assumed to have a single one.
+func ApplyMigProfiles(resources apiv1.ResourceList, profiles []MigProfile) {
+	if len(profiles) == 0 {
+		return
+	}
+	gpus := int64(1)
+	if gpuQuantity, found := resources[ResourceNvidiaGPU]; found && gpuQuantity.Value() > 0 {
+		gpus = gpuQuantity.Value()
+	}
+	for _, profile := range profiles {
+		resources[profile.ResourceName()] = *resource.NewQuantity(profile.Count*gpus, resource.DecimalSI)
+	}
+	resources[ResourceNvidiaGPU] = *resource.NewQuantity(0, resource.DecimalSI)
+}
+
+// HasMigDevices returns true if any MIG device is present in the given resources.
+func HasMigDevices(resources apiv1.ResourceList) bool {
+	for name, quantity := range resources {
+		if strings.HasPrefix(string(name), ResourceNvidiaMigPrefix) && !quantity.IsZero() {
+			return true
+		}
+	}
+	return false
+}
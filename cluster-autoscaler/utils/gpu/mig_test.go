@@ -0,0 +1,65 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gpu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestParseMigProfiles(t *testing.T) {
+	profiles, err := ParseMigProfiles("1g.5gb x 7")
+	assert.NoError(t, err)
+	assert.Equal(t, []MigProfile{{Name: "1g.5gb", Count: 7}}, profiles)
+
+	profiles, err = ParseMigProfiles("3g.20gb x 1, 2g.10gb x2")
+	assert.NoError(t, err)
+	assert.Equal(t, []MigProfile{{Name: "3g.20gb", Count: 1}, {Name: "2g.10gb", Count: 2}}, profiles)
+
+	profiles, err = ParseMigProfiles("")
+	assert.NoError(t, err)
+	assert.Empty(t, profiles)
+
+	for _, invalid := range []string{"1g.5gb", "1g.5gb x", "1g.5gb x 0", "1g.5gb x -1", "x 2", "1g.5gb x two"} {
+		_, err = ParseMigProfiles(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestApplyMigProfiles(t *testing.T) {
+	resources := apiv1.ResourceList{
+		apiv1.ResourceCPU: *resource.NewQuantity(8, resource.DecimalSI),
+		ResourceNvidiaGPU: *resource.NewQuantity(2, resource.DecimalSI),
+	}
+	assert.False(t, HasMigDevices(resources))
+
+	ApplyMigProfiles(resources, []MigProfile{{Name: "3g.20gb", Count: 1}, {Name: "1g.5gb", Count: 4}})
+	mig3g := resources["nvidia.com/mig-3g.20gb"]
+	mig1g := resources["nvidia.com/mig-1g.5gb"]
+	gpus := resources[ResourceNvidiaGPU]
+	assert.Equal(t, int64(2), mig3g.Value())
+	assert.Equal(t, int64(8), mig1g.Value())
+	assert.True(t, gpus.IsZero())
+	assert.True(t, HasMigDevices(resources))
+
+	resources = apiv1.ResourceList{}
+	ApplyMigProfiles(resources, nil)
+	assert.Empty(t, resources)
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gpu
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultUtilizationQuery is the default Prometheus query returning the GPU utilization of nodes, between 0 and 1,
+	// from the metrics of the DCGM exporter. The peak over the last 10 minutes is used so that bursty workloads keep
+	// their nodes.
+	DefaultUtilizationQuery = "max by (Hostname) (max_over_time(DCGM_FI_DEV_GPU_UTIL[10m])) / 100"
+	// DefaultUtilizationNodeLabel is the default label identifying the node in the results of the utilization query.
+	DefaultUtilizationNodeLabel = "Hostname"
+	// DefaultUtilizationQueryTimeout is the default timeout of utilization queries.
+	DefaultUtilizationQueryTimeout = 10 * time.Second
+)
+
+// UtilizationSource returns the measured GPU utilization of nodes.
+type UtilizationSource interface {
+	// NodeUtilization returns the GPU utilization of nodes, between 0 and 1, by node name.
+	// Nodes without measurements are missing from the result.
+	NodeUtilization() (map[string]float64, error)
+}
+
+// PrometheusUtilizationSource queries the GPU utilization of nodes from Prometheus, typically scraping
+// the DCGM exporter.
+type PrometheusUtilizationSource struct {
+	client    *http.Client
+	url       string
+	query     string
+	nodeLabel string
+}
+
+// NewPrometheusUtilizationSource returns a PrometheusUtilizationSource running the query against the Prometheus
+// server at the given URL, and reading node names from the nodeLabel of the results.
+func NewPrometheusUtilizationSource(prometheusURL, query, nodeLabel string, timeout time.Duration) *PrometheusUtilizationSource {
+	return &PrometheusUtilizationSource{
+		client:    &http.Client{Timeout: timeout},
+		url:       prometheusURL,
+		query:     query,
+		nodeLabel: nodeLabel,
+	}
+}
+
+type queryResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Data   struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Metric map[string]string `json:"metric"`
+			Value  []interface{}     `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// NodeUtilization runs the query and returns the GPU utilization of nodes by node name. Nodes with several
+// results keep the highest one.
+func (s *PrometheusUtilizationSource) NodeUtilization() (map[string]float64, error) {
+	endpoint := fmt.Sprintf("%s/api/v1/query?query=%s", s.url, url.QueryEscape(s.query))
+	resp, err := s.client.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query GPU utilization: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var r queryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("failed to decode GPU utilization query response (status %s): %v", resp.Status, err)
+	}
+	if r.Status != "success" {
+		return nil, fmt.Errorf("GPU utilization query failed: %s", r.Error)
+	}
+	if r.Data.ResultType != "vector" {
+		return nil, fmt.Errorf("GPU utilization query returned %q instead of a vector", r.Data.ResultType)
+	}
+
+	utilization := make(map[string]float64, len(r.Data.Result))
+	for _, sample := range r.Data.Result {
+		node := sample.Metric[s.nodeLabel]
+		if node == "" || len(sample.Value) != 2 {
+			continue
+		}
+		raw, ok := sample.Value[1].(string)
+		if !ok {
+			continue
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GPU utilization %q of node %s: %v", raw, node, err)
+		}
+		if current, found := utilization[node]; !found || value > current {
+			utilization[node] = value
+		}
+	}
+	return utilization, nil
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gpu
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusUtilizationSource(t *testing.T) {
+	var query string
+	response := `{"status":"success","data":{"resultType":"vector","result":[
+		{"metric":{"Hostname":"n1"},"value":[1690000000.1,"0.25"]},
+		{"metric":{"Hostname":"n2"},"value":[1690000000.1,"0.1"]},
+		{"metric":{"Hostname":"n2"},"value":[1690000000.1,"0.6"]},
+		{"metric":{"instance":"10.0.0.1:9400"},"value":[1690000000.1,"0.9"]}
+	]}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/query", r.URL.Path)
+		query = r.URL.Query().Get("query")
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	source := NewPrometheusUtilizationSource(server.URL, DefaultUtilizationQuery, DefaultUtilizationNodeLabel, DefaultUtilizationQueryTimeout)
+	utilization, err := source.NodeUtilization()
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultUtilizationQuery, query)
+	assert.Equal(t, map[string]float64{"n1": 0.25, "n2": 0.6}, utilization)
+
+	response = `{"status":"error","error":"parse error"}`
+	_, err = source.NodeUtilization()
+	assert.EqualError(t, err, "GPU utilization query failed: parse error")
+
+	response = `{"status":"success","data":{"resultType":"matrix","result":[]}}`
+	_, err = source.NodeUtilization()
+	assert.Error(t, err)
+}
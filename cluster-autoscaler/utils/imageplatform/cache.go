@@ -0,0 +1,134 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imageplatform
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// maxCachedImages bounds the number of images whose architectures are cached.
+	maxCachedImages = 10000
+	// maxConcurrentResolutions bounds the number of images resolved in parallel.
+	maxConcurrentResolutions = 8
+)
+
+// ErrNotResolved is returned for images whose architectures are still being resolved.
+var ErrNotResolved = errors.New("image architectures are not resolved yet")
+
+type cacheEntry struct {
+	archs     []string
+	err       error
+	expiresAt time.Time
+}
+
+// CachingResolver wraps a Resolver and caches its results, including failures,
+// so that every image is inspected at most once per TTL. Images are resolved in
+// the background, so registry latency never blocks the caller: until the first
+// resolution of an image completes ErrNotResolved is returned, and afterwards
+// the last known result is served while it is being refreshed.
+type CachingResolver struct {
+	resolver   Resolver
+	ttl        time.Duration
+	now        func() time.Time
+	maxEntries int
+	slots      chan struct{}
+
+	sync.Mutex
+	entries   map[string]cacheEntry
+	resolving map[string]bool
+}
+
+// NewCachingResolver returns a new CachingResolver.
+func NewCachingResolver(resolver Resolver, ttl time.Duration) *CachingResolver {
+	return &CachingResolver{
+		resolver:   resolver,
+		ttl:        ttl,
+		now:        time.Now,
+		maxEntries: maxCachedImages,
+		slots:      make(chan struct{}, maxConcurrentResolutions),
+		entries:    make(map[string]cacheEntry),
+		resolving:  make(map[string]bool),
+	}
+}
+
+// Architectures returns the cached architectures of the image, resolving them in
+// the background if they are missing or expired.
+func (c *CachingResolver) Architectures(image string) ([]string, error) {
+	c.Lock()
+	defer c.Unlock()
+	entry, found := c.entries[image]
+	if !found || !c.now().Before(entry.expiresAt) {
+		c.startResolving(image)
+	}
+	if !found {
+		return nil, ErrNotResolved
+	}
+	return entry.archs, entry.err
+}
+
+// startResolving must be called with the lock held. If all resolution slots are
+// taken the image is skipped and picked up again on one of the next calls.
+func (c *CachingResolver) startResolving(image string) {
+	if c.resolving[image] {
+		return
+	}
+	select {
+	case c.slots <- struct{}{}:
+	default:
+		return
+	}
+	c.resolving[image] = true
+	go c.resolve(image)
+}
+
+func (c *CachingResolver) resolve(image string) {
+	archs, err := c.resolver.Architectures(image)
+	<-c.slots
+
+	c.Lock()
+	defer c.Unlock()
+	delete(c.resolving, image)
+	c.entries[image] = cacheEntry{archs: archs, err: err, expiresAt: c.now().Add(c.ttl)}
+	c.evict()
+}
+
+// evict drops expired entries and, if the cache is still over its bound, the
+// entries closest to expiry. It must be called with the lock held.
+func (c *CachingResolver) evict() {
+	if len(c.entries) <= c.maxEntries {
+		return
+	}
+	now := c.now()
+	for image, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, image)
+		}
+	}
+	for len(c.entries) > c.maxEntries {
+		var oldest string
+		var oldestExpiry time.Time
+		for image, entry := range c.entries {
+			if oldestExpiry.IsZero() || entry.expiresAt.Before(oldestExpiry) {
+				oldest, oldestExpiry = image, entry.expiresAt
+			}
+		}
+		delete(c.entries, oldest)
+	}
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imageplatform
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingResolver struct {
+	sync.Mutex
+	calls map[string]int
+}
+
+func (r *countingResolver) Architectures(image string) ([]string, error) {
+	r.Lock()
+	r.calls[image]++
+	r.Unlock()
+	if image == "broken" {
+		return nil, fmt.Errorf("broken image")
+	}
+	return []string{"amd64"}, nil
+}
+
+func (r *countingResolver) callCount(image string) int {
+	r.Lock()
+	defer r.Unlock()
+	return r.calls[image]
+}
+
+func (c *CachingResolver) size() int {
+	c.Lock()
+	defer c.Unlock()
+	return len(c.entries)
+}
+
+type fakeClock struct {
+	sync.Mutex
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.Lock()
+	defer f.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.Lock()
+	defer f.Unlock()
+	f.now = f.now.Add(d)
+}
+
+func TestCachingResolver(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	resolver := &countingResolver{calls: make(map[string]int)}
+	cache := NewCachingResolver(resolver, time.Minute)
+	cache.now = clock.Now
+
+	_, err := cache.Architectures("app")
+	assert.Equal(t, ErrNotResolved, err)
+	_, err = cache.Architectures("broken")
+	assert.Equal(t, ErrNotResolved, err)
+
+	assert.Eventually(t, func() bool {
+		archs, err := cache.Architectures("app")
+		return err == nil && len(archs) == 1 && archs[0] == "amd64"
+	}, time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		_, err := cache.Architectures("broken")
+		return err != nil && err != ErrNotResolved
+	}, time.Second, 10*time.Millisecond)
+	for i := 0; i < 3; i++ {
+		_, err = cache.Architectures("app")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, resolver.callCount("app"))
+	assert.Equal(t, 1, resolver.callCount("broken"))
+
+	// Expired entries are still served while they are being refreshed.
+	clock.Advance(2 * time.Minute)
+	archs, err := cache.Architectures("app")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"amd64"}, archs)
+	assert.Eventually(t, func() bool {
+		return resolver.callCount("app") == 2
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestCachingResolverIsBounded(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	resolver := &countingResolver{calls: make(map[string]int)}
+	cache := NewCachingResolver(resolver, time.Minute)
+	cache.now = clock.Now
+	cache.maxEntries = 2
+
+	for _, image := range []string{"a", "b", "c"} {
+		cache.Architectures(image)
+		assert.Eventually(t, func() bool {
+			_, err := cache.Architectures(image)
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+		clock.Advance(time.Second)
+	}
+	assert.Equal(t, 2, cache.size())
+	_, err := cache.Architectures("a")
+	assert.Equal(t, ErrNotResolved, err)
+}
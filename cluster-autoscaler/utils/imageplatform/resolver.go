@@ -0,0 +1,285 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imageplatform
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultRegistryTimeout is the default timeout of calls to image registries.
+	DefaultRegistryTimeout = 10 * time.Second
+
+	dockerHubRegistry = "registry-1.docker.io"
+	defaultTag        = "latest"
+	linuxOS           = "linux"
+	unknownArch       = "unknown"
+
+	mediaTypeOCIIndex          = "application/vnd.oci.image.index.v1+json"
+	mediaTypeOCIManifest       = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeDockerList        = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeDockerManifest    = "application/vnd.docker.distribution.manifest.v2+json"
+	manifestAcceptHeaderValues = mediaTypeOCIIndex + "," + mediaTypeDockerList + "," + mediaTypeOCIManifest + "," + mediaTypeDockerManifest
+)
+
+// Resolver returns the CPU architectures supported by container images.
+type Resolver interface {
+	// Architectures returns the linux architectures (e.g. amd64, arm64) the image is published for.
+	Architectures(image string) ([]string, error)
+}
+
+// RegistryResolver resolves image architectures by inspecting image manifests in the registry.
+// Only anonymous access is supported, so images from private registries can't be resolved.
+type RegistryResolver struct {
+	client *http.Client
+}
+
+// NewRegistryResolver returns a RegistryResolver using the given timeout for registry calls.
+func NewRegistryResolver(timeout time.Duration) *RegistryResolver {
+	return &RegistryResolver{client: &http.Client{Timeout: timeout}}
+}
+
+type imageReference struct {
+	registry   string
+	repository string
+	reference  string
+}
+
+// parseImageReference splits the image into registry, repository and tag or digest, applying
+// the same defaults as container runtimes do.
+func parseImageReference(image string) (imageReference, error) {
+	if image == "" {
+		return imageReference{}, fmt.Errorf("empty image name")
+	}
+	ref := imageReference{registry: dockerHubRegistry}
+	name := image
+	if i := strings.Index(name, "/"); i >= 0 {
+		domain := name[:i]
+		if strings.ContainsAny(domain, ".:") || domain == "localhost" {
+			ref.registry = domain
+			name = name[i+1:]
+		}
+	}
+	// A digest takes precedence over a tag; "name:tag@digest" is resolved by digest.
+	digest := ""
+	if i := strings.Index(name, "@"); i >= 0 {
+		digest = name[i+1:]
+		name = name[:i]
+	}
+	tag := defaultTag
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		tag = name[i+1:]
+		name = name[:i]
+	}
+	ref.reference = tag
+	if digest != "" {
+		ref.reference = digest
+	}
+	if ref.registry == dockerHubRegistry && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	if name == "" || ref.reference == "" {
+		return imageReference{}, fmt.Errorf("invalid image name %q", image)
+	}
+	ref.repository = name
+	return ref, nil
+}
+
+type platform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+}
+
+type descriptor struct {
+	MediaType string    `json:"mediaType"`
+	Digest    string    `json:"digest"`
+	Platform  *platform `json:"platform,omitempty"`
+}
+
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Manifests []descriptor `json:"manifests,omitempty"`
+	Config    *descriptor  `json:"config,omitempty"`
+}
+
+// Architectures returns the linux architectures the image is published for.
+func (r *RegistryResolver) Architectures(image string) ([]string, error) {
+	ref, err := parseImageReference(image)
+	if err != nil {
+		return nil, err
+	}
+	var m manifest
+	if err := r.get(ref, "manifests/"+ref.reference, manifestAcceptHeaderValues, &m); err != nil {
+		return nil, fmt.Errorf("failed to fetch manifest of %s: %v", image, err)
+	}
+	if len(m.Manifests) > 0 {
+		var archs []string
+		seen := make(map[string]bool)
+		for _, d := range m.Manifests {
+			if d.Platform == nil || (d.Platform.OS != "" && d.Platform.OS != linuxOS) {
+				continue
+			}
+			arch := d.Platform.Architecture
+			if arch == "" || arch == unknownArch || seen[arch] {
+				continue
+			}
+			seen[arch] = true
+			archs = append(archs, arch)
+		}
+		return archs, nil
+	}
+	if m.Config == nil {
+		return nil, fmt.Errorf("manifest of %s has neither platform list nor config", image)
+	}
+	var config platform
+	if err := r.get(ref, "blobs/"+m.Config.Digest, "*/*", &config); err != nil {
+		return nil, fmt.Errorf("failed to fetch config of %s: %v", image, err)
+	}
+	if config.Architecture == "" || (config.OS != "" && config.OS != linuxOS) {
+		return nil, nil
+	}
+	return []string{config.Architecture}, nil
+}
+
+func (r *RegistryResolver) get(ref imageReference, path, accept string, into interface{}) error {
+	endpoint := fmt.Sprintf("https://%s/v2/%s/%s", ref.registry, ref.repository, path)
+	resp, err := r.do(endpoint, accept, "")
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("Www-Authenticate")
+		resp.Body.Close()
+		token, err := r.anonymousToken(challenge)
+		if err != nil {
+			return err
+		}
+		resp, err = r.do(endpoint, accept, token)
+		if err != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(into)
+}
+
+func (r *RegistryResolver) do(endpoint, accept, token string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return r.client.Do(req)
+}
+
+// anonymousToken obtains a token from the auth server advertised in a Bearer challenge.
+func (r *RegistryResolver) anonymousToken(challenge string) (string, error) {
+	params, ok := parseBearerChallenge(challenge)
+	if !ok || params["realm"] == "" {
+		return "", fmt.Errorf("unsupported authentication challenge %q", challenge)
+	}
+	authURL, err := url.Parse(params["realm"])
+	if err != nil {
+		return "", err
+	}
+	query := authURL.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	authURL.RawQuery = query.Encode()
+	resp, err := r.client.Get(authURL.String())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s from auth server", resp.Status)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
+
+func parseBearerChallenge(challenge string) (map[string]string, bool) {
+	const prefix = "bearer "
+	if len(challenge) < len(prefix) || !strings.EqualFold(challenge[:len(prefix)], prefix) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	rest := challenge[len(prefix):]
+	for {
+		rest = strings.TrimLeft(rest, " \t,")
+		i := strings.Index(rest, "=")
+		if i < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:i]))
+		rest = rest[i+1:]
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			// Quoted values may contain commas, e.g. scope="repository:app:pull,push".
+			value, rest = readQuotedString(rest[1:])
+		} else if j := strings.Index(rest, ","); j >= 0 {
+			value, rest = strings.TrimSpace(rest[:j]), rest[j+1:]
+		} else {
+			value, rest = strings.TrimSpace(rest), ""
+		}
+		params[key] = value
+	}
+	return params, true
+}
+
+// readQuotedString reads a quoted-string whose opening quote was already consumed
+// and returns its unescaped value and the remaining input.
+func readQuotedString(s string) (string, string) {
+	var value strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				value.WriteByte(s[i])
+			}
+		case '"':
+			return value.String(), s[i+1:]
+		default:
+			value.WriteByte(s[i])
+		}
+	}
+	return value.String(), ""
+}
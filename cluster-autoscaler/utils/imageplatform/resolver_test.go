@@ -0,0 +1,135 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imageplatform
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseImageReference(t *testing.T) {
+	testCases := []struct {
+		image   string
+		want    imageReference
+		wantErr bool
+	}{
+		{
+			image: "nginx",
+			want:  imageReference{registry: dockerHubRegistry, repository: "library/nginx", reference: "latest"},
+		},
+		{
+			image: "bitnami/redis:7.0",
+			want:  imageReference{registry: dockerHubRegistry, repository: "bitnami/redis", reference: "7.0"},
+		},
+		{
+			image: "registry.k8s.io/autoscaling/cluster-autoscaler:v1.28.0",
+			want:  imageReference{registry: "registry.k8s.io", repository: "autoscaling/cluster-autoscaler", reference: "v1.28.0"},
+		},
+		{
+			image: "localhost:5000/app@sha256:abc",
+			want:  imageReference{registry: "localhost:5000", repository: "app", reference: "sha256:abc"},
+		},
+		{
+			image: "nginx:1.25@sha256:abc",
+			want:  imageReference{registry: dockerHubRegistry, repository: "library/nginx", reference: "sha256:abc"},
+		},
+		{
+			image: "localhost:5000/team/app:v2",
+			want:  imageReference{registry: "localhost:5000", repository: "team/app", reference: "v2"},
+		},
+		{
+			image:   "",
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.image, func(t *testing.T) {
+			got, err := parseImageReference(tc.image)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestParseBearerChallenge(t *testing.T) {
+	params, ok := parseBearerChallenge(`Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:team/app:pull,push"`)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{
+		"realm":   "https://auth.example.com/token",
+		"service": "registry.example.com",
+		"scope":   "repository:team/app:pull,push",
+	}, params)
+
+	params, ok = parseBearerChallenge(`bearer realm=https://auth.example.com/token, service="a \"quoted\" name"`)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{
+		"realm":   "https://auth.example.com/token",
+		"service": `a "quoted" name`,
+	}, params)
+
+	_, ok = parseBearerChallenge(`Basic realm="registry"`)
+	assert.False(t, ok)
+}
+
+func TestRegistryResolverArchitectures(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			fmt.Fprint(w, `{"token": "secret"}`)
+		case r.Header.Get("Authorization") != "Bearer secret":
+			w.Header().Set("Www-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test",scope="repository:multi:pull"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/v2/multi/manifests/latest":
+			fmt.Fprint(w, `{"mediaType": "`+mediaTypeOCIIndex+`", "manifests": [
+				{"digest": "sha256:1", "platform": {"architecture": "amd64", "os": "linux"}},
+				{"digest": "sha256:2", "platform": {"architecture": "arm64", "os": "linux"}},
+				{"digest": "sha256:3", "platform": {"architecture": "amd64", "os": "windows"}},
+				{"digest": "sha256:4", "platform": {"architecture": "unknown", "os": "unknown"}}
+			]}`)
+		case r.URL.Path == "/v2/single/manifests/v1":
+			fmt.Fprint(w, `{"mediaType": "`+mediaTypeDockerManifest+`", "config": {"digest": "sha256:cfg"}}`)
+		case r.URL.Path == "/v2/single/blobs/sha256:cfg":
+			fmt.Fprint(w, `{"architecture": "amd64", "os": "linux"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "https://")
+	resolver := &RegistryResolver{client: server.Client()}
+
+	archs, err := resolver.Architectures(registry + "/multi")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"amd64", "arm64"}, archs)
+
+	archs, err = resolver.Architectures(registry + "/single:v1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"amd64"}, archs)
+
+	_, err = resolver.Architectures(registry + "/missing")
+	assert.Error(t, err)
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package klogx
 
 import (
+	"fmt"
+
 	klog "k8s.io/klog/v2"
 )
 
@@ -53,18 +55,33 @@ func V(n klog.Level) Verbose {
 type Verbose struct {
 	enabled bool
 	v       klog.Verbose
+	// overridden is set if the verbosity comes from a subsystem log level
+	// rather than from klog, in which case active tells if logging is on.
+	overridden bool
+	active     bool
 }
 
 func (v Verbose) enable(b bool) Verbose {
-	return Verbose{
-		enabled: b,
-		v:       v.v}
+	v.enabled = b
+	return v
+}
+
+func (v Verbose) verbose() bool {
+	if v.overridden {
+		return v.active
+	}
+	return v.v.Enabled()
+}
+
+// Enabled returns true if logging through this Verbose would print anything.
+func (v Verbose) Enabled() bool {
+	return v.enabled && v.verbose()
 }
 
 // UpTo calls UpTo from this package if called on true object.
 // The returned value is of type Verbose.
 func (v Verbose) UpTo(q *Quota) Verbose {
-	if v.v.Enabled() {
+	if v.verbose() {
 		q.left--
 		return v.enable(q.left >= 0)
 	}
@@ -74,7 +91,7 @@ func (v Verbose) UpTo(q *Quota) Verbose {
 // Over calls Over from this package if called on true object.
 // The returned value is of type Verbose.
 func (v Verbose) Over(q *Quota) Verbose {
-	if v.v.Enabled() {
+	if v.verbose() {
 		return v.enable(q.left < 0)
 	}
 	return v.enable(false)
@@ -83,15 +100,44 @@ func (v Verbose) Over(q *Quota) Verbose {
 // Infof is a wrapper for klog.Infof that logs if the Quota
 // allows for it.
 func (v Verbose) Infof(format string, args ...interface{}) {
-	if v.enabled {
-		v.v.Infof(format, args...)
+	if !v.enabled {
+		return
 	}
+	if v.overridden {
+		if v.active {
+			klog.InfoDepth(1, fmt.Sprintf(format, args...))
+		}
+		return
+	}
+	v.v.Infof(format, args...)
 }
 
 // Info is a wrapper for klog.Info that logs if the Quota
 // allows for it.
 func (v Verbose) Info(args ...interface{}) {
-	if v.enabled {
-		v.v.Info(args...)
+	if !v.enabled {
+		return
+	}
+	if v.overridden {
+		if v.active {
+			klog.InfoDepth(1, args...)
+		}
+		return
+	}
+	v.v.Info(args...)
+}
+
+// Infoln is a wrapper for klog.Infoln that logs if the Quota
+// allows for it.
+func (v Verbose) Infoln(args ...interface{}) {
+	if !v.enabled {
+		return
+	}
+	if v.overridden {
+		if v.active {
+			klog.InfoDepth(1, fmt.Sprintln(args...))
+		}
+		return
 	}
+	v.v.Infoln(args...)
 }
@@ -0,0 +1,162 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package klogx
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	klog "k8s.io/klog/v2"
+)
+
+// Subsystem is a part of the autoscaler whose log verbosity can be set independently of
+// the global klog verbosity.
+type Subsystem string
+
+const (
+	// Core covers the main autoscaling loop, scale-up and scale-down logic.
+	Core Subsystem = "core"
+	// Simulator covers scheduling simulations.
+	Simulator Subsystem = "simulator"
+	// Estimator covers estimation of the number of nodes needed for scale-up.
+	Estimator Subsystem = "estimator"
+	// ProviderAzure covers the Azure cloud provider.
+	ProviderAzure Subsystem = "provider/azure"
+)
+
+// Subsystems lists all subsystems supporting log level overrides.
+var Subsystems = []Subsystem{Core, Simulator, Estimator, ProviderAzure}
+
+var subsystemLevels = struct {
+	sync.RWMutex
+	levels map[Subsystem]klog.Level
+}{levels: map[Subsystem]klog.Level{}}
+
+// V returns a Verbose for the subsystem. If a log level override is set for the subsystem,
+// it is used instead of the global klog verbosity.
+func (s Subsystem) V(n klog.Level) Verbose {
+	subsystemLevels.RLock()
+	level, found := subsystemLevels.levels[s]
+	subsystemLevels.RUnlock()
+	if !found {
+		return V(n)
+	}
+	return Verbose{enabled: true, v: klog.V(n), overridden: true, active: n <= level}
+}
+
+// SetSubsystemLevels replaces all subsystem log level overrides. Subsystems not present
+// in levels fall back to the global klog verbosity.
+func SetSubsystemLevels(levels map[Subsystem]klog.Level) {
+	copied := make(map[Subsystem]klog.Level, len(levels))
+	for subsystem, level := range levels {
+		copied[subsystem] = level
+	}
+	subsystemLevels.Lock()
+	defer subsystemLevels.Unlock()
+	subsystemLevels.levels = copied
+}
+
+// SubsystemLevels returns currently set subsystem log level overrides.
+func SubsystemLevels() map[Subsystem]klog.Level {
+	subsystemLevels.RLock()
+	defer subsystemLevels.RUnlock()
+	result := make(map[Subsystem]klog.Level, len(subsystemLevels.levels))
+	for subsystem, level := range subsystemLevels.levels {
+		result[subsystem] = level
+	}
+	return result
+}
+
+// ParseSubsystemLevels parses a comma-separated list of subsystem=level pairs,
+// e.g. "core=5,provider/azure=1".
+func ParseSubsystemLevels(spec string) (map[Subsystem]klog.Level, error) {
+	known := make(map[Subsystem]bool, len(Subsystems))
+	for _, subsystem := range Subsystems {
+		known[subsystem] = true
+	}
+	result := make(map[Subsystem]klog.Level)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.Split(pair, "=")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid subsystem log level %q, expected subsystem=level", pair)
+		}
+		subsystem := Subsystem(strings.TrimSpace(parts[0]))
+		if !known[subsystem] {
+			return nil, fmt.Errorf("unknown subsystem %q, expected one of: %s", subsystem, FormatSubsystems())
+		}
+		level, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("invalid log level for subsystem %s: %v", subsystem, err)
+		}
+		result[subsystem] = klog.Level(level)
+	}
+	return result, nil
+}
+
+// FormatSubsystemLevels formats subsystem log levels the same way ParseSubsystemLevels expects them.
+func FormatSubsystemLevels(levels map[Subsystem]klog.Level) string {
+	pairs := make([]string, 0, len(levels))
+	for subsystem, level := range levels {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", subsystem, level))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// FormatSubsystems returns a comma-separated list of known subsystems.
+func FormatSubsystems() string {
+	names := make([]string, 0, len(Subsystems))
+	for _, subsystem := range Subsystems {
+		names = append(names, string(subsystem))
+	}
+	return strings.Join(names, ", ")
+}
+
+// SubsystemLevelsHandler serves subsystem log level overrides. GET returns the current
+// overrides, PUT replaces them with the ones given in the request body.
+func SubsystemLevelsHandler(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		levels, err := ParseSubsystemLevels(string(body))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		SetSubsystemLevels(levels)
+		klog.V(0).Infof("Subsystem log levels set to %q", FormatSubsystemLevels(levels))
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	fmt.Fprintln(w, FormatSubsystemLevels(SubsystemLevels()))
+}
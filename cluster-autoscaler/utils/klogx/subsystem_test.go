@@ -0,0 +1,79 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package klogx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	klog "k8s.io/klog/v2"
+)
+
+func TestParseSubsystemLevels(t *testing.T) {
+	levels, err := ParseSubsystemLevels("core=5, provider/azure=1,")
+	assert.NoError(t, err)
+	assert.Equal(t, map[Subsystem]klog.Level{Core: 5, ProviderAzure: 1}, levels)
+	assert.Equal(t, "core=5,provider/azure=1", FormatSubsystemLevels(levels))
+
+	for _, spec := range []string{"core", "unknown=1", "core=-1", "core=a"} {
+		_, err := ParseSubsystemLevels(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestSubsystemV(t *testing.T) {
+	defer SetSubsystemLevels(nil)
+
+	SetSubsystemLevels(map[Subsystem]klog.Level{Core: 5, Estimator: 0})
+	assert.True(t, Core.V(5).Enabled())
+	assert.False(t, Core.V(6).Enabled())
+	assert.False(t, Estimator.V(1).Enabled())
+	for i := klog.Level(0); i <= 10; i++ {
+		assert.Equal(t, klog.V(i).Enabled(), Simulator.V(i).Enabled())
+	}
+
+	q := NewLoggingQuota(1)
+	assert.True(t, Core.V(5).UpTo(q).Enabled())
+	assert.False(t, Core.V(5).UpTo(q).Enabled())
+	assert.False(t, Core.V(6).UpTo(q).Enabled())
+	assert.Equal(t, -1, q.Left())
+}
+
+func TestSubsystemLevelsHandler(t *testing.T) {
+	defer SetSubsystemLevels(nil)
+
+	w := httptest.NewRecorder()
+	SubsystemLevelsHandler(w, httptest.NewRequest(http.MethodPut, "/", strings.NewReader("simulator=4")))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[Subsystem]klog.Level{Simulator: 4}, SubsystemLevels())
+
+	w = httptest.NewRecorder()
+	SubsystemLevelsHandler(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "simulator=4\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	SubsystemLevelsHandler(w, httptest.NewRequest(http.MethodPut, "/", strings.NewReader("simulator=x")))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, map[Subsystem]klog.Level{Simulator: 4}, SubsystemLevels())
+
+	w = httptest.NewRecorder()
+	SubsystemLevelsHandler(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -297,3 +297,14 @@ func NewConfigMapListerForNamespace(kubeClient client.Interface, stopchannel <-c
 	go reflector.Run(stopchannel)
 	return lister
 }
+
+// NewSyncedConfigMapListerForNamespace builds a configmap lister for the passed namespace, whose informer
+// cache is synced before it is returned. Like the other listers, the informer is never stopped.
+func NewSyncedConfigMapListerForNamespace(kubeClient client.Interface, namespace string) v1lister.ConfigMapNamespaceLister {
+	stopChannel := make(chan struct{})
+	informerFactory := informers.NewSharedInformerFactoryWithOptions(kubeClient, time.Hour, informers.WithNamespace(namespace))
+	lister := informerFactory.Core().V1().ConfigMaps().Lister()
+	informerFactory.Start(stopChannel)
+	informerFactory.WaitForCacheSync(stopChannel)
+	return lister.ConfigMaps(namespace)
+}
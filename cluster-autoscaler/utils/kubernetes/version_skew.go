@@ -0,0 +1,108 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/discovery"
+	klog "k8s.io/klog/v2"
+)
+
+const (
+	// serverVersionRefreshInterval is how often the control plane version is fetched again,
+	// to notice control plane upgrades.
+	serverVersionRefreshInterval = 10 * time.Minute
+)
+
+// VersionSkewChecker checks node versions against the version skew policy of the
+// control plane (https://kubernetes.io/releases/version-skew-policy/): kubelets can't be
+// newer than the API server, and can be a limited number of minor versions older.
+type VersionSkewChecker struct {
+	client        discovery.ServerVersionInterface
+	mutex         sync.Mutex
+	serverVersion *version.Version
+	lastRefresh   time.Time
+}
+
+// NewVersionSkewChecker creates a VersionSkewChecker fetching the control plane version with the given client.
+func NewVersionSkewChecker(client discovery.ServerVersionInterface) *VersionSkewChecker {
+	return &VersionSkewChecker{client: client}
+}
+
+// CheckKubeletVersion returns an error if kubelets of the given version aren't allowed to
+// join the cluster. Empty kubelet versions and unknown control plane versions are not checked.
+func (c *VersionSkewChecker) CheckKubeletVersion(kubeletVersion string, now time.Time) error {
+	if kubeletVersion == "" {
+		return nil
+	}
+	serverVersion := c.getServerVersion(now)
+	if serverVersion == nil {
+		return nil
+	}
+	nodeVersion, err := version.ParseGeneric(kubeletVersion)
+	if err != nil {
+		return fmt.Errorf("invalid kubelet version %q: %v", kubeletVersion, err)
+	}
+	return checkKubeletVersionSkew(serverVersion, nodeVersion)
+}
+
+func (c *VersionSkewChecker) getServerVersion(now time.Time) *version.Version {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.serverVersion != nil && now.Sub(c.lastRefresh) < serverVersionRefreshInterval {
+		return c.serverVersion
+	}
+	info, err := c.client.ServerVersion()
+	if err != nil {
+		// keep checking against the last known version
+		klog.Warningf("Failed to get control plane version: %v", err)
+		return c.serverVersion
+	}
+	serverVersion, err := version.ParseGeneric(info.GitVersion)
+	if err != nil || serverVersion.Major() == 0 {
+		// development builds have no meaningful version
+		klog.Warningf("Unknown control plane version %q, kubelet versions won't be checked", info.GitVersion)
+		return c.serverVersion
+	}
+	c.serverVersion = serverVersion
+	c.lastRefresh = now
+	return c.serverVersion
+}
+
+// checkKubeletVersionSkew returns an error if kubelets of nodeVersion can't be used with an API server of serverVersion.
+func checkKubeletVersionSkew(serverVersion, nodeVersion *version.Version) error {
+	if nodeVersion.Major() != serverVersion.Major() {
+		return fmt.Errorf("kubelet version %s has a different major version than control plane version %s", nodeVersion, serverVersion)
+	}
+	if nodeVersion.Minor() > serverVersion.Minor() {
+		return fmt.Errorf("kubelet version %s is newer than control plane version %s", nodeVersion, serverVersion)
+	}
+	maxSkew := uint(3)
+	// before 1.28 kubelets could only be two minor versions older than the API server
+	if serverVersion.LessThan(version.MajorMinor(1, 28)) {
+		maxSkew = 2
+	}
+	if serverVersion.Minor()-nodeVersion.Minor() > maxSkew {
+		return fmt.Errorf("kubelet version %s is more than %d minor versions older than control plane version %s", nodeVersion, maxSkew, serverVersion)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiversion "k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	core "k8s.io/client-go/testing"
+)
+
+func TestCheckKubeletVersion(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		serverVersion  string
+		kubeletVersion string
+		compatible     bool
+	}{
+		{desc: "same version", serverVersion: "v1.28.2", kubeletVersion: "v1.28.0", compatible: true},
+		{desc: "no kubelet version", serverVersion: "v1.28.2", kubeletVersion: "", compatible: true},
+		{desc: "newer kubelet", serverVersion: "v1.27.5", kubeletVersion: "v1.28.0", compatible: false},
+		{desc: "three minor versions older", serverVersion: "v1.28.2", kubeletVersion: "v1.25.10", compatible: true},
+		{desc: "four minor versions older", serverVersion: "v1.28.2", kubeletVersion: "v1.24.17", compatible: false},
+		{desc: "three minor versions older before 1.28", serverVersion: "v1.27.5", kubeletVersion: "v1.24.17", compatible: false},
+		{desc: "provider suffix", serverVersion: "v1.27.5-eks-2d98532", kubeletVersion: "v1.26.4-eks-0a21954", compatible: true},
+		{desc: "invalid kubelet version", serverVersion: "v1.28.2", kubeletVersion: "latest", compatible: false},
+		{desc: "development control plane", serverVersion: "v0.0.0-master+$Format:%H$", kubeletVersion: "v1.28.0", compatible: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			client := &fakediscovery.FakeDiscovery{
+				Fake:               &core.Fake{},
+				FakedServerVersion: &apiversion.Info{GitVersion: tc.serverVersion},
+			}
+			err := NewVersionSkewChecker(client).CheckKubeletVersion(tc.kubeletVersion, time.Now())
+			assert.Equal(t, tc.compatible, err == nil, "unexpected result: %v", err)
+		})
+	}
+}
+
+func TestCheckKubeletVersionRefreshesServerVersion(t *testing.T) {
+	client := &fakediscovery.FakeDiscovery{
+		Fake:               &core.Fake{},
+		FakedServerVersion: &apiversion.Info{GitVersion: "v1.27.5"},
+	}
+	checker := NewVersionSkewChecker(client)
+	now := time.Now()
+	assert.Error(t, checker.CheckKubeletVersion("v1.28.0", now))
+
+	client.FakedServerVersion = &apiversion.Info{GitVersion: "v1.28.2"}
+	assert.Error(t, checker.CheckKubeletVersion("v1.28.0", now.Add(time.Minute)))
+	assert.NoError(t, checker.CheckKubeletVersion("v1.28.0", now.Add(serverVersionRefreshInterval)))
+}
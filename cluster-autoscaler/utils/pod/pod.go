@@ -26,6 +26,9 @@ import (
 const (
 	// DaemonSetPodAnnotationKey - annotation use to informs the cluster-autoscaler controller when a pod needs to be considered as a Daemonset's Pod.
 	DaemonSetPodAnnotationKey = "cluster-autoscaler.kubernetes.io/daemonset-pod"
+	// InterruptionSensitiveAnnotationKey - annotation telling whether a pod must be kept off node groups backed by
+	// spot or preemptible capacity, "true" or "false".
+	InterruptionSensitiveAnnotationKey = "cluster-autoscaler.kubernetes.io/interruption-sensitive"
 )
 
 // IsDaemonSetPod returns true if the Pod should be considered as Pod managed by a DaemonSet
@@ -83,3 +86,10 @@ func ClearPodNodeNames(pods []*apiv1.Pod) []*apiv1.Pod {
 	}
 	return newPods
 }
+
+// IsInterruptionSensitive returns true if the Pod must not be scheduled on spot or preemptible capacity. Pods
+// are tolerant unless annotated otherwise. This only steers scale-up simulations, the scheduler may still
+// place the Pod on spot nodes.
+func IsInterruptionSensitive(pod *apiv1.Pod) bool {
+	return pod.Annotations[InterruptionSensitiveAnnotationKey] == "true"
+}
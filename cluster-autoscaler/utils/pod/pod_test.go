@@ -290,3 +290,44 @@ func TestClearPodNodeNames(t *testing.T) {
 		})
 	}
 }
+
+func TestIsInterruptionSensitive(t *testing.T) {
+	testCases := []struct {
+		name        string
+		namespace   string
+		annotations map[string]string
+		want        bool
+	}{
+		{
+			name:      "default namespace pod",
+			namespace: "default",
+			want:      false,
+		},
+		{
+			name:      "kube-system pod",
+			namespace: "kube-system",
+			want:      false,
+		},
+		{
+			name:        "annotated pod",
+			namespace:   "default",
+			annotations: map[string]string{InterruptionSensitiveAnnotationKey: "true"},
+			want:        true,
+		},
+		{
+			name:        "pod annotated as tolerant",
+			namespace:   "kube-system",
+			annotations: map[string]string{InterruptionSensitiveAnnotationKey: "false"},
+			want:        false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := BuildTestPod("p", 100, 1)
+			pod.Namespace = tc.namespace
+			pod.Annotations = tc.annotations
+			assert.Equal(t, tc.want, IsInterruptionSensitive(pod))
+		})
+	}
+}
@@ -54,8 +54,64 @@ const (
 
 	// AWS: Indicates that a node has volumes stuck in attaching state and hence it is not fit for scheduling more pods
 	awsNodeWithImpairedVolumesTaint = "NodeWithImpairedVolumes"
+
+	// ToBeDeletedOwnerAnnotation records the identity of the autoscaler instance that placed the ToBeDeleted taint.
+	ToBeDeletedOwnerAnnotation = "cluster-autoscaler.kubernetes.io/to-be-deleted-owner"
+	// ToBeDeletedGenerationAnnotation records the startup generation of the autoscaler instance that placed the ToBeDeleted taint.
+	ToBeDeletedGenerationAnnotation = "cluster-autoscaler.kubernetes.io/to-be-deleted-generation"
 )
 
+// TaintOwner identifies the autoscaler instance placing ToBeDeleted taints.
+type TaintOwner struct {
+	// Identity should be stable across restarts of the same autoscaler deployment.
+	Identity string
+	// Generation is unique for every start of the autoscaler process.
+	Generation string
+}
+
+// NewTaintOwner returns a TaintOwner with the given identity and a fresh startup generation.
+func NewTaintOwner(identity string) TaintOwner {
+	return TaintOwner{
+		Identity:   identity,
+		Generation: strconv.FormatInt(time.Now().UnixNano(), 10),
+	}
+}
+
+// annotations returns the ownership annotations to be set alongside the ToBeDeleted taint.
+func (o TaintOwner) annotations() map[string]string {
+	if o.Identity == "" {
+		return nil
+	}
+	return map[string]string{
+		ToBeDeletedOwnerAnnotation:      o.Identity,
+		ToBeDeletedGenerationAnnotation: o.Generation,
+	}
+}
+
+// CanCleanUp returns true if the ToBeDeleted taint present on the node is stale from the point of view
+// of the owner, i.e. it was placed by a previous generation of the same autoscaler, or by an autoscaler
+// that didn't record its ownership. Taints placed by other owners are never considered stale.
+// An owner with no identity considers every ToBeDeleted taint stale.
+func (o TaintOwner) CanCleanUp(node *apiv1.Node) bool {
+	if o.Identity == "" {
+		return true
+	}
+	owner, found := GetToBeDeletedOwner(node)
+	if !found {
+		return true
+	}
+	return owner.Identity == o.Identity && owner.Generation != o.Generation
+}
+
+// GetToBeDeletedOwner returns the owner recorded for the ToBeDeleted taint on the node, if any.
+func GetToBeDeletedOwner(node *apiv1.Node) (TaintOwner, bool) {
+	identity, found := node.Annotations[ToBeDeletedOwnerAnnotation]
+	if !found {
+		return TaintOwner{}, false
+	}
+	return TaintOwner{Identity: identity, Generation: node.Annotations[ToBeDeletedGenerationAnnotation]}, true
+}
+
 // TaintKeySet is a set of taint key
 type TaintKeySet map[string]bool
 
@@ -122,14 +178,15 @@ func getKeyShortName(key string) string {
 	}
 }
 
-// MarkToBeDeleted sets a taint that makes the node unschedulable.
-func MarkToBeDeleted(node *apiv1.Node, client kube_client.Interface, cordonNode bool) error {
+// MarkToBeDeleted sets a taint that makes the node unschedulable. The taint is annotated with
+// the given owner, so that stale taints can be told apart from those placed by other controllers.
+func MarkToBeDeleted(node *apiv1.Node, client kube_client.Interface, cordonNode bool, owner TaintOwner) error {
 	taint := apiv1.Taint{
 		Key:    ToBeDeletedTaint,
 		Value:  fmt.Sprint(time.Now().Unix()),
 		Effect: apiv1.TaintEffectNoSchedule,
 	}
-	return AddTaint(node, client, taint, cordonNode)
+	return addTaint(node, client, taint, cordonNode, owner.annotations())
 }
 
 // MarkDeletionCandidate sets a soft taint that makes the node preferably unschedulable.
@@ -144,6 +201,10 @@ func MarkDeletionCandidate(node *apiv1.Node, client kube_client.Interface) error
 
 // AddTaint sets the specified taint on the node.
 func AddTaint(node *apiv1.Node, client kube_client.Interface, taint apiv1.Taint, cordonNode bool) error {
+	return addTaint(node, client, taint, cordonNode, nil)
+}
+
+func addTaint(node *apiv1.Node, client kube_client.Interface, taint apiv1.Taint, cordonNode bool, annotations map[string]string) error {
 	retryDeadline := time.Now().Add(maxRetryDeadline)
 	freshNode := node.DeepCopy()
 	var err error
@@ -166,6 +227,7 @@ func AddTaint(node *apiv1.Node, client kube_client.Interface, taint apiv1.Taint,
 			}
 			return nil
 		}
+		setAnnotations(freshNode, annotations)
 		_, err = client.CoreV1().Nodes().Update(context.TODO(), freshNode, metav1.UpdateOptions{})
 		if err != nil && errors.IsConflict(err) && time.Now().Before(retryDeadline) {
 			refresh = true
@@ -197,6 +259,18 @@ func addTaintToSpec(node *apiv1.Node, taint apiv1.Taint, cordonNode bool) bool {
 	return true
 }
 
+func setAnnotations(node *apiv1.Node, annotations map[string]string) {
+	if len(annotations) == 0 {
+		return
+	}
+	if node.Annotations == nil {
+		node.Annotations = make(map[string]string, len(annotations))
+	}
+	for k, v := range annotations {
+		node.Annotations[k] = v
+	}
+}
+
 // HasToBeDeletedTaint returns true if ToBeDeleted taint is applied on the node.
 func HasToBeDeletedTaint(node *apiv1.Node) bool {
 	return HasTaint(node, ToBeDeletedTaint)
@@ -285,6 +359,10 @@ func CleanTaint(node *apiv1.Node, client kube_client.Interface, taintKey string,
 		}
 
 		freshNode.Spec.Taints = newTaints
+		if taintKey == ToBeDeletedTaint {
+			delete(freshNode.Annotations, ToBeDeletedOwnerAnnotation)
+			delete(freshNode.Annotations, ToBeDeletedGenerationAnnotation)
+		}
 		if cordonNode {
 			klog.V(1).Infof("Marking node %v to be uncordoned by Cluster Autoscaler", freshNode.Name)
 			freshNode.Spec.Unschedulable = false
@@ -306,9 +384,22 @@ func CleanTaint(node *apiv1.Node, client kube_client.Interface, taintKey string,
 	}
 }
 
-// CleanAllToBeDeleted cleans ToBeDeleted taints from given nodes.
-func CleanAllToBeDeleted(nodes []*apiv1.Node, client kube_client.Interface, recorder kube_record.EventRecorder, cordonNode bool) {
-	CleanAllTaints(nodes, client, recorder, ToBeDeletedTaint, cordonNode)
+// CleanAllToBeDeleted cleans ToBeDeleted taints from given nodes. Only taints that are stale
+// from the point of view of the given owner are removed, taints placed by other owners are left intact.
+func CleanAllToBeDeleted(nodes []*apiv1.Node, client kube_client.Interface, recorder kube_record.EventRecorder, cordonNode bool, owner TaintOwner) {
+	var owned []*apiv1.Node
+	for _, node := range nodes {
+		if !HasToBeDeletedTaint(node) {
+			continue
+		}
+		if !owner.CanCleanUp(node) {
+			foreignOwner, _ := GetToBeDeletedOwner(node)
+			klog.V(2).Infof("Not cleaning ToBeDeletedTaint on node %v, it is owned by %q (generation %q)", node.Name, foreignOwner.Identity, foreignOwner.Generation)
+			continue
+		}
+		owned = append(owned, node)
+	}
+	CleanAllTaints(owned, client, recorder, ToBeDeletedTaint, cordonNode)
 }
 
 // CleanAllDeletionCandidates cleans DeletionCandidate taints from given nodes.
@@ -43,12 +43,38 @@ func TestMarkNodes(t *testing.T) {
 	defer setConflictRetryInterval(setConflictRetryInterval(time.Millisecond))
 	node := BuildTestNode("node", 1000, 1000)
 	fakeClient := buildFakeClientWithConflicts(t, node)
-	err := MarkToBeDeleted(node, fakeClient, false)
+	err := MarkToBeDeleted(node, fakeClient, false, TaintOwner{})
 	assert.NoError(t, err)
 
 	updatedNode := getNode(t, fakeClient, "node")
 	assert.True(t, HasToBeDeletedTaint(updatedNode))
 	assert.False(t, HasDeletionCandidateTaint(updatedNode))
+	_, found := GetToBeDeletedOwner(updatedNode)
+	assert.False(t, found)
+}
+
+func TestMarkNodesWithOwner(t *testing.T) {
+	defer setConflictRetryInterval(setConflictRetryInterval(time.Millisecond))
+	node := BuildTestNode("node", 1000, 1000)
+	fakeClient := buildFakeClientWithConflicts(t, node)
+	owner := TaintOwner{Identity: "ca", Generation: "1"}
+	err := MarkToBeDeleted(node, fakeClient, false, owner)
+	assert.NoError(t, err)
+
+	updatedNode := getNode(t, fakeClient, "node")
+	assert.True(t, HasToBeDeletedTaint(updatedNode))
+	recordedOwner, found := GetToBeDeletedOwner(updatedNode)
+	assert.True(t, found)
+	assert.Equal(t, owner, recordedOwner)
+
+	cleaned, err := CleanToBeDeleted(updatedNode, fakeClient, false)
+	assert.True(t, cleaned)
+	assert.NoError(t, err)
+
+	updatedNode = getNode(t, fakeClient, "node")
+	assert.False(t, HasToBeDeletedTaint(updatedNode))
+	_, found = GetToBeDeletedOwner(updatedNode)
+	assert.False(t, found)
 }
 
 func TestSoftMarkNodes(t *testing.T) {
@@ -99,7 +125,7 @@ func TestQueryNodes(t *testing.T) {
 	defer setConflictRetryInterval(setConflictRetryInterval(time.Millisecond))
 	node := BuildTestNode("node", 1000, 1000)
 	fakeClient := buildFakeClientWithConflicts(t, node)
-	err := MarkToBeDeleted(node, fakeClient, false)
+	err := MarkToBeDeleted(node, fakeClient, false, TaintOwner{})
 	assert.NoError(t, err)
 
 	updatedNode := getNode(t, fakeClient, "node")
@@ -235,12 +261,44 @@ func TestCleanAllToBeDeleted(t *testing.T) {
 
 	assert.Equal(t, 1, len(getNode(t, fakeClient, "n2").Spec.Taints))
 
-	CleanAllToBeDeleted([]*apiv1.Node{n1, n2}, fakeClient, fakeRecorder, false)
+	CleanAllToBeDeleted([]*apiv1.Node{n1, n2}, fakeClient, fakeRecorder, false, TaintOwner{})
 
 	assert.Equal(t, 0, len(getNode(t, fakeClient, "n1").Spec.Taints))
 	assert.Equal(t, 0, len(getNode(t, fakeClient, "n2").Spec.Taints))
 }
 
+func TestCleanAllToBeDeletedWithOwner(t *testing.T) {
+	owner := TaintOwner{Identity: "ca", Generation: "2"}
+	taint := apiv1.Taint{Key: ToBeDeletedTaint, Value: strconv.FormatInt(time.Now().Unix()-301, 10)}
+	ownerAnnotations := func(identity, generation string) map[string]string {
+		return map[string]string{ToBeDeletedOwnerAnnotation: identity, ToBeDeletedGenerationAnnotation: generation}
+	}
+
+	unowned := BuildTestNode("unowned", 1000, 10)
+	unowned.Spec.Taints = []apiv1.Taint{taint}
+	stale := BuildTestNode("stale", 1000, 10)
+	stale.Spec.Taints = []apiv1.Taint{taint}
+	stale.Annotations = ownerAnnotations("ca", "1")
+	current := BuildTestNode("current", 1000, 10)
+	current.Spec.Taints = []apiv1.Taint{taint}
+	current.Annotations = ownerAnnotations("ca", "2")
+	foreign := BuildTestNode("foreign", 1000, 10)
+	foreign.Spec.Taints = []apiv1.Taint{taint}
+	foreign.Annotations = ownerAnnotations("other", "1")
+
+	fakeClient := buildFakeClient(t, unowned, stale, current, foreign)
+	fakeRecorder := kube_util.CreateEventRecorder(fakeClient, false)
+
+	CleanAllToBeDeleted([]*apiv1.Node{unowned, stale, current, foreign}, fakeClient, fakeRecorder, false, owner)
+
+	assert.False(t, HasToBeDeletedTaint(getNode(t, fakeClient, "unowned")))
+	assert.False(t, HasToBeDeletedTaint(getNode(t, fakeClient, "stale")))
+	_, found := GetToBeDeletedOwner(getNode(t, fakeClient, "stale"))
+	assert.False(t, found)
+	assert.True(t, HasToBeDeletedTaint(getNode(t, fakeClient, "current")))
+	assert.True(t, HasToBeDeletedTaint(getNode(t, fakeClient, "foreign")))
+}
+
 func TestCleanAllDeletionCandidates(t *testing.T) {
 	n1 := BuildTestNode("n1", 1000, 10)
 	n2 := BuildTestNode("n2", 1000, 10)